@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"fomo/internal/history"
+)
+
+// annotationProperty prefixes the run properties annotations are stored
+// in, one per annotation.
+const annotationProperty = "fomo.annotation."
+
+func runAnnotate(a *app, args []string) error {
+	const usage = "usage: fomo annotate <run-id> --message TEXT [--tag TAG]..."
+	fs := a.newFlagSet("annotate", "<run-id> --message TEXT [--tag TAG]...")
+	message := fs.String("message", "", "what happened, e.g. 'hotfix deployed'")
+	var tags tagFlags
+	fs.Var(&tags, "tag", "tag the run too, e.g. with an incident ID (repeatable)")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 || strings.TrimSpace(*message) == "" {
+		return newUsageError(usage)
+	}
+	runID, err := parseRunID(positional[0])
+	if err != nil {
+		return err
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	b, err := c.GetBuild(ctx, runID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch run %d: %w", runID, err)
+	}
+	annotation := history.Annotation{
+		Project:    c.Project,
+		PipelineID: b.Definition.ID,
+		Pipeline:   b.Definition.Name,
+		RunID:      b.ID,
+		RunName:    b.BuildNumber,
+		Message:    *message,
+		Tags:       tags,
+		Time:       time.Now().UTC(),
+	}
+	if me, err := c.Me(ctx); err == nil {
+		annotation.Author = me.UniqueName
+	}
+
+	for _, tag := range tags {
+		if _, err := c.AddBuildTag(ctx, runID, tag); err != nil {
+			return fmt.Errorf("failed to tag run %d with %s: %w", runID, tag, err)
+		}
+	}
+	property := map[string]any{
+		annotationProperty + fmt.Sprint(annotation.Time.UnixMilli()): map[string]any{
+			"message": annotation.Message,
+			"tags":    annotation.Tags,
+			"author":  annotation.Author,
+			"time":    annotation.Time,
+		},
+	}
+	if err := c.SetBuildProperties(ctx, runID, property); err != nil {
+		return fmt.Errorf("failed to annotate run %d: %w", runID, err)
+	}
+	store := &history.Store{Dir: a.cfg.HistoryDir}
+	if err := store.Annotate(annotation); err != nil {
+		return fmt.Errorf("failed to record the annotation in history: %w", err)
+	}
+	a.infof("Annotated %s %s: %s\n", b.Definition.Name, b.BuildNumber, formatAnnotation(annotation))
+	return nil
+}
+
+// pipelineAnnotations returns the annotations recorded for a pipeline since
+// a time, oldest first. The history is only context here, so failing to
+// read it is a warning.
+func pipelineAnnotations(a *app, project string, pipelineID int, since time.Time) []history.Annotation {
+	store := &history.Store{Dir: a.cfg.HistoryDir}
+	all, err := store.Annotations()
+	if err != nil {
+		a.warnf("Could not read annotations: %v\n", err)
+		return nil
+	}
+	var annotations []history.Annotation
+	for _, an := range all {
+		if strings.EqualFold(an.Project, project) && an.PipelineID == pipelineID && !an.Time.Before(since) {
+			annotations = append(annotations, an)
+		}
+	}
+	return annotations
+}
+
+// formatAnnotation renders the message and tags of an annotation.
+func formatAnnotation(an history.Annotation) string {
+	if len(an.Tags) == 0 {
+		return an.Message
+	}
+	return fmt.Sprintf("%s [%s]", an.Message, strings.Join(an.Tags, ", "))
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"fomo/internal/client"
+)
+
+// archiveMarker is the metadata of an archived run. It is written last, so
+// a run directory with one is complete and later archives skip it.
+const archiveMarker = "run.json"
+
+func runArchive(a *app, args []string) error {
+	const usage = "usage: fomo archive <pipeline> --out DIR [--since 90d] [--skip-logs]"
+	fs := a.newFlagSet("archive", "<pipeline> --out DIR [--since 90d] [--skip-logs]")
+	out := fs.String("out", "", "directory to archive to; runs archived in it before are skipped")
+	since := fs.String("since", "90d", "archive the runs queued in this window, e.g. 90d or 26w")
+	skipLogs := fs.Bool("skip-logs", false, "only archive the metadata, timelines and test results of runs")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 || *out == "" {
+		return newUsageError(usage)
+	}
+	window, err := parseAge(*since)
+	if err != nil {
+		return newUsageError(err.Error())
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	p, err := resolvePipeline(ctx, c, positional[0])
+	if err != nil {
+		return err
+	}
+	builds, err := c.ListBuilds(ctx, client.BuildCriteria{
+		Definitions: []int{p.ID},
+		MinTime:     time.Now().Add(-window),
+		Status:      client.RunStateCompleted,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fetch the runs of %s: %w", p.Name, err)
+	}
+	// Oldest first: they are the closest to being deleted
+	slices.Reverse(builds)
+
+	dir := filepath.Join(*out, strings.TrimSuffix(definitionFilePath(p.Folder, p.Name), ".json"))
+	archived, failed := 0, 0
+	for i, b := range builds {
+		runDir := filepath.Join(dir, strconv.Itoa(b.ID))
+		if _, err := os.Stat(filepath.Join(runDir, archiveMarker)); err == nil {
+			continue
+		}
+		a.infof("Archiving %s %s (%d of %d)...\n", p.Name, b.BuildNumber, i+1, len(builds))
+		if err := archiveRun(ctx, c, b.ID, runDir, !*skipLogs); err != nil {
+			a.warnf("Warning: %v\n", err)
+			failed++
+			continue
+		}
+		archived++
+		if a.quiet {
+			a.resultf("%s\n", runDir)
+		}
+	}
+
+	a.infof("Archived %d run(s) of %s to %s; %d were archived before.\n", archived, p.Name, dir, len(builds)-archived-failed)
+	if failed > 0 {
+		return fmt.Errorf("%d run(s) could not be archived; run fomo archive again to retry them", failed)
+	}
+	return nil
+}
+
+// archiveRun saves a run's timeline, test results, logs and metadata to
+// dir. An interrupted download of the logs resumes on the next archive.
+func archiveRun(ctx context.Context, c *client.Client, runID int, dir string, logs bool) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	timeline, err := c.GetTimelineRaw(ctx, runID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch the timeline of run %d: %w", runID, err)
+	}
+	if err := writeArchiveJSON(filepath.Join(dir, "timeline.json"), timeline); err != nil {
+		return err
+	}
+
+	testRuns, err := c.ListTestRunsRaw(ctx, runID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch the test runs of run %d: %w", runID, err)
+	}
+	if len(testRuns) > 0 {
+		type testRun struct {
+			Run     map[string]any   `json:"run"`
+			Results []map[string]any `json:"results"`
+		}
+		tests := make([]testRun, 0, len(testRuns))
+		for _, run := range testRuns {
+			id, _ := run["id"].(float64)
+			results, err := c.ListTestResultsRaw(ctx, int(id))
+			if err != nil {
+				return fmt.Errorf("failed to fetch the results of test run %d of run %d: %w", int(id), runID, err)
+			}
+			tests = append(tests, testRun{Run: run, Results: results})
+		}
+		if err := writeArchiveJSON(filepath.Join(dir, "tests.json"), tests); err != nil {
+			return err
+		}
+	}
+
+	if logs {
+		path := filepath.Join(dir, "logs.zip")
+		if checkZip(path) != nil {
+			if _, err := c.Download(ctx, c.LogsZipURL(runID), "application/zip", path); err != nil {
+				return fmt.Errorf("failed to download the logs of run %d: %w", runID, err)
+			}
+			if err := checkZip(path); err != nil {
+				return errors.Join(fmt.Errorf("the logs of run %d are damaged: %w", runID, err), os.Remove(path))
+			}
+		}
+	}
+
+	build, err := c.GetBuildRaw(ctx, runID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch run %d: %w", runID, err)
+	}
+	return writeArchiveJSON(filepath.Join(dir, archiveMarker), build)
+}
+
+// writeArchiveJSON writes v as indented JSON, replacing path only once it
+// is complete.
+func writeArchiveJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
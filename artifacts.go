@@ -0,0 +1,401 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"fomo/internal/client"
+)
+
+// uploadFile is a local file to upload and where it goes in the artifact.
+type uploadFile struct {
+	path     string
+	itemPath string
+	size     int64
+}
+
+// artifactFiles lists the regular files under root, a file or directory,
+// with their paths inside the artifact called name.
+func artifactFiles(root, name string) ([]uploadFile, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []uploadFile{{path: root, itemPath: path.Join(name, filepath.Base(root)), size: info.Size()}}, nil
+	}
+	var files []uploadFile
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, uploadFile{path: p, itemPath: path.Join(name, filepath.ToSlash(rel)), size: info.Size()})
+		return nil
+	})
+	return files, err
+}
+
+// runContainer finds the file container of a run from its container
+// artifacts. The build API does not expose it otherwise.
+func runContainer(artifacts []client.Artifact) (int64, bool) {
+	for _, artifact := range artifacts {
+		if id, ok := artifact.Resource.ContainerID(); ok {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+func runArtifactsUpload(a *app, args []string) error {
+	fs := a.newFlagSet("artifacts upload", "<run-id> --name NAME --path PATH [--container ID]")
+	name := fs.String("name", "", "name of the artifact")
+	root := fs.String("path", "", "file or directory to upload")
+	container := fs.Int64("container", 0, "ID of the run's file container, if the run has no container artifact yet")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 || *name == "" || *root == "" {
+		return newUsageError("usage: fomo artifacts upload <run-id> --name NAME --path PATH [--container ID]")
+	}
+	if strings.ContainsAny(*name, `/\`) {
+		return newUsageError(fmt.Sprintf("invalid artifact name %q", *name))
+	}
+	runID, err := parseRunID(positional[0])
+	if err != nil {
+		return err
+	}
+	files, err := artifactFiles(*root, *name)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", *root, err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("%s has no files to upload", *root)
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	build, err := c.GetBuild(ctx, runID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch run %d: %w", runID, err)
+	}
+	artifacts, err := c.ListArtifacts(ctx, runID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch the artifacts of run %d: %w", runID, err)
+	}
+	for _, artifact := range artifacts {
+		if strings.EqualFold(artifact.Name, *name) {
+			return newUsageError(fmt.Sprintf("run %d already has an artifact named %s", runID, artifact.Name))
+		}
+	}
+	containerID := *container
+	if containerID == 0 {
+		var ok bool
+		if containerID, ok = runContainer(artifacts); !ok {
+			return fmt.Errorf("cannot find the file container of run %d, which has no container artifacts yet; "+
+				"pass --container with the value of the run's build.containerid variable", runID)
+		}
+	}
+	project, err := c.GetProject(ctx, c.Project)
+	if err != nil {
+		return fmt.Errorf("failed to fetch project %s: %w", c.Project, err)
+	}
+
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+	a.infof("Uploading %d file(s), %s, to run %d of %s...\n", len(files), formatBytes(total), runID, build.Definition.Name)
+	if err := uploadFiles(ctx, c, containerID, project.ID, files); err != nil {
+		return err
+	}
+	if _, err := c.AddContainerArtifact(ctx, runID, containerID, *name); err != nil {
+		return fmt.Errorf("failed to attach artifact %s to run %d: %w", *name, runID, err)
+	}
+	a.infof("Published artifact %s to %s %s (run %d)\n", *name, build.Definition.Name, build.BuildNumber, runID)
+	return nil
+}
+
+func runArtifactsDownload(a *app, args []string) error {
+	const usage = "usage: fomo artifacts download <run-id> --name NAME [--output FILE] [--extract DIR [--include GLOB]...]"
+	fs := a.newFlagSet("artifacts download", "<run-id> --name NAME [--output FILE] [--extract DIR [--include GLOB]...]")
+	name := fs.String("name", "", "name of the artifact")
+	output := fs.String("output", "", "file to save the zip to (default NAME.zip; with --extract, kept only if given)")
+	extract := fs.String("extract", "", "extract the files of the artifact into this directory")
+	var include tagFlags
+	fs.Var(&include, "include", "with --extract, only extract files matching this glob, such as 'bin/**/*.dll' (repeatable)")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 || *name == "" || (len(include) > 0 && *extract == "") {
+		return newUsageError(usage)
+	}
+	runID, err := parseRunID(positional[0])
+	if err != nil {
+		return err
+	}
+	globs, err := parsePathGlobs(include)
+	if err != nil {
+		return newUsageError(err.Error())
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	artifacts, err := c.ListArtifacts(ctx, runID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch the artifacts of run %d: %w", runID, err)
+	}
+	var artifact *client.Artifact
+	var names []string
+	for i := range artifacts {
+		names = append(names, artifacts[i].Name)
+		if strings.EqualFold(artifacts[i].Name, *name) {
+			artifact = &artifacts[i]
+		}
+	}
+	if artifact == nil {
+		return &client.APIError{StatusCode: http.StatusNotFound, Status: "404 Not Found",
+			Message: fmt.Sprintf("run %d has no artifact %s (artifacts: %s)", runID, *name, orDash(strings.Join(names, ", ")))}
+	}
+	if artifact.Resource.DownloadURL == "" {
+		return fmt.Errorf("artifact %s of run %d cannot be downloaded as a zip", artifact.Name, runID)
+	}
+
+	path := *output
+	switch {
+	case path != "":
+	case *extract != "":
+		// Next to the files, so that an interrupted download resumes
+		if err := os.MkdirAll(*extract, 0755); err != nil {
+			return err
+		}
+		path = filepath.Join(*extract, "."+artifact.Name+".zip")
+	default:
+		path = artifact.Name + ".zip"
+	}
+	what := fmt.Sprintf("artifact %s of run %d", artifact.Name, runID)
+	if err := downloadZip(ctx, a, c, artifact.Resource.DownloadURL, path, what); err != nil {
+		return err
+	}
+	if *extract == "" {
+		if err := checkZip(path); err != nil {
+			return fmt.Errorf("%s is damaged: %w; delete it and download it again", path, err)
+		}
+		a.resultf("%s\n", path)
+		return nil
+	}
+
+	files, err := extractZip(ctx, path, *extract, artifact.Name, globs)
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", what, err)
+	}
+	if *output == "" {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	if len(globs) > 0 && len(files) == 0 {
+		return fmt.Errorf("no file of %s matches %s", what, strings.Join(include, ", "))
+	}
+	a.infof("Extracted %d file(s) to %s\n", len(files), *extract)
+	if a.quiet {
+		for _, f := range files {
+			a.resultf("%s\n", f)
+		}
+	}
+	return nil
+}
+
+// downloadZip downloads a zip file, resuming an earlier attempt.
+func downloadZip(ctx context.Context, a *app, c *client.Client, rawURL, path, what string) error {
+	if _, err := os.Stat(path + ".part"); err == nil {
+		a.infof("Resuming the download of %s to %s...\n", what, path)
+	} else {
+		a.infof("Downloading %s to %s...\n", what, path)
+	}
+	downloaded, err := c.Download(ctx, rawURL, "application/zip", path)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", what, err)
+	}
+	if downloaded.Resumed > 0 {
+		a.infof("Downloaded %s, %s of it before the interruption\n", formatBytes(downloaded.Size), formatBytes(downloaded.Resumed))
+	} else {
+		a.infof("Downloaded %s\n", formatBytes(downloaded.Size))
+	}
+	return nil
+}
+
+// extractZip writes the files of an artifact's zip that match globs, or
+// all of them, under dir, 8 at a time, and returns their paths. The zip
+// keeps everything in a folder named after the artifact; paths and globs
+// start inside it. Reading each file to the end verifies its CRC-32.
+func extractZip(ctx context.Context, zipPath, dir, artifact string, globs []*pathGlob) ([]string, error) {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var entries []*zip.File
+	var paths []string
+	for _, f := range r.File {
+		name := strings.TrimPrefix(f.Name, artifact+"/")
+		if f.FileInfo().IsDir() || name == "" {
+			continue
+		}
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return nil, fmt.Errorf("refusing to extract %s outside of %s", f.Name, dir)
+		}
+		if len(globs) > 0 && len(matchingFiles([]string{name}, globs)) == 0 {
+			continue
+		}
+		entries = append(entries, f)
+		paths = append(paths, filepath.Join(dir, filepath.FromSlash(name)))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		sem      = make(chan struct{}, 8)
+	)
+	for i, f := range entries {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if ctx.Err() != nil {
+				return
+			}
+			err := extractFile(f, paths[i])
+			if err == nil {
+				return
+			}
+			mu.Lock()
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", f.Name, err)
+				cancel()
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return paths, firstErr
+}
+
+func extractFile(f *zip.File, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	src, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	mode := f.Mode().Perm()
+	if mode == 0 {
+		mode = 0644
+	}
+	dst, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// checkZip reads every entry of a zip file, which verifies their CRC-32.
+func checkZip(path string) error {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+		_, err = io.Copy(io.Discard, rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+	}
+	return nil
+}
+
+// uploadFiles uploads files to a file container, at most 8 at a time, and
+// returns the first failure.
+func uploadFiles(ctx context.Context, c *client.Client, containerID int64, projectID string, files []uploadFile) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		sem      = make(chan struct{}, 8)
+	)
+	for _, f := range files {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			err := uploadOne(ctx, c, containerID, projectID, f)
+			if err == nil {
+				return
+			}
+			mu.Lock()
+			if firstErr == nil {
+				firstErr = err
+				cancel()
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
+
+func uploadOne(ctx context.Context, c *client.Client, containerID int64, projectID string, f uploadFile) error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := c.UploadContainerFile(ctx, containerID, projectID, f.itemPath, file, f.size); err != nil {
+		return fmt.Errorf("failed to upload %s: %w", f.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"fomo/internal/client"
+)
+
+// retiredImages maps the Microsoft-hosted image labels that were retired,
+// lowercased, to the label to move to. --retired adds to it when Microsoft
+// announces the next retirement.
+var retiredImages = map[string]string{
+	"ubuntu-16.04":     "ubuntu-latest",
+	"ubuntu-18.04":     "ubuntu-latest",
+	"ubuntu-20.04":     "ubuntu-24.04",
+	"vs2015-win2012r2": "windows-latest",
+	"win1803":          "windows-latest",
+	"vs2017-win2016":   "windows-2022",
+	"windows-2019":     "windows-2022",
+	"macos-10.13":      "macOS-latest",
+	"macos-10.14":      "macOS-latest",
+	"macos-10.15":      "macOS-latest",
+	"macos-11":         "macOS-latest",
+	"macos-12":         "macOS-latest",
+	"macos-13":         "macOS-latest",
+}
+
+var (
+	// vmImageLine is a vmImage setting in pipeline YAML
+	vmImageLine = regexp.MustCompile(`(?:^|[\s{,])vmImage:\s*['"]?([^'"\s#,}]+)`)
+	// logImageLine is the image a hosted job ran on, near the top of its log
+	logImageLine = regexp.MustCompile(`^(?:\S+Z )?Image: (\S+)`)
+)
+
+// auditJobLogs bounds how many job logs of a run are read for images.
+const auditJobLogs = 20
+
+// imageFinding is a use of a retired image by a pipeline.
+type imageFinding struct {
+	pipeline    string
+	source      string // file:line, definition, or the run and job
+	image       string
+	replacement string
+}
+
+// retiredFlags collects repeated --retired LABEL=REPLACEMENT flags.
+type retiredFlags map[string]string
+
+func (f retiredFlags) String() string { return "" }
+
+func (f retiredFlags) Set(s string) error {
+	label, replacement, ok := strings.Cut(s, "=")
+	if !ok || label == "" || replacement == "" {
+		return fmt.Errorf("expected LABEL=REPLACEMENT, got %q", s)
+	}
+	f[strings.ToLower(label)] = replacement
+	return nil
+}
+
+// yamlImages finds the vmImage settings in pipeline YAML, with their line
+// numbers. Images set through variables or expressions are skipped.
+func yamlImages(content string) map[int]string {
+	images := map[int]string{}
+	for i, line := range strings.Split(content, "\n") {
+		if m := vmImageLine.FindStringSubmatch(line); m != nil && !strings.HasPrefix(m[1], "$") {
+			images[i+1] = m[1]
+		}
+	}
+	return images
+}
+
+// agentSpecifications finds the hosted images classic pipelines select,
+// in the agentSpecification of the definition and of each phase.
+func agentSpecifications(v any) []string {
+	var images []string
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if spec, ok := value.(map[string]any); ok && key == "agentSpecification" {
+				if id, ok := spec["identifier"].(string); ok && id != "" {
+					images = append(images, id)
+				}
+				continue
+			}
+			images = append(images, agentSpecifications(value)...)
+		}
+	case []any:
+		for _, value := range v {
+			images = append(images, agentSpecifications(value)...)
+		}
+	}
+	return images
+}
+
+// definitionYAML reads the YAML file of a pipeline from the default branch
+// of its repository, given its raw definition. The file name is empty for
+// classic pipelines and pipelines whose YAML is not in Azure Repos.
+func definitionYAML(ctx context.Context, c *client.Client, d client.Definition, definition map[string]any) (file, content string, err error) {
+	process, _ := definition["process"].(map[string]any)
+	file, _ = process["yamlFilename"].(string)
+	if file == "" || d.Repository == nil || d.Repository.Type != client.RepositoryTypeAzureRepos {
+		return "", "", nil
+	}
+	content, err = c.GetFileContent(ctx, d.Repository.ID, file, d.Repository.DefaultBranch)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read %s of %s: %w", file, definitionPath(d.DefinitionReference), err)
+	}
+	return strings.TrimPrefix(file, "/"), content, nil
+}
+
+// auditPipelineImages looks for retired images in a pipeline's definition,
+// its YAML file on the default branch, and the jobs of its latest run.
+func auditPipelineImages(ctx context.Context, c *client.Client, d client.Definition, retired map[string]string, runs bool) ([]imageFinding, error) {
+	name := definitionPath(d.DefinitionReference)
+	var findings []imageFinding
+	add := func(source, image string) {
+		if replacement, ok := retired[strings.ToLower(image)]; ok {
+			findings = append(findings, imageFinding{pipeline: name, source: source, image: image, replacement: replacement})
+		}
+	}
+
+	definition, err := c.GetDefinitionRaw(ctx, d.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pipeline %s: %w", name, err)
+	}
+	for _, image := range agentSpecifications(definition) {
+		add("definition", image)
+	}
+	file, content, err := definitionYAML(ctx, c, d, definition)
+	if err != nil {
+		return nil, err
+	}
+	if file != "" {
+		images := yamlImages(content)
+		lines := make([]int, 0, len(images))
+		for line := range images {
+			lines = append(lines, line)
+		}
+		sort.Ints(lines)
+		for _, line := range lines {
+			add(fmt.Sprintf("%s:%d", file, line), images[line])
+		}
+	}
+
+	if !runs || d.LatestBuild == nil {
+		return findings, nil
+	}
+	runID := d.LatestBuild.ID
+	timeline, err := c.GetTimeline(ctx, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch timeline of run %d of %s: %w", runID, name, err)
+	}
+	read := 0
+	for _, record := range timeline.Records {
+		if record.Type != client.RecordJob || record.Log == nil || read == auditJobLogs {
+			continue
+		}
+		read++
+		text, err := c.GetLogContent(ctx, d.ID, runID, record.Log.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download the log of job %s of run %d: %w", record.Name, runID, err)
+		}
+		lines := strings.SplitN(text, "\n", 50)
+		for _, line := range lines[:min(len(lines), 49)] {
+			if m := logImageLine.FindStringSubmatch(strings.TrimSpace(strings.TrimPrefix(line, "\uFEFF"))); m != nil {
+				add(fmt.Sprintf("run %d, job %s", runID, record.Name), m[1])
+			}
+		}
+	}
+	return findings, nil
+}
+
+func runAuditImages(a *app, args []string) error {
+	fs := a.newFlagSet("audit images", "[--pipeline NAME] [--skip-runs] [--retired LABEL=REPLACEMENT]...")
+	pipeline := fs.String("pipeline", "", "only audit this pipeline (name or ID)")
+	skipRuns := fs.Bool("skip-runs", false, "only read definitions and YAML, not the logs of the latest runs")
+	retired := retiredFlags{}
+	for label, replacement := range retiredImages {
+		retired[label] = replacement
+	}
+	fs.Var(retired, "retired", "also treat image LABEL as retired, to be replaced by REPLACEMENT (repeatable)")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	definitions, err := c.ListDefinitionDetails(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch pipelines: %w", err)
+	}
+	if *pipeline != "" {
+		p, err := resolvePipeline(ctx, c, *pipeline)
+		if err != nil {
+			return err
+		}
+		definitions = slices.DeleteFunc(definitions, func(d client.Definition) bool { return d.ID != p.ID })
+	}
+
+	results := make([][]imageFinding, len(definitions))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 8)
+	for i, d := range definitions {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			findings, err := auditPipelineImages(ctx, c, d, retired, !*skipRuns)
+			if err != nil {
+				a.warnf("Warning: %v\n", err)
+			}
+			results[i] = findings
+		}()
+	}
+	wg.Wait()
+
+	var findings []imageFinding
+	for _, f := range results {
+		findings = append(findings, f...)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return strings.ToLower(findings[i].pipeline) < strings.ToLower(findings[j].pipeline)
+	})
+
+	if a.quiet {
+		for _, f := range findings {
+			a.resultf("%s\t%s\t%s\t%s\n", f.pipeline, f.source, f.image, f.replacement)
+		}
+		return nil
+	}
+	if len(findings) == 0 {
+		a.infof("None of %d pipelines uses a retired hosted image.\n", len(definitions))
+		return nil
+	}
+	pipelines := 0
+	for i, f := range findings {
+		if i == 0 || f.pipeline != findings[i-1].pipeline {
+			if i > 0 {
+				a.resultf("\n")
+			}
+			a.resultf("%s\n", f.pipeline)
+			pipelines++
+		}
+		a.resultf("  %s: %s is retired; use %s\n", f.source, f.image, f.replacement)
+	}
+	a.infof("\n%d of %d pipelines use retired hosted images.\n", pipelines, len(definitions))
+	return nil
+}
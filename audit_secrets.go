@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"fomo/internal/client"
+)
+
+// credentialPatterns recognize credentials by their format.
+var credentialPatterns = []struct {
+	kind string
+	re   *regexp.Regexp
+}{
+	{"private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+	{"AWS access key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"GitHub token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{40,})\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{"Azure storage key", regexp.MustCompile(`(?i)AccountKey=[A-Za-z0-9+/]{80,}={0,2}`)},
+	{"shared access signature", regexp.MustCompile(`(?i)(?:SharedAccessKey=[A-Za-z0-9+/]{40,}={0,2}|[?&]sig=[A-Za-z0-9%]{40,})`)},
+	{"JSON web token", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+	{"password in connection string", regexp.MustCompile(`(?i)(?:password|pwd)=[^;'"\s$]{4,}`)},
+	{"password in URL", regexp.MustCompile(`[a-z][a-z0-9+.-]*://[^/\s:@]+:[^/\s:@$]{4,}@`)},
+}
+
+var (
+	// sensitiveName is a variable or key name that suggests a credential
+	sensitiveName = regexp.MustCompile(`(?i)(?:password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key|private[_-]?key|credential|connection[_-]?string)`)
+	// hexString is a commit ID, GUID or other hexadecimal identifier
+	hexString = regexp.MustCompile(`^[0-9a-fA-F-]+$`)
+	// yamlAssignment is a key and scalar value on a line of YAML
+	yamlAssignment = regexp.MustCompile(`^\s*(?:-\s*)?([\w.-]+)\s*:\s*['"]?([^'"#\s][^'"#]*?)['"]?\s*(?:#.*)?$`)
+)
+
+// secretFinding is a value that looks like a credential stored in
+// plaintext.
+type secretFinding struct {
+	owner    string // the pipeline or variable group
+	location string // file:line or the variable
+	kind     string
+	value    string // masked
+}
+
+// entropy is the Shannon entropy of s in bits per character.
+func entropy(s string) float64 {
+	counts := map[rune]int{}
+	n := 0
+	for _, r := range s {
+		counts[r]++
+		n++
+	}
+	var bits float64
+	for _, count := range counts {
+		p := float64(count) / float64(n)
+		bits -= p * math.Log2(p)
+	}
+	return bits
+}
+
+// looksRandom reports whether a value is a long run of random-looking
+// characters, as generated keys and tokens are. Hexadecimal strings such
+// as commit IDs and GUIDs are random but not secret, and skipped.
+func looksRandom(value string) bool {
+	return len(value) >= 20 && !strings.ContainsAny(value, " \t/\\") && !hexString.MatchString(value) && entropy(value) >= 4
+}
+
+// isExpression reports whether a value refers to other variables or
+// parameters instead of holding a value itself.
+func isExpression(value string) bool {
+	return strings.Contains(value, "$(") || strings.Contains(value, "${{") || strings.Contains(value, "$[")
+}
+
+// maskSecret shows only enough of a value to find it again: its end,
+// since credentials often start with a well-known prefix.
+func maskSecret(value string) string {
+	if len(value) <= 8 {
+		return strings.Repeat("*", len(value))
+	}
+	return strings.Repeat("*", 8) + value[len(value)-4:]
+}
+
+// classifySecret returns what kind of credential a value looks like and
+// the part of it that does, or an empty kind. A name that suggests a
+// credential makes any literal value suspect.
+func classifySecret(name, value string) (kind, match string) {
+	for _, p := range credentialPatterns {
+		if m := p.re.FindString(value); m != "" {
+			return p.kind, m
+		}
+	}
+	if value == "" || isExpression(value) {
+		return "", ""
+	}
+	if name != "" && sensitiveName.MatchString(name) && len(value) >= 6 && !strings.ContainsAny(value, " \t") {
+		return "plaintext value of " + name, value
+	}
+	for _, word := range strings.FieldsFunc(value, func(r rune) bool { return strings.ContainsRune(" \t'\";,", r) }) {
+		if looksRandom(word) {
+			return "high-entropy string", word
+		}
+	}
+	return "", ""
+}
+
+// yamlSecrets scans the lines of pipeline YAML for credentials.
+func yamlSecrets(owner, file, content string) []secretFinding {
+	var findings []secretFinding
+	variable := ""
+	for i, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		name, value := "", line
+		if m := yamlAssignment.FindStringSubmatch(line); m != nil {
+			name, value = m[1], m[2]
+		}
+		// Variables in list form have their name and value on separate
+		// lines: "- name: dbPassword" then "value: ..."
+		switch name {
+		case "name":
+			variable = value
+		case "value":
+			name = variable
+		}
+		if kind, match := classifySecret(name, value); kind != "" {
+			findings = append(findings, secretFinding{owner: owner, location: fmt.Sprintf("%s:%d", file, i+1), kind: kind, value: maskSecret(match)})
+		}
+	}
+	return findings
+}
+
+// variableSecrets checks the values of variables that are not secret.
+func variableSecrets(owner, prefix string, variables map[string]*string) []secretFinding {
+	var findings []secretFinding
+	for _, name := range slices.Sorted(maps.Keys(variables)) {
+		value := variables[name]
+		if value == nil {
+			continue
+		}
+		if kind, match := classifySecret(name, *value); kind != "" {
+			findings = append(findings, secretFinding{owner: owner, location: prefix + name, kind: kind, value: maskSecret(match)})
+		}
+	}
+	return findings
+}
+
+// definitionVariables are the variables of a raw definition that are not
+// marked secret.
+func definitionVariables(definition map[string]any) map[string]*string {
+	raw, _ := definition["variables"].(map[string]any)
+	variables := map[string]*string{}
+	for name, v := range raw {
+		variable, _ := v.(map[string]any)
+		if value, ok := variable["value"].(string); ok && variable["isSecret"] != true {
+			variables[name] = &value
+		}
+	}
+	return variables
+}
+
+// definitionGroups are the IDs of the variable groups a raw definition
+// links.
+func definitionGroups(definition map[string]any) []int {
+	groups, _ := definition["variableGroups"].([]any)
+	var ids []int
+	for _, g := range groups {
+		group, _ := g.(map[string]any)
+		if id, ok := group["id"].(float64); ok {
+			ids = append(ids, int(id))
+		}
+	}
+	return ids
+}
+
+// auditPipelineSecrets scans a pipeline's variables and its YAML file on
+// the default branch, and returns the variable groups it links.
+func auditPipelineSecrets(ctx context.Context, c *client.Client, d client.Definition) ([]secretFinding, []int, error) {
+	name := definitionPath(d.DefinitionReference)
+	definition, err := c.GetDefinitionRaw(ctx, d.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch pipeline %s: %w", name, err)
+	}
+	findings := variableSecrets(name, "variable ", definitionVariables(definition))
+	file, content, err := definitionYAML(ctx, c, d, definition)
+	if err != nil {
+		return findings, definitionGroups(definition), err
+	}
+	if file != "" {
+		findings = append(findings, yamlSecrets(name, file, content)...)
+	}
+	return findings, definitionGroups(definition), nil
+}
+
+func runAuditSecrets(a *app, args []string) error {
+	fs := a.newFlagSet("audit secrets", "[--pipeline NAME]")
+	pipeline := fs.String("pipeline", "", "only audit this pipeline and the variable groups it links (name or ID)")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	definitions, err := c.ListDefinitionDetails(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch pipelines: %w", err)
+	}
+	if *pipeline != "" {
+		p, err := resolvePipeline(ctx, c, *pipeline)
+		if err != nil {
+			return err
+		}
+		definitions = slices.DeleteFunc(definitions, func(d client.Definition) bool { return d.ID != p.ID })
+	}
+	groups, err := c.ListVariableGroups(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch variable groups: %w", err)
+	}
+
+	results := make([][]secretFinding, len(definitions))
+	linked := map[int]bool{}
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		sem = make(chan struct{}, 8)
+	)
+	for i, d := range definitions {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			findings, ids, err := auditPipelineSecrets(ctx, c, d)
+			if err != nil {
+				a.warnf("Warning: %v\n", err)
+			}
+			results[i] = findings
+			mu.Lock()
+			defer mu.Unlock()
+			for _, id := range ids {
+				linked[id] = true
+			}
+		}()
+	}
+	wg.Wait()
+
+	var findings []secretFinding
+	for _, f := range results {
+		findings = append(findings, f...)
+	}
+	scannedGroups := 0
+	for _, g := range groups {
+		// Key Vault groups only hold references to secrets
+		if g.Type == client.VariableGroupAzureKeyVault || (*pipeline != "" && !linked[g.ID]) {
+			continue
+		}
+		scannedGroups++
+		variables := map[string]*string{}
+		for name, v := range g.Variables {
+			if !v.IsSecret {
+				variables[name] = v.Value
+			}
+		}
+		findings = append(findings, variableSecrets("variable group "+g.Name, "variable ", variables)...)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return strings.ToLower(findings[i].owner) < strings.ToLower(findings[j].owner)
+	})
+
+	if a.quiet {
+		for _, f := range findings {
+			a.resultf("%s\t%s\t%s\t%s\n", f.owner, f.location, f.kind, f.value)
+		}
+		return nil
+	}
+	if len(findings) == 0 {
+		a.infof("No plaintext credentials found in %d pipelines and %d variable groups.\n", len(definitions), scannedGroups)
+		return nil
+	}
+	owners := 0
+	for i, f := range findings {
+		if i == 0 || f.owner != findings[i-1].owner {
+			if i > 0 {
+				a.resultf("\n")
+			}
+			a.resultf("%s\n", f.owner)
+			owners++
+		}
+		a.resultf("  %s: %s (%s)\n", f.location, f.kind, f.value)
+	}
+	a.infof("\n%d possible credentials in %d places. Rotate them and move them into secret variables or a Key Vault-linked variable group.\n", len(findings), owners)
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"fomo/internal/config"
+)
+
+// auditFileName is the audit trail of changes made through fomo, next to
+// the user config file. Records are only ever appended.
+const auditFileName = "audit.jsonl"
+
+// auditRecord is one change made, or attempted, through fomo.
+type auditRecord struct {
+	Time         time.Time `json:"time"`
+	User         string    `json:"user,omitempty"` // local account running fomo
+	Profile      string    `json:"profile,omitempty"`
+	Organization string    `json:"org,omitempty"`
+	Project      string    `json:"project,omitempty"`
+	Command      string    `json:"command"`
+	Args         []string  `json:"args"`
+	Result       string    `json:"result"` // ok or failed
+	Error        string    `json:"error,omitempty"`
+}
+
+// audit records a change to the audit trail, and posts it to the
+// configured webhook. Credentials are masked in the arguments and error.
+// Failing to audit is a warning: the change has been made either way.
+func (a *app) audit(command string, args []string, err error) {
+	record := auditRecord{Time: time.Now().UTC(), Profile: a.profile, Command: command, Args: make([]string, len(args)), Result: "ok"}
+	if u, err := user.Current(); err == nil {
+		record.User = u.Username
+	}
+	for i, arg := range args {
+		record.Args[i] = a.redactor.String(arg)
+	}
+	if err != nil {
+		record.Result = "failed"
+		record.Error = a.redactor.String(err.Error())
+	}
+	var webhook string
+	if cfg := a.cfg; cfg != nil {
+		record.Profile, record.Organization, record.Project = cfg.Profile, cfg.Organization, cfg.Project
+		webhook = cfg.Audit.Webhook
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		a.log.Warn("failed to encode audit record", "err", err)
+		return
+	}
+
+	path, err := config.UserConfigPath(os.Getenv)
+	if err == nil {
+		err = appendAudit(filepath.Join(filepath.Dir(path), auditFileName), line)
+	}
+	if err != nil {
+		a.log.Warn("failed to write the audit trail", "err", err)
+	}
+	if webhook != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := postAudit(ctx, webhook, line); err != nil {
+			a.log.Warn("failed to send the audit record", "err", err)
+		}
+	}
+}
+
+func appendAudit(path string, line []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// postAudit sends an audit record to a webhook as JSON.
+func postAudit(ctx context.Context, url string, record []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(record))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
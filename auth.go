@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"fomo/internal/config"
+	"fomo/internal/credentials"
+	"fomo/internal/i18n"
+)
+
+// legacyPATComment preceded the export line that older versions of fomo
+// appended to the shell rc file.
+const legacyPATComment = "# Added by Azure DevOps PAT setup"
+
+var legacyPATExport = regexp.MustCompile(`^\s*export\s+` + patEnv + `=(.*)$`)
+
+// legacyPATValue matches the values of PAT exports that are the token
+// itself: bare, or quoted without anything the shell would expand.
+var legacyPATValue = regexp.MustCompile(`^(?:([A-Za-z0-9._~+/=-]+)|'([^']+)'|"([^"$` + "`" + `\\]+)")$`)
+
+// savePAT stores a PAT entered at the prompt in the encrypted credentials
+// file, if the user agrees to pick a passphrase.
+func (a *app) savePAT(cfg *config.Config) error {
+	if !confirm(i18n.T("Save the PAT for profile %s, encrypted with a passphrase?", cfg.Profile)) {
+		return nil
+	}
+	passphrase := a.newPassphrase()
+	if passphrase == "" {
+		a.warnf(i18n.T("The PAT was not saved.\n"))
+		return nil
+	}
+
+	store := &credentials.Store{Path: cfg.CredentialsFile}
+	if err := store.SavePAT(cfg.Profile, cfg.PAT, passphrase); err != nil {
+		return err
+	}
+	a.warnf(i18n.T("PAT saved to %s. fomo will ask for the passphrase when it needs the PAT; set %s to skip the prompt.\n"), cfg.CredentialsFile, config.EnvPassphrase)
+	return nil
+}
+
+// newPassphrase asks for a new passphrase twice. It returns "" when none
+// was given or the two entries differ.
+func (a *app) newPassphrase() string {
+	passphrase := promptSecret(i18n.T("New passphrase: "))
+	if passphrase == "" {
+		a.warnf(i18n.T("No passphrase given.\n"))
+		return ""
+	}
+	if promptSecret(i18n.T("Repeat passphrase: ")) != passphrase {
+		a.warnf(i18n.T("The passphrases do not match.\n"))
+		return ""
+	}
+	a.redactor.Add(passphrase)
+	return passphrase
+}
+
+// legacyPAT is a PAT export found in a shell rc file.
+type legacyPAT struct {
+	file  string
+	lines []int // indexes of the lines to remove
+	pat   string
+
+	// computed are the line numbers of exports whose value the shell
+	// computes, such as $(pass show ado), which are left alone
+	computed []int
+}
+
+func runAuthMigrate(a *app, args []string) error {
+	fs := a.newFlagSet("auth migrate", "[--dry-run]")
+	dryRun := fs.Bool("dry-run", false, "only show what would be changed")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+	var found []legacyPAT
+	for _, name := range []string{".bashrc", ".zshrc"} {
+		legacy, err := findLegacyPAT(filepath.Join(home, name))
+		if err != nil {
+			return err
+		}
+		if legacy == nil {
+			continue
+		}
+		for _, line := range legacy.computed {
+			a.warnf("Warning: leaving %s:%d alone: its value is not the token itself\n", legacy.file, line)
+		}
+		if len(legacy.lines) > 0 {
+			found = append(found, *legacy)
+		}
+	}
+	if len(found) == 0 {
+		a.infof("No %s exports of a token found in ~/.bashrc or ~/.zshrc; nothing to migrate.\n", patEnv)
+		return nil
+	}
+	for _, legacy := range found {
+		a.redactor.Add(legacy.pat)
+		a.infof("Found %s in %s (%d line(s) to remove)\n", patEnv, legacy.file, len(legacy.lines))
+	}
+	for _, legacy := range found[1:] {
+		if legacy.pat != found[0].pat {
+			return fmt.Errorf("%s and %s export different PATs; remove the stale one and run again", found[0].file, legacy.file)
+		}
+	}
+	if *dryRun {
+		return nil
+	}
+
+	// Only the profile name and file locations are needed here
+	cfg, err := (&config.Loader{Flags: a.flags, Profile: a.profile}).Load()
+	if err != nil {
+		return err
+	}
+	store := &credentials.Store{Path: cfg.CredentialsFile}
+	if existing, err := store.EncryptedPAT(cfg.Profile); err != nil {
+		return err
+	} else if existing != "" && !confirm(i18n.T("Profile %s already has a stored PAT. Replace it?", cfg.Profile)) {
+		return errAborted
+	}
+	passphrase := a.newPassphrase()
+	if passphrase == "" {
+		return errAborted
+	}
+	if err := store.SavePAT(cfg.Profile, found[0].pat, passphrase); err != nil {
+		return fmt.Errorf("failed to save PAT: %w", err)
+	}
+	a.infof("Saved the PAT for profile %s in %s\n", cfg.Profile, cfg.CredentialsFile)
+
+	for _, legacy := range found {
+		backup, err := removeLegacyPAT(legacy)
+		if err != nil {
+			return err
+		}
+		a.infof("Removed the PAT from %s (backup without the token: %s)\n", legacy.file, backup)
+	}
+	a.warnf("Run `unset %s` in open terminals so they use the stored PAT.\n", patEnv)
+	return nil
+}
+
+// findLegacyPAT looks for PAT exports in an rc file. It returns nil when the
+// file does not exist or has none. Only exports of the token itself are
+// taken; those of a command's output or another variable are listed in
+// computed. A file that exports several different tokens is refused, since
+// only one of them can be migrated.
+func findLegacyPAT(path string) (*legacyPAT, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	legacy := &legacyPAT{file: path}
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		m := legacyPATExport.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		value := legacyPATValue.FindStringSubmatch(strings.TrimSpace(m[1]))
+		if value == nil {
+			legacy.computed = append(legacy.computed, i+1)
+			continue
+		}
+		pat := value[1] + value[2] + value[3]
+		if legacy.pat != "" && pat != legacy.pat {
+			return nil, fmt.Errorf("%s exports different PATs on lines %d and %d; remove the stale one and run again", path, legacy.lines[0]+1, i+1)
+		}
+		legacy.pat = pat
+		legacy.lines = append(legacy.lines, i)
+		// Take the comment and blank line fomo wrote along with it
+		if i > 0 && strings.TrimSpace(lines[i-1]) == legacyPATComment {
+			legacy.lines = append(legacy.lines, i-1)
+			if i > 1 && strings.TrimSpace(lines[i-2]) == "" {
+				legacy.lines = append(legacy.lines, i-2)
+			}
+		}
+	}
+	if len(legacy.lines) == 0 && len(legacy.computed) == 0 {
+		return nil, nil
+	}
+	return legacy, nil
+}
+
+// removeLegacyPAT rewrites the rc file without the PAT lines, after saving
+// a backup in which the token is masked. It returns the backup path.
+func removeLegacyPAT(legacy legacyPAT) (string, error) {
+	info, err := os.Stat(legacy.file)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(legacy.file)
+	if err != nil {
+		return "", err
+	}
+
+	backup := fmt.Sprintf("%s.fomo-backup-%s", legacy.file, time.Now().Format("20060102150405"))
+	masked := strings.ReplaceAll(string(data), legacy.pat, "***")
+	if err := os.WriteFile(backup, []byte(masked), 0600); err != nil {
+		return "", fmt.Errorf("failed to write backup of %s: %w", legacy.file, err)
+	}
+
+	remove := map[int]bool{}
+	for _, i := range legacy.lines {
+		remove[i] = true
+	}
+	var kept []string
+	for i, line := range strings.Split(string(data), "\n") {
+		if !remove[i] {
+			kept = append(kept, line)
+		}
+	}
+	if err := os.WriteFile(legacy.file, []byte(strings.Join(kept, "\n")), info.Mode().Perm()); err != nil {
+		return "", fmt.Errorf("failed to update %s: %w", legacy.file, err)
+	}
+	return backup, nil
+}
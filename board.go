@@ -0,0 +1,364 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"fomo/internal/board"
+	"fomo/internal/client"
+	"fomo/internal/watch"
+)
+
+func runBoard(a *app, args []string) error {
+	fs := a.newFlagSet("board", "--config board.yaml [--serve ADDR]")
+	configPath := fs.String("config", "board.yaml", "board file listing the tiles to show")
+	serve := fs.String("serve", "", "serve the board as an HTML page on this address, e.g. :8080, instead of drawing it in the terminal")
+	once := fs.Bool("once", false, "draw the board once and exit")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	cfg, err := board.Load(*configPath)
+	if err != nil {
+		return err
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	if cfg.Title == "" {
+		cfg.Title = c.Project
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	b := &boardState{client: c, cfg: cfg, pipelineIDs: map[string]int{}, environmentIDs: map[string]int{}}
+	b.refresh(ctx)
+
+	if *serve != "" {
+		return b.serve(ctx, a, *serve)
+	}
+	if a.plain {
+		return b.narrate(ctx, a, *once)
+	}
+	plain := !isTerminal(os.Stdout)
+	if *once || plain {
+		if !plain {
+			fmt.Fprint(a.stdout, "\033[2J")
+		}
+		return b.draw(a, plain)
+	}
+
+	// Full screen: alternate buffer, no cursor, restored on exit
+	fmt.Fprint(a.stdout, "\033[?1049h\033[?25l\033[2J")
+	defer fmt.Fprint(a.stdout, "\033[?25h\033[?1049l")
+
+	redraw := time.NewTicker(time.Second)
+	defer redraw.Stop()
+	next := time.Now().Add(cfg.Refresh)
+	for {
+		if err := b.draw(a, false); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-redraw.C:
+		}
+		// Redraw every second to follow resizes and keep ages current,
+		// but only fetch on the configured schedule
+		if time.Now().After(next) {
+			b.refresh(ctx)
+			next = time.Now().Add(cfg.Refresh)
+		}
+	}
+}
+
+// boardState holds the latest tiles of a board.
+type boardState struct {
+	client *client.Client
+	cfg    *board.Config
+
+	mu      sync.Mutex
+	tiles   []board.Tile
+	updated time.Time
+
+	// Resolved names, so refreshes only fetch runs
+	pipelineIDs    map[string]int
+	environmentIDs map[string]int
+}
+
+// refresh fetches every tile concurrently. A tile that fails shows its
+// error without affecting the others.
+func (b *boardState) refresh(ctx context.Context) {
+	tiles := make([]board.Tile, len(b.cfg.Tiles))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 8)
+	for i, tc := range b.cfg.Tiles {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			c := b.client
+			if tc.Project != "" {
+				c = c.WithProject(tc.Project)
+			}
+			tile := board.Tile{Label: tc.Name()}
+			var err error
+			if tc.Environment != "" {
+				err = b.environmentTile(ctx, c, tc, &tile)
+			} else {
+				err = b.pipelineTile(ctx, c, tc, &tile)
+			}
+			if err != nil {
+				tile.Status, tile.Err = board.StatusUnknown, err
+			}
+			tiles[i] = tile
+		}()
+	}
+	wg.Wait()
+
+	b.mu.Lock()
+	b.tiles, b.updated = tiles, time.Now()
+	b.mu.Unlock()
+}
+
+func (b *boardState) lookup(ids map[string]int, key string) (int, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id, ok := ids[key]
+	return id, ok
+}
+
+func (b *boardState) remember(ids map[string]int, key string, id int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ids[key] = id
+}
+
+func (b *boardState) pipelineTile(ctx context.Context, c *client.Client, tc board.TileConfig, tile *board.Tile) error {
+	key := c.Project + "/" + tc.Pipeline
+	id, ok := b.lookup(b.pipelineIDs, key)
+	if !ok {
+		p, err := resolvePipeline(ctx, c, tc.Pipeline)
+		if err != nil {
+			return err
+		}
+		id = p.ID
+		b.remember(b.pipelineIDs, key, id)
+	}
+	runs, err := c.ListRuns(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	tile.Status = board.StatusUnknown
+	found := false
+	for _, run := range runs {
+		branch := watch.ShortBranch(run.Branch())
+		if tc.Branch != "" && branch != watch.ShortBranch(tc.Branch) {
+			continue
+		}
+		if !found {
+			found = true
+			tile.Detail = run.Name + " on " + branch
+			tile.Since = run.CreatedDate
+			if run.Links.Web != nil {
+				tile.URL = run.Links.Web.Href
+			}
+			switch {
+			case run.State != client.RunStateCompleted:
+				tile.Status = board.StatusRunning
+			case run.Result == client.RunResultSucceeded:
+				tile.Status = board.StatusSucceeded
+			case run.Result == client.RunResultCanceled:
+				tile.Status = board.StatusCanceled
+			default:
+				tile.Status = board.StatusFailed
+			}
+			if run.State == client.RunStateCompleted {
+				tile.Since = run.FinishedDate
+			}
+		}
+		if run.Result == client.RunResultFailed {
+			tile.LastFailure = run.FinishedDate
+			break
+		}
+	}
+	if !found {
+		tile.Detail = "no runs"
+	}
+	return nil
+}
+
+func (b *boardState) environmentTile(ctx context.Context, c *client.Client, tc board.TileConfig, tile *board.Tile) error {
+	key := c.Project + "/" + tc.Environment
+	id, ok := b.lookup(b.environmentIDs, key)
+	if !ok {
+		environments, err := c.ListEnvironments(ctx)
+		if err != nil {
+			return err
+		}
+		for _, env := range environments {
+			if env.Name == tc.Environment || strconv.Itoa(env.ID) == tc.Environment {
+				id, ok = env.ID, true
+				break
+			}
+		}
+		if !ok {
+			return fmt.Errorf("environment %q not found", tc.Environment)
+		}
+		b.remember(b.environmentIDs, key, id)
+	}
+	records, err := c.ListDeploymentRecords(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	tile.Status = board.StatusUnknown
+	tile.Detail = "no deployments"
+	for i, record := range records {
+		if i == 0 {
+			tile.Detail = record.Owner.Name + " from " + record.Definition.Name
+			tile.Since = record.StartTime
+			if record.Owner.Links.Web != nil {
+				tile.URL = record.Owner.Links.Web.Href
+			}
+			switch record.Result {
+			case "":
+				tile.Status = board.StatusRunning
+			case client.RunResultSucceeded:
+				tile.Status = board.StatusSucceeded
+			case client.RunResultCanceled:
+				tile.Status = board.StatusCanceled
+			default:
+				tile.Status = board.StatusFailed
+			}
+			if record.Result != "" {
+				tile.Since = record.FinishTime
+			}
+		}
+		if record.Result == client.RunResultFailed {
+			tile.LastFailure = record.FinishTime
+			break
+		}
+	}
+	return nil
+}
+
+// draw renders the board in the terminal, or as one line per tile for
+// plain output.
+func (b *boardState) draw(a *app, plain bool) error {
+	b.mu.Lock()
+	tiles, updated := b.tiles, b.updated
+	b.mu.Unlock()
+	if plain {
+		for _, t := range tiles {
+			detail := t.Detail
+			if t.Err != nil {
+				detail = t.Err.Error()
+			}
+			failure := "-"
+			if !t.LastFailure.IsZero() {
+				failure = a.display.time(t.LastFailure, time.RFC3339)
+			}
+			a.resultf("%s\t%s\t%s\t%s\n", t.Label, t.Status, detail, failure)
+		}
+		return nil
+	}
+
+	width, height, err := terminalSize(os.Stdout)
+	if err != nil || width == 0 || height == 0 {
+		width, height = 120, 40
+		if n, _ := strconv.Atoi(os.Getenv("COLUMNS")); n > 0 {
+			width = n
+		}
+		if n, _ := strconv.Atoi(os.Getenv("LINES")); n > 0 {
+			height = n
+		}
+	}
+	return board.WriteANSI(a.stdout, b.cfg.Title, tiles, b.cfg.Columns, width, height, a.display.in(updated), time.Now())
+}
+
+// narrate writes the board as labeled sentences for screen readers. Instead
+// of repainting, later refreshes only add a line for each tile that changed.
+func (b *boardState) narrate(ctx context.Context, a *app, once bool) error {
+	b.mu.Lock()
+	tiles, updated := b.tiles, b.updated
+	b.mu.Unlock()
+	if err := board.WritePlain(a.stdout, b.cfg.Title, tiles, a.display.in(updated), time.Now()); err != nil || once {
+		return err
+	}
+
+	ticker := time.NewTicker(b.cfg.Refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		b.refresh(ctx)
+		b.mu.Lock()
+		latest, updated := b.tiles, b.updated
+		b.mu.Unlock()
+		for i, t := range latest {
+			if i < len(tiles) && !t.Changed(tiles[i]) {
+				continue
+			}
+			a.resultf("%s %s\n", a.display.time(updated, time.TimeOnly), t.Sentence(updated))
+		}
+		tiles = latest
+	}
+}
+
+// serve publishes the board as an HTML page that reloads itself, refreshing
+// the data in the background.
+func (b *boardState) serve(ctx context.Context, a *app, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		b.mu.Lock()
+		tiles, updated := b.tiles, b.updated
+		b.mu.Unlock()
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		board.WriteHTML(w, b.cfg.Title, tiles, b.cfg.Columns, b.cfg.Refresh, a.display.in(updated), time.Now())
+	})
+	server := &http.Server{Handler: logRequests(a.log, mux)}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	go func() {
+		ticker := time.NewTicker(b.cfg.Refresh)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				b.refresh(ctx)
+			}
+		}
+	}()
+
+	a.log.Info("serving board", "title", b.cfg.Title, "url", "http://"+listener.Addr().String()+"/")
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"fomo/internal/client"
+	"fomo/internal/i18n"
+	"fomo/internal/watch"
+)
+
+// policySettings are the settings of the branch policies fomo describes;
+// each type uses some of them.
+type policySettings struct {
+	MinimumApproverCount int      `json:"minimumApproverCount"`
+	CreatorVoteCounts    bool     `json:"creatorVoteCounts"`
+	ResetOnSourcePush    bool     `json:"resetOnSourcePush"`
+	BuildDefinitionID    int      `json:"buildDefinitionId"`
+	DisplayName          string   `json:"displayName"`
+	ValidDuration        float64  `json:"validDuration"` // minutes; 0 never expires
+	RequiredReviewerIDs  []string `json:"requiredReviewerIds"`
+}
+
+// policyBranch finds the repository a policies command is about, and the
+// branch it names or else the repository's default branch, as a ref.
+func policyBranch(ctx context.Context, c *client.Client, repoName, branch string) (*client.Repository, string, error) {
+	repo, err := c.GetRepository(ctx, repoName)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch repository %s: %w", repoName, err)
+	}
+	return repo, client.BranchRef(cmp.Or(branch, repo.DefaultBranch)), nil
+}
+
+// describePolicyScope tells where a policy applies, relative to the branch
+// it was listed for.
+func describePolicyScope(p client.PolicyConfiguration) string {
+	for _, s := range p.Scopes() {
+		switch {
+		case s.RepositoryID == nil:
+			return "all repositories"
+		case strings.EqualFold(s.MatchKind, "prefix"):
+			return watch.ShortBranch(s.RefName) + "*"
+		}
+	}
+	return "branch"
+}
+
+// describePolicy summarizes the settings of a branch policy; pipelines
+// names build validation pipelines by ID.
+func describePolicy(d display, p client.PolicyConfiguration, pipelines map[int]string) string {
+	var s policySettings
+	json.Unmarshal(p.Settings, &s)
+	switch p.Type.ID {
+	case client.PolicyMinimumReviewers:
+		details := []string{fmt.Sprintf("%d reviewer(s)", s.MinimumApproverCount)}
+		if s.CreatorVoteCounts {
+			details = append(details, "author's vote counts")
+		}
+		if s.ResetOnSourcePush {
+			details = append(details, "votes reset on push")
+		}
+		return strings.Join(details, ", ")
+	case client.PolicyBuild:
+		details := cmp.Or(pipelines[s.BuildDefinitionID], fmt.Sprintf("pipeline %d", s.BuildDefinitionID))
+		if s.DisplayName != "" {
+			details += " (" + s.DisplayName + ")"
+		}
+		if s.ValidDuration > 0 {
+			details += ", expires after " + d.duration(time.Duration(s.ValidDuration)*time.Minute)
+		}
+		return details
+	case client.PolicyRequiredReviewers:
+		return fmt.Sprintf("%d required reviewer(s)", len(s.RequiredReviewerIDs))
+	}
+	return "-"
+}
+
+func runPoliciesList(a *app, args []string) error {
+	fs := a.newFlagSet("policies list", "--repo NAME [--branch NAME]")
+	repoName := fs.String("repo", "", "repository whose branch policies to list")
+	branch := fs.String("branch", "", "branch whose policies to list (default: the repository's default branch)")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 || *repoName == "" {
+		return newUsageError("usage: fomo policies list --repo NAME [--branch NAME]")
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	repo, ref, err := policyBranch(ctx, c, *repoName, *branch)
+	if err != nil {
+		return err
+	}
+	policies, err := c.ListPolicyConfigurations(ctx, repo.ID, ref)
+	if err != nil {
+		return fmt.Errorf("failed to list the policies of %s %s: %w", repo.Name, watch.ShortBranch(ref), err)
+	}
+
+	// Build validation policies only know their pipeline by ID
+	pipelines := map[int]string{}
+	for _, p := range policies {
+		if p.Type.ID == client.PolicyBuild {
+			list, err := c.ListPipelines(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to fetch pipelines: %w", err)
+			}
+			for _, pipeline := range list {
+				pipelines[pipeline.ID] = pipeline.Name
+			}
+			break
+		}
+	}
+
+	if a.quiet {
+		for _, p := range policies {
+			a.resultf("%d\t%s\t%t\t%t\t%s\n", p.ID, p.Type.DisplayName, p.IsEnabled, p.IsBlocking, describePolicy(a.display, p, pipelines))
+		}
+		return nil
+	}
+	if len(policies) == 0 {
+		a.infof("No policies protect %s %s.\n", repo.Name, watch.ShortBranch(ref))
+		return nil
+	}
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tPOLICY\tENABLED\tBLOCKING\tAPPLIES TO\tSETTINGS")
+	for _, p := range policies {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", p.ID, p.Type.DisplayName, yesNo(p.IsEnabled), yesNo(p.IsBlocking), describePolicyScope(p), describePolicy(a.display, p, pipelines))
+	}
+	return w.Flush()
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// wantedPolicy is a policy policies set makes sure exists.
+type wantedPolicy struct {
+	name     string
+	typeID   string
+	settings map[string]any
+	// same tells whether an existing policy of the type is this one, when
+	// a branch can have several, as with build validation
+	same func(policySettings) bool
+}
+
+func runPoliciesSet(a *app, args []string) error {
+	const usage = "--repo NAME [--branch NAME] [--min-reviewers N [--creator-vote-counts] [--reset-on-push]] [--build PIPELINE [--build-expires DURATION]]... [--comment-resolution] [--optional] [--dry-run] [--yes]"
+	fs := a.newFlagSet("policies set", usage)
+	repoName := fs.String("repo", "", "repository whose branch to protect")
+	branch := fs.String("branch", "", "branch to protect (default: the repository's default branch)")
+	minReviewers := fs.Int("min-reviewers", 0, "require this many approving reviewers")
+	creatorVoteCounts := fs.Bool("creator-vote-counts", false, "with --min-reviewers, count the author's own vote")
+	resetOnPush := fs.Bool("reset-on-push", false, "with --min-reviewers, reset votes when new changes are pushed")
+	var builds tagFlags
+	fs.Var(&builds, "build", "require a successful run of this pipeline (name or ID; repeatable)")
+	buildExpires := fs.Duration("build-expires", 12*time.Hour, "with --build, how long a successful run counts after the target branch changes; 0 never expires")
+	commentResolution := fs.Bool("comment-resolution", false, "require every comment thread to be resolved")
+	optional := fs.Bool("optional", false, "only report on the pull request instead of blocking the merge")
+	var flags changeFlags
+	flags.register(fs)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 || *repoName == "" {
+		return newUsageError("usage: fomo policies set " + usage)
+	}
+	if *minReviewers < 0 || *buildExpires < 0 {
+		return newUsageError("--min-reviewers and --build-expires cannot be negative")
+	}
+	if *minReviewers == 0 && len(builds) == 0 && !*commentResolution {
+		return newUsageError("nothing to set; give --min-reviewers, --build or --comment-resolution")
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	repo, ref, err := policyBranch(ctx, c, *repoName, *branch)
+	if err != nil {
+		return err
+	}
+
+	var wanted []wantedPolicy
+	if *minReviewers > 0 {
+		wanted = append(wanted, wantedPolicy{
+			name:   "minimum reviewers",
+			typeID: client.PolicyMinimumReviewers,
+			settings: map[string]any{
+				"minimumApproverCount": *minReviewers,
+				"creatorVoteCounts":    *creatorVoteCounts,
+				"resetOnSourcePush":    *resetOnPush,
+			},
+		})
+	}
+	for _, name := range builds {
+		p, err := resolvePipeline(ctx, c, name)
+		if err != nil {
+			return err
+		}
+		wanted = append(wanted, wantedPolicy{
+			name:   "build validation with " + p.Name,
+			typeID: client.PolicyBuild,
+			settings: map[string]any{
+				"buildDefinitionId":       p.ID,
+				"manualQueueOnly":         false,
+				"queueOnSourceUpdateOnly": true,
+				"validDuration":           buildExpires.Minutes(),
+			},
+			same: func(s policySettings) bool { return s.BuildDefinitionID == p.ID },
+		})
+	}
+	if *commentResolution {
+		wanted = append(wanted, wantedPolicy{name: "comment resolution", typeID: client.PolicyCommentResolution, settings: map[string]any{}})
+	}
+
+	existing, err := c.ListPolicyConfigurations(ctx, repo.ID, ref)
+	if err != nil {
+		return fmt.Errorf("failed to list the policies of %s %s: %w", repo.Name, watch.ShortBranch(ref), err)
+	}
+	where := repo.Name + " " + watch.ShortBranch(ref)
+	policies := "1 branch policy"
+	if len(wanted) > 1 {
+		policies = fmt.Sprintf("%d branch policies", len(wanted))
+	}
+	change := plannedChange{verb: i18n.T("set"), target: policies + " on " + where}
+	for _, w := range wanted {
+		enforced := "blocking"
+		if *optional {
+			enforced = "optional"
+		}
+		change.affects = append(change.affects, fmt.Sprintf("%s (%s)", w.name, enforced))
+	}
+	if ok, err := a.confirmChange(flags, change); !ok {
+		return err
+	}
+	for _, w := range wanted {
+		policy, created, err := setPolicy(ctx, c, existing, w, repo.ID, ref, !*optional)
+		if err != nil {
+			return fmt.Errorf("failed to set %s on %s: %w", w.name, where, err)
+		}
+		if created {
+			a.infof("Created policy %d on %s: %s\n", policy.ID, where, w.name)
+		} else {
+			a.infof("Updated policy %d on %s: %s\n", policy.ID, where, w.name)
+		}
+		a.resultf("%d\n", policy.ID)
+	}
+	return nil
+}
+
+// setPolicy updates the policy of the wanted kind that applies to exactly
+// this branch, keeping the settings it does not change, or creates one.
+func setPolicy(ctx context.Context, c *client.Client, existing []client.PolicyConfiguration, w wantedPolicy, repositoryID, ref string, blocking bool) (policy *client.PolicyConfiguration, created bool, err error) {
+	for _, p := range existing {
+		scopes := p.Scopes()
+		if p.Type.ID != w.typeID || len(scopes) != 1 || scopes[0].RepositoryID == nil ||
+			!strings.EqualFold(*scopes[0].RepositoryID, repositoryID) || scopes[0].RefName != ref || strings.EqualFold(scopes[0].MatchKind, "prefix") {
+			continue
+		}
+		if w.same != nil {
+			var s policySettings
+			json.Unmarshal(p.Settings, &s)
+			if !w.same(s) {
+				continue
+			}
+		}
+		settings := map[string]any{}
+		if err := json.Unmarshal(p.Settings, &settings); err != nil {
+			return nil, false, err
+		}
+		for key, value := range w.settings {
+			settings[key] = value
+		}
+		data, err := json.Marshal(settings)
+		if err != nil {
+			return nil, false, err
+		}
+		p.Settings, p.IsEnabled, p.IsBlocking = data, true, blocking
+		policy, err = c.UpdatePolicyConfiguration(ctx, p)
+		return policy, false, err
+	}
+
+	settings := map[string]any{"scope": []client.PolicyScope{{RepositoryID: &repositoryID, RefName: ref, MatchKind: "exact"}}}
+	for key, value := range w.settings {
+		settings[key] = value
+	}
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return nil, false, err
+	}
+	policy, err = c.CreatePolicyConfiguration(ctx, client.PolicyConfiguration{
+		IsEnabled:  true,
+		IsBlocking: blocking,
+		Type:       client.PolicyType{ID: w.typeID},
+		Settings:   data,
+	})
+	return policy, true, err
+}
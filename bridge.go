@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"fomo/internal/client"
+)
+
+// githubTokenEnvs are where fomo bridge looks for a GitHub token, in order.
+var githubTokenEnvs = []string{"FOMO_GITHUB_TOKEN", "GITHUB_TOKEN", "GH_TOKEN"}
+
+// githubStatus is a commit status as the GitHub API takes it.
+type githubStatus struct {
+	State       string `json:"state"` // pending, success, failure or error
+	TargetURL   string `json:"target_url,omitempty"`
+	Description string `json:"description,omitempty"`
+	Context     string `json:"context"`
+}
+
+// githubState maps the state of a run to a commit status state.
+func githubState(b *client.Build) string {
+	switch {
+	case b.Status != client.RunStateCompleted:
+		return "pending"
+	case b.Result == client.RunResultSucceeded:
+		return "success"
+	case b.Result == client.RunResultCanceled:
+		return "error"
+	}
+	return "failure"
+}
+
+func runBridgeGHStatus(a *app, args []string) error {
+	fs := a.newFlagSet("bridge gh-status", "--run ID [--repo OWNER/NAME] [--context NAME]")
+	runFlag := fs.String("run", "", "run whose result to report")
+	repo := fs.String("repo", "", "GitHub repository, as owner/name (default: github.repo in the config)")
+	statusContext := fs.String("context", "", "status context (default: github.context in the config, or azure-pipelines/<pipeline>)")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 || *runFlag == "" {
+		return newUsageError("usage: fomo bridge gh-status --run ID [--repo OWNER/NAME] [--context NAME]")
+	}
+	runID, err := parseRunID(*runFlag)
+	if err != nil {
+		return err
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	settings := a.cfg.GitHub
+	*repo = cmp.Or(*repo, settings.Repo)
+	if *repo != "" && !githubRepoName(*repo) {
+		return newUsageError("give the GitHub repository as --repo OWNER/NAME, or set github.repo in the config")
+	}
+	var token string
+	for _, env := range githubTokenEnvs {
+		if token = os.Getenv(env); token != "" {
+			break
+		}
+	}
+	if token == "" {
+		return fmt.Errorf("%w: a GitHub token is required in %s", errMissingInput, strings.Join(githubTokenEnvs, ", "))
+	}
+	a.redactor.Add(token)
+
+	ctx := a.ctx
+	b, err := c.GetBuild(ctx, runID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch run %d: %w", runID, err)
+	}
+	// A run of a GitHub repository reports to it without configuration
+	if *repo == "" && b.Repository.Host() == client.HostGitHub && githubRepoName(b.Repository.ID) {
+		*repo = b.Repository.ID
+	}
+	if *repo == "" {
+		return newUsageError("give the GitHub repository as --repo OWNER/NAME, or set github.repo in the config")
+	}
+	// Pull request runs build a merge commit; GitHub shows the statuses of
+	// the head of the pull request
+	sha := b.HeadCommit()
+	if sha == "" {
+		return fmt.Errorf("run %d has no commit to report on", runID)
+	}
+
+	description := fmt.Sprintf("Run %s %s", b.BuildNumber, buildStatus(*b))
+	if b.StartTime != nil && b.FinishTime != nil {
+		description += " in " + a.display.duration(b.FinishTime.Sub(*b.StartTime))
+	}
+	status := githubStatus{
+		State:       githubState(b),
+		TargetURL:   c.WebURL(fmt.Sprintf("_build/results?buildId=%d", b.ID)),
+		Description: description,
+		Context:     cmp.Or(*statusContext, settings.Context, "azure-pipelines/"+b.Definition.Name),
+	}
+	apiURL := cmp.Or(settings.APIURL, "https://api.github.com")
+	if err := postGitHubStatus(ctx, apiURL, token, *repo, sha, status); err != nil {
+		return fmt.Errorf("failed to report run %d to %s: %w", runID, *repo, err)
+	}
+	a.infof("Reported %s as %s on %s@%.7s\n", status.Context, status.State, *repo, sha)
+	a.resultf("%s\n", status.State)
+	return nil
+}
+
+// githubRepoName reports whether repo is a GitHub repository name, as
+// owner/name.
+func githubRepoName(repo string) bool {
+	owner, name, ok := strings.Cut(repo, "/")
+	return ok && owner != "" && name != "" && !strings.Contains(name, "/")
+}
+
+// postGitHubStatus sets a commit status on a GitHub repository.
+func postGitHubStatus(ctx context.Context, apiURL, token, repo, sha string, status githubStatus) error {
+	body, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s/repos/%s/statuses/%s", strings.TrimRight(apiURL, "/"), repo, sha)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var answer struct {
+			Message string `json:"message"`
+		}
+		data, _ := io.ReadAll(resp.Body)
+		json.Unmarshal(data, &answer)
+		return fmt.Errorf("GitHub answered %s: %s", resp.Status, cmp.Or(answer.Message, strings.TrimSpace(string(data))))
+	}
+	return nil
+}
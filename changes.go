@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"fomo/internal/client"
+)
+
+// pathGlob matches repository paths against a glob such as
+// "src/service-a/**" or "docs/*.md": * and ? stay within a directory, **
+// crosses directories. A pattern without wildcards matches the path itself
+// and everything under it.
+type pathGlob struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+func newPathGlob(pattern string) (*pathGlob, error) {
+	trimmed := strings.Trim(pattern, "/")
+	if trimmed == "" {
+		return nil, fmt.Errorf("empty path pattern %q", pattern)
+	}
+	// "dir/**" also matches the directory itself
+	suffix := "$"
+	if !strings.ContainsAny(trimmed, "*?") || strings.HasSuffix(trimmed, "/**") {
+		trimmed, suffix = strings.TrimSuffix(trimmed, "/**"), "(?:/.*)?$"
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(trimmed); i++ {
+		switch ch := trimmed[i]; {
+		case strings.HasPrefix(trimmed[i:], "**/"):
+			// any number of directories, including none
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(trimmed[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case ch == '*':
+			b.WriteString("[^/]*")
+		case ch == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	b.WriteString(suffix)
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid path pattern %q: %w", pattern, err)
+	}
+	return &pathGlob{pattern: pattern, re: re}, nil
+}
+
+// match reports whether a path relative to the repository root matches.
+func (g *pathGlob) match(p string) bool {
+	return g.re.MatchString(p)
+}
+
+// parsePathGlobs compiles patterns given on the command line.
+func parsePathGlobs(patterns []string) ([]*pathGlob, error) {
+	globs := make([]*pathGlob, 0, len(patterns))
+	for _, pattern := range patterns {
+		g, err := newPathGlob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		globs = append(globs, g)
+	}
+	return globs, nil
+}
+
+// changedFiles lists the files changed between two commits of a
+// repository, relative to its root, ignoring folders. complete is false
+// when Azure DevOps did not list every change.
+func changedFiles(ctx context.Context, c *client.Client, repositoryID, base, target string) (files []string, complete bool, err error) {
+	diffs, err := c.DiffCommits(ctx, repositoryID, base, target)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to compare %s with %s: %w", shortCommit(base), shortCommit(target), err)
+	}
+	for _, change := range diffs.Changes {
+		if change.Item.IsFolder {
+			continue
+		}
+		files = append(files, strings.TrimPrefix(change.Item.Path, "/"))
+		// A file moved out of a directory changes it too
+		if change.OriginalPath != "" && change.OriginalPath != change.Item.Path {
+			files = append(files, strings.TrimPrefix(change.OriginalPath, "/"))
+		}
+	}
+	return files, diffs.AllChangesIncluded, nil
+}
+
+// matchingFiles returns the files that match any of the globs.
+func matchingFiles(files []string, globs []*pathGlob) []string {
+	var matched []string
+	for _, f := range files {
+		for _, g := range globs {
+			if g.match(f) {
+				matched = append(matched, f)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// shortCommit abbreviates a commit ID as Git does.
+func shortCommit(commit string) string {
+	return commit[:min(len(commit), 7)]
+}
+
+// describeFiles lists a few files, and how many more there are.
+func describeFiles(files []string) string {
+	const shown = 3
+	if len(files) <= shown {
+		return strings.Join(files, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(files[:shown], ", "), len(files)-shown)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"fomo/internal/client"
+	"fomo/internal/daemon"
+	"fomo/internal/watch"
+)
+
+// chatHelp lists the commands chat can issue.
+const chatHelp = "Commands: run PIPELINE [BRANCH], status [PIPELINE], help"
+
+// chatStatusLines bounds the runs a status reply lists.
+const chatStatusLines = 20
+
+// newChatHandler answers chat commands with the tenant the channel is
+// mapped to. Without a mapping, a daemon serving one profile answers every
+// channel.
+func newChatHandler(channels map[string]string, tenants []*daemonTenant, several bool) daemon.ChatHandler {
+	byProfile := map[string]*daemonTenant{}
+	for _, t := range tenants {
+		byProfile[t.a.cfg.Profile] = t
+	}
+	return func(ctx context.Context, command daemon.ChatCommand) string {
+		var t *daemonTenant
+		if profile, ok := channels[command.Channel]; ok {
+			t = byProfile[profile]
+		} else if len(channels) == 0 && !several {
+			t = tenants[0]
+		}
+		if t == nil {
+			return fmt.Sprintf("This channel (%s) is not mapped to a fomo profile.", command.Channel)
+		}
+		return t.chat(ctx, command)
+	}
+}
+
+// chat runs a chat command for the tenant and returns the reply.
+func (t *daemonTenant) chat(ctx context.Context, command daemon.ChatCommand) string {
+	fields := strings.Fields(command.Text)
+	if len(fields) == 0 {
+		return chatHelp
+	}
+	switch args := fields[1:]; strings.ToLower(fields[0]) {
+	case "run":
+		if len(args) < 1 || len(args) > 2 {
+			return "Usage: run PIPELINE [BRANCH]"
+		}
+		branch := ""
+		if len(args) == 2 {
+			branch = args[1]
+		}
+		via := fmt.Sprintf("%s:%s", command.Platform, command.User)
+		run, err := t.queue(ctx, args[0], branch, "fomo chatops: run", via)
+		if err != nil {
+			return fmt.Sprintf("Could not queue %s: %s", args[0], t.a.redactor.String(err.Error()))
+		}
+		reply := fmt.Sprintf("Queued %s %s for %s", run.Pipeline.Name, run.Name, command.User)
+		if run.Links.Web != nil {
+			reply += ": " + run.Links.Web.Href
+		}
+		return reply
+	case "status":
+		if len(args) > 1 {
+			return "Usage: status [PIPELINE]"
+		}
+		return t.chatStatus(args)
+	case "help":
+		return chatHelp
+	}
+	return fmt.Sprintf("Unknown command %q. %s", fields[0], chatHelp)
+}
+
+// chatStatus lists the latest run of the watched pipelines, or of one.
+func (t *daemonTenant) chatStatus(args []string) string {
+	var lines []string
+	for _, s := range t.watcher.State() {
+		if len(args) == 1 && !strings.EqualFold(s.Pipeline.Name, args[0]) {
+			continue
+		}
+		state := s.Run.Result
+		if s.Run.State != client.RunStateCompleted {
+			state = s.Run.State
+		}
+		lines = append(lines, fmt.Sprintf("%s %s: %s (%s)", s.Pipeline.Name, watch.BranchLabel(s.Branch), state, s.Run.Name))
+	}
+	switch {
+	case len(lines) == 0 && len(args) == 1:
+		return fmt.Sprintf("%s is not watched, or has not run yet.", args[0])
+	case len(lines) == 0:
+		return "No watched pipeline has run yet."
+	case len(lines) > chatStatusLines:
+		lines = append(lines[:chatStatusLines], fmt.Sprintf("... and %d more", len(lines)-chatStatusLines))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"fomo/internal/client"
+	"fomo/internal/watch"
+)
+
+// codeSearchPage is how many results are asked for at a time.
+const codeSearchPage = 200
+
+// codeLine is a line of a file with matches of a code search.
+type codeLine struct {
+	number int
+	column int // of the first match, in bytes from 1
+	text   string
+	spans  [][2]int // byte ranges of the matches in text
+}
+
+// matchLines finds the lines matches fall on. Code search counts offsets
+// in UTF-16 code units, like .NET strings.
+func matchLines(content string, matches []client.CodeMatch) []codeLine {
+	sort.Slice(matches, func(i, j int) bool { return matches[i].CharOffset < matches[j].CharOffset })
+
+	// byteOffset converts a UTF-16 offset to a byte offset, walking
+	// forward from the previous one
+	pos, units := 0, 0
+	byteOffset := func(target int) int {
+		for pos < len(content) && units < target {
+			r, size := utf8.DecodeRuneInString(content[pos:])
+			units += utf16.RuneLen(r)
+			pos += size
+		}
+		return pos
+	}
+
+	var lines []codeLine
+	line, lineStart := 1, 0
+	for _, m := range matches {
+		start := byteOffset(m.CharOffset)
+		end := byteOffset(m.CharOffset + m.Length)
+		for i := strings.IndexByte(content[lineStart:start], '\n'); i >= 0; i = strings.IndexByte(content[lineStart:start], '\n') {
+			lineStart += i + 1
+			line++
+		}
+		lineEnd := len(content)
+		if i := strings.IndexByte(content[lineStart:], '\n'); i >= 0 {
+			lineEnd = lineStart + i
+		}
+		span := [2]int{start - lineStart, min(end, lineEnd) - lineStart}
+		if n := len(lines); n > 0 && lines[n-1].number == line {
+			lines[n-1].spans = append(lines[n-1].spans, span)
+			continue
+		}
+		text := strings.TrimSuffix(content[lineStart:lineEnd], "\r")
+		span[1] = min(span[1], len(text))
+		lines = append(lines, codeLine{number: line, column: start - lineStart + 1, text: text, spans: [][2]int{span}})
+	}
+	return lines
+}
+
+// highlight marks the matches in a line like grep --color does.
+func highlight(l codeLine) string {
+	var b strings.Builder
+	last := 0
+	for _, span := range l.spans {
+		if span[0] < last || span[0] > len(l.text) {
+			continue
+		}
+		end := min(span[1], len(l.text))
+		b.WriteString(l.text[last:span[0]])
+		b.WriteString("\033[1;31m" + l.text[span[0]:end] + "\033[0m")
+		last = end
+	}
+	b.WriteString(l.text[last:])
+	return b.String()
+}
+
+func runCodeSearch(a *app, args []string) error {
+	fs := a.newFlagSet("code search", "<query> [--repo NAME]... [--branch NAME] [--path PATH] [--limit N]")
+	var repos tagFlags
+	fs.Var(&repos, "repo", "only search this repository (repeatable)")
+	branch := fs.String("branch", "", "search this branch instead of the default one (requires --repo)")
+	dir := fs.String("path", "", "only search under this path")
+	limit := fs.Int("limit", 100, "show at most this many files")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	query := strings.Join(positional, " ")
+	if strings.TrimSpace(query) == "" {
+		return newUsageError("usage: fomo code search <query> [--repo NAME]... [--branch NAME] [--path PATH] [--limit N]")
+	}
+	if *limit <= 0 {
+		return newUsageError("--limit must be positive")
+	}
+	if *branch != "" && len(repos) == 0 {
+		return newUsageError("--branch requires --repo")
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	request := client.CodeSearchRequest{SearchText: query, Filters: map[string][]string{"Project": {c.Project}}}
+	if len(repos) > 0 {
+		request.Filters["Repository"] = repos
+	}
+	if *branch != "" {
+		// Code search knows branches by their short name
+		request.Filters["Branch"] = []string{watch.ShortBranch(*branch)}
+	}
+	if *dir != "" {
+		request.Filters["Path"] = []string{"/" + strings.Trim(*dir, "/")}
+	}
+
+	var results []client.CodeSearchResult
+	total := 0
+	for len(results) < *limit {
+		request.Skip, request.Top = len(results), min(codeSearchPage, *limit-len(results))
+		page, err := c.SearchCode(ctx, request)
+		var apiErr *client.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("code search failed; is the Code Search extension installed in %s? %w", c.Organization, err)
+		}
+		if err != nil {
+			return fmt.Errorf("code search failed: %w", err)
+		}
+		total = page.Count
+		results = append(results, page.Results...)
+		if len(page.Results) == 0 || len(results) >= page.Count {
+			break
+		}
+	}
+	if len(results) == 0 {
+		a.infof("No code matches %q.\n", query)
+		return nil
+	}
+
+	lines := fetchMatchLines(ctx, a, c, results)
+	// Paths are relative to the repository root, which editors resolve
+	// from a clone; several repositories are told apart by a prefix
+	prefix := len(repos) != 1
+	color := isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == "" && !a.plain
+	for i, r := range results {
+		name := strings.TrimPrefix(r.Path, "/")
+		if prefix {
+			name = path.Join(r.Repository.Name, name)
+		}
+		switch {
+		case len(r.Matches.Content) == 0:
+			a.resultf("%s:1:1: file name matches\n", name)
+			continue
+		case lines[i] == nil:
+			a.resultf("%s:1:1: %d match(es)\n", name, len(r.Matches.Content))
+			continue
+		}
+		for _, l := range lines[i] {
+			text := l.text
+			if color {
+				text = highlight(l)
+			}
+			a.resultf("%s:%d:%d: %s\n", name, l.number, l.column, text)
+		}
+	}
+	if total > len(results) {
+		a.infof("Showing %d of %d matching files; raise --limit for more.\n", len(results), total)
+	}
+	return nil
+}
+
+// fetchMatchLines downloads the files of results, at most 8 at a time, to
+// find the lines the matches are on. Files that cannot be fetched have nil
+// lines.
+func fetchMatchLines(ctx context.Context, a *app, c *client.Client, results []client.CodeSearchResult) [][]codeLine {
+	lines := make([][]codeLine, len(results))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 8)
+	for i, r := range results {
+		if len(r.Matches.Content) == 0 {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			var content string
+			var err error
+			switch {
+			case len(r.Versions) > 0 && r.Versions[0].ChangeID != "":
+				content, err = c.GetFileAtCommit(ctx, r.Repository.ID, r.Path, r.Versions[0].ChangeID)
+			case len(r.Versions) > 0:
+				content, err = c.GetFileContent(ctx, r.Repository.ID, r.Path, r.Versions[0].BranchName)
+			default:
+				content, err = c.GetFileContent(ctx, r.Repository.ID, r.Path, "")
+			}
+			if err != nil {
+				a.warnf("Warning: failed to fetch %s in %s: %v\n", r.Path, r.Repository.Name, err)
+				return
+			}
+			lines[i] = matchLines(content, r.Matches.Content)
+		}()
+	}
+	wg.Wait()
+	return lines
+}
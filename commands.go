@@ -0,0 +1,397 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// command is a node in the fomo command tree. Groups have subcommands,
+// leaves have a run function. A leaf with subcommands runs itself unless
+// its first argument names one of them.
+type command struct {
+	name        string
+	usage       string
+	summary     string
+	run         func(a *app, args []string) error
+	subcommands []*command
+
+	// writes marks commands that always change something, refused in
+	// safe mode; see checkWrite
+	writes bool
+}
+
+// commands returns the top-level command tree.
+func commands() []*command {
+	return []*command{
+		{
+			name:    "pipelines",
+			summary: "Work with pipeline definitions",
+			subcommands: []*command{
+				{name: "list", summary: "List pipelines in the project", run: runPipelinesList},
+				{name: "create", summary: "Create a YAML pipeline, optionally scaffolding its YAML", run: runPipelinesCreate, writes: true},
+				{name: "delete", summary: "Delete a pipeline after showing its recent activity", run: runPipelinesDelete, writes: true},
+				{name: "rename", summary: "Rename a pipeline after showing its recent activity", run: runPipelinesRename, writes: true},
+				{name: "yaml-diff", summary: "Diff a pipeline's expanded YAML between two branches", run: runPipelinesYAMLDiff},
+				{name: "stale", summary: "Find pipelines that have not run for a while, for cleanup", run: runPipelinesStale},
+				{
+					name:    "tag",
+					summary: "Label pipelines, e.g. by team or service",
+					subcommands: []*command{
+						{name: "add", summary: "Add tags to a pipeline", run: runPipelinesTagAdd, writes: true},
+						{name: "remove", summary: "Remove tags from a pipeline", run: runPipelinesTagRemove, writes: true},
+						{name: "list", summary: "List the tags of a pipeline, or of every tagged pipeline", run: runPipelinesTagList},
+					},
+				},
+				{name: "export", summary: "Export pipeline definitions to files", run: runPipelinesExport},
+				{name: "import", summary: "Recreate exported pipeline definitions in this project", run: runPipelinesImport, writes: true},
+			},
+		},
+		{name: "run", summary: "Queue a run of a pipeline, optionally following it to the end", run: runRun, writes: true},
+		{name: "run-matrix", summary: "Queue the pipelines of the monorepo directories a commit range changed", run: runRunMatrix, writes: true},
+		{name: "orchestrate", summary: "Run pipelines in dependency order, passing outputs on as parameters", run: runOrchestrate, writes: true},
+		{
+			name:    "runs",
+			summary: "Work with pipeline runs",
+			subcommands: []*command{
+				{name: "list", summary: "List recent runs, by pipeline, branch or tag", run: runRunsList},
+				{name: "tag", summary: "List, add or remove the tags of a run", run: runRunsTag},
+				{name: "retry", summary: "Retry the failed jobs of a run or of one stage", run: runRunsRetry, writes: true},
+				{name: "cancel", summary: "Cancel queued or running runs, by ID or pipeline", run: runRunsCancel, writes: true},
+				{name: "retain", summary: "Keep a run from retention cleanup with a lease", run: runRunsRetain, writes: true},
+				{
+					name:    "leases",
+					summary: "List and remove the retention leases of runs",
+					subcommands: []*command{
+						{name: "list", summary: "List the retention leases of a run or an owner", run: runRunsLeasesList},
+						{name: "remove", summary: "Remove retention leases", run: runRunsLeasesRemove, writes: true},
+					},
+				},
+				{name: "gantt", summary: "Chart the stages and jobs of a run and its critical path", run: runRunsGantt},
+				{name: "outputs", summary: "Print the output variables a run's jobs set", run: runRunsOutputs},
+				{name: "changes", summary: "List the commits a run brought in, with the Jira issues they mention", run: runRunsChanges},
+				{name: "latest", summary: "Print the latest run of a pipeline, formatted for scripts", run: runRunsLatest},
+				{name: "repro", summary: "Print the fomo run command or script that queues a run again as it was", run: runRunsRepro},
+			},
+		},
+		{
+			name:    "policy",
+			summary: "Enforce the settings an organization requires of its pipelines",
+			subcommands: []*command{
+				{name: "apply", summary: "Report pipelines that break a policy file, and fix what can be fixed", run: runPolicyApply},
+			},
+		},
+		{
+			name:    "policies",
+			summary: "Manage the branch policies that protect a branch",
+			subcommands: []*command{
+				{name: "list", summary: "List the branch policies of a branch", run: runPoliciesList},
+				{name: "set", summary: "Require reviewers, validation builds or resolved comments on a branch", run: runPoliciesSet, writes: true},
+			},
+		},
+		{
+			name:    "audit",
+			summary: "Audit pipelines for problems before they break",
+			subcommands: []*command{
+				{name: "images", summary: "Find pipelines on retired Microsoft-hosted images", run: runAuditImages},
+				{name: "secrets", summary: "Find credentials stored in plaintext in pipelines and variable groups", run: runAuditSecrets},
+			},
+		},
+		{
+			name:    "vargroups",
+			summary: "Work with variable groups",
+			subcommands: []*command{
+				{name: "list", summary: "List the variable groups of the project", run: runVargroupsList},
+				{name: "show", summary: "Show a variable group, or the vault and secrets of a Key Vault-linked one", run: runVargroupsShow},
+			},
+		},
+		{
+			name:    "tasks",
+			summary: "Work with the tasks pipelines use",
+			subcommands: []*command{
+				{name: "list", summary: "Inventory the tasks pipelines use and flag deprecated ones", run: runTasksList},
+			},
+		},
+		{
+			name:    "templates",
+			summary: "Work with the YAML templates pipelines share",
+			subcommands: []*command{
+				{name: "usage", summary: "Find the pipelines using a templates repository, and the versions they pin", run: runTemplatesUsage},
+			},
+		},
+		{
+			name:    "logs",
+			summary: "Work with the logs of runs",
+			subcommands: []*command{
+				{name: "diff", summary: "Diff the log of a step between two runs", run: runLogsDiff},
+				{name: "download", summary: "Download all the logs of a run as a zip, resuming an interrupted download", run: runLogsDownload},
+			},
+		},
+		{
+			name:    "code",
+			summary: "Work with the code in the project's repositories",
+			subcommands: []*command{
+				{name: "search", summary: "Search code with the Code Search extension, in quickfix format", run: runCodeSearch},
+			},
+		},
+		{
+			name:    "artifacts",
+			summary: "Work with the artifacts of runs",
+			subcommands: []*command{
+				{name: "upload", summary: "Publish files to an existing run as an artifact", run: runArtifactsUpload, writes: true},
+				{name: "download", summary: "Download an artifact of a run as a zip, resuming an interrupted download", run: runArtifactsDownload},
+			},
+		},
+		{
+			name:    "environments",
+			summary: "Work with environments",
+			subcommands: []*command{
+				{
+					name:    "checks",
+					summary: "List and add the checks that guard deployments to an environment",
+					subcommands: []*command{
+						{name: "list", summary: "List the approvals, business hours and locks of an environment", run: runEnvironmentsChecksList},
+						{name: "add", summary: "Add an approval, business hours or exclusive lock check", run: runEnvironmentsChecksAdd, writes: true},
+					},
+				},
+				{name: "resources", summary: "Show the health and last deployment of an environment's Kubernetes and VM resources", run: runEnvironmentsResources},
+			},
+		},
+		{
+			name:    "deployments",
+			summary: "Work with deployments to environments",
+			subcommands: []*command{
+				{name: "list", summary: "List the deployments to an environment, or what is live there now", run: runDeploymentsList},
+				{name: "redeploy", summary: "Re-run the stage of a run that deployed to an environment", run: runDeploymentsRedeploy, writes: true},
+			},
+		},
+		{
+			name:    "stats",
+			summary: "Analyze pipeline run history",
+			subcommands: []*command{
+				{name: "critical-path", summary: "Find the jobs that decide how long a pipeline takes", run: runStatsCriticalPath},
+				{name: "stages", summary: "Chart stage durations over time and flag regressions", run: runStatsStages},
+			},
+		},
+		{
+			name:    "pr",
+			summary: "Work with pull requests",
+			subcommands: []*command{
+				{name: "checks", summary: "Show which branch policies block a pull request from merging", run: runPRChecks},
+				{name: "complete", summary: "Merge a pull request now, or once its policies pass", run: runPRComplete, writes: true},
+				{name: "diff", summary: "Show the changes of a pull request as a unified diff", run: runPRDiff},
+				{name: "comment", summary: "Comment on a pull request, or on a line of one of its files", run: runPRComment, writes: true},
+				{name: "approve", summary: "Approve a pull request", run: runPRApprove, writes: true},
+				{name: "reject", summary: "Reject a pull request", run: runPRReject, writes: true},
+				{name: "reviewers", summary: "List the reviewers of a pull request, or add some by name", run: runPRReviewers},
+			},
+		},
+		{
+			name:    "security",
+			summary: "Inspect who may do what",
+			subcommands: []*command{
+				{name: "who-can", summary: "List the groups and users who can queue or edit a pipeline", run: runSecurityWhoCan},
+			},
+		},
+		{
+			name:    "bridge",
+			summary: "Report Azure Pipelines results to other services",
+			subcommands: []*command{
+				{name: "gh-status", summary: "Post the result of a run as a commit status on its GitHub mirror", run: runBridgeGHStatus, writes: true},
+			},
+		},
+		{
+			name:    "schedules",
+			summary: "Export pipeline schedules, release windows and deployments",
+			subcommands: []*command{
+				{name: "export", summary: "Write scheduled runs, release windows and recent deployments as an iCalendar file", run: runSchedulesExport},
+			},
+		},
+		{name: "annotate", summary: "Note an external event, such as an incident, on a run", run: runAnnotate, writes: true},
+		{name: "search", summary: "Find pipelines, branches and recent runs by name, number or commit message", run: runSearch},
+		{name: "trace", summary: "Export the timeline of a run as an OpenTelemetry trace", run: runTrace},
+		{name: "impact", summary: "Find the pipelines a push to a repository or path would run", run: runImpact},
+		{name: "graph", summary: "Graph the triggers, repositories and environments linking pipelines", run: runGraph},
+		{name: "archive", summary: "Save the logs, test results and metadata of runs before retention deletes them", run: runArchive},
+		{name: "release-notes", summary: "Write Markdown release notes from the commits, work items and pull requests between two runs", run: runReleaseNotes},
+		{name: "migrate", summary: "Copy pipelines, variable groups and environments to another organization", run: runMigrate, writes: true},
+		{name: "me", summary: "Your pull requests, runs and pending approvals across every project", run: runMe},
+		{name: "handoff", summary: "Summarize failures, red pipelines, pending approvals and slow runs of an on-call shift", run: runHandoff},
+		{name: "inbox", summary: "Approvals and reviews waiting on you, actionable from the keyboard", run: runInbox},
+		{name: "board", summary: "Show pipelines and environments as a full-screen board for wall displays", run: runBoard},
+		{name: "statusline", summary: "Print a compact status line for tmux, starship or i3", run: runStatusline},
+		{name: "daemon", summary: "Watch pipelines in the background and serve a local API", run: runDaemon},
+		{name: "doctor", summary: "Check the connection, PAT scopes, rate limit, agent pools and service connections", run: runDoctor},
+		{
+			name:    "version",
+			summary: "Print the version of fomo, or with next the next release version of a pipeline",
+			run:     runVersion,
+			subcommands: []*command{
+				{name: "next", summary: "Print the next release version of a pipeline", run: runVersionNext},
+			},
+		},
+		{name: "update", summary: "Update fomo to the latest release", run: runUpdate},
+		{name: "mock-server", summary: "Serve an in-memory mock of the Azure DevOps API", run: runMockServer},
+		{
+			name:    "db",
+			summary: "Inspect and compact the local run history",
+			subcommands: []*command{
+				{name: "info", summary: "Show what the local run history holds", run: runDBInfo},
+				{name: "compact", summary: "Roll old runs up into daily summaries", run: runDBCompact},
+				{name: "export", summary: "Export the local run history as Parquet or CSV", run: runDBExport},
+			},
+		},
+		{
+			name:    "auth",
+			summary: "Manage stored credentials",
+			subcommands: []*command{
+				{name: "migrate", summary: "Move a PAT exported from a shell rc file into encrypted storage", run: runAuthMigrate},
+				{name: "jira", summary: "Store a Jira API token, encrypted with a passphrase", run: runAuthJira},
+			},
+		},
+		{
+			name:    "plugins",
+			summary: "Discover installed plugins",
+			subcommands: []*command{
+				{name: "list", summary: "List command plugins and notifier types", run: runPluginsList},
+			},
+		},
+		{
+			name:    "notify",
+			summary: "Work with notifiers",
+			subcommands: []*command{
+				{name: "test", summary: "Send a test notification through a configured notifier", run: runNotifyTest},
+			},
+		},
+		{
+			name:    "telemetry",
+			summary: "Control anonymous usage statistics",
+			subcommands: []*command{
+				{name: "status", summary: "Show whether telemetry is on and what has been gathered", run: runTelemetryStatus},
+				{name: "on", summary: "Send anonymous usage statistics", run: runTelemetryOn},
+				{name: "off", summary: "Stop sending usage statistics and delete those gathered", run: runTelemetryOff},
+			},
+		},
+		{
+			name:    "config",
+			summary: "Inspect fomo's configuration",
+			subcommands: []*command{
+				{name: "show", summary: "Show resolved settings and where they came from", run: runConfigShow},
+				{name: "sync", summary: "Sync the team's watch rules and notifiers from a git repository", run: runConfigSync},
+			},
+		},
+	}
+}
+
+// dispatch finds the command named by args and runs it.
+func dispatch(a *app, cmds []*command, prefix string, args []string) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printCommands(a.stderr, prefix, cmds)
+		return nil
+	}
+
+	for _, cmd := range cmds {
+		if cmd.name != args[0] {
+			continue
+		}
+		if cmd.run != nil && (len(args) < 2 || findCommand(cmd.subcommands, args[1]) == nil) {
+			path := strings.Join(strings.Fields("fomo "+prefix+" "+cmd.name), " ")
+			// Checked by newClient once the command has parsed its flags,
+			// which may pick another organization or project
+			if cmd.writes {
+				a.pendingWrite = path
+			}
+			err := cmd.run(a, args[1:])
+			// Changes go to the audit trail, unless previewed or declined;
+			// see checkWrite for commands that only sometimes make any
+			var usageErr *usageError
+			if (cmd.writes || a.writing) && !a.dryRun && !errors.Is(err, errAborted) && !errors.As(err, &usageErr) {
+				a.audit(path, args[1:], err)
+			}
+			return err
+		}
+		return dispatch(a, cmd.subcommands, strings.TrimSpace(prefix+" "+cmd.name), args[1:])
+	}
+
+	// Unknown top-level commands may be provided by a plugin
+	if prefix == "" {
+		if path, err := exec.LookPath(pluginPrefix + args[0]); err == nil {
+			return runPlugin(a, path, args[0], args[1:])
+		}
+	}
+	return newUsageError(fmt.Sprintf("unknown command %q, run '%s' for usage", args[0], strings.Join(strings.Fields("fomo "+prefix+" help"), " ")))
+}
+
+// findCommand returns the command of cmds with the given name, or nil.
+func findCommand(cmds []*command, name string) *command {
+	for _, cmd := range cmds {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+func printCommands(w io.Writer, prefix string, cmds []*command) {
+	fmt.Fprintf(w, "Usage: fomo %s <command> [flags]\n\nCommands:\n", strings.TrimSpace(prefix+" [global flags]"))
+	for _, cmd := range cmds {
+		fmt.Fprintf(w, "  %-14s %s\n", cmd.name, cmd.summary)
+	}
+
+	if prefix == "" {
+		if plugins := findPlugins(); len(plugins) > 0 {
+			fmt.Fprintf(w, "\nPlugins:\n")
+			for _, p := range plugins {
+				fmt.Fprintf(w, "  %-14s %s\n", p.name, p.path)
+			}
+		}
+	}
+}
+
+// newFlagSet returns a flag set for a leaf command that reports errors
+// instead of exiting, so they map onto the usage exit code. Every command
+// inherits the global --org and --project and may override them.
+func (a *app) newFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.StringVar(&a.flags.Organization, "org", a.flags.Organization, "Azure DevOps organization")
+	fs.StringVar(&a.flags.Project, "project", a.flags.Project, "Azure DevOps project")
+	fs.Usage = func() {
+		fmt.Fprintf(a.stderr, "Usage: fomo %s %s\n", name, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseArgs parses flags that may be interleaved with positional arguments
+// and returns the positional arguments.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	// Everything after "--" is positional
+	var rest []string
+	for i, arg := range args {
+		if arg == "--" {
+			args, rest = args[:i], args[i+1:]
+			break
+		}
+	}
+
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, newUsageError(err.Error())
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	return append(positional, rest...), nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"fomo/internal/config"
+)
+
+// teamCheckout is the clone of the team config repository, next to the user
+// config file. It remembers which file of the repository to sync in the
+// fomo.path git setting, and which branch in fomo.ref.
+const teamCheckout = "team"
+
+func runConfigSync(a *app, args []string) error {
+	fs := a.newFlagSet("config sync", "[--repo URL] [--ref BRANCH] [--path FILE]")
+	repo := fs.String("repo", "", "git repository holding the team config; remembered for later syncs")
+	ref := fs.String("ref", "", "branch or tag to sync; the repository's default branch if not set")
+	path := fs.String("path", "", "team config file in the repository (default fomo.yaml)")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return newUsageError("usage: fomo config sync [--repo URL] [--ref BRANCH] [--path FILE]")
+	}
+
+	userConfig, err := config.UserConfigPath(os.Getenv)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(userConfig)
+	checkout := filepath.Join(dir, teamCheckout)
+
+	origin, _ := git("-C", checkout, "remote", "get-url", "origin")
+	switch {
+	case *repo != "" && *repo != origin:
+		// A new repository: start over from a fresh clone
+		if err := os.RemoveAll(checkout); err != nil {
+			return fmt.Errorf("failed to remove the previous team config: %w", err)
+		}
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+		cloneArgs := []string{"clone", "--quiet", "--depth", "1"}
+		if *ref != "" {
+			cloneArgs = append(cloneArgs, "--branch", *ref)
+		}
+		a.infof("Cloning %s...\n", *repo)
+		if _, err := git(append(cloneArgs, *repo, checkout)...); err != nil {
+			return fmt.Errorf("failed to clone the team config: %w", err)
+		}
+	case origin == "":
+		return fmt.Errorf("%w: no team config repository yet: pass --repo", errMissingInput)
+	default:
+		if *ref == "" {
+			*ref, _ = git("-C", checkout, "config", "fomo.ref")
+		}
+		fetch := cmp.Or(*ref, "HEAD")
+		if _, err := git("-C", checkout, "fetch", "--quiet", "--depth", "1", "origin", fetch); err != nil {
+			return fmt.Errorf("failed to fetch the team config: %w", err)
+		}
+		if _, err := git("-C", checkout, "reset", "--quiet", "--hard", "FETCH_HEAD"); err != nil {
+			return fmt.Errorf("failed to update the team config: %w", err)
+		}
+	}
+	if *path == "" {
+		*path, _ = git("-C", checkout, "config", "fomo.path")
+	}
+	*path = cmp.Or(*path, "fomo.yaml")
+	for key, value := range map[string]string{"fomo.ref": *ref, "fomo.path": *path} {
+		if _, err := git("-C", checkout, "config", key, value); err != nil {
+			return fmt.Errorf("failed to remember the team config settings: %w", err)
+		}
+	}
+
+	// Check the file before replacing the synced copy, so a broken commit
+	// to the team repository does not break everyone's fomo
+	source := filepath.Join(checkout, filepath.FromSlash(*path))
+	var team config.Layer
+	if err := config.ReadFile(source, &team); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: the team config repository has no %s", errMissingInput, *path)
+		}
+		return err
+	}
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return err
+	}
+	target := filepath.Join(dir, config.TeamFileName)
+	tmp := target + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save the team config: %w", err)
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to save the team config: %w", err)
+	}
+
+	commit, _ := git("-C", checkout, "rev-parse", "--short", "HEAD")
+	a.infof("Synced %s at %s: %d watch rule(s), %d notifier(s), %d pipeline(s)\n", *path, orDash(commit), len(team.Watch), len(team.Notifiers), len(team.Pipelines))
+	return nil
+}
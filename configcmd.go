@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"fomo/internal/config"
+	"fomo/internal/i18n"
+)
+
+func runConfigShow(a *app, args []string) error {
+	fs := a.newFlagSet("config show", "")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	// Never prompt here: show exactly what the layers provide
+	loader := &config.Loader{Flags: a.flags, Profile: a.profile, PAT: a.pat}
+	cfg, err := loader.Load()
+	if err != nil {
+		return err
+	}
+	a.warnConfig(cfg)
+
+	pat := ""
+	switch {
+	case cfg.PAT != "":
+		pat = "(set)"
+	case cfg.EncryptedPAT != "":
+		pat = "(encrypted)"
+		cfg.Sources["pat"] = config.SourceCredentials
+	}
+
+	a.resultf("profile: %s\n", cfg.Profile)
+	for _, setting := range []struct{ key, value string }{
+		{"org", cfg.Organization},
+		{"project", cfg.Project},
+		{"base_url", cfg.BaseURL},
+		{"pat", pat},
+	} {
+		source := cfg.Sources[setting.key]
+		if source == "" {
+			source = "unset"
+		}
+		a.resultf("%s: %s (%s)\n", setting.key, setting.value, source)
+	}
+	for _, pipeline := range cfg.Pipelines {
+		a.resultf("pipeline: %s\n", pipeline)
+	}
+	for _, rule := range cfg.Watch {
+		tags := ""
+		if len(rule.Tags) > 0 {
+			tags = " tags=" + strings.Join(rule.Tags, ",")
+		}
+		a.resultf("watch: %s%s branches=%s results=%s\n", rule.Pipeline, tags, listOrAny(rule.Branches), listOrAny(rule.Results))
+	}
+	if p := cfg.Polling; p != (config.PollingConfig{}) {
+		a.resultf("polling: interval=%s active=%s idle=%s idle_after=%s\n", p.Interval, p.Active, p.Idle, p.IdleAfter)
+	}
+	if h := cfg.History; h != (config.HistoryConfig{}) {
+		a.resultf("history: retention_days=%d rollup_days=%d\n", h.RetentionDays, h.RollupDays)
+	}
+	switch {
+	case cfg.SafeMode && cfg.AllowWrite:
+		a.resultf("safe_mode: on, writes allowed by the profile\n")
+	case cfg.SafeMode:
+		a.resultf("safe_mode: on\n")
+	}
+	a.resultf("language: %s\n", i18n.Lang())
+	durations := durationsHuman
+	if a.display.iso {
+		durations = durationsISO
+	}
+	a.resultf("display: timezone=%s durations=%s\n", a.display.in(time.Now()).Location(), durations)
+	if cfg.ProfileFile != "" {
+		a.infof("user config: %s\n", cfg.ProfileFile)
+	}
+	if cfg.RepoFile != "" {
+		a.infof("repo config: %s\n", cfg.RepoFile)
+	}
+	if cfg.TeamFile != "" {
+		a.infof("team config: %s\n", cfg.TeamFile)
+	}
+	return nil
+}
+
+func listOrAny(values []string) string {
+	if len(values) == 0 {
+		return "*"
+	}
+	return strings.Join(values, ",")
+}
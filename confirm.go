@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"fomo/internal/i18n"
+)
+
+// confirm asks a yes/no question, defaulting to no. The question is
+// already translated; the answer may be given in English or in the
+// selected language.
+func confirm(question string) bool {
+	return i18n.Yes(promptUser(question + i18n.T(" [y/N]: ")))
+}
+
+// confirmTyped requires the user to type expected exactly, for operations
+// that are hard to undo.
+func confirmTyped(a *app, what, expected string) bool {
+	fmt.Fprint(a.stderr, i18n.T("This cannot be undone. Type %q to confirm %s: ", expected, what))
+	return promptUser("") == expected
+}
+
+// changeFlags are the --yes and --dry-run flags of commands whose changes
+// are hard to undo, such as canceling, deleting or removing retention.
+type changeFlags struct {
+	yes    bool
+	dryRun bool
+}
+
+func (f *changeFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&f.yes, "yes", false, "do not ask for confirmation")
+	fs.BoolVar(&f.dryRun, "dry-run", false, "show what would change without changing anything")
+}
+
+// plannedChange is what a command is about to do, as confirmChange shows
+// it: "verb target?", after listing what it affects.
+type plannedChange struct {
+	verb    string   // translated, lower case, such as "cancel"
+	target  string   // such as "3 runs" or a pipeline name
+	affects []string // what the change touches, one line each
+	typed   string   // when set, must be typed to confirm instead of y
+}
+
+// confirmChange lists what a change affects and asks before it is made.
+// It returns false with no error for a dry run, which leaves the change
+// off the audit trail, and errAborted when the answer is no. Without a
+// terminal to ask on, --yes is required rather than reading an answer
+// from a pipe.
+func (a *app) confirmChange(f changeFlags, change plannedChange) (bool, error) {
+	if len(change.affects) > 0 {
+		a.infof(i18n.T("This will %s %s:\n", change.verb, change.target))
+		for _, line := range change.affects {
+			a.infof("  %s\n", line)
+		}
+	}
+	switch {
+	case f.dryRun:
+		a.dryRun = true
+		a.infof("Dry run: nothing was changed.\n")
+		return false, nil
+	case f.yes:
+		return true, nil
+	case !isTerminal(os.Stdin):
+		return false, fmt.Errorf("%w: pass --yes to %s %s without a terminal to confirm on", errMissingInput, change.verb, change.target)
+	case change.typed != "":
+		if !confirmTyped(a, change.verb, change.typed) {
+			return false, errAborted
+		}
+	case !confirm(i18n.T("%s %s?", change.verb, change.target)):
+		return false, errAborted
+	}
+	return true, nil
+}
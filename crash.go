@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// recentRequests is how many API requests a crash report lists.
+const recentRequests = 20
+
+// requestRecorder remembers the last API requests, without their headers
+// or bodies, so a crash report shows what fomo was doing.
+type requestRecorder struct {
+	mu      sync.Mutex
+	records []requestRecord // ring buffer
+	n       int             // requests seen
+}
+
+type requestRecord struct {
+	time     time.Time
+	method   string
+	url      string
+	status   int
+	duration time.Duration
+	err      string
+}
+
+func (r *requestRecorder) add(record requestRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.records) < recentRequests {
+		r.records = append(r.records, record)
+	} else {
+		r.records[r.n%recentRequests] = record
+	}
+	r.n++
+}
+
+// recordTransport adds the requests of a client to a requestRecorder.
+type recordTransport struct {
+	next     http.RoundTripper
+	recorder *requestRecorder
+}
+
+func (t *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	record := requestRecord{time: start, method: req.Method, url: req.URL.Redacted(), duration: time.Since(start)}
+	if err != nil {
+		record.err = err.Error()
+	} else {
+		record.status = resp.StatusCode
+	}
+	t.recorder.add(record)
+	return resp, err
+}
+
+// recent returns the recorded requests, oldest first.
+func (r *requestRecorder) recent() []requestRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.records) < recentRequests {
+		return append([]requestRecord(nil), r.records...)
+	}
+	i := r.n % recentRequests
+	return append(append([]requestRecord(nil), r.records[i:]...), r.records[:i]...)
+}
+
+// writeCrashReport writes a report of a panic to a temporary file and
+// returns its path. Everything goes through the redactor, so credentials
+// known to fomo are masked.
+func (a *app) writeCrashReport(value any, stack []byte, args []string) (string, error) {
+	f, err := os.CreateTemp("", "fomo-crash-*.txt")
+	if err != nil {
+		return "", err
+	}
+	w := a.redactor.Writer(f)
+
+	fmt.Fprintf(w, "fomo crash report\n\n")
+	fmt.Fprintf(w, "Time:    %s\n", time.Now().UTC().Format(time.RFC3339))
+	fmt.Fprintf(w, "Version: %s\n", buildVersion())
+	fmt.Fprintf(w, "Go:      %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(w, "Command: fomo %s\n", strings.Join(args, " "))
+	fmt.Fprintf(w, "\npanic: %v\n\n%s\n", value, stack)
+
+	if a.requests != nil {
+		fmt.Fprintf(w, "Recent API requests, oldest first:\n")
+		for _, r := range a.requests.recent() {
+			outcome := fmt.Sprint(r.status)
+			if r.err != "" {
+				outcome = "error: " + r.err
+			}
+			fmt.Fprintf(w, "  %s %s %s %s (%s)\n", r.time.UTC().Format(time.TimeOnly), r.method, r.url, outcome, r.duration.Round(time.Millisecond))
+		}
+	}
+
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// buildVersion describes the binary: its release version, or for other
+// builds the revision it was built from.
+func buildVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if version != "dev" || !ok {
+		return version
+	}
+	version := version
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			version += " " + setting.Value
+		case "vcs.modified":
+			if setting.Value == "true" {
+				version += " (modified)"
+			}
+		}
+	}
+	return version
+}
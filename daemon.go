@@ -0,0 +1,513 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"fomo/internal/client"
+	"fomo/internal/config"
+	"fomo/internal/daemon"
+	"fomo/internal/history"
+	"fomo/internal/notify"
+	"fomo/internal/watch"
+)
+
+// defaultDaemonAddr is a per-user unix socket.
+func defaultDaemonAddr() string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		return "unix:" + filepath.Join(os.TempDir(), fmt.Sprintf("fomo-%d.sock", os.Getuid()))
+	}
+	return "unix:" + filepath.Join(dir, "fomo.sock")
+}
+
+// daemonTokenFile holds the API token of a daemon listening on TCP, next to
+// the user config file.
+const daemonTokenFile = "daemon.token"
+
+// writeDaemonToken creates a new API token and writes it where only the
+// current user can read it, returning the token and the file.
+func writeDaemonToken() (string, string, error) {
+	configPath, err := config.UserConfigPath(os.Getenv)
+	if err != nil {
+		return "", "", err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	token := hex.EncodeToString(secret)
+	path := filepath.Join(filepath.Dir(configPath), daemonTokenFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", "", fmt.Errorf("failed to write the API token: %w", err)
+	}
+	// WriteFile keeps the mode of a file that already exists
+	if err := os.Chmod(path, 0600); err != nil {
+		return "", "", err
+	}
+	return token, path, nil
+}
+
+// tenantName is what a profile served as a tenant may be called, to be
+// usable in URLs.
+var tenantName = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+func runDaemon(a *app, args []string) error {
+	fs := a.newFlagSet("daemon", "[flags]")
+	listen := fs.String("listen", defaultDaemonAddr(), "unix:PATH socket or loopback host:port for the local API")
+	interval := fs.Duration("interval", 0, "how often to poll Azure DevOps (default: polling.interval from the config, or 1m)")
+	var profiles tagFlags
+	fs.Var(&profiles, "tenant", "serve this profile as a tenant, under /tenants/PROFILE/ (repeatable)")
+	hooksAddr := fs.String("hooks", "", "host:port to receive Azure DevOps service hooks and chat commands on")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	var tenants []*daemonTenant
+	if len(profiles) == 0 {
+		// Count the API usage of the daemon's whole life
+		if a.metrics == nil {
+			a.metrics = &client.Metrics{}
+		}
+		t, err := newDaemonTenant(a, *interval)
+		if err != nil {
+			return err
+		}
+		tenants = append(tenants, t)
+	}
+	for i, name := range profiles {
+		if !tenantName.MatchString(name) {
+			return newUsageError(fmt.Sprintf("invalid tenant %q: profile names served as tenants may only use letters, digits, '.', '_' and '-'", name))
+		}
+		if slices.Contains(profiles[:i], name) {
+			return newUsageError(fmt.Sprintf("tenant %s is given twice", name))
+		}
+		ta, err := a.tenantApp(name)
+		if err != nil {
+			return fmt.Errorf("tenant %s: %w", name, err)
+		}
+		t, err := newDaemonTenant(ta, *interval)
+		if err != nil {
+			return fmt.Errorf("tenant %s: %w", name, err)
+		}
+		t.name = name
+		tenants = append(tenants, t)
+	}
+
+	listener, err := daemon.Listen(*listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", *listen, err)
+	}
+	// Unlike the socket, a TCP port is open to every local user and to web
+	// pages, so queuing runs on it takes a token only the user can read
+	tcp := !strings.HasPrefix(*listen, "unix:")
+	if tcp {
+		token, path, err := writeDaemonToken()
+		if err != nil {
+			listener.Close()
+			return err
+		}
+		for _, t := range tenants {
+			t.api.Token = token
+		}
+		a.log.Info("API token written", "path", path)
+	}
+	var handler http.Handler
+	if len(profiles) == 0 {
+		handler = tenants[0].api.Handler()
+	} else {
+		served := &daemon.Tenants{Started: time.Now()}
+		for _, t := range tenants {
+			served.Tenants = append(served.Tenants, daemon.Tenant{Name: t.name, Organization: t.a.cfg.Organization, Project: t.a.cfg.Project, API: t.api})
+		}
+		handler = served.Handler()
+	}
+	if tcp {
+		handler = daemon.LocalHost(handler)
+	}
+	server := &http.Server{Handler: logRequests(a.log, handler)}
+	var hooksServer *http.Server
+	if *hooksAddr != "" {
+		if hooksServer, err = newHooksServer(a, tenants, len(profiles) > 0); err != nil {
+			listener.Close()
+			return err
+		}
+		hooksListener, err := net.Listen("tcp", *hooksAddr)
+		if err != nil {
+			listener.Close()
+			return fmt.Errorf("failed to listen on %s: %v", *hooksAddr, err)
+		}
+		go func() {
+			if err := hooksServer.Serve(hooksListener); !errors.Is(err, http.ErrServerClosed) {
+				a.log.Error("service hooks server failed", "err", err)
+			}
+		}()
+		a.log.Info("receiving service hooks", "listen", *hooksAddr)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for _, t := range tenants {
+		go t.watcher.Run(ctx)
+		go compactHistory(ctx, t.a, t.store, historyPolicy(t.a.cfg))
+		t.a.log.Info("daemon started", "org", t.a.cfg.Organization, "project", t.a.cfg.Project, "listen", *listen)
+	}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+		if hooksServer != nil {
+			hooksServer.Shutdown(shutdown)
+		}
+	}()
+
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	for _, t := range tenants {
+		total := t.a.metrics.Total()
+		t.a.log.Info("daemon stopped", "api_calls", total.Calls, "failed", total.Failed, "received_bytes", total.Received, "latency", total.Latency.Round(time.Millisecond))
+	}
+	return nil
+}
+
+// newHooksServer serves what Azure DevOps and chat send the daemon: the
+// service hooks of each tenant, at /hooks or, with several tenants, at
+// /tenants/PROFILE/hooks, and chat commands at /chat/slack and /chat/teams.
+// The listener can be reached from outside, so every endpoint requires a
+// secret.
+func newHooksServer(a *app, tenants []*daemonTenant, several bool) (*http.Server, error) {
+	mux := http.NewServeMux()
+	served := false
+	for _, t := range tenants {
+		label := cmp.Or(t.name, "the profile")
+		if len(t.a.cfg.Hooks.Rules) == 0 {
+			if several {
+				t.a.log.Warn("no hooks rules; service hooks are not received for this tenant")
+			}
+			continue
+		}
+		if t.a.cfg.Hooks.Secret == "" {
+			return nil, fmt.Errorf("%w: %s has hooks rules but no hooks secret: set hooks.secret or %s", errMissingInput, label, config.EnvHooksSecret)
+		}
+		t.a.redactor.Add(t.a.cfg.Hooks.Secret)
+		hooks, err := daemon.NewHooks(t.a.cfg.Hooks.Rules, t.a.cfg.Hooks.Secret, t.notifier.deliver, t.a.log)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", label, err)
+		}
+		if several {
+			prefix := "/tenants/" + t.name
+			mux.Handle(prefix+"/", http.StripPrefix(prefix, hooks.Handler()))
+		} else {
+			mux.Handle("/hooks", hooks.Handler())
+		}
+		served = true
+	}
+
+	// Chat maps channels to tenants, so its settings are those of the
+	// profile the daemon runs with, not of a tenant
+	cfg := a.cfg
+	if several {
+		var err error
+		if cfg, err = (&config.Loader{Flags: a.flags, Profile: a.profile}).Load(); err != nil {
+			return nil, err
+		}
+	}
+	if chat := cfg.ChatOps; chat.SlackSecret != "" || chat.TeamsSecret != "" {
+		a.redactor.Add(chat.SlackSecret)
+		a.redactor.Add(chat.TeamsSecret)
+		for channel, profile := range chat.Channels {
+			if !slices.ContainsFunc(tenants, func(t *daemonTenant) bool { return t.a.cfg.Profile == profile }) {
+				a.log.Warn("chat channel mapped to a profile the daemon does not serve", "channel", channel, "profile", profile)
+			}
+		}
+		chatops := &daemon.ChatOps{
+			SlackSecret: chat.SlackSecret,
+			TeamsSecret: chat.TeamsSecret,
+			Handle:      newChatHandler(chat.Channels, tenants, several),
+			Log:         a.log,
+		}
+		mux.Handle("/chat/", http.StripPrefix("/chat", chatops.Handler()))
+		served = true
+	}
+
+	if !served {
+		return nil, fmt.Errorf("%w: --hooks needs hooks rules or a chatops secret in the config", errMissingInput)
+	}
+	return &http.Server{Handler: logRequests(a.log, mux)}, nil
+}
+
+// daemonTenant is what the daemon runs for one set of credentials and
+// watch rules: the profile selected as usual, or each profile given with
+// --tenant.
+type daemonTenant struct {
+	name     string // empty unless served with --tenant
+	a        *app   // with the tenant's config, logger and metrics
+	client   *client.Client
+	watcher  *watch.Watcher
+	store    *history.Store
+	api      *daemon.API
+	notifier *eventNotifier
+	// refused is why the tenant may not queue runs, decided when it is
+	// built; handlers share a and must not change it
+	refused error
+}
+
+// tenantApp returns a copy of a for serving a profile as a tenant. The
+// profile is resolved on its own, so settings from the environment cannot
+// leak from one tenant into another, and its history is kept apart.
+func (a *app) tenantApp(profile string) (*app, error) {
+	loader := &config.Loader{Profile: profile, Isolated: true}
+	if !a.noPrompt {
+		loader.Passphrase = func(label string) string {
+			passphrase := promptSecret(label)
+			a.redactor.Add(passphrase)
+			return passphrase
+		}
+	}
+	a.redactor.Add(os.Getenv(config.EnvPassphrase))
+	cfg, err := loader.Load()
+	if err != nil {
+		return nil, err
+	}
+	a.redactor.Add(cfg.PAT)
+	cfg.HistoryDir = filepath.Join(cfg.HistoryDir, "tenants", profile)
+
+	ta := *a
+	ta.cfg = cfg
+	ta.profile = profile
+	ta.log = a.log.With("tenant", profile)
+	ta.metrics = &client.Metrics{}
+	for _, warning := range cfg.Warnings {
+		ta.log.Warn(warning)
+	}
+	return &ta, nil
+}
+
+// newDaemonTenant sets up the watcher, history and API of a tenant, to be
+// started by the caller.
+func newDaemonTenant(a *app, interval time.Duration) (*daemonTenant, error) {
+	c, err := a.newClient()
+	if err != nil {
+		return nil, err
+	}
+	cfg := a.cfg
+
+	// Without watch rules, watch the default pipelines on any branch
+	rules := cfg.Watch
+	if len(rules) == 0 {
+		for _, p := range cfg.Pipelines {
+			rules = append(rules, config.WatchRule{Pipeline: p})
+		}
+	}
+
+	notifier := newEventNotifier(a, cfg)
+	store := &history.Store{Dir: cfg.HistoryDir}
+	var lastPollErr string
+	var lastPollLog time.Time
+	watcher := &watch.Watcher{
+		Source:    c,
+		Rules:     rules,
+		Interval:  cmp.Or(interval, cfg.Polling.Interval),
+		Active:    cfg.Polling.Active,
+		Idle:      cfg.Polling.Idle,
+		IdleAfter: cfg.Polling.IdleAfter,
+		OnEvent: func(event watch.Event) {
+			a.log.Info("run event", "kind", event.Kind, "pipeline", event.Pipeline, "run", event.Run.Name, "branch", event.Branch, "result", event.Run.Result)
+		},
+		OnError: func(err error) {
+			// Polls retry every second while the API is unreachable; repeat
+			// the same error at most once a minute
+			if err.Error() == lastPollErr && time.Since(lastPollLog) < time.Minute {
+				return
+			}
+			lastPollErr, lastPollLog = err.Error(), time.Now()
+			a.log.Warn("poll failed", "err", err)
+		},
+		OnComplete: func(event watch.Event) {
+			// Every completion counts, so recoveries are noticed even by
+			// rules that only report failures
+			notifier.send(event)
+			if err := store.Append(historyRun(c.Project, event)); err != nil {
+				a.log.Warn("failed to record run in history", "run", event.Run.Name, "err", err)
+			}
+		},
+	}
+
+	api := &daemon.API{
+		Watcher: watcher,
+		Started: time.Now(),
+		History: store,
+		Metrics: a.metrics,
+	}
+	t := &daemonTenant{a: a, client: c, watcher: watcher, store: store, api: api, notifier: notifier}
+	t.refused = a.writeRefused("queuing runs through the daemon")
+	api.Trigger = func(ctx context.Context, pipeline, branch string) (*client.Run, error) {
+		return t.queue(ctx, pipeline, branch, "fomo daemon: POST /v1/runs")
+	}
+	return t, nil
+}
+
+// queue queues a run for the daemon's API or chat, recording it in the
+// audit trail as command.
+func (t *daemonTenant) queue(ctx context.Context, pipeline, branch, command string, details ...string) (*client.Run, error) {
+	if t.refused != nil {
+		return nil, fmt.Errorf("%w: %w", daemon.ErrForbidden, t.refused)
+	}
+	p, err := resolvePipeline(ctx, t.client, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	run, err := t.client.RunPipeline(ctx, p.ID, runRequestForBranch(t.a.pipelineBranch(p, branch)))
+	t.a.audit(command, append([]string{p.Name, branch}, details...), err)
+	if err == nil {
+		t.watcher.Wake(p.ID)
+	}
+	return run, err
+}
+
+// compactHistory compacts the local run history at startup and then daily,
+// so it stays bounded while the daemon runs unattended.
+func compactHistory(ctx context.Context, a *app, store *history.Store, policy history.Policy) {
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+	for {
+		result, err := store.Compact(policy, time.Now(), false)
+		if err != nil {
+			a.log.Warn("failed to compact history", "err", err)
+		} else if result.RolledUp > 0 || result.DroppedRollups > 0 {
+			a.log.Info("compacted history", "rolled_up", result.RolledUp, "dropped_rollups", result.DroppedRollups)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runRequestForBranch builds a run request for a branch, or the pipeline's
+// default branch when branch is empty.
+func runRequestForBranch(branch string) client.RunRequest {
+	if branch == "" {
+		return client.RunRequest{}
+	}
+	return client.RunRequest{Resources: &client.RunRequestResources{
+		Repositories: map[string]client.RepositoryResource{"self": {RefName: client.BranchRef(branch)}},
+	}}
+}
+
+// eventNotifier forwards watch events to the notifiers named by the
+// matching rule, and service hook events to those of the hooks rules.
+// Unless alerts.every is set, the failures of a pipeline are deduplicated.
+type eventNotifier struct {
+	a       *app
+	configs map[string]config.NotifierConfig
+	dedup   *notify.Deduper // nil to alert for every failure
+
+	mu        sync.Mutex // guards notifiers, built on first use
+	notifiers map[string]notify.Notifier
+}
+
+func newEventNotifier(a *app, cfg *config.Config) *eventNotifier {
+	n := &eventNotifier{a: a, configs: cfg.Notifiers, notifiers: map[string]notify.Notifier{}}
+	if !cfg.Alerts.Every {
+		n.dedup = &notify.Deduper{
+			Remind: cmp.Or(cfg.Alerts.Remind, 3*time.Hour),
+			Window: cmp.Or(cfg.Alerts.Window, 30*time.Minute),
+		}
+	}
+	return n
+}
+
+// send alerts for a completed run. It sees every completion, whatever the
+// results the rule reports, to keep track of failing pipelines.
+func (n *eventNotifier) send(event watch.Event) {
+	if event.Kind != watch.RunCompleted || len(event.Rule.Notify) == 0 {
+		return
+	}
+	message := notify.Event{
+		Title:    fmt.Sprintf("%s %s", event.Pipeline, event.Run.Result),
+		Message:  fmt.Sprintf("Run %s on %s finished: %s", event.Run.Name, watch.BranchLabel(event.Branch), event.Run.Result),
+		Pipeline: event.Pipeline,
+		RunID:    event.Run.ID,
+		RunName:  event.Run.Name,
+		Branch:   event.Branch,
+		Result:   event.Run.Result,
+		Time:     event.Time,
+	}
+	if event.Run.Links.Web != nil {
+		message.URL = event.Run.Links.Web.Href
+	}
+	if n.dedup != nil {
+		var ok bool
+		if message, ok = n.dedup.Filter(message); !ok {
+			n.a.log.Info("alert suppressed", "pipeline", event.Pipeline, "branch", event.Branch, "run", event.Run.Name, "result", event.Run.Result)
+			return
+		}
+	}
+	// Reminders and recoveries go wherever failures are reported too
+	reported := watch.MatchesResult(event.Rule, event.Run.Result)
+	if message.Alert != "" {
+		reported = reported || watch.MatchesResult(event.Rule, client.RunResultFailed)
+	}
+	if !reported {
+		return
+	}
+
+	n.deliver(context.Background(), event.Rule.Notify, message)
+}
+
+// deliver sends a message to the notifiers named, logging failures.
+func (n *eventNotifier) deliver(ctx context.Context, names []string, message notify.Event) {
+	for _, name := range names {
+		notifier, err := n.get(name)
+		if err != nil {
+			n.a.log.Warn("notifier unavailable", "notifier", name, "err", err)
+			continue
+		}
+		ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		if err := notifier.Notify(ctx, message); err != nil {
+			n.a.log.Warn("notification failed", "notifier", name, "err", err)
+		}
+		cancel()
+	}
+}
+
+func (n *eventNotifier) get(name string) (notify.Notifier, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if notifier, ok := n.notifiers[name]; ok {
+		return notifier, nil
+	}
+	nc, ok := n.configs[name]
+	if !ok {
+		return nil, fmt.Errorf("notifier %q is not configured", name)
+	}
+	notifier, err := notify.New(nc.Type, nc.Options)
+	if err != nil {
+		return nil, err
+	}
+	n.notifiers[name] = notifier
+	return notifier, nil
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"fomo/internal/config"
+	"fomo/internal/history"
+	"fomo/internal/parquet"
+	"fomo/internal/redact"
+	"fomo/internal/watch"
+)
+
+// historyPolicy returns the retention configured for the local history.
+func historyPolicy(cfg *config.Config) history.Policy {
+	policy := history.Policy{
+		Retention:       time.Duration(cfg.History.RetentionDays) * 24 * time.Hour,
+		RollupRetention: time.Duration(cfg.History.RollupDays) * 24 * time.Hour,
+	}
+	if policy.Retention <= 0 {
+		policy.Retention = history.DefaultRetention
+	}
+	return policy
+}
+
+// historyRun converts a completed watch event into a history record.
+func historyRun(project string, event watch.Event) history.Run {
+	run := event.Run
+	return history.Run{
+		Project:    project,
+		PipelineID: run.Pipeline.ID,
+		Pipeline:   event.Pipeline,
+		RunID:      run.ID,
+		Name:       run.Name,
+		Branch:     event.Branch,
+		Result:     run.Result,
+		Created:    run.CreatedDate,
+		Finished:   run.FinishedDate,
+	}
+}
+
+// historyConfig loads the configuration for the history commands, which
+// work offline and so never prompt for credentials.
+func historyConfig(a *app) (*config.Config, error) {
+	loader := &config.Loader{Flags: a.flags, Profile: a.profile, PAT: a.pat}
+	return loader.Load()
+}
+
+func runDBInfo(a *app, args []string) error {
+	fs := a.newFlagSet("db info", "")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	cfg, err := historyConfig(a)
+	if err != nil {
+		return err
+	}
+	store := &history.Store{Dir: cfg.HistoryDir}
+	stats, err := store.Stats()
+	if err != nil {
+		return fmt.Errorf("failed to read history: %w", err)
+	}
+	policy := historyPolicy(cfg)
+
+	if a.quiet {
+		a.resultf("%d\t%d\t%d\n", stats.Runs, stats.Rollups, stats.Bytes)
+		return nil
+	}
+	a.resultf("Location:  %s\n", store.Dir)
+	a.resultf("Size:      %s\n", formatBytes(stats.Bytes))
+	a.resultf("Runs:      %d\n", stats.Runs)
+	a.resultf("Rollups:   %d daily\n", stats.Rollups)
+	if stats.Annotations > 0 {
+		a.resultf("Notes:     %d annotation(s)\n", stats.Annotations)
+	}
+	if !stats.Oldest.IsZero() {
+		a.resultf("Covers:    %s to %s\n", a.display.time(stats.Oldest, time.DateOnly), a.display.time(stats.Newest, time.DateOnly))
+	}
+	rollups := "forever"
+	if policy.RollupRetention > 0 {
+		rollups = fmt.Sprintf("%d days", int(policy.RollupRetention.Hours()/24))
+	}
+	a.resultf("Retention: runs %d days, rollups %s\n", int(policy.Retention.Hours()/24), rollups)
+	return nil
+}
+
+func runDBCompact(a *app, args []string) error {
+	fs := a.newFlagSet("db compact", "[--retention DAYS] [--dry-run]")
+	retention := fs.Int("retention", 0, "keep individual runs for this many days (default: history.retention_days, or 90)")
+	dryRun := fs.Bool("dry-run", false, "report what would be compacted without changing anything")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *retention < 0 {
+		return newUsageError("--retention must be a number of days")
+	}
+	cfg, err := historyConfig(a)
+	if err != nil {
+		return err
+	}
+	policy := historyPolicy(cfg)
+	if *retention > 0 {
+		policy.Retention = time.Duration(*retention) * 24 * time.Hour
+	}
+
+	store := &history.Store{Dir: cfg.HistoryDir}
+	result, err := store.Compact(policy, time.Now(), *dryRun)
+	if err != nil {
+		return fmt.Errorf("failed to compact history: %w", err)
+	}
+	if a.quiet {
+		a.resultf("%d\t%d\t%d\n", result.RolledUp, result.DroppedRollups, result.After.Bytes)
+		return nil
+	}
+	verb := "Rolled up"
+	if *dryRun {
+		verb = "Would roll up"
+	}
+	a.resultf("%s %d run(s) older than %d days into daily rollups\n", verb, result.RolledUp, int(policy.Retention.Hours()/24))
+	if result.Duplicates > 0 {
+		a.resultf("Removed %d duplicate record(s)\n", result.Duplicates)
+	}
+	if result.DroppedRollups > 0 {
+		a.resultf("Dropped %d expired rollup(s)\n", result.DroppedRollups)
+	}
+	a.resultf("Runs: %d -> %d, rollups: %d -> %d", result.Before.Runs, result.After.Runs, result.Before.Rollups, result.After.Rollups)
+	if !*dryRun {
+		a.resultf(", size: %s -> %s", formatBytes(result.Before.Bytes), formatBytes(result.After.Bytes))
+	}
+	a.resultf("\n")
+	return nil
+}
+
+func runDBExport(a *app, args []string) error {
+	fs := a.newFlagSet("db export", "[--format parquet|csv] [--out FILE] [--rollups]")
+	format := fs.String("format", "", "parquet or csv (default: from the --out extension, or csv)")
+	out := fs.String("out", "-", "file to write, or - for stdout")
+	rollups := fs.Bool("rollups", false, "export the daily rollups instead of individual runs")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *format == "" {
+		*format = "csv"
+		if strings.EqualFold(filepath.Ext(*out), ".parquet") {
+			*format = "parquet"
+		}
+	}
+	if *format != "csv" && *format != "parquet" {
+		return newUsageError("--format must be parquet or csv")
+	}
+	if *format == "parquet" && *out == "-" && isTerminal(os.Stdout) {
+		return newUsageError("refusing to write parquet to a terminal; use --out FILE")
+	}
+
+	cfg, err := historyConfig(a)
+	if err != nil {
+		return err
+	}
+	store := &history.Store{Dir: cfg.HistoryDir}
+	table := runsTable
+	if *rollups {
+		table = rollupsTable
+	}
+	columns, rows, err := table(store)
+	if err != nil {
+		return fmt.Errorf("failed to read history: %w", err)
+	}
+
+	write := writeCSV
+	if *format == "parquet" {
+		write = writeParquet
+	}
+	if *out == "-" {
+		if err := write(redact.Unwrap(a.stdout), columns, rows); err != nil {
+			return fmt.Errorf("failed to export history: %w", err)
+		}
+		return nil
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return fmt.Errorf("failed to export history: %w", err)
+	}
+	if err := write(f, columns, rows); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to export history: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to export history: %w", err)
+	}
+	a.infof("Exported %d row(s) to %s\n", len(rows), *out)
+	return nil
+}
+
+// runsTable returns the recorded runs as table rows.
+func runsTable(store *history.Store) ([]parquet.Column, [][]any, error) {
+	columns := []parquet.Column{
+		{Name: "project", Type: parquet.String},
+		{Name: "pipeline_id", Type: parquet.Int64},
+		{Name: "pipeline", Type: parquet.String},
+		{Name: "run_id", Type: parquet.Int64},
+		{Name: "name", Type: parquet.String},
+		{Name: "branch", Type: parquet.String},
+		{Name: "result", Type: parquet.String},
+		{Name: "created", Type: parquet.Timestamp},
+		{Name: "finished", Type: parquet.Timestamp},
+		{Name: "duration_seconds", Type: parquet.Double},
+	}
+	runs, err := store.Runs()
+	if err != nil {
+		return nil, nil, err
+	}
+	rows := make([][]any, len(runs))
+	for i, r := range runs {
+		rows[i] = []any{r.Project, r.PipelineID, r.Pipeline, r.RunID, r.Name, r.Branch, r.Result, r.Created, r.Finished, r.Duration().Seconds()}
+	}
+	return columns, rows, nil
+}
+
+// rollupsTable returns the daily rollups as table rows.
+func rollupsTable(store *history.Store) ([]parquet.Column, [][]any, error) {
+	columns := []parquet.Column{
+		{Name: "day", Type: parquet.String},
+		{Name: "project", Type: parquet.String},
+		{Name: "pipeline_id", Type: parquet.Int64},
+		{Name: "pipeline", Type: parquet.String},
+		{Name: "branch", Type: parquet.String},
+		{Name: "runs", Type: parquet.Int64},
+		{Name: "succeeded", Type: parquet.Int64},
+		{Name: "failed", Type: parquet.Int64},
+		{Name: "canceled", Type: parquet.Int64},
+		{Name: "total_seconds", Type: parquet.Double},
+		{Name: "max_seconds", Type: parquet.Double},
+	}
+	rollups, err := store.Rollups()
+	if err != nil {
+		return nil, nil, err
+	}
+	rows := make([][]any, len(rollups))
+	for i, r := range rollups {
+		rows[i] = []any{r.Day, r.Project, r.PipelineID, r.Pipeline, r.Branch, r.Runs, r.Succeeded, r.Failed, r.Canceled, r.TotalSeconds, r.MaxSeconds}
+	}
+	return columns, rows, nil
+}
+
+func writeParquet(w io.Writer, columns []parquet.Column, rows [][]any) error {
+	pw := parquet.NewWriter(w, columns)
+	for _, row := range rows {
+		if err := pw.Write(row...); err != nil {
+			return err
+		}
+	}
+	return pw.Close()
+}
+
+// writeCSV writes a header and the rows, with RFC 3339 timestamps.
+func writeCSV(w io.Writer, columns []parquet.Column, rows [][]any) error {
+	cw := csv.NewWriter(w)
+	record := make([]string, len(columns))
+	for i, col := range columns {
+		record[i] = col.Name
+	}
+	cw.Write(record)
+	for _, row := range rows {
+		for i, v := range row {
+			switch v := v.(type) {
+			case string:
+				record[i] = v
+			case int:
+				record[i] = strconv.Itoa(v)
+			case float64:
+				record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+			case time.Time:
+				record[i] = v.UTC().Format(time.RFC3339)
+			}
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// formatBytes renders a size with a binary unit, e.g. "1.5 MiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
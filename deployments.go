@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"fomo/internal/client"
+)
+
+// resolveEnvironment finds an environment by ID or name (case-insensitive,
+// as Azure DevOps treats environment names).
+func resolveEnvironment(ctx context.Context, c *client.Client, nameOrID string) (*client.Environment, error) {
+	environments, err := c.ListEnvironments(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch environments: %w", err)
+	}
+	id, _ := strconv.Atoi(nameOrID)
+	for i, env := range environments {
+		if env.ID == id || strings.EqualFold(env.Name, nameOrID) {
+			return &environments[i], nil
+		}
+	}
+	return nil, &client.APIError{StatusCode: http.StatusNotFound, Status: "404 Not Found", Message: fmt.Sprintf("environment %q not found", nameOrID)}
+}
+
+func runDeploymentsRedeploy(a *app, args []string) error {
+	fs := a.newFlagSet("deployments redeploy", "<environment> --run <run-id> [--all-jobs]")
+	run := fs.String("run", "", "run whose deployment to the environment should be repeated")
+	allJobs := fs.Bool("all-jobs", false, "re-run every job of the deploying stage, not just the failed ones")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 || *run == "" {
+		return newUsageError("usage: fomo deployments redeploy <environment> --run <run-id>")
+	}
+	runID, err := parseRunID(*run)
+	if err != nil {
+		return err
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+
+	env, err := resolveEnvironment(ctx, c, positional[0])
+	if err != nil {
+		return err
+	}
+	records, err := c.ListDeploymentRecords(ctx, env.ID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch deployments to %s: %w", env.Name, err)
+	}
+
+	// The deployment record names the stage of the run that deployed here
+	for _, record := range records {
+		if record.Owner.ID == runID {
+			return retryStage(ctx, a, c, runID, record.StageName, *allJobs)
+		}
+	}
+	return newUsageError(fmt.Sprintf("run %d has not deployed to environment %s", runID, env.Name))
+}
+
+// deployment is a deployment job to an environment, with the run that
+// deployed and who approved its stage.
+type deployment struct {
+	record    client.DeploymentRecord
+	run       *client.Build
+	approvers []string
+}
+
+// sourceVersion is the full ID of the commit a run deployed.
+func (d deployment) sourceVersion() string {
+	if d.run == nil {
+		return ""
+	}
+	return d.run.SourceVersion
+}
+
+// commit is the short commit ID and the first line, shortened, of the
+// message of the commit a run deployed.
+func (d deployment) commit() string {
+	if d.run == nil || d.run.SourceVersion == "" {
+		return "-"
+	}
+	commit := d.run.SourceVersion[:min(len(d.run.SourceVersion), 7)]
+	message, _, _ := strings.Cut(d.run.CommitMessage(), "\n")
+	message = strings.TrimSpace(message)
+	if r := []rune(message); len(r) > 50 {
+		message = string(r[:49]) + "…"
+	}
+	if message != "" {
+		commit += " " + message
+	}
+	return commit
+}
+
+// stageApprovers returns who approved the stage of a run: the approvers of
+// the approvals under the stage's checkpoint in the run's timeline.
+func stageApprovers(ctx context.Context, c *client.Client, runID int, stage string) ([]string, error) {
+	timeline, err := c.GetTimeline(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	parents := map[string]string{}
+	stageID := ""
+	for _, r := range timeline.Records {
+		parents[r.ID] = r.ParentID
+		if r.Type == client.RecordStage && (r.Identifier == stage || r.Name == stage) {
+			stageID = r.ID
+		}
+	}
+	var ids []string
+	for _, r := range timeline.Records {
+		if r.Type == client.RecordApproval && stageID != "" && parents[r.ParentID] == stageID {
+			ids = append(ids, r.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	approvals, err := c.GetApprovals(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	var approvers []string
+	for _, approval := range approvals {
+		for _, step := range approval.Steps {
+			if step.Status != client.ApprovalApproved {
+				continue
+			}
+			approver := step.AssignedApprover
+			if step.ActualApprover != nil {
+				approver = *step.ActualApprover
+			}
+			if !slices.Contains(approvers, approver.DisplayName) {
+				approvers = append(approvers, approver.DisplayName)
+			}
+		}
+	}
+	return approvers, nil
+}
+
+// loadDeployments fetches the runs of deployment records and who approved
+// them, at most 8 runs at a time. What cannot be fetched is left out with
+// a warning.
+func loadDeployments(ctx context.Context, a *app, c *client.Client, records []client.DeploymentRecord) []deployment {
+	deployments := make([]deployment, len(records))
+	type runStage struct {
+		run   int
+		stage string
+	}
+	runs := map[int]*client.Build{}
+	approvers := map[runStage][]string{}
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		sem = make(chan struct{}, 8)
+	)
+	for _, r := range records {
+		key := runStage{r.Owner.ID, r.StageName}
+		if _, ok := approvers[key]; ok {
+			continue
+		}
+		approvers[key] = nil
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			b, err := c.GetBuild(ctx, key.run)
+			if err != nil {
+				a.warnf("Warning: failed to fetch run %d: %v\n", key.run, err)
+			}
+			names, err := stageApprovers(ctx, c, key.run, key.stage)
+			if err != nil {
+				a.warnf("Warning: failed to fetch the approvals of run %d: %v\n", key.run, err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if b != nil {
+				runs[key.run] = b
+			}
+			approvers[key] = names
+		}()
+	}
+	wg.Wait()
+	for i, r := range records {
+		deployments[i] = deployment{record: r, run: runs[r.Owner.ID], approvers: approvers[runStage{r.Owner.ID, r.StageName}]}
+	}
+	return deployments
+}
+
+func runDeploymentsList(a *app, args []string) error {
+	fs := a.newFlagSet("deployments list", "<environment> [--top N] [--current]")
+	top := fs.Int("top", 20, "how many of the latest deployment jobs to show")
+	current := fs.Bool("current", false, "show what each pipeline has live now and what it replaced, to roll back to")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return newUsageError("usage: fomo deployments list <environment> [--top N] [--current]")
+	}
+	if *top <= 0 {
+		return newUsageError("--top must be positive")
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	env, err := resolveEnvironment(ctx, c, positional[0])
+	if err != nil {
+		return err
+	}
+	records, err := c.ListDeploymentRecords(ctx, env.ID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch deployments to %s: %w", env.Name, err)
+	}
+	if *current {
+		return showCurrentDeployments(ctx, a, c, env, records)
+	}
+	records = records[:min(len(records), *top)]
+	if len(records) == 0 {
+		a.infof("Nothing has deployed to %s yet.\n", env.Name)
+		return nil
+	}
+	deployments := loadDeployments(ctx, a, c, records)
+
+	if a.quiet {
+		for _, d := range deployments {
+			a.resultf("%d\t%s\t%s\t%s\t%s\t%s\t%s\n", d.record.Owner.ID, d.record.Definition.Name, d.record.StageName, d.record.JobName,
+				d.sourceVersion(), strings.Join(d.approvers, ","), d.record.Result)
+		}
+		return nil
+	}
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "RUN\tPIPELINE\tJOB\tCOMMIT\tAPPROVED BY\tRESULT\tFINISHED")
+	for _, d := range deployments {
+		finished := "-"
+		if !d.record.FinishTime.IsZero() {
+			finished = a.display.time(d.record.FinishTime, time.DateTime)
+		}
+		number := d.record.Owner.Name
+		if number == "" {
+			number = strconv.Itoa(d.record.Owner.ID)
+		}
+		fmt.Fprintf(w, "%d\t%s %s\t%s/%s\t%s\t%s\t%s\t%s\n", d.record.Owner.ID, d.record.Definition.Name, number, d.record.StageName, d.record.JobName,
+			d.commit(), orDash(strings.Join(d.approvers, ", ")), orDash(d.record.Result), finished)
+	}
+	return w.Flush()
+}
+
+// showCurrentDeployments shows, for each pipeline that deployed to an
+// environment, its latest successful deployment, which is what is live,
+// and the successful one before it, which is where a rollback goes.
+func showCurrentDeployments(ctx context.Context, a *app, c *client.Client, env *client.Environment, records []client.DeploymentRecord) error {
+	type history struct {
+		pipeline string
+		live     *client.DeploymentRecord
+		previous *client.DeploymentRecord
+	}
+	var pipelines []*history
+	byPipeline := map[int]*history{}
+	// Records are newest first; a run counts once, by its latest job
+	for i, r := range records {
+		if r.Result != "succeeded" {
+			continue
+		}
+		h, ok := byPipeline[r.Definition.ID]
+		if !ok {
+			h = &history{pipeline: r.Definition.Name}
+			byPipeline[r.Definition.ID] = h
+			pipelines = append(pipelines, h)
+		}
+		switch {
+		case h.live == nil:
+			h.live = &records[i]
+		case h.previous == nil && r.Owner.ID != h.live.Owner.ID:
+			h.previous = &records[i]
+		}
+	}
+	if len(pipelines) == 0 {
+		a.infof("Nothing has deployed to %s successfully yet.\n", env.Name)
+		return nil
+	}
+
+	var selected []client.DeploymentRecord
+	for _, h := range pipelines {
+		selected = append(selected, *h.live)
+		if h.previous != nil {
+			selected = append(selected, *h.previous)
+		}
+	}
+	loaded := map[int]deployment{}
+	for _, d := range loadDeployments(ctx, a, c, selected) {
+		loaded[d.record.ID] = d
+	}
+	describe := func(r *client.DeploymentRecord) string {
+		d := loaded[r.ID]
+		text := fmt.Sprintf("run %d (%s), %s, deployed %s", r.Owner.ID, orDash(r.Owner.Name), d.commit(), a.display.time(r.FinishTime, time.DateTime))
+		if len(d.approvers) > 0 {
+			text += ", approved by " + strings.Join(d.approvers, ", ")
+		}
+		return text
+	}
+
+	if a.quiet {
+		for _, h := range pipelines {
+			previous := ""
+			if h.previous != nil {
+				previous = strconv.Itoa(h.previous.Owner.ID)
+			}
+			a.resultf("%s\t%d\t%s\t%s\n", h.pipeline, h.live.Owner.ID, loaded[h.live.ID].sourceVersion(), previous)
+		}
+		return nil
+	}
+	for i, h := range pipelines {
+		if i > 0 {
+			a.resultf("\n")
+		}
+		a.resultf("%s\n", h.pipeline)
+		a.resultf("  live:     %s\n", describe(h.live))
+		if h.previous == nil {
+			a.resultf("  previous: none in the deployment history\n")
+			continue
+		}
+		a.resultf("  previous: %s\n", describe(h.previous))
+		a.infof("  roll back with: fomo deployments redeploy %s --run %d\n", env.Name, h.previous.Owner.ID)
+	}
+	return nil
+}
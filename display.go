@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Duration styles of --durations.
+const (
+	durationsHuman = "human"
+	durationsISO   = "iso"
+)
+
+// display formats times and durations for output. The API reports every
+// timestamp in UTC; display shows them in the chosen time zone.
+type display struct {
+	loc *time.Location
+	iso bool // ISO 8601 durations, e.g. PT1H30M, instead of 1h
+}
+
+// newDisplay resolves a time zone name, local by default, and a duration
+// style, human by default.
+func newDisplay(tz, durations string) (display, error) {
+	d := display{loc: time.Local}
+	switch strings.ToLower(tz) {
+	case "", "local":
+	case "utc":
+		d.loc = time.UTC
+	default:
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return d, newUsageError(fmt.Sprintf("unknown time zone %q: use local, UTC or an IANA name such as Europe/Madrid", tz))
+		}
+		d.loc = loc
+	}
+	switch durations {
+	case "", durationsHuman:
+	case durationsISO:
+		d.iso = true
+	default:
+		return d, newUsageError(fmt.Sprintf("invalid durations %q: use %s or %s", durations, durationsHuman, durationsISO))
+	}
+	return d, nil
+}
+
+// in returns t in the display time zone.
+func (d display) in(t time.Time) time.Time {
+	if d.loc == nil {
+		return t.Local()
+	}
+	return t.In(d.loc)
+}
+
+// time formats t in the display time zone.
+func (d display) time(t time.Time, layout string) string {
+	return d.in(t).Format(layout)
+}
+
+// duration renders d with a single unit, e.g. "45s" or "3h", or in ISO
+// 8601.
+func (d display) duration(v time.Duration) string {
+	if d.iso {
+		return isoDuration(v)
+	}
+	return shortDuration(v)
+}
+
+// exactDuration renders d to the second, e.g. "3m25s", or in ISO 8601.
+func (d display) exactDuration(v time.Duration) string {
+	if d.iso {
+		return isoDuration(v)
+	}
+	return v.Round(time.Second).String()
+}
+
+// isoDuration renders d as an ISO 8601 duration to the second, in hours,
+// minutes and seconds, e.g. PT1H5M30S. Days are left out because they are
+// not always 24 hours long.
+func isoDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	if d == 0 {
+		return "PT0S"
+	}
+	var b strings.Builder
+	b.WriteString(sign + "PT")
+	if h := d / time.Hour; h > 0 {
+		fmt.Fprintf(&b, "%dH", h)
+	}
+	if m := d % time.Hour / time.Minute; m > 0 {
+		fmt.Fprintf(&b, "%dM", m)
+	}
+	if s := d % time.Minute / time.Second; s > 0 {
+		fmt.Fprintf(&b, "%dS", s)
+	}
+	return b.String()
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"fomo/internal/client"
+)
+
+// The outcomes of a doctor check.
+const (
+	doctorOK   = "ok"
+	doctorWarn = "warn"
+	doctorFail = "fail"
+)
+
+// doctorCheck is one line of the readiness report.
+type doctorCheck struct {
+	status string
+	area   string
+	name   string
+	detail string
+}
+
+// patScopes are cheap reads that each need one scope of the PAT. Azure
+// DevOps does not say which scopes a PAT has, so they are tried.
+var patScopes = []struct {
+	scope string
+	probe func(ctx context.Context, c *client.Client) error
+}{
+	{"Build (read)", func(ctx context.Context, c *client.Client) error {
+		_, err := c.ListBuilds(ctx, client.BuildCriteria{Top: 1})
+		return err
+	}},
+	{"Code (read)", func(ctx context.Context, c *client.Client) error {
+		_, err := c.ListRepositories(ctx)
+		return err
+	}},
+	{"Agent Pools (read)", func(ctx context.Context, c *client.Client) error {
+		_, err := c.ListQueues(ctx)
+		return err
+	}},
+	{"Service Connections (read)", func(ctx context.Context, c *client.Client) error {
+		_, err := c.ListServiceEndpoints(ctx)
+		return err
+	}},
+	{"Variable Groups (read)", func(ctx context.Context, c *client.Client) error {
+		_, err := c.ListVariableGroups(ctx)
+		return err
+	}},
+}
+
+// testConnectionSource is the data source Azure Resource Manager service
+// connections are verified with, as the Verify button does.
+const testConnectionSource = "TestConnection"
+
+// isDenied reports whether a request failed for lack of permission or
+// scope.
+func isDenied(err error) bool {
+	var apiErr *client.APIError
+	return errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden)
+}
+
+func checkScopes(ctx context.Context, c *client.Client) []doctorCheck {
+	checks := make([]doctorCheck, len(patScopes))
+	var wg sync.WaitGroup
+	for i, s := range patScopes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checks[i] = doctorCheck{status: doctorOK, area: "PAT scope", name: s.scope, detail: "granted"}
+			switch err := s.probe(ctx, c); {
+			case isDenied(err):
+				checks[i].status, checks[i].detail = doctorFail, "missing; add it to the PAT"
+			case err != nil:
+				checks[i].status, checks[i].detail = doctorWarn, fmt.Sprintf("could not check: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	return checks
+}
+
+func checkProject(ctx context.Context, c *client.Client) []doctorCheck {
+	check := doctorCheck{status: doctorOK, area: "Project", name: c.Project}
+	project, err := c.GetProject(ctx, c.Project)
+	if err != nil {
+		check.status, check.detail = doctorFail, err.Error()
+	} else {
+		check.detail = "found, " + project.ID
+	}
+	return []doctorCheck{check}
+}
+
+func checkRateLimit(ctx context.Context, a *app, c *client.Client) []doctorCheck {
+	check := doctorCheck{status: doctorOK, area: "Rate limit", name: c.Organization}
+	limit, err := c.GetRateLimit(ctx)
+	switch {
+	case err != nil:
+		check.status, check.detail = doctorWarn, fmt.Sprintf("could not check: %v", err)
+	case limit.RetryAfter > 0:
+		check.status, check.detail = doctorFail, fmt.Sprintf("requests are refused for %s", a.display.duration(limit.RetryAfter))
+	case limit.Delay > 0:
+		check.status, check.detail = doctorWarn, fmt.Sprintf("requests are delayed by %s", a.display.duration(limit.Delay))
+	case !limit.Reported || limit.Limit <= 0:
+		check.detail = "not throttled"
+	default:
+		left := limit.Remaining / limit.Limit
+		check.detail = fmt.Sprintf("%.0f%% of %s left", left*100, orDash(limit.Resource))
+		if !limit.Reset.IsZero() {
+			check.detail += ", resets at " + a.display.time(limit.Reset, time.TimeOnly)
+		}
+		if left < 0.2 {
+			check.status = doctorWarn
+		}
+	}
+	return []doctorCheck{check}
+}
+
+// checkPools checks that every self-hosted pool the project uses has an
+// online agent.
+func checkPools(ctx context.Context, c *client.Client) []doctorCheck {
+	queues, err := c.ListQueues(ctx)
+	if err != nil {
+		if isDenied(err) {
+			return nil // reported as a missing scope
+		}
+		return []doctorCheck{{status: doctorWarn, area: "Agent pool", name: "-", detail: fmt.Sprintf("could not list: %v", err)}}
+	}
+	checks := make([]doctorCheck, len(queues))
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, 8)
+	)
+	for i, q := range queues {
+		checks[i] = doctorCheck{status: doctorOK, area: "Agent pool", name: q.Name, detail: "Microsoft-hosted"}
+		if q.Pool.IsHosted {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			agents, err := c.ListAgents(ctx, q.Pool.ID)
+			if err != nil {
+				checks[i].status, checks[i].detail = doctorWarn, fmt.Sprintf("could not list agents: %v", err)
+				return
+			}
+			online := 0
+			for _, agent := range agents {
+				if agent.Enabled && agent.Status == "online" {
+					online++
+				}
+			}
+			checks[i].detail = fmt.Sprintf("%d of %d agents online", online, len(agents))
+			if online == 0 {
+				checks[i].status = doctorFail
+			}
+		}()
+	}
+	wg.Wait()
+	return checks
+}
+
+// checkEndpoint checks that a service connection is ready and, for Azure
+// Resource Manager connections with a client secret, that the secret
+// still signs in: secrets expire, and Azure DevOps cannot tell when.
+func checkEndpoint(ctx context.Context, c *client.Client, e client.ServiceEndpoint) doctorCheck {
+	check := doctorCheck{status: doctorOK, area: "Service connection", name: e.Name}
+	switch {
+	case e.OperationStatus != nil && strings.EqualFold(e.OperationStatus.State, "Failed"):
+		check.status, check.detail = doctorFail, "setup failed: "+orDash(e.OperationStatus.StatusMessage)
+		return check
+	case !e.IsReady:
+		check.status, check.detail = doctorFail, "not ready; open it in Project settings to finish or repair it"
+		return check
+	case e.Authorization.Scheme == "WorkloadIdentityFederation":
+		check.detail = "workload identity federation, no secret to expire"
+		return check
+	case !strings.EqualFold(e.Type, "azurerm") || e.Authorization.Scheme != "ServicePrincipal" || e.Authorization.Parameters["authenticationType"] == "spnCertificate":
+		check.detail = "ready; " + e.Type + " credentials are not verified"
+		return check
+	}
+
+	result, err := c.QueryServiceEndpoint(ctx, e.ID, testConnectionSource, nil)
+	if err != nil {
+		check.status, check.detail = doctorWarn, fmt.Sprintf("could not verify: %v", err)
+		return check
+	}
+	if result.ErrorMessage == "" && (result.StatusCode == "" || strings.EqualFold(result.StatusCode, "ok")) {
+		check.detail = "client secret signs in"
+		return check
+	}
+	message := result.ErrorMessage
+	if message == "" {
+		message = result.StatusCode
+	}
+	check.status = doctorFail
+	// AADSTS7000222: the client secret keys have expired
+	if strings.Contains(message, "AADSTS7000222") || strings.Contains(strings.ToLower(message), "expired") {
+		check.detail = "client secret expired; renew it or switch to workload identity federation"
+	} else {
+		check.detail = "cannot sign in: " + message
+	}
+	return check
+}
+
+func checkEndpoints(ctx context.Context, c *client.Client) []doctorCheck {
+	endpoints, err := c.ListServiceEndpoints(ctx)
+	if err != nil {
+		if isDenied(err) {
+			return nil // reported as a missing scope
+		}
+		return []doctorCheck{{status: doctorWarn, area: "Service connection", name: "-", detail: fmt.Sprintf("could not list: %v", err)}}
+	}
+	checks := make([]doctorCheck, len(endpoints))
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, 8)
+	)
+	for i, e := range endpoints {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			checks[i] = checkEndpoint(ctx, c, e)
+		}()
+	}
+	wg.Wait()
+	return checks
+}
+
+// doctorLabel is the status column of the report, colored for a terminal.
+func doctorLabel(status string, color bool) string {
+	label := fmt.Sprintf("%-4s", strings.ToUpper(status))
+	codes := map[string]string{doctorOK: "32", doctorWarn: "33", doctorFail: "31"}
+	if !color {
+		return label
+	}
+	return "\033[" + codes[status] + "m" + label + "\033[0m"
+}
+
+func runDoctor(a *app, args []string) error {
+	fs := a.newFlagSet("doctor", "")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	a.infof("Checking %s/%s\n\n", c.Organization, c.Project)
+
+	// Nothing else can work without a connection and a valid PAT
+	start := time.Now()
+	me, err := c.Me(ctx)
+	connection := doctorCheck{status: doctorOK, area: "Connection", name: c.BaseURL}
+	if err != nil {
+		connection.status, connection.detail = doctorFail, err.Error()
+	} else {
+		elapsed := time.Since(start)
+		connection.detail = fmt.Sprintf("signed in as %s in %s", me.DisplayName, elapsed.Round(time.Millisecond))
+		if elapsed > 2*time.Second {
+			connection.status = doctorWarn
+		}
+	}
+	checks := []doctorCheck{connection}
+	if err == nil {
+		groups := []func() []doctorCheck{
+			func() []doctorCheck { return checkProject(ctx, c) },
+			func() []doctorCheck { return checkScopes(ctx, c) },
+			func() []doctorCheck { return checkRateLimit(ctx, a, c) },
+			func() []doctorCheck { return checkPools(ctx, c) },
+			func() []doctorCheck { return checkEndpoints(ctx, c) },
+		}
+		results := make([][]doctorCheck, len(groups))
+		var wg sync.WaitGroup
+		for i, check := range groups {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i] = check()
+			}()
+		}
+		wg.Wait()
+		for _, r := range results {
+			checks = append(checks, r...)
+		}
+	}
+
+	counts := map[string]int{}
+	for _, check := range checks {
+		counts[check.status]++
+	}
+	if a.quiet {
+		for _, check := range checks {
+			a.resultf("%s\t%s\t%s\t%s\n", check.status, check.area, check.name, check.detail)
+		}
+	} else {
+		color := isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == "" && !a.plain
+		w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+		for _, check := range checks {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", doctorLabel(check.status, color), check.area, check.name, check.detail)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		a.infof("\n%d checks: %d ok, %d warnings, %d failed.\n", len(checks), counts[doctorOK], counts[doctorWarn], counts[doctorFail])
+	}
+
+	if err != nil {
+		return err
+	}
+	if counts[doctorFail] > 0 {
+		return fmt.Errorf("%d of %d checks failed", counts[doctorFail], len(checks))
+	}
+	return nil
+}
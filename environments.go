@@ -0,0 +1,393 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"fomo/internal/client"
+)
+
+// weekdays are the business days a business hours check takes, by the
+// abbreviations accepted for them.
+var weekdays = []struct{ short, name string }{
+	{"mon", "Monday"}, {"tue", "Tuesday"}, {"wed", "Wednesday"}, {"thu", "Thursday"},
+	{"fri", "Friday"}, {"sat", "Saturday"}, {"sun", "Sunday"},
+}
+
+// parseBusinessDays parses days such as "mon-fri" or "mon,wed,fri" into
+// the comma-separated day names a business hours check takes.
+func parseBusinessDays(s string) (string, error) {
+	index := func(day string) int {
+		day = strings.ToLower(strings.TrimSpace(day))
+		for i, d := range weekdays {
+			if day == d.short || day == strings.ToLower(d.name) {
+				return i
+			}
+		}
+		return -1
+	}
+	selected := make([]bool, len(weekdays))
+	for _, part := range strings.Split(s, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		first, last := index(from), index(to)
+		if !isRange {
+			last = first
+		}
+		if first < 0 || last < 0 || last < first {
+			return "", fmt.Errorf("invalid days %q; use e.g. mon-fri or mon,wed,fri", s)
+		}
+		for i := first; i <= last; i++ {
+			selected[i] = true
+		}
+	}
+	var names []string
+	for i, d := range weekdays {
+		if selected[i] {
+			names = append(names, d.name)
+		}
+	}
+	return strings.Join(names, ","), nil
+}
+
+// describeCheck names the kind of a check and summarizes its settings.
+func describeCheck(check client.CheckConfiguration) (kind, details string) {
+	switch {
+	case check.Type.Is(client.CheckTypeApproval):
+		var s client.ApprovalSettings
+		if json.Unmarshal(check.Settings, &s) != nil {
+			return "approval", "-"
+		}
+		var approvers []string
+		for _, id := range s.Approvers {
+			approvers = append(approvers, id.DisplayName)
+		}
+		details = strings.Join(approvers, ", ")
+		switch {
+		case s.MinRequiredApprovers > 0 && s.MinRequiredApprovers < len(approvers):
+			details = fmt.Sprintf("%d of %s", s.MinRequiredApprovers, details)
+		case len(approvers) > 1:
+			details = "all of " + details
+		}
+		if s.ExecutionOrder == client.ApprovalInSequence {
+			details += ", in sequence"
+		}
+		if s.RequesterCannotBeApprover {
+			details += ", not the requester"
+		}
+		return "approval", details
+	case check.Type.Is(client.CheckTypeExclusiveLock):
+		return "exclusive lock", "one run at a time"
+	case check.Type.Is(client.CheckTypeTask):
+		var s client.TaskCheckSettings
+		if json.Unmarshal(check.Settings, &s) != nil {
+			return "task", "-"
+		}
+		if !strings.EqualFold(s.DefinitionRef.ID, client.BusinessHoursTask.ID) {
+			return "task", orDash(s.DisplayName)
+		}
+		days := s.Inputs["businessDays"]
+		for _, d := range weekdays {
+			days = strings.ReplaceAll(days, d.name, strings.ToUpper(d.short[:1])+d.short[1:])
+		}
+		return "business hours", fmt.Sprintf("%s %s-%s %s", days, s.Inputs["startTime"], s.Inputs["endTime"], s.Inputs["timeZone"])
+	}
+	return strings.ToLower(check.Type.Name), "-"
+}
+
+func runEnvironmentsChecksList(a *app, args []string) error {
+	fs := a.newFlagSet("environments checks list", "<environment>")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return newUsageError("usage: fomo environments checks list <environment>")
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	env, err := resolveEnvironment(ctx, c, positional[0])
+	if err != nil {
+		return err
+	}
+	checks, err := c.ListEnvironmentChecks(ctx, env.ID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch the checks of %s: %w", env.Name, err)
+	}
+
+	if a.quiet {
+		for _, check := range checks {
+			kind, details := describeCheck(check)
+			a.resultf("%d\t%s\t%s\t%d\n", check.ID, kind, details, check.Timeout)
+		}
+		return nil
+	}
+	if len(checks) == 0 {
+		a.infof("%s has no checks; any pipeline allowed to use it deploys right away.\n", env.Name)
+		return nil
+	}
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCHECK\tSETTINGS\tTIMEOUT")
+	for _, check := range checks {
+		kind, details := describeCheck(check)
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", check.ID, kind, details, a.display.duration(time.Duration(check.Timeout)*time.Minute))
+	}
+	return w.Flush()
+}
+
+func runEnvironmentsChecksAdd(a *app, args []string) error {
+	const usage = "usage: fomo environments checks add <environment> approval|business-hours|exclusive-lock [flags]"
+	fs := a.newFlagSet("environments checks add", "<environment> approval|business-hours|exclusive-lock [flags]")
+	var approvers tagFlags
+	fs.Var(&approvers, "approver", "approval: a user or group who can approve: me, an email address or a name, optionally as @name (repeatable)")
+	minApprovers := fs.Int("min", 0, "approval: how many approvers must approve (default all)")
+	inSequence := fs.Bool("in-sequence", false, "approval: approvers approve one after the other, in the order given")
+	instructions := fs.String("instructions", "", "approval: instructions shown to approvers")
+	notRequester := fs.Bool("not-requester", false, "approval: whoever queued the run cannot approve it")
+	days := fs.String("days", "mon-fri", "business-hours: the days deployments may run, e.g. mon-fri or mon,wed")
+	start := fs.String("start", "09:00", "business-hours: when deployments may start, as HH:MM")
+	end := fs.String("end", "17:00", "business-hours: when they must stop, as HH:MM")
+	timeZone := fs.String("time-zone", "UTC", "business-hours: Windows time zone ID of the hours, e.g. \"W. Europe Standard Time\"")
+	timeout := fs.String("timeout", "30d", "how long the check may take before the run fails, e.g. 12h or 30d")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return newUsageError(usage)
+	}
+	age, err := parseAge(*timeout)
+	if err != nil || age < time.Minute {
+		return newUsageError(fmt.Sprintf("invalid --timeout %q", *timeout))
+	}
+
+	var checkType client.CheckType
+	var settings any
+	kind := positional[1]
+	switch kind {
+	case "approval":
+		if len(approvers) == 0 {
+			return newUsageError("an approval check needs at least one --approver")
+		}
+		if *minApprovers < 0 || *minApprovers > len(approvers) {
+			return newUsageError(fmt.Sprintf("--min must be at most the number of approvers, %d", len(approvers)))
+		}
+		checkType = client.CheckTypeApproval
+	case "business-hours":
+		businessDays, err := parseBusinessDays(*days)
+		if err != nil {
+			return newUsageError(err.Error())
+		}
+		for _, t := range []string{*start, *end} {
+			if _, err := time.Parse("15:04", t); err != nil {
+				return newUsageError(fmt.Sprintf("invalid time %q; use HH:MM", t))
+			}
+		}
+		if *end <= *start {
+			return newUsageError("--end must be after --start")
+		}
+		checkType = client.CheckTypeTask
+		settings = client.TaskCheckSettings{
+			DefinitionRef: client.BusinessHoursTask,
+			DisplayName:   "Business Hours",
+			Inputs:        map[string]string{"businessDays": businessDays, "timeZone": *timeZone, "startTime": *start, "endTime": *end},
+			RetryInterval: 5,
+		}
+	case "exclusive-lock":
+		checkType = client.CheckTypeExclusiveLock
+		settings = map[string]any{}
+	default:
+		return newUsageError(fmt.Sprintf("unknown check %q\n%s", kind, usage))
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	env, err := resolveEnvironment(ctx, c, positional[0])
+	if err != nil {
+		return err
+	}
+	if kind == "approval" {
+		s := client.ApprovalSettings{
+			ExecutionOrder:            client.ApprovalAnyOrder,
+			Instructions:              *instructions,
+			BlockedApprovers:          []client.Identity{},
+			MinRequiredApprovers:      *minApprovers,
+			RequesterCannotBeApprover: *notRequester,
+		}
+		if *inSequence {
+			s.ExecutionOrder = client.ApprovalInSequence
+		}
+		identities := newIdentities(c)
+		for _, name := range approvers {
+			id, err := identities.resolve(ctx, name)
+			if err != nil {
+				return err
+			}
+			s.Approvers = append(s.Approvers, client.Identity{ID: id.ID, DisplayName: id.DisplayName})
+		}
+		settings = s
+	}
+
+	check, err := c.AddEnvironmentCheck(ctx, *env, checkType, settings, age)
+	if err != nil {
+		return fmt.Errorf("failed to add the check to %s: %w", env.Name, err)
+	}
+	if a.quiet {
+		a.resultf("%d\n", check.ID)
+		return nil
+	}
+	if check.Settings == nil {
+		check.Settings, _ = json.Marshal(settings)
+	}
+	kind, details := describeCheck(*check)
+	a.resultf("Added %s check %d to %s: %s\n", kind, check.ID, env.Name, details)
+	return nil
+}
+
+// resourceStatus is a resource of an environment, what it points at and
+// whether deployments can reach it.
+type resourceStatus struct {
+	resource client.EnvironmentResource
+	target   string
+	health   string
+	last     *client.DeploymentRecord
+}
+
+// kubernetesStatus describes the namespace of a Kubernetes resource and
+// whether its service connection is usable.
+func kubernetesStatus(ctx context.Context, c *client.Client, envID int, s *resourceStatus) error {
+	k, err := c.GetKubernetesResource(ctx, envID, s.resource.ID)
+	if err != nil {
+		return err
+	}
+	s.target = k.Namespace + " on " + orDash(k.ClusterName)
+	endpoint, err := c.GetServiceEndpoint(ctx, k.ServiceEndpointID)
+	var apiErr *client.APIError
+	switch {
+	case (err == nil && endpoint == nil) || (errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound):
+		s.health = "service connection missing"
+	case err != nil:
+		return err
+	case !endpoint.IsReady:
+		s.health = "service connection not ready"
+	default:
+		s.health = "ok"
+	}
+	return nil
+}
+
+func runEnvironmentsResources(a *app, args []string) error {
+	fs := a.newFlagSet("environments resources", "<environment>")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return newUsageError("usage: fomo environments resources <environment>")
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	env, err := resolveEnvironment(ctx, c, positional[0])
+	if err != nil {
+		return err
+	}
+	resources, err := c.ListEnvironmentResources(ctx, env.ID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch the resources of %s: %w", env.Name, err)
+	}
+	if len(resources) == 0 {
+		a.infof("%s has no Kubernetes or virtual machine resources.\n", env.Name)
+		return nil
+	}
+	records, err := c.ListDeploymentRecords(ctx, env.ID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch deployments to %s: %w", env.Name, err)
+	}
+
+	statuses := make([]resourceStatus, len(resources))
+	var machines map[int]client.VirtualMachineResource
+	for i, r := range resources {
+		statuses[i] = resourceStatus{resource: r, target: "-", health: "-"}
+		// Records are newest first
+		for j, record := range records {
+			if record.ResourceID != nil && *record.ResourceID == r.ID {
+				statuses[i].last = &records[j]
+				break
+			}
+		}
+		switch r.Type {
+		case client.ResourceKubernetes:
+			if err := kubernetesStatus(ctx, c, env.ID, &statuses[i]); err != nil {
+				a.warnf("Warning: failed to check Kubernetes resource %s: %v\n", r.Name, err)
+			}
+		case client.ResourceVirtualMachine:
+			if machines == nil {
+				list, err := c.ListVirtualMachines(ctx, env.ID)
+				if err != nil {
+					return fmt.Errorf("failed to fetch the virtual machines of %s: %w", env.Name, err)
+				}
+				machines = map[int]client.VirtualMachineResource{}
+				for _, m := range list {
+					machines[m.ID] = m
+				}
+			}
+			m, ok := machines[r.ID]
+			if !ok {
+				statuses[i].health = "agent missing"
+				continue
+			}
+			statuses[i].target = m.Agent.Name + " " + m.Agent.Version
+			switch {
+			case !m.Agent.Enabled:
+				statuses[i].health = "agent disabled"
+			case m.Agent.Status != "online":
+				statuses[i].health = "agent " + orDash(m.Agent.Status)
+			default:
+				statuses[i].health = "ok"
+			}
+		}
+	}
+
+	if a.quiet {
+		for _, s := range statuses {
+			run, result := "", ""
+			if s.last != nil {
+				run, result = strconv.Itoa(s.last.Owner.ID), s.last.Result
+			}
+			a.resultf("%d\t%s\t%s\t%s\t%s\t%s\n", s.resource.ID, s.resource.Name, s.resource.Type, s.health, run, result)
+		}
+		return nil
+	}
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "RESOURCE\tTYPE\tTARGET\tHEALTH\tLAST DEPLOYMENT")
+	for _, s := range statuses {
+		last := "-"
+		if s.last != nil {
+			last = fmt.Sprintf("run %d (%s %s), %s, %s", s.last.Owner.ID, s.last.Definition.Name, s.last.Owner.Name,
+				orDash(s.last.Result), a.display.time(s.last.FinishTime, time.DateTime))
+		}
+		kind := s.resource.Type
+		if kind == client.ResourceVirtualMachine {
+			kind = "vm"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", s.resource.Name, kind, s.target, s.health, last)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"fomo/internal/client"
+	"fomo/internal/credentials"
+)
+
+// Exit codes are part of fomo's public contract: scripts branch on them, so
+// existing values must never change meaning. Append new codes at the end.
+const (
+	exitOK             = 0 // command succeeded
+	exitError          = 1 // unexpected or unclassified error
+	exitPipelineFailed = 2 // a pipeline run finished unsuccessfully
+	exitAuth           = 3 // missing or rejected credentials
+	exitNotFound       = 4 // organization, project, pipeline or run not found
+	exitThrottled      = 5 // Azure DevOps rate limited the request
+	exitUsage          = 6 // invalid command line
+	exitCanceled       = 7 // interrupted by the user or timed out
+	exitReadOnly       = 8 // a change was refused in safe mode
+)
+
+var (
+	errPipelineFailed = errors.New("pipeline run failed")
+	errMissingInput   = errors.New("missing required input")
+	errAborted        = errors.New("aborted")
+)
+
+// usageError marks errors caused by invalid command-line usage.
+type usageError struct{ msg string }
+
+func (e *usageError) Error() string { return e.msg }
+
+func newUsageError(msg string) error { return &usageError{msg: msg} }
+
+// exitCode maps an error returned by a command to its exit code.
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+
+	var usageErr *usageError
+	if errors.As(err, &usageErr) {
+		return exitUsage
+	}
+
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return exitAuth
+		case http.StatusNotFound:
+			return exitNotFound
+		case http.StatusTooManyRequests:
+			return exitThrottled
+		}
+	}
+
+	switch {
+	case errors.Is(err, credentials.ErrWrongPassphrase):
+		return exitAuth
+	case errors.Is(err, errPipelineFailed):
+		return exitPipelineFailed
+	case errors.Is(err, errMissingInput):
+		return exitUsage
+	case errors.Is(err, errReadOnly):
+		return exitReadOnly
+	case errors.Is(err, errAborted), errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return exitCanceled
+	}
+	return exitError
+}
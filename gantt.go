@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"fomo/internal/timeline"
+)
+
+func runRunsGantt(a *app, args []string) error {
+	fs := a.newFlagSet("runs gantt", "<run-id> [--out FILE.svg] [--width N]")
+	out := fs.String("out", "", "write the chart as SVG to this file instead")
+	width := fs.Int("width", 0, "chart width in characters (default: fit the terminal)")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return newUsageError("usage: fomo runs gantt <run-id> [--out FILE.svg] [--width N]")
+	}
+	runID, err := parseRunID(positional[0])
+	if err != nil {
+		return err
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	tl, err := c.GetTimeline(a.ctx, runID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch timeline of run %d: %w", runID, err)
+	}
+	bars := timeline.Bars(tl.Records)
+	if len(bars) == 0 {
+		return fmt.Errorf("run %d has no stages or jobs yet", runID)
+	}
+
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		if err := timeline.WriteSVG(f, fmt.Sprintf("Run %d", runID), bars); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write %s: %w", *out, err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to write %s: %w", *out, err)
+		}
+		a.infof("Wrote %s\n", *out)
+		return nil
+	}
+
+	if a.plain {
+		timeline.WritePlain(a.stdout, bars, a.display.exactDuration)
+		return nil
+	}
+	cols := *width
+	if cols <= 0 {
+		cols = chartWidth(bars)
+	}
+	timeline.WriteText(a.stdout, bars, cols, a.display.exactDuration)
+	return nil
+}
+
+// chartWidth fits the chart into $COLUMNS, leaving room for the labels and
+// durations.
+func chartWidth(bars []timeline.Bar) int {
+	columns, _ := strconv.Atoi(os.Getenv("COLUMNS"))
+	if columns <= 0 {
+		columns = 100
+	}
+	label := 0
+	for _, b := range bars {
+		label = max(label, len(b.Name)+2)
+	}
+	return max(columns-min(label, 34)-24, 20)
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"fomo/internal/client"
+	"fomo/internal/redact"
+	"fomo/internal/yaml"
+)
+
+// Kinds of the nodes of a dependency graph.
+const (
+	nodePipeline    = "pipeline"
+	nodeRepository  = "repository"
+	nodeEnvironment = "environment"
+)
+
+// depGraph is the web of pipelines, the repositories that run them and the
+// environments they deploy to. Edges point downstream, from what runs a
+// pipeline to the pipeline, and from a pipeline to where it deploys.
+type depGraph struct {
+	nodes map[string]graphNode
+	edges map[graphEdge]bool
+}
+
+type graphNode struct {
+	kind  string
+	label string
+}
+
+type graphEdge struct {
+	from, to string
+	label    string
+	dashed   bool // the dependency does not trigger a run
+}
+
+func (g *depGraph) node(kind, label string) string {
+	id := kind + ":" + strings.ToLower(label)
+	if _, ok := g.nodes[id]; !ok {
+		g.nodes[id] = graphNode{kind: kind, label: label}
+	}
+	return id
+}
+
+func (g *depGraph) edge(from, to, label string, dashed bool) {
+	g.edges[graphEdge{from: from, to: to, label: label, dashed: dashed}] = true
+}
+
+// sortedNodes returns the node IDs by kind, then label.
+func (g *depGraph) sortedNodes() []string {
+	ids := make([]string, 0, len(g.nodes))
+	for id := range g.nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// sortedEdges returns the edges in a stable order.
+func (g *depGraph) sortedEdges() []graphEdge {
+	edges := make([]graphEdge, 0, len(g.edges))
+	for e := range g.edges {
+		edges = append(edges, e)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].from != edges[j].from {
+			return edges[i].from < edges[j].from
+		}
+		if edges[i].to != edges[j].to {
+			return edges[i].to < edges[j].to
+		}
+		return edges[i].label < edges[j].label
+	})
+	return edges
+}
+
+// yamlEnvironments finds the environments the deployment jobs of pipeline
+// YAML target, as `environment: prod`, `environment: prod.vm1` or
+// `environment: {name: prod}`. Environments set by expressions are skipped.
+func yamlEnvironments(v any) []string {
+	var environments []string
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if key != "environment" {
+				environments = append(environments, yamlEnvironments(value)...)
+				continue
+			}
+			name, _ := value.(string)
+			if m, ok := value.(map[string]any); ok {
+				name, _ = m["name"].(string)
+			}
+			// prod.vm1 is the resource vm1 of environment prod
+			name, _, _ = strings.Cut(name, ".")
+			if name != "" && !strings.Contains(name, "$") {
+				environments = append(environments, name)
+			}
+		}
+	case []any:
+		for _, value := range v {
+			environments = append(environments, yamlEnvironments(value)...)
+		}
+	}
+	return environments
+}
+
+// addPipeline adds a pipeline, its source repository, resources, triggers
+// and environments to the graph. byName and byID find the pipelines of the
+// project, for pipeline resources and classic build completion triggers.
+func (g *depGraph) addPipeline(ctx context.Context, c *client.Client, d client.Definition, byName map[string]client.Definition, byID map[int]client.Definition) error {
+	name := definitionPath(d.DefinitionReference)
+	self := g.node(nodePipeline, name)
+	definition, err := c.GetDefinitionRaw(ctx, d.ID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch pipeline %s: %w", name, err)
+	}
+	file, content, err := definitionYAML(ctx, c, d, definition)
+	if err != nil {
+		return err
+	}
+	var pipeline map[string]any
+	if file != "" {
+		if err := yaml.Unmarshal([]byte(content), &pipeline); err != nil {
+			return fmt.Errorf("could not read the resources of %s in %s: %w", name, file, err)
+		}
+	}
+
+	if d.Repository != nil {
+		repo := d.Repository.Name
+		if d.Repository.Type == client.RepositoryTypeAzureRepos {
+			repo = qualifiedRepo(repo, c.Project)
+		}
+		override, overridden := settingsTrigger(definition)
+		trigger := override
+		if !overridden && file != "" {
+			trigger = ciTrigger{}
+			if t, ok := pipeline["trigger"]; ok {
+				trigger = yamlTrigger(t)
+			}
+		}
+		g.edge(g.node(nodeRepository, repo), self, "source", trigger.disabled)
+	}
+
+	// Classic build completion triggers
+	triggers, _ := definition["triggers"].([]any)
+	for _, t := range triggers {
+		trigger, _ := t.(map[string]any)
+		if trigger["triggerType"] != "buildCompletion" {
+			continue
+		}
+		upstream, _ := trigger["definition"].(map[string]any)
+		id, _ := upstream["id"].(float64)
+		if u, ok := byID[int(id)]; ok {
+			g.edge(g.node(nodePipeline, definitionPath(u.DefinitionReference)), self, "completion", false)
+		}
+	}
+
+	resources, _ := pipeline["resources"].(map[string]any)
+	repositories, _ := resources["repositories"].([]any)
+	for _, r := range repositories {
+		resource, _ := r.(map[string]any)
+		repo, _ := resource["name"].(string)
+		if repo == "" {
+			continue
+		}
+		if t, _ := resource["type"].(string); t == "git" {
+			repo = qualifiedRepo(repo, c.Project)
+		}
+		trigger, ok := resource["trigger"]
+		g.edge(g.node(nodeRepository, repo), self, "resource", !ok || yamlTrigger(trigger).disabled)
+	}
+	pipelines, _ := resources["pipelines"].([]any)
+	for _, p := range pipelines {
+		resource, _ := p.(map[string]any)
+		source, _ := resource["source"].(string)
+		if source == "" {
+			continue
+		}
+		// Sources may name a pipeline with its folder, as in Team\build
+		label := strings.ReplaceAll(strings.Trim(source, `\/`), `\`, "/")
+		if project, _ := resource["project"].(string); project != "" && !strings.EqualFold(project, c.Project) {
+			label = project + "/" + label
+		} else if u, ok := byName[strings.ToLower(label)]; ok {
+			label = definitionPath(u.DefinitionReference)
+		}
+		trigger, ok := resource["trigger"]
+		g.edge(g.node(nodePipeline, label), self, "resource", !ok || yamlTrigger(trigger).disabled)
+	}
+
+	for _, env := range yamlEnvironments(pipeline) {
+		g.edge(self, g.node(nodeEnvironment, env), "deploys", false)
+	}
+	return nil
+}
+
+// dotQuote quotes a string for Graphviz.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func writeDOT(w io.Writer, g *depGraph) error {
+	shapes := map[string]string{nodePipeline: "box", nodeRepository: "cylinder", nodeEnvironment: "hexagon"}
+	var b strings.Builder
+	b.WriteString("digraph fomo {\n\trankdir=LR;\n\tnode [fontname=\"Helvetica\"];\n")
+	for _, id := range g.sortedNodes() {
+		n := g.nodes[id]
+		fmt.Fprintf(&b, "\t%s [label=%s, shape=%s];\n", dotQuote(id), dotQuote(n.label), shapes[n.kind])
+	}
+	for _, e := range g.sortedEdges() {
+		style := ""
+		if e.dashed {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(&b, "\t%s -> %s [label=%s%s];\n", dotQuote(e.from), dotQuote(e.to), dotQuote(e.label), style)
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeMermaid(w io.Writer, g *depGraph) error {
+	// Mermaid IDs cannot hold most punctuation, so number the nodes
+	ids := map[string]string{}
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for i, id := range g.sortedNodes() {
+		n := g.nodes[id]
+		ids[id] = fmt.Sprintf("n%d", i+1)
+		label := `"` + strings.ReplaceAll(n.label, `"`, "#quot;") + `"`
+		switch n.kind {
+		case nodeRepository:
+			fmt.Fprintf(&b, "    %s[(%s)]\n", ids[id], label)
+		case nodeEnvironment:
+			fmt.Fprintf(&b, "    %s{{%s}}\n", ids[id], label)
+		default:
+			fmt.Fprintf(&b, "    %s[%s]\n", ids[id], label)
+		}
+	}
+	for _, e := range g.sortedEdges() {
+		arrow := "-->"
+		if e.dashed {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&b, "    %s %s|%s| %s\n", ids[e.from], arrow, e.label, ids[e.to])
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func runGraph(a *app, args []string) error {
+	fs := a.newFlagSet("graph", "[--format dot|mermaid] [--out FILE]")
+	format := fs.String("format", "", "dot or mermaid (default: from the --out extension, or dot)")
+	out := fs.String("out", "-", "file to write, or - for stdout")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return newUsageError("usage: fomo graph [--format dot|mermaid] [--out FILE]")
+	}
+	if *format == "" {
+		*format = "dot"
+		switch strings.ToLower(filepath.Ext(*out)) {
+		case ".mmd", ".mermaid", ".md":
+			*format = "mermaid"
+		}
+	}
+	write := writeDOT
+	switch *format {
+	case "dot":
+	case "mermaid":
+		write = writeMermaid
+	default:
+		return newUsageError("--format must be dot or mermaid")
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	definitions, err := c.ListDefinitionDetails(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch pipelines: %w", err)
+	}
+	byName := map[string]client.Definition{}
+	byID := map[int]client.Definition{}
+	for _, d := range definitions {
+		byID[d.ID] = d
+		byName[strings.ToLower(d.Name)] = d
+		byName[strings.ToLower(definitionPath(d.DefinitionReference))] = d
+	}
+
+	g := &depGraph{nodes: map[string]graphNode{}, edges: map[graphEdge]bool{}}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 8)
+	for _, d := range definitions {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			// Each pipeline reads its definition and YAML before taking
+			// the lock, so only adding to the graph is serialized
+			pipeline := &depGraph{nodes: map[string]graphNode{}, edges: map[graphEdge]bool{}}
+			if err := pipeline.addPipeline(ctx, c, d, byName, byID); err != nil {
+				a.warnf("Warning: %v\n", err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for id, n := range pipeline.nodes {
+				if _, ok := g.nodes[id]; !ok {
+					g.nodes[id] = n
+				}
+			}
+			for e := range pipeline.edges {
+				g.edges[e] = true
+			}
+		}()
+	}
+	wg.Wait()
+
+	if *out == "-" {
+		return write(redact.Unwrap(a.stdout), g)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return fmt.Errorf("failed to write the graph: %w", err)
+	}
+	if err := write(f, g); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write the graph: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write the graph: %w", err)
+	}
+	a.infof("Wrote a graph of %d pipelines, %d nodes and %d edges to %s\n", len(definitions), len(g.nodes), len(g.edges), *out)
+	return nil
+}
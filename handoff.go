@@ -0,0 +1,241 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"fomo/internal/client"
+	"fomo/internal/watch"
+)
+
+const (
+	// handoffLookback is how far before the shift runs are read, to find
+	// when pipelines started failing and how long their runs usually take.
+	handoffLookback = 7 * 24 * time.Hour
+	// handoffSlowdown is how much longer than usual a run must take to be
+	// worth mentioning, as a factor of the median.
+	handoffSlowdown = 1.5
+)
+
+// handoffItem is one line of a handoff section.
+type handoffItem struct {
+	text string
+	url  string
+}
+
+// handoffSection is a titled list of a handoff summary.
+type handoffSection struct {
+	key   string // section name in quiet output
+	title string
+	items []handoffItem
+}
+
+func runHandoff(a *app, args []string) error {
+	fs := a.newFlagSet("handoff", "[<pipeline>...] [--since 8h] [--format text|markdown]")
+	since := fs.String("since", "8h", "length of the shift to summarize, e.g. 8h or 1d")
+	format := fs.String("format", "text", "text, or markdown with links")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	age, err := parseAge(*since)
+	if err != nil {
+		return newUsageError(err.Error())
+	}
+	if *format != "text" && *format != "markdown" {
+		return newUsageError(fmt.Sprintf("invalid --format %q: use text or markdown", *format))
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	var definitions []int
+	for _, name := range positional {
+		p, err := resolvePipeline(ctx, c, name)
+		if err != nil {
+			return err
+		}
+		definitions = append(definitions, p.ID)
+	}
+
+	now := time.Now()
+	start := now.Add(-age)
+	builds, err := c.ListBuilds(ctx, client.BuildCriteria{MinTime: start.Add(-handoffLookback), Definitions: definitions, Top: 1000})
+	if err != nil {
+		return fmt.Errorf("failed to fetch runs: %w", err)
+	}
+	approvals, err := c.ListPendingApprovals(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch approvals: %w", err)
+	}
+
+	sections := handoffRuns(a, builds, start)
+	pending := handoffSection{key: "approval", title: "Pending approvals"}
+	slices.SortFunc(approvals, func(x, y client.Approval) int { return x.CreatedOn.Compare(y.CreatedOn) })
+	for _, approval := range approvals {
+		if len(definitions) > 0 && !slices.Contains(definitions, approval.Pipeline.Owner.ID) {
+			continue
+		}
+		item := handoffItem{text: fmt.Sprintf("%s %s, waiting for %s", approval.Pipeline.Name, approval.Pipeline.Owner.Name, a.display.duration(now.Sub(approval.CreatedOn)))}
+		if approval.Pipeline.Owner.Links.Web != nil {
+			item.url = approval.Pipeline.Owner.Links.Web.Href
+		}
+		pending.items = append(pending.items, item)
+	}
+	sections = slices.Insert(sections, 2, pending)
+
+	if a.quiet {
+		for _, s := range sections {
+			for _, item := range s.items {
+				a.resultf("%s\t%s\t%s\n", s.key, item.text, orDash(item.url))
+			}
+		}
+		return nil
+	}
+	printHandoff(a, sections, *format == "markdown", c.Project, start, now)
+	return nil
+}
+
+// handoffRuns sorts the runs of a shift into pipelines still failing,
+// pipelines that failed and recovered, and runs that took unusually long.
+// Pull request validation runs are left out: they fail for the author, not
+// for whoever is on call.
+func handoffRuns(a *app, builds []client.Build, start time.Time) []handoffSection {
+	type key struct {
+		pipeline int
+		branch   string
+	}
+	byKey := map[key][]client.Build{} // newest first, as listed
+	var keys []key
+	for _, b := range builds {
+		if b.Reason == "pullRequest" {
+			continue
+		}
+		k := key{b.Definition.ID, b.SourceBranch}
+		if _, ok := byKey[k]; !ok {
+			keys = append(keys, k)
+		}
+		byKey[k] = append(byKey[k], b)
+	}
+
+	failing := handoffSection{key: "failing", title: "Still failing"}
+	recovered := handoffSection{key: "recovered", title: "Failed and recovered"}
+	for _, k := range keys {
+		runs := slices.DeleteFunc(slices.Clone(byKey[k]), func(b client.Build) bool { return b.Status != client.RunStateCompleted })
+		if len(runs) == 0 {
+			continue
+		}
+		name := fmt.Sprintf("%s on %s", runs[0].Definition.Name, watch.ShortBranch(runs[0].SourceBranch))
+
+		if runs[0].Result == client.RunResultFailed {
+			// The streak of failures up to the latest run
+			streak := 1
+			for streak < len(runs) && runs[streak].Result == client.RunResultFailed {
+				streak++
+			}
+			first := runs[streak-1]
+			item := handoffItem{text: fmt.Sprintf("%s: failing for %s, %s, latest %s", name, a.display.duration(time.Since(buildTime(first))), plural(streak, "failed run"), runs[0].BuildNumber)}
+			if runs[0].Links.Web != nil {
+				item.url = runs[0].Links.Web.Href
+			}
+			failing.items = append(failing.items, item)
+			continue
+		}
+
+		var failed []string
+		for _, b := range runs {
+			if b.Result == client.RunResultFailed && !buildTime(b).Before(start) {
+				failed = append(failed, b.BuildNumber)
+			}
+		}
+		if len(failed) > 0 {
+			slices.Reverse(failed)
+			recovered.items = append(recovered.items, handoffItem{text: fmt.Sprintf("%s: %s (%s), green again with %s", name, plural(len(failed), "failed run"), strings.Join(failed, ", "), runs[0].BuildNumber)})
+		}
+	}
+
+	unusual := handoffSection{key: "duration", title: "Unusual durations"}
+	byPipeline := map[int][]time.Duration{} // usual durations, from before the shift
+	for _, b := range builds {
+		if b.Result == client.RunResultSucceeded && b.StartTime != nil && b.FinishTime != nil && b.FinishTime.Before(start) {
+			byPipeline[b.Definition.ID] = append(byPipeline[b.Definition.ID], b.FinishTime.Sub(*b.StartTime))
+		}
+	}
+	for _, b := range builds {
+		usual := byPipeline[b.Definition.ID]
+		if b.Reason == "pullRequest" || b.StartTime == nil || b.FinishTime == nil || b.FinishTime.Before(start) || len(usual) < 3 {
+			continue
+		}
+		took, typical := b.FinishTime.Sub(*b.StartTime), median(usual)
+		if float64(took) < handoffSlowdown*float64(typical) || took-typical < time.Minute {
+			continue
+		}
+		item := handoffItem{text: fmt.Sprintf("%s %s on %s took %s, usually %s", b.Definition.Name, b.BuildNumber, watch.ShortBranch(b.SourceBranch), a.display.duration(took), a.display.duration(typical))}
+		if b.Links.Web != nil {
+			item.url = b.Links.Web.Href
+		}
+		unusual.items = append(unusual.items, item)
+	}
+
+	for _, s := range []*handoffSection{&failing, &recovered} {
+		slices.SortStableFunc(s.items, func(x, y handoffItem) int { return cmp.Compare(x.text, y.text) })
+	}
+	return []handoffSection{failing, recovered, unusual}
+}
+
+// buildTime is when a run finished, or was queued if it has not.
+func buildTime(b client.Build) time.Time {
+	if b.FinishTime != nil {
+		return *b.FinishTime
+	}
+	return b.QueueTime
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// printHandoff prints a handoff summary as text to paste into a chat, or as
+// Markdown for a handoff document.
+func printHandoff(a *app, sections []handoffSection, markdown bool, project string, start, end time.Time) {
+	const layout = "Jan 2 15:04"
+	title := fmt.Sprintf("Handoff for %s, %s to %s", project, a.display.time(start, layout), a.display.time(end, layout))
+	if markdown {
+		a.resultf("## %s\n", title)
+	} else {
+		a.resultf("%s\n", title)
+	}
+	quiet := true
+	for _, s := range sections {
+		if len(s.items) == 0 {
+			continue
+		}
+		quiet = false
+		if markdown {
+			a.resultf("\n### %s (%d)\n\n", s.title, len(s.items))
+		} else {
+			a.resultf("\n%s (%d)\n", s.title, len(s.items))
+		}
+		for _, item := range s.items {
+			switch {
+			case item.url == "":
+				a.resultf("- %s\n", item.text)
+			case markdown:
+				a.resultf("- [%s](%s)\n", item.text, item.url)
+			default:
+				a.resultf("- %s\n  %s\n", item.text, item.url)
+			}
+		}
+	}
+	if quiet {
+		a.resultf("\nA quiet shift: nothing failed, nothing is waiting for approval.\n")
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"fomo/internal/cache"
+	"fomo/internal/client"
+)
+
+// identityTTL is how long resolved users and groups are remembered. They
+// rarely change, and a stale display name only affects what is shown.
+const identityTTL = 24 * time.Hour
+
+// identities resolves the names people type, such as "me", "@jane" or an
+// email address, to users and groups, and identity IDs back to display
+// names, so commands neither take nor show raw GUIDs. Answers are cached
+// per organization.
+type identities struct {
+	c     *client.Client
+	store *cache.Cache // nil when there is no cache directory
+	me    *client.Identity
+}
+
+func newIdentities(c *client.Client) *identities {
+	store, _ := cache.Open("identities")
+	return &identities{c: c, store: store}
+}
+
+func (ids *identities) key(kind, value string) string {
+	return strings.Join([]string{ids.c.BaseURL, ids.c.Organization, kind, strings.ToLower(value)}, "|")
+}
+
+func (ids *identities) cached(key string) (*client.Identity, bool) {
+	if ids.store == nil {
+		return nil, false
+	}
+	var id client.Identity
+	stored, ok := ids.store.Get(key, &id)
+	if !ok || time.Since(stored) > identityTTL {
+		return nil, false
+	}
+	return &id, true
+}
+
+// remember caches an identity under keys; the cache is only a shortcut, so
+// failing to write it is not an error.
+func (ids *identities) remember(id *client.Identity, keys ...string) {
+	if ids.store == nil {
+		return
+	}
+	for _, key := range keys {
+		ids.store.Put(key, id)
+	}
+}
+
+// resolve finds the user or group a name refers to: "me" for the owner of
+// the PAT, or an email address, account name or display name, which may be
+// written as an @mention.
+func (ids *identities) resolve(ctx context.Context, name string) (*client.Identity, error) {
+	name = strings.TrimPrefix(strings.TrimSpace(name), "@")
+	if strings.EqualFold(name, "me") {
+		if ids.me == nil {
+			me, err := ids.c.Me(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve current user: %w", err)
+			}
+			ids.me = me
+		}
+		return ids.me, nil
+	}
+
+	key := ids.key("name", name)
+	if id, ok := ids.cached(key); ok {
+		return id, nil
+	}
+	found, err := ids.c.FindIdentities(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up %s: %w", name, err)
+	}
+	var id *client.Identity
+	for i, candidate := range found {
+		if strings.EqualFold(candidate.UniqueName, name) || strings.EqualFold(candidate.DisplayName, name) {
+			id = &found[i]
+			break
+		}
+	}
+	switch {
+	case id != nil:
+	case len(found) == 0:
+		return nil, fmt.Errorf("no user or group matches %q", name)
+	case len(found) == 1:
+		id = &found[0]
+	default:
+		var matches []string
+		for _, candidate := range found {
+			matches = append(matches, fmt.Sprintf("%s <%s>", candidate.DisplayName, candidate.UniqueName))
+		}
+		return nil, fmt.Errorf("%q matches several users or groups (%s); use an email address", name, strings.Join(matches, ", "))
+	}
+	ids.remember(id, key, ids.key("id", id.ID))
+	return id, nil
+}
+
+// names returns the display names of identity IDs. IDs that cannot be
+// looked up are named by themselves: a GUID is better than nothing.
+func (ids *identities) names(ctx context.Context, idList []string) map[string]string {
+	names := map[string]string{}
+	var missing []string
+	for _, id := range idList {
+		if cached, ok := ids.cached(ids.key("id", id)); ok {
+			names[id] = cached.DisplayName
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		found, _ := ids.c.GetIdentities(ctx, missing)
+		for i, identity := range found {
+			for _, id := range missing {
+				if strings.EqualFold(id, identity.ID) {
+					names[id] = identity.DisplayName
+				}
+			}
+			ids.remember(&found[i], ids.key("id", identity.ID))
+		}
+	}
+	for _, id := range idList {
+		if names[id] == "" {
+			names[id] = id
+		}
+	}
+	return names
+}
+
+// byDescriptor returns the users and groups behind security descriptors.
+// Descriptors that cannot be looked up are named by themselves.
+func (ids *identities) byDescriptor(ctx context.Context, descriptors []string) map[string]client.Identity {
+	found := map[string]client.Identity{}
+	var missing []string
+	for _, descriptor := range descriptors {
+		if cached, ok := ids.cached(ids.key("descriptor", descriptor)); ok {
+			found[descriptor] = *cached
+		} else {
+			missing = append(missing, descriptor)
+		}
+	}
+	if len(missing) > 0 {
+		looked, _ := ids.c.GetIdentitiesByDescriptor(ctx, missing)
+		for descriptor, identity := range looked {
+			found[descriptor] = identity
+			ids.remember(&identity, ids.key("descriptor", descriptor), ids.key("id", identity.ID))
+		}
+	}
+	for _, descriptor := range descriptors {
+		if _, ok := found[descriptor]; !ok {
+			found[descriptor] = client.Identity{DisplayName: descriptor}
+		}
+	}
+	return found
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"fomo/internal/client"
+	"fomo/internal/watch"
+	"fomo/internal/yaml"
+)
+
+// ciTrigger is when a push to a repository runs a pipeline: the branch and
+// path filters of a trigger in its YAML, or of the trigger set in the
+// pipeline settings, which overrides the YAML.
+type ciTrigger struct {
+	disabled        bool
+	branches        []string // empty for every branch
+	excludeBranches []string
+	paths           []string // empty for every path
+	excludePaths    []string
+}
+
+// yamlTrigger reads a trigger of pipeline YAML: none, a list of branches,
+// or include and exclude filters of branches and paths.
+func yamlTrigger(v any) ciTrigger {
+	var t ciTrigger
+	switch v := v.(type) {
+	case string:
+		t.disabled = strings.EqualFold(v, "none")
+	case bool:
+		t.disabled = !v
+	case []any:
+		t.branches = yamlStrings(v)
+	case map[string]any:
+		branches, _ := v["branches"].(map[string]any)
+		t.branches, t.excludeBranches = yamlStrings(branches["include"]), yamlStrings(branches["exclude"])
+		paths, _ := v["paths"].(map[string]any)
+		t.paths, t.excludePaths = yamlStrings(paths["include"]), yamlStrings(paths["exclude"])
+	}
+	return t
+}
+
+// settingsTrigger reads the continuous integration trigger of a pipeline's
+// settings, whose filters are "+refs/heads/main" or "-/docs". ok is false
+// when the settings leave the trigger to the YAML.
+func settingsTrigger(definition map[string]any) (t ciTrigger, ok bool) {
+	triggers, _ := definition["triggers"].([]any)
+	for _, v := range triggers {
+		trigger, _ := v.(map[string]any)
+		if trigger["triggerType"] != "continuousIntegration" {
+			continue
+		}
+		// 2 is "use the trigger of the YAML file"
+		if source, _ := trigger["settingsSourceType"].(float64); source == 2 {
+			return ciTrigger{}, false
+		}
+		for _, filters := range []struct {
+			key              string
+			include, exclude *[]string
+		}{{"branchFilters", &t.branches, &t.excludeBranches}, {"pathFilters", &t.paths, &t.excludePaths}} {
+			for _, f := range yamlStrings(trigger[filters.key]) {
+				// Path filters start with a slash, unlike those of YAML
+				if rest, ok := strings.CutPrefix(f, "-"); ok {
+					*filters.exclude = append(*filters.exclude, strings.TrimPrefix(rest, "/"))
+				} else {
+					*filters.include = append(*filters.include, strings.TrimPrefix(strings.TrimPrefix(f, "+"), "/"))
+				}
+			}
+		}
+		return t, true
+	}
+	return ciTrigger{disabled: true}, true
+}
+
+// yamlStrings returns the strings of a YAML sequence, or of a single value.
+func yamlStrings(v any) []string {
+	var values []string
+	switch v := v.(type) {
+	case string:
+		values = append(values, v)
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	return values
+}
+
+// branchFilterMatches reports whether a branch filter, such as "main" or
+// "releases/*", matches branch.
+func branchFilterMatches(filter, branch string) bool {
+	pattern := regexp.QuoteMeta(watch.ShortBranch(filter))
+	pattern = strings.ReplaceAll(pattern, `\*`, ".*")
+	matched, _ := regexp.MatchString("(?i)^"+pattern+"$", watch.ShortBranch(branch))
+	return matched
+}
+
+// pathFilterMatches reports whether a change under dir can match a path
+// filter: the filter matches dir, or points inside it.
+func pathFilterMatches(filter, dir string) bool {
+	g, err := newPathGlob(filter)
+	if err != nil {
+		return false
+	}
+	filter = strings.Trim(filter, "/")
+	return g.match(dir) || strings.HasPrefix(filter, dir+"/")
+}
+
+// skips explains why a push of branch touching dirs would not run the
+// pipeline, or returns "" if it would. Without a branch or dirs, only
+// a disabled trigger skips.
+func (t ciTrigger) skips(branch string, dirs []string) string {
+	if t.disabled {
+		return "no trigger"
+	}
+	if branch != "" {
+		included := len(t.branches) == 0 || slices.ContainsFunc(t.branches, func(f string) bool { return branchFilterMatches(f, branch) })
+		if !included || slices.ContainsFunc(t.excludeBranches, func(f string) bool { return branchFilterMatches(f, branch) }) {
+			return fmt.Sprintf("branch %s is not in its trigger", watch.ShortBranch(branch))
+		}
+	}
+	if len(dirs) == 0 {
+		return ""
+	}
+	for _, dir := range dirs {
+		dir = strings.Trim(dir, "/")
+		if len(t.paths) > 0 && !slices.ContainsFunc(t.paths, func(f string) bool { return pathFilterMatches(f, dir) }) {
+			continue
+		}
+		excluded := false
+		for _, f := range t.excludePaths {
+			if g, err := newPathGlob(f); err == nil && g.match(dir) {
+				excluded = true
+			}
+		}
+		if !excluded {
+			return ""
+		}
+	}
+	return fmt.Sprintf("its path filters leave out %s", strings.Join(dirs, ", "))
+}
+
+// describe summarizes the filters of a trigger.
+func (t ciTrigger) describe() string {
+	if t.disabled {
+		return "no trigger"
+	}
+	var parts []string
+	filters := func(noun string, include, exclude []string) {
+		if len(include) == 0 && len(exclude) == 0 {
+			return
+		}
+		all := slices.Clone(include)
+		for _, f := range exclude {
+			all = append(all, "!"+f)
+		}
+		parts = append(parts, noun+" "+strings.Join(all, ", "))
+	}
+	var branches []string
+	for _, b := range t.branches {
+		branches = append(branches, watch.ShortBranch(b))
+	}
+	filters("branches", branches, t.excludeBranches)
+	filters("paths", t.paths, t.excludePaths)
+	if len(parts) == 0 {
+		return "every push"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// impactedPipeline is a pipeline that builds or uses the repository.
+type impactedPipeline struct {
+	pipeline string
+	role     string // "source", or "resource ALIAS"
+	trigger  ciTrigger
+	skipped  string // why the push would not run it; empty if it would
+}
+
+// qualifiedRepo prefixes an Azure Repos repository name with the project
+// when it has none.
+func qualifiedRepo(name, project string) string {
+	if strings.Contains(name, "/") {
+		return name
+	}
+	return project + "/" + name
+}
+
+// pipelineImpact finds whether a pipeline has repo as its source or as a
+// repository resource, and what pushes to it run the pipeline.
+func pipelineImpact(ctx context.Context, c *client.Client, d client.Definition, repo, branch string, dirs []string) ([]impactedPipeline, error) {
+	name := definitionPath(d.DefinitionReference)
+	source := d.Repository != nil && templateRepoMatches(qualifiedRepo(d.Repository.Name, c.Project), repo)
+	definition, err := c.GetDefinitionRaw(ctx, d.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pipeline %s: %w", name, err)
+	}
+	override, overridden := settingsTrigger(definition)
+	file, content, err := definitionYAML(ctx, c, d, definition)
+	if err != nil {
+		return nil, err
+	}
+	var pipeline map[string]any
+	if file != "" {
+		if err := yaml.Unmarshal([]byte(content), &pipeline); err != nil {
+			return nil, fmt.Errorf("could not read the triggers of %s in %s: %w", name, file, err)
+		}
+	}
+
+	var impacts []impactedPipeline
+	add := func(role string, t ciTrigger) {
+		impacts = append(impacts, impactedPipeline{pipeline: name, role: role, trigger: t, skipped: t.skips(branch, dirs)})
+	}
+	if source {
+		switch {
+		case overridden || file == "":
+			add("source", override)
+		default:
+			// Without a trigger, pushes to every branch run a YAML pipeline
+			trigger, ok := pipeline["trigger"]
+			if !ok {
+				add("source", ciTrigger{})
+			} else {
+				add("source", yamlTrigger(trigger))
+			}
+		}
+	}
+	resources, _ := pipeline["resources"].(map[string]any)
+	repositories, _ := resources["repositories"].([]any)
+	for _, r := range repositories {
+		resource, _ := r.(map[string]any)
+		alias, _ := resource["repository"].(string)
+		resourceName, _ := resource["name"].(string)
+		if t, _ := resource["type"].(string); t == "git" {
+			resourceName = qualifiedRepo(resourceName, c.Project)
+		}
+		if alias == "" || !templateRepoMatches(resourceName, repo) {
+			continue
+		}
+		// Unlike the source, a repository resource only runs the pipeline
+		// when it has a trigger
+		trigger, ok := resource["trigger"]
+		if !ok {
+			add("resource "+alias, ciTrigger{disabled: true})
+		} else {
+			add("resource "+alias, yamlTrigger(trigger))
+		}
+	}
+	return impacts, nil
+}
+
+func runImpact(a *app, args []string) error {
+	const usage = "usage: fomo impact --repo NAME [--path DIR]... [--branch BRANCH]"
+	fs := a.newFlagSet("impact", "--repo NAME [--path DIR]... [--branch BRANCH]")
+	repo := fs.String("repo", "", "repository to push to (repo or project/repo)")
+	var dirs tagFlags
+	fs.Var(&dirs, "path", "only count pipelines whose path filters include this file or directory (repeatable)")
+	branch := fs.String("branch", "", "only count pipelines whose branch filters include this branch")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 || *repo == "" {
+		return newUsageError(usage)
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	definitions, err := c.ListDefinitionDetails(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch pipelines: %w", err)
+	}
+	results := make([][]impactedPipeline, len(definitions))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 8)
+	for i, d := range definitions {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			impacts, err := pipelineImpact(ctx, c, d, *repo, *branch, dirs)
+			if err != nil {
+				a.warnf("Warning: %v\n", err)
+			}
+			results[i] = impacts
+		}()
+	}
+	wg.Wait()
+
+	var runs, others []impactedPipeline
+	for _, impacts := range results {
+		for _, impact := range impacts {
+			if impact.skipped == "" {
+				runs = append(runs, impact)
+			} else {
+				others = append(others, impact)
+			}
+		}
+	}
+	for _, list := range [][]impactedPipeline{runs, others} {
+		sort.SliceStable(list, func(i, j int) bool {
+			return strings.ToLower(list[i].pipeline) < strings.ToLower(list[j].pipeline)
+		})
+	}
+
+	if a.quiet {
+		for _, p := range runs {
+			a.resultf("%s\t%s\n", p.pipeline, p.role)
+		}
+		return nil
+	}
+	if len(runs)+len(others) == 0 {
+		a.infof("No pipeline builds %s or uses it as a resource.\n", *repo)
+		return nil
+	}
+	a.resultf("Pipelines a push to %s runs:\n", *repo)
+	if len(runs) == 0 {
+		a.resultf("  none\n")
+	}
+	for _, p := range runs {
+		a.resultf("  %s (%s): %s\n", p.pipeline, p.role, p.trigger.describe())
+	}
+	if len(others) > 0 {
+		a.resultf("\nPipelines using %s that it does not run:\n", *repo)
+		for _, p := range others {
+			a.resultf("  %s (%s): %s\n", p.pipeline, p.role, p.skipped)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fomo/internal/client"
+	"fomo/internal/prefetch"
+)
+
+// Projects with items on screen are refreshed on the fast schedule, the
+// others on the slow one.
+const (
+	inboxRefreshVisible = 30 * time.Second
+	inboxRefreshHidden  = 5 * time.Minute
+)
+
+// inboxItem is something waiting on the current user.
+type inboxItem struct {
+	kind    string // "approval" or "review"
+	project string
+	title   string
+	url     string
+	created time.Time
+
+	approval    *client.Approval
+	pullRequest *client.PullRequest
+}
+
+// key identifies an item across refreshes; approvals may have no URL.
+func (item inboxItem) key() string {
+	id := ""
+	switch {
+	case item.approval != nil:
+		id = item.approval.ID
+	case item.pullRequest != nil:
+		id = strconv.Itoa(item.pullRequest.ID)
+	}
+	return item.kind + "/" + item.project + "/" + id
+}
+
+func runInbox(a *app, args []string) error {
+	fs := a.newFlagSet("inbox", "[flags]")
+	thisProject := fs.Bool("this-project", false, "only look in the configured project instead of every project")
+	all := fs.Bool("all", false, "include pending approvals not assigned to you directly, e.g. via groups")
+	list := fs.Bool("list", false, "print the inbox and exit instead of prompting for actions")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	me, err := c.Me(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to resolve current user: %w", err)
+	}
+
+	projects := []string{c.Project}
+	if !*thisProject {
+		all, err := c.ListProjects(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list projects: %w", err)
+		}
+		projects = projects[:0]
+		for _, p := range all {
+			projects = append(projects, p.Name)
+		}
+	}
+
+	cache := &prefetch.Cache{TTL: inboxRefreshVisible}
+	items, _, err := fetchInbox(ctx, cache, c, me, projects, *all)
+	if err != nil {
+		return err
+	}
+
+	interactive := !*list && !a.quiet && isTerminal(os.Stdin)
+	printInbox(a, items)
+	if !interactive {
+		return nil
+	}
+
+	// Keep the inbox warm while waiting for input, so refreshing is instant
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cache.SetVisible(inboxProjects(items)...)
+	go cache.Run(ctx, inboxRefreshVisible, inboxRefreshHidden)
+	handled := map[string]bool{}
+
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Fprint(a.stderr, "\n[a]pprove N, [r]eject N, [o]pen N, re[f]resh, [q]uit> ")
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		action := fields[0]
+		// Accept both "a 2" and "a2"
+		if len(fields) == 1 && len(action) > 1 {
+			if _, err := strconv.Atoi(action[1:]); err == nil {
+				fields = []string{action[:1], action[1:]}
+				action = action[:1]
+			}
+		}
+
+		switch action {
+		case "q":
+			return nil
+		case "f":
+			fresh, updated, err := fetchInbox(ctx, cache, c, me, projects, *all)
+			if err != nil {
+				return err
+			}
+			items = items[:0]
+			for _, item := range fresh {
+				if !handled[item.key()] {
+					items = append(items, item)
+				}
+			}
+			cache.SetVisible(inboxProjects(items)...)
+			printInbox(a, items)
+			if age := time.Since(updated); age > 5*time.Second {
+				a.infof("(as of %s ago; updating in the background)\n", a.display.duration(age))
+			}
+			continue
+		case "a", "r", "o":
+		default:
+			fmt.Fprintf(a.stderr, "Unknown action %q\n", action)
+			continue
+		}
+
+		if len(fields) < 2 {
+			fmt.Fprintln(a.stderr, "Which item? e.g. a 1")
+			continue
+		}
+		n, err := strconv.Atoi(fields[1])
+		if err != nil || n < 1 || n > len(items) {
+			fmt.Fprintf(a.stderr, "No item %s\n", fields[1])
+			continue
+		}
+		item := items[n-1]
+
+		if action == "o" {
+			a.resultf("%s\n", item.url)
+			continue
+		}
+		if err := a.checkWrite("fomo inbox"); err != nil {
+			fmt.Fprintf(a.stderr, "Error: %v\n", err)
+			continue
+		}
+		if err := actOnInboxItem(ctx, c, me, item, action == "a"); err != nil {
+			fmt.Fprintf(a.stderr, "Error: %v\n", err)
+			continue
+		}
+		verb := "Approved"
+		if action == "r" {
+			verb = "Rejected"
+		}
+		a.infof("%s: %s\n", verb, item.title)
+		handled[item.key()] = true
+		items = append(items[:n-1], items[n:]...)
+		printInbox(a, items)
+	}
+}
+
+// inboxProjects returns the projects that have items on screen.
+func inboxProjects(items []inboxItem) []string {
+	var projects []string
+	for _, item := range items {
+		if !slices.Contains(projects, item.project) {
+			projects = append(projects, item.project)
+		}
+	}
+	return projects
+}
+
+// fetchInbox collects pending approvals and review requests across projects
+// concurrently.
+func fetchInbox(ctx context.Context, cache *prefetch.Cache, c *client.Client, me *client.Identity, projects []string, all bool) ([]inboxItem, time.Time, error) {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		items   []inboxItem
+		errs    []error
+		updated = time.Now()
+		sem     = make(chan struct{}, 8)
+	)
+	for _, project := range projects {
+		wg.Add(1)
+		go func(pc *client.Client) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			found, fetched, err := prefetch.Get(ctx, cache, pc.Project, func(ctx context.Context) ([]inboxItem, error) {
+				return fetchProjectInbox(ctx, pc, me, all)
+			})
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", pc.Project, err))
+				return
+			}
+			items = append(items, found...)
+			if fetched.Before(updated) {
+				updated = fetched
+			}
+		}(c.WithProject(project))
+	}
+	wg.Wait()
+
+	if len(errs) > 0 && len(items) == 0 {
+		return nil, updated, errs[0]
+	}
+	// Oldest first: those have been blocking the longest
+	sort.Slice(items, func(i, j int) bool { return items[i].created.Before(items[j].created) })
+	return items, updated, nil
+}
+
+func fetchProjectInbox(ctx context.Context, c *client.Client, me *client.Identity, all bool) ([]inboxItem, error) {
+	var items []inboxItem
+
+	approvals, err := c.ListPendingApprovals(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i, approval := range approvals {
+		if !all && !approval.AssignedTo(me.ID) {
+			continue
+		}
+		item := inboxItem{
+			kind:     "approval",
+			project:  c.Project,
+			title:    fmt.Sprintf("%s #%s", approval.Pipeline.Name, approval.Pipeline.Owner.Name),
+			created:  approval.CreatedOn,
+			approval: &approvals[i],
+		}
+		if approval.Pipeline.Owner.Links.Web != nil {
+			item.url = approval.Pipeline.Owner.Links.Web.Href
+		}
+		items = append(items, item)
+	}
+
+	prs, err := c.ListPullRequests(ctx, client.PullRequestCriteria{Status: "active", ReviewerID: me.ID})
+	if err != nil {
+		return nil, err
+	}
+	for i, pr := range prs {
+		if vote, _ := pr.VoteOf(me.ID); vote != client.VoteNone || pr.IsDraft {
+			continue
+		}
+		items = append(items, inboxItem{
+			kind:        "review",
+			project:     c.Project,
+			title:       fmt.Sprintf("!%d %s (%s)", pr.ID, pr.Title, pr.CreatedBy.DisplayName),
+			url:         pr.WebURL(),
+			created:     pr.CreationDate,
+			pullRequest: &prs[i],
+		})
+	}
+	return items, nil
+}
+
+func printInbox(a *app, items []inboxItem) {
+	if len(items) == 0 {
+		a.infof("Inbox zero: nothing is waiting on you.\n")
+		return
+	}
+	for i, item := range items {
+		if a.quiet {
+			a.resultf("%s\t%s\t%s\n", item.kind, item.project, item.url)
+			continue
+		}
+		a.resultf("%3d  %-8s  %-16s  %-5s  %s\n", i+1, item.kind, item.project, a.display.duration(time.Since(item.created)), item.title)
+	}
+}
+
+func actOnInboxItem(ctx context.Context, c *client.Client, me *client.Identity, item inboxItem, approve bool) error {
+	pc := c.WithProject(item.project)
+	switch item.kind {
+	case "approval":
+		status := client.ApprovalApproved
+		if !approve {
+			status = client.ApprovalRejected
+		}
+		_, err := pc.UpdateApproval(ctx, item.approval.ID, status, "via fomo inbox")
+		return err
+	case "review":
+		vote := client.VoteApproved
+		if !approve {
+			vote = client.VoteRejected
+		}
+		return pc.VotePullRequest(ctx, item.pullRequest.Repository.ID, item.pullRequest.ID, me.ID, vote)
+	}
+	return fmt.Errorf("unknown inbox item kind %q", item.kind)
+}
+
+// isTerminal reports whether f is an interactive terminal. Other character
+// devices, such as /dev/null in CI, are not, so the platform is asked.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0 && isTTY(f)
+}
@@ -0,0 +1,159 @@
+// Package board renders the state of selected pipelines and environments
+// as a grid of large status tiles, for team dashboards on wall displays.
+package board
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"fomo/internal/yaml"
+)
+
+// Tile statuses.
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusRunning   = "running"
+	StatusCanceled  = "canceled"
+	StatusUnknown   = "unknown"
+)
+
+// Refresh limits. Boards run unattended for days, so they must not poll
+// faster than the API comfortably allows.
+const (
+	DefaultRefresh = time.Minute
+	MinRefresh     = 10 * time.Second
+)
+
+// Config is a board file.
+type Config struct {
+	Title   string        `yaml:"title"`
+	Refresh time.Duration `yaml:"refresh"`
+	Columns int           `yaml:"columns"` // 0 picks a roughly square grid
+	Tiles   []TileConfig  `yaml:"tiles"`
+}
+
+// TileConfig selects what one tile shows: the latest run of a pipeline,
+// optionally on one branch, or the latest deployment to an environment.
+type TileConfig struct {
+	Label       string `yaml:"label"`
+	Project     string `yaml:"project"` // defaults to the configured project
+	Pipeline    string `yaml:"pipeline"`
+	Branch      string `yaml:"branch"`
+	Environment string `yaml:"environment"`
+}
+
+// Name returns the tile's label, or what it shows if it has none.
+func (t TileConfig) Name() string {
+	switch {
+	case t.Label != "":
+		return t.Label
+	case t.Environment != "":
+		return t.Environment
+	case t.Branch != "":
+		return t.Pipeline + " (" + t.Branch + ")"
+	}
+	return t.Pipeline
+}
+
+// Load reads and validates a board file.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if len(cfg.Tiles) == 0 {
+		return nil, fmt.Errorf("%s has no tiles", path)
+	}
+	for i, t := range cfg.Tiles {
+		if (t.Pipeline == "") == (t.Environment == "") {
+			return nil, fmt.Errorf("%s: tile %d must set either pipeline or environment", path, i+1)
+		}
+		if t.Branch != "" && t.Environment != "" {
+			return nil, fmt.Errorf("%s: tile %d: branch only applies to pipelines", path, i+1)
+		}
+	}
+	if cfg.Columns < 0 {
+		return nil, fmt.Errorf("%s: columns must not be negative", path)
+	}
+	if cfg.Refresh == 0 {
+		cfg.Refresh = DefaultRefresh
+	}
+	cfg.Refresh = max(cfg.Refresh, MinRefresh)
+	return &cfg, nil
+}
+
+// Tile is the state shown on one tile.
+type Tile struct {
+	Label  string
+	Status string
+	// Detail names the run or deployment, e.g. "20240101.3 on main"
+	Detail string
+	// Since is when the run started or, once completed, finished
+	Since time.Time
+	// LastFailure is when the most recent failure finished; zero if none
+	// of the fetched runs failed
+	LastFailure time.Time
+	URL         string
+	Err         error
+}
+
+// Changed reports whether t shows something other than old, ignoring how
+// long ago things happened.
+func (t Tile) Changed(old Tile) bool {
+	errText := func(err error) string {
+		if err == nil {
+			return ""
+		}
+		return err.Error()
+	}
+	return t.Label != old.Label || t.Status != old.Status || t.Detail != old.Detail ||
+		!t.LastFailure.Equal(old.LastFailure) || errText(t.Err) != errText(old.Err)
+}
+
+// grid returns the number of columns and rows for n tiles.
+func grid(n, columns int) (int, int) {
+	if columns <= 0 {
+		columns = 1
+		for columns*columns < n {
+			columns++
+		}
+	}
+	columns = min(columns, n)
+	return columns, (n + columns - 1) / columns
+}
+
+// ago renders the time since t with a single unit, e.g. "3h ago".
+func ago(t, now time.Time) string {
+	d := max(now.Sub(t), 0)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+}
+
+// lines returns the text lines of a tile, most important first.
+func (t Tile) lines(now time.Time) []string {
+	if t.Err != nil {
+		return []string{t.Label, "ERROR", t.Err.Error()}
+	}
+	status := t.Status
+	if !t.Since.IsZero() {
+		status += " " + ago(t.Since, now)
+	}
+	failure := "no recent failures"
+	if !t.LastFailure.IsZero() {
+		failure = "last failure " + ago(t.LastFailure, now)
+	}
+	return []string{t.Label, status, t.Detail, failure}
+}
@@ -0,0 +1,209 @@
+package board
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ANSI colors of each status: background and foreground.
+var ansiColors = map[string][2]string{
+	StatusSucceeded: {"42", "30"},
+	StatusFailed:    {"41", "97"},
+	StatusRunning:   {"44", "97"},
+	StatusCanceled:  {"100", "97"},
+	StatusUnknown:   {"100", "97"},
+}
+
+// WriteANSI draws the board as a full-screen frame of width by height
+// terminal cells, overwriting the previous frame in place. Updated is when
+// the tiles were fetched; ages are shown relative to now.
+func WriteANSI(w io.Writer, title string, tiles []Tile, columns, width, height int, updated, now time.Time) error {
+	var b strings.Builder
+	b.WriteString("\033[H") // cursor home; the frame overwrites the last one
+
+	header := fmt.Sprintf(" %s", title)
+	clock := fmt.Sprintf("updated %s ", updated.Format("15:04:05"))
+	b.WriteString("\033[1m" + fit(header, width-len(clock)) + strings.Repeat(" ", max(width-len(clock)-utf8.RuneCountInString(header), 0)) + clock + "\033[0m\033[K\n")
+
+	cols, rows := grid(len(tiles), columns)
+	const gap = 1
+	tileWidth := max((width-gap*(cols+1))/cols, 8)
+	tileHeight := max((height-1-gap*rows)/rows, 4)
+
+	for row := 0; row < rows; row++ {
+		b.WriteString("\033[K\n")
+		for y := 0; y < tileHeight; y++ {
+			b.WriteString(strings.Repeat(" ", gap))
+			for col := 0; col < cols; col++ {
+				i := row*cols + col
+				if i >= len(tiles) {
+					break
+				}
+				b.WriteString(tileLine(tiles[i], y, tileWidth, tileHeight, now))
+				b.WriteString(strings.Repeat(" ", gap))
+			}
+			b.WriteString("\033[K")
+			if row < rows-1 || y < tileHeight-1 {
+				b.WriteString("\n")
+			}
+		}
+	}
+	b.WriteString("\033[J") // clear anything left below
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// tileLine renders line y of a tile, with its text centered vertically.
+func tileLine(t Tile, y, width, height int, now time.Time) string {
+	status := t.Status
+	if t.Err != nil {
+		status = StatusUnknown
+	}
+	color, ok := ansiColors[status]
+	if !ok {
+		color = ansiColors[StatusUnknown]
+	}
+
+	lines := t.lines(now)
+	lines = lines[:min(len(lines), height)]
+	top := (height - len(lines)) / 2
+	text := ""
+	if y >= top && y < top+len(lines) {
+		text = lines[y-top]
+	}
+	if y == top {
+		text = strings.ToUpper(text)
+	}
+
+	text = fit(text, width-2)
+	pad := width - utf8.RuneCountInString(text)
+	left := pad / 2
+	cell := strings.Repeat(" ", left) + text + strings.Repeat(" ", pad-left)
+	style := color[0] + ";" + color[1]
+	if y == top {
+		style += ";1"
+	}
+	return "\033[" + style + "m" + cell + "\033[0m"
+}
+
+// WritePlain writes the board as linear text for screen readers: a heading,
+// then one sentence per tile with its status spelled out.
+func WritePlain(w io.Writer, title string, tiles []Tile, updated, now time.Time) error {
+	if _, err := fmt.Fprintf(w, "%s, %d tiles, updated %s\n", title, len(tiles), updated.Format("15:04:05")); err != nil {
+		return err
+	}
+	for _, t := range tiles {
+		if _, err := fmt.Fprintln(w, t.Sentence(now)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sentence describes a tile in one line, e.g. "api: failed 5m ago.
+// 20240101.3 on main. No recent failures."
+func (t Tile) Sentence(now time.Time) string {
+	lines := t.lines(now)
+	if t.Err != nil {
+		lines[1] = "error"
+	}
+	var parts []string
+	for i, line := range lines[1:] {
+		if line == "" {
+			continue
+		}
+		if i > 0 {
+			line = strings.ToUpper(line[:1]) + line[1:]
+		}
+		parts = append(parts, line)
+	}
+	return lines[0] + ": " + strings.Join(parts, ". ") + "."
+}
+
+// fit truncates s to at most n runes.
+func fit(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	if n <= 3 {
+		return string(runes[:n])
+	}
+	return string(runes[:n-3]) + "..."
+}
+
+var htmlTemplate = template.Must(template.New("board").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="{{.Refresh}}">
+<title>{{.Title}}</title>
+<style>
+html, body { height: 100%; margin: 0; background: #111; color: #eee; font-family: system-ui, sans-serif; }
+body { display: flex; flex-direction: column; }
+header { display: flex; justify-content: space-between; padding: 0.5vh 1vw; font-size: 2.5vh; }
+main { flex: 1; display: grid; gap: 1vh; padding: 1vh; grid-template-columns: repeat({{.Columns}}, 1fr); grid-template-rows: repeat({{.Rows}}, 1fr); }
+a.tile { display: flex; flex-direction: column; justify-content: center; align-items: center; text-align: center; border-radius: 1vh; color: inherit; text-decoration: none; overflow: hidden; padding: 1vh; }
+.label { font-size: 5vh; font-weight: bold; text-transform: uppercase; }
+.status { font-size: 3.5vh; }
+.detail, .failure { font-size: 2.2vh; opacity: 0.85; }
+.succeeded { background: #2e7d32; }
+.failed { background: #c62828; }
+.running { background: #1565c0; }
+.canceled, .unknown { background: #555; }
+</style>
+</head>
+<body>
+<header><span>{{.Title}}</span><span>updated {{.Updated}}</span></header>
+<main>
+{{- range .Tiles}}
+<a class="tile {{.Class}}"{{if .URL}} href="{{.URL}}"{{end}}>
+{{- range $i, $line := .Lines}}
+<div class="{{index $.Classes $i}}">{{$line}}</div>
+{{- end}}
+</a>
+{{- end}}
+</main>
+</body>
+</html>
+`))
+
+// WriteHTML renders the board as a self-refreshing HTML page.
+func WriteHTML(w io.Writer, title string, tiles []Tile, columns int, refresh time.Duration, updated, now time.Time) error {
+	type htmlTile struct {
+		Class string
+		URL   string
+		Lines []string
+	}
+	cols, rows := grid(len(tiles), columns)
+	data := struct {
+		Title         string
+		Refresh       int
+		Columns, Rows int
+		Updated       string
+		Tiles         []htmlTile
+		Classes       []string
+	}{
+		Title:   title,
+		Refresh: int(refresh.Seconds()),
+		Columns: cols,
+		Rows:    rows,
+		Updated: updated.Format("15:04:05"),
+		Classes: []string{"label", "status", "detail", "failure"},
+	}
+	for _, t := range tiles {
+		class := t.Status
+		if t.Err != nil {
+			class = StatusUnknown
+		}
+		data.Tiles = append(data.Tiles, htmlTile{Class: class, URL: t.URL, Lines: t.lines(now)})
+	}
+	return htmlTemplate.Execute(w, data)
+}
@@ -0,0 +1,85 @@
+// Package cache is a small file-backed cache of JSON values, keyed by
+// strings, stored under the user's cache directory.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Cache stores entries as individual files in Dir.
+type Cache struct {
+	Dir string
+}
+
+type entry struct {
+	Key     string          `json:"key"`
+	Stored  time.Time       `json:"stored"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// Open returns the cache for a namespace under the user cache directory.
+func Open(namespace string) (*Cache, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate user cache directory: %v", err)
+	}
+	return &Cache{Dir: filepath.Join(dir, "fomo", namespace)}, nil
+}
+
+func (c *Cache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:16])+".json")
+}
+
+// Get decodes the entry for key into v and returns when it was stored. ok
+// is false when there is no readable entry.
+func (c *Cache) Get(key string, v any) (stored time.Time, ok bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return time.Time{}, false
+	}
+	var e entry
+	if json.Unmarshal(data, &e) != nil || e.Key != key {
+		return time.Time{}, false
+	}
+	if json.Unmarshal(e.Payload, v) != nil {
+		return time.Time{}, false
+	}
+	return e.Stored, true
+}
+
+// Put stores v under key, replacing the file atomically so concurrent
+// readers never see a partial entry.
+func (c *Cache) Put(key string, v any) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry{Key: key, Stored: time.Now(), Payload: payload})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.Dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(c.Dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path(key))
+}
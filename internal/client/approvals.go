@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const approvalsAPIVersion = "7.1-preview.1"
+
+// Approval statuses.
+const (
+	ApprovalPending  = "pending"
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+)
+
+type Approval struct {
+	ID           string         `json:"id"`
+	Status       string         `json:"status"`
+	CreatedOn    time.Time      `json:"createdOn"`
+	Instructions string         `json:"instructions,omitempty"`
+	Steps        []ApprovalStep `json:"steps,omitempty"`
+	Pipeline     struct {
+		ID    string `json:"id"`
+		Name  string `json:"name"`
+		Owner struct {
+			ID    int    `json:"id"`
+			Name  string `json:"name"`
+			Links Links  `json:"_links,omitempty"`
+		} `json:"owner"`
+	} `json:"pipeline"`
+	Links Links `json:"_links,omitempty"`
+}
+
+type ApprovalStep struct {
+	AssignedApprover Identity  `json:"assignedApprover"`
+	ActualApprover   *Identity `json:"actualApprover,omitempty"`
+	Status           string    `json:"status"`
+	Comment          string    `json:"comment,omitempty"`
+}
+
+// AssignedTo reports whether a step of the approval is assigned to the
+// identity.
+func (a *Approval) AssignedTo(id string) bool {
+	for _, step := range a.Steps {
+		if step.AssignedApprover.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+type approvalsResponse struct {
+	Count     int        `json:"count"`
+	Approvals []Approval `json:"value"`
+}
+
+// ListPendingApprovals returns the pending approvals in the project.
+func (c *Client) ListPendingApprovals(ctx context.Context) ([]Approval, error) {
+	query := url.Values{
+		"state":       {ApprovalPending},
+		"$expand":     {"steps"},
+		"api-version": {approvalsAPIVersion},
+	}
+	var response approvalsResponse
+	if err := c.do(ctx, http.MethodGet, c.projectURL("pipelines/approvals", query), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Approvals, nil
+}
+
+// GetApprovals returns approvals by ID, decided or not, with their steps.
+// The timeline of a run has a Checkpoint.Approval record for each, with the
+// approval's ID.
+func (c *Client) GetApprovals(ctx context.Context, ids []string) ([]Approval, error) {
+	query := url.Values{
+		"approvalIds": {strings.Join(ids, ",")},
+		"$expand":     {"steps"},
+		"api-version": {approvalsAPIVersion},
+	}
+	var response approvalsResponse
+	if err := c.do(ctx, http.MethodGet, c.projectURL("pipelines/approvals", query), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Approvals, nil
+}
+
+// UpdateApproval approves or rejects an approval with a comment.
+func (c *Client) UpdateApproval(ctx context.Context, approvalID, status, comment string) (*Approval, error) {
+	body := []map[string]string{{"approvalId": approvalID, "status": status, "comment": comment}}
+	var response approvalsResponse
+	query := url.Values{"api-version": {approvalsAPIVersion}}
+	if err := c.do(ctx, http.MethodPatch, c.projectURL("pipelines/approvals", query), body, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Approvals) == 0 {
+		return nil, nil
+	}
+	return &response.Approvals[0], nil
+}
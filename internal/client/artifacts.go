@@ -0,0 +1,116 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const containersAPIVersion = "7.1-preview.4"
+
+// UploadChunkSize is the largest piece of a file sent in one request to
+// a file container.
+const UploadChunkSize = 8 << 20
+
+// ArtifactTypeContainer marks an artifact stored in the run's file
+// container, as published by the PublishBuildArtifacts task.
+const ArtifactTypeContainer = "Container"
+
+// Artifact is a named set of files attached to a run.
+type Artifact struct {
+	ID       int              `json:"id,omitempty"`
+	Name     string           `json:"name"`
+	Resource ArtifactResource `json:"resource"`
+}
+
+type ArtifactResource struct {
+	Type string `json:"type"`
+	// Data locates the artifact; for containers it is #/<container ID>/<path>
+	Data        string            `json:"data"`
+	Properties  map[string]string `json:"properties,omitempty"`
+	DownloadURL string            `json:"downloadUrl,omitempty"`
+}
+
+// ContainerID returns the ID of the file container a container artifact is
+// stored in.
+func (r ArtifactResource) ContainerID() (int64, bool) {
+	if r.Type != ArtifactTypeContainer {
+		return 0, false
+	}
+	id, _, _ := strings.Cut(strings.TrimPrefix(r.Data, "#/"), "/")
+	n, err := strconv.ParseInt(id, 10, 64)
+	return n, err == nil
+}
+
+type artifactsResponse struct {
+	Count     int        `json:"count"`
+	Artifacts []Artifact `json:"value"`
+}
+
+// ListArtifacts returns the artifacts of a run.
+func (c *Client) ListArtifacts(ctx context.Context, buildID int) ([]Artifact, error) {
+	var response artifactsResponse
+	if err := c.do(ctx, http.MethodGet, c.projectURL(fmt.Sprintf("build/builds/%d/artifacts", buildID), nil), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Artifacts, nil
+}
+
+// AddContainerArtifact attaches the files uploaded under name in a file
+// container to a run as an artifact.
+func (c *Client) AddContainerArtifact(ctx context.Context, buildID int, containerID int64, name string) (*Artifact, error) {
+	artifact := Artifact{Name: name, Resource: ArtifactResource{
+		Type: ArtifactTypeContainer,
+		Data: fmt.Sprintf("#/%d/%s", containerID, name),
+	}}
+	var created Artifact
+	if err := c.do(ctx, http.MethodPost, c.projectURL(fmt.Sprintf("build/builds/%d/artifacts", buildID), nil), artifact, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// UploadContainerFile uploads a file of size bytes to itemPath in a file
+// container of a project, in chunks of at most UploadChunkSize.
+func (c *Client) UploadContainerFile(ctx context.Context, containerID int64, projectID, itemPath string, r io.ReaderAt, size int64) error {
+	query := url.Values{"api-version": {containersAPIVersion}, "itemPath": {itemPath}, "scope": {projectID}}
+	rawURL := c.orgURL(fmt.Sprintf("resources/Containers/%d", containerID), query)
+	// An empty file still takes one request to create it
+	for offset := int64(0); ; offset += UploadChunkSize {
+		n := min(UploadChunkSize, size-offset)
+		if err := c.uploadChunk(ctx, rawURL, io.NewSectionReader(r, offset, n), offset, n, size); err != nil {
+			return err
+		}
+		if offset+n >= size {
+			return nil
+		}
+	}
+}
+
+func (c *Client) uploadChunk(ctx context.Context, rawURL string, body io.Reader, offset, n, size int64) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, rawURL, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = n
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if size > 0 {
+		req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+n-1, size))
+	}
+
+	resp, err := c.send(req, true)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return checkResponse(resp, data)
+}
@@ -0,0 +1,13 @@
+package client
+
+import "strings"
+
+// BranchRef qualifies a branch as the ref most of the API expects: "main"
+// becomes "refs/heads/main", and refs such as "refs/heads/main" or
+// "refs/pull/1/merge" are kept as they are.
+func BranchRef(branch string) string {
+	if branch == "" || strings.HasPrefix(branch, "refs/") {
+		return branch
+	}
+	return "refs/heads/" + branch
+}
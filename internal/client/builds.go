@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+)
+
+const stagesAPIVersion = "7.1-preview.1"
+
+// Timeline record types.
+const (
+	RecordStage      = "Stage"
+	RecordPhase      = "Phase"
+	RecordJob        = "Job"
+	RecordTask       = "Task"
+	RecordCheckpoint = "Checkpoint"
+	RecordApproval   = "Checkpoint.Approval"
+)
+
+// TimelineVariable is an output variable. Secret values are not returned.
+type TimelineVariable struct {
+	Value    string `json:"value"`
+	IsSecret bool   `json:"isSecret,omitempty"`
+}
+
+// Duration returns how long the record ran, or zero if it never finished.
+func (r *TimelineRecord) Duration() time.Duration {
+	if r.StartTime == nil || r.FinishTime == nil {
+		return 0
+	}
+	return r.FinishTime.Sub(*r.StartTime)
+}
+
+// GetTimeline returns the timeline of a run (build).
+func (c *Client) GetTimeline(ctx context.Context, buildID int) (*Timeline, error) {
+	var timeline Timeline
+	if err := c.do(ctx, http.MethodGet, c.projectURL(fmt.Sprintf("build/builds/%d/timeline", buildID), nil), nil, &timeline); err != nil {
+		return nil, err
+	}
+	return &timeline, nil
+}
+
+// GetTimelineRaw returns the timeline of a run as raw JSON fields.
+func (c *Client) GetTimelineRaw(ctx context.Context, buildID int) (map[string]any, error) {
+	var timeline map[string]any
+	if err := c.do(ctx, http.MethodGet, c.projectURL(fmt.Sprintf("build/builds/%d/timeline", buildID), nil), nil, &timeline); err != nil {
+		return nil, err
+	}
+	return timeline, nil
+}
+
+// RetryStage re-runs a stage of a run. Unless allJobs is set only the
+// failed jobs of the stage are retried.
+func (c *Client) RetryStage(ctx context.Context, buildID int, stageRefName string, allJobs bool) error {
+	body := map[string]any{"state": "retry", "forceRetryAllJobs": allJobs}
+	query := url.Values{"api-version": {stagesAPIVersion}}
+	path := fmt.Sprintf("build/builds/%d/stages/%s", buildID, url.PathEscape(stageRefName))
+	return c.do(ctx, http.MethodPatch, c.projectURL(path, query), body, nil)
+}
+
+// RetryBuild re-runs the failed jobs of a run.
+func (c *Client) RetryBuild(ctx context.Context, buildID int) error {
+	query := url.Values{"retry": {"true"}}
+	return c.do(ctx, http.MethodPatch, c.projectURL(fmt.Sprintf("build/builds/%d", buildID), query), map[string]any{}, nil)
+}
+
+// CancelBuild asks for a queued or running run to be canceled. The run
+// is canceling until its agents stop.
+func (c *Client) CancelBuild(ctx context.Context, buildID int) error {
+	return c.do(ctx, http.MethodPatch, c.projectURL(fmt.Sprintf("build/builds/%d", buildID), nil), map[string]any{"status": BuildStatusCancelling}, nil)
+}
+
+// ListBuildTags returns the tags of a run.
+func (c *Client) ListBuildTags(ctx context.Context, buildID int) ([]string, error) {
+	return c.tags(ctx, http.MethodGet, fmt.Sprintf("build/builds/%d/tags", buildID))
+}
+
+// AddBuildTag tags a run and returns all of its tags.
+func (c *Client) AddBuildTag(ctx context.Context, buildID int, tag string) ([]string, error) {
+	return c.tags(ctx, http.MethodPut, fmt.Sprintf("build/builds/%d/tags/%s", buildID, url.PathEscape(tag)))
+}
+
+// DeleteBuildTag removes a tag from a run and returns the remaining tags.
+func (c *Client) DeleteBuildTag(ctx context.Context, buildID int, tag string) ([]string, error) {
+	return c.tags(ctx, http.MethodDelete, fmt.Sprintf("build/builds/%d/tags/%s", buildID, url.PathEscape(tag)))
+}
+
+// SetBuildProperties adds or replaces custom properties of a run.
+func (c *Client) SetBuildProperties(ctx context.Context, buildID int, properties map[string]any) error {
+	patch := make([]map[string]any, 0, len(properties))
+	for _, key := range slices.Sorted(maps.Keys(properties)) {
+		patch = append(patch, map[string]any{"op": "add", "path": "/" + key, "value": properties[key]})
+	}
+	query := url.Values{"api-version": {"7.1-preview.1"}}
+	return c.doAs(ctx, http.MethodPatch, c.projectURL(fmt.Sprintf("build/builds/%d/properties", buildID), query), "application/json-patch+json", patch, nil)
+}
+
+type buildChangesResponse struct {
+	Count   int           `json:"count"`
+	Changes []BuildChange `json:"value"`
+}
+
+// ListBuildChanges returns the commits a run brought in, newest first, up
+// to top of them.
+func (c *Client) ListBuildChanges(ctx context.Context, buildID, top int) ([]BuildChange, error) {
+	query := url.Values{"$top": {fmt.Sprint(top)}, "includeSourceChange": {"true"}}
+	var response buildChangesResponse
+	if err := c.do(ctx, http.MethodGet, c.projectURL(fmt.Sprintf("build/builds/%d/changes", buildID), query), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Changes, nil
+}
+
+// buildsBetweenAPIVersion is the version of the endpoints comparing two
+// runs.
+const buildsBetweenAPIVersion = "7.1-preview.2"
+
+// ListChangesBetweenBuilds returns the commits built after the run
+// fromBuildID up to the run toBuildID, of the same pipeline, newest first,
+// up to top of them.
+func (c *Client) ListChangesBetweenBuilds(ctx context.Context, fromBuildID, toBuildID, top int) ([]BuildChange, error) {
+	query := url.Values{
+		"api-version": {buildsBetweenAPIVersion},
+		"fromBuildId": {fmt.Sprint(fromBuildID)},
+		"toBuildId":   {fmt.Sprint(toBuildID)},
+		"$top":        {fmt.Sprint(top)},
+	}
+	var response buildChangesResponse
+	if err := c.do(ctx, http.MethodGet, c.projectURL("build/changes", query), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Changes, nil
+}
+
+// ListWorkItemsBetweenBuilds returns the work items linked to the commits
+// built after the run fromBuildID up to the run toBuildID.
+func (c *Client) ListWorkItemsBetweenBuilds(ctx context.Context, fromBuildID, toBuildID, top int) ([]ResourceRef, error) {
+	query := url.Values{
+		"api-version": {buildsBetweenAPIVersion},
+		"fromBuildId": {fmt.Sprint(fromBuildID)},
+		"toBuildId":   {fmt.Sprint(toBuildID)},
+		"$top":        {fmt.Sprint(top)},
+	}
+	var response resourceRefsResponse
+	if err := c.do(ctx, http.MethodGet, c.projectURL("build/workitems", query), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Refs, nil
+}
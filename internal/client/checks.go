@@ -0,0 +1,123 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const checksAPIVersion = "7.1-preview.1"
+
+// CheckType identifies the kind of a check on a protected resource.
+type CheckType struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Check types that can be configured on environments. Business hours is a
+// task check running the evaluatebusinesshours task.
+var (
+	CheckTypeApproval      = CheckType{ID: "8c6f20a7-a545-4486-9777-f762fafe0d4d", Name: "Approval"}
+	CheckTypeExclusiveLock = CheckType{ID: "2ef31ad6-baa0-403a-8b45-2cbc9b4e5563", Name: "ExclusiveLock"}
+	CheckTypeTask          = CheckType{ID: "fe1de3ee-a436-41b4-bb20-f6eb4cb879a7", Name: "Task Check"}
+)
+
+// BusinessHoursTask is the task a business hours check runs.
+var BusinessHoursTask = TaskReference{ID: "445fde2f-6c39-441c-807f-8a59ff2e075f", Name: "evaluatebusinesshours", Version: "0.0.1"}
+
+// Is reports whether two check types are the same; IDs are compared
+// ignoring case, as the API returns them either way.
+func (t CheckType) Is(other CheckType) bool { return strings.EqualFold(t.ID, other.ID) }
+
+// CheckResource is the resource a check protects.
+type CheckResource struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// CheckConfiguration is a check on a protected resource such as an
+// environment. Settings depend on the type: see ApprovalSettings and
+// TaskCheckSettings. Timeout is in minutes.
+type CheckConfiguration struct {
+	ID        int             `json:"id,omitempty"`
+	Type      CheckType       `json:"type"`
+	Resource  CheckResource   `json:"resource"`
+	Settings  json.RawMessage `json:"settings,omitempty"`
+	Timeout   int             `json:"timeout,omitempty"`
+	CreatedBy *Identity       `json:"createdBy,omitempty"`
+	CreatedOn *time.Time      `json:"createdOn,omitempty"`
+}
+
+// ApprovalSettings are the settings of an approval check. With
+// MinRequiredApprovers 0, every approver must approve.
+type ApprovalSettings struct {
+	Approvers                 []Identity `json:"approvers"`
+	ExecutionOrder            string     `json:"executionOrder"`
+	Instructions              string     `json:"instructions"`
+	BlockedApprovers          []Identity `json:"blockedApprovers"`
+	MinRequiredApprovers      int        `json:"minRequiredApprovers"`
+	RequesterCannotBeApprover bool       `json:"requesterCannotBeApprover"`
+}
+
+// Approval execution orders.
+const (
+	ApprovalAnyOrder   = "anyOrder"
+	ApprovalInSequence = "inSequence"
+)
+
+// TaskCheckSettings are the settings of a check that runs a task, such as
+// business hours.
+type TaskCheckSettings struct {
+	DefinitionRef TaskReference     `json:"definitionRef"`
+	DisplayName   string            `json:"displayName"`
+	Inputs        map[string]string `json:"inputs"`
+	RetryInterval int               `json:"retryInterval"`
+}
+
+type checkConfigurationsResponse struct {
+	Count          int                  `json:"count"`
+	Configurations []CheckConfiguration `json:"value"`
+}
+
+// ListEnvironmentChecks returns the checks of an environment, with their
+// settings.
+func (c *Client) ListEnvironmentChecks(ctx context.Context, environmentID int) ([]CheckConfiguration, error) {
+	var response checkConfigurationsResponse
+	query := url.Values{
+		"resourceType": {"environment"},
+		"resourceId":   {strconv.Itoa(environmentID)},
+		"$expand":      {"settings"},
+		"api-version":  {checksAPIVersion},
+	}
+	if err := c.do(ctx, http.MethodGet, c.projectURL("pipelines/checks/configurations", query), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Configurations, nil
+}
+
+// AddEnvironmentCheck adds a check to an environment. Settings are
+// marshaled as the check type expects them.
+func (c *Client) AddEnvironmentCheck(ctx context.Context, env Environment, checkType CheckType, settings any, timeout time.Duration) (*CheckConfiguration, error) {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return nil, fmt.Errorf("invalid check settings: %w", err)
+	}
+	check := CheckConfiguration{
+		Type:     checkType,
+		Resource: CheckResource{Type: "environment", ID: strconv.Itoa(env.ID), Name: env.Name},
+		Settings: data,
+		Timeout:  int(timeout.Minutes()),
+	}
+	var created CheckConfiguration
+	query := url.Values{"api-version": {checksAPIVersion}}
+	if err := c.do(ctx, http.MethodPost, c.projectURL("pipelines/checks/configurations", query), check, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
@@ -0,0 +1,165 @@
+// Package client is a small Azure DevOps REST API client used by fomo.
+package client
+
+//go:generate go run gen_models.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	DefaultBaseURL = "https://dev.azure.com"
+	apiVersion     = "7.0"
+)
+
+// Client talks to a single Azure DevOps organization and project.
+type Client struct {
+	BaseURL      string
+	Organization string
+	Project      string
+	PAT          string
+	HTTPClient   *http.Client // its Transport sends the requests, see send
+
+	// Middleware wraps every request, first one outermost, around the
+	// retries and authentication the client always applies
+	Middleware []Middleware
+
+	// OnDrift, when set, is called for every response whose shape does
+	// not match the type it is decoded into; see Drift
+	OnDrift func(Drift)
+}
+
+// New returns a client for the given organization and project.
+func New(organization, project, pat string) *Client {
+	return &Client{
+		BaseURL:      DefaultBaseURL,
+		Organization: organization,
+		Project:      project,
+		PAT:          pat,
+		HTTPClient:   &http.Client{},
+	}
+}
+
+// APIError is returned when Azure DevOps answers with a non-success status.
+type APIError struct {
+	StatusCode int
+	Status     string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("request failed, status: %s: %s", e.Status, e.Message)
+	}
+	return fmt.Sprintf("request failed, status: %s", e.Status)
+}
+
+// projectURL builds a project-scoped API URL.
+func (c *Client) projectURL(path string, query url.Values) string {
+	return c.buildURL(fmt.Sprintf("%s/%s/_apis/%s", url.PathEscape(c.Organization), url.PathEscape(c.Project), path), query)
+}
+
+// orgURL builds an organization-scoped API URL.
+func (c *Client) orgURL(path string, query url.Values) string {
+	return c.buildURL(fmt.Sprintf("%s/_apis/%s", url.PathEscape(c.Organization), path), query)
+}
+
+// WebURL returns the browser link of a page of the project, such as
+// "_build/results?buildId=1".
+func (c *Client) WebURL(page string) string {
+	return fmt.Sprintf("%s/%s/%s/%s", strings.TrimRight(c.BaseURL, "/"), url.PathEscape(c.Organization), url.PathEscape(c.Project), page)
+}
+
+func (c *Client) buildURL(path string, query url.Values) string {
+	if query == nil {
+		query = url.Values{}
+	}
+	if query.Get("api-version") == "" {
+		query.Set("api-version", apiVersion)
+	}
+	return fmt.Sprintf("%s/%s?%s", strings.TrimRight(c.BaseURL, "/"), path, query.Encode())
+}
+
+// do sends a request and decodes a JSON response into out (if non-nil).
+func (c *Client) do(ctx context.Context, method, rawURL string, in, out any) error {
+	return c.doAs(ctx, method, rawURL, "application/json", in, out)
+}
+
+// doAs is do with another content type for the request body, such as
+// application/json-patch+json.
+func (c *Client) doAs(ctx context.Context, method, rawURL, contentType string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.send(req, true)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if err := checkResponse(resp, data); err != nil {
+		return err
+	}
+
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return err
+	}
+	if c.OnDrift != nil {
+		if d := checkShape(data, out); d.Missing != nil || d.Unexpected != nil {
+			d.Request = method + " " + req.URL.Path
+			c.OnDrift(d)
+		}
+	}
+	return nil
+}
+
+// checkResponse turns unsuccessful responses into an *APIError.
+func checkResponse(resp *http.Response, body []byte) error {
+	// Azure DevOps answers an invalid PAT with a 203 and an HTML sign-in page
+	if resp.StatusCode == http.StatusNonAuthoritativeInfo {
+		return &APIError{StatusCode: http.StatusUnauthorized, Status: resp.Status, Message: "authentication failed, check your PAT"}
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	apiErr := &APIError{StatusCode: resp.StatusCode, Status: resp.Status}
+	var payload struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &payload) == nil {
+		apiErr.Message = payload.Message
+	}
+	return apiErr
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+const searchAPIVersion = "7.1-preview.1"
+
+// searchHost serves the Search extension's APIs for dev.azure.com.
+const searchHost = "almsearch.dev.azure.com"
+
+// CodeSearchRequest is a query of the Code Search extension. Filters are
+// keyed by Project, Repository, Branch, Path or CodeElement.
+type CodeSearchRequest struct {
+	SearchText    string              `json:"searchText"`
+	Skip          int                 `json:"$skip"`
+	Top           int                 `json:"$top"`
+	Filters       map[string][]string `json:"filters,omitempty"`
+	IncludeFacets bool                `json:"includeFacets"`
+}
+
+// CodeMatch is a match in a file, in UTF-16 code units from the start of
+// the file.
+type CodeMatch struct {
+	CharOffset int `json:"charOffset"`
+	Length     int `json:"length"`
+}
+
+// CodeSearchResult is a file matching a code search.
+type CodeSearchResult struct {
+	FileName string `json:"fileName"`
+	Path     string `json:"path"`
+	Matches  struct {
+		Content []CodeMatch `json:"content"`
+	} `json:"matches"`
+	Repository struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"repository"`
+	Versions []struct {
+		BranchName string `json:"branchName"`
+		ChangeID   string `json:"changeId"`
+	} `json:"versions"`
+}
+
+// CodeSearchResponse is a page of code search results.
+type CodeSearchResponse struct {
+	Count   int                `json:"count"`
+	Results []CodeSearchResult `json:"results"`
+}
+
+// searchClient returns a copy of the client that talks to the Search
+// extension, which dev.azure.com serves from its own host. Azure DevOps
+// Server and mocks serve it from the usual one.
+func (c *Client) searchClient() *Client {
+	clone := *c
+	if u, err := url.Parse(c.BaseURL); err == nil && u.Host == "dev.azure.com" {
+		u.Host = searchHost
+		clone.BaseURL = u.String()
+	}
+	return &clone
+}
+
+// SearchCode returns a page of the files of the project matching a code
+// search. It needs the Code Search extension installed in the organization.
+func (c *Client) SearchCode(ctx context.Context, request CodeSearchRequest) (*CodeSearchResponse, error) {
+	s := c.searchClient()
+	query := url.Values{"api-version": {searchAPIVersion}}
+	var response CodeSearchResponse
+	if err := s.do(ctx, http.MethodPost, s.projectURL("search/codesearchresults", query), request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
@@ -0,0 +1,160 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// GetDefinitionRaw returns a build definition as raw JSON fields, so it can
+// be modified and written back without dropping fields fomo doesn't model.
+func (c *Client) GetDefinitionRaw(ctx context.Context, definitionID int) (map[string]any, error) {
+	var definition map[string]any
+	if err := c.do(ctx, http.MethodGet, c.projectURL(fmt.Sprintf("build/definitions/%d", definitionID), nil), nil, &definition); err != nil {
+		return nil, err
+	}
+	return definition, nil
+}
+
+// UpdateDefinitionRaw replaces a build definition. The definition must
+// carry the revision it was read at.
+func (c *Client) UpdateDefinitionRaw(ctx context.Context, definitionID int, definition map[string]any) (map[string]any, error) {
+	var updated map[string]any
+	if err := c.do(ctx, http.MethodPut, c.projectURL(fmt.Sprintf("build/definitions/%d", definitionID), nil), definition, &updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeleteDefinition deletes a build definition and its runs.
+func (c *Client) DeleteDefinition(ctx context.Context, definitionID int) error {
+	return c.do(ctx, http.MethodDelete, c.projectURL(fmt.Sprintf("build/definitions/%d", definitionID), nil), nil, nil)
+}
+
+type definitionsResponse struct {
+	Count       int                   `json:"count"`
+	Definitions []DefinitionReference `json:"value"`
+}
+
+// ListDefinitions returns the build definitions in the project.
+func (c *Client) ListDefinitions(ctx context.Context) ([]DefinitionReference, error) {
+	var response definitionsResponse
+	if err := c.do(ctx, http.MethodGet, c.projectURL("build/definitions", nil), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Definitions, nil
+}
+
+// Definition is a build definition with its author, repository and
+// latest run.
+type Definition struct {
+	DefinitionReference
+	AuthoredBy  Identity              `json:"authoredBy"`
+	CreatedDate time.Time             `json:"createdDate"`
+	Tags        []string              `json:"tags,omitempty"`
+	Repository  *DefinitionRepository `json:"repository,omitempty"`
+	LatestBuild *Build                `json:"latestBuild,omitempty"` // nil if it never ran or its runs were deleted
+}
+
+// DefinitionRepository is the repository a build definition builds.
+type DefinitionRepository struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Type          string `json:"type"` // TfsGit for Azure Repos, GitHub, ...
+	DefaultBranch string `json:"defaultBranch,omitempty"`
+}
+
+// RepositoryTypeAzureRepos is the repository type of Azure Repos Git.
+const RepositoryTypeAzureRepos = "TfsGit"
+
+type definitionDetailsResponse struct {
+	Count       int          `json:"count"`
+	Definitions []Definition `json:"value"`
+}
+
+// ListDefinitionDetails returns the build definitions in the project with
+// their author, tags, repository and latest run.
+func (c *Client) ListDefinitionDetails(ctx context.Context) ([]Definition, error) {
+	var response definitionDetailsResponse
+	query := url.Values{"includeAllProperties": {"true"}, "includeLatestBuilds": {"true"}}
+	if err := c.do(ctx, http.MethodGet, c.projectURL("build/definitions", query), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Definitions, nil
+}
+
+// PipelineTags returns the tags of every tagged build definition, by
+// definition ID.
+func (c *Client) PipelineTags(ctx context.Context) (map[int][]string, error) {
+	definitions, err := c.ListDefinitionDetails(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tags := map[int][]string{}
+	for _, d := range definitions {
+		if len(d.Tags) > 0 {
+			tags[d.ID] = d.Tags
+		}
+	}
+	return tags, nil
+}
+
+// tagsResponse is the list of tags returned by the tags API.
+type tagsResponse struct {
+	Count int      `json:"count"`
+	Tags  []string `json:"value"`
+}
+
+// tagsAPIVersion is the version of the build and definition tags API.
+const tagsAPIVersion = "7.1-preview.3"
+
+// ListDefinitionTags returns the tags of a build definition.
+func (c *Client) ListDefinitionTags(ctx context.Context, definitionID int) ([]string, error) {
+	return c.tags(ctx, http.MethodGet, fmt.Sprintf("build/definitions/%d/tags", definitionID))
+}
+
+// AddDefinitionTag tags a build definition and returns all of its tags.
+func (c *Client) AddDefinitionTag(ctx context.Context, definitionID int, tag string) ([]string, error) {
+	return c.tags(ctx, http.MethodPut, fmt.Sprintf("build/definitions/%d/tags/%s", definitionID, url.PathEscape(tag)))
+}
+
+// DeleteDefinitionTag removes a tag from a build definition and returns the
+// remaining tags.
+func (c *Client) DeleteDefinitionTag(ctx context.Context, definitionID int, tag string) ([]string, error) {
+	return c.tags(ctx, http.MethodDelete, fmt.Sprintf("build/definitions/%d/tags/%s", definitionID, url.PathEscape(tag)))
+}
+
+func (c *Client) tags(ctx context.Context, method, path string) ([]string, error) {
+	var response tagsResponse
+	query := url.Values{"api-version": {tagsAPIVersion}}
+	if err := c.do(ctx, method, c.projectURL(path, query), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Tags, nil
+}
+
+// CreateDefinitionRaw creates a build definition from raw JSON fields.
+func (c *Client) CreateDefinitionRaw(ctx context.Context, definition map[string]any) (map[string]any, error) {
+	var created map[string]any
+	if err := c.do(ctx, http.MethodPost, c.projectURL("build/definitions", nil), definition, &created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+type queuesResponse struct {
+	Count  int          `json:"count"`
+	Queues []AgentQueue `json:"value"`
+}
+
+// ListQueues returns the agent queues available to the project.
+func (c *Client) ListQueues(ctx context.Context) ([]AgentQueue, error) {
+	var response queuesResponse
+	query := url.Values{"api-version": {"7.1-preview.1"}}
+	if err := c.do(ctx, http.MethodGet, c.projectURL("distributedtask/queues", query), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Queues, nil
+}
@@ -0,0 +1,176 @@
+package client
+
+import (
+	"cmp"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// downloadState is kept next to a partial download, so that it can be
+// resumed from the same content.
+type downloadState struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	Size         int64  `json:"size,omitempty"` // of the whole content, if known
+	MD5          string `json:"md5,omitempty"`  // base64, if the server gave one
+}
+
+// Downloaded describes a finished download.
+type Downloaded struct {
+	Size    int64
+	Resumed int64 // bytes kept from an earlier, interrupted attempt
+}
+
+// Download saves what rawURL answers to path. The content is written to
+// path+".part" and what is needed to resume it to path+".part.json", so a
+// download that is interrupted continues where it stopped when Download is
+// called again for the same URL. The file is only moved into place once
+// its size, and MD5 when the server gives one, check out.
+func (c *Client) Download(ctx context.Context, rawURL, accept, path string) (*Downloaded, error) {
+	part, statePath := path+".part", path+".part.json"
+	var state downloadState
+	var offset int64
+	if data, err := os.ReadFile(statePath); err == nil && json.Unmarshal(data, &state) == nil && state.URL == rawURL {
+		if info, err := os.Stat(part); err == nil {
+			offset = info.Size()
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		// Without a validator a changed file would be resumed into garbage;
+		// the size and MD5 checks still catch most of that
+		if validator := cmp.Or(state.ETag, state.LastModified); validator != "" {
+			req.Header.Set("If-Range", validator)
+		}
+	}
+	resp, err := c.send(req, true)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		start, total, ok := contentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			return nil, fmt.Errorf("server resumed %s at the wrong place; delete %s to start over", path, part)
+		}
+		if state.Size == 0 {
+			state.Size = total
+		}
+		flags |= os.O_APPEND
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0 && offset == state.Size:
+		// Everything arrived last time; only the checks are left
+		return finishDownload(part, statePath, path, state, offset)
+	case resp.StatusCode == http.StatusOK:
+		// A fresh start, or the content changed since the last attempt
+		offset = 0
+		state = downloadState{
+			URL:          rawURL,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			Size:         max(resp.ContentLength, 0),
+			MD5:          cmp.Or(resp.Header.Get("x-ms-blob-content-md5"), resp.Header.Get("Content-MD5")),
+		}
+		data, err := json.Marshal(state)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(statePath, data, 0600); err != nil {
+			return nil, err
+		}
+		flags |= os.O_TRUNC
+	default:
+		data, _ := io.ReadAll(resp.Body)
+		if err := checkResponse(resp, data); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("unexpected answer %s to a download", resp.Status)
+	}
+	// Range answers carry the MD5 of the whole blob in their own header
+	if state.MD5 == "" {
+		state.MD5 = resp.Header.Get("x-ms-blob-content-md5")
+	}
+
+	f, err := os.OpenFile(part, flags, 0644)
+	if err != nil {
+		return nil, err
+	}
+	n, copyErr := io.Copy(f, resp.Body)
+	if err := f.Close(); copyErr == nil {
+		copyErr = err
+	}
+	if copyErr != nil {
+		return nil, fmt.Errorf("download of %s stopped after %d bytes; run the command again to resume: %w", path, offset+n, copyErr)
+	}
+	return finishDownload(part, statePath, path, state, offset)
+}
+
+// finishDownload checks a complete part file and moves it into place. A
+// part that fails the checks is removed, as resuming it cannot fix it.
+func finishDownload(part, statePath, path string, state downloadState, resumed int64) (*Downloaded, error) {
+	f, err := os.Open(part)
+	if err != nil {
+		return nil, err
+	}
+	hash := md5.New()
+	size, err := io.Copy(hash, f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var problem string
+	switch {
+	case state.Size > 0 && size != state.Size:
+		problem = fmt.Sprintf("has %d bytes instead of %d", size, state.Size)
+	case state.MD5 != "" && base64.StdEncoding.EncodeToString(hash.Sum(nil)) != state.MD5:
+		problem = "does not match its MD5"
+	}
+	if problem != "" {
+		os.Remove(part)
+		os.Remove(statePath)
+		return nil, fmt.Errorf("download of %s %s and was discarded; run the command again", path, problem)
+	}
+
+	if err := os.Rename(part, path); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(statePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return &Downloaded{Size: size, Resumed: resumed}, nil
+}
+
+// contentRange reads "bytes START-END/TOTAL"; TOTAL may be "*".
+func contentRange(value string) (start, total int64, ok bool) {
+	spec, found := strings.CutPrefix(value, "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+	span, size, _ := strings.Cut(spec, "/")
+	first, _, _ := strings.Cut(span, "-")
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	total, _ = strconv.ParseInt(size, 10, 64)
+	return start, total, true
+}
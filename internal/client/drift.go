@@ -0,0 +1,147 @@
+package client
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Drift is how a response differs from the type it was decoded into,
+// which is how changes to Azure DevOps payloads show up: a renamed field
+// would otherwise just decode as a zero value. Fields are named
+// Type.field, after the Go type and the JSON name.
+type Drift struct {
+	Request    string   // method and path
+	Missing    []string // fields fomo reads that no object in the response had
+	Unexpected []string // fields the response had that fomo does not read
+}
+
+var unmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+
+// jsonField is a struct field as encoding/json sees it. Fields that can
+// be nil or are omitempty are optional; the rest are expected in every
+// response.
+type jsonField struct {
+	name     string
+	typ      reflect.Type
+	required bool
+}
+
+// jsonFields returns the fields of a struct keyed by their lowercased JSON
+// name, as encoding/json matches keys case-insensitively.
+func jsonFields(t reflect.Type) map[string]jsonField {
+	fields := map[string]jsonField{}
+	var embedded []map[string]jsonField
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, jsonFields(ft))
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		nilable := slices.Contains([]reflect.Kind{reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface}, sf.Type.Kind())
+		fields[strings.ToLower(name)] = jsonField{name: name, typ: sf.Type, required: !nilable && !strings.Contains(opts, "omitempty")}
+	}
+	// Fields of the outer struct win over those of embedded ones
+	for _, e := range embedded {
+		for key, f := range e {
+			if _, ok := fields[key]; !ok {
+				fields[key] = f
+			}
+		}
+	}
+	return fields
+}
+
+// shape accumulates how a response compares with its type.
+type shape struct {
+	present    map[string]int  // Type.field -> objects that had it
+	required   map[string]bool // Type.field of every required field seen
+	unexpected map[string]bool
+}
+
+func (s *shape) walk(v any, t reflect.Type) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// Types that decode themselves, such as time.Time, have their own shape
+	if t.Implements(unmarshalerType) || reflect.PointerTo(t).Implements(unmarshalerType) {
+		return
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return
+		}
+		name := t.Name()
+		if name == "" {
+			name = "response"
+		}
+		fields := jsonFields(t)
+		for _, f := range fields {
+			if f.required {
+				s.required[name+"."+f.name] = true
+			}
+		}
+		for key, value := range obj {
+			f, ok := fields[strings.ToLower(key)]
+			if !ok {
+				s.unexpected[name+"."+key] = true
+				continue
+			}
+			s.present[name+"."+f.name]++
+			s.walk(value, f.typ)
+		}
+	case reflect.Slice, reflect.Array:
+		items, _ := v.([]any)
+		for _, item := range items {
+			s.walk(item, t.Elem())
+		}
+	case reflect.Map:
+		obj, _ := v.(map[string]any)
+		for _, value := range obj {
+			s.walk(value, t.Elem())
+		}
+	}
+}
+
+// checkShape compares a response body with the value it was decoded into.
+func checkShape(data []byte, out any) Drift {
+	var v any
+	if json.Unmarshal(data, &v) != nil {
+		return Drift{}
+	}
+	s := &shape{present: map[string]int{}, required: map[string]bool{}, unexpected: map[string]bool{}}
+	s.walk(v, reflect.TypeOf(out))
+
+	var d Drift
+	for field := range s.required {
+		if s.present[field] == 0 {
+			d.Missing = append(d.Missing, field)
+		}
+	}
+	for field := range s.unexpected {
+		d.Unexpected = append(d.Unexpected, field)
+	}
+	slices.Sort(d.Missing)
+	slices.Sort(d.Unexpected)
+	return d
+}
@@ -0,0 +1,163 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type Environment struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	CreatedOn   time.Time `json:"createdOn"`
+}
+
+// Environment resource types.
+const (
+	ResourceKubernetes     = "kubernetes"
+	ResourceVirtualMachine = "virtualMachine"
+)
+
+// EnvironmentResource is a Kubernetes namespace or virtual machine of an
+// environment.
+type EnvironmentResource struct {
+	ID   int      `json:"id"`
+	Name string   `json:"name"`
+	Type string   `json:"type"`
+	Tags []string `json:"tags,omitempty"`
+}
+
+// KubernetesResource is a namespace of a cluster that deployments reach
+// through a service connection.
+type KubernetesResource struct {
+	ID                int    `json:"id"`
+	Name              string `json:"name"`
+	Namespace         string `json:"namespace"`
+	ClusterName       string `json:"clusterName"`
+	ServiceEndpointID string `json:"serviceEndpointId"`
+}
+
+// VirtualMachineResource is a machine running an agent registered with an
+// environment.
+type VirtualMachineResource struct {
+	ID    int      `json:"id"`
+	Name  string   `json:"name"`
+	Tags  []string `json:"tags,omitempty"`
+	Agent struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+		Status  string `json:"status"` // online or offline
+		Enabled bool   `json:"enabled"`
+	} `json:"agent"`
+}
+
+type environmentsResponse struct {
+	Count        int           `json:"count"`
+	Environments []Environment `json:"value"`
+}
+
+// DeploymentRecord is a deployment job that targeted an environment.
+type DeploymentRecord struct {
+	ID            int       `json:"id"`
+	EnvironmentID int       `json:"environmentId"`
+	StageName     string    `json:"stageName"`
+	StageAttempt  int       `json:"stageAttempt"`
+	JobName       string    `json:"jobName"`
+	JobAttempt    int       `json:"jobAttempt"`
+	PlanID        string    `json:"planId"`
+	PlanType      string    `json:"planType"`
+	ResourceID    *int      `json:"resourceId,omitempty"` // nil for jobs that targeted the environment itself
+	Result        string    `json:"result,omitempty"`
+	QueueTime     time.Time `json:"queueTime"`
+	StartTime     time.Time `json:"startTime"`
+	FinishTime    time.Time `json:"finishTime"`
+	Definition    struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"definition"`
+	Owner struct {
+		ID    int    `json:"id"`
+		Name  string `json:"name"`
+		Links Links  `json:"_links,omitempty"`
+	} `json:"owner"`
+}
+
+type deploymentRecordsResponse struct {
+	Count   int                `json:"count"`
+	Records []DeploymentRecord `json:"value"`
+}
+
+// ListEnvironments returns the environments in the project.
+func (c *Client) ListEnvironments(ctx context.Context) ([]Environment, error) {
+	var response environmentsResponse
+	query := url.Values{"api-version": {"7.1-preview.1"}}
+	if err := c.do(ctx, http.MethodGet, c.projectURL("distributedtask/environments", query), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Environments, nil
+}
+
+// ListDeploymentRecords returns the deployments to an environment, newest
+// first.
+func (c *Client) ListDeploymentRecords(ctx context.Context, environmentID int) ([]DeploymentRecord, error) {
+	var response deploymentRecordsResponse
+	query := url.Values{"api-version": {"7.1-preview.1"}}
+	path := fmt.Sprintf("distributedtask/environments/%d/environmentdeploymentrecords", environmentID)
+	if err := c.do(ctx, http.MethodGet, c.projectURL(path, query), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Records, nil
+}
+
+// CreateEnvironment creates an environment without resources.
+func (c *Client) CreateEnvironment(ctx context.Context, name, description string) (*Environment, error) {
+	var env Environment
+	query := url.Values{"api-version": {"7.1-preview.1"}}
+	body := map[string]string{"name": name, "description": description}
+	if err := c.do(ctx, http.MethodPost, c.projectURL("distributedtask/environments", query), body, &env); err != nil {
+		return nil, err
+	}
+	return &env, nil
+}
+
+// ListEnvironmentResources returns the resources of an environment.
+func (c *Client) ListEnvironmentResources(ctx context.Context, environmentID int) ([]EnvironmentResource, error) {
+	var env struct {
+		Resources []EnvironmentResource `json:"resources"`
+	}
+	query := url.Values{"expands": {"resourceReferences"}, "api-version": {"7.1-preview.1"}}
+	if err := c.do(ctx, http.MethodGet, c.projectURL(fmt.Sprintf("distributedtask/environments/%d", environmentID), query), nil, &env); err != nil {
+		return nil, err
+	}
+	return env.Resources, nil
+}
+
+// GetKubernetesResource returns the namespace and cluster of a Kubernetes
+// resource of an environment.
+func (c *Client) GetKubernetesResource(ctx context.Context, environmentID, resourceID int) (*KubernetesResource, error) {
+	var resource KubernetesResource
+	query := url.Values{"api-version": {"7.1-preview.1"}}
+	path := fmt.Sprintf("distributedtask/environments/%d/providers/kubernetes/%d", environmentID, resourceID)
+	if err := c.do(ctx, http.MethodGet, c.projectURL(path, query), nil, &resource); err != nil {
+		return nil, err
+	}
+	return &resource, nil
+}
+
+// ListVirtualMachines returns the virtual machines of an environment, with
+// the status of their agents.
+func (c *Client) ListVirtualMachines(ctx context.Context, environmentID int) ([]VirtualMachineResource, error) {
+	var response struct {
+		Count    int                      `json:"count"`
+		Machines []VirtualMachineResource `json:"value"`
+	}
+	query := url.Values{"api-version": {"7.1-preview.1"}}
+	path := fmt.Sprintf("distributedtask/environments/%d/providers/virtualmachines", environmentID)
+	if err := c.do(ctx, http.MethodGet, c.projectURL(path, query), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Machines, nil
+}
@@ -0,0 +1,265 @@
+package client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// FixtureMode selects whether a FixtureTransport records or replays.
+type FixtureMode int
+
+const (
+	FixtureRecord FixtureMode = iota
+	FixtureReplay
+)
+
+// fixture is one recorded request/response pair as stored on disk.
+type fixture struct {
+	Method   string            `json:"method"`
+	URL      string            `json:"url"`
+	Status   int               `json:"status"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Body     json.RawMessage   `json:"body,omitempty"`
+	BodyText string            `json:"bodyText,omitempty"`
+}
+
+// recordedHeaders are the only response headers kept in fixtures.
+var recordedHeaders = []string{"Content-Type", "Retry-After", "X-RateLimit-Remaining", "X-RateLimit-Reset", "X-RateLimit-Limit", "X-RateLimit-Resource", "X-RateLimit-Delay"}
+
+// FixtureTransport records API responses to Dir, or replays previously
+// recorded ones, so commands can be exercised without live credentials.
+// Credentials and people never reach the fixture files: request headers
+// are not stored, any occurrence of Secrets is masked, and so are email
+// addresses, the names and descriptors of identities, and Organization.
+type FixtureTransport struct {
+	Mode    FixtureMode
+	Dir     string
+	Secrets []string
+	Next    http.RoundTripper // used when recording; defaults to http.DefaultTransport
+
+	// Organization is replaced by FixtureOrganization in fixtures and their
+	// names, so fixtures recorded in one organization replay in any
+	Organization string
+}
+
+// FixtureOrganization stands in for the organization in fixtures.
+const FixtureOrganization = "fixture-org"
+
+// Masked values of identities in fixtures.
+const (
+	fixtureUser       = "Fixture User"
+	fixtureEmail      = "user@example.com"
+	fixtureDescriptor = "fixture.descriptor"
+)
+
+func (t *FixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+	path := filepath.Join(t.Dir, fixtureName(req, reqBody, t.Organization))
+
+	if t.Mode == FixtureReplay {
+		return t.replay(req, path)
+	}
+	return t.record(req, path)
+}
+
+func (t *FixtureTransport) replay(req *http.Request, path string) (*http.Response, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no recorded fixture for %s %s (expected %s)", req.Method, req.URL.Path, path)
+		}
+		return nil, err
+	}
+	var f fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture %s: %v", path, err)
+	}
+
+	body := []byte(f.BodyText)
+	if len(f.Body) > 0 {
+		body = f.Body
+	}
+	header := http.Header{}
+	for k, v := range f.Headers {
+		header.Set(k, v)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.Status, http.StatusText(f.Status)),
+		StatusCode:    f.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func (t *FixtureTransport) record(req *http.Request, path string) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	f := fixture{
+		Method:  req.Method,
+		URL:     t.sanitize(req.URL.String()),
+		Status:  resp.StatusCode,
+		Headers: map[string]string{},
+	}
+	for _, h := range recordedHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			f.Headers[h] = v
+		}
+	}
+	sanitized := t.sanitizeBody(body)
+	if json.Valid([]byte(sanitized)) {
+		f.Body = json.RawMessage(sanitized)
+	} else {
+		f.BodyText = sanitized
+	}
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(t.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create fixture directory: %v", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write fixture: %v", err)
+	}
+	return resp, nil
+}
+
+// fixtureEmails matches email addresses, in identities and elsewhere, such
+// as commit messages.
+var fixtureEmails = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+
+// fixtureDescriptors matches subject descriptors, such as aad.ZmF..., which
+// also appear in the avatar links of identities.
+var fixtureDescriptors = regexp.MustCompile(`\b(?:aad|aadgp|msa|vss|vssgp|svc|s2s|bnd|imp)\.[A-Za-z0-9_-]{8,}`)
+
+func (t *FixtureTransport) sanitize(s string) string {
+	for _, secret := range t.Secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, "REDACTED")
+		}
+	}
+	if t.Organization != "" {
+		org := regexp.QuoteMeta(t.Organization)
+		// As a path segment of dev.azure.com and its sibling hosts, and as
+		// the host of the older visualstudio.com URLs
+		s = regexp.MustCompile(`(?i)(://[^/\s"]+/)`+org+`\b`).ReplaceAllString(s, "${1}"+FixtureOrganization)
+		s = regexp.MustCompile(`(?i)://`+org+`\.`).ReplaceAllString(s, "://"+FixtureOrganization+".")
+	}
+	s = fixtureDescriptors.ReplaceAllString(s, fixtureDescriptor)
+	return fixtureEmails.ReplaceAllString(s, fixtureEmail)
+}
+
+// sanitizeBody masks a response body. JSON bodies have their identities
+// masked too: the objects with a uniqueName, a descriptor or an email.
+func (t *FixtureTransport) sanitizeBody(body []byte) string {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return t.sanitize(string(body))
+	}
+	maskIdentities(v)
+	masked, err := json.Marshal(v)
+	if err != nil {
+		return t.sanitize(string(body))
+	}
+	return t.sanitize(string(masked))
+}
+
+// maskIdentities replaces the names and descriptors of the identities in a
+// decoded JSON value, such as requestedFor, createdBy, the reviewers of a
+// pull request or the author of a commit.
+func maskIdentities(v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		_, unique := v["uniqueName"]
+		_, descriptor := v["descriptor"]
+		_, email := v["email"]
+		if unique || descriptor || email {
+			for _, key := range []string{"displayName", "name", "directoryAlias"} {
+				if _, ok := v[key].(string); ok {
+					v[key] = fixtureUser
+				}
+			}
+			for _, key := range []string{"uniqueName", "email", "mailAddress", "principalName"} {
+				if _, ok := v[key].(string); ok {
+					v[key] = fixtureEmail
+				}
+			}
+		}
+		for key, value := range v {
+			if key == "descriptor" || key == "subjectDescriptor" {
+				if _, ok := value.(string); ok {
+					v[key] = fixtureDescriptor
+					continue
+				}
+			}
+			maskIdentities(value)
+		}
+	case []any:
+		for _, item := range v {
+			maskIdentities(item)
+		}
+	}
+}
+
+var unsafeFixtureChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// fixtureName derives a stable, readable file name from the request method,
+// path, query and body. The organization is left out of it, so fixtures do
+// not depend on where they were recorded.
+func fixtureName(req *http.Request, body []byte, organization string) string {
+	segments := strings.Split(req.URL.Path, "/")
+	for i, segment := range segments {
+		if organization != "" && strings.EqualFold(segment, organization) {
+			segments[i] = FixtureOrganization
+		}
+	}
+	path := strings.Join(segments, "/")
+
+	// url.Values.Encode sorts by key, so parameter order does not matter
+	key := req.Method + " " + path + "?" + req.URL.Query().Encode() + "\n" + string(body)
+	sum := sha256.Sum256([]byte(key))
+
+	readable := unsafeFixtureChars.ReplaceAllString(strings.Trim(path, "/"), "_")
+	if len(readable) > 80 {
+		readable = readable[len(readable)-80:]
+	}
+	return fmt.Sprintf("%s_%s_%s.json", strings.ToLower(req.Method), readable, hex.EncodeToString(sum[:])[:12])
+}
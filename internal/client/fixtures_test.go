@@ -0,0 +1,78 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFixtureTransportSanitizes(t *testing.T) {
+	const body = `{"count":1,"value":[{"id":7,"name":"api-build",
+		"requestedFor":{"displayName":"Ana Garcia","uniqueName":"ana.garcia@fabrikam.com","descriptor":"aad.ZmFicmlrYW0tYW5h",
+			"imageUrl":"https://dev.azure.com/fabrikam/_apis/GraphProfile/MemberAvatars/aad.ZmFicmlrYW0tYW5h"},
+		"_links":{"web":{"href":"https://dev.azure.com/fabrikam/web/_build/results?buildId=7"}},
+		"triggerInfo":{"ci.message":"Merged PR 3 from li.wei@fabrikam.com"},
+		"token":"s3cr3t-pat"}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	c := New("fabrikam", "web", "s3cr3t-pat")
+	c.BaseURL = server.URL
+	c.HTTPClient.Transport = &FixtureTransport{Mode: FixtureRecord, Dir: dir, Secrets: []string{c.PAT}, Organization: c.Organization}
+	if _, err := c.ListBuilds(context.Background(), BuildCriteria{Top: 1}); err != nil {
+		t.Fatalf("ListBuilds: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("recorded %v (%v), want one fixture", files, err)
+	}
+	if name := filepath.Base(files[0]); !strings.Contains(name, FixtureOrganization) || strings.Contains(name, "fabrikam") {
+		t.Errorf("fixture name %s does not replace the organization", name)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, leaked := range []string{"s3cr3t-pat", "Ana Garcia", "ana.garcia@", "li.wei@", "aad.ZmFi", "fabrikam"} {
+		if strings.Contains(string(data), leaked) {
+			t.Errorf("fixture contains %q:\n%s", leaked, data)
+		}
+	}
+	for _, kept := range []string{`"api-build"`, fixtureUser, fixtureEmail, fixtureDescriptor, "dev.azure.com/fixture-org/web"} {
+		if !strings.Contains(string(data), kept) {
+			t.Errorf("fixture lacks %q:\n%s", kept, data)
+		}
+	}
+}
+
+func TestFixtureTransportReplays(t *testing.T) {
+	// Fixtures recorded in one organization replay in another
+	c := New("contoso", "web", "unused")
+	c.BaseURL = "http://fixtures.invalid"
+	c.HTTPClient.Transport = &FixtureTransport{Mode: FixtureReplay, Dir: filepath.Join("..", "..", "testdata", "fixtures"), Organization: c.Organization}
+
+	pipelines, err := c.ListPipelines(context.Background())
+	if err != nil {
+		t.Fatalf("ListPipelines: %v", err)
+	}
+	var names []string
+	for _, p := range pipelines {
+		names = append(names, p.Name)
+	}
+	if got, want := strings.Join(names, ","), "api-build,web-deploy"; got != want {
+		t.Errorf("pipelines = %s, want %s", got, want)
+	}
+
+	if _, err := c.GetRun(context.Background(), 12, 1); err == nil || !strings.Contains(err.Error(), "no recorded fixture") {
+		t.Errorf("GetRun without a fixture: err = %v, want no recorded fixture", err)
+	}
+}
@@ -0,0 +1,26 @@
+//go:build ignore
+
+// gen_models writes models_gen.go from the specs in specs, as configured by
+// specs/models.json. Run it with go generate after editing the specs.
+package main
+
+import (
+	"log"
+	"os"
+
+	"fomo/internal/modelgen"
+)
+
+func main() {
+	cfg, err := modelgen.LoadConfig("specs/models.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	src, err := modelgen.Generate(cfg, "specs")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("models_gen.go", src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
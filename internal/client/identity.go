@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// identityHost serves the identities API for dev.azure.com.
+const identityHost = "vssps.dev.azure.com"
+
+// Identity is a user or group as referenced by most APIs.
+type Identity struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+	UniqueName  string `json:"uniqueName,omitempty"`
+	IsContainer bool   `json:"isContainer,omitempty"`
+}
+
+type connectionData struct {
+	AuthenticatedUser struct {
+		ID                  string `json:"id"`
+		ProviderDisplayName string `json:"providerDisplayName"`
+	} `json:"authenticatedUser"`
+}
+
+// Me returns the identity the PAT belongs to.
+func (c *Client) Me(ctx context.Context) (*Identity, error) {
+	var data connectionData
+	if err := c.do(ctx, http.MethodGet, c.orgURL("connectionData", nil), nil, &data); err != nil {
+		return nil, err
+	}
+	return &Identity{ID: data.AuthenticatedUser.ID, DisplayName: data.AuthenticatedUser.ProviderDisplayName}, nil
+}
+
+type identitiesResponse struct {
+	Count      int `json:"count"`
+	Identities []struct {
+		ID                  string `json:"id"`
+		Descriptor          string `json:"descriptor"`
+		ProviderDisplayName string `json:"providerDisplayName"`
+		IsContainer         bool   `json:"isContainer"`
+		Properties          struct {
+			Account struct {
+				Value string `json:"$value"`
+			} `json:"Account"`
+		} `json:"properties"`
+	} `json:"value"`
+}
+
+// FindIdentities looks up users and groups by email address, account name
+// or display name. dev.azure.com serves identities from its own host, like
+// search.
+func (c *Client) FindIdentities(ctx context.Context, name string) ([]Identity, error) {
+	return c.identities(ctx, url.Values{"searchFilter": {"General"}, "filterValue": {name}, "queryMembership": {"None"}})
+}
+
+// GetIdentities returns the users and groups with the given IDs. IDs that
+// name no identity are left out.
+func (c *Client) GetIdentities(ctx context.Context, ids []string) ([]Identity, error) {
+	return c.identities(ctx, url.Values{"identityIds": {strings.Join(ids, ",")}, "queryMembership": {"None"}})
+}
+
+// GetIdentitiesByDescriptor returns the users and groups that security
+// descriptors, such as those of access control entries, stand for, keyed
+// by descriptor. Descriptors that name no identity are left out.
+func (c *Client) GetIdentitiesByDescriptor(ctx context.Context, descriptors []string) (map[string]Identity, error) {
+	s := c.identityClient()
+	query := url.Values{"descriptors": {strings.Join(descriptors, ",")}, "queryMembership": {"None"}}
+	var response identitiesResponse
+	if err := s.do(ctx, http.MethodGet, s.orgURL("identities", query), nil, &response); err != nil {
+		return nil, err
+	}
+	identities := map[string]Identity{}
+	for _, i := range response.Identities {
+		for _, descriptor := range descriptors {
+			if i.ID != "" && strings.EqualFold(descriptor, i.Descriptor) {
+				identities[descriptor] = Identity{ID: i.ID, DisplayName: i.ProviderDisplayName, UniqueName: i.Properties.Account.Value, IsContainer: i.IsContainer}
+			}
+		}
+	}
+	return identities, nil
+}
+
+// identityClient returns a copy of the client that talks to the host
+// serving identities.
+func (c *Client) identityClient() *Client {
+	s := *c
+	if u, err := url.Parse(c.BaseURL); err == nil && u.Host == "dev.azure.com" {
+		u.Host = identityHost
+		s.BaseURL = u.String()
+	}
+	return &s
+}
+
+func (c *Client) identities(ctx context.Context, query url.Values) ([]Identity, error) {
+	s := c.identityClient()
+	var response identitiesResponse
+	if err := s.do(ctx, http.MethodGet, s.orgURL("identities", query), nil, &response); err != nil {
+		return nil, err
+	}
+	identities := make([]Identity, 0, len(response.Identities))
+	for _, i := range response.Identities {
+		// Unknown IDs come back as null entries
+		if i.ID == "" {
+			continue
+		}
+		identities = append(identities, Identity{ID: i.ID, DisplayName: i.ProviderDisplayName, UniqueName: i.Properties.Account.Value, IsContainer: i.IsContainer})
+	}
+	return identities, nil
+}
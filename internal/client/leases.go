@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const leasesAPIVersion = "7.1-preview.2"
+
+// RetentionLease keeps a run, and the artifacts it produced, from being
+// deleted by retention policies until it expires.
+type RetentionLease struct {
+	LeaseID         int       `json:"leaseId"`
+	OwnerID         string    `json:"ownerId"`
+	RunID           int       `json:"runId"`
+	DefinitionID    int       `json:"definitionId"`
+	CreatedOn       time.Time `json:"createdOn"`
+	ValidUntil      time.Time `json:"validUntil"`
+	ProtectPipeline bool      `json:"protectPipeline"`
+}
+
+// NewRetentionLease asks for a lease on a run.
+type NewRetentionLease struct {
+	DefinitionID int    `json:"definitionId"`
+	RunID        int    `json:"runId"`
+	OwnerID      string `json:"ownerId"`
+	DaysValid    int    `json:"daysValid"`
+	// ProtectPipeline also keeps the pipeline from being deleted while the
+	// lease lasts
+	ProtectPipeline bool `json:"protectPipeline"`
+}
+
+type leasesResponse struct {
+	Count  int              `json:"count"`
+	Leases []RetentionLease `json:"value"`
+}
+
+// AddRetentionLease adds a lease to a run.
+func (c *Client) AddRetentionLease(ctx context.Context, lease NewRetentionLease) (*RetentionLease, error) {
+	var response leasesResponse
+	query := url.Values{"api-version": {leasesAPIVersion}}
+	if err := c.do(ctx, http.MethodPost, c.projectURL("build/retention/leases", query), []NewRetentionLease{lease}, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Leases) == 0 {
+		return nil, fmt.Errorf("no lease was created for run %d", lease.RunID)
+	}
+	return &response.Leases[0], nil
+}
+
+// ListBuildLeases returns the leases on a run.
+func (c *Client) ListBuildLeases(ctx context.Context, buildID int) ([]RetentionLease, error) {
+	var response leasesResponse
+	query := url.Values{"api-version": {leasesAPIVersion}}
+	if err := c.do(ctx, http.MethodGet, c.projectURL(fmt.Sprintf("build/builds/%d/leases", buildID), query), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Leases, nil
+}
+
+// ListOwnerLeases returns the leases an owner holds, on the runs of one
+// pipeline unless definitionID is 0.
+func (c *Client) ListOwnerLeases(ctx context.Context, ownerID string, definitionID int) ([]RetentionLease, error) {
+	query := url.Values{"api-version": {leasesAPIVersion}, "ownerId": {ownerID}}
+	if definitionID != 0 {
+		query.Set("definitionId", strconv.Itoa(definitionID))
+	}
+	var response leasesResponse
+	if err := c.do(ctx, http.MethodGet, c.projectURL("build/retention/leases", query), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Leases, nil
+}
+
+// DeleteRetentionLeases removes leases by ID.
+func (c *Client) DeleteRetentionLeases(ctx context.Context, leaseIDs []int) error {
+	ids := make([]string, len(leaseIDs))
+	for i, id := range leaseIDs {
+		ids[i] = strconv.Itoa(id)
+	}
+	query := url.Values{"api-version": {leasesAPIVersion}, "ids": {strings.Join(ids, ",")}}
+	return c.do(ctx, http.MethodDelete, c.projectURL("build/retention/leases", query), nil, nil)
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type Log struct {
+	ID            int            `json:"id"`
+	LineCount     int            `json:"lineCount"`
+	CreatedOn     time.Time      `json:"createdOn"`
+	LastChangedOn time.Time      `json:"lastChangedOn"`
+	URL           string         `json:"url,omitempty"`
+	SignedContent *SignedContent `json:"signedContent,omitempty"`
+}
+
+type SignedContent struct {
+	URL              string    `json:"url"`
+	SignatureExpires time.Time `json:"signatureExpires"`
+}
+
+type LogsResponse struct {
+	Logs []Log `json:"logs"`
+}
+
+// ListLogs returns the logs recorded for a run.
+func (c *Client) ListLogs(ctx context.Context, pipelineID, runID int) ([]Log, error) {
+	var logsResponse LogsResponse
+	if err := c.do(ctx, http.MethodGet, c.projectURL(fmt.Sprintf("pipelines/%d/runs/%d/logs", pipelineID, runID), nil), nil, &logsResponse); err != nil {
+		return nil, err
+	}
+	return logsResponse.Logs, nil
+}
+
+// LogsZipURL returns the URL of all the logs of a run, as a zip file, to
+// be fetched with Download.
+func (c *Client) LogsZipURL(buildID int) string {
+	return c.projectURL(fmt.Sprintf("build/builds/%d/logs", buildID), nil)
+}
+
+// GetLogContent downloads the text of a single log.
+func (c *Client) GetLogContent(ctx context.Context, pipelineID, runID, logID int) (string, error) {
+	var log Log
+	query := url.Values{"$expand": {"signedContent"}}
+	if err := c.do(ctx, http.MethodGet, c.projectURL(fmt.Sprintf("pipelines/%d/runs/%d/logs/%d", pipelineID, runID, logID), query), nil, &log); err != nil {
+		return "", err
+	}
+	if log.SignedContent == nil {
+		return "", fmt.Errorf("log %d has no downloadable content", logID)
+	}
+
+	// The signed URL carries its own authorization
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, log.SignedContent.URL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.send(req, false)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if err := checkResponse(resp, data); err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
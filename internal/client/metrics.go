@@ -0,0 +1,139 @@
+package client
+
+import (
+	"cmp"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheHeader marks responses that a caching middleware answered without
+// going to the network.
+const CacheHeader = "X-From-Cache"
+
+// Usage is what a set of API requests cost.
+type Usage struct {
+	Calls    int           `json:"calls"`
+	Failed   int           `json:"failed"`    // network errors and 4xx or 5xx answers
+	Cached   int           `json:"cached"`    // answered by a cache, see CacheHeader
+	Sent     int64         `json:"sent"`      // request body bytes
+	Received int64         `json:"received"`  // response body bytes read
+	Latency  time.Duration `json:"latencyNs"` // summed time to the response headers
+}
+
+func (u *Usage) add(o Usage) {
+	u.Calls += o.Calls
+	u.Failed += o.Failed
+	u.Cached += o.Cached
+	u.Sent += o.Sent
+	u.Received += o.Received
+	u.Latency += o.Latency
+}
+
+// EndpointUsage is the usage of one endpoint, such as
+// "build/builds/{id}/timeline".
+type EndpointUsage struct {
+	Endpoint string `json:"endpoint"`
+	Usage
+}
+
+// Metrics counts the requests that pass through its middleware, in total
+// and per endpoint. It is safe for concurrent use.
+type Metrics struct {
+	mu        sync.Mutex
+	endpoints map[string]*Usage
+}
+
+// Middleware counts every request.
+func (m *Metrics) Middleware(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		usage := Usage{Calls: 1, Sent: max(req.ContentLength, 0)}
+		start := time.Now()
+		resp, err := next.RoundTrip(req)
+		usage.Latency = time.Since(start)
+		endpoint := endpointOf(req)
+		if err != nil || resp.StatusCode >= 400 {
+			usage.Failed = 1
+		}
+		if resp != nil && resp.Header.Get(CacheHeader) != "" {
+			usage.Cached = 1
+		}
+		m.record(endpoint, usage)
+		if resp != nil {
+			resp.Body = &countingBody{ReadCloser: resp.Body, count: func(n int) { m.record(endpoint, Usage{Received: int64(n)}) }}
+		}
+		return resp, err
+	})
+}
+
+func (m *Metrics) record(endpoint string, usage Usage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.endpoints == nil {
+		m.endpoints = map[string]*Usage{}
+	}
+	if m.endpoints[endpoint] == nil {
+		m.endpoints[endpoint] = &Usage{}
+	}
+	m.endpoints[endpoint].add(usage)
+}
+
+// Total returns the usage of all requests so far.
+func (m *Metrics) Total() Usage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var total Usage
+	for _, u := range m.endpoints {
+		total.add(*u)
+	}
+	return total
+}
+
+// Endpoints returns the usage per endpoint, the slowest in total first.
+func (m *Metrics) Endpoints() []EndpointUsage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var usages []EndpointUsage
+	for endpoint, u := range m.endpoints {
+		usages = append(usages, EndpointUsage{Endpoint: endpoint, Usage: *u})
+	}
+	slices.SortFunc(usages, func(a, b EndpointUsage) int {
+		return cmp.Or(cmp.Compare(b.Latency, a.Latency), strings.Compare(a.Endpoint, b.Endpoint))
+	})
+	return usages
+}
+
+// endpointOf names the endpoint of a request: its path after _apis, with
+// IDs replaced by {id} so requests for different runs add up. Requests
+// outside the API, such as signed log downloads, are named by host.
+func endpointOf(req *http.Request) string {
+	_, path, ok := strings.Cut(req.URL.Path, "/_apis/")
+	if !ok {
+		return req.URL.Host
+	}
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if _, err := strconv.Atoi(s); err == nil || len(s) == 36 && strings.Count(s, "-") == 4 {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// countingBody reports the bytes read from a response body.
+type countingBody struct {
+	io.ReadCloser
+	count func(n int)
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.count(n)
+	}
+	return n, err
+}
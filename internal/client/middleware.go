@@ -0,0 +1,157 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Middleware wraps a RoundTripper with one concern of request handling,
+// such as authentication, retries or logging.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc turns a function into an http.RoundTripper.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Chain wraps base in the middlewares; the first one sees requests first.
+func Chain(base http.RoundTripper, middlewares ...Middleware) http.RoundTripper {
+	for _, m := range slices.Backward(middlewares) {
+		base = m(base)
+	}
+	return base
+}
+
+// Auth adds a PAT as basic authentication to the requests for host, and
+// not to the storage or sign-in hosts Azure DevOps redirects to.
+func Auth(pat, host string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Host != host {
+				return next.RoundTrip(req)
+			}
+			// A RoundTripper must not change the request it is given
+			req = req.Clone(req.Context())
+			req.SetBasicAuth("", pat)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+const (
+	retryAttempts = 3
+	retryBackoff  = 500 * time.Millisecond
+	// Throttling for longer than this is reported rather than waited out
+	maxRetryAfter = time.Minute
+)
+
+type noRetryKey struct{}
+
+// withoutRetries marks requests whose first answer is wanted as is.
+func withoutRetries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetryKey{}, true)
+}
+
+// Retry sends a request up to attempts times. Throttled requests (429) are
+// retried after the Retry-After the answer asks for; server errors and
+// network failures are retried with backoff, but only for idempotent
+// methods, as the first attempt may have taken effect.
+func Retry(attempts int) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Context().Value(noRetryKey{}) != nil || (req.Body != nil && req.GetBody == nil) {
+				return next.RoundTrip(req)
+			}
+			idempotent := req.Method != http.MethodPost && req.Method != http.MethodPatch
+			for attempt := 1; ; attempt++ {
+				try := req
+				if attempt > 1 && req.GetBody != nil {
+					body, err := req.GetBody()
+					if err != nil {
+						return nil, err
+					}
+					try = req.Clone(req.Context())
+					try.Body = body
+				}
+				resp, err := next.RoundTrip(try)
+				if attempt == attempts {
+					return resp, err
+				}
+
+				wait := retryBackoff << (attempt - 1)
+				var netErr net.Error
+				switch {
+				case err != nil:
+					if !idempotent || !errors.As(err, &netErr) {
+						return nil, err
+					}
+				case resp.StatusCode == http.StatusTooManyRequests:
+					after, ok := retryAfter(resp.Header.Get("Retry-After"))
+					if !ok || after > maxRetryAfter {
+						return resp, nil
+					}
+					wait = max(after, wait)
+				case resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented && idempotent:
+				default:
+					return resp, nil
+				}
+				if resp != nil {
+					io.Copy(io.Discard, resp.Body)
+					resp.Body.Close()
+				}
+
+				timer := time.NewTimer(wait)
+				select {
+				case <-req.Context().Done():
+					timer.Stop()
+					return nil, req.Context().Err()
+				case <-timer.C:
+				}
+			}
+		})
+	}
+}
+
+// retryAfter reads a Retry-After header, in seconds or as a date.
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, true
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t), true
+	}
+	return 0, false
+}
+
+// send runs a request through the caller's middleware, then retries and,
+// if authenticate is set, authentication, and finally the HTTP client's
+// transport.
+func (c *Client) send(req *http.Request, authenticate bool) (*http.Response, error) {
+	transport := c.HTTPClient.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	middlewares := append(slices.Clip(c.Middleware), Retry(retryAttempts))
+	if authenticate {
+		base, err := url.Parse(c.BaseURL)
+		if err != nil {
+			return nil, err
+		}
+		middlewares = append(middlewares, Auth(c.PAT, base.Host))
+	}
+	hc := *c.HTTPClient
+	hc.Transport = Chain(transport, middlewares...)
+	return hc.Do(req)
+}
@@ -0,0 +1,401 @@
+// Code generated by modelgen from the specs in specs; DO NOT EDIT.
+
+package client
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Pipeline is the Pipeline definition of pipelines.json.
+type Pipeline struct {
+	Links         Links                  `json:"_links,omitempty"`
+	Configuration *PipelineConfiguration `json:"configuration,omitempty"`
+	Folder        string                 `json:"folder,omitempty"`
+	ID            int                    `json:"id"`
+	Name          string                 `json:"name"`
+	Revision      int                    `json:"revision,omitempty"`
+	URL           string                 `json:"url,omitempty"`
+}
+
+// PipelineReference is the PipelineReference definition of pipelines.json.
+type PipelineReference struct {
+	Folder   string `json:"folder,omitempty"`
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Revision int    `json:"revision,omitempty"`
+	URL      string `json:"url,omitempty"`
+}
+
+// Run is the Run definition of pipelines.json.
+type Run struct {
+	Links              Links               `json:"_links,omitempty"`
+	CreatedDate        time.Time           `json:"createdDate"`
+	FinalYAML          string              `json:"finalYaml,omitempty"`
+	FinishedDate       time.Time           `json:"finishedDate"`
+	ID                 int                 `json:"id"`
+	Name               string              `json:"name"`
+	Pipeline           PipelineReference   `json:"pipeline"`
+	Resources          RunResources        `json:"resources"`
+	Result             string              `json:"result,omitempty"`
+	State              string              `json:"state"`
+	TemplateParameters map[string]any      `json:"templateParameters,omitempty"`
+	URL                string              `json:"url,omitempty"`
+	Variables          map[string]Variable `json:"variables,omitempty"`
+}
+
+// RunResources is the RunResources definition of pipelines.json.
+type RunResources struct {
+	Containers   map[string]json.RawMessage    `json:"containers,omitempty"`
+	Packages     map[string]json.RawMessage    `json:"packages,omitempty"`
+	Pipelines    map[string]json.RawMessage    `json:"pipelines,omitempty"`
+	Repositories map[string]RepositoryResource `json:"repositories,omitempty"`
+}
+
+// RepositoryResource is the RepositoryResource definition of
+// pipelines.json.
+type RepositoryResource struct {
+	RefName    string          `json:"refName"`
+	Repository json.RawMessage `json:"repository,omitempty"`
+	Version    string          `json:"version,omitempty"`
+}
+
+// Variable is the Variable definition of pipelines.json.
+type Variable struct {
+	IsSecret bool   `json:"isSecret,omitempty"`
+	Value    string `json:"value"`
+}
+
+// Build is the build API's view of a run, which includes the agent queue it
+// was queued in.
+type Build struct {
+	Links                        Links               `json:"_links,omitempty"`
+	AgentSpecification           json.RawMessage     `json:"agentSpecification,omitempty"`
+	AppendCommitMessageToRunName bool                `json:"appendCommitMessageToRunName,omitempty"`
+	BuildNumber                  string              `json:"buildNumber"`
+	BuildNumberRevision          int                 `json:"buildNumberRevision,omitempty"`
+	Controller                   json.RawMessage     `json:"controller,omitempty"`
+	Definition                   DefinitionReference `json:"definition"`
+	Deleted                      bool                `json:"deleted,omitempty"`
+	DeletedBy                    Identity            `json:"deletedBy,omitempty"`
+	DeletedDate                  time.Time           `json:"deletedDate,omitempty"`
+	DeletedReason                string              `json:"deletedReason,omitempty"`
+	Demands                      []json.RawMessage   `json:"demands,omitempty"`
+	FinishTime                   *time.Time          `json:"finishTime,omitempty"`
+	ID                           int                 `json:"id"`
+	KeepForever                  bool                `json:"keepForever,omitempty"`
+	LastChangedBy                Identity            `json:"lastChangedBy,omitempty"`
+	LastChangedDate              time.Time           `json:"lastChangedDate,omitempty"`
+	Logs                         BuildLogReference   `json:"logs,omitempty"`
+	OrchestrationPlan            json.RawMessage     `json:"orchestrationPlan,omitempty"`
+	Parameters                   string              `json:"parameters,omitempty"`
+	Plans                        []json.RawMessage   `json:"plans,omitempty"`
+	Priority                     string              `json:"priority,omitempty"`
+	Project                      Project             `json:"project,omitempty"`
+	Properties                   json.RawMessage     `json:"properties,omitempty"`
+	Quality                      string              `json:"quality,omitempty"`
+	Queue                        AgentQueue          `json:"queue"`
+	QueueOptions                 string              `json:"queueOptions,omitempty"`
+	QueuePosition                int                 `json:"queuePosition,omitempty"`
+	QueueTime                    time.Time           `json:"queueTime"`
+	Reason                       string              `json:"reason,omitempty"`
+	Repository                   SourceRepository    `json:"repository,omitempty"`
+	RequestedBy                  Identity            `json:"requestedBy,omitempty"`
+	RequestedFor                 Identity            `json:"requestedFor,omitempty"`
+	Result                       string              `json:"result,omitempty"`
+	RetainedByRelease            bool                `json:"retainedByRelease,omitempty"`
+	SourceBranch                 string              `json:"sourceBranch,omitempty"`
+	SourceVersion                string              `json:"sourceVersion,omitempty"`
+	StartTime                    *time.Time          `json:"startTime,omitempty"`
+	Status                       string              `json:"status"`
+	Tags                         []string            `json:"tags,omitempty"`
+	TemplateParameters           map[string]string   `json:"templateParameters,omitempty"`
+	TriggerInfo                  map[string]string   `json:"triggerInfo,omitempty"`
+	TriggeredByBuild             *Build              `json:"triggeredByBuild,omitempty"`
+	URI                          string              `json:"uri,omitempty"`
+	URL                          string              `json:"url,omitempty"`
+	ValidationResults            []json.RawMessage   `json:"validationResults,omitempty"`
+}
+
+// AgentQueue is a project's view of an agent pool.
+type AgentQueue struct {
+	Links Links              `json:"_links,omitempty"`
+	ID    int                `json:"id"`
+	Name  string             `json:"name"`
+	Pool  AgentPoolReference `json:"pool"`
+	URL   string             `json:"url,omitempty"`
+}
+
+// AgentPoolReference is the TaskAgentPoolReference definition of
+// build.json.
+type AgentPoolReference struct {
+	ID       int    `json:"id"`
+	IsHosted bool   `json:"isHosted"`
+	Name     string `json:"name"`
+}
+
+// DefinitionReference is the summary of a build definition returned by list
+// calls.
+type DefinitionReference struct {
+	CreatedDate time.Time `json:"createdDate,omitempty"`
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Path        string    `json:"path"`
+	Project     Project   `json:"project,omitempty"`
+	QueueStatus string    `json:"queueStatus,omitempty"`
+	Revision    int       `json:"revision"`
+	Type        string    `json:"type,omitempty"`
+	URI         string    `json:"uri,omitempty"`
+	URL         string    `json:"url,omitempty"`
+}
+
+// SourceRepository is the repository a run built, as the builds API
+// describes it. Its links follow the host of the repository: runs of a
+// GitHub or GitLab repository link to their commits and pull requests
+// there, not in Azure Repos.
+type SourceRepository struct {
+	CheckoutSubmodules bool              `json:"checkoutSubmodules,omitempty"`
+	Clean              string            `json:"clean,omitempty"`
+	DefaultBranch      string            `json:"defaultBranch,omitempty"`
+	ID                 string            `json:"id"`
+	Name               string            `json:"name,omitempty"`
+	Properties         map[string]string `json:"properties,omitempty"`
+	RootFolder         string            `json:"rootFolder,omitempty"`
+	Type               string            `json:"type"`
+	URL                string            `json:"url,omitempty"`
+}
+
+// BuildLogReference is the BuildLogReference definition of build.json.
+type BuildLogReference struct {
+	ID   int    `json:"id"`
+	Type string `json:"type,omitempty"`
+	URL  string `json:"url"`
+}
+
+// BuildChange is a commit a run built that the previous run of its pipeline
+// on the branch did not.
+type BuildChange struct {
+	Author           Identity  `json:"author"`
+	DisplayURI       string    `json:"displayUri,omitempty"`
+	ID               string    `json:"id"`
+	Location         string    `json:"location,omitempty"`
+	Message          string    `json:"message"`
+	MessageTruncated bool      `json:"messageTruncated,omitempty"`
+	Pusher           string    `json:"pusher,omitempty"`
+	Timestamp        time.Time `json:"timestamp"`
+	Type             string    `json:"type,omitempty"`
+}
+
+// Timeline is the Timeline definition of build.json.
+type Timeline struct {
+	ChangeID      int              `json:"changeId,omitempty"`
+	ID            string           `json:"id"`
+	LastChangedBy string           `json:"lastChangedBy,omitempty"`
+	LastChangedOn time.Time        `json:"lastChangedOn,omitempty"`
+	Records       []TimelineRecord `json:"records"`
+	URL           string           `json:"url,omitempty"`
+}
+
+// TimelineRecord is a stage, phase, job or task of a run.
+type TimelineRecord struct {
+	Links            Links                       `json:"_links,omitempty"`
+	Attempt          int                         `json:"attempt"`
+	ChangeID         int                         `json:"changeId,omitempty"`
+	CurrentOperation string                      `json:"currentOperation,omitempty"`
+	Details          json.RawMessage             `json:"details,omitempty"`
+	ErrorCount       int                         `json:"errorCount"`
+	FinishTime       *time.Time                  `json:"finishTime,omitempty"`
+	ID               string                      `json:"id"`
+	Identifier       string                      `json:"identifier,omitempty"`
+	Issues           []Issue                     `json:"issues,omitempty"`
+	LastModified     time.Time                   `json:"lastModified,omitempty"`
+	Log              *BuildLogReference          `json:"log,omitempty"`
+	Name             string                      `json:"name"`
+	Order            int                         `json:"order"`
+	ParentID         string                      `json:"parentId,omitempty"`
+	PercentComplete  int                         `json:"percentComplete,omitempty"`
+	PreviousAttempts []json.RawMessage           `json:"previousAttempts,omitempty"`
+	QueueID          int                         `json:"queueId,omitempty"`
+	Result           string                      `json:"result,omitempty"`
+	ResultCode       string                      `json:"resultCode,omitempty"`
+	StartTime        *time.Time                  `json:"startTime,omitempty"`
+	State            string                      `json:"state"`
+	Task             *TaskReference              `json:"task,omitempty"`
+	Type             string                      `json:"type"`
+	URL              string                      `json:"url,omitempty"`
+	Variables        map[string]TimelineVariable `json:"variables,omitempty"`
+	WarningCount     int                         `json:"warningCount"`
+	WorkerName       string                      `json:"workerName,omitempty"`
+}
+
+// TaskReference names the task, and its exact version, a task record ran.
+type TaskReference struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Issue is the Issue definition of build.json.
+type Issue struct {
+	Category string            `json:"category,omitempty"`
+	Data     map[string]string `json:"data,omitempty"`
+	Message  string            `json:"message"`
+	Type     string            `json:"type"`
+}
+
+// PullRequest is the GitPullRequest definition of git.json.
+type PullRequest struct {
+	Links                 Links              `json:"_links,omitempty"`
+	ArtifactID            string             `json:"artifactId,omitempty"`
+	AutoCompleteSetBy     *Identity          `json:"autoCompleteSetBy,omitempty"`
+	ClosedBy              Identity           `json:"closedBy,omitempty"`
+	ClosedDate            time.Time          `json:"closedDate,omitempty"`
+	CodeReviewID          int                `json:"codeReviewId,omitempty"`
+	Commits               []CommitRef        `json:"commits,omitempty"`
+	CompletionOptions     *CompletionOptions `json:"completionOptions,omitempty"`
+	CompletionQueueTime   time.Time          `json:"completionQueueTime,omitempty"`
+	CreatedBy             Identity           `json:"createdBy"`
+	CreationDate          time.Time          `json:"creationDate"`
+	Description           string             `json:"description,omitempty"`
+	ForkSource            json.RawMessage    `json:"forkSource,omitempty"`
+	HasMultipleMergeBases bool               `json:"hasMultipleMergeBases,omitempty"`
+	IsDraft               bool               `json:"isDraft,omitempty"`
+	Labels                []json.RawMessage  `json:"labels,omitempty"`
+	LastMergeCommit       *CommitRef         `json:"lastMergeCommit,omitempty"`
+	LastMergeSourceCommit *CommitRef         `json:"lastMergeSourceCommit,omitempty"`
+	LastMergeTargetCommit *CommitRef         `json:"lastMergeTargetCommit,omitempty"`
+	MergeFailureMessage   string             `json:"mergeFailureMessage,omitempty"`
+	MergeFailureType      string             `json:"mergeFailureType,omitempty"`
+	MergeID               string             `json:"mergeId,omitempty"`
+	MergeOptions          json.RawMessage    `json:"mergeOptions,omitempty"`
+	MergeStatus           string             `json:"mergeStatus,omitempty"`
+	ID                    int                `json:"pullRequestId"`
+	RemoteURL             string             `json:"remoteUrl,omitempty"`
+	Repository            Repository         `json:"repository"`
+	Reviewers             []Reviewer         `json:"reviewers,omitempty"`
+	SourceRefName         string             `json:"sourceRefName"`
+	Status                string             `json:"status"`
+	SupportsIterations    bool               `json:"supportsIterations,omitempty"`
+	TargetRefName         string             `json:"targetRefName"`
+	Title                 string             `json:"title"`
+	URL                   string             `json:"url,omitempty"`
+	WorkItemRefs          []json.RawMessage  `json:"workItemRefs,omitempty"`
+}
+
+// CommitRef is the GitCommitRef definition of git.json.
+type CommitRef struct {
+	Links            *Links            `json:"_links,omitempty"`
+	Author           *GitUserDate      `json:"author,omitempty"`
+	ChangeCounts     json.RawMessage   `json:"changeCounts,omitempty"`
+	Changes          []json.RawMessage `json:"changes,omitempty"`
+	Comment          string            `json:"comment,omitempty"`
+	CommentTruncated bool              `json:"commentTruncated,omitempty"`
+	CommitID         string            `json:"commitId"`
+	Committer        *GitUserDate      `json:"committer,omitempty"`
+	Parents          []string          `json:"parents,omitempty"`
+	Push             json.RawMessage   `json:"push,omitempty"`
+	RemoteURL        string            `json:"remoteUrl,omitempty"`
+	Statuses         []json.RawMessage `json:"statuses,omitempty"`
+	URL              string            `json:"url,omitempty"`
+	WorkItems        []json.RawMessage `json:"workItems,omitempty"`
+}
+
+// GitUserDate is the GitUserDate definition of git.json.
+type GitUserDate struct {
+	Date     time.Time `json:"date,omitempty"`
+	Email    string    `json:"email,omitempty"`
+	ImageURL string    `json:"imageUrl,omitempty"`
+	Name     string    `json:"name,omitempty"`
+}
+
+// CompletionOptions control how a pull request is merged.
+type CompletionOptions struct {
+	AutoCompleteIgnoreConfigIDs []int  `json:"autoCompleteIgnoreConfigIds,omitempty"`
+	BypassPolicy                bool   `json:"bypassPolicy,omitempty"`
+	BypassReason                string `json:"bypassReason,omitempty"`
+	DeleteSourceBranch          bool   `json:"deleteSourceBranch"`
+	MergeCommitMessage          string `json:"mergeCommitMessage,omitempty"`
+	MergeStrategy               string `json:"mergeStrategy,omitempty"`
+	SquashMerge                 bool   `json:"squashMerge,omitempty"`
+	TransitionWorkItems         bool   `json:"transitionWorkItems"`
+	TriggeredByAutoComplete     bool   `json:"triggeredByAutoComplete,omitempty"`
+}
+
+// Repository is the GitRepository definition of git.json.
+type Repository struct {
+	Links            Links           `json:"_links,omitempty"`
+	DefaultBranch    string          `json:"defaultBranch,omitempty"`
+	ID               string          `json:"id"`
+	IsDisabled       bool            `json:"isDisabled,omitempty"`
+	IsFork           bool            `json:"isFork,omitempty"`
+	IsInMaintenance  bool            `json:"isInMaintenance,omitempty"`
+	Name             string          `json:"name"`
+	ParentRepository json.RawMessage `json:"parentRepository,omitempty"`
+	Project          Project         `json:"project,omitempty"`
+	RemoteURL        string          `json:"remoteUrl,omitempty"`
+	Size             int64           `json:"size,omitempty"`
+	SSHURL           string          `json:"sshUrl,omitempty"`
+	URL              string          `json:"url,omitempty"`
+	ValidRemoteURLs  []string        `json:"validRemoteUrls,omitempty"`
+	WebURL           string          `json:"webUrl,omitempty"`
+}
+
+// TestRun is the TestRun definition of test.json.
+type TestRun struct {
+	Build              ShallowReference `json:"build,omitempty"`
+	Comment            string           `json:"comment,omitempty"`
+	CompletedDate      time.Time        `json:"completedDate,omitempty"`
+	ErrorMessage       string           `json:"errorMessage,omitempty"`
+	ID                 int              `json:"id"`
+	IncompleteTests    int              `json:"incompleteTests,omitempty"`
+	IsAutomated        bool             `json:"isAutomated,omitempty"`
+	LastUpdatedDate    time.Time        `json:"lastUpdatedDate,omitempty"`
+	Name               string           `json:"name"`
+	NotApplicableTests int              `json:"notApplicableTests,omitempty"`
+	Owner              Identity         `json:"owner,omitempty"`
+	PassedTests        int              `json:"passedTests,omitempty"`
+	Project            ShallowReference `json:"project,omitempty"`
+	Revision           int              `json:"revision,omitempty"`
+	StartedDate        time.Time        `json:"startedDate,omitempty"`
+	State              string           `json:"state,omitempty"`
+	TotalTests         int              `json:"totalTests,omitempty"`
+	UnanalyzedTests    int              `json:"unanalyzedTests,omitempty"`
+	URL                string           `json:"url,omitempty"`
+	WebAccessURL       string           `json:"webAccessUrl,omitempty"`
+}
+
+// TestCaseResult is the TestCaseResult definition of test.json.
+type TestCaseResult struct {
+	AutomatedTestName    string           `json:"automatedTestName,omitempty"`
+	AutomatedTestStorage string           `json:"automatedTestStorage,omitempty"`
+	AutomatedTestType    string           `json:"automatedTestType,omitempty"`
+	Build                ShallowReference `json:"build,omitempty"`
+	Comment              string           `json:"comment,omitempty"`
+	CompletedDate        time.Time        `json:"completedDate,omitempty"`
+	ComputerName         string           `json:"computerName,omitempty"`
+	CreatedDate          time.Time        `json:"createdDate,omitempty"`
+	DurationInMs         float64          `json:"durationInMs,omitempty"`
+	ErrorMessage         string           `json:"errorMessage,omitempty"`
+	FailureType          string           `json:"failureType,omitempty"`
+	ID                   int              `json:"id"`
+	LastUpdatedDate      time.Time        `json:"lastUpdatedDate,omitempty"`
+	Outcome              string           `json:"outcome,omitempty"`
+	Owner                Identity         `json:"owner,omitempty"`
+	Priority             int              `json:"priority,omitempty"`
+	Project              ShallowReference `json:"project,omitempty"`
+	Revision             int              `json:"revision,omitempty"`
+	StackTrace           string           `json:"stackTrace,omitempty"`
+	StartedDate          time.Time        `json:"startedDate,omitempty"`
+	State                string           `json:"state,omitempty"`
+	TestCase             ShallowReference `json:"testCase,omitempty"`
+	TestCaseTitle        string           `json:"testCaseTitle,omitempty"`
+	TestRun              ShallowReference `json:"testRun,omitempty"`
+	URL                  string           `json:"url,omitempty"`
+}
+
+// ShallowReference is the ShallowReference definition of test.json.
+type ShallowReference struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
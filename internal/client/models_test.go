@@ -0,0 +1,27 @@
+package client
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"fomo/internal/modelgen"
+)
+
+func TestModelsUpToDate(t *testing.T) {
+	cfg, err := modelgen.LoadConfig("specs/models.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := modelgen.Generate(cfg, "specs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("models_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("models_gen.go is out of date with the specs; run go generate ./internal/client")
+	}
+}
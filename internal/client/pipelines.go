@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+type PipelinesResponse struct {
+	Count     int        `json:"count"`
+	Pipelines []Pipeline `json:"value"`
+}
+
+// Links holds the hypermedia links returned with most resources.
+type Links struct {
+	Web  *Link `json:"web,omitempty"`
+	Self *Link `json:"self,omitempty"`
+}
+
+type Link struct {
+	Href string `json:"href"`
+}
+
+// Run states and results as reported by the Pipelines API.
+const (
+	RunStateInProgress = "inProgress"
+	RunStateCanceling  = "canceling"
+	RunStateCompleted  = "completed"
+
+	RunResultSucceeded = "succeeded"
+	RunResultFailed    = "failed"
+	RunResultCanceled  = "canceled"
+)
+
+// BuildStatusCancelling is the status of a run being canceled, as the
+// Build API spells it.
+const BuildStatusCancelling = "cancelling"
+
+// Branch returns the ref the run built from its own repository.
+func (r *Run) Branch() string {
+	return r.Resources.Repositories["self"].RefName
+}
+
+type RunsResponse struct {
+	Count int   `json:"count"`
+	Runs  []Run `json:"value"`
+}
+
+// ListPipelines returns the pipelines defined in the project.
+func (c *Client) ListPipelines(ctx context.Context) ([]Pipeline, error) {
+	var pipelinesResponse PipelinesResponse
+	if err := c.do(ctx, http.MethodGet, c.projectURL("pipelines", nil), nil, &pipelinesResponse); err != nil {
+		return nil, err
+	}
+	return pipelinesResponse.Pipelines, nil
+}
+
+// ListRuns returns the most recent runs of a pipeline, newest first.
+func (c *Client) ListRuns(ctx context.Context, pipelineID int) ([]Run, error) {
+	var runsResponse RunsResponse
+	if err := c.do(ctx, http.MethodGet, c.projectURL(fmt.Sprintf("pipelines/%d/runs", pipelineID), nil), nil, &runsResponse); err != nil {
+		return nil, err
+	}
+	return runsResponse.Runs, nil
+}
+
+// GetPipeline returns a pipeline with its configuration.
+func (c *Client) GetPipeline(ctx context.Context, pipelineID int) (*Pipeline, error) {
+	var pipeline Pipeline
+	if err := c.do(ctx, http.MethodGet, c.projectURL(fmt.Sprintf("pipelines/%d", pipelineID), nil), nil, &pipeline); err != nil {
+		return nil, err
+	}
+	return &pipeline, nil
+}
+
+// GetRun returns a single run of a pipeline.
+func (c *Client) GetRun(ctx context.Context, pipelineID, runID int) (*Run, error) {
+	var run Run
+	if err := c.do(ctx, http.MethodGet, c.projectURL(fmt.Sprintf("pipelines/%d/runs/%d", pipelineID, runID), nil), nil, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// RunRequest is the body of a request to queue a pipeline run.
+type RunRequest struct {
+	Resources          *RunRequestResources `json:"resources,omitempty"`
+	TemplateParameters map[string]string    `json:"templateParameters,omitempty"`
+	Variables          map[string]Variable  `json:"variables,omitempty"`
+}
+
+type RunRequestResources struct {
+	Repositories map[string]RepositoryResource `json:"repositories,omitempty"`
+}
+
+// RunPipeline queues a new run of a pipeline.
+func (c *Client) RunPipeline(ctx context.Context, pipelineID int, request RunRequest) (*Run, error) {
+	var run Run
+	if err := c.do(ctx, http.MethodPost, c.projectURL(fmt.Sprintf("pipelines/%d/runs", pipelineID), nil), request, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// previewAPIVersion is the version of the endpoint that expands a
+// pipeline's YAML without running it.
+const previewAPIVersion = "7.1-preview.1"
+
+// PreviewPipeline returns the final YAML a run of a pipeline would
+// execute, with its templates expanded, for the branch and parameters of
+// request. Nothing is queued.
+func (c *Client) PreviewPipeline(ctx context.Context, pipelineID int, request RunRequest) (string, error) {
+	body := struct {
+		RunRequest
+		PreviewRun bool `json:"previewRun"`
+	}{request, true}
+	var preview struct {
+		FinalYAML string `json:"finalYaml"`
+	}
+	query := url.Values{"api-version": {previewAPIVersion}}
+	if err := c.do(ctx, http.MethodPost, c.projectURL(fmt.Sprintf("pipelines/%d/preview", pipelineID), query), body, &preview); err != nil {
+		return "", err
+	}
+	return preview.FinalYAML, nil
+}
+
+// CreatePipelineRequest describes a new YAML pipeline.
+type CreatePipelineRequest struct {
+	Name          string                `json:"name"`
+	Folder        string                `json:"folder,omitempty"`
+	Configuration PipelineConfiguration `json:"configuration"`
+}
+
+type PipelineConfiguration struct {
+	Type       string                `json:"type"`
+	Path       string                `json:"path"`
+	Repository PipelineRepositoryRef `json:"repository"`
+}
+
+type PipelineRepositoryRef struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	Type string `json:"type"`
+}
+
+// CreatePipeline creates a YAML pipeline.
+func (c *Client) CreatePipeline(ctx context.Context, request CreatePipelineRequest) (*Pipeline, error) {
+	var pipeline Pipeline
+	if err := c.do(ctx, http.MethodPost, c.projectURL("pipelines", nil), request, &pipeline); err != nil {
+		return nil, err
+	}
+	return &pipeline, nil
+}
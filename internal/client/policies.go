@@ -0,0 +1,127 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const policyAPIVersion = "7.1-preview.1"
+
+// Policy type IDs of the built-in branch policies.
+const (
+	PolicyBuild             = "0609b952-1397-4640-95ec-e00a01b2c241"
+	PolicyMinimumReviewers  = "fa4e907d-c16b-4a4c-9dfa-4906e5d171dd"
+	PolicyRequiredReviewers = "fd2167ab-b0be-447a-8ec8-39368250530e"
+	PolicyCommentResolution = "c6a1889d-b943-4856-b76f-9e46bb6b0df2"
+	PolicyWorkItemLinking   = "40e92b44-2fe1-4dd6-b3d8-74a9c21d0c6e"
+)
+
+// Policy evaluation statuses.
+const (
+	PolicyQueued        = "queued"
+	PolicyRunning       = "running"
+	PolicyApproved      = "approved"
+	PolicyRejected      = "rejected"
+	PolicyNotApplicable = "notApplicable"
+	PolicyBroken        = "broken"
+)
+
+// PolicyEvaluation is the state of one branch policy on a pull request.
+type PolicyEvaluation struct {
+	ID            string              `json:"evaluationId"`
+	Status        string              `json:"status"`
+	Configuration PolicyConfiguration `json:"configuration"`
+	// Context holds type-specific state, e.g. the build of a build
+	// validation policy.
+	Context json.RawMessage `json:"context,omitempty"`
+}
+
+type PolicyConfiguration struct {
+	ID         int             `json:"id"`
+	IsEnabled  bool            `json:"isEnabled"`
+	IsBlocking bool            `json:"isBlocking"`
+	Type       PolicyType      `json:"type"`
+	Settings   json.RawMessage `json:"settings,omitempty"`
+}
+
+type PolicyType struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+// PolicyScope is where a policy applies: a branch of a repository, or
+// branches starting with RefName when MatchKind is "prefix". A nil
+// RepositoryID means every repository of the project.
+type PolicyScope struct {
+	RepositoryID *string `json:"repositoryId"`
+	RefName      string  `json:"refName,omitempty"`
+	MatchKind    string  `json:"matchKind,omitempty"` // exact or prefix
+}
+
+// Scopes returns where the policy applies, read from its settings.
+func (p *PolicyConfiguration) Scopes() []PolicyScope {
+	var settings struct {
+		Scope []PolicyScope `json:"scope"`
+	}
+	json.Unmarshal(p.Settings, &settings)
+	return settings.Scope
+}
+
+type policyEvaluationsResponse struct {
+	Count       int                `json:"count"`
+	Evaluations []PolicyEvaluation `json:"value"`
+}
+
+// ListPolicyEvaluations returns the evaluations of the branch policies that
+// apply to a pull request.
+func (c *Client) ListPolicyEvaluations(ctx context.Context, projectID string, pullRequestID int) ([]PolicyEvaluation, error) {
+	query := url.Values{
+		"artifactId":  {fmt.Sprintf("vstfs:///CodeReview/CodeReviewId/%s/%d", projectID, pullRequestID)},
+		"api-version": {policyAPIVersion},
+	}
+	var response policyEvaluationsResponse
+	if err := c.do(ctx, http.MethodGet, c.projectURL("policy/evaluations", query), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Evaluations, nil
+}
+
+type policyConfigurationsResponse struct {
+	Count          int                   `json:"count"`
+	Configurations []PolicyConfiguration `json:"value"`
+}
+
+// ListPolicyConfigurations returns the branch policies of a branch of a
+// repository, including those set for every repository of the project.
+func (c *Client) ListPolicyConfigurations(ctx context.Context, repositoryID, refName string) ([]PolicyConfiguration, error) {
+	query := url.Values{"repositoryId": {repositoryID}, "refName": {refName}, "api-version": {policyAPIVersion}}
+	var response policyConfigurationsResponse
+	if err := c.do(ctx, http.MethodGet, c.projectURL("policy/configurations", query), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Configurations, nil
+}
+
+// CreatePolicyConfiguration adds a branch policy. Its settings say where it
+// applies, in their scope.
+func (c *Client) CreatePolicyConfiguration(ctx context.Context, config PolicyConfiguration) (*PolicyConfiguration, error) {
+	var created PolicyConfiguration
+	query := url.Values{"api-version": {policyAPIVersion}}
+	if err := c.do(ctx, http.MethodPost, c.projectURL("policy/configurations", query), config, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// UpdatePolicyConfiguration replaces a branch policy with config.
+func (c *Client) UpdatePolicyConfiguration(ctx context.Context, config PolicyConfiguration) (*PolicyConfiguration, error) {
+	var updated PolicyConfiguration
+	query := url.Values{"api-version": {policyAPIVersion}}
+	if err := c.do(ctx, http.MethodPut, c.projectURL(fmt.Sprintf("policy/configurations/%d", config.ID), query), config, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
@@ -0,0 +1,152 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CommitMessage returns the message of the commit that triggered the run,
+// if a push did.
+func (b *Build) CommitMessage() string {
+	return b.TriggerInfo["ci.message"]
+}
+
+// BuildCriteria filters ListBuilds.
+type BuildCriteria struct {
+	RequestedFor string    // identity ID
+	MinTime      time.Time // only runs queued after this
+	Definitions  []int     // only runs of these pipelines
+	Branch       string    // only runs of this branch or ref
+	Tags         []string  // only runs with all of these tags
+	Status       string    // only runs in this status, such as completed
+	Result       string    // only finished runs with this result
+	Reason       string    // only runs queued for this reason, such as pullRequest
+	Top          int
+}
+
+type buildsResponse struct {
+	Count  int     `json:"count"`
+	Builds []Build `json:"value"`
+}
+
+// ListBuilds returns the runs of the project matching criteria, newest
+// first.
+func (c *Client) ListBuilds(ctx context.Context, criteria BuildCriteria) ([]Build, error) {
+	query := url.Values{"queryOrder": {"queueTimeDescending"}}
+	if criteria.RequestedFor != "" {
+		query.Set("requestedFor", criteria.RequestedFor)
+	}
+	if !criteria.MinTime.IsZero() {
+		query.Set("minTime", criteria.MinTime.UTC().Format(time.RFC3339))
+	}
+	if len(criteria.Definitions) > 0 {
+		ids := make([]string, len(criteria.Definitions))
+		for i, id := range criteria.Definitions {
+			ids[i] = strconv.Itoa(id)
+		}
+		query.Set("definitions", strings.Join(ids, ","))
+	}
+	if criteria.Branch != "" {
+		query.Set("branchName", BranchRef(criteria.Branch))
+	}
+	if len(criteria.Tags) > 0 {
+		query.Set("tagFilters", strings.Join(criteria.Tags, ","))
+	}
+	if criteria.Status != "" {
+		query.Set("statusFilter", criteria.Status)
+	}
+	if criteria.Result != "" {
+		query.Set("resultFilter", criteria.Result)
+	}
+	if criteria.Reason != "" {
+		query.Set("reasonFilter", criteria.Reason)
+	}
+	if criteria.Top > 0 {
+		query.Set("$top", fmt.Sprint(criteria.Top))
+	}
+	var response buildsResponse
+	if err := c.do(ctx, http.MethodGet, c.projectURL("build/builds", query), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Builds, nil
+}
+
+// GetBuild returns a run through the build API.
+func (c *Client) GetBuild(ctx context.Context, buildID int) (*Build, error) {
+	var build Build
+	if err := c.do(ctx, http.MethodGet, c.projectURL(fmt.Sprintf("build/builds/%d", buildID), nil), nil, &build); err != nil {
+		return nil, err
+	}
+	return &build, nil
+}
+
+// GetBuildRaw returns a run through the build API as raw JSON fields.
+func (c *Client) GetBuildRaw(ctx context.Context, buildID int) (map[string]any, error) {
+	var build map[string]any
+	if err := c.do(ctx, http.MethodGet, c.projectURL(fmt.Sprintf("build/builds/%d", buildID), nil), nil, &build); err != nil {
+		return nil, err
+	}
+	return build, nil
+}
+
+// JobRequest is a job waiting for or running on an agent of a pool.
+type JobRequest struct {
+	RequestID   int64      `json:"requestId"`
+	QueueTime   time.Time  `json:"queueTime"`
+	AssignTime  *time.Time `json:"assignTime,omitempty"`
+	ReceiveTime *time.Time `json:"receiveTime,omitempty"`
+	FinishTime  *time.Time `json:"finishTime,omitempty"`
+	Result      string     `json:"result,omitempty"`
+	PlanType    string     `json:"planType"`
+	Definition  struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"definition"`
+	Owner struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"owner"`
+}
+
+type jobRequestsResponse struct {
+	Count    int          `json:"count"`
+	Requests []JobRequest `json:"value"`
+}
+
+// ListJobRequests returns the queued, running and recently finished jobs
+// of an agent pool.
+func (c *Client) ListJobRequests(ctx context.Context, poolID int) ([]JobRequest, error) {
+	var response jobRequestsResponse
+	query := url.Values{"api-version": {"7.1-preview.1"}}
+	if err := c.do(ctx, http.MethodGet, c.orgURL(fmt.Sprintf("distributedtask/pools/%d/jobrequests", poolID), query), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Requests, nil
+}
+
+// Agent is a self-hosted agent of a pool.
+type Agent struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Status  string `json:"status"` // online or offline
+	Enabled bool   `json:"enabled"`
+}
+
+type agentsResponse struct {
+	Count  int     `json:"count"`
+	Agents []Agent `json:"value"`
+}
+
+// ListAgents returns the agents registered in a pool.
+func (c *Client) ListAgents(ctx context.Context, poolID int) ([]Agent, error) {
+	var response agentsResponse
+	if err := c.do(ctx, http.MethodGet, c.orgURL(fmt.Sprintf("distributedtask/pools/%d/agents", poolID), nil), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Agents, nil
+}
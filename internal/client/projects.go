@@ -0,0 +1,53 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+type Project struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	State string `json:"state,omitempty"`
+}
+
+type projectsResponse struct {
+	Count    int       `json:"count"`
+	Projects []Project `json:"value"`
+}
+
+// ListProjects returns the projects in the organization.
+func (c *Client) ListProjects(ctx context.Context) ([]Project, error) {
+	var response projectsResponse
+	if err := c.do(ctx, http.MethodGet, c.orgURL("projects", nil), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Projects, nil
+}
+
+// WithProject returns a copy of the client scoped to another project in the
+// same organization.
+func (c *Client) WithProject(project string) *Client {
+	clone := *c
+	clone.Project = project
+	return &clone
+}
+
+// WithOrganization returns a copy of the client scoped to a project in
+// another organization.
+func (c *Client) WithOrganization(organization, project string) *Client {
+	clone := *c
+	clone.Organization = organization
+	clone.Project = project
+	return &clone
+}
+
+// GetProject returns a project of the organization by name or ID.
+func (c *Client) GetProject(ctx context.Context, nameOrID string) (*Project, error) {
+	var project Project
+	if err := c.do(ctx, http.MethodGet, c.orgURL("projects/"+url.PathEscape(nameOrID), nil), nil, &project); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
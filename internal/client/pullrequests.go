@@ -0,0 +1,376 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Reviewer votes.
+const (
+	VoteApproved               = 10
+	VoteApprovedWithSuggestion = 5
+	VoteNone                   = 0
+	VoteWaitingForAuthor       = -5
+	VoteRejected               = -10
+)
+
+// Pull request statuses.
+const (
+	PullRequestActive    = "active"
+	PullRequestCompleted = "completed"
+	PullRequestAbandoned = "abandoned"
+)
+
+// Merge strategies for completing a pull request.
+const (
+	MergeNoFastForward = "noFastForward"
+	MergeSquash        = "squash"
+	MergeRebase        = "rebase"
+	MergeRebaseMerge   = "rebaseMerge"
+)
+
+// PullRequestUpdate holds the fields of a pull request to change.
+type PullRequestUpdate struct {
+	Status                string             `json:"status,omitempty"`
+	Description           string             `json:"description,omitempty"`
+	LastMergeSourceCommit *CommitRef         `json:"lastMergeSourceCommit,omitempty"`
+	AutoCompleteSetBy     *Identity          `json:"autoCompleteSetBy,omitempty"`
+	CompletionOptions     *CompletionOptions `json:"completionOptions,omitempty"`
+}
+
+type Reviewer struct {
+	Identity
+	Vote       int  `json:"vote"`
+	IsRequired bool `json:"isRequired,omitempty"`
+}
+
+// VoteOf returns the identity's vote on the pull request.
+func (pr *PullRequest) VoteOf(id string) (int, bool) {
+	for _, r := range pr.Reviewers {
+		if r.ID == id {
+			return r.Vote, true
+		}
+	}
+	return VoteNone, false
+}
+
+// WebURL returns the browser link of the pull request.
+func (pr *PullRequest) WebURL() string {
+	if pr.Repository.WebURL == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/pullrequest/%d", pr.Repository.WebURL, pr.ID)
+}
+
+// PullRequestCriteria filters ListPullRequests.
+type PullRequestCriteria struct {
+	Status     string // active, completed, abandoned or all
+	CreatorID  string
+	ReviewerID string
+}
+
+type pullRequestsResponse struct {
+	Count        int           `json:"count"`
+	PullRequests []PullRequest `json:"value"`
+}
+
+// ListPullRequests returns pull requests in the project matching criteria.
+func (c *Client) ListPullRequests(ctx context.Context, criteria PullRequestCriteria) ([]PullRequest, error) {
+	query := url.Values{}
+	if criteria.Status != "" {
+		query.Set("searchCriteria.status", criteria.Status)
+	}
+	if criteria.CreatorID != "" {
+		query.Set("searchCriteria.creatorId", criteria.CreatorID)
+	}
+	if criteria.ReviewerID != "" {
+		query.Set("searchCriteria.reviewerId", criteria.ReviewerID)
+	}
+	var response pullRequestsResponse
+	if err := c.do(ctx, http.MethodGet, c.projectURL("git/pullrequests", query), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.PullRequests, nil
+}
+
+// GetPullRequest returns a pull request of the project by ID.
+func (c *Client) GetPullRequest(ctx context.Context, id int) (*PullRequest, error) {
+	var pr PullRequest
+	if err := c.do(ctx, http.MethodGet, c.projectURL(fmt.Sprintf("git/pullrequests/%d", id), nil), nil, &pr); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// UpdatePullRequest changes a pull request, e.g. to complete it or set it
+// to complete automatically.
+func (c *Client) UpdatePullRequest(ctx context.Context, repositoryID string, id int, update PullRequestUpdate) (*PullRequest, error) {
+	path := fmt.Sprintf("git/repositories/%s/pullrequests/%d", url.PathEscape(repositoryID), id)
+	var pr PullRequest
+	if err := c.do(ctx, http.MethodPatch, c.projectURL(path, nil), update, &pr); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// Comment thread statuses.
+const (
+	ThreadActive  = "active"
+	ThreadPending = "pending"
+	ThreadFixed   = "fixed"
+)
+
+type CommentThread struct {
+	ID            int            `json:"id,omitempty"`
+	Status        string         `json:"status,omitempty"`
+	IsDeleted     bool           `json:"isDeleted,omitempty"`
+	Comments      []Comment      `json:"comments"`
+	ThreadContext *ThreadContext `json:"threadContext,omitempty"`
+}
+
+type Comment struct {
+	ID          int       `json:"id,omitempty"`
+	Author      *Identity `json:"author,omitempty"`
+	Content     string    `json:"content"`
+	CommentType string    `json:"commentType,omitempty"`
+}
+
+// ThreadContext places a thread on a line of a file in the pull request.
+type ThreadContext struct {
+	FilePath       string        `json:"filePath"`
+	RightFileStart *FilePosition `json:"rightFileStart,omitempty"`
+	RightFileEnd   *FilePosition `json:"rightFileEnd,omitempty"`
+}
+
+type FilePosition struct {
+	Line   int `json:"line"`
+	Offset int `json:"offset"`
+}
+
+// Unresolved reports whether the thread is a discussion that still needs
+// to be resolved.
+func (t *CommentThread) Unresolved() bool {
+	if t.IsDeleted || len(t.Comments) == 0 || t.Comments[0].CommentType == "system" {
+		return false
+	}
+	return t.Status == ThreadActive || t.Status == ThreadPending
+}
+
+type threadsResponse struct {
+	Count   int             `json:"count"`
+	Threads []CommentThread `json:"value"`
+}
+
+// ListPullRequestThreads returns the comment threads of a pull request.
+func (c *Client) ListPullRequestThreads(ctx context.Context, repositoryID string, pullRequestID int) ([]CommentThread, error) {
+	path := fmt.Sprintf("git/repositories/%s/pullrequests/%d/threads", url.PathEscape(repositoryID), pullRequestID)
+	var response threadsResponse
+	if err := c.do(ctx, http.MethodGet, c.projectURL(path, nil), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Threads, nil
+}
+
+// CreateThread starts a comment thread on a pull request.
+func (c *Client) CreateThread(ctx context.Context, repositoryID string, pullRequestID int, thread CommentThread) (*CommentThread, error) {
+	path := fmt.Sprintf("git/repositories/%s/pullrequests/%d/threads", url.PathEscape(repositoryID), pullRequestID)
+	var created CommentThread
+	if err := c.do(ctx, http.MethodPost, c.projectURL(path, nil), thread, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// ResourceRef points at another resource, such as a linked work item.
+type ResourceRef struct {
+	ID  string `json:"id"`
+	URL string `json:"url,omitempty"`
+}
+
+type resourceRefsResponse struct {
+	Count int           `json:"count"`
+	Refs  []ResourceRef `json:"value"`
+}
+
+// ListPullRequestWorkItems returns the work items linked to a pull request.
+func (c *Client) ListPullRequestWorkItems(ctx context.Context, repositoryID string, pullRequestID int) ([]ResourceRef, error) {
+	path := fmt.Sprintf("git/repositories/%s/pullrequests/%d/workitems", url.PathEscape(repositoryID), pullRequestID)
+	var response resourceRefsResponse
+	if err := c.do(ctx, http.MethodGet, c.projectURL(path, nil), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Refs, nil
+}
+
+// VotePullRequest records a reviewer's vote on a pull request.
+func (c *Client) VotePullRequest(ctx context.Context, repositoryID string, pullRequestID int, reviewerID string, vote int) error {
+	path := fmt.Sprintf("git/repositories/%s/pullrequests/%d/reviewers/%s", url.PathEscape(repositoryID), pullRequestID, url.PathEscape(reviewerID))
+	return c.do(ctx, http.MethodPut, c.projectURL(path, nil), map[string]int{"vote": vote}, nil)
+}
+
+type reviewersResponse struct {
+	Count     int        `json:"count"`
+	Reviewers []Reviewer `json:"value"`
+}
+
+// AddPullRequestReviewers adds users or groups, by identity ID, as
+// reviewers of a pull request, required ones if required is set. Existing
+// reviewers keep their votes.
+func (c *Client) AddPullRequestReviewers(ctx context.Context, repositoryID string, pullRequestID int, reviewerIDs []string, required bool) ([]Reviewer, error) {
+	path := fmt.Sprintf("git/repositories/%s/pullrequests/%d/reviewers", url.PathEscape(repositoryID), pullRequestID)
+	type reviewer struct {
+		ID         string `json:"id"`
+		IsRequired bool   `json:"isRequired,omitempty"`
+	}
+	body := make([]reviewer, len(reviewerIDs))
+	for i, id := range reviewerIDs {
+		body[i] = reviewer{ID: id, IsRequired: required}
+	}
+	var response reviewersResponse
+	if err := c.do(ctx, http.MethodPost, c.projectURL(path, nil), body, &response); err != nil {
+		return nil, err
+	}
+	return response.Reviewers, nil
+}
+
+// GetRepository returns a Git repository of the project by name or ID.
+func (c *Client) GetRepository(ctx context.Context, nameOrID string) (*Repository, error) {
+	var repo Repository
+	if err := c.do(ctx, http.MethodGet, c.projectURL("git/repositories/"+url.PathEscape(nameOrID), nil), nil, &repo); err != nil {
+		return nil, err
+	}
+	return &repo, nil
+}
+
+type repositoriesResponse struct {
+	Count        int          `json:"count"`
+	Repositories []Repository `json:"value"`
+}
+
+// ListRepositories returns the Git repositories of the project.
+func (c *Client) ListRepositories(ctx context.Context) ([]Repository, error) {
+	var response repositoriesResponse
+	if err := c.do(ctx, http.MethodGet, c.projectURL("git/repositories", nil), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Repositories, nil
+}
+
+// BranchExists reports whether a Git repository of the project has a
+// branch.
+func (c *Client) BranchExists(ctx context.Context, repositoryID, branch string) (bool, error) {
+	commit, err := c.BranchCommit(ctx, repositoryID, branch)
+	return commit != "", err
+}
+
+// BranchCommit returns the commit a branch of a Git repository points at,
+// or "" when there is no such branch.
+func (c *Client) BranchCommit(ctx context.Context, repositoryID, branch string) (string, error) {
+	name := BranchRef(branch)
+	var response struct {
+		Refs []struct {
+			Name     string `json:"name"`
+			ObjectID string `json:"objectId"`
+		} `json:"value"`
+	}
+	query := url.Values{"filter": {strings.TrimPrefix(name, "refs/")}}
+	if err := c.do(ctx, http.MethodGet, c.projectURL("git/repositories/"+url.PathEscape(repositoryID)+"/refs", query), nil, &response); err != nil {
+		return "", err
+	}
+	// The filter matches by prefix
+	for _, ref := range response.Refs {
+		if ref.Name == name {
+			return ref.ObjectID, nil
+		}
+	}
+	return "", nil
+}
+
+// GetFileContent returns the content of a file in a Git repository at a
+// branch, or at the default branch when branch is empty.
+func (c *Client) GetFileContent(ctx context.Context, repositoryID, path, branch string) (string, error) {
+	query := url.Values{}
+	if branch != "" {
+		query.Set("versionDescriptor.version", strings.TrimPrefix(branch, "refs/heads/"))
+		query.Set("versionDescriptor.versionType", "branch")
+	}
+	return c.getFile(ctx, repositoryID, path, query)
+}
+
+// GetFileAtCommit returns the content of a file in a Git repository as of
+// a commit.
+func (c *Client) GetFileAtCommit(ctx context.Context, repositoryID, path, commitID string) (string, error) {
+	query := url.Values{"versionDescriptor.version": {commitID}, "versionDescriptor.versionType": {"commit"}}
+	return c.getFile(ctx, repositoryID, path, query)
+}
+
+func (c *Client) getFile(ctx context.Context, repositoryID, path string, query url.Values) (string, error) {
+	query.Set("path", path)
+	query.Set("includeContent", "true")
+	query.Set("$format", "json")
+	var item struct {
+		Content string `json:"content"`
+	}
+	if err := c.do(ctx, http.MethodGet, c.projectURL("git/repositories/"+url.PathEscape(repositoryID)+"/items", query), nil, &item); err != nil {
+		return "", err
+	}
+	return item.Content, nil
+}
+
+// Change is a file changed between two commits. ChangeType is a comma
+// separated list such as "edit" or "edit, rename".
+type Change struct {
+	ChangeType   string `json:"changeType"`
+	OriginalPath string `json:"originalPath,omitempty"`
+	Item         struct {
+		Path          string `json:"path"`
+		IsFolder      bool   `json:"isFolder,omitempty"`
+		GitObjectType string `json:"gitObjectType,omitempty"`
+	} `json:"item"`
+}
+
+// CommitDiffs lists the files changed between two commits.
+type CommitDiffs struct {
+	BaseCommit   string   `json:"baseCommit"`
+	TargetCommit string   `json:"targetCommit"`
+	CommonCommit string   `json:"commonCommit"`
+	Changes      []Change `json:"changes"`
+	// AllChangesIncluded is false when the service stopped listing changes
+	// before the last one
+	AllChangesIncluded bool `json:"allChangesIncluded"`
+}
+
+// diffPage is the most changes DiffCommits asks for at once.
+const diffPage = 2000
+
+// DiffCommits lists the files changed on target since its common ancestor
+// with base, a page at a time until all of them are listed or the service
+// stops returning more; AllChangesIncluded tells which.
+func (c *Client) DiffCommits(ctx context.Context, repositoryID, base, target string) (*CommitDiffs, error) {
+	var diffs CommitDiffs
+	for {
+		query := url.Values{
+			"baseVersion":       {base},
+			"baseVersionType":   {"commit"},
+			"targetVersion":     {target},
+			"targetVersionType": {"commit"},
+			"diffCommonCommit":  {"true"},
+			"$top":              {strconv.Itoa(diffPage)},
+			"$skip":             {strconv.Itoa(len(diffs.Changes))},
+		}
+		var page CommitDiffs
+		if err := c.do(ctx, http.MethodGet, c.projectURL("git/repositories/"+url.PathEscape(repositoryID)+"/diffs/commits", query), nil, &page); err != nil {
+			return nil, err
+		}
+		more := len(page.Changes) > 0
+		page.Changes = append(diffs.Changes, page.Changes...)
+		diffs = page
+		if diffs.AllChangesIncluded || !more {
+			return &diffs, nil
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimit is what Azure DevOps reports about the rate limiting of the
+// identity behind the PAT. It only sends the headers once requests are
+// close to being, or are being, delayed; Reported is false otherwise.
+type RateLimit struct {
+	Reported   bool
+	Resource   string  // the limited resource, such as Core or ATExhaustion
+	Limit      float64 // throughput units allowed per window
+	Remaining  float64
+	Reset      time.Time
+	Delay      time.Duration // how long the last request was held back
+	RetryAfter time.Duration // set once requests are refused
+}
+
+// GetRateLimit makes a cheap request and reads the rate limiting headers
+// of the answer.
+func (c *Client) GetRateLimit(ctx context.Context) (*RateLimit, error) {
+	// Being throttled is what this reports, so it is not waited out
+	req, err := http.NewRequestWithContext(withoutRetries(ctx), http.MethodGet, c.orgURL("connectionData", nil), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.send(req, true)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	h := resp.Header
+	limit := &RateLimit{Resource: h.Get("X-RateLimit-Resource")}
+	number := func(name string) float64 {
+		if v := h.Get(name); v != "" {
+			limit.Reported = true
+			n, _ := strconv.ParseFloat(v, 64)
+			return n
+		}
+		return 0
+	}
+	limit.Limit = number("X-RateLimit-Limit")
+	limit.Remaining = number("X-RateLimit-Remaining")
+	if reset := number("X-RateLimit-Reset"); reset > 0 {
+		limit.Reset = time.Unix(int64(reset), 0)
+	}
+	limit.Delay = time.Duration(number("X-RateLimit-Delay") * float64(time.Second))
+	limit.RetryAfter = time.Duration(number("Retry-After")) * time.Second
+	// Being refused is a finding here, not an error
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return limit, nil
+	}
+	if err := checkResponse(resp, data); err != nil {
+		return nil, err
+	}
+	return limit, nil
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+const retentionAPIVersion = "7.1-preview.1"
+
+// RetentionSetting is a project retention setting and the range the
+// organization allows for it.
+type RetentionSetting struct {
+	Min   int `json:"min"`
+	Max   int `json:"max"`
+	Value int `json:"value"`
+}
+
+// ProjectRetention is how long a project keeps runs and their artifacts.
+// Days, except for the runs kept per protected branch.
+type ProjectRetention struct {
+	PurgeArtifacts               RetentionSetting  `json:"purgeArtifacts"`
+	PurgePullRequestRuns         RetentionSetting  `json:"purgePullRequestRuns"`
+	PurgeRuns                    RetentionSetting  `json:"purgeRuns"`
+	RetainRunsPerProtectedBranch *RetentionSetting `json:"retainRunsPerProtectedBranch,omitempty"`
+}
+
+// GetProjectRetention returns the retention settings of the project.
+func (c *Client) GetProjectRetention(ctx context.Context) (*ProjectRetention, error) {
+	var retention ProjectRetention
+	query := url.Values{"api-version": {retentionAPIVersion}}
+	if err := c.do(ctx, http.MethodGet, c.projectURL("build/retention", query), nil, &retention); err != nil {
+		return nil, err
+	}
+	return &retention, nil
+}
+
+// SetRunRetention changes how many days the project keeps runs.
+func (c *Client) SetRunRetention(ctx context.Context, days int) (*ProjectRetention, error) {
+	body := map[string]any{"runRetention": map[string]int{"value": days}}
+	var retention ProjectRetention
+	query := url.Values{"api-version": {retentionAPIVersion}}
+	if err := c.do(ctx, http.MethodPatch, c.projectURL("build/retention", query), body, &retention); err != nil {
+		return nil, err
+	}
+	return &retention, nil
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// NamespaceBuild is the security namespace of pipelines and their runs.
+// Its tokens are the project ID, followed by the pipeline's folders and ID,
+// such as "<project-id>/Team/Service/12".
+const NamespaceBuild = "33344d9c-fc72-4d6f-aba5-fa317101a7e9"
+
+// Permission bits of NamespaceBuild.
+const (
+	BuildPermissionQueue = 128
+	BuildPermissionEdit  = 2048
+)
+
+// ACE is the permissions an access control entry allows and denies one
+// identity, as bit masks of the namespace's permissions.
+type ACE struct {
+	Descriptor string `json:"descriptor"`
+	Allow      int    `json:"allow"`
+	Deny       int    `json:"deny"`
+}
+
+// ACL is the access control list of a security token.
+type ACL struct {
+	Token              string         `json:"token"`
+	InheritPermissions bool           `json:"inheritPermissions"`
+	ACEs               map[string]ACE `json:"acesDictionary"` // by identity descriptor
+}
+
+type aclsResponse struct {
+	Count int   `json:"count"`
+	ACLs  []ACL `json:"value"`
+}
+
+// ListACLs returns the access control list of a token in a namespace, and
+// with recurse those of the tokens below it. Tokens without entries of
+// their own are left out.
+func (c *Client) ListACLs(ctx context.Context, namespaceID, token string, recurse bool) ([]ACL, error) {
+	query := url.Values{"token": {token}, "recurse": {strconv.FormatBool(recurse)}}
+	var response aclsResponse
+	if err := c.do(ctx, http.MethodGet, c.orgURL("accesscontrollists/"+url.PathEscape(namespaceID), query), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.ACLs, nil
+}
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+const (
+	serviceEndpointsAPIVersion = "7.1-preview.4"
+	endpointProxyAPIVersion    = "7.1-preview.1"
+)
+
+// ServiceEndpoint is a service connection of the project.
+type ServiceEndpoint struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	URL     string `json:"url"`
+	IsReady bool   `json:"isReady"`
+	// Authorization is how the connection signs in. Secret parameters are
+	// never returned.
+	Authorization struct {
+		Scheme     string            `json:"scheme"` // ServicePrincipal, WorkloadIdentityFederation, Token, ...
+		Parameters map[string]string `json:"parameters,omitempty"`
+	} `json:"authorization"`
+	OperationStatus *struct {
+		State         string `json:"state"`
+		StatusMessage string `json:"statusMessage"`
+	} `json:"operationStatus,omitempty"`
+}
+
+type serviceEndpointsResponse struct {
+	Count     int               `json:"count"`
+	Endpoints []ServiceEndpoint `json:"value"`
+}
+
+// ListServiceEndpoints returns the service connections of the project.
+func (c *Client) ListServiceEndpoints(ctx context.Context) ([]ServiceEndpoint, error) {
+	var response serviceEndpointsResponse
+	query := url.Values{"api-version": {serviceEndpointsAPIVersion}}
+	if err := c.do(ctx, http.MethodGet, c.projectURL("serviceendpoint/endpoints", query), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Endpoints, nil
+}
+
+// GetServiceEndpoint returns a service connection. Azure DevOps answers
+// with no content rather than 404 for one that was deleted, which is
+// reported as a nil endpoint.
+func (c *Client) GetServiceEndpoint(ctx context.Context, endpointID string) (*ServiceEndpoint, error) {
+	var endpoint *ServiceEndpoint
+	query := url.Values{"api-version": {serviceEndpointsAPIVersion}}
+	if err := c.do(ctx, http.MethodGet, c.projectURL("serviceendpoint/endpoints/"+url.PathEscape(endpointID), query), nil, &endpoint); err != nil {
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+// EndpointProxyResult is the answer of a data source queried through a
+// service connection. StatusCode and ErrorMessage are those of the call
+// Azure DevOps made with the connection's credentials.
+type EndpointProxyResult struct {
+	Result       []any  `json:"result"`
+	StatusCode   string `json:"statusCode"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+// QueryServiceEndpoint queries a data source of a service connection's
+// type, such as the secrets of a Key Vault, with the connection's
+// credentials.
+func (c *Client) QueryServiceEndpoint(ctx context.Context, endpointID, dataSource string, parameters map[string]string) (*EndpointProxyResult, error) {
+	request := map[string]any{
+		"dataSourceDetails": map[string]any{"dataSourceName": dataSource, "parameters": parameters},
+	}
+	query := url.Values{"endpointId": {endpointID}, "api-version": {endpointProxyAPIVersion}}
+	var result EndpointProxyResult
+	if err := c.do(ctx, http.MethodPost, c.projectURL("serviceendpoint/endpointproxy", query), request, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,152 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Hosts of source repositories.
+const (
+	HostAzureRepos = "azure-repos"
+	HostGitHub     = "github"
+	HostGitLab     = "gitlab"
+	HostBitbucket  = "bitbucket"
+)
+
+// Host returns where the repository is hosted, as one of the Host
+// constants, or "" when fomo does not know how to link into it. GitLab
+// repositories are built as "Other Git" ones and are told by their URL.
+func (r SourceRepository) Host() string {
+	switch strings.ToLower(r.Type) {
+	case "tfsgit", "azurereposgit":
+		return HostAzureRepos
+	case "github", "githubenterprise":
+		return HostGitHub
+	case "bitbucket":
+		return HostBitbucket
+	}
+	web := strings.ToLower(r.WebURL())
+	switch {
+	case strings.Contains(web, "gitlab"):
+		return HostGitLab
+	case strings.HasPrefix(web, "https://github.com/"):
+		return HostGitHub
+	case strings.HasPrefix(web, "https://bitbucket.org/"):
+		return HostBitbucket
+	}
+	return ""
+}
+
+// scpURL matches the scp-like syntax of SSH clone URLs, as in
+// git@gitlab.com:group/project.git.
+var scpURL = regexp.MustCompile(`^[\w.-]+@([\w.-]+):(.+)$`)
+
+// WebURL returns the browser link of the repository, from its clone URL.
+func (r SourceRepository) WebURL() string {
+	web := r.URL
+	if m := scpURL.FindStringSubmatch(web); m != nil {
+		web = "https://" + m[1] + "/" + m[2]
+	} else if u, err := url.Parse(web); err == nil && u.Host != "" {
+		u.User = nil
+		if u.Scheme != "http" {
+			u.Scheme = "https"
+		}
+		web = u.String()
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(web, "/"), ".git")
+}
+
+// CommitURL returns the browser link of a commit of the repository, or ""
+// when its host is not known.
+func (r SourceRepository) CommitURL(sha string) string {
+	if sha == "" {
+		return ""
+	}
+	switch r.Host() {
+	case HostAzureRepos, HostGitHub:
+		return r.WebURL() + "/commit/" + sha
+	case HostGitLab:
+		return r.WebURL() + "/-/commit/" + sha
+	case HostBitbucket:
+		return r.WebURL() + "/commits/" + sha
+	}
+	return ""
+}
+
+// PullRequestURL returns the browser link of a pull request, or merge
+// request on GitLab, of the repository, or "" when its host is not known.
+func (r SourceRepository) PullRequestURL(number int) string {
+	switch r.Host() {
+	case HostAzureRepos:
+		return fmt.Sprintf("%s/pullrequest/%d", r.WebURL(), number)
+	case HostGitHub:
+		return fmt.Sprintf("%s/pull/%d", r.WebURL(), number)
+	case HostGitLab:
+		return fmt.Sprintf("%s/-/merge_requests/%d", r.WebURL(), number)
+	case HostBitbucket:
+		return fmt.Sprintf("%s/pull-requests/%d", r.WebURL(), number)
+	}
+	return ""
+}
+
+// pullRequestRef matches the refs pull request runs build: refs/pull/N/merge
+// or refs/pull/N/head, and refs/merge-requests/N/head on GitLab.
+var pullRequestRef = regexp.MustCompile(`^refs/(?:pull|merge-requests)/(\d+)/(?:merge|head)$`)
+
+// PullRequestNumber returns the number of the pull request whose ref a run
+// built, or 0 if ref is not one.
+func PullRequestNumber(ref string) int {
+	m := pullRequestRef.FindStringSubmatch(ref)
+	if m == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[1])
+	return n
+}
+
+// PullRequest returns the number of the pull request the run validated, or
+// 0 if it did not validate one.
+func (b *Build) PullRequest() int {
+	if n, err := strconv.Atoi(b.TriggerInfo["pr.number"]); err == nil {
+		return n
+	}
+	return PullRequestNumber(b.SourceBranch)
+}
+
+// BranchName returns the ref of the branch the run built. For a pull
+// request run, that is the source branch of the pull request rather than
+// the merge ref, when the trigger recorded it.
+func (b *Build) BranchName() string {
+	if branch := b.TriggerInfo["pr.sourceBranch"]; branch != "" && b.PullRequest() != 0 {
+		return branch
+	}
+	return b.SourceBranch
+}
+
+// HeadCommit returns the commit the run was queued for: the head of the
+// pull request for a pull request run, whose SourceVersion is the merge
+// commit the service made.
+func (b *Build) HeadCommit() string {
+	if sha := b.TriggerInfo["pr.sourceSha"]; sha != "" {
+		return sha
+	}
+	return b.SourceVersion
+}
+
+// CommitURL returns the browser link of the commit the run built, on the
+// host of its repository.
+func (b *Build) CommitURL() string {
+	return b.Repository.CommitURL(b.HeadCommit())
+}
+
+// PullRequestURL returns the browser link of the pull request the run
+// validated, or "".
+func (b *Build) PullRequestURL() string {
+	if n := b.PullRequest(); n != 0 {
+		return b.Repository.PullRequestURL(n)
+	}
+	return ""
+}
@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Task definition types.
+const (
+	TaskTypeTask      = "task"
+	TaskTypeTaskGroup = "metaTask"
+)
+
+// TaskDefinition is a task installed in the organization: built in, from
+// an extension, or a task group.
+type TaskDefinition struct {
+	ID           string      `json:"id"`
+	Name         string      `json:"name"`
+	FriendlyName string      `json:"friendlyName"`
+	Version      TaskVersion `json:"version"`
+	Deprecated   bool        `json:"deprecated,omitempty"`
+	// DefinitionType is TaskTypeTask or TaskTypeTaskGroup
+	DefinitionType string `json:"definitionType,omitempty"`
+	// ContributionIdentifier is set for tasks installed by an extension
+	ContributionIdentifier string `json:"contributionIdentifier,omitempty"`
+	// Execution is keyed by the handlers the task can run with, such as
+	// Node10, Node16 or PowerShell3
+	Execution map[string]any `json:"execution,omitempty"`
+}
+
+type TaskVersion struct {
+	Major  int  `json:"major"`
+	Minor  int  `json:"minor"`
+	Patch  int  `json:"patch"`
+	IsTest bool `json:"isTest,omitempty"`
+}
+
+func (v TaskVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+type taskDefinitionsResponse struct {
+	Count int              `json:"count"`
+	Tasks []TaskDefinition `json:"value"`
+}
+
+// ListTaskDefinitions returns the tasks installed in the organization, the
+// latest version of each major version.
+func (c *Client) ListTaskDefinitions(ctx context.Context) ([]TaskDefinition, error) {
+	var response taskDefinitionsResponse
+	if err := c.do(ctx, http.MethodGet, c.orgURL("distributedtask/tasks", nil), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Tasks, nil
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// testResultsPage is the most test results the API returns at once.
+const testResultsPage = 1000
+
+// ListTestRuns returns the test runs a run published.
+func (c *Client) ListTestRuns(ctx context.Context, buildID int) ([]TestRun, error) {
+	return listTestRuns[TestRun](ctx, c, buildID)
+}
+
+// ListTestRunsRaw returns the test runs a run published, as raw JSON
+// fields.
+func (c *Client) ListTestRunsRaw(ctx context.Context, buildID int) ([]map[string]any, error) {
+	return listTestRuns[map[string]any](ctx, c, buildID)
+}
+
+func listTestRuns[T any](ctx context.Context, c *Client, buildID int) ([]T, error) {
+	query := url.Values{"buildUri": {fmt.Sprintf("vstfs:///Build/Build/%d", buildID)}, "includeRunDetails": {"true"}}
+	var response struct {
+		Value []T `json:"value"`
+	}
+	if err := c.do(ctx, http.MethodGet, c.projectURL("test/runs", query), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Value, nil
+}
+
+// ListTestResults returns every result of a test run.
+func (c *Client) ListTestResults(ctx context.Context, testRunID int) ([]TestCaseResult, error) {
+	return listTestResults[TestCaseResult](ctx, c, testRunID)
+}
+
+// ListTestResultsRaw returns every result of a test run, as raw JSON
+// fields.
+func (c *Client) ListTestResultsRaw(ctx context.Context, testRunID int) ([]map[string]any, error) {
+	return listTestResults[map[string]any](ctx, c, testRunID)
+}
+
+func listTestResults[T any](ctx context.Context, c *Client, testRunID int) ([]T, error) {
+	var results []T
+	for skip := 0; ; skip += testResultsPage {
+		query := url.Values{"$top": {strconv.Itoa(testResultsPage)}, "$skip": {strconv.Itoa(skip)}}
+		var response struct {
+			Value []T `json:"value"`
+		}
+		if err := c.do(ctx, http.MethodGet, c.projectURL(fmt.Sprintf("test/Runs/%d/results", testRunID), query), nil, &response); err != nil {
+			return nil, err
+		}
+		results = append(results, response.Value...)
+		if len(response.Value) < testResultsPage {
+			return results, nil
+		}
+	}
+}
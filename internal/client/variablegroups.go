@@ -0,0 +1,94 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const variableGroupsAPIVersion = "7.1-preview.2"
+
+// Variable group types.
+const (
+	VariableGroupVsts          = "Vsts"
+	VariableGroupAzureKeyVault = "AzureKeyVault"
+)
+
+type VariableGroup struct {
+	ID           int                      `json:"id,omitempty"`
+	Name         string                   `json:"name"`
+	Description  string                   `json:"description,omitempty"`
+	Type         string                   `json:"type"`
+	Variables    map[string]GroupVariable `json:"variables"`
+	ProviderData *VariableGroupProvider   `json:"providerData,omitempty"`
+	CreatedBy    *Identity                `json:"createdBy,omitempty"`
+	ModifiedOn   time.Time                `json:"modifiedOn,omitempty"`
+	References   []VariableGroupReference `json:"variableGroupProjectReferences,omitempty"`
+}
+
+// GroupVariable is a variable in a group. Secret values are never returned
+// by the API. In Key Vault-linked groups each variable is a secret of the
+// vault, with its content type and expiry as of the last refresh.
+type GroupVariable struct {
+	Value       *string    `json:"value"`
+	IsSecret    bool       `json:"isSecret,omitempty"`
+	Enabled     *bool      `json:"enabled,omitempty"`
+	ContentType string     `json:"contentType,omitempty"`
+	Expires     *time.Time `json:"expires,omitempty"`
+}
+
+// VariableGroupProvider holds the Key Vault link of a linked group.
+type VariableGroupProvider struct {
+	ServiceEndpointID string     `json:"serviceEndpointId,omitempty"`
+	Vault             string     `json:"vault,omitempty"`
+	LastRefreshedOn   *time.Time `json:"lastRefreshedOn,omitempty"`
+}
+
+type VariableGroupReference struct {
+	Name             string           `json:"name"`
+	Description      string           `json:"description,omitempty"`
+	ProjectReference ProjectReference `json:"projectReference"`
+}
+
+type ProjectReference struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type variableGroupsResponse struct {
+	Count  int             `json:"count"`
+	Groups []VariableGroup `json:"value"`
+}
+
+// ListVariableGroups returns the variable groups of the project.
+func (c *Client) ListVariableGroups(ctx context.Context) ([]VariableGroup, error) {
+	var response variableGroupsResponse
+	query := url.Values{"api-version": {variableGroupsAPIVersion}}
+	if err := c.do(ctx, http.MethodGet, c.projectURL("distributedtask/variablegroups", query), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Groups, nil
+}
+
+// GetVariableGroup returns a single variable group.
+func (c *Client) GetVariableGroup(ctx context.Context, groupID int) (*VariableGroup, error) {
+	var group VariableGroup
+	query := url.Values{"api-version": {variableGroupsAPIVersion}}
+	if err := c.do(ctx, http.MethodGet, c.projectURL(fmt.Sprintf("distributedtask/variablegroups/%d", groupID), query), nil, &group); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// CreateVariableGroup creates a variable group. Its project references
+// decide which projects share it.
+func (c *Client) CreateVariableGroup(ctx context.Context, group VariableGroup) (*VariableGroup, error) {
+	var created VariableGroup
+	query := url.Values{"api-version": {variableGroupsAPIVersion}}
+	if err := c.do(ctx, http.MethodPost, c.orgURL("distributedtask/variablegroups", query), group, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// wikiAPIVersion is the version of the wiki pages API.
+const wikiAPIVersion = "7.1"
+
+// WikiPage is a page of a project or code wiki.
+type WikiPage struct {
+	Path      string `json:"path"`
+	RemoteURL string `json:"remoteUrl,omitempty"` // browser link
+}
+
+// PutWikiPage creates the page at path in a wiki, or replaces its content
+// with a new revision when it exists.
+func (c *Client) PutWikiPage(ctx context.Context, wiki, path, content string) (*WikiPage, error) {
+	pageURL := c.projectURL(fmt.Sprintf("wiki/wikis/%s/pages", url.PathEscape(wiki)), url.Values{
+		"api-version": {wikiAPIVersion},
+		"path":        {path},
+	})
+
+	// Replacing a page requires the ETag of its current revision
+	etag := ""
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.send(req, true)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	switch err := checkResponse(resp, data); {
+	case err == nil:
+		etag = resp.Header.Get("ETag")
+	case resp.StatusCode != http.StatusNotFound:
+		return nil, err
+	}
+
+	body, err := json.Marshal(map[string]string{"content": content})
+	if err != nil {
+		return nil, err
+	}
+	req, err = http.NewRequestWithContext(ctx, http.MethodPut, pageURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	if etag != "" {
+		req.Header.Set("If-Match", etag)
+	}
+	resp, err = c.send(req, true)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if data, err = io.ReadAll(resp.Body); err != nil {
+		return nil, err
+	}
+	if err := checkResponse(resp, data); err != nil {
+		return nil, err
+	}
+	var page WikiPage
+	if err := json.Unmarshal(data, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
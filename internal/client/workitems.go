@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// WorkItem is a work item with the fields fomo reads.
+type WorkItem struct {
+	ID     int `json:"id"`
+	Fields struct {
+		Title string `json:"System.Title"`
+		Type  string `json:"System.WorkItemType"` // Bug, User Story, Feature, Task...
+		State string `json:"System.State"`
+	} `json:"fields"`
+}
+
+// workItemsBatch is how many work items one request may fetch.
+const workItemsBatch = 200
+
+type workItemsResponse struct {
+	Count     int         `json:"count"`
+	WorkItems []*WorkItem `json:"value"`
+}
+
+// GetWorkItems returns the work items with the given IDs. Those that do
+// not exist or cannot be read are left out.
+func (c *Client) GetWorkItems(ctx context.Context, ids []int) ([]WorkItem, error) {
+	var items []WorkItem
+	for start := 0; start < len(ids); start += workItemsBatch {
+		batch := ids[start:min(start+workItemsBatch, len(ids))]
+		list := make([]string, len(batch))
+		for i, id := range batch {
+			list[i] = strconv.Itoa(id)
+		}
+		query := url.Values{
+			"ids":         {strings.Join(list, ",")},
+			"fields":      {"System.Title,System.WorkItemType,System.State"},
+			"errorPolicy": {"omit"},
+		}
+		var response workItemsResponse
+		if err := c.do(ctx, http.MethodGet, c.projectURL("wit/workitems", query), nil, &response); err != nil {
+			return nil, err
+		}
+		for _, item := range response.WorkItems {
+			if item != nil {
+				items = append(items, *item)
+			}
+		}
+	}
+	return items, nil
+}
+
+// WorkItemURL returns the browser link of a work item.
+func (c *Client) WorkItemURL(id int) string {
+	return c.WebURL(fmt.Sprintf("_workitems/edit/%d", id))
+}
@@ -0,0 +1,597 @@
+// Package config resolves fomo's settings from its layered sources. In order
+// of precedence: command-line flags, environment variables, the repo-local
+// .fomo.yaml, the selected profile in the user config file, the team config
+// synced from a shared repository, and finally an interactive prompt.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"fomo/internal/credentials"
+	"fomo/internal/i18n"
+	"fomo/internal/notify"
+	"fomo/internal/yaml"
+)
+
+// Source identifies the layer a setting was resolved from.
+type Source string
+
+const (
+	SourceFlag    Source = "flag"
+	SourceEnv     Source = "env"
+	SourceRepo    Source = "repo"
+	SourceProfile Source = "profile"
+	SourceTeam    Source = "team"
+	SourcePrompt  Source = "prompt"
+
+	SourceCredentials Source = "credentials"
+)
+
+const (
+	EnvOrganization = "FOMO_ORG"
+	EnvProject      = "FOMO_PROJECT"
+	EnvProfile      = "FOMO_PROFILE"
+	EnvConfigFile   = "FOMO_CONFIG"
+	EnvBaseURL      = "FOMO_BASE_URL"
+	EnvPAT          = "AZURE_DEVOPS_PAT"
+	EnvPassphrase   = "FOMO_PASSPHRASE"
+	EnvHooksSecret  = "FOMO_HOOKS_SECRET"
+	EnvSlackSecret  = "FOMO_SLACK_SIGNING_SECRET"
+	EnvTeamsSecret  = "FOMO_TEAMS_SECRET"
+
+	RepoFileName   = ".fomo.yaml"
+	TeamFileName   = "team.yaml" // synced team config, next to the user config file
+	DefaultProfile = "default"
+)
+
+// Settings are the values every layer can contribute.
+type Settings struct {
+	Organization string `yaml:"org"`
+	Project      string `yaml:"project"`
+	BaseURL      string `yaml:"base_url"` // API endpoint, for Azure DevOps Server or a mock server
+}
+
+// Layer is the content of a repo-local .fomo.yaml, of a single profile or
+// of the team config.
+type Layer struct {
+	Settings   `yaml:",inline"`
+	Pipelines  []string                  `yaml:"pipelines"` // default pipelines, by name or ID
+	Branches   map[string]string         `yaml:"branches"`  // default branch per pipeline name or ID
+	Watch      []WatchRule               `yaml:"watch"`
+	Notifiers  map[string]NotifierConfig `yaml:"notifiers"`
+	Statusline StatuslineConfig          `yaml:"statusline"`
+	Polling    PollingConfig             `yaml:"polling"`
+	Alerts     AlertsConfig              `yaml:"alerts"`
+	History    HistoryConfig             `yaml:"history"`
+	GitHub     GitHubConfig              `yaml:"github"`
+	Jira       JiraConfig                `yaml:"jira"`
+	Audit      AuditConfig               `yaml:"audit"`
+	Hooks      HooksConfig               `yaml:"hooks"`
+	ChatOps    ChatOpsConfig             `yaml:"chatops"`
+
+	// SafeMode refuses commands that make changes, such as queuing,
+	// deleting or approving, unless --allow-write is given. AllowWrite
+	// lifts it for a profile; it has no effect in a shared file.
+	SafeMode   bool `yaml:"safe_mode"`
+	AllowWrite bool `yaml:"allow_write"`
+}
+
+// StatuslineConfig customizes the output of fomo statusline.
+type StatuslineConfig struct {
+	Template string        `yaml:"template"` // text/template over the latest run
+	Color    string        `yaml:"color"`    // none, ansi or tmux
+	Emoji    bool          `yaml:"emoji"`
+	TTL      time.Duration `yaml:"ttl"` // how long a fetched status is reused
+}
+
+// WatchRule selects pipeline runs worth watching.
+type WatchRule struct {
+	Pipeline string   `yaml:"pipeline"` // pipeline name, glob or ID
+	Tags     []string `yaml:"tags"`     // pipeline tags, all of which are required
+	Branches []string `yaml:"branches"` // branch globs; empty means any branch
+	Results  []string `yaml:"results"`  // run results to report; empty means all
+	Notify   []string `yaml:"notify"`   // notifiers to alert for matching runs
+
+	Interval time.Duration `yaml:"interval"` // poll interval for these pipelines; overrides polling.interval
+
+	// Unwatch, in a profile, drops the shared rules for the same pipeline
+	// and tags instead of adding a rule.
+	Unwatch bool `yaml:"unwatch"`
+}
+
+// PollingConfig tunes how often watch modes poll Azure DevOps.
+type PollingConfig struct {
+	Interval  time.Duration `yaml:"interval"`   // default poll interval
+	Active    time.Duration `yaml:"active"`     // interval while a run is in progress
+	Idle      time.Duration `yaml:"idle"`       // interval for idle pipelines
+	IdleAfter time.Duration `yaml:"idle_after"` // time without runs after which a pipeline is idle
+}
+
+// AlertsConfig controls how the daemon alerts for a pipeline that keeps
+// failing.
+type AlertsConfig struct {
+	Every  bool          `yaml:"every"`  // alert for every failed run, instead of once per streak of failures
+	Remind time.Duration `yaml:"remind"` // how often to remind while a pipeline keeps failing
+	Window time.Duration `yaml:"window"` // how long after recovering a new failure counts as flapping
+}
+
+// GitHubConfig names the GitHub mirror of a repository, where fomo bridge
+// reports run results. The token comes from FOMO_GITHUB_TOKEN, GITHUB_TOKEN
+// or GH_TOKEN, never from a config file.
+type GitHubConfig struct {
+	Repo    string `yaml:"repo"`    // owner/name
+	APIURL  string `yaml:"api_url"` // for GitHub Enterprise Server; https://api.github.com by default
+	Context string `yaml:"context"` // status context; azure-pipelines/<pipeline> by default
+}
+
+// JiraConfig points at the Jira site whose issues commit messages mention.
+// The API token comes from FOMO_JIRA_TOKEN or from fomo auth jira.
+type JiraConfig struct {
+	URL      string   `yaml:"url"`      // such as https://contoso.atlassian.net
+	User     string   `yaml:"user"`     // email address for Jira Cloud; empty for a Server or Data Center PAT
+	Projects []string `yaml:"projects"` // issue key prefixes to look for; empty means any
+}
+
+// AuditConfig sends the audit trail of changes made through fomo to a
+// webhook, besides the local audit file.
+type AuditConfig struct {
+	Webhook string `yaml:"webhook"` // receives each record as a JSON POST
+}
+
+// HooksConfig turns the Azure DevOps service hooks the daemon receives
+// into notifications.
+type HooksConfig struct {
+	Secret string     `yaml:"secret"` // password service hooks send with basic authentication
+	Rules  []HookRule `yaml:"rules"`
+}
+
+// HookRule routes the service hook events it matches to notifiers. When,
+// Title, Message and URL are text/templates over the event's JSON payload.
+type HookRule struct {
+	Name    string   `yaml:"name"`    // for logs
+	Event   string   `yaml:"event"`   // event type glob, such as build.complete; empty means any
+	When    string   `yaml:"when"`    // the rule applies when this renders "true"; empty means always
+	Notify  []string `yaml:"notify"`  // notifiers to send matching events to
+	Title   string   `yaml:"title"`   // default: the event's message
+	Message string   `yaml:"message"` // default: the event's detailed message
+	URL     string   `yaml:"url"`     // default: the web link of the event's resource
+}
+
+// ChatOpsConfig lets the daemon take commands from Slack slash commands and
+// Teams outgoing webhooks.
+type ChatOpsConfig struct {
+	SlackSecret string            `yaml:"slack_signing_secret"` // signing secret of the Slack app
+	TeamsSecret string            `yaml:"teams_secret"`         // security token of the Teams outgoing webhook
+	Channels    map[string]string `yaml:"channels"`             // channel ID to the profile it commands; empty allows every channel
+}
+
+// HistoryConfig controls how long the local run history is kept.
+type HistoryConfig struct {
+	RetentionDays int `yaml:"retention_days"` // individual runs; older ones are rolled up per day
+	RollupDays    int `yaml:"rollup_days"`    // daily rollups; 0 keeps them forever
+}
+
+// NotifierConfig configures a named notification destination.
+type NotifierConfig struct {
+	Type    string            `yaml:"type"`
+	Options map[string]string `yaml:"options"`
+}
+
+// File is the user config file holding named profiles.
+type File struct {
+	DefaultProfile string           `yaml:"default_profile"`
+	Locale         string           `yaml:"locale"`    // language of prompts and messages, e.g. es
+	Timezone       string           `yaml:"timezone"`  // time zone times are shown in: local, UTC or an IANA name
+	Durations      string           `yaml:"durations"` // human or iso
+	Profiles       map[string]Layer `yaml:"profiles"`
+}
+
+// Config is the fully resolved configuration for a command.
+type Config struct {
+	Settings
+	PAT     string
+	Profile string
+
+	// EncryptedPAT is the profile's stored PAT when it was left locked
+	// because no passphrase was available.
+	EncryptedPAT string
+
+	Pipelines  []string
+	Branches   map[string]string
+	Watch      []WatchRule
+	Notifiers  map[string]NotifierConfig
+	Statusline StatuslineConfig
+	Polling    PollingConfig
+	Alerts     AlertsConfig
+	History    HistoryConfig
+	GitHub     GitHubConfig
+	Jira       JiraConfig
+	Audit      AuditConfig
+	Hooks      HooksConfig
+	ChatOps    ChatOpsConfig
+
+	SafeMode   bool // set by any layer
+	AllowWrite bool // set by the profile
+
+	// Sources records which layer each setting came from, keyed by the
+	// setting name ("org", "project", "base_url", "pat").
+	Sources map[string]Source
+
+	// Warnings report settings of the shared files that were ignored, see
+	// Layer.dropPersonal
+	Warnings []string
+
+	ProfileFile string // user config file, if it exists
+	RepoFile    string // repo-local .fomo.yaml, if one was found
+	TeamFile    string // synced team config, if there is one
+
+	CredentialsFile string // encrypted credentials, next to the user config file
+	HistoryDir      string // local run history, next to the user config file
+}
+
+// Loader resolves a Config from its layers.
+type Loader struct {
+	Flags   Settings // values given on the command line
+	Profile string   // profile selected with --profile
+	PAT     string   // PAT given with --pat-stdin or --pat-file
+
+	Getenv func(string) string // defaults to os.Getenv
+	Dir    string              // directory searched for .fomo.yaml; defaults to the working directory
+
+	// Prompt asks the user for a missing value; nil disables prompting.
+	Prompt       func(label string) string
+	PromptSecret func(label string) string
+
+	// Passphrase asks for the passphrase of the encrypted credentials file
+	// when FOMO_PASSPHRASE is not set; nil leaves the PAT locked.
+	Passphrase func(label string) string
+
+	// Isolated resolves the profile on its own, ignoring the environment's
+	// settings and PAT and any repo-local .fomo.yaml, for a daemon serving
+	// several profiles at once.
+	Isolated bool
+}
+
+// UserConfigPath returns the location of the user config file.
+func UserConfigPath(getenv func(string) string) (string, error) {
+	if path := getenv(EnvConfigFile); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate user config directory: %v", err)
+	}
+	return filepath.Join(dir, "fomo", "config.yaml"), nil
+}
+
+// ReadFile reads and decodes a YAML config file into v. A missing file is
+// reported with an error satisfying errors.Is(err, os.ErrNotExist).
+func ReadFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return nil
+}
+
+// Load resolves every layer and returns the merged configuration.
+func (l *Loader) Load() (*Config, error) {
+	getenv := l.Getenv
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	cfg := &Config{Sources: map[string]Source{}}
+
+	// User profile
+	profilePath, err := UserConfigPath(getenv)
+	if err != nil {
+		return nil, err
+	}
+	var file File
+	if err := ReadFile(profilePath, &file); err == nil {
+		cfg.ProfileFile = profilePath
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	cfg.Profile = firstNonEmpty(l.Profile, getenv(EnvProfile), file.DefaultProfile, DefaultProfile)
+	profile, ok := file.Profiles[cfg.Profile]
+	if !ok && cfg.Profile != DefaultProfile && (l.Profile != "" || getenv(EnvProfile) != "") {
+		return nil, fmt.Errorf("profile %q is not defined in %s", cfg.Profile, profilePath)
+	}
+
+	// Team config, synced by fomo config sync
+	var team Layer
+	teamPath := filepath.Join(filepath.Dir(profilePath), TeamFileName)
+	if err := ReadFile(teamPath, &team); err == nil {
+		cfg.TeamFile = teamPath
+		cfg.Warnings = append(cfg.Warnings, team.dropPersonal(teamPath)...)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	// Repo-local project file
+	var repo Layer
+	dir := l.Dir
+	if dir == "" {
+		if dir, err = os.Getwd(); err != nil {
+			return nil, err
+		}
+	}
+	if repoPath := FindRepoFile(dir); repoPath != "" && !l.Isolated {
+		if err := ReadFile(repoPath, &repo); err != nil {
+			return nil, err
+		}
+		cfg.RepoFile = repoPath
+		cfg.Warnings = append(cfg.Warnings, repo.dropPersonal(repoPath)...)
+	}
+
+	var env Settings
+	if !l.Isolated {
+		env = Settings{Organization: getenv(EnvOrganization), Project: getenv(EnvProject), BaseURL: getenv(EnvBaseURL)}
+	}
+	layers := []struct {
+		source   Source
+		settings Settings
+	}{
+		{SourceFlag, l.Flags},
+		{SourceEnv, env},
+		{SourceRepo, repo.Settings},
+		{SourceProfile, profile.Settings},
+		{SourceTeam, team.Settings},
+	}
+	for _, layer := range layers {
+		cfg.set("org", &cfg.Organization, layer.settings.Organization, layer.source)
+		cfg.set("project", &cfg.Project, layer.settings.Project, layer.source)
+		cfg.set("base_url", &cfg.BaseURL, layer.settings.BaseURL, layer.source)
+	}
+	cfg.set("pat", &cfg.PAT, l.PAT, SourceFlag)
+	if !l.Isolated {
+		cfg.set("pat", &cfg.PAT, getenv(EnvPAT), SourceEnv)
+	}
+
+	// Encrypted credentials of the profile
+	cfg.CredentialsFile = filepath.Join(filepath.Dir(profilePath), credentials.FileName)
+	cfg.HistoryDir = filepath.Join(filepath.Dir(profilePath), "history")
+	if cfg.PAT == "" {
+		if err := l.unlockPAT(cfg, getenv); err != nil {
+			return nil, err
+		}
+	}
+
+	// The repository's shared defaults win over personal ones, while
+	// personal watch rules are kept alongside the shared ones. The team
+	// config only fills in what neither sets.
+	cfg.Pipelines = repo.Pipelines
+	if len(cfg.Pipelines) == 0 {
+		cfg.Pipelines = profile.Pipelines
+	}
+	if len(cfg.Pipelines) == 0 {
+		cfg.Pipelines = team.Pipelines
+	}
+	cfg.Branches = map[string]string{}
+	for _, branches := range []map[string]string{team.Branches, profile.Branches, repo.Branches} {
+		for pipeline, branch := range branches {
+			cfg.Branches[pipeline] = branch
+		}
+	}
+	cfg.Watch = mergeWatch(append(append([]WatchRule{}, team.Watch...), repo.Watch...), profile.Watch)
+	cfg.Statusline = profile.Statusline
+	cfg.History = profile.History
+	cfg.Polling = profile.Polling
+	for _, polling := range []PollingConfig{repo.Polling, team.Polling} {
+		if cfg.Polling == (PollingConfig{}) {
+			cfg.Polling = polling
+		}
+	}
+	cfg.Alerts = profile.Alerts
+	for _, alerts := range []AlertsConfig{repo.Alerts, team.Alerts} {
+		if cfg.Alerts == (AlertsConfig{}) {
+			cfg.Alerts = alerts
+		}
+	}
+	cfg.GitHub = profile.GitHub
+	if cfg.GitHub == (GitHubConfig{}) {
+		cfg.GitHub = repo.GitHub
+	}
+	cfg.Jira = profile.Jira
+	for _, jira := range []JiraConfig{repo.Jira, team.Jira} {
+		if cfg.Jira.URL == "" {
+			cfg.Jira = jira
+		}
+	}
+	cfg.Audit = profile.Audit
+	for _, audit := range []AuditConfig{repo.Audit, team.Audit} {
+		if cfg.Audit.Webhook == "" {
+			cfg.Audit = audit
+		}
+	}
+	cfg.Hooks = profile.Hooks
+	if secret := getenv(EnvHooksSecret); secret != "" && !l.Isolated {
+		cfg.Hooks.Secret = secret
+	}
+	for _, hooks := range []HooksConfig{repo.Hooks, team.Hooks} {
+		if len(cfg.Hooks.Rules) == 0 {
+			cfg.Hooks.Rules = hooks.Rules
+		}
+		if cfg.Hooks.Secret == "" {
+			cfg.Hooks.Secret = hooks.Secret
+		}
+	}
+	cfg.ChatOps = profile.ChatOps
+	if !l.Isolated {
+		cfg.ChatOps.SlackSecret = firstNonEmpty(getenv(EnvSlackSecret), cfg.ChatOps.SlackSecret)
+		cfg.ChatOps.TeamsSecret = firstNonEmpty(getenv(EnvTeamsSecret), cfg.ChatOps.TeamsSecret)
+	}
+	for _, chatops := range []ChatOpsConfig{repo.ChatOps, team.ChatOps} {
+		cfg.ChatOps.SlackSecret = firstNonEmpty(cfg.ChatOps.SlackSecret, chatops.SlackSecret)
+		cfg.ChatOps.TeamsSecret = firstNonEmpty(cfg.ChatOps.TeamsSecret, chatops.TeamsSecret)
+		if len(cfg.ChatOps.Channels) == 0 {
+			cfg.ChatOps.Channels = chatops.Channels
+		}
+	}
+	cfg.SafeMode = team.SafeMode || repo.SafeMode || profile.SafeMode
+	cfg.AllowWrite = profile.AllowWrite
+	cfg.Notifiers = map[string]NotifierConfig{}
+	for _, notifiers := range []map[string]NotifierConfig{team.Notifiers, repo.Notifiers, profile.Notifiers} {
+		for name, notifier := range notifiers {
+			cfg.Notifiers[name] = notifier
+		}
+	}
+
+	// Interactive prompt as the last resort
+	if l.Prompt != nil {
+		if cfg.Organization == "" {
+			cfg.set("org", &cfg.Organization, l.Prompt(i18n.T("Enter your Azure DevOps organization: ")), SourcePrompt)
+		}
+		if cfg.Project == "" {
+			cfg.set("project", &cfg.Project, l.Prompt(i18n.T("Enter your Azure DevOps project: ")), SourcePrompt)
+		}
+	}
+	if l.PromptSecret != nil && cfg.PAT == "" {
+		cfg.set("pat", &cfg.PAT, l.PromptSecret(i18n.T("Enter your Azure DevOps PAT: ")), SourcePrompt)
+	}
+	return cfg, nil
+}
+
+// dropPersonal clears the settings a shared file, the repo-local
+// .fomo.yaml or the team config, may not set: where requests and the
+// credentials they carry are sent, secrets, and notifiers that run
+// commands. Otherwise cloning a repository would be enough to have fomo
+// send the PAT to a host of the repository's choosing, or run a program of
+// its choosing. It returns a warning for each setting cleared.
+func (l *Layer) dropPersonal(path string) []string {
+	var warnings []string
+	drop := func(key string, value *string) {
+		if *value != "" {
+			warnings = append(warnings, fmt.Sprintf("ignoring %s in %s; set it in your profile instead", key, path))
+			*value = ""
+		}
+	}
+	drop("base_url", &l.BaseURL)
+	drop("github.api_url", &l.GitHub.APIURL)
+	drop("jira.url", &l.Jira.URL)
+	drop("audit.webhook", &l.Audit.Webhook)
+	drop("hooks.secret", &l.Hooks.Secret)
+	drop("chatops.slack_signing_secret", &l.ChatOps.SlackSecret)
+	drop("chatops.teams_secret", &l.ChatOps.TeamsSecret)
+
+	names := make([]string, 0, len(l.Notifiers))
+	for name := range l.Notifiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if kind := l.Notifiers[name].Type; notify.RunsCommand(kind) {
+			warnings = append(warnings, fmt.Sprintf("ignoring notifier %s in %s; %s notifiers run commands, so set it in your profile instead", name, path, kind))
+			delete(l.Notifiers, name)
+		}
+	}
+	return warnings
+}
+
+// mergeWatch combines the shared watch rules of the team and the repository
+// with personal ones. A personal rule for the same pipeline and tags as
+// shared rules replaces them, so everyone can narrow the branches, results
+// or notifiers of a team rule; one with unwatch set only drops them.
+func mergeWatch(shared, personal []WatchRule) []WatchRule {
+	key := func(rule WatchRule) string {
+		return rule.Pipeline + "\x00" + strings.Join(rule.Tags, "\x00")
+	}
+	overridden := map[string]bool{}
+	for _, rule := range personal {
+		overridden[key(rule)] = true
+	}
+	var rules []WatchRule
+	for _, rule := range shared {
+		if !overridden[key(rule)] {
+			rules = append(rules, rule)
+		}
+	}
+	for _, rule := range personal {
+		if !rule.Unwatch {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// unlockPAT decrypts the profile's stored PAT. Without a passphrase the PAT
+// stays locked and only EncryptedPAT is set.
+func (l *Loader) unlockPAT(cfg *Config, getenv func(string) string) error {
+	store := &credentials.Store{Path: cfg.CredentialsFile}
+	encrypted, err := store.EncryptedPAT(cfg.Profile)
+	if err != nil || encrypted == "" {
+		return err
+	}
+	passphrase := getenv(EnvPassphrase)
+	if passphrase == "" && l.Passphrase != nil {
+		passphrase = l.Passphrase(i18n.T("Passphrase for the PAT of profile %s: ", cfg.Profile))
+	}
+	if passphrase == "" {
+		cfg.EncryptedPAT = encrypted
+		return nil
+	}
+	pat, err := credentials.Decrypt(encrypted, passphrase)
+	if err != nil {
+		return fmt.Errorf("failed to unlock the PAT of profile %s: %w", cfg.Profile, err)
+	}
+	cfg.set("pat", &cfg.PAT, pat, SourceCredentials)
+	return nil
+}
+
+// FindRepoFile looks for .fomo.yaml in dir and its parents up to the root of
+// the enclosing git repository. Outside a repository only dir is checked.
+func FindRepoFile(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+
+	var candidates []string
+	for d := dir; ; d = filepath.Dir(d) {
+		candidates = append(candidates, d)
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			break
+		}
+		if filepath.Dir(d) == d {
+			// Not inside a repository
+			candidates = candidates[:1]
+			break
+		}
+	}
+
+	for _, d := range candidates {
+		path := filepath.Join(d, RepoFileName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// set assigns value to dst unless dst was already set by a higher layer.
+func (c *Config) set(key string, dst *string, value string, source Source) {
+	if *dst != "" || value == "" {
+		return
+	}
+	*dst = value
+	c.Sources[key] = source
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,129 @@
+// Package credentials keeps PATs encrypted at rest with a passphrase, for
+// systems without a keychain.
+package credentials
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// FileName is the credentials file, kept next to the user config file.
+const FileName = "credentials.json"
+
+// Store is the credentials file. It maps profile names to encrypted PATs,
+// and to the encrypted secrets of other services, such as Jira.
+type Store struct {
+	Path string
+}
+
+type file struct {
+	Profiles map[string]entry `json:"profiles"`
+}
+
+type entry struct {
+	PAT     string            `json:"pat,omitempty"`     // encrypted with Encrypt
+	Secrets map[string]string `json:"secrets,omitempty"` // by service, encrypted with Encrypt
+}
+
+func (s *Store) read() (file, error) {
+	var f file
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return file{Profiles: map[string]entry{}}, nil
+	}
+	if err != nil {
+		return f, err
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return f, fmt.Errorf("failed to parse %s: %v", s.Path, err)
+	}
+	if f.Profiles == nil {
+		f.Profiles = map[string]entry{}
+	}
+	return f, nil
+}
+
+// EncryptedPAT returns the encrypted PAT of a profile, or "" if none is
+// stored.
+func (s *Store) EncryptedPAT(profile string) (string, error) {
+	f, err := s.read()
+	if err != nil {
+		return "", err
+	}
+	return f.Profiles[profile].PAT, nil
+}
+
+// SavePAT encrypts pat with passphrase and stores it for the profile. The
+// file is only readable by the current user.
+func (s *Store) SavePAT(profile, pat, passphrase string) error {
+	f, err := s.read()
+	if err != nil {
+		return err
+	}
+	encrypted, err := Encrypt(pat, passphrase)
+	if err != nil {
+		return err
+	}
+	e := f.Profiles[profile]
+	e.PAT = encrypted
+	f.Profiles[profile] = e
+	return s.write(f)
+}
+
+// EncryptedSecret returns the encrypted secret of a service for a profile,
+// or "" if none is stored.
+func (s *Store) EncryptedSecret(profile, service string) (string, error) {
+	f, err := s.read()
+	if err != nil {
+		return "", err
+	}
+	return f.Profiles[profile].Secrets[service], nil
+}
+
+// SaveSecret encrypts the secret of a service, such as an API token, with
+// passphrase and stores it for the profile.
+func (s *Store) SaveSecret(profile, service, secret, passphrase string) error {
+	f, err := s.read()
+	if err != nil {
+		return err
+	}
+	encrypted, err := Encrypt(secret, passphrase)
+	if err != nil {
+		return err
+	}
+	e := f.Profiles[profile]
+	if e.Secrets == nil {
+		e.Secrets = map[string]string{}
+	}
+	e.Secrets[service] = encrypted
+	f.Profiles[profile] = e
+	return s.write(f)
+}
+
+// write replaces the file atomically, readable only by the current user.
+func (s *Store) write(f file) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.Path), ".credentials-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.Path)
+}
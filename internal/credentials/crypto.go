@@ -0,0 +1,106 @@
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Encrypted values look like "fomo-v1:<base64 of salt, nonce and sealed
+// data>". The key is derived from the passphrase with PBKDF2-HMAC-SHA256
+// and the data sealed with AES-256-GCM.
+const (
+	formatPrefix = "fomo-v1:"
+	iterations   = 600000
+	saltSize     = 16
+	keySize      = 32
+)
+
+// ErrWrongPassphrase is returned when a value cannot be decrypted, either
+// because the passphrase is wrong or because the data was altered.
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupted credentials")
+
+// Encrypt seals plaintext with a key derived from passphrase.
+func Encrypt(plaintext, passphrase string) (string, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	out := append(append([]byte{}, salt...), nonce...)
+	out = aead.Seal(out, nonce, []byte(plaintext), []byte(formatPrefix))
+	return formatPrefix + base64.StdEncoding.EncodeToString(out), nil
+}
+
+// Decrypt opens a value produced by Encrypt.
+func Decrypt(value, passphrase string) (string, error) {
+	encoded, ok := strings.CutPrefix(value, formatPrefix)
+	if !ok {
+		return "", fmt.Errorf("unsupported credentials format")
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(data) < saltSize {
+		return "", ErrWrongPassphrase
+	}
+	aead, err := newAEAD(passphrase, data[:saltSize])
+	if err != nil {
+		return "", err
+	}
+	data = data[saltSize:]
+	if len(data) < aead.NonceSize() {
+		return "", ErrWrongPassphrase
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(formatPrefix))
+	if err != nil {
+		return "", ErrWrongPassphrase
+	}
+	return string(plaintext), nil
+}
+
+func newAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(pbkdf2([]byte(passphrase), salt, iterations, keySize))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// pbkdf2 derives a key as described in RFC 8018, section 5.2.
+func pbkdf2(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	var key []byte
+	u := make([]byte, 0, prf.Size())
+	t := make([]byte, prf.Size())
+	for block := uint32(1); len(key) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(binary.BigEndian.AppendUint32(nil, block))
+		u = prf.Sum(u[:0])
+		copy(t, u)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
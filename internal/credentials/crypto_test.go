@@ -0,0 +1,94 @@
+package credentials
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	const pat = "s3cr3t-pat"
+	value, err := Encrypt(pat, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(value, formatPrefix) || strings.Contains(value, pat) {
+		t.Fatalf("Encrypt = %q, want a %s value without the plaintext", value, formatPrefix)
+	}
+	if again, err := Encrypt(pat, "correct horse"); err != nil || again == value {
+		t.Errorf("Encrypt twice = %q, %v, want a different value with a fresh salt and nonce", again, err)
+	}
+
+	got, err := Decrypt(value, "correct horse")
+	if err != nil || got != pat {
+		t.Fatalf("Decrypt = %q, %v, want %q", got, err, pat)
+	}
+	if got, err := Decrypt(value, "wrong horse"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Decrypt with a wrong passphrase = %q, %v, want ErrWrongPassphrase", got, err)
+	}
+}
+
+func TestDecryptTampered(t *testing.T) {
+	value, err := Encrypt("s3cr3t-pat", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, formatPrefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encode := func(data []byte) string {
+		return formatPrefix + base64.StdEncoding.EncodeToString(data)
+	}
+	flip := func(i int) string {
+		altered := append([]byte{}, data...)
+		altered[i] ^= 1
+		return encode(altered)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"salt", flip(0)},
+		{"nonce", flip(saltSize)},
+		{"ciphertext", flip(saltSize + 12)},
+		{"tag", flip(len(data) - 1)},
+		{"truncated", encode(data[:len(data)-1])},
+		{"too short", encode(data[:saltSize+4])},
+		{"not base64", formatPrefix + "%%%"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := Decrypt(tt.value, "correct horse"); !errors.Is(err, ErrWrongPassphrase) {
+				t.Errorf("Decrypt = %q, %v, want ErrWrongPassphrase", got, err)
+			}
+		})
+	}
+
+	if _, err := Decrypt("plain-pat", "correct horse"); err == nil || errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Decrypt of an unencrypted value = %v, want an unsupported format error", err)
+	}
+}
+
+// The PBKDF2-HMAC-SHA256 test vectors of RFC 7914, section 11.
+func TestPBKDF2(t *testing.T) {
+	tests := []struct {
+		password, salt string
+		iterations     int
+		want           string
+	}{
+		{"passwd", "salt", 1, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc" +
+			"49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+		{"Password", "NaCl", 80000, "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56" +
+			"a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d"},
+	}
+	for _, tt := range tests {
+		got := hex.EncodeToString(pbkdf2([]byte(tt.password), []byte(tt.salt), tt.iterations, 64))
+		if got != tt.want {
+			t.Errorf("pbkdf2(%q, %q, %d) = %s, want %s", tt.password, tt.salt, tt.iterations, got, tt.want)
+		}
+	}
+}
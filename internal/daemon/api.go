@@ -0,0 +1,212 @@
+// Package daemon exposes the state of a running watcher over a local HTTP
+// API, for editor extensions and status-bar widgets.
+package daemon
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"fomo/internal/client"
+	"fomo/internal/history"
+	"fomo/internal/watch"
+)
+
+// Trigger queues a run of a pipeline, identified by name or ID.
+type Trigger func(ctx context.Context, pipeline, branch string) (*client.Run, error)
+
+// ErrForbidden marks Trigger errors for runs the daemon may not queue,
+// answered with 403 Forbidden.
+var ErrForbidden = errors.New("forbidden")
+
+// API serves the local daemon endpoints:
+//
+//	GET  /v1/health          daemon liveness and the last polling error
+//	GET  /v1/state           latest run of every watched pipeline and branch
+//	GET  /v1/events?since=N  events newer than ID N
+//	POST /v1/runs            queue a run: {"pipeline": "...", "branch": "..."}
+//	GET  /v1/metrics         Azure DevOps API usage since the daemon started
+//	/grafana/...             the run history as a Grafana JSON datasource
+//
+// A daemon serving several tenants serves one API per tenant; see Tenants.
+type API struct {
+	Watcher *watch.Watcher
+	Trigger Trigger
+	Started time.Time
+	History *history.Store  // optional; enables the Grafana endpoints
+	Metrics *client.Metrics // optional; enables /v1/metrics
+
+	// Token, when set, must be sent as "Authorization: Bearer TOKEN" to
+	// queue a run
+	Token string
+}
+
+// Handler returns the HTTP handler for the API.
+func (api *API) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/health", api.health)
+	mux.HandleFunc("GET /v1/state", api.state)
+	mux.HandleFunc("GET /v1/events", api.events)
+	mux.HandleFunc("POST /v1/runs", api.queueRun)
+	if api.Metrics != nil {
+		mux.HandleFunc("GET /v1/metrics", api.metrics)
+	}
+	if api.History != nil {
+		grafana := &Grafana{Store: api.History}
+		mux.Handle("/grafana/", http.StripPrefix("/grafana", grafana.Handler()))
+	}
+	return mux
+}
+
+func (api *API) health(w http.ResponseWriter, r *http.Request) {
+	status := struct {
+		Status    string    `json:"status"`
+		Started   time.Time `json:"started"`
+		LastError string    `json:"lastError,omitempty"`
+	}{Status: "ok", Started: api.Started}
+	if err := api.Watcher.LastError(); err != nil {
+		status.Status = "degraded"
+		status.LastError = err.Error()
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (api *API) state(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"value": api.Watcher.State()})
+}
+
+func (api *API) events(w http.ResponseWriter, r *http.Request) {
+	var since int64
+	if s := r.URL.Query().Get("since"); s != "" {
+		var err error
+		if since, err = strconv.ParseInt(s, 10, 64); err != nil {
+			writeError(w, http.StatusBadRequest, "since must be an event ID")
+			return
+		}
+	}
+	events := api.Watcher.Events(since)
+	if events == nil {
+		events = []watch.Event{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"value": events})
+}
+
+func (api *API) metrics(w http.ResponseWriter, r *http.Request) {
+	endpoints := api.Metrics.Endpoints()
+	if endpoints == nil {
+		endpoints = []client.EndpointUsage{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"since": api.Started, "total": api.Metrics.Total(), "value": endpoints})
+}
+
+func (api *API) queueRun(w http.ResponseWriter, r *http.Request) {
+	// A JSON body cannot be sent from another site's page without a CORS
+	// preflight, which the daemon never answers
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+		return
+	}
+	if api.Token != "" {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(api.Token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "queuing runs needs the daemon's API token")
+			return
+		}
+	}
+
+	var request struct {
+		Pipeline string `json:"pipeline"`
+		Branch   string `json:"branch"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Pipeline == "" {
+		writeError(w, http.StatusBadRequest, `expected {"pipeline": "...", "branch": "..."}`)
+		return
+	}
+
+	run, err := api.Trigger(r.Context(), request.Pipeline, request.Branch)
+	if err != nil {
+		status := http.StatusBadGateway
+		var apiErr *client.APIError
+		switch {
+		case errors.Is(err, ErrForbidden):
+			status = http.StatusForbidden
+		case errors.As(err, &apiErr):
+			status = apiErr.StatusCode
+		}
+		writeError(w, status, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, run)
+}
+
+// Listen opens the daemon's listener. Addresses of the form "unix:PATH"
+// create a unix socket readable only by the current user; anything else is
+// a TCP address, which must be on the loopback interface.
+func Listen(addr string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		// Remove a stale socket left by a previous run
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("another daemon is already listening on %s", path)
+		}
+		os.Remove(path)
+
+		l, err := net.Listen("unix", path)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Chmod(path, 0600); err != nil {
+			l.Close()
+			return nil, err
+		}
+		return l, nil
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return nil, fmt.Errorf("refusing to listen on non-loopback address %s", addr)
+	}
+	return net.Listen("tcp", addr)
+}
+
+// LocalHost wraps the handler of a loopback TCP listener to refuse
+// requests naming a host other than localhost or a loopback address. A
+// page of another site can only reach the listener by pointing its own
+// name at 127.0.0.1, and then sends that name as the Host.
+func LocalHost(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		host = strings.Trim(host, "[]")
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			writeError(w, http.StatusForbidden, "the daemon only answers requests for localhost")
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"message": message})
+}
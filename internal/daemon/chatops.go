@@ -0,0 +1,192 @@
+package daemon
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// chatMaxSkew is how old a chat request may be. Requests are also refused
+// if they were already received within it, so that one cannot be replayed.
+const chatMaxSkew = 5 * time.Minute
+
+// ChatCommand is a command typed in a chat channel, such as
+// "run api-build main".
+type ChatCommand struct {
+	Platform string // slack or teams
+	Channel  string // channel ID
+	User     string
+	Text     string // without the slash command or the mention of the bot
+}
+
+// ChatHandler runs a chat command and returns the reply to post.
+type ChatHandler func(ctx context.Context, command ChatCommand) string
+
+// ChatOps receives commands from chat, each verified with the platform's
+// request signature:
+//
+//	POST /slack    a Slack slash command, signed with the app's signing secret
+//	POST /teams    a Teams outgoing webhook, signed with its security token
+//
+// A platform without a secret is not served.
+type ChatOps struct {
+	SlackSecret string
+	TeamsSecret string // base64, as Teams shows it
+	Handle      ChatHandler
+	Log         *slog.Logger
+
+	mu   sync.Mutex
+	seen map[string]time.Time // request -> when it was received
+}
+
+// teamsMention is the mention of the outgoing webhook that starts the text
+// of a Teams message.
+var teamsMention = regexp.MustCompile(`(?i)^\s*<at>[^<]*</at>\s*`)
+
+// Handler returns the HTTP handler receiving chat commands.
+func (c *ChatOps) Handler() http.Handler {
+	mux := http.NewServeMux()
+	if c.SlackSecret != "" {
+		mux.HandleFunc("POST /slack", c.slack)
+	}
+	if c.TeamsSecret != "" {
+		mux.HandleFunc("POST /teams", c.teams)
+	}
+	return mux
+}
+
+func (c *ChatOps) slack(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxHookPayload))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read the request")
+		return
+	}
+	// https://api.slack.com/authentication/verifying-requests-from-slack
+	timestamp := r.Header.Get("X-Slack-Request-Timestamp")
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || time.Since(time.Unix(sent, 0)).Abs() > chatMaxSkew {
+		writeError(w, http.StatusUnauthorized, "missing or stale request timestamp")
+		return
+	}
+	mac := hmac.New(sha256.New, []byte(c.SlackSecret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(r.Header.Get("X-Slack-Signature"))) {
+		writeError(w, http.StatusUnauthorized, "invalid request signature")
+		return
+	}
+	// The signature covers the timestamp, so it is unique to the request
+	if !c.firstReceived("slack " + expected) {
+		writeError(w, http.StatusConflict, "request already received")
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "expected a slash command")
+		return
+	}
+	command := ChatCommand{Platform: "slack", Channel: form.Get("channel_id"), User: form.Get("user_name"), Text: form.Get("text")}
+	reply := c.run(r.Context(), command)
+	writeJSON(w, http.StatusOK, map[string]string{"response_type": "in_channel", "text": reply})
+}
+
+func (c *ChatOps) teams(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxHookPayload))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read the request")
+		return
+	}
+	// https://learn.microsoft.com/microsoftteams/platform/webhooks-and-connectors/how-to/add-outgoing-webhook
+	key, err := base64.StdEncoding.DecodeString(c.TeamsSecret)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "the Teams security token is not base64")
+		return
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	expected := "HMAC " + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(r.Header.Get("Authorization"))) {
+		writeError(w, http.StatusUnauthorized, "invalid request signature")
+		return
+	}
+
+	var activity struct {
+		ID        string    `json:"id"`
+		Timestamp time.Time `json:"timestamp"`
+		Text      string    `json:"text"`
+		From      struct {
+			Name string `json:"name"`
+		} `json:"from"`
+		Conversation struct {
+			ID string `json:"id"`
+		} `json:"conversation"`
+		ChannelData struct {
+			Channel struct {
+				ID string `json:"id"`
+			} `json:"channel"`
+		} `json:"channelData"`
+	}
+	if err := json.Unmarshal(body, &activity); err != nil {
+		writeError(w, http.StatusBadRequest, "expected a Teams message")
+		return
+	}
+	if activity.ID == "" || activity.Timestamp.IsZero() || time.Since(activity.Timestamp).Abs() > chatMaxSkew {
+		writeError(w, http.StatusUnauthorized, "missing or stale activity timestamp")
+		return
+	}
+	if !c.firstReceived("teams " + activity.ID) {
+		writeError(w, http.StatusConflict, "activity already received")
+		return
+	}
+	channel := activity.ChannelData.Channel.ID
+	if channel == "" {
+		channel = activity.Conversation.ID
+	}
+	text := teamsMention.ReplaceAllString(strings.ReplaceAll(activity.Text, "&nbsp;", " "), "")
+	command := ChatCommand{Platform: "teams", Channel: channel, User: activity.From.Name, Text: text}
+	reply := c.run(r.Context(), command)
+	writeJSON(w, http.StatusOK, map[string]string{"type": "message", "text": reply})
+}
+
+// firstReceived records a request and reports whether it is the first time
+// it was received. A request is remembered for twice chatMaxSkew, since its
+// timestamp may be that far ahead as well as behind; after that it is
+// refused by its timestamp.
+func (c *ChatOps) firstReceived(request string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for r, received := range c.seen {
+		if now.Sub(received) > 2*chatMaxSkew {
+			delete(c.seen, r)
+		}
+	}
+	if _, ok := c.seen[request]; ok {
+		return false
+	}
+	if c.seen == nil {
+		c.seen = map[string]time.Time{}
+	}
+	c.seen[request] = now
+	return true
+}
+
+func (c *ChatOps) run(ctx context.Context, command ChatCommand) string {
+	command.Text = strings.TrimSpace(command.Text)
+	c.Log.Info("chat command", "platform", command.Platform, "channel", command.Channel, "user", command.User, "text", command.Text)
+	return c.Handle(ctx, command)
+}
@@ -0,0 +1,252 @@
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"fomo/internal/history"
+)
+
+// Grafana serves the local run history to Grafana's JSON datasource
+// plugin:
+//
+//	GET  /             connection test
+//	POST /search       list the metrics that can be queried
+//	POST /query        time series of metrics, or a table of runs
+//	POST /annotations  failed runs and annotated runs, to mark on graphs
+//
+// Metrics are named PIPELINE/METRIC, with "all" standing for every
+// pipeline; see metrics for the metric names.
+type Grafana struct {
+	Store *history.Store
+}
+
+// allPipelines is the pipeline name of metrics across every pipeline.
+const allPipelines = "all"
+
+// runsTable is the target that queries the recorded runs as a table.
+const runsTable = "runs"
+
+// metrics maps each metric to its value for a bucket; false means the
+// bucket has no value, rather than zero.
+var metrics = map[string]func(history.Bucket) (float64, bool){
+	"runs":      func(b history.Bucket) (float64, bool) { return float64(b.Runs), true },
+	"succeeded": func(b history.Bucket) (float64, bool) { return float64(b.Succeeded), true },
+	"failed":    func(b history.Bucket) (float64, bool) { return float64(b.Failed), true },
+	"success_rate": func(b history.Bucket) (float64, bool) {
+		return 100 * float64(b.Succeeded) / float64(b.Runs), b.Runs > 0
+	},
+	"duration_avg": func(b history.Bucket) (float64, bool) {
+		return b.TotalSeconds / float64(b.Runs), b.Runs > 0
+	},
+	"duration_max": func(b history.Bucket) (float64, bool) { return b.MaxSeconds, b.Runs > 0 },
+}
+
+// maxBuckets bounds the points of a series, whatever interval is asked for.
+const maxBuckets = 10000
+
+var metricOrder = []string{"runs", "succeeded", "failed", "success_rate", "duration_avg", "duration_max"}
+
+// Handler returns the HTTP handler for the datasource.
+func (g *Grafana) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("POST /search", g.search)
+	mux.HandleFunc("POST /query", g.query)
+	mux.HandleFunc("POST /annotations", g.annotations)
+	return mux
+}
+
+// timeRange is the dashboard's time range in a request.
+type timeRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+func (g *Grafana) search(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Target string `json:"target"`
+	}
+	json.NewDecoder(r.Body).Decode(&request) // an empty body lists everything
+
+	pipelines, err := g.Store.Pipelines()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	targets := []string{runsTable}
+	for _, pipeline := range append([]string{allPipelines}, pipelines...) {
+		for _, metric := range metricOrder {
+			targets = append(targets, pipeline+"/"+metric)
+		}
+	}
+	matches := []string{}
+	for _, target := range targets {
+		if strings.Contains(target, request.Target) {
+			matches = append(matches, target)
+		}
+	}
+	writeJSON(w, http.StatusOK, matches)
+}
+
+func (g *Grafana) query(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Range      timeRange `json:"range"`
+		IntervalMs int64     `json:"intervalMs"`
+		Targets    []struct {
+			Target string `json:"target"`
+			Type   string `json:"type"`
+		} `json:"targets"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid query: "+err.Error())
+		return
+	}
+	step := max(time.Duration(request.IntervalMs)*time.Millisecond, time.Minute)
+	step = max(step, request.Range.To.Sub(request.Range.From)/maxBuckets)
+
+	response := []any{}
+	for _, target := range request.Targets {
+		if target.Target == runsTable || target.Type == "table" {
+			table, err := g.runsTable(request.Range)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			response = append(response, table)
+			continue
+		}
+
+		i := strings.LastIndex(target.Target, "/")
+		if i < 0 {
+			writeError(w, http.StatusBadRequest, "unknown target "+target.Target)
+			return
+		}
+		pipeline, metric := target.Target[:i], target.Target[i+1:]
+		value, ok := metrics[metric]
+		if !ok {
+			writeError(w, http.StatusBadRequest, "unknown metric "+metric)
+			return
+		}
+		if pipeline == allPipelines {
+			pipeline = ""
+		}
+		buckets, err := g.Store.Aggregate(pipeline, request.Range.From, request.Range.To, step)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		datapoints := [][2]float64{}
+		for _, b := range buckets {
+			if v, ok := value(b); ok {
+				datapoints = append(datapoints, [2]float64{v, float64(b.Start.UnixMilli())})
+			}
+		}
+		response = append(response, map[string]any{"target": target.Target, "datapoints": datapoints})
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// runsTable lists the recorded runs that finished in a time range, newest
+// first.
+func (g *Grafana) runsTable(tr timeRange) (any, error) {
+	runs, err := g.Store.Runs()
+	if err != nil {
+		return nil, err
+	}
+	type column struct {
+		Text string `json:"text"`
+		Type string `json:"type"`
+	}
+	rows := [][]any{}
+	for i := len(runs) - 1; i >= 0; i-- {
+		r := runs[i]
+		if r.Finished.Before(tr.From) || r.Finished.After(tr.To) {
+			continue
+		}
+		rows = append(rows, []any{r.Finished.UnixMilli(), r.Pipeline, r.Name, r.Branch, r.Result, r.Duration().Seconds()})
+	}
+	return map[string]any{
+		"type": "table",
+		"columns": []column{
+			{"Time", "time"}, {"Pipeline", "string"}, {"Run", "string"},
+			{"Branch", "string"}, {"Result", "string"}, {"Duration", "number"},
+		},
+		"rows": rows,
+	}, nil
+}
+
+// annotations returns the failed runs and the annotations of runs in a
+// time range. The annotation's query, if any, names the pipeline to limit
+// them to.
+func (g *Grafana) annotations(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Range      timeRange `json:"range"`
+		Annotation struct {
+			Name  string `json:"name"`
+			Query string `json:"query"`
+		} `json:"annotation"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid annotation query: "+err.Error())
+		return
+	}
+	runs, err := g.Store.Runs()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	type annotation struct {
+		Annotation any      `json:"annotation"`
+		Time       int64    `json:"time"`
+		TimeEnd    int64    `json:"timeEnd"`
+		Title      string   `json:"title"`
+		Text       string   `json:"text"`
+		Tags       []string `json:"tags"`
+	}
+	pipeline := strings.TrimSpace(request.Annotation.Query)
+	out := []annotation{}
+	for _, run := range runs {
+		if run.Result != "failed" || run.Finished.Before(request.Range.From) || run.Finished.After(request.Range.To) {
+			continue
+		}
+		if pipeline != "" && pipeline != allPipelines && run.Pipeline != pipeline {
+			continue
+		}
+		out = append(out, annotation{
+			Annotation: request.Annotation,
+			Time:       run.Created.UnixMilli(),
+			TimeEnd:    run.Finished.UnixMilli(),
+			Title:      run.Pipeline + " " + run.Name + " failed",
+			Text:       "on " + run.Branch,
+			Tags:       []string{run.Pipeline, run.Branch, run.Result},
+		})
+	}
+	notes, err := g.Store.Annotations()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for _, note := range notes {
+		if note.Time.Before(request.Range.From) || note.Time.After(request.Range.To) {
+			continue
+		}
+		if pipeline != "" && pipeline != allPipelines && note.Pipeline != pipeline {
+			continue
+		}
+		out = append(out, annotation{
+			Annotation: request.Annotation,
+			Time:       note.Time.UnixMilli(),
+			TimeEnd:    note.Time.UnixMilli(),
+			Title:      note.Pipeline + " " + note.RunName,
+			Text:       note.Message,
+			Tags:       append([]string{note.Pipeline}, note.Tags...),
+		})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
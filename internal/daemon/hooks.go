@@ -0,0 +1,215 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"fomo/internal/config"
+	"fomo/internal/notify"
+	"fomo/internal/watch"
+)
+
+// maxHookPayload bounds the service hook payloads read.
+const maxHookPayload = 1 << 20
+
+// Send delivers a notification to the notifiers named.
+type Send func(ctx context.Context, notifiers []string, event notify.Event)
+
+// Hooks receives Azure DevOps service hooks and turns the events its rules
+// match into notifications:
+//
+//	POST /hooks    a service hook event, as a "Web Hooks" subscription sends it
+//
+// Every rule that matches an event sends it; the payload is answered
+// before the notifications go out.
+type Hooks struct {
+	Secret string // required as the basic authentication password
+	Send   Send
+	Log    *slog.Logger
+	routes []hookRoute
+}
+
+// hookRoute is a rule with its templates parsed.
+type hookRoute struct {
+	rule                      config.HookRule
+	when, title, message, url *template.Template
+}
+
+// hookFuncs are the functions hook templates may use besides the built-in
+// ones.
+var hookFuncs = template.FuncMap{
+	"shortBranch": watch.ShortBranch,
+	"hasPrefix":   strings.HasPrefix,
+	"hasSuffix":   strings.HasSuffix,
+	"contains":    strings.Contains,
+	"lower":       strings.ToLower,
+	"upper":       strings.ToUpper,
+	"glob": func(pattern, s string) bool {
+		matched, _ := path.Match(pattern, s)
+		return matched
+	},
+}
+
+// NewHooks parses the templates of rules.
+func NewHooks(rules []config.HookRule, secret string, send Send, log *slog.Logger) (*Hooks, error) {
+	h := &Hooks{Secret: secret, Send: send, Log: log}
+	for i, rule := range rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("hooks rule %d", i+1)
+		}
+		if len(rule.Notify) == 0 {
+			return nil, fmt.Errorf("%s: notify names no notifier", name)
+		}
+		if _, err := path.Match(rule.Event, ""); err != nil {
+			return nil, fmt.Errorf("%s: invalid event %q: %w", name, rule.Event, err)
+		}
+		route := hookRoute{rule: rule}
+		route.rule.Name = name
+		for _, t := range []struct {
+			field, text string
+			into        **template.Template
+		}{
+			{"when", rule.When, &route.when},
+			{"title", rule.Title, &route.title},
+			{"message", rule.Message, &route.message},
+			{"url", rule.URL, &route.url},
+		} {
+			if t.text == "" {
+				continue
+			}
+			parsed, err := template.New(t.field).Funcs(hookFuncs).Option("missingkey=zero").Parse(t.text)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid %s: %w", name, t.field, err)
+			}
+			*t.into = parsed
+		}
+		h.routes = append(h.routes, route)
+	}
+	return h, nil
+}
+
+// Handler returns the HTTP handler receiving service hooks.
+func (h *Hooks) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /hooks", h.receive)
+	return mux
+}
+
+func (h *Hooks) receive(w http.ResponseWriter, r *http.Request) {
+	_, password, _ := r.BasicAuth()
+	if h.Secret == "" || subtle.ConstantTimeCompare([]byte(password), []byte(h.Secret)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Basic realm="fomo"`)
+		writeError(w, http.StatusUnauthorized, "expected the hooks secret as the basic authentication password")
+		return
+	}
+	var payload map[string]any
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxHookPayload)).Decode(&payload); err != nil {
+		writeError(w, http.StatusBadRequest, "expected a service hook event as JSON")
+		return
+	}
+
+	eventType, _ := payload["eventType"].(string)
+	var matched []string
+	var sends []func()
+	for _, route := range h.routes {
+		event, ok, err := route.apply(eventType, payload)
+		if err != nil {
+			h.Log.Warn("hooks rule failed", "rule", route.rule.Name, "event", eventType, "err", err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		matched = append(matched, route.rule.Name)
+		notifiers := route.rule.Notify
+		sends = append(sends, func() { h.Send(context.Background(), notifiers, event) })
+	}
+	h.Log.Info("service hook received", "event", eventType, "rules", matched)
+	go func() {
+		for _, send := range sends {
+			send()
+		}
+	}()
+	if matched == nil {
+		matched = []string{}
+	}
+	writeJSON(w, http.StatusAccepted, map[string]any{"rules": matched})
+}
+
+// apply matches a payload against the rule and renders its notification.
+func (route *hookRoute) apply(eventType string, payload map[string]any) (notify.Event, bool, error) {
+	if route.rule.Event != "" {
+		if matched, _ := path.Match(route.rule.Event, eventType); !matched {
+			return notify.Event{}, false, nil
+		}
+	}
+	if route.when != nil {
+		when, err := render(route.when, payload)
+		if err != nil || when != "true" {
+			return notify.Event{}, false, err
+		}
+	}
+
+	resource, _ := payload["resource"].(map[string]any)
+	event := notify.Event{
+		Title:    text(payload, "message", "text"),
+		Message:  text(payload, "detailedMessage", "text"),
+		URL:      text(resource, "_links", "web", "href"),
+		Pipeline: firstText(resource, []string{"definition", "name"}, []string{"pipeline", "name"}),
+		Branch:   watch.ShortBranch(firstText(resource, []string{"sourceBranch"}, []string{"run", "resources", "repositories", "self", "refName"})),
+		Result:   firstText(resource, []string{"result"}, []string{"run", "result"}),
+		RunName:  firstText(resource, []string{"buildNumber"}, []string{"run", "name"}),
+		Time:     time.Now(),
+	}
+	for _, field := range []struct {
+		t    *template.Template
+		into *string
+	}{{route.title, &event.Title}, {route.message, &event.Message}, {route.url, &event.URL}} {
+		if field.t == nil {
+			continue
+		}
+		value, err := render(field.t, payload)
+		if err != nil {
+			return notify.Event{}, false, err
+		}
+		*field.into = value
+	}
+	return event, true, nil
+}
+
+func render(t *template.Template, payload map[string]any) (string, error) {
+	var b bytes.Buffer
+	if err := t.Execute(&b, payload); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// text returns the string at a path of keys into a JSON object, or "".
+func text(v map[string]any, keys ...string) string {
+	for _, key := range keys[:len(keys)-1] {
+		v, _ = v[key].(map[string]any)
+	}
+	s, _ := v[keys[len(keys)-1]].(string)
+	return s
+}
+
+// firstText returns the first non-empty string of several paths.
+func firstText(v map[string]any, paths ...[]string) string {
+	for _, keys := range paths {
+		if s := text(v, keys...); s != "" {
+			return s
+		}
+	}
+	return ""
+}
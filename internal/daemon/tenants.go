@@ -0,0 +1,81 @@
+package daemon
+
+import (
+	"net/http"
+	"time"
+)
+
+// Tenant is one of several sets of credentials and watch rules a daemon
+// serves, each with its own watcher, history and API.
+type Tenant struct {
+	Name         string
+	Organization string
+	Project      string
+	API          *API
+}
+
+// Tenants serves the APIs of several tenants side by side:
+//
+//	GET  /v1/health         daemon liveness, degraded if any tenant is
+//	GET  /v1/tenants        the tenants and the health of each
+//	/tenants/NAME/...       the API of tenant NAME, as served for one
+//
+// Tenants share nothing but the listener: a tenant's endpoints only see
+// its own runs, events and history.
+type Tenants struct {
+	Tenants []Tenant
+	Started time.Time
+}
+
+// tenantStatus describes a tenant in /v1/tenants.
+type tenantStatus struct {
+	Name         string `json:"name"`
+	Organization string `json:"organization"`
+	Project      string `json:"project"`
+	Status       string `json:"status"`
+	LastError    string `json:"lastError,omitempty"`
+}
+
+// Handler returns the HTTP handler for every tenant.
+func (t *Tenants) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/health", t.health)
+	mux.HandleFunc("GET /v1/tenants", t.list)
+	for _, tenant := range t.Tenants {
+		prefix := "/tenants/" + tenant.Name
+		mux.Handle(prefix+"/", http.StripPrefix(prefix, tenant.API.Handler()))
+	}
+	return mux
+}
+
+func (t *Tenants) statuses() []tenantStatus {
+	statuses := make([]tenantStatus, 0, len(t.Tenants))
+	for _, tenant := range t.Tenants {
+		status := tenantStatus{Name: tenant.Name, Organization: tenant.Organization, Project: tenant.Project, Status: "ok"}
+		if err := tenant.API.Watcher.LastError(); err != nil {
+			status.Status = "degraded"
+			status.LastError = err.Error()
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+func (t *Tenants) health(w http.ResponseWriter, r *http.Request) {
+	status := struct {
+		Status   string    `json:"status"`
+		Started  time.Time `json:"started"`
+		Degraded []string  `json:"degraded,omitempty"`
+	}{Status: "ok", Started: t.Started}
+	for _, tenant := range t.statuses() {
+		if tenant.Status != "ok" {
+			status.Status = "degraded"
+			status.Degraded = append(status.Degraded, tenant.Name)
+		}
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (t *Tenants) list(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"value": t.statuses()})
+}
@@ -0,0 +1,232 @@
+// Package diff produces unified diffs of text files, for showing changes
+// fetched from the API, which only reports which files changed.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Context is the number of unchanged lines shown around each change.
+const Context = 3
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+type op struct {
+	kind opKind
+	line string
+}
+
+// Unified returns the unified diff between two versions of a file, or ""
+// if they are equal. An empty oldName or newName marks an added or deleted
+// file.
+func Unified(oldName, newName, old, new string) string {
+	if old == new {
+		return ""
+	}
+	var b strings.Builder
+	from, to := "a/"+strings.TrimPrefix(oldName, "/"), "b/"+strings.TrimPrefix(newName, "/")
+	if oldName == "" {
+		from = "/dev/null"
+	}
+	if newName == "" {
+		to = "/dev/null"
+	}
+	if strings.ContainsRune(old, 0) || strings.ContainsRune(new, 0) {
+		fmt.Fprintf(&b, "Binary files %s and %s differ\n", from, to)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", from, to)
+
+	ops := diffLines(splitLines(old), splitLines(new))
+	for _, h := range hunks(ops) {
+		writeHunk(&b, ops, h)
+	}
+	return b.String()
+}
+
+// splitLines splits text into lines, keeping a marker for a missing final
+// newline the way diff(1) does.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	} else {
+		lines[len(lines)-1] += "\n\\ No newline at end of file\n"
+	}
+	return lines
+}
+
+// diffLines computes a shortest edit script. The lines both versions
+// start and end with are set aside first, which keeps long and mostly
+// equal texts, such as logs, cheap to compare.
+func diffLines(a, b []string) []op {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	ops := make([]op, 0, max(len(a), len(b)))
+	for _, line := range a[:prefix] {
+		ops = append(ops, op{opEqual, line})
+	}
+	if middleA, middleB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]; len(middleA)+len(middleB) > 0 {
+		ops = append(ops, myers(middleA, middleB)...)
+	}
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, op{opEqual, line})
+	}
+	return ops
+}
+
+// myers computes a shortest edit script with Myers' algorithm.
+func myers(a, b []string) []op {
+	n, m := len(a), len(b)
+	limit := n + m
+	offset := limit + 1
+	v := make([]int, 2*limit+2)
+	var trace [][]int
+
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, d, offset)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack walks the saved states of myers back from the end to
+// recover the edit script.
+func backtrack(a, b []string, trace [][]int, d, offset int) []op {
+	var ops []op
+	x, y := len(a), len(b)
+	for ; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, op{opEqual, a[x]})
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, op{opInsert, b[y]})
+		} else {
+			x--
+			ops = append(ops, op{opDelete, a[x]})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		ops = append(ops, op{opEqual, a[x]})
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// hunk is a range of ops shown together.
+type hunk struct{ start, end int }
+
+// hunks groups changes that are within 2*Context lines of each other.
+func hunks(ops []op) []hunk {
+	var out []hunk
+	for i := 0; i < len(ops); i++ {
+		if ops[i].kind == opEqual {
+			continue
+		}
+		start := max(0, i-Context)
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != opEqual {
+				end = j
+			} else if j-end > 2*Context {
+				break
+			}
+		}
+		end = min(len(ops), end+Context+1)
+		out = append(out, hunk{start, end})
+		i = end - 1
+	}
+	return out
+}
+
+func writeHunk(b *strings.Builder, ops []op, h hunk) {
+	// Line numbers of the hunk's first line in both versions
+	oldLine, newLine := 1, 1
+	for _, o := range ops[:h.start] {
+		if o.kind != opInsert {
+			oldLine++
+		}
+		if o.kind != opDelete {
+			newLine++
+		}
+	}
+	oldCount, newCount := 0, 0
+	for _, o := range ops[h.start:h.end] {
+		if o.kind != opInsert {
+			oldCount++
+		}
+		if o.kind != opDelete {
+			newCount++
+		}
+	}
+	fmt.Fprintf(b, "@@ -%s +%s @@\n", hunkRange(oldLine, oldCount), hunkRange(newLine, newCount))
+	for _, o := range ops[h.start:h.end] {
+		switch o.kind {
+		case opEqual:
+			b.WriteString(" " + o.line)
+		case opDelete:
+			b.WriteString("-" + o.line)
+		case opInsert:
+			b.WriteString("+" + o.line)
+		}
+	}
+}
+
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -0,0 +1,143 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// DefaultRetention is how long individual runs are kept by default.
+const DefaultRetention = 90 * 24 * time.Hour
+
+// Policy decides what compaction keeps.
+type Policy struct {
+	// Retention is how long individual runs are kept before they are
+	// folded into daily rollups.
+	Retention time.Duration
+	// RollupRetention is how long rollups are kept; zero keeps them
+	// forever.
+	RollupRetention time.Duration
+}
+
+// CompactResult reports what a compaction did, or would do.
+type CompactResult struct {
+	RolledUp       int // runs folded into rollups
+	Duplicates     int // runs recorded more than once
+	DroppedRollups int // rollups past their retention
+	Before, After  Stats
+}
+
+// Compact folds runs older than the policy's retention into daily rollups
+// and drops expired rollups. With dryRun the store is left untouched.
+func (s *Store) Compact(p Policy, now time.Time, dryRun bool) (CompactResult, error) {
+	var result CompactResult
+	unlock, err := s.lock()
+	if err != nil {
+		return result, err
+	}
+	defer unlock()
+
+	if result.Before, err = s.Stats(); err != nil {
+		return result, err
+	}
+	raw, err := readLines[Run](filepath.Join(s.Dir, runsFile))
+	if err != nil {
+		return result, err
+	}
+	runs := dedupe(raw)
+	result.Duplicates = len(raw) - len(runs)
+	rollups, err := s.Rollups()
+	if err != nil {
+		return result, err
+	}
+
+	// Roll up whole days only, so a day is never split between runs and
+	// its rollup
+	cutoff := startOfDay(now.Add(-p.Retention))
+	var kept, old []Run
+	for _, r := range runs {
+		if r.Finished.Before(cutoff) {
+			old = append(old, r)
+		} else {
+			kept = append(kept, r)
+		}
+	}
+	result.RolledUp = len(old)
+	rollups = merge(rollups, old)
+
+	if p.RollupRetention > 0 {
+		oldest := startOfDay(now.Add(-p.RollupRetention)).Format(time.DateOnly)
+		var live []Rollup
+		for _, r := range rollups {
+			if r.Day >= oldest {
+				live = append(live, r)
+			}
+		}
+		result.DroppedRollups = len(rollups) - len(live)
+		rollups = live
+	}
+
+	if dryRun {
+		// Sizes are only known once the files are rewritten
+		result.After = Stats{Runs: len(kept), Rollups: len(rollups)}
+		return result, nil
+	}
+	if result.RolledUp == 0 && result.Duplicates == 0 && result.DroppedRollups == 0 {
+		result.After = result.Before
+		return result, nil
+	}
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return result, err
+	}
+	if err := writeLines(filepath.Join(s.Dir, rollupsFile), rollups); err != nil {
+		return result, err
+	}
+	if err := writeLines(filepath.Join(s.Dir, runsFile), kept); err != nil {
+		return result, err
+	}
+	result.After, err = s.Stats()
+	return result, err
+}
+
+// merge adds runs to the matching daily rollups.
+func merge(rollups []Rollup, runs []Run) []Rollup {
+	type key struct {
+		day, project, branch string
+		pipelineID           int
+	}
+	index := map[key]int{}
+	for i, r := range rollups {
+		index[key{r.Day, r.Project, r.Branch, r.PipelineID}] = i
+	}
+	for _, run := range runs {
+		k := key{run.Finished.UTC().Format(time.DateOnly), run.Project, run.Branch, run.PipelineID}
+		i, ok := index[k]
+		if !ok {
+			i = len(rollups)
+			index[k] = i
+			rollups = append(rollups, Rollup{Day: k.day, Project: run.Project, PipelineID: run.PipelineID, Pipeline: run.Pipeline, Branch: run.Branch})
+		}
+		r := &rollups[i]
+		r.Pipeline = run.Pipeline // follow renames
+		r.Runs++
+		switch run.Result {
+		case "succeeded":
+			r.Succeeded++
+		case "failed":
+			r.Failed++
+		case "canceled":
+			r.Canceled++
+		}
+		seconds := run.Duration().Seconds()
+		r.TotalSeconds += seconds
+		r.MaxSeconds = max(r.MaxSeconds, seconds)
+	}
+	sort.SliceStable(rollups, func(i, j int) bool { return rollups[i].Day < rollups[j].Day })
+	return rollups
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
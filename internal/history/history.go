@@ -0,0 +1,307 @@
+// Package history keeps a local record of completed pipeline runs, so run
+// statistics outlive Azure DevOps' own retention. Recent runs are kept
+// individually; older ones are compacted into daily rollups.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// File names in the store directory.
+const (
+	runsFile        = "runs.jsonl"
+	rollupsFile     = "rollups.jsonl"
+	annotationsFile = "annotations.jsonl"
+	lockFile        = "lock"
+)
+
+// Run is a completed pipeline run.
+type Run struct {
+	Project    string    `json:"project"`
+	PipelineID int       `json:"pipelineId"`
+	Pipeline   string    `json:"pipeline"`
+	RunID      int       `json:"runId"`
+	Name       string    `json:"name"`
+	Branch     string    `json:"branch"`
+	Result     string    `json:"result"`
+	Created    time.Time `json:"created"`
+	Finished   time.Time `json:"finished"`
+}
+
+// Duration returns how long the run took.
+func (r Run) Duration() time.Duration {
+	if r.Finished.Before(r.Created) {
+		return 0
+	}
+	return r.Finished.Sub(r.Created)
+}
+
+// Rollup aggregates the runs of one pipeline and branch on one UTC day.
+type Rollup struct {
+	Day        string `json:"day"` // YYYY-MM-DD
+	Project    string `json:"project"`
+	PipelineID int    `json:"pipelineId"`
+	Pipeline   string `json:"pipeline"`
+	Branch     string `json:"branch"`
+
+	Runs      int `json:"runs"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	Canceled  int `json:"canceled"`
+
+	TotalSeconds float64 `json:"totalSeconds"`
+	MaxSeconds   float64 `json:"maxSeconds"`
+}
+
+// Annotation is a note on a run, such as the incident it caused or
+// fixed. Annotations are never compacted.
+type Annotation struct {
+	Project    string    `json:"project"`
+	PipelineID int       `json:"pipelineId"`
+	Pipeline   string    `json:"pipeline"`
+	RunID      int       `json:"runId"`
+	RunName    string    `json:"runName"`
+	Message    string    `json:"message"`
+	Tags       []string  `json:"tags,omitempty"`
+	Author     string    `json:"author,omitempty"`
+	Time       time.Time `json:"time"`
+}
+
+// Store is a history directory holding JSON Lines files: individual runs,
+// appended as they complete, rollups, rewritten on compaction, and
+// annotations.
+type Store struct {
+	Dir string
+}
+
+// Append records completed runs.
+func (s *Store) Append(runs ...Run) error {
+	return appendLines(s, runsFile, runs)
+}
+
+// Annotate records annotations.
+func (s *Store) Annotate(annotations ...Annotation) error {
+	return appendLines(s, annotationsFile, annotations)
+}
+
+// Annotations returns the recorded annotations, oldest first.
+func (s *Store) Annotations() ([]Annotation, error) {
+	annotations, err := readLines[Annotation](filepath.Join(s.Dir, annotationsFile))
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(annotations, func(i, j int) bool { return annotations[i].Time.Before(annotations[j].Time) })
+	return annotations, nil
+}
+
+// appendLines appends values to a JSON Lines file of the store.
+func appendLines[T any](s *Store, name string, values []T) error {
+	if len(values) == 0 {
+		return nil
+	}
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := os.OpenFile(filepath.Join(s.Dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, v := range values {
+		if err := enc.Encode(v); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Runs returns the recorded runs, oldest first. A run recorded twice is
+// returned once.
+func (s *Store) Runs() ([]Run, error) {
+	runs, err := readLines[Run](filepath.Join(s.Dir, runsFile))
+	if err != nil {
+		return nil, err
+	}
+	return dedupe(runs), nil
+}
+
+// Rollups returns the daily rollups, oldest first.
+func (s *Store) Rollups() ([]Rollup, error) {
+	return readLines[Rollup](filepath.Join(s.Dir, rollupsFile))
+}
+
+// Stats describes the size of a store.
+type Stats struct {
+	Runs        int
+	Rollups     int
+	Annotations int
+	Oldest      time.Time // oldest run or rollup day
+	Newest      time.Time
+	Bytes       int64
+}
+
+// Stats reports how much the store holds.
+func (s *Store) Stats() (Stats, error) {
+	var st Stats
+	runs, err := s.Runs()
+	if err != nil {
+		return st, err
+	}
+	rollups, err := s.Rollups()
+	if err != nil {
+		return st, err
+	}
+	annotations, err := s.Annotations()
+	if err != nil {
+		return st, err
+	}
+	st.Runs, st.Rollups, st.Annotations = len(runs), len(rollups), len(annotations)
+	for _, r := range runs {
+		st.widen(r.Finished)
+	}
+	for _, r := range rollups {
+		if day, err := time.Parse(time.DateOnly, r.Day); err == nil {
+			st.widen(day)
+		}
+	}
+	for _, name := range []string{runsFile, rollupsFile, annotationsFile} {
+		if info, err := os.Stat(filepath.Join(s.Dir, name)); err == nil {
+			st.Bytes += info.Size()
+		}
+	}
+	return st, nil
+}
+
+func (st *Stats) widen(t time.Time) {
+	if st.Oldest.IsZero() || t.Before(st.Oldest) {
+		st.Oldest = t
+	}
+	if t.After(st.Newest) {
+		st.Newest = t
+	}
+}
+
+// readLines decodes a JSON Lines file, skipping lines that do not decode,
+// such as one cut short by a crash. A missing file is empty.
+func readLines[T any](path string) ([]T, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []T
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var v T
+		if json.Unmarshal(scanner.Bytes(), &v) == nil {
+			out = append(out, v)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return out, nil
+}
+
+// writeLines replaces a JSON Lines file atomically.
+func writeLines[T any](path string, values []T) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, v := range values {
+		if err := enc.Encode(v); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func dedupe(runs []Run) []Run {
+	type key struct {
+		project string
+		id      int
+	}
+	index := map[key]int{}
+	var out []Run
+	for _, r := range runs {
+		k := key{r.Project, r.RunID}
+		if i, ok := index[k]; ok {
+			out[i] = r
+			continue
+		}
+		index[k] = len(out)
+		out = append(out, r)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Finished.Before(out[j].Finished) })
+	return out
+}
+
+// staleLock is how old a lock file must be before it is assumed to be left
+// over from a crashed process.
+const staleLock = time.Minute
+
+// lock takes the store's lock file, shared by every fomo process, so a
+// compaction cannot drop runs appended while it rewrites the files.
+func (s *Store) lock() (func(), error) {
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(s.Dir, lockFile)
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLock {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("history store %s is locked by another fomo process", s.Dir)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,102 @@
+package history
+
+import (
+	"sort"
+	"time"
+)
+
+// Bucket aggregates the runs that finished in one time interval.
+type Bucket struct {
+	Start     time.Time
+	Runs      int
+	Succeeded int
+	Failed    int
+	Canceled  int
+
+	TotalSeconds float64
+	MaxSeconds   float64
+}
+
+// Pipelines returns the names of the pipelines with recorded runs or
+// rollups, sorted.
+func (s *Store) Pipelines() ([]string, error) {
+	runs, err := s.Runs()
+	if err != nil {
+		return nil, err
+	}
+	rollups, err := s.Rollups()
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, r := range runs {
+		seen[r.Pipeline] = true
+	}
+	for _, r := range rollups {
+		seen[r.Pipeline] = true
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Aggregate sums the runs of a pipeline, or of every pipeline if it is
+// empty, that finished between from and to into buckets of step. Rollups
+// count at the start of their day, so buckets shorter than a day only
+// hold individual runs for days that were compacted.
+func (s *Store) Aggregate(pipeline string, from, to time.Time, step time.Duration) ([]Bucket, error) {
+	if step <= 0 || !to.After(from) {
+		return nil, nil
+	}
+	runs, err := s.Runs()
+	if err != nil {
+		return nil, err
+	}
+	rollups, err := s.Rollups()
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := make([]Bucket, int((to.Sub(from)+step-1)/step))
+	for i := range buckets {
+		buckets[i].Start = from.Add(time.Duration(i) * step)
+	}
+	bucket := func(t time.Time) *Bucket {
+		if t.Before(from) || !t.Before(to) {
+			return nil
+		}
+		return &buckets[int(t.Sub(from)/step)]
+	}
+
+	for _, r := range runs {
+		b := bucket(r.Finished)
+		if b == nil || (pipeline != "" && r.Pipeline != pipeline) {
+			continue
+		}
+		b.add(merge(nil, []Run{r})[0])
+	}
+	for _, r := range rollups {
+		day, err := time.Parse(time.DateOnly, r.Day)
+		if err != nil {
+			continue
+		}
+		b := bucket(day)
+		if b == nil || (pipeline != "" && r.Pipeline != pipeline) {
+			continue
+		}
+		b.add(r)
+	}
+	return buckets, nil
+}
+
+func (b *Bucket) add(r Rollup) {
+	b.Runs += r.Runs
+	b.Succeeded += r.Succeeded
+	b.Failed += r.Failed
+	b.Canceled += r.Canceled
+	b.TotalSeconds += r.TotalSeconds
+	b.MaxSeconds = max(b.MaxSeconds, r.MaxSeconds)
+}
@@ -0,0 +1,62 @@
+package i18n
+
+// spanish is the Spanish catalog. Keep the keys identical to the English
+// messages, verbs and trailing spaces or newlines included.
+var spanish = map[string]string{
+	// Yes/no questions
+	" [y/N]: ": " [s/N]: ",
+	"y|yes":    "s|si|sí",
+
+	// Configuration and credentials
+	"Enter your Azure DevOps organization: ":                    "Introduce tu organización de Azure DevOps: ",
+	"Enter your Azure DevOps project: ":                         "Introduce tu proyecto de Azure DevOps: ",
+	"Enter your Azure DevOps PAT: ":                             "Introduce tu PAT de Azure DevOps: ",
+	"Passphrase for the PAT of profile %s: ":                    "Frase de contraseña del PAT del perfil %s: ",
+	"Save the PAT for profile %s, encrypted with a passphrase?": "¿Guardar el PAT del perfil %s, cifrado con una frase de contraseña?",
+	"Profile %s already has a stored PAT. Replace it?":          "El perfil %s ya tiene un PAT guardado. ¿Reemplazarlo?",
+	"Jira API token: ":                                          "Token de la API de Jira: ",
+	"Passphrase for the Jira token of profile %s: ":             "Frase de contraseña del token de Jira del perfil %s: ",
+	"Profile %s already has a stored Jira token. Replace it?":   "El perfil %s ya tiene un token de Jira guardado. ¿Reemplazarlo?",
+	"New passphrase: ":                                          "Nueva frase de contraseña: ",
+	"Repeat passphrase: ":                                       "Repite la frase de contraseña: ",
+	"No passphrase given.\n":                                    "No se indicó ninguna frase de contraseña.\n",
+	"The passphrases do not match.\n":                           "Las frases de contraseña no coinciden.\n",
+	"The PAT was not saved.\n":                                  "El PAT no se guardó.\n",
+	"PAT saved to %s. fomo will ask for the passphrase when it needs the PAT; set %s to skip the prompt.\n": "PAT guardado en %s. fomo pedirá la frase de contraseña cuando necesite el PAT; define %s para no tener que escribirla.\n",
+
+	// Destructive operations
+	"This cannot be undone. Type %q to confirm %s: ": "Esto no se puede deshacer. Escribe %q para confirmar que quieres %s: ",
+	"%s ran %d times in the last 30 days.\n":         "%s se ejecutó %d veces en los últimos 30 días.\n",
+	"%s %s?":                                         "¿%s %s?",
+	"delete pipeline":                                "eliminar el pipeline",
+	"rename pipeline":                                "renombrar el pipeline",
+	"This will %s %s:\n":                             "Se va a %s %s:\n",
+	"cancel":                                         "cancelar",
+	"remove":                                         "quitar",
+	"set":                                            "establecer",
+	"fix":                                            "corregir",
+
+	// Running pipelines and migrating
+	"A value is required.": "Se necesita un valor.",
+	"Value for secret %s in variable group %s (empty to skip): ": "Valor del secreto %s del grupo de variables %s (vacío para omitirlo): ",
+
+	// Updates and telemetry
+	"Update %s from %s to %s?": "¿Actualizar %s de %s a %s?",
+	"fomo can send anonymous usage statistics to its maintainers: how often each\n" +
+		"command runs and fails, with fomo's version and your OS. It never sends\n" +
+		"arguments, organization or project names, or credentials. You can change\n" +
+		"your mind any time with fomo telemetry on|off.\n": "fomo puede enviar estadísticas de uso anónimas a sus mantenedores: cuántas\n" +
+		"veces se ejecuta y falla cada comando, con la versión de fomo y tu sistema\n" +
+		"operativo. Nunca envía argumentos, nombres de organizaciones o proyectos ni\n" +
+		"credenciales. Puedes cambiar de opinión cuando quieras con fomo telemetry on|off.\n",
+	"Send anonymous usage statistics?": "¿Enviar estadísticas de uso anónimas?",
+
+	// Offline mode
+	"Offline: stale as of %s (%s ago).\n": "Sin conexión: datos del %s (hace %s).\n",
+
+	// Errors
+	"Hint: check that your PAT is valid, has not expired and has the scopes this command needs; fomo config show tells where it came from.\n": "Sugerencia: comprueba que tu PAT es válido, no ha caducado y tiene los permisos que necesita este comando; fomo config show indica de dónde procede.\n",
+	"Hint: check the organization, project and pipeline names; fomo config show lists the ones in use.\n":                                     "Sugerencia: comprueba los nombres de la organización, el proyecto y el pipeline; fomo config show muestra los que se están usando.\n",
+	"Hint: Azure DevOps is rate limiting your requests; wait a minute and try again.\n":                                                       "Sugerencia: Azure DevOps está limitando tus peticiones; espera un minuto y vuelve a intentarlo.\n",
+	"fomo crashed: %v\n\nA crash report was written to %s\nPlease review it and attach it to a bug report.\n":                                 "fomo ha fallado: %v\n\nSe ha escrito un informe del fallo en %s\nRevísalo y adjúntalo a un informe de error.\n",
+}
@@ -0,0 +1,95 @@
+// Package i18n translates fomo's interactive prompts, hints and error
+// prefixes. Messages are keyed by their English text, so a message missing
+// from a catalog is simply shown in English, and adding a language never
+// needs a change to the code that prints.
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// EnvLang selects the language, taking precedence over the locale setting
+// in the user config file and the system locale.
+const EnvLang = "FOMO_LANG"
+
+// catalogs maps a language to its translations, keyed by the English text.
+var catalogs = map[string]map[string]string{
+	"es": spanish,
+}
+
+var (
+	lang    = "en"
+	catalog map[string]string // nil for English
+)
+
+// Languages returns the languages fomo speaks, English first.
+func Languages() []string {
+	var langs []string
+	for l := range catalogs {
+		langs = append(langs, l)
+	}
+	sort.Strings(langs)
+	return append([]string{"en"}, langs...)
+}
+
+// SystemLocale returns the locale of the environment, from LC_ALL,
+// LC_MESSAGES and LANG in that order.
+func SystemLocale(getenv func(string) string) string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Set selects the language of a locale such as "es", "es_ES.UTF-8" or
+// "es-MX". Languages without a catalog, and the C and POSIX locales, fall
+// back to English. It returns the language selected.
+func Set(locale string) string {
+	l := strings.ToLower(locale)
+	if i := strings.IndexAny(l, "_-.@"); i >= 0 {
+		l = l[:i]
+	}
+	lang, catalog = "en", nil
+	if c, ok := catalogs[l]; ok {
+		lang, catalog = l, c
+	}
+	return lang
+}
+
+// Lang returns the selected language.
+func Lang() string {
+	return lang
+}
+
+// T translates an English message and, given args, formats it like
+// fmt.Sprintf. Translations keep the verbs of the English text in the same
+// order.
+func T(msg string, args ...any) string {
+	if translated, ok := catalog[msg]; ok {
+		msg = translated
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// Yes reports whether answer means yes in the selected language. English
+// answers are always understood.
+func Yes(answer string) bool {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	switch answer {
+	case "y", "yes":
+		return true
+	}
+	for _, word := range strings.Split(T("y|yes"), "|") {
+		if answer == word {
+			return true
+		}
+	}
+	return false
+}
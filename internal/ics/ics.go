@@ -0,0 +1,143 @@
+// Package ics writes iCalendar files (RFC 5545) of one-off and weekly or
+// monthly recurring events, for importing into or subscribing from team
+// calendars. Recurring events are written in the IANA time zone of their
+// start time, which calendar applications resolve themselves; no
+// VTIMEZONE definitions are included.
+package ics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Event is a calendar event.
+type Event struct {
+	UID         string // unique and stable across exports, so re-imports update events
+	Summary     string
+	Description string
+	URL         string
+	Start       time.Time // in the time zone the event recurs in
+	End         time.Time
+	Repeat      *Recurrence // nil for a one-off event
+}
+
+// Recurrence is when an event repeats, at the time of day of its start.
+// Without any field set, it repeats every day.
+type Recurrence struct {
+	Weekdays  []time.Weekday // on these days of the week
+	MonthDays []int          // on these days of the month
+	Months    []time.Month   // only in these months
+}
+
+var weekdayCodes = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// rule renders the recurrence as an RRULE value.
+func (r *Recurrence) rule() string {
+	var parts []string
+	switch {
+	case len(r.MonthDays) > 0:
+		parts = append(parts, "FREQ=MONTHLY", "BYMONTHDAY="+join(r.MonthDays, strconv.Itoa))
+	case len(r.Weekdays) > 0 && len(r.Weekdays) < 7:
+		parts = append(parts, "FREQ=WEEKLY", "BYDAY="+join(r.Weekdays, func(d time.Weekday) string { return weekdayCodes[d] }))
+	default:
+		parts = append(parts, "FREQ=DAILY")
+	}
+	if len(r.Months) > 0 {
+		parts = append(parts, "BYMONTH="+join(r.Months, func(m time.Month) string { return strconv.Itoa(int(m)) }))
+	}
+	return strings.Join(parts, ";")
+}
+
+func join[T any](values []T, format func(T) string) string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = format(v)
+	}
+	return strings.Join(s, ",")
+}
+
+// Write writes a calendar with the given name and events.
+func Write(w io.Writer, name string, events []Event) error {
+	bw := bufio.NewWriter(w)
+	lw := &lineWriter{w: bw}
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	lw.line("BEGIN:VCALENDAR")
+	lw.line("VERSION:2.0")
+	lw.line("PRODID:-//fomo//fomo//EN")
+	lw.line("CALSCALE:GREGORIAN")
+	if name != "" {
+		lw.line("X-WR-CALNAME:" + escape(name))
+	}
+	for _, e := range events {
+		lw.line("BEGIN:VEVENT")
+		lw.line("UID:" + e.UID)
+		lw.line("DTSTAMP:" + stamp)
+		lw.line("DTSTART" + datetime(e.Start))
+		lw.line("DTEND" + datetime(e.End))
+		if e.Repeat != nil {
+			lw.line("RRULE:" + e.Repeat.rule())
+		}
+		lw.line("SUMMARY:" + escape(e.Summary))
+		if e.Description != "" {
+			lw.line("DESCRIPTION:" + escape(e.Description))
+		}
+		if e.URL != "" {
+			lw.line("URL:" + e.URL)
+		}
+		lw.line("END:VEVENT")
+	}
+	lw.line("END:VCALENDAR")
+	if lw.err != nil {
+		return lw.err
+	}
+	return bw.Flush()
+}
+
+// datetime renders a time as the value of DTSTART or DTEND, with its
+// parameters: in UTC, or local to a named time zone.
+func datetime(t time.Time) string {
+	zone := t.Location().String()
+	if zone == "UTC" || zone == "Local" {
+		return ":" + t.UTC().Format("20060102T150405Z")
+	}
+	return fmt.Sprintf(";TZID=%s:%s", zone, t.Format("20060102T150405"))
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escape(text string) string {
+	return escaper.Replace(text)
+}
+
+// lineWriter writes content lines, folded at 75 octets without splitting
+// UTF-8 sequences, and remembers the first error.
+type lineWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (lw *lineWriter) line(s string) {
+	if lw.err != nil {
+		return
+	}
+	var b strings.Builder
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		limit = 74 // continuation lines start with a space
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+	_, lw.err = io.WriteString(lw.w, b.String())
+}
@@ -0,0 +1,402 @@
+// Package mockserver is a small in-memory stand-in for the Azure DevOps
+// pipelines, runs and logs endpoints, used for demos and local testing.
+package mockserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fomo/internal/client"
+)
+
+var pipelineNames = []string{
+	"api-build", "web-build", "infra-deploy", "nightly-tests", "mobile-release",
+	"docs-publish", "db-migrations", "perf-suite", "security-scan", "sdk-publish",
+}
+
+var branches = []string{"refs/heads/main", "refs/heads/main", "refs/heads/develop", "refs/heads/release/1.2", "refs/heads/feature/login"}
+
+var stepNames = []string{"Initialize job", "Checkout", "Restore", "Build", "Test", "Publish artifacts", "Finalize job"}
+
+// run is a seeded or queued run. Queued runs complete on their own once
+// their planned duration has elapsed.
+type run struct {
+	client.Run
+	pipelineID int
+	finishAt   time.Time
+	result     string
+	logs       [][]string
+}
+
+// Options controls the generated data.
+type Options struct {
+	Organization string
+	Project      string
+	Seed         int64
+	Pipelines    int
+	RunsPerPipe  int
+	RunDuration  time.Duration // how long queued runs take to complete
+}
+
+// Server holds the in-memory organization.
+type Server struct {
+	mu        sync.Mutex
+	opts      Options
+	rand      *rand.Rand
+	baseURL   string
+	pipelines []client.Pipeline
+	runs      map[int]*run
+	nextRunID int
+	now       func() time.Time
+}
+
+// New returns a server seeded deterministically from opts.Seed.
+func New(opts Options) *Server {
+	if opts.Pipelines <= 0 {
+		opts.Pipelines = 5
+	}
+	if opts.RunsPerPipe <= 0 {
+		opts.RunsPerPipe = 10
+	}
+	if opts.RunDuration <= 0 {
+		opts.RunDuration = 30 * time.Second
+	}
+
+	s := &Server{
+		opts:      opts,
+		rand:      rand.New(rand.NewSource(opts.Seed)),
+		runs:      map[int]*run{},
+		nextRunID: 1,
+		now:       time.Now,
+	}
+	s.seed()
+	return s
+}
+
+func (s *Server) seed() {
+	now := s.now().UTC().Truncate(time.Second)
+	for i := 0; i < s.opts.Pipelines; i++ {
+		name := pipelineNames[i%len(pipelineNames)]
+		if i >= len(pipelineNames) {
+			name = fmt.Sprintf("%s-%d", name, i/len(pipelineNames)+1)
+		}
+		s.pipelines = append(s.pipelines, client.Pipeline{ID: i + 1, Name: name, Folder: `\`, Revision: 1})
+	}
+
+	for _, p := range s.pipelines {
+		start := now.Add(-time.Duration(s.opts.RunsPerPipe) * 6 * time.Hour)
+		for j := 0; j < s.opts.RunsPerPipe; j++ {
+			created := start.Add(time.Duration(j)*6*time.Hour + time.Duration(s.rand.Intn(3600))*time.Second)
+			duration := time.Duration(120+s.rand.Intn(900)) * time.Second
+			r := s.newRun(p, created, branches[s.rand.Intn(len(branches))], s.randomResult())
+			r.finishAt = created.Add(duration)
+			r.complete()
+		}
+	}
+}
+
+func (s *Server) randomResult() string {
+	switch n := s.rand.Intn(10); {
+	case n < 7:
+		return client.RunResultSucceeded
+	case n < 9:
+		return client.RunResultFailed
+	}
+	return client.RunResultCanceled
+}
+
+func (s *Server) newRun(p client.Pipeline, created time.Time, branch, result string) *run {
+	id := s.nextRunID
+	s.nextRunID++
+
+	r := &run{
+		Run: client.Run{
+			ID:          id,
+			Name:        fmt.Sprintf("%s.%d", created.Format("20060102"), id),
+			State:       client.RunStateInProgress,
+			CreatedDate: created,
+			Pipeline:    client.PipelineReference{ID: p.ID, Name: p.Name, Folder: p.Folder},
+			Resources: client.RunResources{Repositories: map[string]client.RepositoryResource{
+				"self": {RefName: branch, Version: fmt.Sprintf("%016x%016x%08x", s.rand.Uint64(), s.rand.Uint64(), s.rand.Uint32())},
+			}},
+		},
+		pipelineID: p.ID,
+		result:     result,
+	}
+
+	// One log per step; a failed run fails in its build or test step
+	failAt := -1
+	if result == client.RunResultFailed {
+		failAt = 3 + s.rand.Intn(2)
+	}
+	for i, step := range stepNames {
+		lines := []string{fmt.Sprintf("##[section]Starting: %s", step)}
+		for k := 0; k < 3+s.rand.Intn(5); k++ {
+			lines = append(lines, fmt.Sprintf("%s: line %d", strings.ToLower(step), k+1))
+		}
+		if i == failAt {
+			lines = append(lines, "##[error]Process completed with exit code 1.")
+			r.logs = append(r.logs, lines)
+			break
+		}
+		lines = append(lines, fmt.Sprintf("##[section]Finishing: %s", step))
+		r.logs = append(r.logs, lines)
+	}
+
+	s.runs[id] = r
+	return r
+}
+
+// complete marks a run finished with its planned result.
+func (r *run) complete() {
+	r.State = client.RunStateCompleted
+	r.Result = r.result
+	r.FinishedDate = r.finishAt
+}
+
+// refresh completes queued runs whose planned duration has elapsed.
+func (s *Server) refresh() {
+	now := s.now()
+	for _, r := range s.runs {
+		if r.State != client.RunStateCompleted && !now.Before(r.finishAt) {
+			r.complete()
+		}
+	}
+}
+
+// Handler returns the HTTP handler serving the mock API. baseURL is the
+// externally visible address used in links.
+func (s *Server) Handler(baseURL string) http.Handler {
+	s.baseURL = strings.TrimRight(baseURL, "/")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{org}/{project}/_apis/pipelines", s.listPipelines)
+	mux.HandleFunc("GET /{org}/{project}/_apis/pipelines/{pipeline}", s.getPipeline)
+	mux.HandleFunc("GET /{org}/{project}/_apis/pipelines/{pipeline}/runs", s.listRuns)
+	mux.HandleFunc("POST /{org}/{project}/_apis/pipelines/{pipeline}/runs", s.queueRun)
+	mux.HandleFunc("GET /{org}/{project}/_apis/pipelines/{pipeline}/runs/{run}", s.getRun)
+	mux.HandleFunc("GET /{org}/{project}/_apis/pipelines/{pipeline}/runs/{run}/logs", s.listLogs)
+	mux.HandleFunc("GET /{org}/{project}/_apis/pipelines/{pipeline}/runs/{run}/logs/{log}", s.getLog)
+
+	// Signed log URLs carry their own authorization, like the real service
+	signed := http.NewServeMux()
+	signed.HandleFunc("GET /_mock/logs/{run}/{log}", s.logContent)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler := http.Handler(signed)
+		if !strings.HasPrefix(r.URL.Path, "/_mock/") {
+			if _, pat, ok := r.BasicAuth(); !ok || pat == "" {
+				writeError(w, http.StatusUnauthorized, "a personal access token is required")
+				return
+			}
+			if !s.inScope(r) {
+				writeError(w, http.StatusNotFound, "organization or project not found")
+				return
+			}
+			handler = mux
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.refresh()
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// inScope reports whether the request targets the served org and project.
+func (s *Server) inScope(r *http.Request) bool {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 3)
+	if len(parts) < 3 {
+		return false
+	}
+	return strings.EqualFold(parts[0], s.opts.Organization) && strings.EqualFold(parts[1], s.opts.Project)
+}
+
+func (s *Server) listPipelines(w http.ResponseWriter, r *http.Request) {
+	pipelines := make([]client.Pipeline, len(s.pipelines))
+	for i, p := range s.pipelines {
+		pipelines[i] = s.withLinks(p)
+	}
+	writeJSON(w, client.PipelinesResponse{Count: len(pipelines), Pipelines: pipelines})
+}
+
+func (s *Server) getPipeline(w http.ResponseWriter, r *http.Request) {
+	p, ok := s.pipeline(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, s.withLinks(p))
+}
+
+func (s *Server) listRuns(w http.ResponseWriter, r *http.Request) {
+	p, ok := s.pipeline(w, r)
+	if !ok {
+		return
+	}
+	var runs []client.Run
+	for _, run := range s.runs {
+		if run.pipelineID == p.ID {
+			runs = append(runs, s.runLinks(run))
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].ID > runs[j].ID })
+	writeJSON(w, client.RunsResponse{Count: len(runs), Runs: runs})
+}
+
+func (s *Server) queueRun(w http.ResponseWriter, r *http.Request) {
+	p, ok := s.pipeline(w, r)
+	if !ok {
+		return
+	}
+	var request client.RunRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+			return
+		}
+	}
+
+	branch := "refs/heads/main"
+	if request.Resources != nil {
+		if ref := request.Resources.Repositories["self"].RefName; ref != "" {
+			branch = ref
+			if !strings.HasPrefix(branch, "refs/") {
+				branch = "refs/heads/" + branch
+			}
+		}
+	}
+
+	now := s.now().UTC().Truncate(time.Second)
+	run := s.newRun(p, now, branch, s.randomResult())
+	run.finishAt = now.Add(s.opts.RunDuration)
+	writeJSON(w, s.runLinks(run))
+}
+
+func (s *Server) getRun(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.run(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, s.runLinks(run))
+}
+
+func (s *Server) listLogs(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.run(w, r)
+	if !ok {
+		return
+	}
+	var logs []client.Log
+	for i, lines := range run.visibleLogs(s.now()) {
+		logs = append(logs, client.Log{ID: i + 1, LineCount: len(lines), CreatedOn: run.CreatedDate, LastChangedOn: run.CreatedDate})
+	}
+	writeJSON(w, client.LogsResponse{Logs: logs})
+}
+
+func (s *Server) getLog(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.run(w, r)
+	if !ok {
+		return
+	}
+	logs := run.visibleLogs(s.now())
+	id, err := strconv.Atoi(r.PathValue("log"))
+	if err != nil || id < 1 || id > len(logs) {
+		writeError(w, http.StatusNotFound, "log not found")
+		return
+	}
+	log := client.Log{ID: id, LineCount: len(logs[id-1]), CreatedOn: run.CreatedDate, LastChangedOn: run.CreatedDate}
+	if r.URL.Query().Get("$expand") == "signedContent" {
+		log.SignedContent = &client.SignedContent{
+			URL:              fmt.Sprintf("%s/_mock/logs/%d/%d", s.baseURL, run.ID, id),
+			SignatureExpires: s.now().Add(time.Hour).UTC(),
+		}
+	}
+	writeJSON(w, log)
+}
+
+func (s *Server) logContent(w http.ResponseWriter, r *http.Request) {
+	runID, _ := strconv.Atoi(r.PathValue("run"))
+	id, _ := strconv.Atoi(r.PathValue("log"))
+	run, ok := s.runs[runID]
+	if !ok {
+		writeError(w, http.StatusNotFound, "run not found")
+		return
+	}
+	logs := run.visibleLogs(s.now())
+	if id < 1 || id > len(logs) {
+		writeError(w, http.StatusNotFound, "log not found")
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	for _, line := range logs[id-1] {
+		fmt.Fprintf(w, "%s %s\n", run.CreatedDate.Format(time.RFC3339Nano), line)
+	}
+}
+
+// visibleLogs returns the logs written so far; a running run reveals its
+// steps progressively.
+func (r *run) visibleLogs(now time.Time) [][]string {
+	if r.State == client.RunStateCompleted {
+		return r.logs
+	}
+	total := r.finishAt.Sub(r.CreatedDate)
+	if total <= 0 {
+		return r.logs
+	}
+	n := int(float64(len(r.logs)) * float64(now.Sub(r.CreatedDate)) / float64(total))
+	return r.logs[:min(max(n, 1), len(r.logs))]
+}
+
+func (s *Server) pipeline(w http.ResponseWriter, r *http.Request) (client.Pipeline, bool) {
+	id, err := strconv.Atoi(r.PathValue("pipeline"))
+	if err == nil {
+		for _, p := range s.pipelines {
+			if p.ID == id {
+				return p, true
+			}
+		}
+	}
+	writeError(w, http.StatusNotFound, fmt.Sprintf("pipeline %s not found", r.PathValue("pipeline")))
+	return client.Pipeline{}, false
+}
+
+func (s *Server) run(w http.ResponseWriter, r *http.Request) (*run, bool) {
+	p, ok := s.pipeline(w, r)
+	if !ok {
+		return nil, false
+	}
+	id, err := strconv.Atoi(r.PathValue("run"))
+	if run, found := s.runs[id]; err == nil && found && run.pipelineID == p.ID {
+		return run, true
+	}
+	writeError(w, http.StatusNotFound, fmt.Sprintf("run %s not found", r.PathValue("run")))
+	return nil, false
+}
+
+func (s *Server) withLinks(p client.Pipeline) client.Pipeline {
+	p.Links = client.Links{Web: &client.Link{Href: fmt.Sprintf("%s/%s/%s/_build?definitionId=%d", s.baseURL, s.opts.Organization, s.opts.Project, p.ID)}}
+	return p
+}
+
+func (s *Server) runLinks(r *run) client.Run {
+	out := r.Run
+	out.Links = client.Links{Web: &client.Link{Href: fmt.Sprintf("%s/%s/%s/_build/results?buildId=%d", s.baseURL, s.opts.Organization, s.opts.Project, r.ID)}}
+	return out
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
@@ -0,0 +1,297 @@
+// Package modelgen generates the types of the client package from Swagger
+// 2.0 definitions of the Azure DevOps REST API, so that a field the service
+// adds is one property in a spec file rather than edits to every struct
+// that has it. fomo's spec files are hand-maintained; the generator reads
+// the same format as the published specs.
+//
+// A Config names the definitions to generate and what to call them.
+// Definitions it neither generates nor maps to a hand-written type decode
+// as json.RawMessage, so they are kept without being modeled.
+package modelgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Config describes the types to generate.
+type Config struct {
+	Package string `json:"package"`
+	// External maps definitions, from any spec, to hand-written types
+	External map[string]string `json:"external,omitempty"`
+	Types    []Type            `json:"types"`
+}
+
+// Type is a definition to generate a struct from.
+type Type struct {
+	Name       string `json:"name"`       // of the Go type
+	Spec       string `json:"spec"`       // file the definition is in
+	Definition string `json:"definition"` // name of the definition in the spec
+	// Required properties are expected in every response: they are not
+	// omitempty, which is what Drift reports as missing when they are not
+	Required []string `json:"required,omitempty"`
+	// Pointers are the object properties that can be absent, and must not
+	// be sent empty in request bodies
+	Pointers []string          `json:"pointers,omitempty"`
+	Rename   map[string]string `json:"rename,omitempty"` // property -> Go field name
+	Doc      string            `json:"doc,omitempty"`    // of the Go type, by default what it is generated from
+	// Properties the service returns that the spec leaves out
+	Properties map[string]*Schema `json:"properties,omitempty"`
+}
+
+// Schema is the part of a Swagger schema object the generator reads.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties,omitempty"` // a schema or a boolean
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+}
+
+type spec struct {
+	Definitions map[string]*Schema `json:"definitions"`
+}
+
+// LoadConfig reads a Config from a JSON file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+type generator struct {
+	cfg   *Config
+	dir   string
+	specs map[string]*spec
+	names map[string]string // spec#definition -> generated type
+	buf   bytes.Buffer
+	time  bool
+	raw   bool
+}
+
+// Generate returns the Go source of the types cfg describes, reading the
+// specs it names from dir.
+func Generate(cfg *Config, dir string) ([]byte, error) {
+	g := &generator{cfg: cfg, dir: dir, specs: map[string]*spec{}, names: map[string]string{}}
+	for _, t := range cfg.Types {
+		g.names[t.Spec+"#"+t.Definition] = t.Name
+	}
+	var body bytes.Buffer
+	for _, t := range cfg.Types {
+		if err := g.writeType(&body, t); err != nil {
+			return nil, fmt.Errorf("%s (%s in %s): %w", t.Name, t.Definition, t.Spec, err)
+		}
+	}
+
+	fmt.Fprintf(&g.buf, "// Code generated by modelgen from the specs in %s; DO NOT EDIT.\n\n", filepath.ToSlash(dir))
+	fmt.Fprintf(&g.buf, "package %s\n\n", cfg.Package)
+	var imports []string
+	if g.raw {
+		imports = append(imports, `"encoding/json"`)
+	}
+	if g.time {
+		imports = append(imports, `"time"`)
+	}
+	if len(imports) > 0 {
+		fmt.Fprintf(&g.buf, "import (\n%s\n)\n\n", strings.Join(imports, "\n"))
+	}
+	g.buf.Write(body.Bytes())
+	return format.Source(g.buf.Bytes())
+}
+
+func (g *generator) load(name string) (*spec, error) {
+	if s, ok := g.specs[name]; ok {
+		return s, nil
+	}
+	data, err := os.ReadFile(filepath.Join(g.dir, name))
+	if err != nil {
+		return nil, err
+	}
+	var s spec
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	g.specs[name] = &s
+	return &s, nil
+}
+
+// properties returns the properties of a definition, with those of the
+// definitions it extends through allOf.
+func (g *generator) properties(s *spec, schema *Schema, props map[string]*Schema) error {
+	for _, part := range schema.AllOf {
+		base := part
+		if part.Ref != "" {
+			base = s.Definitions[refName(part.Ref)]
+			if base == nil {
+				return fmt.Errorf("allOf refers to undefined %s", part.Ref)
+			}
+		}
+		if err := g.properties(s, base, props); err != nil {
+			return err
+		}
+	}
+	for name, p := range schema.Properties {
+		props[name] = p
+	}
+	return nil
+}
+
+func (g *generator) writeType(w *bytes.Buffer, t Type) error {
+	s, err := g.load(t.Spec)
+	if err != nil {
+		return err
+	}
+	def := s.Definitions[t.Definition]
+	if def == nil {
+		return fmt.Errorf("no such definition")
+	}
+	props := map[string]*Schema{}
+	if err := g.properties(s, def, props); err != nil {
+		return err
+	}
+	for name, p := range t.Properties {
+		props[name] = p
+	}
+	for _, name := range append(slices.Clone(t.Required), t.Pointers...) {
+		if props[name] == nil {
+			return fmt.Errorf("no property %s", name)
+		}
+	}
+
+	doc := t.Doc
+	if doc == "" {
+		doc = fmt.Sprintf("%s is the %s definition of %s.", t.Name, t.Definition, t.Spec)
+	}
+	writeComment(w, doc)
+	fmt.Fprintf(w, "type %s struct {\n", t.Name)
+	fields := map[string]string{}
+	for _, name := range slices.Sorted(maps.Keys(props)) {
+		field := t.Rename[name]
+		if field == "" {
+			field = fieldName(name)
+		}
+		if other, ok := fields[field]; ok {
+			return fmt.Errorf("properties %s and %s are both field %s", other, name, field)
+		}
+		fields[field] = name
+		typ := g.goType(t.Spec, props[name])
+		if slices.Contains(t.Pointers, name) {
+			typ = "*" + typ
+		}
+		tag := name
+		if !slices.Contains(t.Required, name) {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(w, "\t%s %s `json:%q`\n", field, typ, tag)
+	}
+	fmt.Fprintf(w, "}\n\n")
+	return nil
+}
+
+// goType returns the Go type of a schema in a spec.
+func (g *generator) goType(specName string, s *Schema) string {
+	if s.Ref != "" {
+		def := refName(s.Ref)
+		if name, ok := g.names[specName+"#"+def]; ok {
+			return name
+		}
+		if name, ok := g.cfg.External[def]; ok {
+			return name
+		}
+		g.raw = true
+		return "json.RawMessage"
+	}
+	switch s.Type {
+	case "string":
+		if s.Format == "date-time" {
+			g.time = true
+			return "time.Time"
+		}
+		return "string"
+	case "integer":
+		if s.Format == "int64" {
+			return "int64"
+		}
+		return "int"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		if s.Items == nil {
+			return "[]any"
+		}
+		return "[]" + g.goType(specName, s.Items)
+	}
+	var values Schema
+	if json.Unmarshal(s.AdditionalProperties, &values) == nil && (values.Ref != "" || values.Type != "") {
+		// Values that are any object are just any JSON value
+		value := g.goType(specName, &values)
+		if value == "map[string]any" {
+			value = "any"
+		}
+		return "map[string]" + value
+	}
+	return "map[string]any"
+}
+
+// writeComment writes text as a comment wrapped at 76 columns.
+func writeComment(w *bytes.Buffer, text string) {
+	line := "//"
+	for _, word := range strings.Fields(text) {
+		if len(line)+1+len(word) > 76 && line != "//" {
+			fmt.Fprintln(w, line)
+			line = "//"
+		}
+		line += " " + word
+	}
+	fmt.Fprintln(w, line)
+}
+
+func refName(ref string) string {
+	return strings.TrimPrefix(ref, "#/definitions/")
+}
+
+// initialisms are the words Go spells in capitals.
+var initialisms = map[string]string{
+	"Api": "API", "Id": "ID", "Ids": "IDs", "Json": "JSON", "Ssh": "SSH",
+	"Uri": "URI", "Url": "URL", "Urls": "URLs", "Yaml": "YAML",
+}
+
+// fieldName returns the Go name of a JSON property: "_links" becomes
+// Links and "webUrl" WebURL.
+func fieldName(property string) string {
+	var words []string
+	start := 0
+	runes := []rune(strings.TrimLeft(property, "_"))
+	for i := 1; i <= len(runes); i++ {
+		if i == len(runes) || unicode.IsUpper(runes[i]) || !unicode.IsLetter(runes[i]) && !unicode.IsDigit(runes[i]) {
+			word := strings.Trim(string(runes[start:i]), "._-")
+			if word != "" {
+				word = strings.ToUpper(word[:1]) + word[1:]
+				if initialism, ok := initialisms[word]; ok {
+					word = initialism
+				}
+				words = append(words, word)
+			}
+			start = i
+		}
+	}
+	return strings.Join(words, "")
+}
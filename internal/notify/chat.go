@@ -0,0 +1,144 @@
+package notify
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Chat backends post to a Matrix room or a Discord channel, for the teams
+// that use neither Slack nor Teams.
+
+func init() {
+	Register("matrix", func(options map[string]string) (Notifier, error) {
+		if options["homeserver"] == "" || options["token"] == "" || options["room"] == "" {
+			return nil, fmt.Errorf("matrix notifier requires homeserver, token and room options")
+		}
+		if !strings.HasPrefix(options["room"], "!") {
+			return nil, fmt.Errorf("matrix notifier: room must be a room ID like !abc:example.org, not %q", options["room"])
+		}
+		return &matrixNotifier{
+			homeserver: strings.TrimRight(options["homeserver"], "/"),
+			token:      options["token"],
+			room:       options["room"],
+		}, nil
+	})
+	Register("discord", func(options map[string]string) (Notifier, error) {
+		if options["webhook"] == "" {
+			return nil, fmt.Errorf("discord notifier requires a webhook option")
+		}
+		return &discordNotifier{webhook: options["webhook"], username: options["username"]}, nil
+	})
+}
+
+// matrixTxn numbers the messages sent to Matrix, which deduplicates them
+// by transaction ID.
+var matrixTxn atomic.Int64
+
+// matrixNotifier posts notices to a Matrix room with the access token of
+// an account in it.
+type matrixNotifier struct {
+	homeserver string
+	token      string
+	room       string // room ID, e.g. !abc:example.org
+}
+
+func (n *matrixNotifier) Notify(ctx context.Context, event Event) error {
+	plain := []string{event.Title}
+	formatted := []string{"<b>" + html.EscapeString(event.Title) + "</b>"}
+	if event.Message != "" && event.Message != event.Title {
+		plain = append(plain, event.Message)
+		formatted = append(formatted, strings.ReplaceAll(html.EscapeString(event.Message), "\n", "<br>"))
+	}
+	if event.URL != "" {
+		plain = append(plain, event.URL)
+		formatted = append(formatted, `<a href="`+html.EscapeString(event.URL)+`">Open run</a>`)
+	}
+	body, err := json.Marshal(map[string]string{
+		"msgtype":        "m.notice",
+		"body":           strings.Join(plain, "\n"),
+		"format":         "org.matrix.custom.html",
+		"formatted_body": strings.Join(formatted, "<br>"),
+	})
+	if err != nil {
+		return err
+	}
+	txn := fmt.Sprintf("fomo.%d.%d", time.Now().UnixNano(), matrixTxn.Add(1))
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s", n.homeserver, url.PathEscape(n.room), txn)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+n.token)
+	return send(req, "Matrix")
+}
+
+// discordNotifier posts embeds to a Discord channel through a webhook.
+type discordNotifier struct {
+	webhook  string
+	username string // overrides the webhook's name
+}
+
+// Embed colors by result.
+const (
+	discordRed    = 0xd13438
+	discordGreen  = 0x107c10
+	discordOrange = 0xff8c00
+)
+
+func (n *discordNotifier) Notify(ctx context.Context, event Event) error {
+	embed := map[string]any{
+		// Discord limits titles to 256 characters and descriptions to 4096
+		"title":       truncate(event.Title, 256),
+		"description": truncate(cmp.Or(event.Message, event.Title), 4096),
+	}
+	if event.URL != "" {
+		embed["url"] = event.URL
+	}
+	if !event.Time.IsZero() {
+		embed["timestamp"] = event.Time.UTC().Format(time.RFC3339)
+	}
+	switch {
+	case failed(event):
+		embed["color"] = discordRed
+	case event.Result == "succeeded":
+		embed["color"] = discordGreen
+	case event.Result != "":
+		embed["color"] = discordOrange
+	}
+	message := map[string]any{
+		"embeds":           []any{embed},
+		"allowed_mentions": map[string]any{"parse": []string{}},
+	}
+	if n.username != "" {
+		message["username"] = n.username
+	}
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return send(req, "Discord")
+}
+
+// truncate shortens s to at most n runes.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,18 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// consoleNotifier prints events to stdout; handy for trying out rules.
+type consoleNotifier struct{}
+
+func (consoleNotifier) Notify(ctx context.Context, event Event) error {
+	fmt.Fprintf(os.Stdout, "[%s] %s: %s\n", event.Time.Format("15:04:05"), event.Title, event.Message)
+	if event.URL != "" {
+		fmt.Fprintf(os.Stdout, "  %s\n", event.URL)
+	}
+	return nil
+}
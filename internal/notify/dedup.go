@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Alert kinds, besides the plain alert for a run.
+const (
+	AlertReminder = "reminder" // the pipeline is still failing
+	AlertRecovery = "recovery" // the pipeline succeeded again after failing
+)
+
+// Deduper collapses the failures of a pipeline on a branch into one alert,
+// followed by reminders while it keeps failing and a recovery alert when it
+// succeeds again. A pipeline that fails again soon after recovering is
+// flapping: it is not alerted again until it keeps failing past a reminder
+// or stays green for a while.
+type Deduper struct {
+	// Remind is how often to remind of a pipeline that keeps failing.
+	Remind time.Duration
+	// Window is how long after a recovery a new failure counts as flapping.
+	Window time.Duration
+
+	mu      sync.Mutex
+	streaks map[string]*streak // by pipeline and branch
+}
+
+// streak is a run of failures of a pipeline on a branch.
+type streak struct {
+	since     time.Time // time of the first failure
+	failures  int
+	alerted   time.Time // time of the last alert, reminder or recovery
+	recovered time.Time // zero while failing
+	silent    bool      // nothing was alerted since the last recovery
+}
+
+// Filter decides whether to alert for the completed run an event describes,
+// and returns the event to send, which for reminders and recoveries is
+// reworded. Results other than failed and succeeded pass through untouched.
+func (d *Deduper) Filter(event Event) (Event, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.streaks == nil {
+		d.streaks = map[string]*streak{}
+	}
+	key := event.Pipeline + "\x00" + event.Branch
+	s := d.streaks[key]
+	now := event.Time
+
+	switch event.Result {
+	case "failed":
+		switch {
+		case s == nil || !s.recovered.IsZero() && now.Sub(s.recovered) >= d.Window:
+			d.streaks[key] = &streak{since: now, failures: 1, alerted: now}
+			return event, true
+		case !s.recovered.IsZero():
+			// Flapping: carry on with the streak, quietly
+			s.recovered, s.silent = time.Time{}, true
+			s.failures++
+			return event, false
+		}
+		s.failures++
+		if now.Sub(s.alerted) < d.Remind {
+			return event, false
+		}
+		s.alerted, s.silent = now, false
+		event.Alert = AlertReminder
+		event.Title = fmt.Sprintf("%s still failing (%s)", event.Pipeline, roughly(now.Sub(s.since)))
+		event.Message = fmt.Sprintf("%d failed runs on %s since %s; the latest is %s", s.failures, event.Branch, s.since.Format("Jan 2 15:04"), event.RunName)
+		return event, true
+
+	case "succeeded":
+		switch {
+		case s == nil:
+			return event, true
+		case !s.recovered.IsZero():
+			if now.Sub(s.recovered) >= d.Window {
+				delete(d.streaks, key)
+			}
+			return event, true
+		}
+		s.recovered = now
+		if s.silent {
+			// The last alert already was a recovery
+			return event, false
+		}
+		s.alerted = now
+		runs := "runs"
+		if s.failures == 1 {
+			runs = "run"
+		}
+		event.Alert = AlertRecovery
+		event.Title = fmt.Sprintf("%s recovered", event.Pipeline)
+		event.Message = fmt.Sprintf("Run %s on %s succeeded after %s of failures (%d failed %s)", event.RunName, event.Branch, roughly(now.Sub(s.since)), s.failures, runs)
+		return event, true
+	}
+	return event, true
+}
+
+// roughly formats a duration to the minute, such as 3h or 2h30m.
+func roughly(d time.Duration) string {
+	s := strings.TrimSuffix(max(d, time.Minute).Round(time.Minute).String(), "0s")
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// PluginPrefix is the executable name prefix of external notifiers.
+const PluginPrefix = "fomo-notify-"
+
+func init() {
+	Register("exec", func(options map[string]string) (Notifier, error) {
+		command := options["command"]
+		if command == "" {
+			return nil, fmt.Errorf("exec notifier requires a command option")
+		}
+		return &execNotifier{command: command, options: options}, nil
+	})
+	Register("console", func(options map[string]string) (Notifier, error) {
+		return consoleNotifier{}, nil
+	})
+}
+
+func lookPlugin(kind string) (string, error) {
+	return exec.LookPath(PluginPrefix + kind)
+}
+
+// execNotifier runs an external command with the event and options as JSON
+// on stdin.
+type execNotifier struct {
+	command string
+	options map[string]string
+}
+
+func (n *execNotifier) Notify(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(struct {
+		Event   Event             `json:"event"`
+		Options map[string]string `json:"options,omitempty"`
+	}{event, n.options})
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, n.command)
+	cmd.Stdin = bytes.NewReader(payload)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("notifier %s failed: %v: %s", n.command, err, msg)
+		}
+		return fmt.Errorf("notifier %s failed: %v", n.command, err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+// Package notify delivers pipeline events to notification backends.
+//
+// Backends implement Notifier and register a Factory under a type name with
+// Register, usually from an init function. Types that are not built in are
+// looked up as external "fomo-notify-<type>" executables on PATH, which
+// receive the event as JSON on stdin.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Event describes something worth telling a user about.
+type Event struct {
+	Title    string    `json:"title"`
+	Message  string    `json:"message"`
+	Pipeline string    `json:"pipeline,omitempty"`
+	RunID    int       `json:"runId,omitempty"`
+	RunName  string    `json:"runName,omitempty"`
+	Branch   string    `json:"branch,omitempty"`
+	Result   string    `json:"result,omitempty"`
+	URL      string    `json:"url,omitempty"`
+	Time     time.Time `json:"time"`
+	// Alert is AlertReminder or AlertRecovery for the alerts of a Deduper,
+	// and empty otherwise.
+	Alert string `json:"alert,omitempty"`
+}
+
+// Notifier sends events to a single destination.
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// Factory builds a Notifier from its configured options.
+type Factory func(options map[string]string) (Notifier, error)
+
+var (
+	mu        sync.RWMutex
+	factories = map[string]Factory{}
+)
+
+// Register makes a notifier type available by name. It panics if the name
+// is registered twice.
+func Register(kind string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, dup := factories[kind]; dup {
+		panic(fmt.Sprintf("notify: type %q registered twice", kind))
+	}
+	factories[kind] = factory
+}
+
+// Types returns the names of the registered notifier types.
+func Types() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	kinds := make([]string, 0, len(factories))
+	for kind := range factories {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// RunsCommand reports whether notifiers of the given type run a local
+// command: exec notifiers, and fomo-notify-<type> executables for types
+// that are not built in.
+func RunsCommand(kind string) bool {
+	mu.RLock()
+	_, builtin := factories[kind]
+	mu.RUnlock()
+	return kind == "exec" || !builtin
+}
+
+// New builds a notifier of the given type, falling back to an external
+// fomo-notify-<type> executable.
+func New(kind string, options map[string]string) (Notifier, error) {
+	mu.RLock()
+	factory, ok := factories[kind]
+	mu.RUnlock()
+	if ok {
+		return factory(options)
+	}
+
+	if path, err := lookPlugin(kind); err == nil {
+		return &execNotifier{command: path, options: options}, nil
+	}
+	return nil, fmt.Errorf("unknown notifier type %q", kind)
+}
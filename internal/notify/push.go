@@ -0,0 +1,205 @@
+package notify
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Push backends send phone notifications through ntfy, Pushover or Gotify.
+// Failed runs are sent with a higher priority than other events, so they
+// can break through do-not-disturb where the app allows it; the priority
+// option overrides this.
+
+func init() {
+	Register("ntfy", func(options map[string]string) (Notifier, error) {
+		if options["topic"] == "" {
+			return nil, fmt.Errorf("ntfy notifier requires a topic option")
+		}
+		return &ntfyNotifier{
+			server:   strings.TrimRight(cmp.Or(options["server"], "https://ntfy.sh"), "/"),
+			topic:    options["topic"],
+			token:    options["token"],
+			priority: options["priority"],
+		}, nil
+	})
+	Register("pushover", func(options map[string]string) (Notifier, error) {
+		if options["token"] == "" || options["user"] == "" {
+			return nil, fmt.Errorf("pushover notifier requires token and user options")
+		}
+		return &pushoverNotifier{
+			token:    options["token"],
+			user:     options["user"],
+			device:   options["device"],
+			priority: options["priority"],
+		}, nil
+	})
+	Register("gotify", func(options map[string]string) (Notifier, error) {
+		if options["server"] == "" || options["token"] == "" {
+			return nil, fmt.Errorf("gotify notifier requires server and token options")
+		}
+		n := &gotifyNotifier{server: strings.TrimRight(options["server"], "/"), token: options["token"]}
+		if p := options["priority"]; p != "" {
+			priority, err := strconv.Atoi(p)
+			if err != nil {
+				return nil, fmt.Errorf("gotify notifier: invalid priority %q", p)
+			}
+			n.priority = &priority
+		}
+		return n, nil
+	})
+}
+
+func failed(event Event) bool {
+	return event.Result == "failed"
+}
+
+// send performs a request to a notification service and turns an error status into
+// an error, with the message the service gives when there is one.
+func send(req *http.Request, service string) error {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var answer struct {
+		Error   string   `json:"error"`   // ntfy, Gotify, Matrix
+		Errors  []string `json:"errors"`  // Pushover
+		Message string   `json:"message"` // Discord
+	}
+	json.Unmarshal(data, &answer)
+	message := cmp.Or(answer.Error, strings.Join(answer.Errors, "; "), answer.Message, strings.TrimSpace(string(data)))
+	return fmt.Errorf("%s answered %s: %s", service, resp.Status, message)
+}
+
+// ntfyNotifier publishes to an ntfy topic, on ntfy.sh or a self-hosted
+// server.
+type ntfyNotifier struct {
+	server   string
+	topic    string
+	token    string // access token, for protected topics
+	priority string // 1 to 5, or min, low, default, high, max
+}
+
+func (n *ntfyNotifier) Notify(ctx context.Context, event Event) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.server+"/"+url.PathEscape(n.topic), strings.NewReader(event.Message))
+	if err != nil {
+		return err
+	}
+	// ntfy takes non-ASCII header values RFC 2047 encoded
+	req.Header.Set("Title", mime.QEncoding.Encode("utf-8", event.Title))
+	if event.URL != "" {
+		req.Header.Set("Click", event.URL)
+	}
+	priority, tag := n.priority, ""
+	switch {
+	case failed(event):
+		priority, tag = cmp.Or(priority, "high"), "x"
+	case event.Result == "succeeded":
+		tag = "white_check_mark"
+	case event.Result != "":
+		tag = "warning"
+	}
+	if priority != "" {
+		req.Header.Set("Priority", priority)
+	}
+	if tag != "" {
+		req.Header.Set("Tags", tag)
+	}
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+	return send(req, "ntfy")
+}
+
+// pushoverNotifier sends messages through Pushover, with the token of a
+// Pushover application and the key of the user or group to notify.
+type pushoverNotifier struct {
+	token    string
+	user     string
+	device   string // only notify these devices, comma separated
+	priority string // -2 to 2
+}
+
+func (n *pushoverNotifier) Notify(ctx context.Context, event Event) error {
+	form := url.Values{
+		"token":   {n.token},
+		"user":    {n.user},
+		"title":   {event.Title},
+		"message": {cmp.Or(event.Message, event.Title)},
+	}
+	if !event.Time.IsZero() {
+		form.Set("timestamp", strconv.FormatInt(event.Time.Unix(), 10))
+	}
+	if event.URL != "" {
+		form.Set("url", event.URL)
+		form.Set("url_title", "Open run")
+	}
+	if n.device != "" {
+		form.Set("device", n.device)
+	}
+	priority := n.priority
+	if priority == "" && failed(event) {
+		priority = "1"
+	}
+	if priority != "" {
+		form.Set("priority", priority)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.pushover.net/1/messages.json", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return send(req, "Pushover")
+}
+
+// gotifyNotifier sends messages to a Gotify server with an application
+// token.
+type gotifyNotifier struct {
+	server   string
+	token    string
+	priority *int // nil to choose by result
+}
+
+func (n *gotifyNotifier) Notify(ctx context.Context, event Event) error {
+	priority := 5
+	switch {
+	case n.priority != nil:
+		priority = *n.priority
+	case failed(event):
+		priority = 8
+	}
+	message := map[string]any{
+		"title":    event.Title,
+		"message":  cmp.Or(event.Message, event.Title),
+		"priority": priority,
+	}
+	if event.URL != "" {
+		message["extras"] = map[string]any{
+			"client::notification": map[string]any{"click": map[string]string{"url": event.URL}},
+		}
+	}
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.server+"/message", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", n.token)
+	return send(req, "Gotify")
+}
@@ -0,0 +1,242 @@
+// Package otlp exports traces to an OpenTelemetry collector, or any backend
+// that accepts the OpenTelemetry protocol, over its HTTP transport with
+// JSON encoding.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultEndpoint is where collectors listen for OTLP over HTTP.
+const DefaultEndpoint = "localhost:4318"
+
+// grpcPort is the conventional OTLP/gRPC port, which this package cannot
+// talk to.
+const grpcPort = "4317"
+
+// Span is one operation of a trace.
+type Span struct {
+	TraceID  [16]byte
+	SpanID   [8]byte
+	ParentID [8]byte // zero for the root span
+	Name     string
+	Start    time.Time
+	End      time.Time
+	// Attributes hold string, int or bool values
+	Attributes map[string]any
+	Failed     bool
+	Message    string // status message of a failed span
+}
+
+// TraceID derives a trace ID from key, so exporting the same thing twice
+// yields the same trace.
+func TraceID(key string) [16]byte {
+	sum := sha256.Sum256([]byte(key))
+	return [16]byte(sum[:16])
+}
+
+// SpanID derives a span ID from key.
+func SpanID(key string) [8]byte {
+	sum := sha256.Sum256([]byte(key))
+	return [8]byte(sum[:8])
+}
+
+// Endpoint turns host:port or a base URL into the URL traces are posted
+// to, e.g. "tempo:4318" into "http://tempo:4318/v1/traces".
+func Endpoint(s string) (string, error) {
+	if !strings.Contains(s, "://") {
+		s = "http://" + s
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid OTLP endpoint %q", s)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+	}
+	return u.String(), nil
+}
+
+// ParseHeaders parses headers in the OTEL_EXPORTER_OTLP_HEADERS format:
+// comma-separated key=value pairs with URL-encoded values.
+func ParseHeaders(s string) (map[string]string, error) {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid OTLP header %q: want key=value", pair)
+		}
+		value, err := url.QueryUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid OTLP header %q: %v", pair, err)
+		}
+		headers[strings.TrimSpace(key)] = value
+	}
+	return headers, nil
+}
+
+// Exporter posts traces to an OTLP/HTTP endpoint.
+type Exporter struct {
+	URL        string // see Endpoint
+	Headers    map[string]string
+	HTTPClient *http.Client // defaults to a client with a 30s timeout
+}
+
+// Export sends the spans of one resource, such as one pipeline.
+func (e *Exporter) Export(ctx context.Context, resource map[string]any, spans []Span) error {
+	body, err := Marshal(resource, spans)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.Headers {
+		req.Header.Set(key, value)
+	}
+	client := e.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if req.URL.Port() == grpcPort {
+			return fmt.Errorf("%w (port %s is usually OTLP/gRPC; fomo sends OTLP/HTTP, usually on port 4318)", err, grpcPort)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s returned %s: %s", e.URL, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// The OTLP JSON encoding of an ExportTraceServiceRequest. IDs are hex and
+// 64-bit integers are strings.
+type (
+	request struct {
+		ResourceSpans []resourceSpans `json:"resourceSpans"`
+	}
+	resourceSpans struct {
+		Resource   resourceJSON `json:"resource"`
+		ScopeSpans []scopeSpans `json:"scopeSpans"`
+	}
+	resourceJSON struct {
+		Attributes []keyValue `json:"attributes"`
+	}
+	scopeSpans struct {
+		Scope scope      `json:"scope"`
+		Spans []spanJSON `json:"spans"`
+	}
+	scope struct {
+		Name string `json:"name"`
+	}
+	spanJSON struct {
+		TraceID      string     `json:"traceId"`
+		SpanID       string     `json:"spanId"`
+		ParentSpanID string     `json:"parentSpanId,omitempty"`
+		Name         string     `json:"name"`
+		Kind         int        `json:"kind"`
+		Start        string     `json:"startTimeUnixNano"`
+		End          string     `json:"endTimeUnixNano"`
+		Attributes   []keyValue `json:"attributes,omitempty"`
+		Status       status     `json:"status"`
+	}
+	status struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	keyValue struct {
+		Key   string   `json:"key"`
+		Value anyValue `json:"value"`
+	}
+	anyValue struct {
+		String *string `json:"stringValue,omitempty"`
+		Int    *string `json:"intValue,omitempty"`
+		Bool   *bool   `json:"boolValue,omitempty"`
+	}
+)
+
+// Span kinds and status codes.
+const (
+	kindInternal = 1
+	statusOK     = 1
+	statusError  = 2
+)
+
+// Marshal encodes spans as an OTLP/JSON export request.
+func Marshal(resource map[string]any, spans []Span) ([]byte, error) {
+	scoped := scopeSpans{Scope: scope{Name: "fomo"}, Spans: []spanJSON{}}
+	for _, s := range spans {
+		span := spanJSON{
+			TraceID:    hex.EncodeToString(s.TraceID[:]),
+			SpanID:     hex.EncodeToString(s.SpanID[:]),
+			Name:       s.Name,
+			Kind:       kindInternal,
+			Start:      strconv.FormatInt(s.Start.UnixNano(), 10),
+			End:        strconv.FormatInt(s.End.UnixNano(), 10),
+			Attributes: attributes(s.Attributes),
+			Status:     status{Code: statusOK},
+		}
+		if s.ParentID != [8]byte{} {
+			span.ParentSpanID = hex.EncodeToString(s.ParentID[:])
+		}
+		if s.Failed {
+			span.Status = status{Code: statusError, Message: s.Message}
+		}
+		scoped.Spans = append(scoped.Spans, span)
+	}
+	return json.Marshal(request{ResourceSpans: []resourceSpans{{
+		Resource:   resourceJSON{Attributes: attributes(resource)},
+		ScopeSpans: []scopeSpans{scoped},
+	}}})
+}
+
+// attributes encodes attributes in key order, dropping empty strings.
+func attributes(m map[string]any) []keyValue {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	out := []keyValue{}
+	for _, key := range keys {
+		var value anyValue
+		switch v := m[key].(type) {
+		case string:
+			if v == "" {
+				continue
+			}
+			value.String = &v
+		case int:
+			n := strconv.Itoa(v)
+			value.Int = &n
+		case bool:
+			value.Bool = &v
+		default:
+			s := fmt.Sprint(v)
+			value.String = &s
+		}
+		out = append(out, keyValue{Key: key, Value: value})
+	}
+	return out
+}
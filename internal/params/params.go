@@ -0,0 +1,189 @@
+// Package params discovers the runtime parameters a YAML pipeline declares
+// and checks values against them before a run is queued.
+package params
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"fomo/internal/yaml"
+)
+
+// Parameter types that can be set when queuing a run. Step, job and stage
+// parameters can only be given by templates.
+const (
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+	TypeObject  = "object"
+)
+
+// Parameter is a runtime parameter declared in a pipeline's YAML.
+type Parameter struct {
+	Name        string   `yaml:"name"`
+	DisplayName string   `yaml:"displayName"`
+	Type        string   `yaml:"type"`
+	Default     any      `yaml:"default"`
+	Values      []string `yaml:"values"`
+}
+
+// Label returns the display name, or the name if there is none.
+func (p Parameter) Label() string {
+	if p.DisplayName != "" {
+		return p.DisplayName
+	}
+	return p.Name
+}
+
+// Required reports whether the parameter has no default.
+func (p Parameter) Required() bool {
+	return p.Default == nil
+}
+
+// DefaultString formats the default value as it would be given on the
+// command line.
+func (p Parameter) DefaultString() string {
+	if p.Default == nil {
+		return ""
+	}
+	return fmt.Sprint(p.Default)
+}
+
+// Parse returns the parameters declared in pipeline YAML. Only the
+// top-level parameters block is read, so template expressions elsewhere
+// in the file do not get in the way.
+func Parse(content string) ([]Parameter, error) {
+	var block []string
+	inBlock := false
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		// Items of the block may sit at the indentation of its key
+		top := line != "" && line[0] != ' ' && line[0] != '\t' && line[0] != '#' && line[0] != '-'
+		switch {
+		case top && strings.HasPrefix(line, "parameters:"):
+			inBlock = true
+		case top:
+			inBlock = false
+		}
+		if inBlock {
+			block = append(block, line)
+		}
+	}
+	if len(block) == 0 {
+		return nil, nil
+	}
+
+	var doc struct {
+		Parameters []Parameter `yaml:"parameters"`
+	}
+	if err := yaml.Unmarshal([]byte(strings.Join(block, "\n")), &doc); err != nil {
+		return nil, fmt.Errorf("failed to read the parameters of the pipeline: %w", err)
+	}
+	for i := range doc.Parameters {
+		if doc.Parameters[i].Type == "" {
+			doc.Parameters[i].Type = TypeString
+		}
+	}
+	return doc.Parameters, nil
+}
+
+// Find returns the parameter with the given name.
+func Find(params []Parameter, name string) (Parameter, bool) {
+	for _, p := range params {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Parameter{}, false
+}
+
+// Check validates a value for a parameter.
+func (p Parameter) Check(value string) error {
+	if len(p.Values) > 0 && !slices.Contains(p.Values, value) {
+		return fmt.Errorf("parameter %s must be one of %s, not %q", p.Name, strings.Join(p.Values, ", "), value)
+	}
+	switch p.Type {
+	case TypeString, TypeObject:
+	case TypeNumber:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("parameter %s must be a number, not %q", p.Name, value)
+		}
+	case TypeBoolean:
+		if value != "true" && value != "false" {
+			return fmt.Errorf("parameter %s must be true or false, not %q", p.Name, value)
+		}
+	default:
+		return fmt.Errorf("parameter %s has type %s, which can only be set by templates", p.Name, p.Type)
+	}
+	return nil
+}
+
+// Validate checks the given values against the declared parameters. It
+// reports unknown names, with a suggestion when one is close, and values
+// of the wrong type. Missing required parameters are returned separately
+// so callers can ask for them.
+func Validate(params []Parameter, given map[string]string) (missing []Parameter, err error) {
+	names := make([]string, 0, len(given))
+	for name := range given {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		p, ok := Find(params, name)
+		if !ok {
+			return nil, unknownError(params, name)
+		}
+		if err := p.Check(given[name]); err != nil {
+			return nil, err
+		}
+	}
+	for _, p := range params {
+		if _, ok := given[p.Name]; !ok && p.Required() {
+			missing = append(missing, p)
+		}
+	}
+	return missing, nil
+}
+
+func unknownError(params []Parameter, name string) error {
+	if len(params) == 0 {
+		return fmt.Errorf("the pipeline declares no parameters, but %s was given", name)
+	}
+	best, bestDistance := "", 3
+	var known []string
+	for _, p := range params {
+		known = append(known, p.Name)
+		if d := distance(strings.ToLower(name), strings.ToLower(p.Name)); d < bestDistance {
+			best, bestDistance = p.Name, d
+		}
+	}
+	if best != "" {
+		return fmt.Errorf("unknown parameter %s; did you mean %s?", name, best)
+	}
+	return fmt.Errorf("unknown parameter %s (declared: %s)", name, strings.Join(known, ", "))
+}
+
+// distance is the Levenshtein distance between a and b.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
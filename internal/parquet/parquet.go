@@ -0,0 +1,245 @@
+// Package parquet writes flat tables as Apache Parquet files, for loading
+// exported data into notebooks and BI tools. It writes the minimal valid
+// subset of the format: required columns, plain encoding, no compression
+// and a single row group, which suits the table sizes fomo exports.
+package parquet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Type is the type of a column's values.
+type Type int
+
+// Column types, and the Go values Write accepts for them.
+const (
+	String    Type = iota // string
+	Int64                 // int or int64
+	Double                // float64
+	Timestamp             // time.Time, stored as UTC milliseconds
+)
+
+// Column describes one column of the table.
+type Column struct {
+	Name string
+	Type Type
+}
+
+// Parquet physical types, converted types and enums used in the footer.
+const (
+	physicalInt64     = 2
+	physicalDouble    = 5
+	physicalByteArray = 6
+
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+
+	repetitionRequired = 0
+	encodingPlain      = 0
+	encodingRLE        = 3
+	codecUncompressed  = 0
+	pageData           = 0
+)
+
+const magic = "PAR1"
+
+// Writer buffers rows and writes the file on Close.
+type Writer struct {
+	w       io.Writer
+	columns []Column
+	data    [][]byte // plain-encoded values of each column
+	rows    int
+}
+
+// NewWriter returns a writer of a table with the given columns.
+func NewWriter(w io.Writer, columns []Column) *Writer {
+	return &Writer{w: w, columns: columns, data: make([][]byte, len(columns))}
+}
+
+// Write adds a row, with one value per column.
+func (w *Writer) Write(row ...any) error {
+	if len(row) != len(w.columns) {
+		return fmt.Errorf("parquet: row has %d values, want %d", len(row), len(w.columns))
+	}
+	for i, v := range row {
+		col := w.columns[i]
+		b := w.data[i]
+		switch col.Type {
+		case String:
+			s, ok := v.(string)
+			if !ok {
+				return typeError(col, v)
+			}
+			b = binary.LittleEndian.AppendUint32(b, uint32(len(s)))
+			b = append(b, s...)
+		case Int64:
+			switch n := v.(type) {
+			case int:
+				b = binary.LittleEndian.AppendUint64(b, uint64(n))
+			case int64:
+				b = binary.LittleEndian.AppendUint64(b, uint64(n))
+			default:
+				return typeError(col, v)
+			}
+		case Double:
+			f, ok := v.(float64)
+			if !ok {
+				return typeError(col, v)
+			}
+			b = binary.LittleEndian.AppendUint64(b, math.Float64bits(f))
+		case Timestamp:
+			t, ok := v.(time.Time)
+			if !ok {
+				return typeError(col, v)
+			}
+			b = binary.LittleEndian.AppendUint64(b, uint64(t.UnixMilli()))
+		default:
+			return fmt.Errorf("parquet: column %s has unknown type %d", col.Name, col.Type)
+		}
+		w.data[i] = b
+	}
+	w.rows++
+	return nil
+}
+
+func typeError(col Column, v any) error {
+	return fmt.Errorf("parquet: column %s cannot hold %T", col.Name, v)
+}
+
+// columnChunk records where a column was written, for the footer.
+type columnChunk struct {
+	offset int64
+	size   int64 // page header and data
+}
+
+// Close writes the file: the magic number, one data page per column, the
+// footer and its length, and the magic number again. It does not close
+// the underlying writer.
+func (w *Writer) Close() error {
+	offset := int64(len(magic))
+	if _, err := io.WriteString(w.w, magic); err != nil {
+		return err
+	}
+
+	chunks := make([]columnChunk, len(w.columns))
+	for i, data := range w.data {
+		var header encoder
+		header.i32(1, pageData)
+		header.i32(2, int32(len(data)))
+		header.i32(3, int32(len(data)))
+		header.structField(5) // DataPageHeader
+		header.i32(1, int32(w.rows))
+		header.i32(2, encodingPlain)
+		header.i32(3, encodingRLE)
+		header.i32(4, encodingRLE)
+		header.end()
+		header.end()
+
+		if _, err := w.w.Write(header.b); err != nil {
+			return err
+		}
+		if _, err := w.w.Write(data); err != nil {
+			return err
+		}
+		size := int64(len(header.b) + len(data))
+		chunks[i] = columnChunk{offset: offset, size: size}
+		offset += size
+	}
+
+	footer := w.footer(chunks)
+	if _, err := w.w.Write(footer); err != nil {
+		return err
+	}
+	tail := binary.LittleEndian.AppendUint32(nil, uint32(len(footer)))
+	tail = append(tail, magic...)
+	_, err := w.w.Write(tail)
+	return err
+}
+
+// footer encodes the FileMetaData structure.
+func (w *Writer) footer(chunks []columnChunk) []byte {
+	var e encoder
+	e.i32(1, 1) // version
+
+	e.list(2, typeStruct, len(w.columns)+1)
+	e.begin() // the root of the schema
+	e.str(4, "schema")
+	e.i32(5, int32(len(w.columns)))
+	e.end()
+	for _, col := range w.columns {
+		e.begin()
+		e.i32(1, physicalType(col.Type))
+		e.i32(3, repetitionRequired)
+		e.str(4, col.Name)
+		switch col.Type {
+		case String:
+			e.i32(6, convertedUTF8)
+			e.structField(10) // LogicalType
+			e.structField(1)  // STRING
+			e.end()
+			e.end()
+		case Timestamp:
+			e.i32(6, convertedTimestampMillis)
+			e.structField(10) // LogicalType
+			e.structField(8)  // TIMESTAMP
+			e.boolean(1, true)
+			e.structField(2) // unit
+			e.structField(1) // MILLIS
+			e.end()
+			e.end()
+			e.end()
+			e.end()
+		}
+		e.end()
+	}
+
+	e.i64(3, int64(w.rows))
+
+	var total int64
+	for _, c := range chunks {
+		total += c.size
+	}
+	e.list(4, typeStruct, 1)
+	e.begin() // RowGroup
+	e.list(1, typeStruct, len(w.columns))
+	for i, col := range w.columns {
+		c := chunks[i]
+		e.begin() // ColumnChunk
+		e.i64(2, c.offset)
+		e.structField(3) // ColumnMetaData
+		e.i32(1, physicalType(col.Type))
+		e.list(2, typeI32, 2)
+		e.rawI32(encodingPlain)
+		e.rawI32(encodingRLE)
+		e.list(3, typeBinary, 1)
+		e.rawString(col.Name)
+		e.i32(4, codecUncompressed)
+		e.i64(5, int64(w.rows))
+		e.i64(6, c.size)
+		e.i64(7, c.size)
+		e.i64(9, c.offset)
+		e.end()
+		e.end()
+	}
+	e.i64(2, total)
+	e.i64(3, int64(w.rows))
+	e.end()
+
+	e.str(6, "fomo")
+	e.end()
+	return e.b
+}
+
+func physicalType(t Type) int32 {
+	switch t {
+	case String:
+		return physicalByteArray
+	case Double:
+		return physicalDouble
+	}
+	return physicalInt64
+}
@@ -0,0 +1,110 @@
+package parquet
+
+// Thrift compact protocol type codes.
+const (
+	typeTrue   = 1
+	typeFalse  = 2
+	typeI32    = 5
+	typeI64    = 6
+	typeBinary = 8
+	typeList   = 9
+	typeStruct = 12
+)
+
+// encoder writes the Thrift compact protocol, which Parquet uses for page
+// headers and the file footer. Only what those structures need is
+// supported.
+type encoder struct {
+	b     []byte
+	last  int16   // last field ID written in the current struct
+	outer []int16 // last field IDs of the enclosing structs
+}
+
+func (e *encoder) varint(v uint64) {
+	for v >= 0x80 {
+		e.b = append(e.b, byte(v)|0x80)
+		v >>= 7
+	}
+	e.b = append(e.b, byte(v))
+}
+
+func (e *encoder) zigzag(v int64) {
+	e.varint(uint64(v<<1 ^ v>>63))
+}
+
+// field writes a field header, as a delta from the previous field ID when
+// it fits.
+func (e *encoder) field(id int16, typ byte) {
+	if delta := id - e.last; delta > 0 && delta <= 15 {
+		e.b = append(e.b, byte(delta)<<4|typ)
+	} else {
+		e.b = append(e.b, typ)
+		e.zigzag(int64(id))
+	}
+	e.last = id
+}
+
+func (e *encoder) i32(id int16, v int32) {
+	e.field(id, typeI32)
+	e.zigzag(int64(v))
+}
+
+func (e *encoder) i64(id int16, v int64) {
+	e.field(id, typeI64)
+	e.zigzag(v)
+}
+
+func (e *encoder) boolean(id int16, v bool) {
+	if v {
+		e.field(id, typeTrue)
+	} else {
+		e.field(id, typeFalse)
+	}
+}
+
+func (e *encoder) str(id int16, s string) {
+	e.field(id, typeBinary)
+	e.rawString(s)
+}
+
+func (e *encoder) rawString(s string) {
+	e.varint(uint64(len(s)))
+	e.b = append(e.b, s...)
+}
+
+// list writes a list header; the caller then writes n elements with
+// rawString, rawI32 or begin/end for structs.
+func (e *encoder) list(id int16, elem byte, n int) {
+	e.field(id, typeList)
+	if n < 15 {
+		e.b = append(e.b, byte(n)<<4|elem)
+	} else {
+		e.b = append(e.b, 0xf0|elem)
+		e.varint(uint64(n))
+	}
+}
+
+func (e *encoder) rawI32(v int32) {
+	e.zigzag(int64(v))
+}
+
+// structField starts a struct-valued field.
+func (e *encoder) structField(id int16) {
+	e.field(id, typeStruct)
+	e.begin()
+}
+
+// begin starts a nested struct, such as a list element.
+func (e *encoder) begin() {
+	e.outer = append(e.outer, e.last)
+	e.last = 0
+}
+
+// end closes the current struct.
+func (e *encoder) end() {
+	e.b = append(e.b, 0)
+	if n := len(e.outer); n > 0 {
+		e.last = e.outer[n-1]
+		e.outer = e.outer[:n-1]
+	}
+}
@@ -0,0 +1,179 @@
+// Package prefetch keeps API results warm for interactive and watch modes.
+// Reads never wait on the network once a value is known: stale values are
+// served while a refresh runs in the background, identical requests in
+// flight are coalesced, and a background loop refreshes visible keys more
+// often than hidden ones.
+package prefetch
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Fetch loads the current value of a key.
+type Fetch func(ctx context.Context) (any, error)
+
+// Cache holds the latest value of every key it has fetched. The zero value
+// is ready to use.
+type Cache struct {
+	// TTL is how long a value counts as fresh; older values are served
+	// but trigger a background refresh. Defaults to 30 seconds.
+	TTL time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+type entry struct {
+	fetch   Fetch
+	value   any
+	err     error
+	fetched time.Time
+	visible bool
+	call    *call // in flight, if any
+}
+
+// call is a fetch in progress that any number of readers can wait on.
+type call struct {
+	done  chan struct{}
+	value any
+	err   error
+}
+
+func (c *Cache) ttl() time.Duration {
+	if c.TTL <= 0 {
+		return 30 * time.Second
+	}
+	return c.TTL
+}
+
+// Get returns the value of key, fetching it if it has never been loaded.
+// A known value is returned immediately along with when it was fetched,
+// and refreshed in the background once it is older than the TTL.
+func (c *Cache) Get(ctx context.Context, key string, fetch Fetch) (any, time.Time, error) {
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = map[string]*entry{}
+	}
+	e := c.entries[key]
+	if e == nil {
+		e = &entry{}
+		c.entries[key] = e
+	}
+	e.fetch = fetch
+	if !e.fetched.IsZero() {
+		value, fetched, err := e.value, e.fetched, e.err
+		if time.Since(fetched) > c.ttl() {
+			c.start(key, e)
+		}
+		c.mu.Unlock()
+		return value, fetched, err
+	}
+	cl := c.start(key, e)
+	c.mu.Unlock()
+
+	select {
+	case <-cl.done:
+		return cl.value, time.Now(), cl.err
+	case <-ctx.Done():
+		return nil, time.Time{}, ctx.Err()
+	}
+}
+
+// Refresh fetches key now, coalescing with a fetch already in flight, and
+// waits for the result.
+func (c *Cache) Refresh(ctx context.Context, key string) (any, error) {
+	c.mu.Lock()
+	e := c.entries[key]
+	if e == nil || e.fetch == nil {
+		c.mu.Unlock()
+		return nil, nil
+	}
+	cl := c.start(key, e)
+	c.mu.Unlock()
+
+	select {
+	case <-cl.done:
+		return cl.value, cl.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// start launches a fetch of key unless one is already running. The caller
+// holds c.mu.
+func (c *Cache) start(key string, e *entry) *call {
+	if e.call != nil {
+		return e.call
+	}
+	cl := &call{done: make(chan struct{})}
+	e.call = cl
+	fetch := e.fetch
+	go func() {
+		// Background fetches outlive the reader that triggered them
+		cl.value, cl.err = fetch(context.Background())
+		c.mu.Lock()
+		e.call = nil
+		// Keep serving the last good value if a refresh fails
+		if cl.err == nil || e.fetched.IsZero() {
+			e.value, e.err = cl.value, cl.err
+		}
+		e.fetched = time.Now()
+		c.mu.Unlock()
+		close(cl.done)
+	}()
+	return cl
+}
+
+// SetVisible marks which keys are on screen. Visible keys are refreshed on
+// the fast schedule of Run; all others on the slow one.
+func (c *Cache) SetVisible(keys ...string) {
+	visible := map[string]bool{}
+	for _, key := range keys {
+		visible[key] = true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, e := range c.entries {
+		e.visible = visible[key]
+	}
+}
+
+// Run refreshes known keys in the background until ctx is canceled:
+// visible keys once they are older than hot, hidden keys once they are
+// older than cold.
+func (c *Cache) Run(ctx context.Context, hot, cold time.Duration) {
+	tick := min(hot, cold) / 4
+	if tick < 100*time.Millisecond {
+		tick = 100 * time.Millisecond
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		c.mu.Lock()
+		for key, e := range c.entries {
+			if e.fetch == nil || e.fetched.IsZero() {
+				continue
+			}
+			age := time.Since(e.fetched)
+			if (e.visible && age >= hot) || (!e.visible && age >= cold) {
+				c.start(key, e)
+			}
+		}
+		c.mu.Unlock()
+	}
+}
+
+// Get is a typed wrapper around Cache.Get.
+func Get[T any](ctx context.Context, c *Cache, key string, fetch func(ctx context.Context) (T, error)) (T, time.Time, error) {
+	value, fetched, err := c.Get(ctx, key, func(ctx context.Context) (any, error) { return fetch(ctx) })
+	typed, _ := value.(T)
+	return typed, fetched, err
+}
@@ -0,0 +1,122 @@
+// Package queue estimates when a job waiting in an agent pool will start.
+package queue
+
+import (
+	"sort"
+	"time"
+
+	"fomo/internal/client"
+)
+
+// defaultJobDuration is assumed for pipelines without finished jobs in the
+// pool's history.
+const defaultJobDuration = 5 * time.Minute
+
+// Estimate is the queue position of a run's next job and when it is likely
+// to start.
+type Estimate struct {
+	Position int           // 1 when next in line
+	Ahead    int           // queued jobs ahead of it
+	Running  int           // jobs running in the pool
+	Agents   int           // agents able to take jobs
+	Wait     time.Duration // expected time until an agent picks it up
+}
+
+// Estimated returns the queue estimate for the first waiting job of run,
+// or false if the run has no job waiting for an agent.
+//
+// Remaining durations come from the average duration of each pipeline's
+// finished jobs in the same pool. Running and queued jobs ahead are then
+// assigned, in queue order, to whichever agent frees up first.
+func Estimated(requests []client.JobRequest, runID, agents int, now time.Time) (Estimate, bool) {
+	var mine *client.JobRequest
+	for i, r := range requests {
+		if r.Owner.ID == runID && r.AssignTime == nil && r.FinishTime == nil {
+			if mine == nil || r.QueueTime.Before(mine.QueueTime) {
+				mine = &requests[i]
+			}
+		}
+	}
+	if mine == nil {
+		return Estimate{}, false
+	}
+
+	durations := averageDurations(requests)
+	duration := func(r client.JobRequest) time.Duration {
+		if d, ok := durations[r.Definition.ID]; ok {
+			return d
+		}
+		return defaultJobDuration
+	}
+
+	var running []time.Duration // time left of each running job
+	var ahead []client.JobRequest
+	for _, r := range requests {
+		switch {
+		case r.FinishTime != nil:
+		case r.AssignTime != nil:
+			start := *r.AssignTime
+			if r.ReceiveTime != nil {
+				start = *r.ReceiveTime
+			}
+			running = append(running, max(duration(r)-now.Sub(start), 0))
+		case r.RequestID != mine.RequestID && r.QueueTime.Before(mine.QueueTime):
+			ahead = append(ahead, r)
+		}
+	}
+	sort.Slice(ahead, func(i, j int) bool { return ahead[i].QueueTime.Before(ahead[j].QueueTime) })
+
+	// Without agent information, assume the pool runs as many jobs in
+	// parallel as it does now
+	if agents <= 0 {
+		agents = max(len(running), 1)
+	}
+	free := make([]time.Duration, agents)
+	sort.Slice(running, func(i, j int) bool { return running[i] < running[j] })
+	for i, left := range running {
+		if i < agents {
+			free[i] = left
+		}
+	}
+	for _, r := range ahead {
+		next := earliest(free)
+		free[next] += duration(r)
+	}
+
+	return Estimate{
+		Position: len(ahead) + 1,
+		Ahead:    len(ahead),
+		Running:  len(running),
+		Agents:   agents,
+		Wait:     free[earliest(free)],
+	}, true
+}
+
+func earliest(free []time.Duration) int {
+	best := 0
+	for i, t := range free {
+		if t < free[best] {
+			best = i
+		}
+	}
+	return best
+}
+
+// averageDurations returns the mean duration of finished jobs per
+// pipeline definition.
+func averageDurations(requests []client.JobRequest) map[int]time.Duration {
+	total := map[int]time.Duration{}
+	count := map[int]int{}
+	for _, r := range requests {
+		if r.FinishTime == nil || r.ReceiveTime == nil || r.Result == "canceled" {
+			continue
+		}
+		total[r.Definition.ID] += r.FinishTime.Sub(*r.ReceiveTime)
+		count[r.Definition.ID]++
+	}
+	out := map[int]time.Duration{}
+	for id, n := range count {
+		out[id] = total[id] / time.Duration(n)
+	}
+	return out
+}
@@ -0,0 +1,110 @@
+// Package redact masks credentials in text before it is shown to the user,
+// so that tokens never reach a terminal, a log file or a bug report.
+package redact
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Mask replaces every redacted value.
+const Mask = "***"
+
+// minSecretLength keeps short values, which would mask ordinary words, out
+// of the redactor.
+const minSecretLength = 6
+
+// patterns match credentials whose exact value fomo does not know.
+var patterns = []struct {
+	re          *regexp.Regexp
+	replacement string
+}{
+	// Authorization header values, as sent, in curl commands and in Go's
+	// printing of http.Header
+	{regexp.MustCompile(`(?i)(\bauthorization["']?\s*[:=]\s*\[?["']?(?:bearer|basic)\s+)[A-Za-z0-9._~+/-]+=*`), "${1}" + Mask},
+	// Passwords in URLs
+	{regexp.MustCompile(`(://[^/\s:@]+):[^@\s/]+@`), "$1:" + Mask + "@"},
+	// Azure DevOps personal access tokens
+	{regexp.MustCompile(`\b[a-z2-7]{52}\b`), Mask},
+	{regexp.MustCompile(`\b[A-Za-z0-9]{76}AZDO[A-Za-z0-9]{4}\b`), Mask},
+}
+
+// Redactor masks known secrets and anything that looks like a credential.
+// It is safe for concurrent use.
+type Redactor struct {
+	mu      sync.RWMutex
+	secrets []string
+}
+
+// Add registers secret values to mask. A PAT is also masked in its basic
+// auth encoding.
+func (r *Redactor) Add(secrets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, secret := range secrets {
+		if len(secret) < minSecretLength {
+			continue
+		}
+		r.secrets = append(r.secrets, secret, base64.StdEncoding.EncodeToString([]byte(":"+secret)))
+	}
+}
+
+// String returns s with every secret masked.
+func (r *Redactor) String(s string) string {
+	r.mu.RLock()
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, Mask)
+	}
+	r.mu.RUnlock()
+	for _, pattern := range patterns {
+		s = pattern.re.ReplaceAllString(s, pattern.replacement)
+	}
+	return s
+}
+
+// Bytes returns a copy of p with every secret masked. Unlike String, it
+// does not need p to be valid UTF-8.
+func (r *Redactor) Bytes(p []byte) []byte {
+	r.mu.RLock()
+	for _, secret := range r.secrets {
+		p = bytes.ReplaceAll(p, []byte(secret), []byte(Mask))
+	}
+	r.mu.RUnlock()
+	for _, pattern := range patterns {
+		p = pattern.re.ReplaceAll(p, []byte(pattern.replacement))
+	}
+	return p
+}
+
+// Writer returns a writer that redacts the bytes written to w, whatever
+// they are. Each write is redacted on its own, so a secret split across two
+// writes is missed; fmt's printing functions write their output in one
+// call. Output that must arrive byte for byte bypasses it with Unwrap.
+func (r *Redactor) Writer(w io.Writer) io.Writer {
+	return &writer{r: r, w: w}
+}
+
+// Unwrap returns the writer a redacting writer writes to, or w itself, for
+// output that must arrive byte for byte, such as an export to stdout.
+func Unwrap(w io.Writer) io.Writer {
+	if rw, ok := w.(*writer); ok {
+		return rw.w
+	}
+	return w
+}
+
+type writer struct {
+	r *Redactor
+	w io.Writer
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if _, err := w.w.Write(w.r.Bytes(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,32 @@
+// Package scaffold holds starter azure-pipelines.yml templates.
+package scaffold
+
+import (
+	"embed"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//go:embed templates/*.yml
+var templates embed.FS
+
+// Names returns the available template names.
+func Names() []string {
+	entries, _ := templates.ReadDir("templates")
+	var names []string
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".yml"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Template returns the content of a named template.
+func Template(name string) ([]byte, error) {
+	data, err := templates.ReadFile("templates/" + name + ".yml")
+	if err != nil {
+		return nil, fmt.Errorf("unknown template %q (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	return data, nil
+}
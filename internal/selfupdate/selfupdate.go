@@ -0,0 +1,368 @@
+// Package selfupdate finds, verifies and installs new releases of fomo.
+//
+// A release publishes one binary per platform, named fomo_GOOS_GOARCH
+// (with .exe on Windows), a checksums.txt file in sha256sum format, and
+// checksums.txt.sig, an Ed25519 signature of checksums.txt made with the
+// release key. checksums.txt also names the release it belongs to, in a
+// "# version v1.4.0" line, so that the signed files of one release cannot
+// be passed off as another. A binary is installed only if the signature
+// matches the key built into the running binary, the signed version is the
+// release's tag, and the binary matches its checksum.
+package selfupdate
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultFeed is the latest release of fomo on GitHub.
+const DefaultFeed = "https://api.github.com/repos/barbera01/fomo/releases/latest"
+
+// Asset names of the checksums and their signature.
+const (
+	checksumsAsset = "checksums.txt"
+	signatureAsset = "checksums.txt.sig"
+)
+
+// maxDownload bounds the size of any downloaded asset.
+const maxDownload = 200 << 20
+
+// ErrUnsigned is returned when the running binary was built without a
+// release key, so nothing it downloads can be verified.
+var ErrUnsigned = errors.New("this build of fomo has no release key to verify updates with")
+
+// Release is a published release.
+type Release struct {
+	Tag    string  `json:"tag_name"`
+	URL    string  `json:"html_url"`
+	Assets []Asset `json:"assets"`
+}
+
+// Asset is a file attached to a release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Updater checks a release feed and installs releases from it.
+type Updater struct {
+	Feed       string // URL of the latest release; DefaultFeed if empty
+	PublicKey  string // base64 Ed25519 release key
+	HTTPClient *http.Client
+}
+
+func (u *Updater) client() *http.Client {
+	if u.HTTPClient != nil {
+		return u.HTTPClient
+	}
+	return &http.Client{Timeout: 5 * time.Minute}
+}
+
+// Latest returns the latest release.
+func (u *Updater) Latest(ctx context.Context) (*Release, error) {
+	feed := u.Feed
+	if feed == "" {
+		feed = DefaultFeed
+	}
+	data, err := u.get(ctx, feed, 1<<20)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for releases: %w", err)
+	}
+	var release Release
+	if err := json.Unmarshal(data, &release); err != nil {
+		return nil, fmt.Errorf("failed to parse release feed: %w", err)
+	}
+	if release.Tag == "" {
+		return nil, fmt.Errorf("release feed %s names no release", feed)
+	}
+	return &release, nil
+}
+
+// AssetName returns the name of the binary for the running platform.
+func AssetName() string {
+	name := fmt.Sprintf("fomo_%s_%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+func (r *Release) asset(name string) (Asset, error) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a, nil
+		}
+	}
+	return Asset{}, fmt.Errorf("release %s has no %s", r.Tag, name)
+}
+
+// Download fetches the running platform's binary from a release and
+// verifies it.
+func (u *Updater) Download(ctx context.Context, r *Release) ([]byte, error) {
+	if u.PublicKey == "" {
+		return nil, ErrUnsigned
+	}
+	key, err := base64.StdEncoding.DecodeString(u.PublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid release key built into this binary")
+	}
+
+	name := AssetName()
+	fetch := func(name string, limit int64) ([]byte, error) {
+		asset, err := r.asset(name)
+		if err != nil {
+			return nil, err
+		}
+		data, err := u.get(ctx, asset.URL, limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download %s: %w", name, err)
+		}
+		return data, nil
+	}
+	checksums, err := fetch(checksumsAsset, 1<<20)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := fetch(signatureAsset, 4096)
+	if err != nil {
+		return nil, err
+	}
+	if err := verify(ed25519.PublicKey(key), r.Tag, checksums, signature); err != nil {
+		return nil, err
+	}
+	want, err := checksum(checksums, name)
+	if err != nil {
+		return nil, fmt.Errorf("release %s: %w", r.Tag, err)
+	}
+
+	binary, err := fetch(name, maxDownload)
+	if err != nil {
+		return nil, err
+	}
+	if sum := sha256.Sum256(binary); !bytes.Equal(sum[:], want) {
+		return nil, fmt.Errorf("the checksum of %s in release %s does not match", name, r.Tag)
+	}
+	return binary, nil
+}
+
+// verify checks that checksums is signed with key and names release tag.
+func verify(key ed25519.PublicKey, tag string, checksums, signature []byte) error {
+	if !ed25519.Verify(key, checksums, decodeSignature(signature)) {
+		return fmt.Errorf("the signature of %s in release %s does not match the release key", checksumsAsset, tag)
+	}
+	signed, ok := signedVersion(checksums)
+	switch {
+	case !ok:
+		return fmt.Errorf("%s in release %s names no version", checksumsAsset, tag)
+	case signed != tag:
+		return fmt.Errorf("%s in release %s is signed for %s", checksumsAsset, tag, signed)
+	}
+	return nil
+}
+
+// signedVersion returns the version a checksums file names.
+func signedVersion(checksums []byte) (string, bool) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 3 && fields[0] == "#" && fields[1] == "version" {
+			return fields[2], true
+		}
+	}
+	return "", false
+}
+
+// decodeSignature accepts a raw or base64-encoded signature.
+func decodeSignature(data []byte) []byte {
+	if len(data) == ed25519.SignatureSize {
+		return data
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil
+	}
+	return decoded
+}
+
+// checksum finds the SHA-256 of name in a sha256sum file.
+func checksum(checksums []byte, name string) ([]byte, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.TrimPrefix(fields[1], "*") != name {
+			continue
+		}
+		sum, err := hex.DecodeString(fields[0])
+		if err != nil || len(sum) != sha256.Size {
+			return nil, fmt.Errorf("invalid checksum of %s", name)
+		}
+		return sum, nil
+	}
+	return nil, fmt.Errorf("%s lists no checksum for %s", checksumsAsset, name)
+}
+
+func (u *Updater) get(ctx context.Context, url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json, application/octet-stream")
+	resp, err := u.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s is larger than %d bytes", url, limit)
+	}
+	return data, nil
+}
+
+// Replace atomically replaces the executable at path with binary. The new
+// file is written next to it and renamed over it, so the executable is
+// never left half-written. Windows cannot replace a running executable, so
+// there the old one is first moved aside to path.old.
+func Replace(path string, binary []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".new-*")
+	if err != nil {
+		return fmt.Errorf("cannot write to %s: %w", dir, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(info.Mode().Perm() | 0111); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if runtime.GOOS == "windows" {
+		old := path + ".old"
+		os.Remove(old)
+		if err := os.Rename(path, old); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp.Name(), path); err != nil {
+			os.Rename(old, path)
+			return err
+		}
+		return nil
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Newer reports whether version a is newer than b. Versions are
+// vMAJOR.MINOR.PATCH with an optional -prerelease suffix, which sorts
+// before the release itself and is compared as semantic versioning does,
+// so rc.10 is newer than rc.9; anything else, such as a development build,
+// is older than every release.
+func Newer(a, b string) bool {
+	va, okA := parseVersion(a)
+	vb, okB := parseVersion(b)
+	switch {
+	case !okA:
+		return false
+	case !okB:
+		return true
+	}
+	for i := range 3 {
+		if va.parts[i] != vb.parts[i] {
+			return va.parts[i] > vb.parts[i]
+		}
+	}
+	switch {
+	case va.pre == vb.pre:
+		return false
+	case va.pre == "":
+		return true
+	case vb.pre == "":
+		return false
+	}
+	return comparePrerelease(va.pre, vb.pre) > 0
+}
+
+// comparePrerelease compares the dot-separated identifiers of two
+// prereleases in turn: numeric ones by value and before alphanumeric ones,
+// which compare as text. A prerelease that runs out of identifiers first
+// is the older.
+func comparePrerelease(a, b string) int {
+	ids, other := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(ids) && i < len(other); i++ {
+		x, errX := strconv.ParseUint(ids[i], 10, 64)
+		y, errY := strconv.ParseUint(other[i], 10, 64)
+		switch {
+		case errX == nil && errY == nil:
+			if c := cmp.Compare(x, y); c != 0 {
+				return c
+			}
+		case errX == nil:
+			return -1
+		case errY == nil:
+			return 1
+		default:
+			if c := strings.Compare(ids[i], other[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return cmp.Compare(len(ids), len(other))
+}
+
+type version struct {
+	parts [3]int
+	pre   string
+}
+
+func parseVersion(s string) (version, bool) {
+	var v version
+	s, ok := strings.CutPrefix(s, "v")
+	if !ok {
+		return v, false
+	}
+	s, v.pre, _ = strings.Cut(s, "-")
+	fields := strings.Split(s, ".")
+	if len(fields) != 3 {
+		return v, false
+	}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return v, false
+		}
+		v.parts[i] = n
+	}
+	return v, true
+}
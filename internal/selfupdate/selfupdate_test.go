@@ -0,0 +1,192 @@
+package selfupdate
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestNewer(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"v1.2.0", "v1.1.9", true},
+		{"v1.10.0", "v1.9.0", true},
+		{"v1.2.0", "v1.2.0", false},
+		{"v1.2.0", "v1.2.0-rc.1", true},
+		{"v1.2.0-rc.1", "v1.2.0", false},
+		{"v1.2.0-rc.10", "v1.2.0-rc.9", true},
+		{"v1.2.0-rc.9", "v1.2.0-rc.10", false},
+		{"v1.2.0-rc.1", "v1.2.0-beta.2", true},
+		{"v1.2.0-rc", "v1.2.0-1", true},
+		{"v1.2.0-rc.1", "v1.2.0-rc", true},
+		{"v1.2.0-rc.1.1", "v1.2.0-rc.1", true},
+		{"v1.0.0", "dev", true},
+		{"dev", "v1.0.0", false},
+		{"1.2.0", "v1.0.0", false},
+	}
+	for _, tt := range tests {
+		if got := Newer(tt.a, tt.b); got != tt.want {
+			t.Errorf("Newer(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	sum := sha256.Sum256([]byte("binary"))
+	checksums := fmt.Sprintf("# version v1.4.0\n%x  fomo_linux_amd64\n%x *fomo_windows_amd64.exe\nzz  fomo_darwin_arm64\n", sum, sum)
+
+	tests := []struct {
+		name    string
+		want    []byte
+		wantErr string
+	}{
+		{"fomo_linux_amd64", sum[:], ""},
+		{"fomo_windows_amd64.exe", sum[:], ""},
+		{"fomo_darwin_arm64", nil, "invalid checksum of fomo_darwin_arm64"},
+		{"fomo_linux_arm64", nil, "lists no checksum for fomo_linux_arm64"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := checksum([]byte(checksums), tt.name)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("checksum = %x, %v, want an error containing %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !bytes.Equal(got, tt.want) {
+				t.Errorf("checksum = %x, %v, want %x", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, other, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := []byte("# version v1.4.0\n" + strings.Repeat("0", 64) + "  fomo_linux_amd64\n")
+	unversioned := []byte(strings.Repeat("0", 64) + "  fomo_linux_amd64\n")
+
+	tests := []struct {
+		name      string
+		tag       string
+		checksums []byte
+		signature []byte
+		wantErr   string
+	}{
+		{"raw signature", "v1.4.0", signed, ed25519.Sign(private, signed), ""},
+		{"base64 signature", "v1.4.0", signed, []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(private, signed)) + "\n"), ""},
+		{"other key", "v1.4.0", signed, ed25519.Sign(other, signed), "does not match the release key"},
+		{"tampered checksums", "v1.4.0", append([]byte("# "), signed...), ed25519.Sign(private, signed), "does not match the release key"},
+		{"garbage signature", "v1.4.0", signed, []byte("not a signature"), "does not match the release key"},
+		{"signed for another release", "v1.5.0", signed, ed25519.Sign(private, signed), "is signed for v1.4.0"},
+		{"no version", "v1.4.0", unversioned, ed25519.Sign(private, unversioned), "names no version"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verify(public, tt.tag, tt.checksums, tt.signature)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("verify: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("verify = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDownload(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary := []byte("new fomo")
+	sum := sha256.Sum256(binary)
+	checksums := []byte("# version v1.4.0\n" + hex.EncodeToString(sum[:]) + "  " + AssetName() + "\n")
+	assets := map[string][]byte{
+		checksumsAsset: checksums,
+		signatureAsset: ed25519.Sign(private, checksums),
+		AssetName():    binary,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := assets[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	defer srv.Close()
+	release := &Release{Tag: "v1.4.0"}
+	for name := range assets {
+		release.Assets = append(release.Assets, Asset{Name: name, URL: srv.URL + "/" + name})
+	}
+
+	u := &Updater{PublicKey: base64.StdEncoding.EncodeToString(public), HTTPClient: srv.Client()}
+	got, err := u.Download(context.Background(), release)
+	if err != nil || !bytes.Equal(got, binary) {
+		t.Fatalf("Download = %q, %v, want %q", got, err, binary)
+	}
+
+	assets[AssetName()] = []byte("tampered fomo")
+	if _, err := u.Download(context.Background(), release); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("Download of a tampered binary = %v, want a checksum error", err)
+	}
+	if _, err := (&Updater{HTTPClient: srv.Client()}).Download(context.Background(), release); err != ErrUnsigned {
+		t.Errorf("Download without a key = %v, want ErrUnsigned", err)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fomo")
+	if err := os.WriteFile(path, []byte("old"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := Replace(path, []byte("new")); err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "new" {
+		t.Errorf("executable holds %q, %v, want %q", data, err, "new")
+	}
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mode := info.Mode().Perm(); mode != 0711 {
+			t.Errorf("executable mode = %v, want %v", mode, os.FileMode(0711))
+		}
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 && runtime.GOOS != "windows" {
+		t.Errorf("Replace left %d files behind, want only the executable", len(entries)-1)
+	}
+
+	if err := Replace(filepath.Join(t.TempDir(), "missing"), []byte("new")); err == nil {
+		t.Error("Replace of a missing executable succeeded")
+	}
+}
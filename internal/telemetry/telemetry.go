@@ -0,0 +1,160 @@
+// Package telemetry keeps opt-in, anonymous usage statistics: how often
+// each command is run and how often it fails, by category. It never
+// records arguments, organization or project names, or credentials, and
+// there is no installation or user ID.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// SendInterval is how often the statistics are sent.
+const SendInterval = 24 * time.Hour
+
+// State is the telemetry file: the user's choice and the statistics
+// gathered since they were last sent.
+type State struct {
+	// Decided is set once the user has answered the consent prompt or run
+	// fomo telemetry on/off, so they are only asked once
+	Decided bool `json:"decided"`
+	Enabled bool `json:"enabled"`
+
+	Since    time.Time      `json:"since"`
+	LastSent time.Time      `json:"lastSent"`
+	Commands map[string]int `json:"commands,omitempty"`
+	Errors   map[string]int `json:"errors,omitempty"` // "command: category"
+}
+
+// Load reads the telemetry file. A missing file is an undecided state.
+func Load(path string) (*State, error) {
+	var s State
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// Save writes the telemetry file atomically.
+func (s *State) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// SetEnabled records the user's choice. Turning telemetry off discards
+// the statistics gathered so far.
+func (s *State) SetEnabled(enabled bool, now time.Time) {
+	s.Decided, s.Enabled = true, enabled
+	s.Commands, s.Errors = nil, nil
+	s.Since = time.Time{}
+	if enabled {
+		s.Since = now
+	}
+}
+
+// Record counts a run of command, failing with an error category unless
+// it is empty.
+func (s *State) Record(command, category string) {
+	if !s.Enabled {
+		return
+	}
+	if s.Commands == nil {
+		s.Commands = map[string]int{}
+	}
+	s.Commands[command]++
+	if category != "" {
+		if s.Errors == nil {
+			s.Errors = map[string]int{}
+		}
+		s.Errors[command+": "+category]++
+	}
+}
+
+// Due reports whether statistics have been gathered for long enough to be
+// sent.
+func (s *State) Due(now time.Time) bool {
+	return s.Enabled && len(s.Commands) > 0 && now.Sub(s.Since) >= SendInterval
+}
+
+// Report is what is sent: the statistics and the platform, nothing else.
+type Report struct {
+	Version  string         `json:"version"`
+	OS       string         `json:"os"`
+	Arch     string         `json:"arch"`
+	Since    time.Time      `json:"since"`
+	Until    time.Time      `json:"until"`
+	Commands map[string]int `json:"commands"`
+	Errors   map[string]int `json:"errors,omitempty"`
+}
+
+// Report returns the report of the statistics gathered so far.
+func (s *State) Report(version string, now time.Time) Report {
+	return Report{
+		Version:  version,
+		OS:       runtime.GOOS,
+		Arch:     runtime.GOARCH,
+		Since:    s.Since,
+		Until:    now,
+		Commands: s.Commands,
+		Errors:   s.Errors,
+	}
+}
+
+// Send posts the report to endpoint and, if that succeeds, starts
+// gathering afresh.
+func (s *State) Send(ctx context.Context, endpoint, version string, now time.Time) error {
+	body, err := json.Marshal(s.Report(version, now))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s returned %s", endpoint, resp.Status)
+	}
+	s.Commands, s.Errors = nil, nil
+	s.Since, s.LastSent = now, now
+	return nil
+}
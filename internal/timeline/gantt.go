@@ -0,0 +1,227 @@
+package timeline
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"fomo/internal/client"
+)
+
+const (
+	criticalChar = "█"
+	otherChar    = "▒"
+	maxLabel     = 32
+)
+
+// span returns the earliest start and latest finish of the bars. Bars still
+// running end at now.
+func span(bars []Bar, now time.Time) (start, finish time.Time) {
+	for _, b := range bars {
+		if b.Start.IsZero() {
+			continue
+		}
+		end := b.Finish
+		if end.IsZero() {
+			end = now
+		}
+		if start.IsZero() || b.Start.Before(start) {
+			start = b.Start
+		}
+		if end.After(finish) {
+			finish = end
+		}
+	}
+	return start, finish
+}
+
+// WriteText renders bars as a Gantt chart for the terminal, with the chart
+// width cols characters wide and durations formatted by format, or to the
+// second if it is nil. Bars on the critical path are drawn solid.
+func WriteText(w io.Writer, bars []Bar, cols int, format func(time.Duration) string) {
+	if format == nil {
+		format = formatDuration
+	}
+	now := time.Now()
+	start, finish := span(bars, now)
+	total := finish.Sub(start)
+
+	labelWidth := 0
+	for _, b := range bars {
+		labelWidth = max(labelWidth, len(label(b)))
+	}
+
+	fmt.Fprintf(w, "%-*s %s %s\n", labelWidth, "", axis(total, cols, format), format(total))
+	for _, b := range bars {
+		line := make([]string, cols)
+		for i := range line {
+			line[i] = " "
+		}
+		if !b.Start.IsZero() && total > 0 {
+			end := b.Finish
+			if end.IsZero() {
+				end = now
+			}
+			from := int(float64(b.Start.Sub(start)) / float64(total) * float64(cols))
+			to := int(float64(end.Sub(start)) / float64(total) * float64(cols))
+			to = min(max(to, from+1), cols)
+			char := otherChar
+			if b.Critical {
+				char = criticalChar
+			}
+			for i := min(from, cols-1); i < to; i++ {
+				line[i] = char
+			}
+		}
+		fmt.Fprintf(w, "%-*s %s %s\n", labelWidth, label(b), strings.Join(line, ""), status(b, format))
+	}
+	fmt.Fprintf(w, "\n%s critical path  %s other\n", criticalChar, otherChar)
+}
+
+// axis returns a scale line with a tick at each quarter of the run.
+func axis(total time.Duration, cols int, format func(time.Duration) string) string {
+	line := []byte(strings.Repeat(" ", cols))
+	for q := 0; q < 4; q++ {
+		tick := "|" + format(total*time.Duration(q)/4)
+		at := q * cols / 4
+		if at+len(tick) <= cols {
+			copy(line[at:], tick)
+		}
+	}
+	return string(line)
+}
+
+func label(b Bar) string {
+	name := b.Name
+	if len(name) > maxLabel {
+		name = name[:maxLabel-3] + "..."
+	}
+	if b.Type == client.RecordJob {
+		return "  " + name
+	}
+	return name
+}
+
+func status(b Bar, format func(time.Duration) string) string {
+	switch {
+	case b.Start.IsZero():
+		return "not run"
+	case b.Finish.IsZero():
+		return "running"
+	case b.Result != "" && b.Result != "succeeded":
+		return format(b.Duration()) + " " + b.Result
+	}
+	return format(b.Duration())
+}
+
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return "0s"
+	}
+	return d.Round(time.Second).String()
+}
+
+// SVG layout, in pixels.
+const (
+	svgLabelWidth = 260
+	svgChartWidth = 900
+	svgRowHeight  = 22
+	svgPadding    = 10
+)
+
+var svgColors = map[string]string{
+	"succeeded":           "#2da44e",
+	"succeededWithIssues": "#d4a72c",
+	"failed":              "#cf222e",
+	"canceled":            "#8c959f",
+}
+
+// WriteSVG renders bars as an SVG Gantt chart. Bars are colored by result
+// and those on the critical path are outlined.
+func WriteSVG(w io.Writer, title string, bars []Bar) error {
+	now := time.Now()
+	start, finish := span(bars, now)
+	total := finish.Sub(start)
+	width := svgLabelWidth + svgChartWidth + 2*svgPadding
+	height := (len(bars)+2)*svgRowHeight + 2*svgPadding
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="12">`+"\n", width, height)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="#ffffff"/>`+"\n")
+	fmt.Fprintf(&b, `<text x="%d" y="%d" font-weight="bold">%s (%s)</text>`+"\n", svgPadding, svgPadding+14, html.EscapeString(title), formatDuration(total))
+
+	for q := 0; q <= 4; q++ {
+		x := svgPadding + svgLabelWidth + q*svgChartWidth/4
+		fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#d0d7de"/>`+"\n", x, svgPadding+svgRowHeight, x, height-svgPadding)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" fill="#57606a">%s</text>`+"\n", x+2, svgPadding+svgRowHeight+12, formatDuration(total*time.Duration(q)/4))
+	}
+
+	for i, bar := range bars {
+		y := svgPadding + (i+2)*svgRowHeight
+		weight := "bold"
+		if bar.Type == client.RecordJob {
+			weight = "normal"
+		}
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-weight="%s">%s</text>`+"\n", svgPadding, y+15, weight, html.EscapeString(label(bar)))
+		if bar.Start.IsZero() || total <= 0 {
+			continue
+		}
+		end := bar.Finish
+		if end.IsZero() {
+			end = now
+		}
+		x := float64(svgLabelWidth+svgPadding) + float64(bar.Start.Sub(start))/float64(total)*svgChartWidth
+		barWidth := max(float64(end.Sub(bar.Start))/float64(total)*svgChartWidth, 1)
+		color, ok := svgColors[bar.Result]
+		if !ok {
+			color = "#54aeff" // running or unknown
+		}
+		stroke := ""
+		if bar.Critical {
+			stroke = ` stroke="#1f2328" stroke-width="2"`
+		}
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%d" width="%.1f" height="%d" rx="3" fill="%s"%s><title>%s: %s</title></rect>`+"\n",
+			x, y+3, barWidth, svgRowHeight-6, color, stroke, html.EscapeString(bar.Name), status(bar, formatDuration))
+	}
+	b.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WritePlain lists bars as text for screen readers, one line each with
+// when it started, how long it took, its result and whether it is on the
+// critical path. Durations are formatted like in WriteText.
+func WritePlain(w io.Writer, bars []Bar, format func(time.Duration) string) {
+	if format == nil {
+		format = formatDuration
+	}
+	now := time.Now()
+	start, finish := span(bars, now)
+	fmt.Fprintf(w, "Run took %s.\n", format(finish.Sub(start)))
+	for _, b := range bars {
+		kind := "Stage"
+		if b.Type == client.RecordJob {
+			kind = "  Job"
+		}
+		if b.Start.IsZero() {
+			fmt.Fprintf(w, "%s %s: not run.\n", kind, b.Name)
+			continue
+		}
+		line := fmt.Sprintf("%s %s: started at %s", kind, b.Name, format(b.Start.Sub(start)))
+		if b.Finish.IsZero() {
+			line += ", still running"
+		} else {
+			line += ", took " + format(b.Duration())
+			if b.Result != "" {
+				line += ", " + b.Result
+			}
+		}
+		if b.Critical {
+			line += ", on the critical path"
+		}
+		fmt.Fprintln(w, line+".")
+	}
+}
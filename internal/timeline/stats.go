@@ -0,0 +1,173 @@
+package timeline
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"fomo/internal/client"
+)
+
+// JobStats aggregates one job across the timelines of several runs.
+type JobStats struct {
+	Stage string
+	Name  string
+
+	Runs      int           // runs the job took part in
+	Total     time.Duration // summed duration
+	OnPath    int           // runs in which it was on the critical path
+	PathTotal time.Duration // summed duration while on the critical path
+	Wait      time.Duration // summed time it waited, while on the critical path, for the job before it
+}
+
+// Average returns the job's mean duration.
+func (s JobStats) Average() time.Duration {
+	if s.Runs == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Runs)
+}
+
+// PathShare returns how often the job was on the critical path, from 0 to 1.
+func (s JobStats) PathShare() float64 {
+	if s.Runs == 0 {
+		return 0
+	}
+	return float64(s.OnPath) / float64(s.Runs)
+}
+
+// Chain is a pair of jobs of the same stage that ran one after the other on
+// the critical path.
+type Chain struct {
+	Stage, Before, After string
+	Runs                 int
+}
+
+// Summary is the critical path analysis of several runs.
+type Summary struct {
+	Runs    int
+	Average time.Duration // mean duration from the first job's start to the last job's end
+	Jobs    []JobStats    // ordered by time spent on the critical path, most first
+	Chains  []Chain       // ordered by how often they occurred, most first
+}
+
+// Summarize aggregates the timelines of several runs of one pipeline. Jobs
+// are matched across runs by stage and job name.
+func Summarize(timelines [][]client.TimelineRecord) Summary {
+	var summary Summary
+	var total time.Duration
+	stats := map[[2]string]*JobStats{}
+	chains := map[[3]string]int{}
+
+	for _, records := range timelines {
+		path := CriticalPath(records)
+		if len(path) == 0 {
+			continue
+		}
+		summary.Runs++
+		total += path[len(path)-1].FinishTime.Sub(*path[0].StartTime)
+
+		byID := map[string]*client.TimelineRecord{}
+		for i := range records {
+			byID[records[i].ID] = &records[i]
+		}
+		key := func(r client.TimelineRecord) [2]string {
+			stage := ""
+			if s := StageOf(byID, r); s != nil {
+				stage = s.Name
+			}
+			k := [2]string{stage, r.Name}
+			if stats[k] == nil {
+				stats[k] = &JobStats{Stage: stage, Name: r.Name}
+			}
+			return k
+		}
+
+		for _, r := range records {
+			if r.Type == client.RecordJob && r.Duration() > 0 {
+				s := stats[key(r)]
+				s.Runs++
+				s.Total += r.Duration()
+			}
+		}
+		for i, r := range path {
+			s := stats[key(r)]
+			s.OnPath++
+			s.PathTotal += r.Duration()
+			if i > 0 {
+				previous := stats[key(path[i-1])]
+				s.Wait += max(r.StartTime.Sub(*path[i-1].FinishTime), 0)
+				if previous.Stage == s.Stage {
+					chains[[3]string{s.Stage, previous.Name, s.Name}]++
+				}
+			}
+		}
+	}
+
+	if summary.Runs > 0 {
+		summary.Average = total / time.Duration(summary.Runs)
+	}
+	for _, s := range stats {
+		summary.Jobs = append(summary.Jobs, *s)
+	}
+	for k, runs := range chains {
+		summary.Chains = append(summary.Chains, Chain{Stage: k[0], Before: k[1], After: k[2], Runs: runs})
+	}
+	sort.Slice(summary.Chains, func(i, j int) bool {
+		if summary.Chains[i].Runs != summary.Chains[j].Runs {
+			return summary.Chains[i].Runs > summary.Chains[j].Runs
+		}
+		return summary.Chains[i].Before+summary.Chains[i].After < summary.Chains[j].Before+summary.Chains[j].After
+	})
+	sort.Slice(summary.Jobs, func(i, j int) bool {
+		if summary.Jobs[i].PathTotal != summary.Jobs[j].PathTotal {
+			return summary.Jobs[i].PathTotal > summary.Jobs[j].PathTotal
+		}
+		return summary.Jobs[i].Total > summary.Jobs[j].Total
+	})
+	return summary
+}
+
+// Thresholds for suggestions.
+const (
+	dominantShare   = 0.25        // of the run's duration
+	consistentShare = 0.8         // of runs on the critical path
+	longWait        = time.Minute // average wait for an agent
+)
+
+// Suggestions returns advice on shortening the runs, derived from where
+// the critical path spends its time.
+func (s Summary) Suggestions() []string {
+	var out []string
+	if s.Runs == 0 || s.Average <= 0 {
+		return nil
+	}
+	for _, job := range s.Jobs {
+		if job.OnPath == 0 {
+			continue
+		}
+		avgOnPath := job.PathTotal / time.Duration(job.OnPath)
+		share := float64(avgOnPath) / float64(s.Average)
+		if job.PathShare() >= consistentShare && share >= dominantShare {
+			out = append(out, fmt.Sprintf("%s takes %.0f%% of the run and is almost always on the critical path: split it into parallel jobs or cache its work", job.label(), share*100))
+		}
+		if wait := job.Wait / time.Duration(job.OnPath); wait >= longWait {
+			out = append(out, fmt.Sprintf("%s waits %s for an agent on average after the job before it: add agents to the pool or reduce parallel demand", job.label(), formatDuration(wait)))
+		}
+	}
+
+	// Jobs of one stage run in parallel unless one depends on the other
+	for _, chain := range s.Chains {
+		if float64(chain.Runs)/float64(s.Runs) >= consistentShare {
+			out = append(out, fmt.Sprintf("in stage %s, %s runs after %s on the critical path: if it does not need its output, drop the dependency so both run in parallel", chain.Stage, chain.After, chain.Before))
+		}
+	}
+	return out
+}
+
+func (s JobStats) label() string {
+	if s.Stage == "" {
+		return s.Name
+	}
+	return s.Stage + "/" + s.Name
+}
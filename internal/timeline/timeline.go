@@ -0,0 +1,155 @@
+// Package timeline analyzes and renders the timelines of pipeline runs:
+// which stages and jobs ran when, and which of them decided how long the
+// run took.
+package timeline
+
+import (
+	"sort"
+	"time"
+
+	"fomo/internal/client"
+)
+
+// criticalSlack is how far apart a job's start and the end of the job it
+// waited for may be. Agents take a few seconds to pick up a job.
+const criticalSlack = 30 * time.Second
+
+// Bar is a stage or job in a Gantt chart.
+type Bar struct {
+	ID       string
+	Name     string
+	Type     string // client.RecordStage or client.RecordJob
+	Result   string
+	Start    time.Time // zero if the record never started
+	Finish   time.Time // zero if the record has not finished
+	Critical bool
+}
+
+// Duration returns how long the bar ran, or zero if it never finished.
+func (b Bar) Duration() time.Duration {
+	if b.Start.IsZero() || b.Finish.IsZero() {
+		return 0
+	}
+	return b.Finish.Sub(b.Start)
+}
+
+// Bars returns the stages of a run in order, each followed by its jobs.
+// Jobs and stages on the critical path are marked.
+func Bars(records []client.TimelineRecord) []Bar {
+	byID := map[string]*client.TimelineRecord{}
+	for i := range records {
+		byID[records[i].ID] = &records[i]
+	}
+	critical := map[string]bool{}
+	for _, job := range CriticalPath(records) {
+		critical[job.ID] = true
+		if stage := StageOf(byID, job); stage != nil {
+			critical[stage.ID] = true
+		}
+	}
+
+	var stages []client.TimelineRecord
+	jobs := map[string][]client.TimelineRecord{}
+	for _, r := range records {
+		switch r.Type {
+		case client.RecordStage:
+			stages = append(stages, r)
+		case client.RecordJob:
+			if stage := StageOf(byID, r); stage != nil {
+				jobs[stage.ID] = append(jobs[stage.ID], r)
+			}
+		}
+	}
+	sortRecords(stages)
+
+	var bars []Bar
+	for _, stage := range stages {
+		bars = append(bars, newBar(stage, critical))
+		sortRecords(jobs[stage.ID])
+		for _, job := range jobs[stage.ID] {
+			bars = append(bars, newBar(job, critical))
+		}
+	}
+	return bars
+}
+
+func newBar(r client.TimelineRecord, critical map[string]bool) Bar {
+	bar := Bar{ID: r.ID, Name: r.Name, Type: r.Type, Result: r.Result, Critical: critical[r.ID]}
+	if r.StartTime != nil {
+		bar.Start = *r.StartTime
+	}
+	if r.FinishTime != nil {
+		bar.Finish = *r.FinishTime
+	}
+	return bar
+}
+
+// sortRecords orders records by start time, falling back to their order in
+// the pipeline for records that never started.
+func sortRecords(records []client.TimelineRecord) {
+	sort.SliceStable(records, func(i, j int) bool {
+		a, b := records[i].StartTime, records[j].StartTime
+		if a != nil && b != nil && !a.Equal(*b) {
+			return a.Before(*b)
+		}
+		if (a == nil) != (b == nil) {
+			return a != nil
+		}
+		return records[i].Order < records[j].Order
+	})
+}
+
+// StageOf returns the stage a record belongs to.
+func StageOf(byID map[string]*client.TimelineRecord, r client.TimelineRecord) *client.TimelineRecord {
+	for parent := byID[r.ParentID]; parent != nil; parent = byID[parent.ParentID] {
+		if parent.Type == client.RecordStage {
+			return parent
+		}
+	}
+	return nil
+}
+
+// CriticalPath returns the chain of jobs that determined the run's
+// duration, from first to last. It starts at the job that finished last
+// and repeatedly steps back to the job that finished last before the
+// current one started, which is the one it most likely waited for.
+func CriticalPath(records []client.TimelineRecord) []client.TimelineRecord {
+	var jobs []client.TimelineRecord
+	for _, r := range records {
+		if r.Type == client.RecordJob && r.StartTime != nil && r.FinishTime != nil {
+			jobs = append(jobs, r)
+		}
+	}
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	current := jobs[0]
+	for _, job := range jobs[1:] {
+		if job.FinishTime.After(*current.FinishTime) {
+			current = job
+		}
+	}
+	path := []client.TimelineRecord{current}
+	for {
+		var previous *client.TimelineRecord
+		for i, job := range jobs {
+			if job.ID == current.ID || job.FinishTime.After(current.StartTime.Add(criticalSlack)) || !job.StartTime.Before(*current.StartTime) {
+				continue
+			}
+			if previous == nil || job.FinishTime.After(*previous.FinishTime) {
+				previous = &jobs[i]
+			}
+		}
+		if previous == nil {
+			break
+		}
+		current = *previous
+		path = append(path, current)
+	}
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
@@ -0,0 +1,441 @@
+// Package watch polls Azure DevOps for the runs selected by watch rules and
+// turns changes in their state into events.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fomo/internal/client"
+	"fomo/internal/config"
+)
+
+// Source is the part of the API client the watcher needs.
+type Source interface {
+	ListPipelines(ctx context.Context) ([]client.Pipeline, error)
+	ListRuns(ctx context.Context, pipelineID int) ([]client.Run, error)
+	// PipelineTags is only called when a rule selects pipelines by tag.
+	PipelineTags(ctx context.Context) (map[int][]string, error)
+}
+
+// Event kinds.
+const (
+	RunStarted   = "run.started"
+	RunCompleted = "run.completed"
+)
+
+// Event is a change observed in a watched run.
+type Event struct {
+	ID       int64      `json:"id"`
+	Kind     string     `json:"kind"`
+	Time     time.Time  `json:"time"`
+	Pipeline string     `json:"pipeline"`
+	Branch   string     `json:"branch"`
+	Run      client.Run `json:"run"`
+
+	// Rule is the first watch rule that matched the run.
+	Rule config.WatchRule `json:"-"`
+}
+
+// Status is the latest known run of a watched pipeline and branch.
+type Status struct {
+	Pipeline  client.Pipeline `json:"pipeline"`
+	Branch    string          `json:"branch"`
+	Run       client.Run      `json:"run"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+}
+
+type key struct {
+	pipelineID int
+	branch     string
+}
+
+const maxEvents = 500
+
+// Watcher tracks the latest run of every watched pipeline and branch.
+type Watcher struct {
+	Source Source
+	Rules  []config.WatchRule
+
+	// Interval is how often pipelines are polled, unless their rule sets
+	// its own interval. Defaults to a minute.
+	Interval time.Duration
+	// Active replaces the interval while a pipeline has a run in progress,
+	// and Idle once it had no run for IdleAfter. Zero values disable the
+	// adjustment.
+	Active    time.Duration
+	Idle      time.Duration
+	IdleAfter time.Duration
+
+	// OnEvent is called for every event, outside the watcher's lock.
+	OnEvent func(Event)
+	// OnComplete is called for every run seen to complete, whatever the
+	// results its rule reports, outside the watcher's lock.
+	OnComplete func(Event)
+	// OnError is called for every failed poll.
+	OnError func(error)
+
+	mu      sync.Mutex
+	primed  bool
+	state   map[key]*Status
+	events  []Event
+	nextID  int64
+	lastErr error
+
+	// Scheduling state, guarded by pollMu so polls never overlap
+	pollMu      sync.Mutex
+	pipelines   []client.Pipeline
+	tags        map[int][]string // tags of each pipeline, if a rule uses them
+	pipelinesAt time.Time
+	due         map[int]time.Time // next poll of each pipeline
+}
+
+// tick is how often Run checks for pipelines that are due.
+const tick = time.Second
+
+// Run polls until ctx is canceled, each pipeline on its own schedule.
+// Polling errors are kept for LastError rather than stopping the watcher.
+func (w *Watcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	w.Poll(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		err := w.poll(ctx, false)
+		w.mu.Lock()
+		w.lastErr = err
+		w.mu.Unlock()
+		if err != nil && w.OnError != nil && ctx.Err() == nil {
+			w.OnError(err)
+		}
+	}
+}
+
+// Poll fetches every watched pipeline's runs once and emits events for any
+// changes. The first poll only records the current state.
+func (w *Watcher) Poll(ctx context.Context) error {
+	err := w.poll(ctx, true)
+	w.mu.Lock()
+	w.lastErr = err
+	w.mu.Unlock()
+	if err != nil && w.OnError != nil {
+		w.OnError(err)
+	}
+	return err
+}
+
+func (w *Watcher) interval() time.Duration {
+	if w.Interval <= 0 {
+		return time.Minute
+	}
+	return w.Interval
+}
+
+// poll fetches the runs of the pipelines that are due, or of all of them
+// if all is set.
+func (w *Watcher) poll(ctx context.Context, all bool) error {
+	w.pollMu.Lock()
+	defer w.pollMu.Unlock()
+
+	now := time.Now()
+	if all || w.pipelines == nil || now.Sub(w.pipelinesAt) >= w.interval() {
+		pipelines, err := w.Source.ListPipelines(ctx)
+		if err != nil {
+			return err
+		}
+		var tags map[int][]string
+		if w.usesTags() {
+			if tags, err = w.Source.PipelineTags(ctx); err != nil {
+				return err
+			}
+		}
+		w.pipelines, w.tags, w.pipelinesAt = pipelines, tags, now
+	}
+	if w.due == nil {
+		w.due = map[int]time.Time{}
+	}
+
+	var emitted, completed []Event
+	for _, p := range w.pipelines {
+		if !w.watchesPipeline(p) {
+			continue
+		}
+		if due, ok := w.due[p.ID]; !all && ok && now.Before(due) {
+			continue
+		}
+		runs, err := w.Source.ListRuns(ctx, p.ID)
+		if err != nil {
+			return err
+		}
+		w.due[p.ID] = now.Add(w.pollInterval(p, runs, now))
+
+		// Runs come newest first; keep the latest per branch
+		latest := map[string]client.Run{}
+		for _, run := range runs {
+			branch := ShortBranch(run.Branch())
+			if _, seen := latest[branch]; seen {
+				continue
+			}
+			if _, ok := w.match(p, branch); ok {
+				latest[branch] = run
+			}
+		}
+
+		w.mu.Lock()
+		for branch, run := range latest {
+			rule, _ := w.match(p, branch)
+			events, done := w.update(p, branch, run, rule)
+			emitted = append(emitted, events...)
+			if done != nil {
+				completed = append(completed, *done)
+			}
+		}
+		w.mu.Unlock()
+	}
+
+	w.mu.Lock()
+	w.primed = true
+	w.mu.Unlock()
+
+	if w.OnEvent != nil {
+		for _, event := range emitted {
+			w.OnEvent(event)
+		}
+	}
+	if w.OnComplete != nil {
+		for _, event := range completed {
+			w.OnComplete(event)
+		}
+	}
+	return nil
+}
+
+// update records run as the latest for its pipeline and branch and returns
+// the resulting events, and the completion of the run if it was just seen
+// to complete. The caller holds w.mu.
+func (w *Watcher) update(p client.Pipeline, branch string, run client.Run, rule config.WatchRule) ([]Event, *Event) {
+	if w.state == nil {
+		w.state = map[key]*Status{}
+	}
+	k := key{p.ID, branch}
+	prev, known := w.state[k]
+	w.state[k] = &Status{Pipeline: p, Branch: branch, Run: run, UpdatedAt: time.Now()}
+	if !w.primed {
+		return nil, nil
+	}
+
+	var kinds []string
+	switch {
+	case !known || prev.Run.ID != run.ID:
+		if run.State != client.RunStateCompleted {
+			kinds = append(kinds, RunStarted)
+		} else {
+			kinds = append(kinds, RunStarted, RunCompleted)
+		}
+	case prev.Run.State != client.RunStateCompleted && run.State == client.RunStateCompleted:
+		kinds = append(kinds, RunCompleted)
+	}
+
+	var events []Event
+	var done *Event
+	for _, kind := range kinds {
+		if kind == RunCompleted {
+			done = &Event{Kind: kind, Time: time.Now(), Pipeline: p.Name, Branch: branch, Run: run, Rule: rule}
+			if !MatchesResult(rule, run.Result) {
+				continue
+			}
+		}
+		w.nextID++
+		event := Event{ID: w.nextID, Kind: kind, Time: time.Now(), Pipeline: p.Name, Branch: branch, Run: run, Rule: rule}
+		w.events = append(w.events, event)
+		events = append(events, event)
+	}
+	if len(w.events) > maxEvents {
+		w.events = w.events[len(w.events)-maxEvents:]
+	}
+	return events, done
+}
+
+// Wake makes a pipeline due for polling at the next tick, e.g. after a run
+// was queued for it.
+func (w *Watcher) Wake(pipelineID int) {
+	w.pollMu.Lock()
+	defer w.pollMu.Unlock()
+	if w.due != nil {
+		w.due[pipelineID] = time.Time{}
+	}
+}
+
+// pollInterval decides when to poll a pipeline next: sooner while a run is
+// in progress, later once the pipeline has been idle for a while.
+func (w *Watcher) pollInterval(p client.Pipeline, runs []client.Run, now time.Time) time.Duration {
+	interval := w.interval()
+	for _, rule := range w.Rules {
+		if rule.Interval > 0 && w.matchesPipeline(rule, p) {
+			interval = rule.Interval
+			break
+		}
+	}
+
+	var last time.Time
+	for _, run := range runs {
+		if run.State != client.RunStateCompleted {
+			if w.Active > 0 {
+				return min(interval, w.Active)
+			}
+			return interval
+		}
+		for _, t := range []time.Time{run.CreatedDate, run.FinishedDate} {
+			if t.After(last) {
+				last = t
+			}
+		}
+	}
+	if w.Idle > 0 && w.IdleAfter > 0 && now.Sub(last) >= w.IdleAfter {
+		return max(interval, w.Idle)
+	}
+	return interval
+}
+
+// State returns the latest run of every watched pipeline and branch.
+func (w *Watcher) State() []Status {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	out := make([]Status, 0, len(w.state))
+	for _, s := range w.state {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Pipeline.Name != out[j].Pipeline.Name {
+			return out[i].Pipeline.Name < out[j].Pipeline.Name
+		}
+		return out[i].Branch < out[j].Branch
+	})
+	return out
+}
+
+// Events returns the retained events with an ID greater than since.
+func (w *Watcher) Events(since int64) []Event {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var out []Event
+	for _, e := range w.events {
+		if e.ID > since {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// LastError returns the error of the most recent poll, if any.
+func (w *Watcher) LastError() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lastErr
+}
+
+// watchesPipeline reports whether any rule selects the pipeline. Without
+// rules every pipeline is watched.
+func (w *Watcher) watchesPipeline(p client.Pipeline) bool {
+	if len(w.Rules) == 0 {
+		return true
+	}
+	for _, rule := range w.Rules {
+		if w.matchesPipeline(rule, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// match returns the first rule selecting the pipeline and branch.
+func (w *Watcher) match(p client.Pipeline, branch string) (config.WatchRule, bool) {
+	if len(w.Rules) == 0 {
+		return config.WatchRule{}, true
+	}
+	for _, rule := range w.Rules {
+		if w.matchesPipeline(rule, p) && matchesBranch(rule.Branches, branch) {
+			return rule, true
+		}
+	}
+	return config.WatchRule{}, false
+}
+
+// usesTags reports whether any rule selects pipelines by tag.
+func (w *Watcher) usesTags() bool {
+	for _, rule := range w.Rules {
+		if len(rule.Tags) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPipeline reports whether the rule's pattern and tags select the
+// pipeline. Tags compare case-insensitively, like in Azure DevOps.
+func (w *Watcher) matchesPipeline(rule config.WatchRule, p client.Pipeline) bool {
+	for _, want := range rule.Tags {
+		found := false
+		for _, tag := range w.tags[p.ID] {
+			found = found || strings.EqualFold(tag, want)
+		}
+		if !found {
+			return false
+		}
+	}
+	if rule.Pipeline == "" || rule.Pipeline == strconv.Itoa(p.ID) {
+		return true
+	}
+	ok, _ := path.Match(rule.Pipeline, p.Name)
+	return ok
+}
+
+func matchesBranch(patterns []string, branch string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(ShortBranch(pattern), branch); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesResult reports whether a rule reports runs with the given result.
+func MatchesResult(rule config.WatchRule, result string) bool {
+	if len(rule.Results) == 0 {
+		return true
+	}
+	for _, r := range rule.Results {
+		if strings.EqualFold(r, result) {
+			return true
+		}
+	}
+	return false
+}
+
+// ShortBranch strips the refs/heads/ prefix from a branch ref.
+func ShortBranch(ref string) string {
+	return strings.TrimPrefix(ref, "refs/heads/")
+}
+
+// BranchLabel names a ref for people: "PR 12" for the ref a pull request
+// run builds, on any host, and the short branch name otherwise.
+func BranchLabel(ref string) string {
+	if n := client.PullRequestNumber(ref); n != 0 {
+		return fmt.Sprintf("PR %d", n)
+	}
+	return ShortBranch(ref)
+}
@@ -0,0 +1,256 @@
+package yaml
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Unmarshal decodes a YAML document into v, which must be a non-nil pointer.
+// Struct fields are matched by their `yaml` tag, or by the lower-cased field
+// name when there is none; a tag of "-" skips the field.
+func Unmarshal(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("yaml: Unmarshal requires a non-nil pointer")
+	}
+	node, err := parse(data)
+	if err != nil {
+		return err
+	}
+	if node == nil {
+		return nil
+	}
+	return decode(node, rv.Elem(), "")
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func decode(node any, v reflect.Value, path string) error {
+	if node == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return decode(node, v.Elem(), path)
+	}
+
+	if v.Kind() == reflect.Interface && v.NumMethod() == 0 {
+		v.Set(reflect.ValueOf(generic(node)))
+		return nil
+	}
+
+	switch n := node.(type) {
+	case map[string]any:
+		return decodeMapping(n, v, path)
+	case []any:
+		return decodeSequence(n, v, path)
+	case scalar:
+		return decodeScalar(n, v, path)
+	}
+	return fmt.Errorf("yaml: %s: unsupported node", displayPath(path))
+}
+
+func decodeMapping(m map[string]any, v reflect.Value, path string) error {
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("yaml: %s: map keys must be strings", displayPath(path))
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		for key, child := range m {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := decode(child, elem, joinPath(path, key)); err != nil {
+				return err
+			}
+			v.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), elem)
+		}
+		return nil
+	case reflect.Struct:
+		fields := structFields(v.Type())
+		for key, child := range m {
+			index, ok := fields[key]
+			if !ok {
+				continue
+			}
+			if err := decode(child, v.FieldByIndex(index), joinPath(path, key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("yaml: %s: cannot decode a mapping into %s", displayPath(path), v.Type())
+}
+
+func decodeSequence(seq []any, v reflect.Value, path string) error {
+	switch v.Kind() {
+	case reflect.Slice:
+		out := reflect.MakeSlice(v.Type(), len(seq), len(seq))
+		for i, child := range seq {
+			if err := decode(child, out.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		v.Set(out)
+		return nil
+	case reflect.String:
+		return fmt.Errorf("yaml: %s: expected a single value, got a list", displayPath(path))
+	}
+	return fmt.Errorf("yaml: %s: cannot decode a list into %s", displayPath(path), v.Type())
+}
+
+func decodeScalar(s scalar, v reflect.Value, path string) error {
+	if !s.quoted && isNull(s.text) {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	fail := func() error {
+		return fmt.Errorf("yaml: %s: cannot use %q as %s", displayPath(path), s.text, v.Type())
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s.text)
+		if err != nil {
+			return fail()
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s.text)
+	case reflect.Bool:
+		b, ok := parseBool(s.text)
+		if !ok {
+			return fail()
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(strings.ReplaceAll(s.text, "_", ""), 0, v.Type().Bits())
+		if err != nil {
+			return fail()
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(strings.ReplaceAll(s.text, "_", ""), 0, v.Type().Bits())
+		if err != nil {
+			return fail()
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s.text, v.Type().Bits())
+		if err != nil {
+			return fail()
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		// A single value where a list is expected becomes a one-element list
+		out := reflect.MakeSlice(v.Type(), 1, 1)
+		if err := decodeScalar(s, out.Index(0), path+"[0]"); err != nil {
+			return err
+		}
+		v.Set(out)
+	default:
+		return fail()
+	}
+	return nil
+}
+
+func isNull(text string) bool {
+	return text == "" || text == "~" || text == "null" || text == "Null" || text == "NULL"
+}
+
+func parseBool(text string) (bool, bool) {
+	switch text {
+	case "true", "True", "TRUE":
+		return true, true
+	case "false", "False", "FALSE":
+		return false, true
+	}
+	return false, false
+}
+
+// generic converts a node into plain Go values for interface{} destinations.
+func generic(node any) any {
+	switch n := node.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(n))
+		for k, v := range n {
+			out[k] = generic(v)
+		}
+		return out
+	case []any:
+		out := make([]any, len(n))
+		for i, v := range n {
+			out[i] = generic(v)
+		}
+		return out
+	case scalar:
+		if n.quoted {
+			return n.text
+		}
+		if isNull(n.text) {
+			return nil
+		}
+		if b, ok := parseBool(n.text); ok {
+			return b
+		}
+		if i, err := strconv.ParseInt(n.text, 10, 64); err == nil {
+			return i
+		}
+		if f, err := strconv.ParseFloat(n.text, 64); err == nil {
+			return f
+		}
+		return n.text
+	}
+	return nil
+}
+
+func structFields(t reflect.Type) map[string][]int {
+	fields := map[string][]int{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && f.Type.Kind() == reflect.Struct && strings.Contains(opts, "inline") {
+			for k, idx := range structFields(f.Type) {
+				fields[k] = append([]int{i}, idx...)
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = []int{i}
+	}
+	return fields
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "document"
+	}
+	return path
+}
@@ -0,0 +1,553 @@
+// Package yaml decodes the subset of YAML used by fomo's configuration files:
+// block mappings and sequences, flow collections, quoted and plain scalars,
+// literal and folded block scalars, and comments. Flow collections and plain
+// scalars may span lines. Anchors, aliases, tags and multi-document streams
+// are not supported, and are reported as errors rather than read as text.
+package yaml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// scalar is a leaf value. Quoted scalars are always strings; plain ones are
+// typed according to the destination they are decoded into.
+type scalar struct {
+	text   string
+	quoted bool
+}
+
+type line struct {
+	num    int
+	indent int
+	text   string // content with indentation and comments removed
+	raw    string
+}
+
+type parser struct {
+	lines []line
+	pos   int
+}
+
+// parse turns a document into a tree of map[string]any, []any and scalar.
+func parse(data []byte) (any, error) {
+	p := &parser{}
+	content := false
+	for i, raw := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		if strings.Contains(leadingSpace(raw), "\t") {
+			return nil, fmt.Errorf("yaml: line %d: tabs are not allowed for indentation", i+1)
+		}
+		text := strings.TrimSpace(stripComment(raw))
+		if text == "---" || text == "..." {
+			if text == "---" && content {
+				return nil, fmt.Errorf("yaml: line %d: multi-document streams are not supported", i+1)
+			}
+			text = ""
+		}
+		content = content || text != ""
+		p.lines = append(p.lines, line{num: i + 1, indent: len(raw) - len(strings.TrimLeft(raw, " ")), text: text, raw: raw})
+	}
+
+	if !p.skipBlank() {
+		return nil, nil
+	}
+	node, err := p.parseNode(p.lines[p.pos].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.skipBlank() {
+		return nil, p.errorf("unexpected content")
+	}
+	return node, nil
+}
+
+func leadingSpace(s string) string {
+	return s[:len(s)-len(strings.TrimLeft(s, " \t"))]
+}
+
+// stripComment removes a trailing comment that is not inside quotes.
+func stripComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' {
+				i++
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || s[i-1] == ' ' || s[i-1] == '[' || s[i-1] == '{' || s[i-1] == ',' || s[i-1] == ':' || s[i-1] == '-' {
+				quote = c
+			}
+		case c == '#' && (i == 0 || s[i-1] == ' '):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// skipBlank advances past empty lines and reports whether any remain.
+func (p *parser) skipBlank() bool {
+	for p.pos < len(p.lines) && p.lines[p.pos].text == "" {
+		p.pos++
+	}
+	return p.pos < len(p.lines)
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	num := len(p.lines)
+	if p.pos < len(p.lines) {
+		num = p.lines[p.pos].num
+	}
+	return fmt.Errorf("yaml: line %d: %s", num, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) parseNode(indent int) (any, error) {
+	l := p.lines[p.pos]
+	switch {
+	case isSequenceItem(l.text):
+		return p.parseSequence(l.indent)
+	case keyEnd(l.text) >= 0:
+		return p.parseMapping(l.indent)
+	}
+	p.pos++
+	return p.parseInline(l.indent-1, l.text)
+}
+
+func isSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// keyEnd returns the index of the colon ending a mapping key, or -1.
+func keyEnd(text string) int {
+	if text == "" || text[0] == '[' || text[0] == '{' {
+		return -1
+	}
+	if text[0] == '"' || text[0] == '\'' {
+		end := closingQuote(text, 0)
+		if end < 0 || end+1 >= len(text) || text[end+1] != ':' {
+			return -1
+		}
+		if end+2 == len(text) || text[end+2] == ' ' {
+			return end + 1
+		}
+		return -1
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+			return i
+		}
+	}
+	return -1
+}
+
+func closingQuote(text string, start int) int {
+	q := text[start]
+	for i := start + 1; i < len(text); i++ {
+		switch {
+		case q == '"' && text[i] == '\\':
+			i++
+		case text[i] == q && q == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		case text[i] == q:
+			return i
+		}
+	}
+	return -1
+}
+
+func (p *parser) parseMapping(indent int) (any, error) {
+	m := map[string]any{}
+	for p.skipBlank() {
+		l := p.lines[p.pos]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent {
+			return nil, p.errorf("unexpected indentation")
+		}
+		end := keyEnd(l.text)
+		if end < 0 {
+			if isSequenceItem(l.text) {
+				break
+			}
+			return nil, p.errorf("expected a mapping key")
+		}
+
+		key, err := unquoteKey(strings.TrimSpace(l.text[:end]))
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		if err := unsupported(key); err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		if _, dup := m[key]; dup {
+			return nil, p.errorf("duplicate key %q", key)
+		}
+		rest := strings.TrimSpace(l.text[end+1:])
+		p.pos++
+
+		value, err := p.parseValue(indent, rest, true)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = value
+	}
+	return m, nil
+}
+
+// parseValue parses whatever follows a "key:" or "- " marker.
+func (p *parser) parseValue(indent int, rest string, inMapping bool) (any, error) {
+	if strings.HasPrefix(rest, "|") || strings.HasPrefix(rest, ">") {
+		return p.parseBlockScalar(indent, rest)
+	}
+	if rest != "" {
+		return p.parseInline(indent, rest)
+	}
+	if !p.skipBlank() {
+		return nil, nil
+	}
+	next := p.lines[p.pos]
+	// A sequence may sit at the same indentation as its mapping key
+	if next.indent > indent || (inMapping && next.indent == indent && isSequenceItem(next.text)) {
+		return p.parseNode(next.indent)
+	}
+	return nil, nil
+}
+
+func (p *parser) parseSequence(indent int) (any, error) {
+	seq := []any{}
+	for p.skipBlank() {
+		l := p.lines[p.pos]
+		if l.indent != indent || !isSequenceItem(l.text) {
+			if l.indent > indent {
+				return nil, p.errorf("unexpected indentation")
+			}
+			break
+		}
+		rest := strings.TrimSpace(strings.TrimPrefix(l.text, "-"))
+
+		// "- key: value" or "- - item" starts a nested block on the same
+		// line; re-read the remainder as if it were on its own line.
+		if keyEnd(rest) >= 0 || isSequenceItem(rest) {
+			offset := strings.Index(l.raw, rest)
+			p.lines[p.pos].indent = offset
+			p.lines[p.pos].text = rest
+			item, err := p.parseNode(offset)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, item)
+			continue
+		}
+
+		p.pos++
+		item, err := p.parseValue(indent, rest, false)
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, item)
+	}
+	return seq, nil
+}
+
+// parseBlockScalar reads a literal (|) or folded (>) block scalar.
+func (p *parser) parseBlockScalar(indent int, header string) (any, error) {
+	folded := header[0] == '>'
+	chomp := strings.TrimSpace(header[1:])
+
+	var body []string
+	blockIndent := -1
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if strings.TrimSpace(l.raw) == "" {
+			body = append(body, "")
+			p.pos++
+			continue
+		}
+		if l.indent <= indent {
+			break
+		}
+		if blockIndent < 0 {
+			blockIndent = l.indent
+		}
+		if l.indent < blockIndent {
+			break
+		}
+		body = append(body, l.raw[blockIndent:])
+		p.pos++
+	}
+
+	// Trailing blank lines belong to the following content
+	trailing := 0
+	for len(body) > 0 && body[len(body)-1] == "" {
+		body = body[:len(body)-1]
+		trailing++
+	}
+	p.pos -= trailing
+
+	var text string
+	if folded {
+		var b strings.Builder
+		for i, s := range body {
+			if s == "" {
+				b.WriteString("\n")
+				continue
+			}
+			if i > 0 && body[i-1] != "" {
+				b.WriteString(" ")
+			}
+			b.WriteString(s)
+		}
+		text = b.String()
+	} else {
+		text = strings.Join(body, "\n")
+	}
+
+	switch chomp {
+	case "-":
+	case "+":
+		text += strings.Repeat("\n", trailing+1)
+	default:
+		if text != "" {
+			text += "\n"
+		}
+	}
+	return scalar{text: text, quoted: true}, nil
+}
+
+func unquoteKey(key string) (string, error) {
+	if key != "" && (key[0] == '"' || key[0] == '\'') {
+		return unquote(key)
+	}
+	return key, nil
+}
+
+func unquote(s string) (string, error) {
+	if s[0] == '\'' {
+		if len(s) < 2 || s[len(s)-1] != '\'' {
+			return "", fmt.Errorf("unterminated string %s", s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+	v, err := strconv.Unquote(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid string %s", s)
+	}
+	return v, nil
+}
+
+// parseInline parses a value that starts on the line before p.pos: a flow
+// collection or a scalar. It goes on over the lines that continue it, which
+// are indented more than indent.
+func (p *parser) parseInline(indent int, text string) (any, error) {
+	num := p.lines[p.pos-1].num
+	if text[0] == '[' || text[0] == '{' {
+		for !flowClosed(text) && p.skipBlank() && p.lines[p.pos].indent > indent {
+			text += " " + p.lines[p.pos].text
+			p.pos++
+		}
+	} else if text[0] != '"' && text[0] != '\'' {
+		text = p.foldPlain(indent, text)
+	}
+
+	fp := &flowParser{s: text}
+	v, err := fp.value()
+	if err == nil {
+		fp.skipSpace()
+		if fp.i != len(fp.s) {
+			err = fmt.Errorf("unexpected %q after value", fp.s[fp.i:])
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("yaml: line %d: %w", num, err)
+	}
+	return v, nil
+}
+
+// foldPlain joins a plain scalar with its continuation lines: a line break
+// becomes a space, and each blank line between them a newline.
+func (p *parser) foldPlain(indent int, text string) string {
+	breaks := 0
+	for i := p.pos; i < len(p.lines); i++ {
+		l := p.lines[i]
+		switch {
+		case strings.TrimSpace(l.raw) == "":
+			breaks++
+			continue
+		case l.text == "" || l.indent <= indent || keyEnd(l.text) >= 0:
+			// A comment, a less indented line or a key ends the scalar
+			return text
+		case breaks > 0:
+			text += strings.Repeat("\n", breaks) + l.text
+		default:
+			text += " " + l.text
+		}
+		breaks = 0
+		p.pos = i + 1
+	}
+	return text
+}
+
+// flowClosed reports whether text closes every flow collection it opens.
+func flowClosed(text string) bool {
+	depth := 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+		case '"', '\'':
+			// Only a quote that starts a value opens a string
+			if i > 0 && !strings.ContainsRune(" [{,:", rune(text[i-1])) {
+				continue
+			}
+			end := closingQuote(text, i)
+			if end < 0 {
+				return false
+			}
+			i = end
+		}
+	}
+	return depth <= 0
+}
+
+// unsupported reports the constructs the package does not read, which would
+// otherwise be taken for the start of a plain scalar.
+func unsupported(text string) error {
+	if text == "" {
+		return nil
+	}
+	switch text[0] {
+	case '&':
+		return fmt.Errorf("anchors are not supported: %s", text)
+	case '*':
+		return fmt.Errorf("aliases are not supported: %s", text)
+	case '!':
+		return fmt.Errorf("tags are not supported: %s", text)
+	}
+	return nil
+}
+
+type flowParser struct {
+	s     string
+	i     int
+	depth int
+}
+
+func (f *flowParser) skipSpace() {
+	for f.i < len(f.s) && f.s[f.i] == ' ' {
+		f.i++
+	}
+}
+
+func (f *flowParser) value() (any, error) {
+	f.skipSpace()
+	if f.i >= len(f.s) {
+		return scalar{}, nil
+	}
+	switch f.s[f.i] {
+	case '[':
+		return f.sequence()
+	case '{':
+		return f.mapping()
+	case '"', '\'':
+		end := closingQuote(f.s, f.i)
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated string %s", f.s[f.i:])
+		}
+		v, err := unquote(f.s[f.i : end+1])
+		if err != nil {
+			return nil, err
+		}
+		f.i = end + 1
+		return scalar{text: v, quoted: true}, nil
+	}
+
+	// Plain scalar; inside flow collections it stops at , ] }
+	if err := unsupported(f.s[f.i:]); err != nil {
+		return nil, err
+	}
+	start := f.i
+	for f.i < len(f.s) {
+		c := f.s[f.i]
+		if f.depth > 0 && (c == ',' || c == ']' || c == '}') {
+			break
+		}
+		if f.depth > 0 && c == ':' && (f.i+1 == len(f.s) || f.s[f.i+1] == ' ') {
+			break
+		}
+		f.i++
+	}
+	return scalar{text: strings.TrimSpace(f.s[start:f.i])}, nil
+}
+
+func (f *flowParser) sequence() (any, error) {
+	f.i++ // [
+	f.depth++
+	defer func() { f.depth-- }()
+
+	seq := []any{}
+	for {
+		f.skipSpace()
+		if f.i >= len(f.s) {
+			return nil, fmt.Errorf("unterminated flow sequence")
+		}
+		if f.s[f.i] == ']' {
+			f.i++
+			return seq, nil
+		}
+		v, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, v)
+		f.skipSpace()
+		if f.i < len(f.s) && f.s[f.i] == ',' {
+			f.i++
+		}
+	}
+}
+
+func (f *flowParser) mapping() (any, error) {
+	f.i++ // {
+	f.depth++
+	defer func() { f.depth-- }()
+
+	m := map[string]any{}
+	for {
+		f.skipSpace()
+		if f.i >= len(f.s) {
+			return nil, fmt.Errorf("unterminated flow mapping")
+		}
+		if f.s[f.i] == '}' {
+			f.i++
+			return m, nil
+		}
+		k, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(scalar)
+		if !ok {
+			return nil, fmt.Errorf("flow mapping keys must be scalars")
+		}
+		f.skipSpace()
+		var v any
+		if f.i < len(f.s) && f.s[f.i] == ':' {
+			f.i++
+			if v, err = f.value(); err != nil {
+				return nil, err
+			}
+		}
+		m[key.text] = v
+		f.skipSpace()
+		if f.i < len(f.s) && f.s[f.i] == ',' {
+			f.i++
+		}
+	}
+}
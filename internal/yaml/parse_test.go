@@ -0,0 +1,95 @@
+package yaml
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want any
+	}{
+		{"block mapping", "a: 1\nb: text\n", map[string]any{"a": int64(1), "b": "text"}},
+		{"nested mapping", "a:\n  b: true\n", map[string]any{"a": map[string]any{"b": true}}},
+		{"sequence under a key", "a:\n  - x\n  - y\n", map[string]any{"a": []any{"x", "y"}}},
+		{"sequence at the indentation of its key", "a:\n- x\n- y\nb: 2\n", map[string]any{"a": []any{"x", "y"}, "b": int64(2)}},
+		{"sequence of mappings", "- name: x\n  type: string\n- name: y\n", []any{
+			map[string]any{"name": "x", "type": "string"},
+			map[string]any{"name": "y"},
+		}},
+		{"flow collections", "a: [x, 'y, z']\nb: {c: 1, d: [2]}\n", map[string]any{
+			"a": []any{"x", "y, z"},
+			"b": map[string]any{"c": int64(1), "d": []any{int64(2)}},
+		}},
+		{"flow sequence over lines", "include: [main,\n  release/*]\nexclude: []\n", map[string]any{
+			"include": []any{"main", "release/*"},
+			"exclude": []any{},
+		}},
+		{"flow sequence starting on its own line", "a:\n  [x,\n   y]\n", map[string]any{"a": []any{"x", "y"}}},
+		{"flow mapping over lines", "params: {environment: dev, # where\n  region: west}\n", map[string]any{
+			"params": map[string]any{"environment": "dev", "region": "west"},
+		}},
+		{"flow sequence with an apostrophe", "a: [don't, x]\n", map[string]any{"a": []any{"don't", "x"}}},
+		{"plain scalar over lines", "summary: the first\n  and second line\nnext: 1\n", map[string]any{
+			"summary": "the first and second line",
+			"next":    int64(1),
+		}},
+		{"plain scalar with a blank line", "a: one\n\n  two\n", map[string]any{"a": "one\ntwo"}},
+		{"plain scalar on its own lines", "a:\n  one\n  two\n", map[string]any{"a": "one two"}},
+		{"plain scalar over lines in a sequence", "- one\n  two\n- three\n", []any{"one two", "three"}},
+		{"literal block scalar", "a: |\n  x\n  y\n", map[string]any{"a": "x\ny\n"}},
+		{"folded block scalar", "a: >-\n  x\n  y\n", map[string]any{"a": "x y"}},
+		{"comments", "# top\na: 1 # one\nb: '#2'\n", map[string]any{"a": int64(1), "b": "#2"}},
+		{"document markers", "---\na: 1\n...\n", map[string]any{"a": int64(1)}},
+		{"empty document", "# nothing\n", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got any
+			if err := Unmarshal([]byte(tt.in), &got); err != nil {
+				t.Fatalf("Unmarshal(%q): %v", tt.in, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Unmarshal(%q) = %#v, want %#v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"anchor", "base: &defaults\n  pool: linux\n", "line 1: anchors are not supported: &defaults"},
+		{"anchor on a scalar", "a: &x 1\n", "line 1: anchors are not supported"},
+		{"alias", "a: 1\nb: *x\n", "line 2: aliases are not supported: *x"},
+		{"alias in a sequence", "- *x\n", "line 1: aliases are not supported"},
+		{"alias in a flow sequence", "a: [1, *x]\n", "line 1: aliases are not supported"},
+		{"merge key", "a:\n  <<: *defaults\n", "line 2: aliases are not supported"},
+		{"anchored key", "&k a: 1\n", "line 1: anchors are not supported"},
+		{"tag", "a: !!str 1\n", "line 1: tags are not supported: !!str 1"},
+		{"second document", "a: 1\n---\nb: 2\n", "line 2: multi-document streams are not supported"},
+		{"unterminated flow sequence", "a: [x,\n  y\nb: 1\n", "line 1: unterminated flow sequence"},
+		{"unterminated string", "a: \"x\n", "line 1: unterminated string"},
+		{"key after a plain scalar", "a: x\n  b: y\n", "line 2: unexpected indentation"},
+		{"duplicate key", "a: 1\na: 2\n", "line 2: duplicate key"},
+		{"tab indentation", "a:\n\tb: 1\n", "line 2: tabs are not allowed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got any
+			err := Unmarshal([]byte(tt.in), &got)
+			if err == nil {
+				t.Fatalf("Unmarshal(%q) = %#v, want an error", tt.in, got)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Unmarshal(%q) error = %q, want it to contain %q", tt.in, err, tt.want)
+			}
+		})
+	}
+}
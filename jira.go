@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"fomo/internal/config"
+	"fomo/internal/credentials"
+	"fomo/internal/i18n"
+)
+
+const (
+	jiraTokenEnv = "FOMO_JIRA_TOKEN" // Jira API token, instead of the stored one
+	jiraService  = "jira"            // name of the Jira token in the credentials file
+)
+
+var issueKeyPattern = regexp.MustCompile(`\b[A-Z][A-Z0-9_]+-[1-9][0-9]*\b`)
+
+// issueKeys returns the Jira issue keys a text mentions, once each, in the
+// order they appear. With projects, only keys of those projects count.
+func issueKeys(text string, projects []string) []string {
+	var keys []string
+	for _, key := range issueKeyPattern.FindAllString(text, -1) {
+		project, _, _ := strings.Cut(key, "-")
+		if len(projects) > 0 && !slices.Contains(projects, project) || slices.Contains(keys, key) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// jiraIssue is what fomo shows of a Jira issue.
+type jiraIssue struct {
+	Key     string
+	Summary string
+	Status  string
+}
+
+// jiraClient reads issues from Jira Cloud, with an email address and API
+// token, or from Jira Server and Data Center, with a personal access token.
+type jiraClient struct {
+	baseURL string
+	user    string
+	token   string
+}
+
+// jira returns a client for the configured Jira site, or nil when none is
+// configured. The token is FOMO_JIRA_TOKEN, or the one stored with fomo
+// auth jira, which takes the passphrase of the credentials file.
+func (a *app) jira() (*jiraClient, error) {
+	cfg := a.cfg.Jira
+	if cfg.URL == "" {
+		return nil, nil
+	}
+	token := os.Getenv(jiraTokenEnv)
+	if token == "" {
+		store := &credentials.Store{Path: a.cfg.CredentialsFile}
+		encrypted, err := store.EncryptedSecret(a.cfg.Profile, jiraService)
+		if err != nil {
+			return nil, err
+		}
+		if encrypted == "" {
+			return nil, fmt.Errorf("%w: jira.url is set but there is no Jira token; run fomo auth jira or set %s", errMissingInput, jiraTokenEnv)
+		}
+		passphrase := os.Getenv(config.EnvPassphrase)
+		if passphrase == "" && !a.noPrompt {
+			passphrase = promptSecret(i18n.T("Passphrase for the Jira token of profile %s: ", a.cfg.Profile))
+		}
+		if passphrase == "" {
+			return nil, fmt.Errorf("%w: the Jira token of profile %s is locked; set %s", errMissingInput, a.cfg.Profile, config.EnvPassphrase)
+		}
+		a.redactor.Add(passphrase)
+		if token, err = credentials.Decrypt(encrypted, passphrase); err != nil {
+			return nil, fmt.Errorf("failed to unlock the Jira token of profile %s: %w", a.cfg.Profile, err)
+		}
+	}
+	a.redactor.Add(token)
+	return &jiraClient{baseURL: strings.TrimRight(cfg.URL, "/"), user: cfg.User, token: token}, nil
+}
+
+// issues looks up issues by key, eight at a time. Keys that name no issue
+// the token can see are left out.
+func (j *jiraClient) issues(ctx context.Context, keys []string) (map[string]jiraIssue, error) {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		sem      = make(chan struct{}, 8)
+		issues   = map[string]jiraIssue{}
+		firstErr error
+	)
+	for _, key := range keys {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			issue, err := j.issue(ctx, key)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err != nil && firstErr == nil:
+				firstErr = err
+			case issue != nil:
+				issues[key] = *issue
+			}
+		}()
+	}
+	wg.Wait()
+	return issues, firstErr
+}
+
+// issue returns an issue, or nil if there is none by that key.
+func (j *jiraClient) issue(ctx context.Context, key string) (*jiraIssue, error) {
+	// Version 3 is Jira Cloud's current API; Server and Data Center only
+	// have version 2
+	version := "2"
+	if j.user != "" {
+		version = "3"
+	}
+	endpoint := fmt.Sprintf("%s/rest/api/%s/issue/%s?fields=summary,status", j.baseURL, version, url.PathEscape(key))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if j.user != "" {
+		req.SetBasicAuth(j.user, j.token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+j.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, nil
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, fmt.Errorf("Jira refused the token (%s); check jira.user and the token", resp.Status)
+	case resp.StatusCode >= 300:
+		return nil, fmt.Errorf("Jira answered %s for %s", resp.Status, key)
+	}
+	var answer struct {
+		Key    string `json:"key"`
+		Fields struct {
+			Summary string `json:"summary"`
+			Status  struct {
+				Name string `json:"name"`
+			} `json:"status"`
+		} `json:"fields"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+		return nil, fmt.Errorf("failed to read Jira issue %s: %w", key, err)
+	}
+	return &jiraIssue{Key: answer.Key, Summary: answer.Fields.Summary, Status: answer.Fields.Status.Name}, nil
+}
+
+func runAuthJira(a *app, args []string) error {
+	fs := a.newFlagSet("auth jira", "")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	// Only the profile name and file locations are needed here
+	cfg, err := (&config.Loader{Flags: a.flags, Profile: a.profile}).Load()
+	if err != nil {
+		return err
+	}
+	token := promptSecret(i18n.T("Jira API token: "))
+	if token == "" {
+		return errAborted
+	}
+	a.redactor.Add(token)
+
+	store := &credentials.Store{Path: cfg.CredentialsFile}
+	if existing, err := store.EncryptedSecret(cfg.Profile, jiraService); err != nil {
+		return err
+	} else if existing != "" && !confirm(i18n.T("Profile %s already has a stored Jira token. Replace it?", cfg.Profile)) {
+		return errAborted
+	}
+	passphrase := a.newPassphrase()
+	if passphrase == "" {
+		return errAborted
+	}
+	if err := store.SaveSecret(cfg.Profile, jiraService, token, passphrase); err != nil {
+		return fmt.Errorf("failed to save the Jira token: %w", err)
+	}
+	a.infof("Saved the Jira token for profile %s in %s\n", cfg.Profile, cfg.CredentialsFile)
+	if cfg.Jira.URL == "" {
+		a.warnf("Set jira.url in the config file for fomo to use it.\n")
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+
+	"fomo/internal/config"
+	"fomo/internal/i18n"
+)
+
+// readUserFile reads the user config file for the settings that apply
+// before any command runs. A missing or broken file reads as empty; the
+// commands that load the configuration report it.
+func readUserFile() config.File {
+	var file config.File
+	if path, err := config.UserConfigPath(os.Getenv); err == nil {
+		config.ReadFile(path, &file)
+	}
+	return file
+}
+
+// setLocale selects the language of prompts and hints: FOMO_LANG, then
+// the locale in the user config file, then the system locale.
+func setLocale(configured string) {
+	locale := os.Getenv(i18n.EnvLang)
+	if locale == "" {
+		locale = configured
+	}
+	if locale == "" {
+		locale = i18n.SystemLocale(os.Getenv)
+	}
+	i18n.Set(locale)
+}
+
+// errorHint suggests what to do about an error with the given exit code.
+func errorHint(code int) string {
+	switch code {
+	case exitAuth:
+		return i18n.T("Hint: check that your PAT is valid, has not expired and has the scopes this command needs; fomo config show tells where it came from.\n")
+	case exitNotFound:
+		return i18n.T("Hint: check the organization, project and pipeline names; fomo config show lists the ones in use.\n")
+	case exitThrottled:
+		return i18n.T("Hint: Azure DevOps is rate limiting your requests; wait a minute and try again.\n")
+	}
+	return ""
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"fomo/internal/client"
+)
+
+// logOptions are the global logging flags. Logs are for the long-running
+// modes (daemon, servers) and for debugging; commands print their
+// human-oriented output with infof and warnf instead.
+type logOptions struct {
+	level  string
+	format string
+	file   string
+}
+
+// setupLog creates the app's logger. It writes to stderr, or appends to
+// --log-file, through the redactor. The returned function closes the log
+// file.
+func (a *app) setupLog(opts logOptions) (func(), error) {
+	level := slog.LevelInfo
+	if a.quiet {
+		level = slog.LevelWarn
+	}
+	if opts.level != "" {
+		if err := level.UnmarshalText([]byte(opts.level)); err != nil {
+			return nil, newUsageError("--log-level must be debug, info, warn or error")
+		}
+	}
+
+	w, closeLog := a.stderr, func() {}
+	if opts.file != "" {
+		f, err := os.OpenFile(opts.file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+		w, closeLog = a.redactor.Writer(f), func() { f.Close() }
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(opts.format) {
+	case "", "text":
+		handler = slog.NewTextHandler(w, handlerOpts)
+	case "json":
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	default:
+		closeLog()
+		return nil, newUsageError("--log-format must be text or json")
+	}
+	a.log = slog.New(handler)
+	return closeLog, nil
+}
+
+// logTransport logs every API request at debug level.
+type logTransport struct {
+	next http.RoundTripper
+	log  *slog.Logger
+}
+
+func (t *logTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	attrs := []any{"method", req.Method, "url", req.URL.Redacted(), "duration", time.Since(start).Round(time.Millisecond)}
+	if err != nil {
+		t.log.Debug("api request failed", append(attrs, "err", err)...)
+		return nil, err
+	}
+	t.log.Debug("api request", append(attrs, "status", resp.StatusCode)...)
+	return resp, nil
+}
+
+// driftLog warns about the response fields that differ from fomo's types,
+// each once per invocation, for --strict.
+type driftLog struct {
+	log  *slog.Logger
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+func (l *driftLog) report(d client.Drift) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fresh := func(fields []string) []string {
+		var out []string
+		for _, f := range fields {
+			if !l.seen[f] {
+				l.seen[f] = true
+				out = append(out, f)
+			}
+		}
+		return out
+	}
+	if missing := fresh(d.Missing); len(missing) > 0 {
+		l.log.Warn("response lacks fields fomo reads; they will show as empty", "request", d.Request, "fields", strings.Join(missing, ","))
+	}
+	if unexpected := fresh(d.Unexpected); len(unexpected) > 0 {
+		l.log.Warn("response has fields fomo does not read", "request", d.Request, "fields", strings.Join(unexpected, ","))
+	}
+}
+
+// logRequests logs every request a server handles at debug level.
+func logRequests(log *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		log.Debug("http request", "method", r.Method, "path", r.URL.Path, "status", rec.status, "duration", time.Since(start).Round(time.Millisecond))
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"fomo/internal/client"
+	"fomo/internal/diff"
+)
+
+var (
+	// logTimestamp is the time the agent puts in front of every log line
+	logTimestamp = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?Z ?`)
+	// anyTimestamp is a date and time anywhere in a line, as tools print them
+	anyTimestamp = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`)
+)
+
+// normalizeLog drops what differs between runs on every line, so a diff of
+// two logs shows what changed: the agent's line timestamps and the times
+// tools print.
+func normalizeLog(text string) string {
+	text = strings.TrimPrefix(text, "\uFEFF")
+	lines := strings.SplitAfter(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		line = logTimestamp.ReplaceAllString(line, "")
+		lines[i] = anyTimestamp.ReplaceAllString(line, "<time>")
+	}
+	return strings.Join(lines, "")
+}
+
+// findStep resolves a step of a run by display name, within the job named
+// job unless that is empty.
+func findStep(timeline *client.Timeline, step, job string) (*client.TimelineRecord, error) {
+	records := map[string]*client.TimelineRecord{}
+	for i := range timeline.Records {
+		records[timeline.Records[i].ID] = &timeline.Records[i]
+	}
+	var matches []*client.TimelineRecord
+	var jobs, steps []string
+	for i, record := range timeline.Records {
+		if record.Type != client.RecordTask {
+			continue
+		}
+		jobName := ""
+		if parent, ok := records[record.ParentID]; ok {
+			jobName = parent.Name
+		}
+		if !strings.EqualFold(record.Name, step) {
+			if !slices.Contains(steps, record.Name) {
+				steps = append(steps, record.Name)
+			}
+			continue
+		}
+		if job != "" && !strings.EqualFold(jobName, job) {
+			continue
+		}
+		matches = append(matches, &timeline.Records[i])
+		jobs = append(jobs, jobName)
+	}
+	switch {
+	case len(matches) == 1:
+		return matches[0], nil
+	case len(matches) > 1:
+		return nil, newUsageError(fmt.Sprintf("step %q runs in several jobs (%s); pick one with --job", step, strings.Join(jobs, ", ")))
+	case job != "":
+		return nil, newUsageError(fmt.Sprintf("job %q has no step %q", job, step))
+	}
+	sort.Strings(steps)
+	return nil, newUsageError(fmt.Sprintf("no step %q (steps: %s)", step, strings.Join(steps, ", ")))
+}
+
+// stepLog downloads the log of a step of a run.
+func stepLog(ctx context.Context, c *client.Client, runID int, step, job string) (string, error) {
+	build, err := c.GetBuild(ctx, runID)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch run %d: %w", runID, err)
+	}
+	timeline, err := c.GetTimeline(ctx, runID)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch timeline of run %d: %w", runID, err)
+	}
+	record, err := findStep(timeline, step, job)
+	if err != nil {
+		return "", fmt.Errorf("run %d: %w", runID, err)
+	}
+	if record.Log == nil {
+		return "", fmt.Errorf("step %q of run %d has no log (%s)", record.Name, runID, record.State)
+	}
+	text, err := c.GetLogContent(ctx, build.Definition.ID, runID, record.Log.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to download the %s log of run %d: %w", record.Name, runID, err)
+	}
+	return text, nil
+}
+
+func runLogsDownload(a *app, args []string) error {
+	fs := a.newFlagSet("logs download", "<run-id> [--output FILE]")
+	output := fs.String("output", "", "file to save the zip to (default run-ID-logs.zip)")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return newUsageError("usage: fomo logs download <run-id> [--output FILE]")
+	}
+	runID, err := parseRunID(positional[0])
+	if err != nil {
+		return err
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	path := *output
+	if path == "" {
+		path = fmt.Sprintf("run-%d-logs.zip", runID)
+	}
+	if err := downloadZip(a.ctx, a, c, c.LogsZipURL(runID), path, fmt.Sprintf("the logs of run %d", runID)); err != nil {
+		return err
+	}
+	if err := checkZip(path); err != nil {
+		return fmt.Errorf("%s is damaged: %w; delete it and download it again", path, err)
+	}
+	a.resultf("%s\n", path)
+	return nil
+}
+
+func runLogsDiff(a *app, args []string) error {
+	fs := a.newFlagSet("logs diff", "<run-a> <run-b> --step NAME [--job NAME] [--raw]")
+	step := fs.String("step", "", "display name of the step whose logs to compare")
+	job := fs.String("job", "", "job the step runs in, when it runs in several")
+	raw := fs.Bool("raw", false, "compare the logs as they are, timestamps included")
+	noPager := fs.Bool("no-pager", false, "do not pipe the diff through $PAGER")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 || *step == "" {
+		return newUsageError("usage: fomo logs diff <run-a> <run-b> --step NAME [--job NAME] [--raw]")
+	}
+	runA, err := parseRunID(positional[0])
+	if err != nil {
+		return err
+	}
+	runB, err := parseRunID(positional[1])
+	if err != nil {
+		return err
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	logA, err := stepLog(ctx, c, runA, *step, *job)
+	if err != nil {
+		return err
+	}
+	logB, err := stepLog(ctx, c, runB, *step, *job)
+	if err != nil {
+		return err
+	}
+	if !*raw {
+		logA, logB = normalizeLog(logA), normalizeLog(logB)
+	}
+
+	text := diff.Unified(fmt.Sprintf("%d/%s", runA, *step), fmt.Sprintf("%d/%s", runB, *step), logA, logB)
+	if text == "" {
+		a.infof("The %s logs of runs %d and %d are the same.\n", *step, runA, runB)
+		return nil
+	}
+	color := isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == "" && !a.plain
+	return a.page(*noPager, func(w io.Writer) error {
+		_, err := io.WriteString(w, colorDiff(text, color))
+		return err
+	})
+}
@@ -2,143 +2,282 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"io/ioutil"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime/debug"
 	"strings"
+	"syscall"
+	"time"
+
+	"fomo/internal/cache"
+	"fomo/internal/client"
+	"fomo/internal/config"
+	"fomo/internal/i18n"
+	"fomo/internal/redact"
 )
 
 const (
-	baseURL = "https://dev.azure.com"
-	patEnv  = "AZURE_DEVOPS_PAT" // Environment variable for storing PAT
-)
+	patEnv = config.EnvPAT // Environment variable for storing PAT
 
-type Pipeline struct {
-	ID   int    `json:"id"`
-	Name string `json:"name"`
-}
+	plainEnv       = "FOMO_PLAIN"    // Turn on --plain by default
+	recordEnv      = "FOMO_RECORD"   // Record API responses as fixtures
+	replayEnv      = "FOMO_REPLAY"   // Serve API responses from fixtures
+	fixturesDirEnv = "FOMO_FIXTURES" // Fixture directory, testdata/fixtures by default
+)
 
-type PipelinesResponse struct {
-	Count     int        `json:"count"`
-	Pipelines []Pipeline `json:"value"`
+func promptUser(prompt string) string {
+	reader := bufio.NewReader(os.Stdin)
+	// Prompts go to stderr so stdout only ever carries results
+	fmt.Fprint(os.Stderr, prompt)
+	input, _ := reader.ReadString('\n')
+	return strings.TrimSpace(input)
 }
 
-func getPipelines(organization, project, pat string) ([]Pipeline, error) {
-	url := fmt.Sprintf("%s/%s/%s/_apis/pipelines?api-version=7.0", baseURL, organization, project)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	// Add PAT as Basic Auth header
-	req.SetBasicAuth("", pat)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+// loadConfig resolves the layered configuration once per invocation,
+// prompting for anything that was not supplied.
+func (a *app) loadConfig() (*config.Config, error) {
+	if a.cfg != nil {
+		return a.cfg, nil
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch pipelines, status: %s", resp.Status)
+	loader := &config.Loader{Flags: a.flags, Profile: a.profile, PAT: a.pat}
+	if !a.noPrompt {
+		loader.Prompt = promptUser
+		loader.PromptSecret = promptSecret
+		loader.Passphrase = func(label string) string {
+			passphrase := promptSecret(label)
+			a.redactor.Add(passphrase)
+			return passphrase
+		}
 	}
-
-	body, err := ioutil.ReadAll(resp.Body)
+	a.redactor.Add(os.Getenv(config.EnvPassphrase))
+	cfg, err := loader.Load()
 	if err != nil {
 		return nil, err
 	}
+	a.redactor.Add(cfg.PAT)
+	a.warnConfig(cfg)
 
-	var pipelinesResponse PipelinesResponse
-	if err := json.Unmarshal(body, &pipelinesResponse); err != nil {
-		return nil, err
+	// Offer to keep a freshly entered PAT, encrypted with a passphrase
+	if cfg.Sources["pat"] == config.SourcePrompt && cfg.PAT != "" {
+		if err := a.savePAT(cfg); err != nil {
+			return nil, fmt.Errorf("failed to save PAT: %w", err)
+		}
 	}
 
-	return pipelinesResponse.Pipelines, nil
+	a.cfg = cfg
+	return cfg, nil
 }
 
-func promptUser(prompt string) string {
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Print(prompt)
-	input, _ := reader.ReadString('\n')
-	return strings.TrimSpace(input)
+// warnConfig reports the settings of shared config files that were
+// ignored.
+func (a *app) warnConfig(cfg *config.Config) {
+	for _, warning := range cfg.Warnings {
+		a.warnf("Warning: %s\n", warning)
+	}
 }
 
-func persistPATToShell(pat string) error {
-	// Determine the user's home directory
-	homeDir, err := os.UserHomeDir()
+// newClient returns an API client for the resolved organization and project.
+func (a *app) newClient() (*client.Client, error) {
+	cfg, err := a.loadConfig()
 	if err != nil {
-		return fmt.Errorf("failed to get home directory: %v", err)
+		return nil, err
 	}
-
-	// Determine which shell RC file to write to
-	rcFile := fmt.Sprintf("%s/.bashrc", homeDir) // Change to .zshrc for zsh users
-	if shell := os.Getenv("SHELL"); strings.Contains(shell, "zsh") {
-		rcFile = fmt.Sprintf("%s/.zshrc", homeDir)
+	if action := a.pendingWrite; action != "" && !a.dryRun {
+		a.pendingWrite = ""
+		if err := a.checkWrite(action); err != nil {
+			return nil, err
+		}
 	}
 
-	// Check if the file already contains the PAT
-	existingLines, err := ioutil.ReadFile(rcFile)
-	if err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to read shell config file: %v", err)
+	// Validate inputs
+	if cfg.Organization == "" || cfg.Project == "" || cfg.PAT == "" {
+		return nil, fmt.Errorf("%w: all inputs (organization, project, PAT) are required", errMissingInput)
 	}
 
-	if strings.Contains(string(existingLines), fmt.Sprintf("export %s=", patEnv)) {
-		return nil // PAT already exists
+	c := client.New(cfg.Organization, cfg.Project, cfg.PAT)
+	if cfg.BaseURL != "" {
+		c.BaseURL = cfg.BaseURL
 	}
 
-	// Append the PAT to the shell RC file
-	f, err := os.OpenFile(rcFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to open shell config file: %v", err)
+	// Record or replay API fixtures for end-to-end testing
+	dir := os.Getenv(fixturesDirEnv)
+	if dir == "" {
+		dir = filepath.Join("testdata", "fixtures")
 	}
-	defer f.Close()
-
-	_, err = f.WriteString(fmt.Sprintf("\n# Added by Azure DevOps PAT setup\nexport %s=%s\n", patEnv, pat))
-	if err != nil {
-		return fmt.Errorf("failed to write to shell config file: %v", err)
+	switch {
+	case os.Getenv(recordEnv) == "1":
+		c.HTTPClient.Transport = &client.FixtureTransport{Mode: client.FixtureRecord, Dir: dir, Secrets: []string{cfg.PAT}, Organization: cfg.Organization}
+	case os.Getenv(replayEnv) == "1":
+		c.HTTPClient.Transport = &client.FixtureTransport{Mode: client.FixtureReplay, Dir: dir, Organization: cfg.Organization}
 	}
 
-	fmt.Printf("PAT saved to %s. Restart your terminal or run `source %s` to apply the changes.\n", rcFile, rcFile)
-	return nil
+	// Crash reports list every request, even those answered offline
+	c.Middleware = append(c.Middleware, func(next http.RoundTripper) http.RoundTripper {
+		return &recordTransport{next: next, recorder: a.requests}
+	})
+	if a.log.Enabled(context.Background(), slog.LevelDebug) {
+		c.Middleware = append(c.Middleware, func(next http.RoundTripper) http.RoundTripper {
+			return &logTransport{next: next, log: a.log}
+		})
+	}
+	if a.metrics != nil {
+		c.Middleware = append(c.Middleware, a.metrics.Middleware)
+	}
+	if a.cached != nil {
+		if a.cached.store == nil {
+			if a.cached.store, err = cache.Open("offline"); err != nil {
+				return nil, err
+			}
+		}
+		c.Middleware = append(c.Middleware, a.cached.wrap)
+	}
+	if a.strict {
+		if a.drift == nil {
+			a.drift = &driftLog{log: a.log, seen: map[string]bool{}}
+		}
+		c.OnDrift = a.drift.report
+	}
+	return c, nil
 }
 
-func main() {
-	// Prompt for inputs interactively
-	organization := promptUser("Enter your Azure DevOps organization: ")
-	project := promptUser("Enter your Azure DevOps project: ")
-
-	// Check if PAT exists in the environment
-	pat := os.Getenv(patEnv)
-	if pat == "" {
-		// Prompt the user for PAT if not already set
-		pat = promptUser("Enter your Azure DevOps PAT: ")
-
-		// Persist the PAT in the shell configuration file
-		if err := persistPATToShell(pat); err != nil {
-			log.Fatalf("Error saving PAT to shell: %v", err)
+func run(args []string) (code int) {
+	redactor := &redact.Redactor{}
+	a := &app{stdout: redactor.Writer(os.Stdout), stderr: redactor.Writer(os.Stderr), redactor: redactor, requests: &requestRecorder{}}
+
+	// The first interrupt cancels the command; once it is canceled, another
+	// one kills fomo, in case it waits on something that ignores ctx
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+	a.ctx = ctx
+
+	// Count the command once its exit code is final, after any crash
+	var command string
+	defer func() {
+		if command != "" {
+			a.recordTelemetry(command, code)
+		}
+	}()
+
+	// Crash reports go through the redactor too: a stack trace can carry
+	// request headers or a resolved config
+	argv := args
+	defer func() {
+		if r := recover(); r != nil {
+			code = exitError
+			stack := debug.Stack()
+			path, err := a.writeCrashReport(r, stack, argv)
+			if err != nil {
+				fmt.Fprintf(a.stderr, "panic: %v\n\n%s", r, stack)
+				return
+			}
+			fmt.Fprint(a.stderr, i18n.T("fomo crashed: %v\n\nA crash report was written to %s\nPlease review it and attach it to a bug report.\n", r, path))
+		}
+	}()
+
+	userFile := readUserFile()
+	setLocale(userFile.Locale)
+	fs := flag.NewFlagSet("fomo", flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.StringVar(&a.flags.Organization, "org", "", "Azure DevOps organization")
+	fs.StringVar(&a.flags.Project, "project", "", "Azure DevOps project")
+	fs.StringVar(&a.profile, "profile", "", "profile from the user config file")
+	fs.BoolVar(&a.quiet, "quiet", false, "only print IDs and results")
+	fs.BoolVar(&a.quiet, "q", false, "shorthand for --quiet")
+	fs.BoolVar(&a.offline, "offline", false, "show cached data instead of calling Azure DevOps (pipelines list, runs list, stats)")
+	timings := fs.Bool("timings", false, "report the API calls, bytes and latency of the command on stderr")
+	fs.BoolVar(&a.allowWrite, "allow-write", false, "allow commands that make changes when the config turns on safe mode")
+	fs.BoolVar(&a.strict, "strict", false, "warn when API responses have missing or unexpected fields")
+	fs.BoolVar(&a.plain, "plain", os.Getenv(plainEnv) == "1", "linear, labeled output for screen readers (default from "+plainEnv+")")
+	tz := fs.String("tz", userFile.Timezone, "time zone to show times in: local, UTC or an IANA name such as Europe/Madrid")
+	durations := fs.String("durations", userFile.Durations, "how to show durations: human (1h) or iso (PT1H30M)")
+	var logOpts logOptions
+	fs.StringVar(&logOpts.level, "log-level", "", "log level: debug, info, warn or error (default info, or warn with --quiet)")
+	fs.StringVar(&logOpts.format, "log-format", "text", "log format: text or json")
+	fs.StringVar(&logOpts.file, "log-file", "", "append logs to this file instead of stderr")
+	patStdin := fs.Bool("pat-stdin", false, "read the PAT from stdin")
+	patFile := fs.String("pat-file", "", "read the PAT from a file")
+	fs.Usage = func() {
+		printCommands(a.stderr, "", commands())
+		fmt.Fprintln(a.stderr, "\nGlobal flags:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
 		}
+		return exitUsage
 	}
+	closeLog, err := a.setupLog(logOpts)
+	if err != nil {
+		fmt.Fprint(a.stderr, i18n.T("Error: %v\n", err))
+		return exitCode(err)
+	}
+	defer closeLog()
+	if a.display, err = newDisplay(*tz, *durations); err != nil {
+		fmt.Fprint(a.stderr, i18n.T("Error: %v\n", err))
+		return exitCode(err)
+	}
+	pat, err := readPAT(*patStdin, *patFile)
+	if err != nil {
+		fmt.Fprint(a.stderr, i18n.T("Error: %v\n", err))
+		return exitCode(err)
+	}
+	a.pat = pat
+	redactor.Add(pat)
 
-	// Validate inputs
-	if organization == "" || project == "" || pat == "" {
-		log.Fatal("All inputs (organization, project, PAT) are required.")
+	// Without a command, keep the original behavior of listing pipelines
+	args = fs.Args()
+	if len(args) == 0 {
+		args = []string{"pipelines", "list"}
 	}
+	command = commandPath(commands(), args)
+	a.askTelemetryConsent(command)
 
-	// Fetch pipelines
-	pipelines, err := getPipelines(organization, project, pat)
-	if err != nil {
-		log.Fatalf("Error fetching pipelines: %v", err)
+	if *timings {
+		a.metrics = &client.Metrics{}
+	}
+	if offlineCommands[command] {
+		a.cached = &offlineCache{offline: a.offline, display: a.display}
+	}
+	start := time.Now()
+	if a.offline && a.cached == nil {
+		err = offlineUsage(command)
+	} else {
+		err = dispatch(a, commands(), "", args)
+	}
+	if *timings {
+		a.printTimings(time.Since(start))
+	}
+	// Shown even with --quiet: scripts should know the data may be old
+	if a.cached != nil {
+		if since := a.cached.staleSince(); !since.IsZero() {
+			fmt.Fprint(a.stderr, i18n.T("Offline: stale as of %s (%s ago).\n", a.display.time(since, time.DateTime), a.display.duration(time.Since(since))))
+		}
+	}
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return exitOK
 	}
 
-	// Display pipelines
-	fmt.Println("Azure DevOps Pipelines:")
-	for _, pipeline := range pipelines {
-		fmt.Printf("ID: %d, Name: %s\n", pipeline.ID, pipeline.Name)
+	// Plugins report their own errors; just pass their status through
+	var pluginErr *pluginExitError
+	if errors.As(err, &pluginErr) {
+		return pluginErr.code
 	}
+	fmt.Fprint(a.stderr, i18n.T("Error: %v\n", err))
+	code = exitCode(err)
+	a.warnf("%s", errorHint(code))
+	return code
 }
 
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runReplayed runs fomo against the recorded fixtures in testdata/fixtures
+// and returns its exit code, stdout and stderr.
+// fixturePAT is the PAT the replayed commands run with. It is not in a
+// format the redactor recognizes, so only knowing it keeps it masked.
+const fixturePAT = "fixture-s3cr3t-pat"
+
+func runReplayed(t *testing.T, args ...string) (code int, stdout, stderr string) {
+	t.Helper()
+	home := t.TempDir()
+	for key, value := range map[string]string{
+		replayEnv:          "1",
+		fixturesDirEnv:     filepath.Join("testdata", "fixtures"),
+		"HOME":             home,
+		"XDG_CACHE_HOME":   filepath.Join(home, "cache"),
+		"FOMO_CONFIG":      filepath.Join(home, "config.yaml"),
+		"FOMO_BASE_URL":    "",
+		"FOMO_PROFILE":     "",
+		"FOMO_PROJECT":     "",
+		"AZURE_DEVOPS_PAT": fixturePAT,
+	} {
+		t.Setenv(key, value)
+	}
+
+	// The fixtures were recorded in another organization
+	args = append([]string{"--org", "contoso", "--project", "web", "--tz", "UTC"}, args...)
+	stdout, stderr = captureOutput(t, func() { code = run(args) })
+	return code, stdout, stderr
+}
+
+// captureOutput returns what f writes to os.Stdout and os.Stderr.
+func captureOutput(t *testing.T, f func()) (stdout, stderr string) {
+	t.Helper()
+	capture := func(file **os.File) (restore func() string) {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		saved := *file
+		*file = w
+		out := make(chan string)
+		go func() {
+			data, _ := io.ReadAll(r)
+			out <- string(data)
+		}()
+		return func() string {
+			*file = saved
+			w.Close()
+			return <-out
+		}
+	}
+	restoreStdout := capture(&os.Stdout)
+	restoreStderr := capture(&os.Stderr)
+	f()
+	return restoreStdout(), restoreStderr()
+}
+
+func TestReplayFixtures(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{
+			args: []string{"pipelines", "list"},
+			want: []string{"ID: 12, Name: api-build", "ID: 15, Name: web-deploy"},
+		},
+		{
+			args: []string{"runs", "list", "--pipeline", "api-build"},
+			want: []string{
+				"4812  api-build  20240502.3  main           inProgress  2024-05-02 09:14:03",
+				"4810  api-build  20240502.2  feature/retry  failed      2024-05-02 08:01:00",
+				"4807  api-build  20240502.1  main           succeeded   2024-05-02 07:30:12",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			code, out, _ := runReplayed(t, tt.args...)
+			if code != exitOK {
+				t.Fatalf("exit code %d, want %d; output:\n%s", code, exitOK, out)
+			}
+			for _, line := range tt.want {
+				if !strings.Contains(out, line) {
+					t.Errorf("output lacks %q:\n%s", line, out)
+				}
+			}
+		})
+	}
+}
+
+func TestReplayIfChanged(t *testing.T) {
+	tests := []struct {
+		branch string
+		want   string
+	}{
+		// The matching file is on the second page of the diff
+		{"main", "Running api-build on main: services/api/handler.go changed since run 4807 (4f1c2a9)."},
+		// Azure DevOps stops listing changes before the last one
+		{"release", "Warning: Azure DevOps listed only 2 of the files changed since run 4807 (4f1c2a9); running api-build in case the others match."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.branch, func(t *testing.T) {
+			code, stdout, stderr := runReplayed(t, "run", "api-build", "--branch", tt.branch, "--if-changed", "services/api/**", "--list-params")
+			if code != exitOK {
+				t.Fatalf("exit code %d, want %d; stderr:\n%s", code, exitOK, stderr)
+			}
+			if out := stdout + stderr; !strings.Contains(out, tt.want) {
+				t.Errorf("output lacks %q:\n%s", tt.want, out)
+			}
+		})
+	}
+}
+
+func TestReplayRedactsPAT(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"success", []string{"pipelines", "list"}, exitOK},
+		{"success with debug logs", []string{"--log-level", "debug", "pipelines", "list"}, exitOK},
+		// The PAT is echoed in the error
+		{"error", []string{"runs", "list", "--pipeline", fixturePAT}, exitNotFound},
+		// The PAT is in the URLs that are logged and in the error
+		{"error with debug logs", []string{"--log-level", "debug", "run", "api-build", "--branch", fixturePAT, "--list-params"}, exitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := runReplayed(t, tt.args...)
+			if code != tt.code {
+				t.Fatalf("exit code %d, want %d; stderr:\n%s", code, tt.code, stderr)
+			}
+			if strings.Contains(stdout, fixturePAT) {
+				t.Errorf("stdout holds the PAT:\n%s", stdout)
+			}
+			if strings.Contains(stderr, fixturePAT) {
+				t.Errorf("stderr holds the PAT:\n%s", stderr)
+			}
+		})
+	}
+}
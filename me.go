@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"fomo/internal/client"
+)
+
+// Urgency groups of fomo me, most urgent first.
+const (
+	urgencyWaiting    = iota // someone is blocked on you
+	urgencyAttention         // your work failed or was sent back
+	urgencyInProgress        // your work is moving along
+	urgencyDone              // finished recently
+)
+
+var urgencyTitles = []string{
+	urgencyWaiting:    "Waiting on you",
+	urgencyAttention:  "Needs your attention",
+	urgencyInProgress: "In progress",
+	urgencyDone:       "Done recently",
+}
+
+// workItem is one entry of the "my work" view.
+type workItem struct {
+	urgency int
+	kind    string // "approval", "review", "pr" or "run"
+	project string
+	title   string
+	detail  string
+	url     string
+	time    time.Time
+}
+
+func runMe(a *app, args []string) error {
+	fs := a.newFlagSet("me", "[--since 7d] [--this-project]")
+	since := fs.String("since", "7d", "how far back to look for your runs, e.g. 24h, 7d or 2w")
+	thisProject := fs.Bool("this-project", false, "only look in the configured project instead of every project")
+	done := fs.Bool("done", false, "also list your runs that succeeded")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	age, err := parseAge(*since)
+	if err != nil {
+		return newUsageError(err.Error())
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	me, err := c.Me(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to resolve current user: %w", err)
+	}
+
+	projects := []string{c.Project}
+	if !*thisProject {
+		all, err := c.ListProjects(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list projects: %w", err)
+		}
+		projects = projects[:0]
+		for _, p := range all {
+			projects = append(projects, p.Name)
+		}
+	}
+
+	items, err := fetchMyWork(ctx, c, me, projects, time.Now().Add(-age))
+	if err != nil {
+		return err
+	}
+	printMyWork(a, items, *done)
+	return nil
+}
+
+// fetchMyWork collects the current user's work across projects
+// concurrently. Projects that fail are reported as long as others succeed.
+func fetchMyWork(ctx context.Context, c *client.Client, me *client.Identity, projects []string, since time.Time) ([]workItem, error) {
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		items []workItem
+		errs  []error
+		sem   = make(chan struct{}, 8)
+	)
+	for _, project := range projects {
+		wg.Add(1)
+		go func(pc *client.Client) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			found, err := fetchProjectWork(ctx, pc, me, since)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", pc.Project, err))
+				return
+			}
+			items = append(items, found...)
+		}(c.WithProject(project))
+	}
+	wg.Wait()
+
+	if len(errs) > 0 && len(items) == 0 {
+		return nil, errs[0]
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].urgency != items[j].urgency {
+			return items[i].urgency < items[j].urgency
+		}
+		// Waiting items oldest first, as they have blocked others the
+		// longest; everything else newest first
+		if items[i].urgency == urgencyWaiting {
+			return items[i].time.Before(items[j].time)
+		}
+		return items[i].time.After(items[j].time)
+	})
+	return items, nil
+}
+
+func fetchProjectWork(ctx context.Context, c *client.Client, me *client.Identity, since time.Time) ([]workItem, error) {
+	var items []workItem
+
+	// Approvals and reviews are the same as in the inbox
+	inbox, err := fetchProjectInbox(ctx, c, me, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range inbox {
+		items = append(items, workItem{urgency: urgencyWaiting, kind: item.kind, project: item.project, title: item.title, url: item.url, time: item.created})
+	}
+
+	prs, err := c.ListPullRequests(ctx, client.PullRequestCriteria{Status: "active", CreatorID: me.ID})
+	if err != nil {
+		return nil, err
+	}
+	for _, pr := range prs {
+		item := workItem{urgency: urgencyInProgress, kind: "pr", project: c.Project, title: fmt.Sprintf("!%d %s", pr.ID, pr.Title), url: pr.WebURL(), time: pr.CreationDate}
+		approvals := 0
+		for _, r := range pr.Reviewers {
+			switch {
+			case r.ID == me.ID:
+			case r.Vote == client.VoteRejected:
+				item.urgency, item.detail = urgencyAttention, "rejected by "+r.DisplayName
+			case r.Vote == client.VoteWaitingForAuthor && item.urgency != urgencyAttention:
+				item.urgency, item.detail = urgencyAttention, r.DisplayName+" is waiting for you"
+			case r.Vote >= client.VoteApprovedWithSuggestion:
+				approvals++
+			}
+		}
+		switch {
+		case pr.MergeStatus == "conflicts":
+			item.urgency, item.detail = urgencyAttention, "merge conflicts"
+		case item.urgency == urgencyAttention:
+		case pr.IsDraft:
+			item.detail = "draft"
+		case approvals > 0:
+			item.detail = fmt.Sprintf("%d approval(s)", approvals)
+		default:
+			item.detail = "awaiting review"
+		}
+		items = append(items, item)
+	}
+
+	builds, err := c.ListBuilds(ctx, client.BuildCriteria{RequestedFor: me.ID, MinTime: since, Top: 100})
+	if err != nil {
+		return nil, err
+	}
+	// Only the latest run of each pipeline and branch matters: a failure
+	// fixed by a later run needs no attention
+	seen := map[string]bool{}
+	for _, b := range builds {
+		key := fmt.Sprintf("%d %s", b.Definition.ID, b.SourceBranch)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		item := workItem{kind: "run", project: c.Project, title: fmt.Sprintf("%s %s on %s", b.Definition.Name, b.BuildNumber, buildBranch(b)), time: b.QueueTime}
+		if b.Links.Web != nil {
+			item.url = b.Links.Web.Href
+		}
+		switch {
+		case b.Status != "completed":
+			item.urgency, item.detail = urgencyInProgress, b.Status
+		case b.Result == client.RunResultSucceeded:
+			item.urgency, item.detail = urgencyDone, b.Result
+		default:
+			item.urgency, item.detail = urgencyAttention, b.Result
+		}
+		if b.FinishTime != nil {
+			item.time = *b.FinishTime
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func printMyWork(a *app, items []workItem, done bool) {
+	if a.quiet {
+		for _, item := range items {
+			if item.urgency == urgencyDone && !done {
+				continue
+			}
+			a.resultf("%s\t%s\t%s\t%s\n", urgencyTitles[item.urgency], item.kind, item.project, item.url)
+		}
+		return
+	}
+
+	shown, hidden := 0, 0
+	for urgency, title := range urgencyTitles {
+		var group []workItem
+		for _, item := range items {
+			if item.urgency == urgency {
+				group = append(group, item)
+			}
+		}
+		if len(group) == 0 {
+			continue
+		}
+		if urgency == urgencyDone && !done {
+			hidden = len(group)
+			continue
+		}
+		if shown > 0 {
+			a.resultf("\n")
+		}
+		shown++
+		a.resultf("%s (%d)\n", title, len(group))
+		for _, item := range group {
+			line := fmt.Sprintf("  %-8s  %-16s  %-5s  %s", item.kind, item.project, a.display.duration(time.Since(item.time)), item.title)
+			if item.detail != "" {
+				line += " - " + item.detail
+			}
+			a.resultf("%s\n", line)
+		}
+	}
+	if shown == 0 {
+		a.resultf("Nothing in flight and nothing waiting on you.\n")
+	}
+	if hidden > 0 {
+		a.infof("\n%d of your runs succeeded; list them with --done.\n", hidden)
+	}
+}
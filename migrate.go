@@ -0,0 +1,375 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"text/tabwriter"
+
+	"fomo/internal/client"
+	"fomo/internal/i18n"
+)
+
+// targetPATEnv holds the PAT for the target organization when it differs
+// from the source one.
+const targetPATEnv = "FOMO_TARGET_PAT"
+
+// Migration results, as shown in the report.
+const (
+	migrated      = "migrated"
+	migrateExists = "exists"
+	migrateManual = "manual"
+	migrateFailed = "failed"
+	migrateDryRun = "dry run"
+)
+
+// migrationItem is one line of the migration report.
+type migrationItem struct {
+	Kind   string
+	Name   string
+	Result string
+	Detail string
+}
+
+type migration struct {
+	a      *app
+	src    *client.Client
+	dst    *client.Client
+	dryRun bool
+	report []migrationItem
+
+	// groupIDs maps source variable group IDs to their target IDs
+	groupIDs map[int]int
+}
+
+func (m *migration) add(kind, name, result, detail string) {
+	m.report = append(m.report, migrationItem{Kind: kind, Name: name, Result: result, Detail: detail})
+}
+
+func runMigrate(a *app, args []string) error {
+	const usage = "usage: fomo migrate --from ORG/PROJECT --to ORG/PROJECT [--pipelines GLOB] [--no-environments] [--dry-run]"
+	fs := a.newFlagSet("migrate", "--from ORG/PROJECT --to ORG/PROJECT [--pipelines GLOB]")
+	from := fs.String("from", "", "source organization and project")
+	to := fs.String("to", "", "target organization and project")
+	pattern := fs.String("pipelines", "*", "glob selecting the pipelines to migrate by name")
+	noEnvironments := fs.Bool("no-environments", false, "do not copy environments")
+	dryRun := fs.Bool("dry-run", false, "report what would be migrated without creating anything")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	fromOrg, fromProject, ok1 := strings.Cut(*from, "/")
+	toOrg, toProject, ok2 := strings.Cut(*to, "/")
+	if !ok1 || !ok2 || fromOrg == "" || fromProject == "" || toOrg == "" || toProject == "" {
+		return newUsageError(usage)
+	}
+	if _, err := path.Match(*pattern, ""); err != nil {
+		return newUsageError(fmt.Sprintf("invalid --pipelines pattern: %v", err))
+	}
+	a.dryRun = *dryRun
+
+	a.flags.Organization, a.flags.Project = fromOrg, fromProject
+	src, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	dst := src.WithOrganization(toOrg, toProject)
+	if pat := os.Getenv(targetPATEnv); pat != "" {
+		dst.PAT = pat
+		a.redactor.Add(pat)
+	}
+	ctx := a.ctx
+
+	m := &migration{a: a, src: src, dst: dst, dryRun: *dryRun, groupIDs: map[int]int{}}
+
+	definitions, err := src.ListDefinitions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list pipelines in %s: %w", *from, err)
+	}
+	var selected []map[string]any
+	for _, ref := range definitions {
+		if ok, _ := path.Match(*pattern, ref.Name); !ok {
+			continue
+		}
+		definition, err := src.GetDefinitionRaw(ctx, ref.ID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch pipeline %s: %w", ref.Name, err)
+		}
+		selected = append(selected, definition)
+	}
+	if len(selected) == 0 {
+		return fmt.Errorf("no pipelines in %s match %q", *from, *pattern)
+	}
+
+	if err := m.variableGroups(ctx, selected); err != nil {
+		return err
+	}
+	if !*noEnvironments {
+		if err := m.environments(ctx); err != nil {
+			return err
+		}
+	}
+	if err := m.definitions(ctx, selected); err != nil {
+		return err
+	}
+
+	failed := m.printReport()
+	if failed > 0 {
+		return fmt.Errorf("%d item(s) could not be migrated", failed)
+	}
+	return nil
+}
+
+// variableGroups copies the variable groups referenced by the selected
+// definitions, asking for the values of secret variables.
+func (m *migration) variableGroups(ctx context.Context, definitions []map[string]any) error {
+	referenced := map[int]bool{}
+	for _, definition := range definitions {
+		groups, _ := definition["variableGroups"].([]any)
+		for _, g := range groups {
+			if group, ok := g.(map[string]any); ok {
+				if id, ok := group["id"].(float64); ok {
+					referenced[int(id)] = true
+				}
+			}
+		}
+	}
+	if len(referenced) == 0 {
+		return nil
+	}
+
+	sourceGroups, err := m.src.ListVariableGroups(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list variable groups: %w", err)
+	}
+	targetGroups, err := m.dst.ListVariableGroups(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list variable groups in the target project: %w", err)
+	}
+	project, err := m.dst.GetProject(ctx, m.dst.Project)
+	if err != nil {
+		return fmt.Errorf("failed to look up the target project: %w", err)
+	}
+
+	for _, group := range sourceGroups {
+		if !referenced[group.ID] {
+			continue
+		}
+		if target := findVariableGroup(targetGroups, group.Name); target != nil {
+			m.groupIDs[group.ID] = target.ID
+			m.add("variable group", group.Name, migrateExists, "already in the target project; variables not compared")
+			continue
+		}
+		if group.Type == client.VariableGroupAzureKeyVault {
+			m.add("variable group", group.Name, migrateManual, "linked to Key Vault; recreate it with a service connection in the target organization")
+			continue
+		}
+
+		var secrets []string
+		variables := map[string]client.GroupVariable{}
+		for name, v := range group.Variables {
+			if v.IsSecret {
+				secrets = append(secrets, name)
+				if !m.dryRun {
+					value := m.secretValue(group.Name, name)
+					v.Value = &value
+				}
+			}
+			variables[name] = v
+		}
+		detail := ""
+		if len(secrets) > 0 {
+			detail = fmt.Sprintf("%d secret(s) entered at the prompt", len(secrets))
+		}
+		if m.dryRun {
+			m.add("variable group", group.Name, migrateDryRun, detail)
+			continue
+		}
+
+		created, err := m.dst.CreateVariableGroup(ctx, client.VariableGroup{
+			Name:        group.Name,
+			Description: group.Description,
+			Type:        group.Type,
+			Variables:   variables,
+			References: []client.VariableGroupReference{{
+				Name:             group.Name,
+				Description:      group.Description,
+				ProjectReference: client.ProjectReference{ID: project.ID, Name: project.Name},
+			}},
+		})
+		if err != nil {
+			m.add("variable group", group.Name, migrateFailed, err.Error())
+			continue
+		}
+		m.groupIDs[group.ID] = created.ID
+		m.add("variable group", group.Name, migrated, detail)
+	}
+	return nil
+}
+
+// secretValue prompts for the value of a secret variable, which the API
+// never returns. An empty answer leaves the secret unset.
+func (m *migration) secretValue(group, name string) string {
+	if m.a.noPrompt {
+		return ""
+	}
+	value := promptSecret(i18n.T("Value for secret %s in variable group %s (empty to skip): ", name, group))
+	m.a.redactor.Add(value)
+	return value
+}
+
+func findVariableGroup(groups []client.VariableGroup, name string) *client.VariableGroup {
+	for i := range groups {
+		if strings.EqualFold(groups[i].Name, name) {
+			return &groups[i]
+		}
+	}
+	return nil
+}
+
+// environments creates the source project's environments in the target.
+// Only names and descriptions are copied: resources and checks are tied to
+// the source organization.
+func (m *migration) environments(ctx context.Context) error {
+	sourceEnvs, err := m.src.ListEnvironments(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list environments: %w", err)
+	}
+	targetEnvs, err := m.dst.ListEnvironments(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list environments in the target project: %w", err)
+	}
+
+	for _, env := range sourceEnvs {
+		exists := false
+		for _, t := range targetEnvs {
+			if strings.EqualFold(t.Name, env.Name) {
+				exists = true
+				break
+			}
+		}
+		switch {
+		case exists:
+			m.add("environment", env.Name, migrateExists, "already in the target project")
+		case m.dryRun:
+			m.add("environment", env.Name, migrateDryRun, "")
+		default:
+			if _, err := m.dst.CreateEnvironment(ctx, env.Name, env.Description); err != nil {
+				m.add("environment", env.Name, migrateFailed, err.Error())
+				continue
+			}
+			m.add("environment", env.Name, migrated, "approvals, checks and resources must be added by hand")
+		}
+	}
+	return nil
+}
+
+// definitions creates the selected pipeline definitions in the target,
+// pointing them at the migrated variable groups.
+func (m *migration) definitions(ctx context.Context, definitions []map[string]any) error {
+	existing, err := m.dst.ListDefinitions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list pipelines in the target project: %w", err)
+	}
+	queues, err := m.dst.ListQueues(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list agent queues in the target project: %w", err)
+	}
+
+	for _, definition := range definitions {
+		name, _ := definition["name"].(string)
+		folder, _ := definition["path"].(string)
+		if definitionExists(existing, folder, name) {
+			m.add("pipeline", name, migrateExists, "a pipeline with this name already exists in "+folderOrRoot(folder))
+			continue
+		}
+
+		for _, field := range volatileDefinitionFields {
+			delete(definition, field)
+		}
+		notes, err := retargetDefinition(ctx, m.dst, definition, queues)
+		if err != nil {
+			m.add("pipeline", name, migrateFailed, err.Error())
+			continue
+		}
+		notes = append(notes, m.remapVariableGroups(definition)...)
+		for _, secret := range secretVariables(definition) {
+			notes = append(notes, fmt.Sprintf("set a value for secret variable %s", secret))
+		}
+
+		result := migrated
+		if len(notes) > 0 {
+			result = migrateManual
+		}
+		if m.dryRun {
+			result = migrateDryRun
+		} else if _, err := m.dst.CreateDefinitionRaw(ctx, definition); err != nil {
+			m.add("pipeline", name, migrateFailed, err.Error())
+			continue
+		}
+		m.add("pipeline", name, result, strings.Join(notes, "; "))
+	}
+	return nil
+}
+
+// remapVariableGroups replaces source variable group IDs with their target
+// counterparts, dropping references to groups that were not migrated.
+func (m *migration) remapVariableGroups(definition map[string]any) []string {
+	groups, _ := definition["variableGroups"].([]any)
+	if len(groups) == 0 {
+		return nil
+	}
+	var notes []string
+	var kept []any
+	for _, g := range groups {
+		group, ok := g.(map[string]any)
+		if !ok {
+			continue
+		}
+		id, _ := group["id"].(float64)
+		name, _ := group["name"].(string)
+		target, ok := m.groupIDs[int(id)]
+		if !ok && !m.dryRun {
+			notes = append(notes, fmt.Sprintf("link variable group %s", name))
+			continue
+		}
+		kept = append(kept, map[string]any{"id": target, "name": name})
+	}
+	definition["variableGroups"] = kept
+	return notes
+}
+
+// printReport prints the migration report and returns the number of
+// failures.
+func (m *migration) printReport() int {
+	failed := 0
+	if m.a.quiet {
+		for _, item := range m.report {
+			if item.Result == migrateFailed || item.Result == migrateManual {
+				m.a.resultf("%s\t%s\t%s\n", item.Kind, item.Name, item.Result)
+			}
+			if item.Result == migrateFailed {
+				failed++
+			}
+		}
+		return failed
+	}
+
+	counts := map[string]int{}
+	w := tabwriter.NewWriter(m.a.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tNAME\tRESULT\tDETAIL")
+	for _, item := range m.report {
+		counts[item.Result]++
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", item.Kind, item.Name, item.Result, item.Detail)
+	}
+	w.Flush()
+
+	fmt.Fprintf(m.a.stdout, "\n%d migrated, %d already present, %d need manual follow-up, %d failed",
+		counts[migrated], counts[migrateExists], counts[migrateManual], counts[migrateFailed])
+	if m.dryRun {
+		fmt.Fprintf(m.a.stdout, ", %d would be migrated", counts[migrateDryRun])
+	}
+	fmt.Fprintln(m.a.stdout)
+	return counts[migrateFailed]
+}
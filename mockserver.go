@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"fomo/internal/config"
+	"fomo/internal/mockserver"
+)
+
+func runMockServer(a *app, args []string) error {
+	fs := a.newFlagSet("mock-server", "[flags]")
+	addr := fs.String("addr", "127.0.0.1:8080", "address to listen on")
+	seed := fs.Int64("seed", 1, "seed for the generated data")
+	pipelines := fs.Int("pipelines", 5, "number of pipelines to generate")
+	runs := fs.Int("runs", 10, "number of historical runs per pipeline")
+	duration := fs.Duration("run-duration", 30*time.Second, "how long queued runs take to complete")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	// The mock serves any org/project given, defaulting to demo/demo
+	organization := a.flags.Organization
+	if organization == "" {
+		organization = "demo"
+	}
+	project := a.flags.Project
+	if project == "" {
+		project = "demo"
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", *addr, err)
+	}
+	baseURL := "http://" + listener.Addr().String()
+
+	server := mockserver.New(mockserver.Options{
+		Organization: organization,
+		Project:      project,
+		Seed:         *seed,
+		Pipelines:    *pipelines,
+		RunsPerPipe:  *runs,
+		RunDuration:  *duration,
+	})
+
+	a.infof("Mock Azure DevOps serving %s/%s at %s\n", organization, project, baseURL)
+	a.infof("Point fomo at it with:\n  export %s=%s %s=%s %s=%s %s=mock\n",
+		config.EnvBaseURL, baseURL, config.EnvOrganization, organization, config.EnvProject, project, config.EnvPAT)
+	a.resultf("%s\n", baseURL)
+	return http.Serve(listener, logRequests(a.log, server.Handler(baseURL)))
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"fomo/internal/cache"
+	"fomo/internal/client"
+)
+
+// offlineCommands are the read commands that keep the answers they get and
+// can show them again with --offline.
+var offlineCommands = map[string]bool{
+	"pipelines list":      true,
+	"runs list":           true,
+	"stats critical-path": true,
+	"stats stages":        true,
+}
+
+// cachedResponse is an answer to a GET request as kept for offline use.
+type cachedResponse struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"contentType,omitempty"`
+	Body        string `json:"body"`
+}
+
+// offlineCache keeps the successful answers to GET requests. Offline, it
+// serves them instead of going to the network, and remembers the oldest
+// one served so the command's output can be marked stale.
+type offlineCache struct {
+	store   *cache.Cache
+	offline bool
+	display display // for the time in the hint when the network is down
+
+	mu     sync.Mutex
+	oldest time.Time
+}
+
+// wrap is the client.Middleware of the cache.
+func (t *offlineCache) wrap(next http.RoundTripper) http.RoundTripper {
+	return client.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return t.roundTrip(next, req)
+	})
+}
+
+func (t *offlineCache) roundTrip(next http.RoundTripper, req *http.Request) (*http.Response, error) {
+	key := req.Method + " " + req.URL.String()
+	if t.offline {
+		if req.Method != http.MethodGet {
+			return nil, fmt.Errorf("cannot %s offline", req.Method)
+		}
+		var cached cachedResponse
+		stored, ok := t.store.Get(key, &cached)
+		if !ok {
+			return nil, fmt.Errorf("nothing cached; run the command once without --offline")
+		}
+		t.mu.Lock()
+		if t.oldest.IsZero() || stored.Before(t.oldest) {
+			t.oldest = stored
+		}
+		t.mu.Unlock()
+		header := http.Header{client.CacheHeader: {"1"}}
+		if cached.ContentType != "" {
+			header.Set("Content-Type", cached.ContentType)
+		}
+		return &http.Response{
+			Status:     fmt.Sprintf("%d %s", cached.Status, http.StatusText(cached.Status)),
+			StatusCode: cached.Status,
+			Header:     header,
+			Body:       io.NopCloser(strings.NewReader(cached.Body)),
+			Request:    req,
+		}, nil
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		var cached cachedResponse
+		if stored, ok := t.store.Get(key, &cached); ok && req.Method == http.MethodGet {
+			return nil, fmt.Errorf("%w; --offline shows the copy cached at %s", err, t.display.time(stored, time.DateTime))
+		}
+		return nil, err
+	}
+	if req.Method != http.MethodGet || resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	// A full disk should not fail the command
+	t.store.Put(key, cachedResponse{Status: resp.StatusCode, ContentType: resp.Header.Get("Content-Type"), Body: string(body)})
+	return resp, nil
+}
+
+// staleSince is when the oldest answer served offline was cached, or zero
+// if none was.
+func (t *offlineCache) staleSince() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.oldest
+}
+
+// offlineUsage explains which commands work with --offline.
+func offlineUsage(command string) error {
+	return newUsageError(fmt.Sprintf("%s needs the network; --offline works with %s", command, strings.Join(slices.Sorted(maps.Keys(offlineCommands)), ", ")))
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"fomo/internal/client"
+	"fomo/internal/yaml"
+)
+
+// orchestrationPlan declares pipelines to run in dependency order, as read
+// from the file given to orchestrate --plan:
+//
+//	steps:
+//	  - name: build
+//	    pipeline: api-build
+//	    params: {configuration: release}
+//	  - name: deploy
+//	    pipeline: api-deploy
+//	    needs: [build]
+//	    params:
+//	      image: $(build.imageTag)
+//
+// A parameter value can refer to the output variables of a step it needs
+// as $(step.variable).
+type orchestrationPlan struct {
+	Steps []*planStep `yaml:"steps"`
+}
+
+type planStep struct {
+	Name     string            `yaml:"name"`
+	Pipeline string            `yaml:"pipeline"`
+	Branch   string            `yaml:"branch"`
+	Needs    []string          `yaml:"needs"`
+	Params   map[string]string `yaml:"params"`
+
+	status   string
+	pipeline *client.Pipeline
+	run      *client.Run
+	outputs  map[string]client.TimelineVariable
+	err      error
+}
+
+// The statuses of a plan step.
+const (
+	stepWaiting   = "waiting"
+	stepRunning   = "running"
+	stepSucceeded = "succeeded"
+	stepFailed    = "failed"
+	stepSkipped   = "skipped"
+)
+
+// outputReference is $(step.variable) in a parameter value.
+var outputReference = regexp.MustCompile(`\$\(([A-Za-z_][\w-]*)\.([\w.-]+)\)`)
+
+// loadPlan reads and validates a plan, and returns its steps in waves that
+// can run together.
+func loadPlan(path string) (*orchestrationPlan, [][]*planStep, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var plan orchestrationPlan
+	if err := yaml.Unmarshal(data, &plan); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if len(plan.Steps) == 0 {
+		return nil, nil, fmt.Errorf("%s declares no steps", path)
+	}
+	names := map[string]bool{}
+	for i, s := range plan.Steps {
+		switch {
+		case s.Name == "" || s.Pipeline == "":
+			return nil, nil, fmt.Errorf("%s: step %d needs a name and a pipeline", path, i+1)
+		case names[s.Name]:
+			return nil, nil, fmt.Errorf("%s: there are two steps named %s", path, s.Name)
+		}
+		names[s.Name] = true
+	}
+	for _, s := range plan.Steps {
+		for _, need := range s.Needs {
+			if !names[need] || need == s.Name {
+				return nil, nil, fmt.Errorf("%s: step %s needs %s, which is not another step", path, s.Name, need)
+			}
+		}
+		for _, value := range s.Params {
+			for _, m := range outputReference.FindAllStringSubmatch(value, -1) {
+				// $(Build.BuildId) and the like are left to Azure DevOps
+				if names[m[1]] && !slices.Contains(s.Needs, m[1]) {
+					return nil, nil, fmt.Errorf("%s: step %s uses the outputs of %s, so it must need it", path, s.Name, m[1])
+				}
+			}
+		}
+		s.status = stepWaiting
+	}
+	waves, err := planWaves(plan.Steps)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &plan, waves, nil
+}
+
+// planWaves groups steps so that each step comes after every step it
+// needs. It fails if steps need each other in a cycle.
+func planWaves(steps []*planStep) ([][]*planStep, error) {
+	placed := map[string]bool{}
+	var waves [][]*planStep
+	for len(placed) < len(steps) {
+		var wave []*planStep
+		for _, s := range steps {
+			if !placed[s.Name] && !slices.ContainsFunc(s.Needs, func(need string) bool { return !placed[need] }) {
+				wave = append(wave, s)
+			}
+		}
+		if len(wave) == 0 {
+			var cycle []string
+			for _, s := range steps {
+				if !placed[s.Name] {
+					cycle = append(cycle, s.Name)
+				}
+			}
+			return nil, fmt.Errorf("steps %s need each other", strings.Join(cycle, ", "))
+		}
+		for _, s := range wave {
+			placed[s.Name] = true
+		}
+		waves = append(waves, wave)
+	}
+	return waves, nil
+}
+
+// findOutput looks up an output variable by its full name or by its last
+// parts, as long as only one variable ends with them.
+func findOutput(outputs map[string]client.TimelineVariable, name string) (string, client.TimelineVariable, error) {
+	if v, ok := outputs[name]; ok {
+		return name, v, nil
+	}
+	var matches []string
+	for full := range outputs {
+		if strings.HasSuffix(strings.ToLower(full), "."+strings.ToLower(name)) {
+			matches = append(matches, full)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", client.TimelineVariable{}, fmt.Errorf("no output variable %s", name)
+	case 1:
+		return matches[0], outputs[matches[0]], nil
+	}
+	slices.Sort(matches)
+	return "", client.TimelineVariable{}, fmt.Errorf("%s is ambiguous: it could be %s", name, strings.Join(matches, ", "))
+}
+
+// stepParams fills in the outputs of the steps a step needs.
+func stepParams(s *planStep, steps map[string]*planStep) (map[string]string, error) {
+	params := map[string]string{}
+	var err error
+	for name, value := range s.Params {
+		params[name] = outputReference.ReplaceAllStringFunc(value, func(ref string) string {
+			m := outputReference.FindStringSubmatch(ref)
+			from, ok := steps[m[1]]
+			if !ok || err != nil {
+				return ref
+			}
+			full, v, lookupErr := findOutput(from.outputs, m[2])
+			switch {
+			case lookupErr != nil:
+				err = fmt.Errorf("parameter %s: %s: %w", name, from.Name, lookupErr)
+			case v.IsSecret:
+				err = fmt.Errorf("parameter %s: %s.%s is secret and cannot be passed on", name, from.Name, full)
+			}
+			return v.Value
+		})
+	}
+	return params, err
+}
+
+// stepEvent reports a step that was queued or finished.
+type stepEvent struct {
+	step     *planStep
+	finished bool
+}
+
+func runOrchestrate(a *app, args []string) error {
+	fs := a.newFlagSet("orchestrate", "--plan FILE [--branch NAME] [--keep-going] [--dry-run]")
+	planFile := fs.String("plan", "", "YAML file declaring the pipelines to run and what each needs")
+	branch := fs.String("branch", "", "branch for steps that name none (default: the checked-out branch, else each pipeline's default branch)")
+	keepGoing := fs.Bool("keep-going", false, "after a failure, still start the steps that do not depend on it")
+	dryRun := fs.Bool("dry-run", false, "show the order the steps would run in without running them")
+	interval := fs.Duration("interval", 10*time.Second, "how often to check running steps")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 || *planFile == "" {
+		return newUsageError("usage: fomo orchestrate --plan FILE [--branch NAME] [--keep-going] [--dry-run]")
+	}
+	a.dryRun = *dryRun
+	plan, waves, err := loadPlan(*planFile)
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "WAVE\tSTEP\tPIPELINE\tNEEDS")
+		for i, wave := range waves {
+			for _, s := range wave {
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", i+1, s.Name, s.Pipeline, orDash(strings.Join(s.Needs, ", ")))
+			}
+		}
+		return w.Flush()
+	}
+	if *branch == "" {
+		*branch = currentGitBranch()
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	// Resolve every pipeline up front, so a typo fails before anything runs
+	steps := map[string]*planStep{}
+	for _, s := range plan.Steps {
+		if s.pipeline, err = resolvePipeline(ctx, c, s.Pipeline); err != nil {
+			return fmt.Errorf("step %s: %w", s.Name, err)
+		}
+		steps[s.Name] = s
+	}
+
+	events := make(chan stepEvent)
+	running, failures := 0, 0
+	for {
+		// Waves are in dependency order, so skips cascade in one pass
+		for _, wave := range waves {
+			for _, s := range wave {
+				if s.status != stepWaiting {
+					continue
+				}
+				blocked := ""
+				for _, need := range s.Needs {
+					if status := steps[need].status; status == stepFailed || status == stepSkipped {
+						blocked = need
+						break
+					}
+				}
+				switch {
+				case blocked != "":
+					s.status = stepSkipped
+					a.infof("%s %s skipped: %s did not succeed\n", a.display.time(time.Now(), time.TimeOnly), s.Name, blocked)
+				case failures > 0 && !*keepGoing:
+					s.status = stepSkipped
+					a.infof("%s %s skipped after an earlier failure\n", a.display.time(time.Now(), time.TimeOnly), s.Name)
+				case !slices.ContainsFunc(s.Needs, func(need string) bool { return steps[need].status != stepSucceeded }):
+					params, err := stepParams(s, steps)
+					if err != nil {
+						s.status, s.err = stepFailed, err
+						failures++
+						a.warnf("Failed to start %s: %v\n", s.Name, err)
+						continue
+					}
+					s.status = stepRunning
+					running++
+					go runPlanStep(ctx, c, s, a.pipelineBranch(s.pipeline, *branch), params, *interval, events)
+				}
+			}
+		}
+		if running == 0 {
+			break
+		}
+
+		e := <-events
+		s, now := e.step, a.display.time(time.Now(), time.TimeOnly)
+		switch {
+		case !e.finished:
+			a.infof("%s %s started: %s #%s\n", now, s.Name, s.pipeline.Name, s.run.Name)
+			continue
+		case s.err != nil:
+			s.status = stepFailed
+			a.warnf("%s %s failed: %v\n", now, s.Name, s.err)
+		case s.run.Result != client.RunResultSucceeded:
+			s.status = stepFailed
+			a.infof("%s %s %s: %s #%s after %s\n", now, s.Name, s.run.Result, s.pipeline.Name, s.run.Name, a.display.duration(s.run.FinishedDate.Sub(s.run.CreatedDate)))
+		default:
+			s.status = stepSucceeded
+			a.infof("%s %s succeeded: %s #%s after %s\n", now, s.Name, s.pipeline.Name, s.run.Name, a.display.duration(s.run.FinishedDate.Sub(s.run.CreatedDate)))
+		}
+		running--
+		if s.status == stepFailed {
+			failures++
+			if running > 0 && !*keepGoing {
+				a.infof("Waiting for %d running steps to finish; no more will start.\n", running)
+			}
+		}
+	}
+
+	if ctx.Err() != nil {
+		cancelSteps(a, c, plan.Steps)
+	}
+
+	a.infof("\n")
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	if !a.quiet {
+		fmt.Fprintln(w, "STEP\tPIPELINE\tRUN\tRESULT\tDURATION")
+	}
+	unsuccessful := 0
+	for _, wave := range waves {
+		for _, s := range wave {
+			run, result, duration := "-", s.status, "-"
+			if s.run != nil {
+				run = fmt.Sprint(s.run.ID)
+				if s.run.State == client.RunStateCompleted {
+					result = s.run.Result
+					duration = a.display.duration(s.run.FinishedDate.Sub(s.run.CreatedDate))
+				}
+			}
+			if s.status != stepSucceeded {
+				unsuccessful++
+			}
+			if a.quiet {
+				a.resultf("%s\t%s\t%s\n", s.Name, run, result)
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", s.Name, s.pipeline.Name, run, result, duration)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if unsuccessful > 0 {
+		a.infof("%d of %d steps did not succeed.\n", unsuccessful, len(plan.Steps))
+		return errPipelineFailed
+	}
+	return nil
+}
+
+// cancelSteps cancels the runs of the steps that were still running when
+// the orchestration was interrupted, so that nothing keeps deploying after
+// fomo stops following it.
+func cancelSteps(a *app, c *client.Client, steps []*planStep) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(a.ctx), 30*time.Second)
+	defer cancel()
+	for _, s := range steps {
+		if s.run == nil || s.run.State == client.RunStateCompleted {
+			continue
+		}
+		// The run may have finished since the step last checked it
+		if b, err := c.GetBuild(ctx, s.run.ID); err == nil && b.Status == client.RunStateCompleted {
+			continue
+		}
+		if err := c.CancelBuild(ctx, s.run.ID); err != nil {
+			a.warnf("Failed to cancel %s run %d: %v\n", s.Name, s.run.ID, err)
+			continue
+		}
+		a.infof("Canceled %s: %s #%s\n", s.Name, s.pipeline.Name, s.run.Name)
+	}
+}
+
+// runPlanStep queues a step's pipeline, waits for the run to finish and
+// collects its outputs, reporting to events as it goes.
+func runPlanStep(ctx context.Context, c *client.Client, s *planStep, branch string, params map[string]string, interval time.Duration, events chan<- stepEvent) {
+	if s.Branch != "" {
+		branch = s.Branch
+	}
+	request := runRequestForBranch(branch)
+	if len(params) > 0 {
+		request.TemplateParameters = params
+	}
+	run, err := c.RunPipeline(ctx, s.pipeline.ID, request)
+	if err != nil {
+		s.err = fmt.Errorf("failed to queue %s: %w", s.pipeline.Name, err)
+		events <- stepEvent{step: s, finished: true}
+		return
+	}
+	s.run = run
+	events <- stepEvent{step: s}
+
+	for run.State != client.RunStateCompleted {
+		select {
+		case <-ctx.Done():
+		case <-time.After(interval):
+		}
+		if run, err = c.GetRun(ctx, s.pipeline.ID, run.ID); err != nil {
+			s.err = fmt.Errorf("failed to check run %d: %w", s.run.ID, err)
+			events <- stepEvent{step: s, finished: true}
+			return
+		}
+	}
+	s.run = run
+	if run.Result == client.RunResultSucceeded {
+		if s.outputs, err = runOutputs(ctx, c, run.ID); err != nil {
+			s.err = fmt.Errorf("failed to read the outputs of run %d: %w", run.ID, err)
+		}
+	}
+	events <- stepEvent{step: s, finished: true}
+}
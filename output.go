@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"fomo/internal/client"
+	"fomo/internal/config"
+	"fomo/internal/redact"
+)
+
+// app carries the global options and I/O streams shared by every command.
+type app struct {
+	stdout io.Writer
+	stderr io.Writer
+	quiet  bool
+	log    *slog.Logger // see setupLog
+
+	// ctx is canceled on Ctrl-C or SIGTERM, so that commands stop what
+	// they are doing and fomo exits with exitCanceled
+	ctx context.Context
+
+	// plain asks for linear, labeled text for screen readers: no live
+	// repainting, block characters or color as the only signal
+	plain bool
+
+	display display // time zone and duration style, see newDisplay
+
+	// noPrompt makes missing settings an error instead of a prompt, for
+	// commands that run non-interactively
+	noPrompt bool
+
+	// Global flags; leaf commands may override org and project
+	flags   config.Settings
+	profile string
+	pat     string // read with --pat-stdin or --pat-file
+
+	cfg *config.Config
+
+	// redactor masks credentials in everything written to stdout and
+	// stderr; commands add the secrets they handle
+	redactor *redact.Redactor
+
+	// requests records recent API requests for crash reports
+	requests *requestRecorder
+
+	// offline serves the commands in offlineCommands from what they
+	// cached when last run online; cached is set for those commands
+	offline bool
+	cached  *offlineCache
+
+	// metrics counts the API requests of the command, for --timings and
+	// the daemon
+	metrics *client.Metrics
+
+	// strict reports responses that do not match fomo's types
+	strict bool
+	drift  *driftLog
+
+	// allowWrite lets commands make changes in safe mode; writing is set
+	// once a command asks to make one, see checkWrite. pendingWrite names
+	// a command marked writes until newClient checks it
+	allowWrite   bool
+	writing      bool
+	pendingWrite string
+
+	// dryRun is set once a command previews a change instead of making it,
+	// or finds it has none to make: it is then neither refused in safe
+	// mode nor put on the audit trail
+	dryRun bool
+}
+
+// infof prints non-essential, human-oriented output. It is suppressed by
+// --quiet so that scripts only see results.
+func (a *app) infof(format string, args ...any) {
+	if a.quiet {
+		return
+	}
+	fmt.Fprintf(a.stdout, format, args...)
+}
+
+// resultf prints essential output that is always shown, even with --quiet.
+func (a *app) resultf(format string, args ...any) {
+	fmt.Fprintf(a.stdout, format, args...)
+}
+
+// warnf prints warnings and hints to stderr unless --quiet is set.
+func (a *app) warnf(format string, args ...any) {
+	if a.quiet {
+		return
+	}
+	fmt.Fprintf(a.stderr, format, args...)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// envPager overrides $PAGER for fomo's output.
+const envPager = "FOMO_PAGER"
+
+// page writes long output through the user's pager when stdout is a
+// terminal, and straight to stdout otherwise.
+func (a *app) page(noPager bool, write func(w io.Writer) error) error {
+	pager := os.Getenv(envPager)
+	if pager == "" {
+		pager = os.Getenv("PAGER")
+	}
+	if pager == "" {
+		pager = "less -FRX"
+	}
+	args := strings.Fields(pager)
+	if noPager || a.quiet || !isTerminal(os.Stdout) || len(args) == 0 || args[0] == "cat" {
+		return write(a.stdout)
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		// No pager installed; print directly
+		return write(a.stdout)
+	}
+	err = write(a.redactor.Writer(stdin))
+	stdin.Close()
+	waitErr := cmd.Wait()
+	// Quitting the pager early closes the pipe; that is not an error
+	var pathErr *fs.PathError
+	if err != nil && !errors.As(err, &pathErr) {
+		return err
+	}
+	var exitErr *exec.ExitError
+	if waitErr != nil && !errors.As(waitErr, &exitErr) {
+		return waitErr
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fomo/internal/client"
+	"fomo/internal/scaffold"
+	"fomo/internal/watch"
+)
+
+// latestRunFetches bounds the concurrent requests of --with-latest.
+const latestRunFetches = 8
+
+func runPipelinesList(a *app, args []string) error {
+	fs := a.newFlagSet("pipelines list", "[--tag TAG]... [--with-latest]")
+	withLatest := fs.Bool("with-latest", false, "show the result, branch and finish time of each pipeline's latest run")
+	var tags tagFlags
+	fs.Var(&tags, "tag", "only list pipelines with this tag; repeat to require several")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+
+	// Fetch pipelines
+	var pipelines []client.Pipeline
+	if len(tags) > 0 {
+		pipelines, err = taggedPipelines(ctx, c, tags)
+	} else if pipelines, err = c.ListPipelines(ctx); err != nil {
+		err = fmt.Errorf("failed to fetch pipelines: %w", err)
+	}
+	if err != nil {
+		return err
+	}
+	var latest []latestRun
+	if *withLatest {
+		latest = fetchLatestRuns(ctx, c, pipelines)
+	}
+
+	// Display pipelines
+	a.infof("Azure DevOps Pipelines:\n")
+	for i, pipeline := range pipelines {
+		switch {
+		case a.quiet && latest != nil:
+			a.resultf("%d\t%s\n", pipeline.ID, latest[i].quiet())
+		case a.quiet:
+			a.resultf("%d\n", pipeline.ID)
+		case latest != nil:
+			a.resultf("ID: %d, Name: %s, Latest: %s\n", pipeline.ID, pipeline.Name, latest[i].describe(a.display))
+		default:
+			a.resultf("ID: %d, Name: %s\n", pipeline.ID, pipeline.Name)
+		}
+	}
+	return nil
+}
+
+// latestRun is the most recent run of a pipeline, nil if it never ran.
+type latestRun struct {
+	run *client.Run
+	err error
+}
+
+// fetchLatestRuns fetches the latest run of each pipeline concurrently. A
+// pipeline whose runs cannot be fetched shows the error without affecting
+// the others.
+func fetchLatestRuns(ctx context.Context, c *client.Client, pipelines []client.Pipeline) []latestRun {
+	latest := make([]latestRun, len(pipelines))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, latestRunFetches)
+	for i, p := range pipelines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			runs, err := c.ListRuns(ctx, p.ID)
+			if err != nil {
+				latest[i].err = err
+			} else if len(runs) > 0 {
+				latest[i].run = &runs[0]
+			}
+		}()
+	}
+	wg.Wait()
+	return latest
+}
+
+// describe reads like "succeeded on main, finished 2024-01-01 12:00".
+func (l latestRun) describe(d display) string {
+	switch {
+	case l.err != nil:
+		return "error: " + l.err.Error()
+	case l.run == nil:
+		return "no runs"
+	case l.run.State != client.RunStateCompleted:
+		return fmt.Sprintf("%s on %s, started %s", runStatus(l.run), watch.ShortBranch(l.run.Branch()), d.time(l.run.CreatedDate, "2006-01-02 15:04"))
+	}
+	return fmt.Sprintf("%s on %s, finished %s", runStatus(l.run), watch.ShortBranch(l.run.Branch()), d.time(l.run.FinishedDate, "2006-01-02 15:04"))
+}
+
+// quiet is the latest run as tab-separated status, branch and finish time
+// in RFC 3339, with - for anything missing.
+func (l latestRun) quiet() string {
+	switch {
+	case l.err != nil:
+		return "error\t-\t-"
+	case l.run == nil:
+		return "none\t-\t-"
+	case l.run.State != client.RunStateCompleted:
+		return runStatus(l.run) + "\t" + watch.ShortBranch(l.run.Branch()) + "\t-"
+	}
+	return runStatus(l.run) + "\t" + watch.ShortBranch(l.run.Branch()) + "\t" + l.run.FinishedDate.UTC().Format(time.RFC3339)
+}
+
+// resolvePipeline finds a pipeline by ID or exact name.
+func resolvePipeline(ctx context.Context, c *client.Client, nameOrID string) (*client.Pipeline, error) {
+	pipelines, err := c.ListPipelines(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pipelines: %w", err)
+	}
+	id, _ := strconv.Atoi(nameOrID)
+	for i, p := range pipelines {
+		if p.ID == id || p.Name == nameOrID {
+			return &pipelines[i], nil
+		}
+	}
+	return nil, &client.APIError{StatusCode: http.StatusNotFound, Status: "404 Not Found", Message: fmt.Sprintf("pipeline %q not found", nameOrID)}
+}
+
+// pipelineBranch is the branch to run p on: branch when one was given,
+// otherwise the one configured for p under branches, or "" for the
+// pipeline's own default branch.
+func (a *app) pipelineBranch(p *client.Pipeline, branch string) string {
+	if branch != "" || a.cfg == nil {
+		return branch
+	}
+	for key, configured := range a.cfg.Branches {
+		if strings.EqualFold(key, p.Name) || key == strconv.Itoa(p.ID) {
+			return configured
+		}
+	}
+	return ""
+}
+
+func runPipelinesCreate(a *app, args []string) error {
+	fs := a.newFlagSet("pipelines create", "--repo NAME [--yaml-path PATH] [--folder PATH] [--name NAME] [--from-template go|node|docker]")
+	repo := fs.String("repo", "", "Azure Repos Git repository holding the YAML file")
+	yamlPath := fs.String("yaml-path", "azure-pipelines.yml", "path of the YAML file in the repository")
+	folder := fs.String("folder", "", `pipeline folder, e.g. Team/Service`)
+	name := fs.String("name", "", "pipeline name; defaults to the repository name")
+	fromTemplate := fs.String("from-template", "", "write a starter YAML file from a template: "+strings.Join(scaffold.Names(), ", "))
+	force := fs.Bool("force", false, "overwrite an existing YAML file when scaffolding")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *repo == "" && *fromTemplate == "" {
+		return newUsageError("--repo or --from-template is required")
+	}
+
+	// Scaffold the YAML file locally
+	if *fromTemplate != "" {
+		content, err := scaffold.Template(*fromTemplate)
+		if err != nil {
+			return newUsageError(err.Error())
+		}
+		if _, err := os.Stat(*yamlPath); err == nil && !*force {
+			return fmt.Errorf("%s already exists, use --force to overwrite it", *yamlPath)
+		}
+		if dir := filepath.Dir(*yamlPath); dir != "." {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+		}
+		if err := os.WriteFile(*yamlPath, content, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %v", *yamlPath, err)
+		}
+		a.infof("Wrote %s from the %s template.\n", *yamlPath, *fromTemplate)
+		if *repo == "" {
+			a.infof("Commit and push it, then run `fomo pipelines create --repo <repo> --yaml-path %s`.\n", *yamlPath)
+			return nil
+		}
+		a.warnf("Make sure %s is pushed to the default branch of %s before the pipeline runs.\n", *yamlPath, *repo)
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+
+	repository, err := c.GetRepository(ctx, *repo)
+	if err != nil {
+		return fmt.Errorf("failed to find repository %s: %w", *repo, err)
+	}
+	if *name == "" {
+		*name = repository.Name
+	}
+
+	// The API expects backslash-separated folders rooted at \
+	request := client.CreatePipelineRequest{
+		Name:   *name,
+		Folder: `\` + strings.Trim(strings.ReplaceAll(*folder, "/", `\`), `\`),
+		Configuration: client.PipelineConfiguration{
+			Type:       "yaml",
+			Path:       filepath.ToSlash(*yamlPath),
+			Repository: client.PipelineRepositoryRef{ID: repository.ID, Name: repository.Name, Type: "azureReposGit"},
+		},
+	}
+	pipeline, err := c.CreatePipeline(ctx, request)
+	if err != nil {
+		return fmt.Errorf("failed to create pipeline: %w", err)
+	}
+
+	a.infof("Created pipeline %s in %s\n", pipeline.Name, request.Folder)
+	a.resultf("%d\n", pipeline.ID)
+	return nil
+}
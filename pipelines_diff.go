@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"fomo/internal/client"
+	"fomo/internal/diff"
+	"fomo/internal/watch"
+)
+
+func runPipelinesYAMLDiff(a *app, args []string) error {
+	const usage = "usage: fomo pipelines yaml-diff <pipeline> --compare BRANCH [--base BRANCH] [--param NAME=VALUE]... [--raw]"
+	fs := a.newFlagSet("pipelines yaml-diff", "<pipeline> --compare BRANCH [--base BRANCH] [--param NAME=VALUE]... [--raw]")
+	base := fs.String("base", "", "branch to compare against (default: the pipeline's default branch)")
+	compare := fs.String("compare", "", "branch with the changes, such as a pull request's source branch")
+	given := paramFlags{}
+	fs.Var(given, "param", "set a template parameter for the expansion, as name=value (repeatable)")
+	raw := fs.Bool("raw", false, "diff the pipeline's YAML file as written, without expanding templates")
+	noPager := fs.Bool("no-pager", false, "do not pipe the diff through $PAGER")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 || *compare == "" {
+		return newUsageError(usage)
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	p, err := resolvePipeline(ctx, c, positional[0])
+	if err != nil {
+		return err
+	}
+	*base = a.pipelineBranch(p, *base)
+
+	fetch := func(branch string) (string, error) {
+		return expandedYAML(ctx, c, p, branch, given)
+	}
+	if *raw {
+		definition, err := c.GetDefinitionRaw(ctx, p.ID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch pipeline definition: %w", err)
+		}
+		fetch = func(branch string) (string, error) {
+			return yamlFileContent(ctx, c, p, definition, branch)
+		}
+	}
+	before, err := fetch(*base)
+	if err != nil {
+		return err
+	}
+	after, err := fetch(*compare)
+	if err != nil {
+		return err
+	}
+
+	text := diff.Unified(p.Name+"@"+branchLabel(*base, "default"), p.Name+"@"+watch.ShortBranch(*compare), before, after)
+	if text == "" {
+		a.infof("The YAML of %s is the same on %s and %s.\n", p.Name, branchLabel(*base, "the default branch"), watch.ShortBranch(*compare))
+		return nil
+	}
+	color := isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == "" && !a.plain
+	return a.page(*noPager, func(w io.Writer) error {
+		_, err := io.WriteString(w, colorDiff(text, color))
+		return err
+	})
+}
+
+// branchLabel returns the short name of a branch, or fallback without one.
+func branchLabel(branch, fallback string) string {
+	if branch == "" {
+		return fallback
+	}
+	return watch.ShortBranch(branch)
+}
+
+// expandedYAML returns the YAML a run of a pipeline on branch would
+// execute, with templates expanded, or the default branch's if branch is
+// empty.
+func expandedYAML(ctx context.Context, c *client.Client, p *client.Pipeline, branch string, params map[string]string) (string, error) {
+	request := runRequestForBranch(branch)
+	if len(params) > 0 {
+		request.TemplateParameters = params
+	}
+	yaml, err := c.PreviewPipeline(ctx, p.ID, request)
+	if err != nil {
+		return "", fmt.Errorf("failed to expand the YAML of %s on %s: %w", p.Name, branchLabel(branch, "the default branch"), err)
+	}
+	return yaml, nil
+}
+
+// yamlFileContent returns a YAML pipeline's file on branch, as written.
+func yamlFileContent(ctx context.Context, c *client.Client, p *client.Pipeline, definition map[string]any, branch string) (string, error) {
+	process, _ := definition["process"].(map[string]any)
+	file, _ := process["yamlFilename"].(string)
+	repository, _ := definition["repository"].(map[string]any)
+	repositoryID, _ := repository["id"].(string)
+	if file == "" || repository["type"] != client.RepositoryTypeAzureRepos {
+		return "", fmt.Errorf("%s is not a YAML pipeline in Azure Repos; leave out --raw to diff its expanded YAML", p.Name)
+	}
+	if branch == "" {
+		branch, _ = repository["defaultBranch"].(string)
+	}
+	content, err := c.GetFileContent(ctx, repositoryID, file, branch)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s on %s: %w", strings.TrimPrefix(file, "/"), branchLabel(branch, "the default branch"), err)
+	}
+	return content, nil
+}
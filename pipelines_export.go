@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"fomo/internal/client"
+)
+
+// exportFormatVersion is bumped whenever the export file layout changes.
+const exportFormatVersion = 1
+
+// exportFile is the on-disk form of an exported pipeline definition.
+type exportFile struct {
+	FomoExport int `json:"fomoExport"`
+	Source     struct {
+		Organization string `json:"organization"`
+		Project      string `json:"project"`
+		ID           int    `json:"id"`
+	} `json:"source"`
+	Definition map[string]any `json:"definition"`
+}
+
+// volatileDefinitionFields are specific to one project and are dropped on
+// export; import recreates them.
+var volatileDefinitionFields = []string{"_links", "url", "uri", "id", "revision", "createdDate", "authoredBy", "project", "latestBuild", "latestCompletedBuild"}
+
+var unsafePathChars = regexp.MustCompile(`[<>:"|?*\x00-\x1f]`)
+
+// definitionFilePath maps a definition's folder and name to a relative file
+// path, e.g. \Team\Service + api -> Team/Service/api.json.
+func definitionFilePath(folder, name string) string {
+	parts := []string{}
+	for _, part := range strings.Split(strings.Trim(folder, `\`), `\`) {
+		if part != "" {
+			parts = append(parts, unsafePathChars.ReplaceAllString(part, "_"))
+		}
+	}
+	parts = append(parts, unsafePathChars.ReplaceAllString(strings.ReplaceAll(name, "/", "_"), "_")+".json")
+	return filepath.Join(parts...)
+}
+
+func runPipelinesExport(a *app, args []string) error {
+	fs := a.newFlagSet("pipelines export", "(--all | <pipeline>...) --out DIR")
+	all := fs.Bool("all", false, "export every pipeline in the project")
+	out := fs.String("out", "", "directory to write the definitions to")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if *out == "" || (*all == (len(positional) > 0)) {
+		return newUsageError("usage: fomo pipelines export (--all | <pipeline>...) --out DIR")
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+
+	definitions, err := c.ListDefinitions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list pipelines: %w", err)
+	}
+	if !*all {
+		var selected []client.DefinitionReference
+		for _, name := range positional {
+			p, err := resolvePipeline(ctx, c, name)
+			if err != nil {
+				return err
+			}
+			selected = append(selected, client.DefinitionReference{ID: p.ID, Name: p.Name})
+		}
+		definitions = selected
+	}
+
+	for _, ref := range definitions {
+		definition, err := c.GetDefinitionRaw(ctx, ref.ID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch pipeline %s: %w", ref.Name, err)
+		}
+
+		file := exportFile{FomoExport: exportFormatVersion, Definition: definition}
+		file.Source.Organization = c.Organization
+		file.Source.Project = c.Project
+		file.Source.ID = ref.ID
+		for _, field := range volatileDefinitionFields {
+			delete(definition, field)
+		}
+
+		folder, _ := definition["path"].(string)
+		path := filepath.Join(*out, definitionFilePath(folder, ref.Name))
+		data, err := json.MarshalIndent(file, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %v", path, err)
+		}
+		a.infof("Exported %s to %s\n", ref.Name, path)
+		if a.quiet {
+			a.resultf("%s\n", path)
+		}
+
+		for _, name := range secretVariables(definition) {
+			a.warnf("  Note: secret variable %s is exported without its value\n", name)
+		}
+	}
+	return nil
+}
+
+// secretVariables returns the names of a definition's secret variables,
+// whose values the API never returns.
+func secretVariables(definition map[string]any) []string {
+	variables, _ := definition["variables"].(map[string]any)
+	var names []string
+	for name, v := range variables {
+		if variable, ok := v.(map[string]any); ok && variable["isSecret"] == true {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func runPipelinesImport(a *app, args []string) error {
+	fs := a.newFlagSet("pipelines import", "--from DIR [--dry-run]")
+	from := fs.String("from", "", "directory written by fomo pipelines export")
+	dryRun := fs.Bool("dry-run", false, "show what would be imported without creating anything")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *from == "" {
+		return newUsageError("usage: fomo pipelines import --from DIR [--dry-run]")
+	}
+	a.dryRun = *dryRun
+
+	files, err := readExportDir(*from)
+	if err != nil {
+		return err
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+
+	existing, err := c.ListDefinitions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list pipelines: %w", err)
+	}
+	queues, err := c.ListQueues(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list agent queues: %w", err)
+	}
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var failed int
+	for _, path := range paths {
+		file := files[path]
+		name, _ := file.Definition["name"].(string)
+		folder, _ := file.Definition["path"].(string)
+
+		if definitionExists(existing, folder, name) {
+			a.infof("skip    %s: a pipeline with this name already exists in %s\n", name, folderOrRoot(folder))
+			continue
+		}
+
+		notes, err := retargetDefinition(ctx, c, file.Definition, queues)
+		if err != nil {
+			failed++
+			a.infof("fail    %s (%s): %v\n", name, path, err)
+			continue
+		}
+		for _, secret := range secretVariables(file.Definition) {
+			notes = append(notes, fmt.Sprintf("set a value for secret variable %s", secret))
+		}
+
+		if *dryRun {
+			a.infof("create  %s in %s (dry run)\n", name, folderOrRoot(folder))
+		} else {
+			created, err := c.CreateDefinitionRaw(ctx, file.Definition)
+			if err != nil {
+				failed++
+				a.infof("fail    %s: %v\n", name, err)
+				continue
+			}
+			a.infof("create  %s in %s\n", name, folderOrRoot(folder))
+			if id, ok := created["id"].(float64); ok && a.quiet {
+				a.resultf("%d\n", int(id))
+			}
+		}
+		for _, note := range notes {
+			a.infof("        todo: %s\n", note)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d pipeline(s) could not be imported", failed)
+	}
+	return nil
+}
+
+// readExportDir loads every export file under dir, keyed by path.
+func readExportDir(dir string) (map[string]exportFile, error) {
+	files := map[string]exportFile{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".json" {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var file exportFile
+		if err := json.Unmarshal(data, &file); err != nil || file.FomoExport == 0 {
+			return nil // not an export file
+		}
+		if file.FomoExport > exportFormatVersion {
+			return fmt.Errorf("%s was written by a newer fomo (format %d)", path, file.FomoExport)
+		}
+		files[path] = file
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no exported pipelines found in %s", dir)
+	}
+	return files, nil
+}
+
+// retargetDefinition points a definition's repository and agent queue at
+// their counterparts in the target project. It returns follow-up notes for
+// things that could not be mapped automatically.
+func retargetDefinition(ctx context.Context, c *client.Client, definition map[string]any, queues []client.AgentQueue) ([]string, error) {
+	var notes []string
+
+	if repo, ok := definition["repository"].(map[string]any); ok {
+		repoType, _ := repo["type"].(string)
+		repoName, _ := repo["name"].(string)
+		switch repoType {
+		case "TfsGit":
+			target, err := c.GetRepository(ctx, repoName)
+			if err != nil {
+				return nil, fmt.Errorf("repository %s does not exist in the target project", repoName)
+			}
+			repo["id"] = target.ID
+			delete(repo, "url")
+		default:
+			notes = append(notes, fmt.Sprintf("check the service connection of the %s repository %s", repoType, repoName))
+		}
+	}
+
+	if queue, ok := definition["queue"].(map[string]any); ok {
+		queueName, _ := queue["name"].(string)
+		var target *client.AgentQueue
+		for i := range queues {
+			if strings.EqualFold(queues[i].Name, queueName) {
+				target = &queues[i]
+				break
+			}
+		}
+		if target == nil {
+			return nil, fmt.Errorf("agent queue %s does not exist in the target project", queueName)
+		}
+		definition["queue"] = map[string]any{"id": target.ID, "name": target.Name, "pool": map[string]any{"id": target.Pool.ID, "name": target.Pool.Name}}
+	}
+	return notes, nil
+}
+
+func definitionExists(existing []client.DefinitionReference, folder, name string) bool {
+	for _, d := range existing {
+		if strings.EqualFold(d.Name, name) && strings.EqualFold(strings.Trim(d.Path, `\`), strings.Trim(folder, `\`)) {
+			return true
+		}
+	}
+	return false
+}
+
+func folderOrRoot(folder string) string {
+	if strings.Trim(folder, `\`) == "" {
+		return `\`
+	}
+	return folder
+}
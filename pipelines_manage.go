@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"fomo/internal/client"
+	"fomo/internal/i18n"
+	"fomo/internal/watch"
+)
+
+// recentActivityWindow is how recently a pipeline must have run for
+// destructive changes to require typing its name.
+const recentActivityWindow = 30 * 24 * time.Hour
+
+// pipelineActivity summarizes a pipeline's recent runs.
+type pipelineActivity struct {
+	lastRun    *client.Run
+	recentRuns int
+}
+
+func (p pipelineActivity) active() bool {
+	return p.lastRun != nil && time.Since(p.lastRun.CreatedDate) < recentActivityWindow
+}
+
+func fetchActivity(ctx context.Context, c *client.Client, pipelineID int) (pipelineActivity, error) {
+	runs, err := c.ListRuns(ctx, pipelineID)
+	if err != nil {
+		return pipelineActivity{}, fmt.Errorf("failed to fetch runs: %w", err)
+	}
+	var activity pipelineActivity
+	for i, run := range runs {
+		if activity.lastRun == nil || run.CreatedDate.After(activity.lastRun.CreatedDate) {
+			activity.lastRun = &runs[i]
+		}
+		if time.Since(run.CreatedDate) < recentActivityWindow {
+			activity.recentRuns++
+		}
+	}
+	return activity, nil
+}
+
+func printActivity(a *app, p *client.Pipeline, activity pipelineActivity) {
+	a.infof("Pipeline %d: %s\n", p.ID, p.Name)
+	if activity.lastRun == nil {
+		a.infof("  No runs recorded\n")
+		return
+	}
+	last := activity.lastRun
+	a.infof("  Last run:  %s on %s, %s ago (%s)\n", last.Name, watch.ShortBranch(last.Branch()),
+		a.display.duration(time.Since(last.CreatedDate)), runStatus(last))
+	a.infof("  Runs in the last 30 days: %d\n", activity.recentRuns)
+}
+
+// runStatus is the result of a finished run, or its state otherwise.
+func runStatus(run *client.Run) string {
+	if run.State == client.RunStateCompleted {
+		return run.Result
+	}
+	return run.State
+}
+
+// pipelineChange describes a change to a pipeline for confirmChange,
+// demanding the typed pipeline name when it ran recently.
+func pipelineChange(a *app, p *client.Pipeline, activity pipelineActivity, verb string) plannedChange {
+	change := plannedChange{verb: verb, target: p.Name}
+	if activity.active() {
+		a.warnf(i18n.T("%s ran %d times in the last 30 days.\n"), p.Name, activity.recentRuns)
+		change.typed = p.Name
+	}
+	return change
+}
+
+func runPipelinesDelete(a *app, args []string) error {
+	fs := a.newFlagSet("pipelines delete", "<pipeline> [--dry-run] [--yes]")
+	var flags changeFlags
+	flags.register(fs)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return newUsageError("usage: fomo pipelines delete <pipeline> [--dry-run] [--yes]")
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	p, err := resolvePipeline(ctx, c, positional[0])
+	if err != nil {
+		return err
+	}
+	activity, err := fetchActivity(ctx, c, p.ID)
+	if err != nil {
+		return err
+	}
+
+	printActivity(a, p, activity)
+	if ok, err := a.confirmChange(flags, pipelineChange(a, p, activity, i18n.T("delete pipeline"))); !ok {
+		return err
+	}
+
+	if err := c.DeleteDefinition(ctx, p.ID); err != nil {
+		return fmt.Errorf("failed to delete pipeline %s: %w", p.Name, err)
+	}
+	a.infof("Deleted pipeline %s\n", p.Name)
+	a.resultf("%d\n", p.ID)
+	return nil
+}
+
+func runPipelinesRename(a *app, args []string) error {
+	fs := a.newFlagSet("pipelines rename", "<pipeline> <new-name> [--dry-run] [--yes]")
+	var flags changeFlags
+	flags.register(fs)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 || positional[1] == "" {
+		return newUsageError("usage: fomo pipelines rename <pipeline> <new-name> [--dry-run] [--yes]")
+	}
+	newName := positional[1]
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	p, err := resolvePipeline(ctx, c, positional[0])
+	if err != nil {
+		return err
+	}
+	activity, err := fetchActivity(ctx, c, p.ID)
+	if err != nil {
+		return err
+	}
+
+	printActivity(a, p, activity)
+	a.infof("  New name: %s\n", newName)
+	if ok, err := a.confirmChange(flags, pipelineChange(a, p, activity, i18n.T("rename pipeline"))); !ok {
+		return err
+	}
+
+	definition, err := c.GetDefinitionRaw(ctx, p.ID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch pipeline definition: %w", err)
+	}
+	definition["name"] = newName
+	if _, err := c.UpdateDefinitionRaw(ctx, p.ID, definition); err != nil {
+		return fmt.Errorf("failed to rename pipeline %s: %w", p.Name, err)
+	}
+	a.infof("Renamed pipeline %s to %s\n", p.Name, newName)
+	a.resultf("%d\n", p.ID)
+	return nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"fomo/internal/client"
+)
+
+// sourceChecks bounds the concurrent repository and branch lookups of
+// pipelines stale.
+const sourceChecks = 8
+
+// stalePipeline is a pipeline without recent runs and what is wrong with
+// the sources it builds.
+type stalePipeline struct {
+	client.Definition
+	problems []string
+}
+
+func runPipelinesStale(a *app, args []string) error {
+	fs := a.newFlagSet("pipelines stale", "[--no-runs-since 180d] [--skip-sources]")
+	since := fs.String("no-runs-since", "180d", "list pipelines without runs in this window, e.g. 90d or 26w")
+	skipSources := fs.Bool("skip-sources", false, "do not check whether the repositories and branches still exist")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	window, err := parseAge(*since)
+	if err != nil {
+		return newUsageError(err.Error())
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	definitions, err := c.ListDefinitionDetails(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch pipelines: %w", err)
+	}
+
+	cutoff := time.Now().Add(-window)
+	var stale []stalePipeline
+	for _, d := range definitions {
+		if d.LatestBuild == nil || d.LatestBuild.QueueTime.Before(cutoff) {
+			stale = append(stale, stalePipeline{Definition: d})
+		}
+	}
+	// Never run first, then the longest idle
+	sort.SliceStable(stale, func(i, j int) bool {
+		return lastQueued(stale[i].Definition).Before(lastQueued(stale[j].Definition))
+	})
+	if !*skipSources {
+		checkSources(ctx, c, stale)
+	}
+
+	if a.quiet {
+		for _, p := range stale {
+			a.resultf("%d\n", p.ID)
+		}
+		return nil
+	}
+	if len(stale) == 0 {
+		a.infof("Every pipeline ran in the last %s.\n", *since)
+		return nil
+	}
+	a.infof("%d of %d pipelines have not run in the last %s:\n\n", len(stale), len(definitions), *since)
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tPIPELINE\tLAST RUN\tOWNER\tREPOSITORY\tPROBLEMS")
+	for _, p := range stale {
+		last := "never"
+		if p.LatestBuild != nil {
+			last = a.display.time(p.LatestBuild.QueueTime, time.DateOnly)
+		}
+		owner := p.AuthoredBy.UniqueName
+		if owner == "" {
+			owner = p.AuthoredBy.DisplayName
+		}
+		problems := "-"
+		if len(p.problems) > 0 {
+			problems = strings.Join(p.problems, "; ")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", p.ID, definitionPath(p.DefinitionReference), last, orDash(owner), orDash(repositoryLabel(p.Repository)), problems)
+	}
+	return w.Flush()
+}
+
+// lastQueued is when a definition last ran, zero if never.
+func lastQueued(d client.Definition) time.Time {
+	if d.LatestBuild == nil {
+		return time.Time{}
+	}
+	return d.LatestBuild.QueueTime
+}
+
+// definitionPath is the folder and name of a definition, e.g. Team/api.
+func definitionPath(d client.DefinitionReference) string {
+	folder := strings.Trim(strings.ReplaceAll(d.Path, `\`, "/"), "/")
+	if folder == "" {
+		return d.Name
+	}
+	return folder + "/" + d.Name
+}
+
+func repositoryLabel(r *client.DefinitionRepository) string {
+	if r == nil {
+		return ""
+	}
+	if r.DefaultBranch == "" {
+		return r.Name
+	}
+	return r.Name + "@" + strings.TrimPrefix(r.DefaultBranch, "refs/heads/")
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// checkSources records which Azure Repos repositories and default branches
+// of the pipelines no longer exist. Each repository and branch is looked up
+// once; other repository types are not checked.
+func checkSources(ctx context.Context, c *client.Client, pipelines []stalePipeline) {
+	type source struct{ repository, branch string } // branch empty for the repository itself
+	results := map[source]string{}
+	var sources []source
+	for _, p := range pipelines {
+		r := p.Repository
+		if r == nil || r.Type != client.RepositoryTypeAzureRepos {
+			continue
+		}
+		// Without a default branch both are the repository itself
+		for _, s := range []source{{repository: r.ID}, {r.ID, r.DefaultBranch}} {
+			if _, ok := results[s]; !ok {
+				results[s] = ""
+				sources = append(sources, s)
+			}
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, sourceChecks)
+	for _, s := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			var problem string
+			if s.branch == "" {
+				_, err := c.GetRepository(ctx, s.repository)
+				var apiErr *client.APIError
+				switch {
+				case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+					problem = "repository deleted"
+				case err != nil:
+					problem = "repository not checked: " + err.Error()
+				}
+			} else {
+				exists, err := c.BranchExists(ctx, s.repository, s.branch)
+				switch {
+				case err != nil:
+					// A deleted repository is reported on its own
+				case !exists:
+					problem = "branch " + strings.TrimPrefix(s.branch, "refs/heads/") + " deleted"
+				}
+			}
+			mu.Lock()
+			results[s] = problem
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	for i := range pipelines {
+		r := pipelines[i].Repository
+		if r == nil || r.Type != client.RepositoryTypeAzureRepos {
+			continue
+		}
+		if problem := results[source{repository: r.ID}]; problem != "" {
+			pipelines[i].problems = append(pipelines[i].problems, problem)
+		}
+		if problem := results[source{r.ID, r.DefaultBranch}]; problem != "" && r.DefaultBranch != "" {
+			pipelines[i].problems = append(pipelines[i].problems, problem)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"fomo/internal/client"
+)
+
+// tagFlags collects repeated --tag flags.
+type tagFlags []string
+
+func (f *tagFlags) String() string { return strings.Join(*f, ",") }
+
+func (f *tagFlags) Set(s string) error {
+	if strings.TrimSpace(s) == "" {
+		return fmt.Errorf("empty tag")
+	}
+	*f = append(*f, s)
+	return nil
+}
+
+// hasTags reports whether tags include every one of want. Tags compare
+// case-insensitively, like in Azure DevOps.
+func hasTags(tags, want []string) bool {
+	for _, w := range want {
+		if !slices.ContainsFunc(tags, func(t string) bool { return strings.EqualFold(t, w) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// pipelineOrTags reports whether the arguments name one pipeline, or none
+// and --tag was given instead.
+func pipelineOrTags(positional []string, tags tagFlags) bool {
+	if len(tags) > 0 {
+		return len(positional) == 0
+	}
+	return len(positional) == 1
+}
+
+// taggedPipelines returns the pipelines carrying every one of tags.
+func taggedPipelines(ctx context.Context, c *client.Client, tags []string) ([]client.Pipeline, error) {
+	definitions, err := c.ListDefinitionDetails(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pipeline tags: %w", err)
+	}
+	var pipelines []client.Pipeline
+	for _, d := range definitions {
+		if hasTags(d.Tags, tags) {
+			pipelines = append(pipelines, client.Pipeline{ID: d.ID, Name: d.Name, Folder: d.Path})
+		}
+	}
+	sort.Slice(pipelines, func(i, j int) bool { return pipelines[i].Name < pipelines[j].Name })
+	return pipelines, nil
+}
+
+// forEachPipeline runs fn for the pipeline named by positional or, with
+// tags, for every pipeline carrying them. A failure for one tagged pipeline
+// is reported and the others still run.
+func forEachPipeline(ctx context.Context, a *app, c *client.Client, positional, tags []string, fn func(p *client.Pipeline) error) error {
+	if len(tags) == 0 {
+		p, err := resolvePipeline(ctx, c, positional[0])
+		if err != nil {
+			return err
+		}
+		return fn(p)
+	}
+	pipelines, err := taggedPipelines(ctx, c, tags)
+	if err != nil {
+		return err
+	}
+	if len(pipelines) == 0 {
+		return fmt.Errorf("no pipeline is tagged %s", strings.Join(tags, " and "))
+	}
+	failed := 0
+	for i := range pipelines {
+		if i > 0 {
+			a.infof("\n")
+		}
+		if err := fn(&pipelines[i]); err != nil {
+			a.warnf("Warning: %v\n", err)
+			failed++
+		}
+	}
+	if failed == len(pipelines) {
+		return fmt.Errorf("none of the %d pipelines tagged %s could be analyzed", failed, strings.Join(tags, " and "))
+	}
+	return nil
+}
+
+func runPipelinesTagList(a *app, args []string) error {
+	fs := a.newFlagSet("pipelines tag list", "[<pipeline>]")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 1 {
+		return newUsageError("usage: fomo pipelines tag list [<pipeline>]")
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	if len(positional) == 1 {
+		p, err := resolvePipeline(ctx, c, positional[0])
+		if err != nil {
+			return err
+		}
+		tags, err := c.ListDefinitionTags(ctx, p.ID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch the tags of %s: %w", p.Name, err)
+		}
+		sort.Strings(tags)
+		for _, tag := range tags {
+			a.resultf("%s\n", tag)
+		}
+		return nil
+	}
+
+	definitions, err := c.ListDefinitionDetails(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch pipeline tags: %w", err)
+	}
+	sort.Slice(definitions, func(i, j int) bool { return definitions[i].Name < definitions[j].Name })
+	for _, d := range definitions {
+		if len(d.Tags) == 0 {
+			continue
+		}
+		sort.Strings(d.Tags)
+		if a.quiet {
+			a.resultf("%d\t%s\n", d.ID, strings.Join(d.Tags, ","))
+			continue
+		}
+		a.resultf("%s: %s\n", definitionPath(d.DefinitionReference), strings.Join(d.Tags, ", "))
+	}
+	return nil
+}
+
+func runPipelinesTagAdd(a *app, args []string) error {
+	return changePipelineTags(a, args, "add", true)
+}
+
+func runPipelinesTagRemove(a *app, args []string) error {
+	return changePipelineTags(a, args, "remove", false)
+}
+
+func changePipelineTags(a *app, args []string, name string, add bool) error {
+	fs := a.newFlagSet("pipelines tag "+name, "<pipeline> <tag>...")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 2 {
+		return newUsageError(fmt.Sprintf("usage: fomo pipelines tag %s <pipeline> <tag>...", name))
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	p, err := resolvePipeline(ctx, c, positional[0])
+	if err != nil {
+		return err
+	}
+	var tags []string
+	for _, tag := range positional[1:] {
+		if add {
+			tags, err = c.AddDefinitionTag(ctx, p.ID, tag)
+		} else {
+			tags, err = c.DeleteDefinitionTag(ctx, p.ID, tag)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to %s tag %s: %w", name, tag, err)
+		}
+	}
+	sort.Strings(tags)
+	if len(tags) == 0 {
+		a.infof("%s has no tags\n", p.Name)
+		return nil
+	}
+	a.infof("%s is tagged %s\n", p.Name, strings.Join(tags, ", "))
+	return nil
+}
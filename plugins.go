@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"fomo/internal/config"
+	"fomo/internal/notify"
+)
+
+// pluginPrefix is the executable name prefix of command plugins: running
+// "fomo deploy-check" executes "fomo-deploy-check" from PATH.
+const pluginPrefix = "fomo-"
+
+type plugin struct {
+	name string
+	path string
+}
+
+// pluginExitError carries a plugin's exit status back to fomo's own.
+type pluginExitError struct {
+	name string
+	code int
+}
+
+func (e *pluginExitError) Error() string {
+	return fmt.Sprintf("plugin %s exited with status %d", e.name, e.code)
+}
+
+// findPlugins lists the command plugins on PATH, first match winning like
+// the shell does. Notifier plugins are listed separately.
+func findPlugins() []plugin {
+	seen := map[string]bool{}
+	var plugins []plugin
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if !strings.HasPrefix(name, pluginPrefix) || strings.HasPrefix(name, notify.PluginPrefix) {
+				continue
+			}
+			name = strings.TrimSuffix(strings.TrimPrefix(name, pluginPrefix), filepath.Ext(name))
+			if name == "" || seen[name] {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if info, err := os.Stat(path); err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+				continue
+			}
+			seen[name] = true
+			plugins = append(plugins, plugin{name: name, path: path})
+		}
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].name < plugins[j].name })
+	return plugins
+}
+
+// runPlugin executes a command plugin, passing the global options through
+// the environment so the plugin can call back into fomo with the same scope.
+func runPlugin(a *app, path, name string, args []string) error {
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = a.stdout
+	cmd.Stderr = a.stderr
+	cmd.Env = os.Environ()
+	if a.flags.Organization != "" {
+		cmd.Env = append(cmd.Env, config.EnvOrganization+"="+a.flags.Organization)
+	}
+	if a.flags.Project != "" {
+		cmd.Env = append(cmd.Env, config.EnvProject+"="+a.flags.Project)
+	}
+	if a.profile != "" {
+		cmd.Env = append(cmd.Env, config.EnvProfile+"="+a.profile)
+	}
+	if a.quiet {
+		cmd.Env = append(cmd.Env, "FOMO_QUIET=1")
+	}
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &pluginExitError{name: name, code: exitErr.ExitCode()}
+	}
+	return err
+}
+
+func runPluginsList(a *app, args []string) error {
+	fs := a.newFlagSet("plugins list", "")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	a.infof("Command plugins:\n")
+	for _, p := range findPlugins() {
+		a.resultf("%s\t%s\n", p.name, p.path)
+	}
+	a.infof("\nNotifier types:\n")
+	for _, kind := range notify.Types() {
+		a.resultf("%s\tbuilt-in\n", kind)
+	}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		matches, _ := filepath.Glob(filepath.Join(dir, notify.PluginPrefix+"*"))
+		for _, match := range matches {
+			a.resultf("%s\t%s\n", strings.TrimPrefix(filepath.Base(match), notify.PluginPrefix), match)
+		}
+	}
+	return nil
+}
+
+func runNotifyTest(a *app, args []string) error {
+	fs := a.newFlagSet("notify test", "<notifier>")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return newUsageError("usage: fomo notify test <notifier>")
+	}
+
+	loader := &config.Loader{Flags: a.flags, Profile: a.profile}
+	cfg, err := loader.Load()
+	if err != nil {
+		return err
+	}
+	a.warnConfig(cfg)
+	nc, ok := cfg.Notifiers[positional[0]]
+	if !ok {
+		return newUsageError(fmt.Sprintf("notifier %q is not configured", positional[0]))
+	}
+	notifier, err := notify.New(nc.Type, nc.Options)
+	if err != nil {
+		return err
+	}
+
+	event := notify.Event{
+		Title:    "fomo test notification",
+		Message:  fmt.Sprintf("Notifier %q is working", positional[0]),
+		Pipeline: "example-pipeline",
+		Result:   "succeeded",
+		Time:     time.Now(),
+	}
+	if err := notifier.Notify(a.ctx, event); err != nil {
+		return err
+	}
+	a.infof("Test notification sent via %s\n", positional[0])
+	return nil
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"fomo/internal/client"
+	"fomo/internal/i18n"
+	"fomo/internal/yaml"
+)
+
+// pipelinePolicy is the settings an organization requires of its
+// pipelines, as read from the file given to policy apply --file:
+//
+//	pipelines: [platform/**]
+//	exclude: [platform/sandbox/**]
+//	yamlPath: [.azure-pipelines/*.yml]
+//	forbiddenTasks: [CmdLine@1, InstallSSHKey]
+//	retention:
+//	  days: 30
+//	settings:
+//	  jobAuthorizationScope: project
+//	  jobTimeoutInMinutes: 60
+type pipelinePolicy struct {
+	Pipelines      []string          `yaml:"pipelines"`
+	Exclude        []string          `yaml:"exclude"`
+	YAMLPath       []string          `yaml:"yamlPath"`
+	ForbiddenTasks []string          `yaml:"forbiddenTasks"`
+	Retention      retentionPolicy   `yaml:"retention"`
+	Settings       map[string]string `yaml:"settings"`
+
+	include, exclude, yamlPaths []*pathGlob
+	forbidden                   []taskSpec
+}
+
+type retentionPolicy struct {
+	Days int `yaml:"days"`
+}
+
+// taskSpec is a task name, and optionally its major version, as in
+// CmdLine@1.
+type taskSpec struct {
+	name  string
+	major int // -1 for any version
+}
+
+func parseTaskSpec(s string) (taskSpec, error) {
+	name, version, found := strings.Cut(s, "@")
+	spec := taskSpec{name: strings.TrimSpace(name), major: -1}
+	if found {
+		major, err := strconv.Atoi(version)
+		if err != nil || major < 0 {
+			return taskSpec{}, fmt.Errorf("invalid task %q: want NAME or NAME@MAJOR", s)
+		}
+		spec.major = major
+	}
+	if spec.name == "" {
+		return taskSpec{}, fmt.Errorf("invalid task %q: want NAME or NAME@MAJOR", s)
+	}
+	return spec, nil
+}
+
+func (t taskSpec) match(name string, major int) bool {
+	return strings.EqualFold(t.name, name) && (t.major < 0 || t.major == major)
+}
+
+// yamlTaskLine is a task step in pipeline YAML, with its version.
+var yamlTaskLine = regexp.MustCompile(`^\s*(?:-\s*)?task:\s*['"]?([\w.-]+)(?:@(\d+))?`)
+
+// loadPolicy reads and validates a policy.
+func loadPolicy(path string) (*pipelinePolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p pipelinePolicy
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if len(p.YAMLPath) == 0 && len(p.ForbiddenTasks) == 0 && p.Retention.Days == 0 && len(p.Settings) == 0 {
+		return nil, fmt.Errorf("%s requires nothing: set yamlPath, forbiddenTasks, retention or settings", path)
+	}
+	if p.Retention.Days < 0 {
+		return nil, fmt.Errorf("%s: retention days must be positive", path)
+	}
+	for _, globs := range []struct {
+		patterns []string
+		into     *[]*pathGlob
+	}{{p.Pipelines, &p.include}, {p.Exclude, &p.exclude}, {p.YAMLPath, &p.yamlPaths}} {
+		if *globs.into, err = parsePathGlobs(globs.patterns); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	for _, s := range p.ForbiddenTasks {
+		spec, err := parseTaskSpec(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		p.forbidden = append(p.forbidden, spec)
+	}
+	return &p, nil
+}
+
+// covers reports whether the policy applies to a pipeline.
+func (p *pipelinePolicy) covers(d client.Definition) bool {
+	name := definitionPath(d.DefinitionReference)
+	if len(p.include) > 0 && len(matchingFiles([]string{name}, p.include)) == 0 {
+		return false
+	}
+	return len(matchingFiles([]string{name}, p.exclude)) == 0
+}
+
+// policyViolation is a way a pipeline, or the project, breaks the policy.
+// Violations without a fix have to be fixed by hand.
+type policyViolation struct {
+	owner   string
+	rule    string
+	problem string
+	fix     func(ctx context.Context) error
+}
+
+// settingValue converts a required setting to the JSON type the
+// definition holds it as, so that it can be written back. The type of a
+// setting that is not set yet is guessed from the value.
+func settingValue(current any, want string) (any, error) {
+	switch current.(type) {
+	case float64:
+		return strconv.ParseFloat(want, 64)
+	case bool:
+		return strconv.ParseBool(want)
+	case nil:
+		if b, err := strconv.ParseBool(want); err == nil {
+			return b, nil
+		}
+		if f, err := strconv.ParseFloat(want, 64); err == nil {
+			return f, nil
+		}
+	}
+	return want, nil
+}
+
+// checkPipelinePolicy checks a pipeline's YAML file and settings.
+func checkPipelinePolicy(ctx context.Context, c *client.Client, p *pipelinePolicy, d client.Definition) ([]policyViolation, error) {
+	name := definitionPath(d.DefinitionReference)
+	definition, err := c.GetDefinitionRaw(ctx, d.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pipeline %s: %w", name, err)
+	}
+	var violations []policyViolation
+
+	process, _ := definition["process"].(map[string]any)
+	file, _ := process["yamlFilename"].(string)
+	file = strings.TrimPrefix(file, "/")
+	switch {
+	case len(p.yamlPaths) == 0:
+	case file == "":
+		violations = append(violations, policyViolation{owner: name, rule: "yaml path", problem: "classic pipeline, not YAML"})
+	case len(matchingFiles([]string{file}, p.yamlPaths)) == 0:
+		violations = append(violations, policyViolation{owner: name, rule: "yaml path", problem: fmt.Sprintf("%s is not under %s", file, strings.Join(p.YAMLPath, ", "))})
+	}
+
+	for _, setting := range slices.Sorted(maps.Keys(p.Settings)) {
+		want := p.Settings[setting]
+		current := definition[setting]
+		if current != nil && fmt.Sprint(current) == want {
+			continue
+		}
+		v := policyViolation{owner: name, rule: "setting " + setting, problem: fmt.Sprintf("%v, not %s", current, want)}
+		if current == nil {
+			v.problem = "not set, not " + want
+		}
+		value, err := settingValue(current, want)
+		if err != nil {
+			v.problem += fmt.Sprintf(" (%s cannot hold %q)", setting, want)
+		} else {
+			v.fix = func(ctx context.Context) error {
+				definition[setting] = value
+				updated, err := c.UpdateDefinitionRaw(ctx, d.ID, definition)
+				if err != nil {
+					return fmt.Errorf("failed to set %s of pipeline %s: %w", setting, name, err)
+				}
+				// The next fix needs the new revision
+				definition = updated
+				return nil
+			}
+		}
+		violations = append(violations, v)
+	}
+
+	if len(p.forbidden) > 0 {
+		_, content, err := definitionYAML(ctx, c, d, definition)
+		if err != nil {
+			return violations, err
+		}
+		for i, line := range strings.Split(content, "\n") {
+			m := yamlTaskLine.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			task, major := m[1], -1
+			if m[2] != "" {
+				major, _ = strconv.Atoi(m[2])
+				task += "@" + m[2]
+			}
+			if slices.ContainsFunc(p.forbidden, func(t taskSpec) bool { return t.match(m[1], major) }) {
+				violations = append(violations, policyViolation{owner: name, rule: "forbidden task", problem: fmt.Sprintf("%s at %s:%d", task, file, i+1)})
+			}
+		}
+	}
+	return violations, nil
+}
+
+// checkRetentionPolicy checks how long the project keeps runs.
+func checkRetentionPolicy(ctx context.Context, c *client.Client, p *pipelinePolicy) ([]policyViolation, error) {
+	if p.Retention.Days == 0 {
+		return nil, nil
+	}
+	retention, err := c.GetProjectRetention(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the retention settings of %s: %w", c.Project, err)
+	}
+	if retention.PurgeRuns.Value >= p.Retention.Days {
+		return nil, nil
+	}
+	v := policyViolation{owner: "project " + c.Project, rule: "retention", problem: fmt.Sprintf("keeps runs %d days, not at least %d", retention.PurgeRuns.Value, p.Retention.Days)}
+	if retention.PurgeRuns.Max > 0 && p.Retention.Days > retention.PurgeRuns.Max {
+		v.problem += fmt.Sprintf(" (the organization allows at most %d)", retention.PurgeRuns.Max)
+	} else {
+		v.fix = func(ctx context.Context) error {
+			if _, err := c.SetRunRetention(ctx, p.Retention.Days); err != nil {
+				return fmt.Errorf("failed to change the retention of %s: %w", c.Project, err)
+			}
+			return nil
+		}
+	}
+	return []policyViolation{v}, nil
+}
+
+func runPolicyApply(a *app, args []string) error {
+	fs := a.newFlagSet("policy apply", "--file FILE [--fix] [--yes]")
+	file := fs.String("file", "", "YAML file with the settings pipelines must have")
+	fix := fs.Bool("fix", false, "fix the violations that can be fixed: settings and retention")
+	yes := fs.Bool("yes", false, "fix without asking")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 || *file == "" {
+		return newUsageError("usage: fomo policy apply --file FILE [--fix] [--yes]")
+	}
+	policy, err := loadPolicy(*file)
+	if err != nil {
+		return err
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	definitions, err := c.ListDefinitionDetails(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch pipelines: %w", err)
+	}
+	definitions = slices.DeleteFunc(definitions, func(d client.Definition) bool { return !policy.covers(d) })
+
+	violations, err := checkRetentionPolicy(ctx, c, policy)
+	if err != nil {
+		return err
+	}
+	results := make([][]policyViolation, len(definitions))
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, 8)
+	)
+	for i, d := range definitions {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			found, err := checkPipelinePolicy(ctx, c, policy, d)
+			if err != nil {
+				a.warnf("Warning: %v\n", err)
+			}
+			results[i] = found
+		}()
+	}
+	wg.Wait()
+
+	// Forbidden tasks can also come from templates, which only the
+	// latest runs show
+	if len(policy.forbidden) > 0 {
+		for _, u := range timelineTasks(ctx, a, c, definitions) {
+			if !slices.ContainsFunc(policy.forbidden, func(t taskSpec) bool { return t.match(u.name, u.major) }) {
+				continue
+			}
+			task := fmt.Sprintf("%s@%d", u.name, u.major)
+			for pipeline := range u.pipelines {
+				i := slices.IndexFunc(definitions, func(d client.Definition) bool { return definitionPath(d.DefinitionReference) == pipeline })
+				// Already found in its YAML file
+				if slices.ContainsFunc(results[i], func(v policyViolation) bool {
+					return v.rule == "forbidden task" && strings.HasPrefix(strings.ToLower(v.problem), strings.ToLower(task+" "))
+				}) {
+					continue
+				}
+				results[i] = append(results[i], policyViolation{owner: pipeline, rule: "forbidden task", problem: task + " in its latest run"})
+			}
+		}
+	}
+	for _, found := range results {
+		violations = append(violations, found...)
+	}
+
+	fixable := 0
+	for _, v := range violations {
+		if v.fix != nil {
+			fixable++
+		}
+	}
+	if a.quiet {
+		for _, v := range violations {
+			a.resultf("%s\t%s\t%s\t%t\n", v.owner, v.rule, v.problem, v.fix != nil)
+		}
+	} else if len(violations) == 0 {
+		a.infof("%d pipelines comply with %s.\n", len(definitions), *file)
+		return nil
+	} else {
+		w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "PIPELINE\tRULE\tPROBLEM\tFIX")
+		for _, v := range violations {
+			how := "by hand"
+			if v.fix != nil {
+				how = "--fix"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", v.owner, v.rule, v.problem, how)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+
+	if !*fix || fixable == 0 {
+		if fixable > 0 {
+			a.infof("\n%d of %d violations can be fixed with --fix.\n", fixable, len(violations))
+		}
+		return fmt.Errorf("%d violations of %s", len(violations), *file)
+	}
+	if err := a.checkWrite("fomo policy apply --fix"); err != nil {
+		return err
+	}
+	if _, err := a.confirmChange(changeFlags{yes: *yes}, plannedChange{verb: i18n.T("fix"), target: plural(fixable, "violation")}); err != nil {
+		return err
+	}
+	fixed := 0
+	for _, v := range violations {
+		if v.fix == nil {
+			continue
+		}
+		if err := v.fix(ctx); err != nil {
+			a.warnf("Warning: %v\n", err)
+			continue
+		}
+		fixed++
+	}
+	a.infof("Fixed %d of %d violations.\n", fixed, len(violations))
+	if fixed < len(violations) {
+		return fmt.Errorf("%d violations of %s remain", len(violations)-fixed, *file)
+	}
+	return nil
+}
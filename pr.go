@@ -0,0 +1,448 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"fomo/internal/client"
+	"fomo/internal/watch"
+)
+
+// Check results, as shown by pr checks.
+const (
+	checkPassed   = "passed"
+	checkBlocking = "blocking"
+	checkPending  = "pending"
+	checkOptional = "optional"
+)
+
+// prCheck is one requirement for merging a pull request.
+type prCheck struct {
+	Result string
+	Name   string
+	Detail string
+	Links  []string
+}
+
+// parsePullRequestID parses a pull request ID given on the command line.
+func parsePullRequestID(arg string) (int, error) {
+	id, err := strconv.Atoi(strings.TrimPrefix(arg, "!"))
+	if err != nil || id <= 0 {
+		return 0, newUsageError(fmt.Sprintf("invalid pull request ID %q", arg))
+	}
+	return id, nil
+}
+
+func runPRChecks(a *app, args []string) error {
+	fs := a.newFlagSet("pr checks", "<pr-id>")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return newUsageError("usage: fomo pr checks <pr-id>")
+	}
+	id, err := parsePullRequestID(positional[0])
+	if err != nil {
+		return err
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+
+	pr, err := c.GetPullRequest(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to fetch pull request %d: %w", id, err)
+	}
+	checks, err := pullRequestChecks(ctx, c, pr)
+	if err != nil {
+		return err
+	}
+
+	blocking := 0
+	for _, check := range checks {
+		if check.Result == checkBlocking || check.Result == checkPending {
+			blocking++
+		}
+	}
+	printPRChecks(a, pr, checks, blocking)
+	if blocking > 0 {
+		return fmt.Errorf("pull request %d cannot be merged yet: %d requirement(s) not met", pr.ID, blocking)
+	}
+	return nil
+}
+
+// mergeStrategies maps --merge-strategy values to the API's names.
+var mergeStrategies = map[string]string{
+	"merge":        client.MergeNoFastForward,
+	"squash":       client.MergeSquash,
+	"rebase":       client.MergeRebase,
+	"rebase-merge": client.MergeRebaseMerge,
+}
+
+func runPRComplete(a *app, args []string) error {
+	const usage = "usage: fomo pr complete <pr-id> [--merge-strategy merge|squash|rebase|rebase-merge] [--delete-source] [--auto-complete]"
+	fs := a.newFlagSet("pr complete", "<pr-id> [--merge-strategy STRATEGY] [--delete-source] [--auto-complete]")
+	strategy := fs.String("merge-strategy", "merge", "how to merge: merge, squash, rebase or rebase-merge")
+	deleteSource := fs.Bool("delete-source", false, "delete the source branch after merging")
+	autoComplete := fs.Bool("auto-complete", false, "merge automatically once all required policies pass")
+	keepWorkItems := fs.Bool("keep-work-items", false, "do not transition linked work items when merging")
+	message := fs.String("message", "", "merge commit message (default: generated by Azure DevOps)")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return newUsageError(usage)
+	}
+	mergeStrategy, ok := mergeStrategies[*strategy]
+	if !ok {
+		return newUsageError(fmt.Sprintf("invalid --merge-strategy %q\n%s", *strategy, usage))
+	}
+	id, err := parsePullRequestID(positional[0])
+	if err != nil {
+		return err
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+
+	pr, err := c.GetPullRequest(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to fetch pull request %d: %w", id, err)
+	}
+	if pr.Status != client.PullRequestActive {
+		return fmt.Errorf("pull request %d is %s", pr.ID, pr.Status)
+	}
+	options := &client.CompletionOptions{
+		MergeStrategy:       mergeStrategy,
+		DeleteSourceBranch:  *deleteSource,
+		TransitionWorkItems: !*keepWorkItems,
+		MergeCommitMessage:  *message,
+	}
+	checks, err := pullRequestChecks(ctx, c, pr)
+	if err != nil {
+		return err
+	}
+	var blocking []prCheck
+	for _, check := range checks {
+		if check.Result == checkBlocking || check.Result == checkPending {
+			blocking = append(blocking, check)
+		}
+	}
+
+	if *autoComplete {
+		me, err := c.Me(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to look up the current user: %w", err)
+		}
+		update := client.PullRequestUpdate{AutoCompleteSetBy: &client.Identity{ID: me.ID}, CompletionOptions: options}
+		if _, err := c.UpdatePullRequest(ctx, pr.Repository.ID, pr.ID, update); err != nil {
+			return fmt.Errorf("failed to set auto-complete on pull request %d: %w", pr.ID, err)
+		}
+		a.infof("Pull request %d will be merged (%s) into %s once its required policies pass\n", pr.ID, *strategy, watch.ShortBranch(pr.TargetRefName))
+		for _, check := range blocking {
+			a.infof("  waiting for %s\n", check.Name)
+		}
+		a.resultf("%d\n", pr.ID)
+		return nil
+	}
+
+	if len(blocking) > 0 {
+		for _, check := range blocking {
+			a.warnf("  %s: %s\n", check.Name, check.Detail)
+		}
+		return fmt.Errorf("pull request %d cannot be completed: %d requirement(s) not met (use --auto-complete to merge once they are)", pr.ID, len(blocking))
+	}
+
+	workItems, err := c.ListPullRequestWorkItems(ctx, pr.Repository.ID, pr.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list work items of pull request %d: %w", pr.ID, err)
+	}
+	update := client.PullRequestUpdate{
+		Status:                client.PullRequestCompleted,
+		LastMergeSourceCommit: pr.LastMergeSourceCommit,
+		CompletionOptions:     options,
+	}
+	if _, err := c.UpdatePullRequest(ctx, pr.Repository.ID, pr.ID, update); err != nil {
+		return fmt.Errorf("failed to complete pull request %d: %w", pr.ID, err)
+	}
+
+	// The merge itself happens asynchronously
+	completed, err := waitForMerge(ctx, c, pr.ID)
+	if err != nil {
+		return err
+	}
+	a.infof("Merged pull request %d (%s) into %s\n", pr.ID, *strategy, watch.ShortBranch(pr.TargetRefName))
+	if *deleteSource {
+		a.infof("Deleted %s\n", watch.ShortBranch(pr.SourceRefName))
+	}
+	if len(workItems) > 0 && !*keepWorkItems {
+		var ids []string
+		for _, w := range workItems {
+			ids = append(ids, w.ID)
+		}
+		a.infof("Transitioned work items %s\n", strings.Join(ids, ", "))
+	}
+	if completed.LastMergeCommit != nil {
+		a.resultf("%s\n", completed.LastMergeCommit.CommitID)
+	}
+	return nil
+}
+
+// waitForMerge polls a pull request being completed until the merge went
+// through or failed.
+func waitForMerge(ctx context.Context, c *client.Client, id int) (*client.PullRequest, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	for {
+		pr, err := c.GetPullRequest(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check pull request %d: %w", id, err)
+		}
+		switch {
+		case pr.Status == client.PullRequestCompleted:
+			return pr, nil
+		case pr.MergeStatus == "conflicts" || pr.MergeStatus == "failure" || pr.MergeStatus == "rejectedByPolicy":
+			return nil, fmt.Errorf("failed to merge pull request %d: merge status %s", id, pr.MergeStatus)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("pull request %d is still merging; check it in the browser: %s", id, pr.WebURL())
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// pullRequestChecks evaluates what stands between a pull request and its
+// merge: the branch policies, plus draft state and merge conflicts, which
+// block a merge without being policies.
+func pullRequestChecks(ctx context.Context, c *client.Client, pr *client.PullRequest) ([]prCheck, error) {
+	var checks []prCheck
+	if pr.IsDraft {
+		checks = append(checks, prCheck{Result: checkBlocking, Name: "Draft", Detail: "publish the pull request to allow merging", Links: []string{pr.WebURL()}})
+	}
+	if pr.MergeStatus == "conflicts" {
+		checks = append(checks, prCheck{Result: checkBlocking, Name: "Merge conflicts", Detail: "resolve the conflicts with " + watch.ShortBranch(pr.TargetRefName), Links: []string{pr.WebURL()}})
+	}
+
+	evaluations, err := c.ListPolicyEvaluations(ctx, pr.Repository.Project.ID, pr.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate branch policies: %w", err)
+	}
+	for _, evaluation := range evaluations {
+		if !evaluation.Configuration.IsEnabled || evaluation.Status == client.PolicyNotApplicable {
+			continue
+		}
+		check, err := policyCheck(ctx, c, pr, evaluation)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+// policyCheck describes one policy evaluation, fetching whatever explains
+// why the policy is not satisfied.
+func policyCheck(ctx context.Context, c *client.Client, pr *client.PullRequest, evaluation client.PolicyEvaluation) (prCheck, error) {
+	config := evaluation.Configuration
+	check := prCheck{Name: config.Type.DisplayName}
+	switch {
+	case evaluation.Status == client.PolicyApproved:
+		check.Result = checkPassed
+	case !config.IsBlocking:
+		check.Result = checkOptional
+	case evaluation.Status == client.PolicyQueued || evaluation.Status == client.PolicyRunning:
+		check.Result = checkPending
+	default:
+		check.Result = checkBlocking
+	}
+
+	var settings struct {
+		DisplayName          string   `json:"displayName"`
+		MinimumApproverCount int      `json:"minimumApproverCount"`
+		CreatorVoteCounts    bool     `json:"creatorVoteCounts"`
+		RequiredReviewerIDs  []string `json:"requiredReviewerIds"`
+	}
+	json.Unmarshal(config.Settings, &settings)
+
+	switch config.Type.ID {
+	case client.PolicyBuild:
+		var build struct {
+			BuildID   int  `json:"buildId"`
+			IsExpired bool `json:"isExpired"`
+		}
+		json.Unmarshal(evaluation.Context, &build)
+		if settings.DisplayName != "" {
+			check.Name += " (" + settings.DisplayName + ")"
+		}
+		switch {
+		case build.BuildID == 0:
+			check.Detail = "no build has run for the latest changes"
+		case build.IsExpired:
+			check.Detail = fmt.Sprintf("run %d expired; queue the build again", build.BuildID)
+		case evaluation.Status == client.PolicyApproved:
+			check.Detail = fmt.Sprintf("run %d succeeded", build.BuildID)
+		case evaluation.Status == client.PolicyQueued || evaluation.Status == client.PolicyRunning:
+			check.Detail = fmt.Sprintf("run %d in progress", build.BuildID)
+		default:
+			check.Detail = fmt.Sprintf("run %d failed", build.BuildID)
+		}
+		if build.BuildID != 0 && evaluation.Status != client.PolicyApproved {
+			check.Links = append(check.Links, c.WebURL(fmt.Sprintf("_build/results?buildId=%d", build.BuildID)))
+		}
+
+	case client.PolicyMinimumReviewers:
+		approvals := 0
+		var against []string
+		for _, r := range pr.Reviewers {
+			if r.ID == pr.CreatedBy.ID && !settings.CreatorVoteCounts {
+				continue
+			}
+			switch {
+			case r.Vote >= client.VoteApprovedWithSuggestion:
+				approvals++
+			case r.Vote == client.VoteWaitingForAuthor:
+				against = append(against, r.DisplayName+" is waiting for the author")
+			case r.Vote == client.VoteRejected:
+				against = append(against, r.DisplayName+" rejected")
+			}
+		}
+		check.Detail = fmt.Sprintf("%d of %d approvals", approvals, settings.MinimumApproverCount)
+		if len(against) > 0 {
+			check.Detail += "; " + strings.Join(against, ", ")
+		}
+		if check.Result != checkPassed {
+			check.Links = append(check.Links, pr.WebURL())
+		}
+
+	case client.PolicyRequiredReviewers:
+		var waiting, unknown []string
+		for _, id := range settings.RequiredReviewerIDs {
+			vote, ok := pr.VoteOf(id)
+			switch {
+			case vote >= client.VoteApprovedWithSuggestion:
+				continue
+			case !ok:
+				// Not a reviewer yet, so only known by ID
+				unknown = append(unknown, id)
+			}
+			waiting = append(waiting, id)
+		}
+		if len(waiting) > 0 && check.Result != checkPassed {
+			names := map[string]string{}
+			if len(unknown) > 0 {
+				names = newIdentities(c).names(ctx, unknown)
+			}
+			for _, r := range pr.Reviewers {
+				names[r.ID] = r.DisplayName
+			}
+			for i, id := range waiting {
+				waiting[i] = names[id]
+			}
+			check.Detail = "waiting for " + strings.Join(waiting, ", ")
+			check.Links = append(check.Links, pr.WebURL())
+		}
+
+	case client.PolicyCommentResolution:
+		if check.Result == checkPassed {
+			break
+		}
+		threads, err := c.ListPullRequestThreads(ctx, pr.Repository.ID, pr.ID)
+		if err != nil {
+			return check, fmt.Errorf("failed to list comments of pull request %d: %w", pr.ID, err)
+		}
+		unresolved := 0
+		for _, thread := range threads {
+			if !thread.Unresolved() {
+				continue
+			}
+			unresolved++
+			link := fmt.Sprintf("%s?discussionId=%d", pr.WebURL(), thread.ID)
+			if where := threadLocation(thread); where != "" {
+				link += "  (" + where + ")"
+			}
+			check.Links = append(check.Links, link)
+		}
+		check.Detail = fmt.Sprintf("%d unresolved comment thread(s)", unresolved)
+
+	case client.PolicyWorkItemLinking:
+		if check.Result == checkPassed {
+			break
+		}
+		workItems, err := c.ListPullRequestWorkItems(ctx, pr.Repository.ID, pr.ID)
+		if err != nil {
+			return check, fmt.Errorf("failed to list work items of pull request %d: %w", pr.ID, err)
+		}
+		if len(workItems) == 0 {
+			check.Detail = "no work items linked"
+			check.Links = append(check.Links, pr.WebURL())
+		}
+
+	default:
+		if check.Result != checkPassed {
+			check.Detail = evaluation.Status
+			check.Links = append(check.Links, pr.WebURL())
+		}
+	}
+	return check, nil
+}
+
+// threadLocation returns the file and line a comment thread is on, if any.
+func threadLocation(thread client.CommentThread) string {
+	where := thread.ThreadContext
+	if where == nil || where.FilePath == "" {
+		return ""
+	}
+	if where.RightFileStart != nil {
+		return fmt.Sprintf("%s:%d", where.FilePath, where.RightFileStart.Line)
+	}
+	return where.FilePath
+}
+
+func printPRChecks(a *app, pr *client.PullRequest, checks []prCheck, blocking int) {
+	if a.quiet {
+		for _, check := range checks {
+			if check.Result == checkBlocking || check.Result == checkPending {
+				a.resultf("%s\t%s\n", check.Result, check.Name)
+			}
+		}
+		return
+	}
+
+	a.resultf("Pull request %d: %s (%s into %s)\n", pr.ID, pr.Title, watch.ShortBranch(pr.SourceRefName), watch.ShortBranch(pr.TargetRefName))
+	if url := pr.WebURL(); url != "" {
+		a.resultf("%s\n", url)
+	}
+	a.resultf("\n")
+	if len(checks) == 0 {
+		a.resultf("No branch policies apply.\n")
+	}
+	for _, check := range checks {
+		line := fmt.Sprintf("%-9s %s", check.Result, check.Name)
+		if check.Detail != "" {
+			line += ": " + check.Detail
+		}
+		a.resultf("%s\n", line)
+		for _, link := range check.Links {
+			a.resultf("          %s\n", link)
+		}
+	}
+	a.resultf("\n")
+	if blocking == 0 {
+		a.resultf("Ready to merge.\n")
+	} else {
+		a.resultf("%d requirement(s) block the merge.\n", blocking)
+	}
+}
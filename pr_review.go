@@ -0,0 +1,406 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"fomo/internal/client"
+	"fomo/internal/diff"
+)
+
+// fileDiff is the diff of one file changed by a pull request.
+type fileDiff struct {
+	status  string // A, M, D or R
+	oldPath string
+	newPath string
+	text    string
+}
+
+func runPRDiff(a *app, args []string) error {
+	fs := a.newFlagSet("pr diff", "<pr-id> [PATH...]")
+	nameOnly := fs.Bool("name-only", false, "only list the changed files")
+	noPager := fs.Bool("no-pager", false, "do not pipe the diff through $PAGER")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 1 {
+		return newUsageError("usage: fomo pr diff <pr-id> [PATH...] [--name-only]")
+	}
+	id, err := parsePullRequestID(positional[0])
+	if err != nil {
+		return err
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+
+	pr, err := c.GetPullRequest(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to fetch pull request %d: %w", id, err)
+	}
+	files, err := pullRequestDiff(ctx, c, pr, positional[1:], !*nameOnly)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		a.infof("No changes.\n")
+		return nil
+	}
+
+	color := isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == "" && !a.plain
+	return a.page(*noPager, func(w io.Writer) error {
+		for _, f := range files {
+			if *nameOnly {
+				name := f.newPath
+				if f.status == "R" {
+					name = f.oldPath + " -> " + f.newPath
+				}
+				if _, err := fmt.Fprintf(w, "%s  %s\n", f.status, name); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, err := io.WriteString(w, formatFileDiff(f, color)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// pullRequestDiff diffs the files a pull request changes against the
+// common ancestor of its source and target branches. Paths limit the diff
+// to files under them.
+func pullRequestDiff(ctx context.Context, c *client.Client, pr *client.PullRequest, paths []string, withContent bool) ([]fileDiff, error) {
+	if pr.LastMergeSourceCommit == nil || pr.LastMergeTargetCommit == nil {
+		return nil, fmt.Errorf("pull request %d has no commits to compare", pr.ID)
+	}
+	source := pr.LastMergeSourceCommit.CommitID
+	diffs, err := c.DiffCommits(ctx, pr.Repository.ID, pr.LastMergeTargetCommit.CommitID, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the changes of pull request %d: %w", pr.ID, err)
+	}
+	base := diffs.CommonCommit
+	if base == "" {
+		base = pr.LastMergeTargetCommit.CommitID
+	}
+
+	var files []fileDiff
+	for _, change := range diffs.Changes {
+		if change.Item.IsFolder || change.Item.GitObjectType == "tree" || !underPaths(change.Item.Path, paths) {
+			continue
+		}
+		f := fileDiff{status: "M", oldPath: change.Item.Path, newPath: change.Item.Path}
+		switch {
+		case strings.Contains(change.ChangeType, "add"):
+			f.status = "A"
+		case strings.Contains(change.ChangeType, "delete"):
+			f.status = "D"
+		case strings.Contains(change.ChangeType, "rename"):
+			f.status = "R"
+			f.oldPath = change.OriginalPath
+		}
+		if withContent {
+			var old, new string
+			if f.status != "A" {
+				if old, err = c.GetFileAtCommit(ctx, pr.Repository.ID, f.oldPath, base); err != nil {
+					return nil, fmt.Errorf("failed to fetch %s: %w", f.oldPath, err)
+				}
+			}
+			if f.status != "D" {
+				if new, err = c.GetFileAtCommit(ctx, pr.Repository.ID, f.newPath, source); err != nil {
+					return nil, fmt.Errorf("failed to fetch %s: %w", f.newPath, err)
+				}
+			}
+			oldName, newName := f.oldPath, f.newPath
+			switch f.status {
+			case "A":
+				oldName = ""
+			case "D":
+				newName = ""
+			}
+			f.text = diff.Unified(oldName, newName, old, new)
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+func underPaths(path string, paths []string) bool {
+	if len(paths) == 0 {
+		return true
+	}
+	path = strings.TrimPrefix(path, "/")
+	for _, p := range paths {
+		p = strings.Trim(p, "/")
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// formatFileDiff renders a file's diff with a git-style header, in color
+// if asked.
+func formatFileDiff(f fileDiff, color bool) string {
+	var b strings.Builder
+	header := fmt.Sprintf("diff --git a/%s b/%s\n", strings.TrimPrefix(f.oldPath, "/"), strings.TrimPrefix(f.newPath, "/"))
+	switch f.status {
+	case "A":
+		header += "new file\n"
+	case "D":
+		header += "deleted file\n"
+	case "R":
+		header += fmt.Sprintf("rename from %s\nrename to %s\n", strings.TrimPrefix(f.oldPath, "/"), strings.TrimPrefix(f.newPath, "/"))
+	}
+	if color {
+		header = "\033[1m" + strings.TrimSuffix(header, "\n") + "\033[0m\n"
+	}
+	b.WriteString(header)
+	b.WriteString(colorDiff(f.text, color))
+	return b.String()
+}
+
+// colorDiff colors the lines of a unified diff for a terminal.
+func colorDiff(text string, color bool) string {
+	if !color {
+		return text
+	}
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if line == "" {
+			continue
+		}
+		code := ""
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			code = "1"
+		case strings.HasPrefix(line, "@@"):
+			code = "36"
+		case strings.HasPrefix(line, "+"):
+			code = "32"
+		case strings.HasPrefix(line, "-"):
+			code = "31"
+		}
+		if code == "" {
+			b.WriteString(line)
+			continue
+		}
+		b.WriteString("\033[" + code + "m" + strings.TrimSuffix(line, "\n") + "\033[0m\n")
+	}
+	return b.String()
+}
+
+func runPRComment(a *app, args []string) error {
+	const usage = "usage: fomo pr comment <pr-id> [--file PATH [--line N]] <text>..."
+	fs := a.newFlagSet("pr comment", "<pr-id> [--file PATH [--line N]] <text>...")
+	file := fs.String("file", "", "comment on this file of the pull request")
+	line := fs.Int("line", 0, "comment on this line of --file, in the new version")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 1 || (*line != 0 && *file == "") || *line < 0 {
+		return newUsageError(usage)
+	}
+	id, err := parsePullRequestID(positional[0])
+	if err != nil {
+		return err
+	}
+	text := strings.Join(positional[1:], " ")
+	if text == "" && !isTerminal(os.Stdin) {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		text = strings.TrimSpace(string(data))
+	}
+	if text == "" {
+		return newUsageError(usage)
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+
+	pr, err := c.GetPullRequest(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to fetch pull request %d: %w", id, err)
+	}
+	thread := client.CommentThread{
+		Status:   client.ThreadActive,
+		Comments: []client.Comment{{Content: text, CommentType: "text"}},
+	}
+	if *file != "" {
+		thread.ThreadContext = &client.ThreadContext{FilePath: "/" + strings.TrimPrefix(*file, "/")}
+		if *line > 0 {
+			thread.ThreadContext.RightFileStart = &client.FilePosition{Line: *line, Offset: 1}
+			thread.ThreadContext.RightFileEnd = &client.FilePosition{Line: *line, Offset: 1}
+		}
+	}
+	created, err := c.CreateThread(ctx, pr.Repository.ID, pr.ID, thread)
+	if err != nil {
+		return fmt.Errorf("failed to comment on pull request %d: %w", pr.ID, err)
+	}
+	a.infof("Commented on pull request %d\n", pr.ID)
+	a.resultf("%s?discussionId=%d\n", pr.WebURL(), created.ID)
+	return nil
+}
+
+func runPRApprove(a *app, args []string) error {
+	return votePullRequest(a, "approve", client.VoteApproved, args)
+}
+
+func runPRReject(a *app, args []string) error {
+	return votePullRequest(a, "reject", client.VoteRejected, args)
+}
+
+// votePullRequest records the current user's vote on a pull request, with
+// an optional comment explaining it.
+func votePullRequest(a *app, name string, vote int, args []string) error {
+	fs := a.newFlagSet("pr "+name, "<pr-id> [--comment TEXT]")
+	comment := fs.String("comment", "", "also leave this comment")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return newUsageError(fmt.Sprintf("usage: fomo pr %s <pr-id> [--comment TEXT]", name))
+	}
+	id, err := parsePullRequestID(positional[0])
+	if err != nil {
+		return err
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+
+	pr, err := c.GetPullRequest(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to fetch pull request %d: %w", id, err)
+	}
+	me, err := c.Me(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to look up the current user: %w", err)
+	}
+	if *comment != "" {
+		thread := client.CommentThread{Status: client.ThreadActive, Comments: []client.Comment{{Content: *comment, CommentType: "text"}}}
+		if _, err := c.CreateThread(ctx, pr.Repository.ID, pr.ID, thread); err != nil {
+			return fmt.Errorf("failed to comment on pull request %d: %w", pr.ID, err)
+		}
+	}
+	if err := c.VotePullRequest(ctx, pr.Repository.ID, pr.ID, me.ID, vote); err != nil {
+		return fmt.Errorf("failed to %s pull request %d: %w", name, pr.ID, err)
+	}
+	if vote == client.VoteApproved {
+		a.infof("Approved pull request %d: %s\n", pr.ID, pr.Title)
+	} else {
+		a.infof("Rejected pull request %d: %s\n", pr.ID, pr.Title)
+	}
+	a.resultf("%d\n", pr.ID)
+	return nil
+}
+
+// voteLabel describes a reviewer's vote the way the web UI does.
+func voteLabel(vote int) string {
+	switch vote {
+	case client.VoteApproved:
+		return "approved"
+	case client.VoteApprovedWithSuggestion:
+		return "approved with suggestions"
+	case client.VoteWaitingForAuthor:
+		return "waiting for author"
+	case client.VoteRejected:
+		return "rejected"
+	}
+	return "no vote"
+}
+
+func runPRReviewers(a *app, args []string) error {
+	fs := a.newFlagSet("pr reviewers", "<pr-id> [USER...] [--required]")
+	required := fs.Bool("required", false, "add the users as required reviewers")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 1 || *required && len(positional) < 2 {
+		return newUsageError("usage: fomo pr reviewers <pr-id> [USER...] [--required]")
+	}
+	id, err := parsePullRequestID(positional[0])
+	if err != nil {
+		return err
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	pr, err := c.GetPullRequest(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to fetch pull request %d: %w", id, err)
+	}
+
+	reviewers := pr.Reviewers
+	if len(positional) > 1 {
+		if err := a.checkWrite("fomo pr reviewers"); err != nil {
+			return err
+		}
+		identities := newIdentities(c)
+		var ids, names []string
+		for _, name := range positional[1:] {
+			user, err := identities.resolve(ctx, name)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, user.ID)
+			names = append(names, user.DisplayName)
+		}
+		if reviewers, err = c.AddPullRequestReviewers(ctx, pr.Repository.ID, pr.ID, ids, *required); err != nil {
+			return fmt.Errorf("failed to add reviewers to pull request %d: %w", pr.ID, err)
+		}
+		a.infof("Added %s to the reviewers of pull request %d: %s\n", strings.Join(names, ", "), pr.ID, pr.Title)
+		if a.quiet {
+			a.resultf("%d\n", pr.ID)
+			return nil
+		}
+		// The answer lists only the reviewers just added
+		if pr, err = c.GetPullRequest(ctx, id); err == nil {
+			reviewers = pr.Reviewers
+		}
+	}
+
+	if a.quiet {
+		for _, r := range reviewers {
+			a.resultf("%s\t%s\t%d\t%t\n", r.DisplayName, r.UniqueName, r.Vote, r.IsRequired)
+		}
+		return nil
+	}
+	if len(reviewers) == 0 {
+		a.infof("Pull request %d has no reviewers.\n", pr.ID)
+		return nil
+	}
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "REVIEWER\tVOTE\tREQUIRED")
+	for _, r := range reviewers {
+		required := "no"
+		if r.IsRequired {
+			required = "yes"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", r.DisplayName, voteLabel(r.Vote), required)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"fomo/internal/client"
+	"fomo/internal/i18n"
+)
+
+// maxPRDescription is the longest description Azure Repos accepts for a
+// pull request.
+const maxPRDescription = 4000
+
+// releaseNoteGroups are the sections work items are grouped in, by type.
+// Types not listed go under "Other work items".
+var releaseNoteGroups = []struct {
+	title string
+	types []string
+}{
+	{"Features", []string{"Epic", "Feature", "User Story", "Product Backlog Item", "Requirement"}},
+	{"Fixes", []string{"Bug", "Issue"}},
+}
+
+// mergedPR matches the messages of the commits that merge a pull request:
+// Azure Repos' "Merged PR 12: title", GitHub's "Merge pull request #12
+// from ..." and squashed "title (#12)", and GitLab's "See merge request
+// group/project!12".
+var mergedPR = []*regexp.Regexp{
+	regexp.MustCompile(`^Merged PR (\d+): (.*)`),
+	regexp.MustCompile(`^Merge pull request #(\d+) from \S+(?:\n\n(.*))?`),
+	regexp.MustCompile(`^([^\n]*) \(#(\d+)\)(?:\n|$)`),
+	regexp.MustCompile(`(?s)^(?:Merge branch '[^']*' into '[^']*'\n\n)?(.*?)\n.*See merge request [^\s!]+!(\d+)`),
+}
+
+// releasePR is a pull request a commit merged.
+type releasePR struct {
+	number int
+	title  string
+}
+
+// parseMergedPR returns the pull request a commit message says it merged.
+func parseMergedPR(message string) (releasePR, bool) {
+	for i, re := range mergedPR {
+		m := re.FindStringSubmatch(message)
+		if m == nil {
+			continue
+		}
+		number, title := m[1], m[2]
+		if i >= 2 {
+			number, title = m[2], m[1]
+		}
+		n, _ := strconv.Atoi(number)
+		title, _, _ = strings.Cut(title, "\n")
+		return releasePR{number: n, title: strings.TrimSpace(title)}, true
+	}
+	return releasePR{}, false
+}
+
+// markdownText escapes text for Markdown, so titles and messages cannot
+// break the links and lists they are in.
+var markdownText = strings.NewReplacer(`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", "&lt;")
+
+func runReleaseNotes(a *app, args []string) error {
+	const usage = "usage: fomo release-notes --from RUN|TAG [--to RUN|TAG] [--pipeline NAME] [--title TEXT] [--out FILE] [--wiki NAME [--wiki-path PATH]] [--pr ID]"
+	fs := a.newFlagSet("release-notes", "--from RUN|TAG [--to RUN|TAG] [--pipeline NAME] [--out FILE] [--wiki NAME] [--pr ID]")
+	from := fs.String("from", "", "the run of the previous release: a run ID, or a tag of the run")
+	to := fs.String("to", "", "the run of this release: a run ID or tag (default: the pipeline's latest succeeded run)")
+	pipelineName := fs.String("pipeline", "", "pipeline whose runs tags name (default: any)")
+	title := fs.String("title", "", "title of the notes (default: the pipeline and build number of the release)")
+	top := fs.Int("top", 500, "read at most this many commits and work items")
+	out := fs.String("out", "", "write the notes to this file")
+	wiki := fs.String("wiki", "", "publish the notes to this wiki of the project")
+	wikiPath := fs.String("wiki-path", "", "path of the wiki page (default: /Release notes/<title>)")
+	prID := fs.Int("pr", 0, "replace the description of this pull request with the notes")
+	var flags changeFlags
+	flags.register(fs)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 || *from == "" {
+		return newUsageError(usage)
+	}
+	if *wikiPath != "" && *wiki == "" {
+		return newUsageError("--wiki-path needs --wiki")
+	}
+	if *top <= 0 {
+		return newUsageError("--top must be positive")
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	var p *client.Pipeline
+	if *pipelineName != "" {
+		if p, err = resolvePipeline(ctx, c, *pipelineName); err != nil {
+			return err
+		}
+	}
+	fromRun, err := releaseRun(ctx, c, *from, p)
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		p = &client.Pipeline{ID: fromRun.Definition.ID, Name: fromRun.Definition.Name}
+	}
+	var toRun *client.Build
+	if *to == "" {
+		toRun, err = latestBuild(ctx, c, p, "", client.RunResultSucceeded, nil)
+	} else {
+		toRun, err = releaseRun(ctx, c, *to, p)
+	}
+	if err != nil {
+		return err
+	}
+	switch {
+	case fromRun.Definition.ID != toRun.Definition.ID:
+		return newUsageError(fmt.Sprintf("run %d is of %s and run %d of %s; compare runs of one pipeline", fromRun.ID, fromRun.Definition.Name, toRun.ID, toRun.Definition.Name))
+	case fromRun.ID == toRun.ID:
+		return newUsageError(fmt.Sprintf("--from and --to are both run %d", fromRun.ID))
+	case toRun.QueueTime.Before(fromRun.QueueTime):
+		return newUsageError(fmt.Sprintf("run %d is older than run %d; give the previous release as --from", toRun.ID, fromRun.ID))
+	}
+
+	commits, err := c.ListChangesBetweenBuilds(ctx, fromRun.ID, toRun.ID, *top)
+	if err != nil {
+		return fmt.Errorf("failed to list the commits between runs %d and %d: %w", fromRun.ID, toRun.ID, err)
+	}
+	refs, err := c.ListWorkItemsBetweenBuilds(ctx, fromRun.ID, toRun.ID, *top)
+	if err != nil {
+		return fmt.Errorf("failed to list the work items between runs %d and %d: %w", fromRun.ID, toRun.ID, err)
+	}
+	// Azure DevOps cuts the lists off at --top without saying so
+	if len(commits) == *top {
+		a.warnf("Warning: the notes stop at %d commits, the most --top reads; raise it if there are more.\n", *top)
+	}
+	if len(refs) == *top {
+		a.warnf("Warning: the notes stop at %d work items, the most --top reads; raise it if there are more.\n", *top)
+	}
+	var ids []int
+	for _, ref := range refs {
+		if id, err := strconv.Atoi(ref.ID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	workItems, err := c.GetWorkItems(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("failed to fetch work items: %w", err)
+	}
+
+	*title = cmp.Or(*title, toRun.Definition.Name+" "+toRun.BuildNumber)
+	notes := releaseNotes(c, *title, fromRun, toRun, commits, workItems)
+	a.infof("%s and %s between %s and %s\n", plural(len(commits), "commit"), plural(len(workItems), "work item"), fromRun.BuildNumber, toRun.BuildNumber)
+
+	if *out != "" {
+		if err := os.WriteFile(*out, []byte(notes), 0o644); err != nil {
+			return err
+		}
+		a.infof("Wrote %s\n", *out)
+	} else if *wiki == "" && *prID == 0 {
+		a.resultf("%s", notes)
+	}
+	if *wiki == "" && *prID == 0 {
+		return nil
+	}
+	return publishReleaseNotes(ctx, a, c, flags, notes, *title, *wiki, *wikiPath, *prID)
+}
+
+// releaseRun resolves a --from or --to value: a run ID, or a tag of the
+// latest run with it, of p when one is given.
+func releaseRun(ctx context.Context, c *client.Client, value string, p *client.Pipeline) (*client.Build, error) {
+	if id, err := strconv.Atoi(value); err == nil {
+		b, err := c.GetBuild(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch run %d: %w", id, err)
+		}
+		return b, nil
+	}
+	criteria := client.BuildCriteria{Tags: []string{value}, Top: 1}
+	if p != nil {
+		criteria.Definitions = []int{p.ID}
+	}
+	builds, err := c.ListBuilds(ctx, criteria)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the run tagged %s: %w", value, err)
+	}
+	if len(builds) == 0 {
+		return nil, &client.APIError{StatusCode: http.StatusNotFound, Status: "404 Not Found", Message: fmt.Sprintf("no run is tagged %s", value)}
+	}
+	return &builds[0], nil
+}
+
+// releaseNotes renders the notes as Markdown: work items grouped by type,
+// then the pull requests merged and the other commits.
+func releaseNotes(c *client.Client, title string, fromRun, toRun *client.Build, commits []client.BuildChange, workItems []client.WorkItem) string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# %s\n\n", markdownText.Replace(title))
+	fmt.Fprintf(&b, "%s on %s since %s %s.\n", plural(len(commits), "commit"), markdownText.Replace(buildBranch(*toRun)),
+		markdownText.Replace(fromRun.Definition.Name), fromRun.BuildNumber)
+
+	slices.SortFunc(workItems, func(x, y client.WorkItem) int { return x.ID - y.ID })
+	grouped := map[int]bool{}
+	section := func(heading string, items []client.WorkItem) {
+		if len(items) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n", heading)
+		for _, item := range items {
+			grouped[item.ID] = true
+			fmt.Fprintf(&b, "- [#%d](%s) %s", item.ID, c.WorkItemURL(item.ID), markdownText.Replace(item.Fields.Title))
+			if item.Fields.State != "" {
+				fmt.Fprintf(&b, " (%s)", item.Fields.State)
+			}
+			b.WriteString("\n")
+		}
+	}
+	for _, group := range releaseNoteGroups {
+		var items []client.WorkItem
+		for _, item := range workItems {
+			if slices.Contains(group.types, item.Fields.Type) {
+				items = append(items, item)
+			}
+		}
+		section(group.title, items)
+	}
+	var others []client.WorkItem
+	for _, item := range workItems {
+		if !grouped[item.ID] {
+			others = append(others, item)
+		}
+	}
+	section("Other work items", others)
+
+	var prs []releasePR
+	var rest []client.BuildChange
+	for _, commit := range commits {
+		if pr, ok := parseMergedPR(commit.Message); ok {
+			prs = append(prs, pr)
+		} else {
+			rest = append(rest, commit)
+		}
+	}
+	if len(prs) > 0 {
+		b.WriteString("\n## Pull requests\n\n")
+		for _, pr := range prs {
+			label := fmt.Sprintf("PR %d", pr.number)
+			if link := toRun.Repository.PullRequestURL(pr.number); link != "" {
+				label = fmt.Sprintf("[%s](%s)", label, link)
+			}
+			fmt.Fprintf(&b, "- %s %s\n", label, markdownText.Replace(pr.title))
+		}
+	}
+	if len(rest) > 0 {
+		b.WriteString("\n## Commits\n\n")
+		for _, commit := range rest {
+			message, _, _ := strings.Cut(commit.Message, "\n")
+			id := fmt.Sprintf("`%.7s`", commit.ID)
+			if link := cmp.Or(toRun.Repository.CommitURL(commit.ID), commit.DisplayURI); link != "" {
+				id = fmt.Sprintf("[%s](%s)", id, link)
+			}
+			fmt.Fprintf(&b, "- %s %s (%s)\n", id, markdownText.Replace(strings.TrimSpace(message)), markdownText.Replace(commit.Author.DisplayName))
+		}
+	}
+	return b.String()
+}
+
+// publishReleaseNotes puts the notes on a wiki page, in the description of
+// a pull request, or both. Replacing a description that is not empty is
+// confirmed first.
+func publishReleaseNotes(ctx context.Context, a *app, c *client.Client, flags changeFlags, notes, title, wiki, wikiPath string, prID int) error {
+	if err := a.checkWrite("fomo release-notes"); err != nil {
+		return err
+	}
+	change := plannedChange{verb: i18n.T("publish"), target: "the release notes"}
+	if wiki != "" {
+		wikiPath = cmp.Or(wikiPath, "/Release notes/"+strings.ReplaceAll(title, "/", "-"))
+		change.affects = append(change.affects, fmt.Sprintf("wiki %s, page %s", wiki, wikiPath))
+	}
+	var pr *client.PullRequest
+	if prID != 0 {
+		if len(notes) > maxPRDescription {
+			return fmt.Errorf("the release notes are %d characters long and a pull request description holds %d; publish them to the wiki instead", len(notes), maxPRDescription)
+		}
+		var err error
+		if pr, err = c.GetPullRequest(ctx, prID); err != nil {
+			return fmt.Errorf("failed to fetch pull request %d: %w", prID, err)
+		}
+		line := fmt.Sprintf("the description of pull request %d", prID)
+		if strings.TrimSpace(pr.Description) != "" {
+			line += ", replacing the current one"
+		}
+		change.affects = append(change.affects, line)
+	}
+	if flags.dryRun || pr != nil && strings.TrimSpace(pr.Description) != "" {
+		if ok, err := a.confirmChange(flags, change); !ok {
+			return err
+		}
+	}
+
+	if wiki != "" {
+		page, err := c.PutWikiPage(ctx, wiki, wikiPath, notes)
+		if err != nil {
+			return fmt.Errorf("failed to publish to wiki %s: %w", wiki, err)
+		}
+		a.infof("Published to %s\n", cmp.Or(page.RemoteURL, wikiPath))
+	}
+	if pr != nil {
+		if _, err := c.UpdatePullRequest(ctx, pr.Repository.ID, pr.ID, client.PullRequestUpdate{Description: notes}); err != nil {
+			return fmt.Errorf("failed to update pull request %d: %w", pr.ID, err)
+		}
+		a.infof("Set the description of pull request %d: %s\n", pr.ID, pr.WebURL())
+	}
+	return nil
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"fomo/internal/client"
+	"fomo/internal/i18n"
+	"fomo/internal/params"
+	"fomo/internal/queue"
+	"fomo/internal/watch"
+)
+
+// paramFlags collects repeated --param name=value flags.
+type paramFlags map[string]string
+
+func (f paramFlags) String() string { return "" }
+
+func (f paramFlags) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("expected name=value, got %q", s)
+	}
+	f[name] = value
+	return nil
+}
+
+func runRun(a *app, args []string) error {
+	const usage = "usage: fomo run <pipeline> [--branch NAME] [--commit SHA] [--param NAME=VALUE]... [--var NAME=VALUE]... [--form] [--list-params] [--if-changed GLOB]... [--follow]"
+	fs := a.newFlagSet("run", "<pipeline> [--branch NAME] [--commit SHA] [--param NAME=VALUE]... [--var NAME=VALUE]... [--if-changed GLOB]... [--follow]")
+	branch := fs.String("branch", "", "branch to run (default: the pipeline's default branch)")
+	commit := fs.String("commit", "", "commit of the branch to run instead of its head")
+	given := paramFlags{}
+	fs.Var(given, "param", "set a template parameter, as name=value (repeatable)")
+	variables := paramFlags{}
+	fs.Var(variables, "var", "set a variable that is settable at queue time, as name=value (repeatable)")
+	form := fs.Bool("form", false, "ask for every template parameter, showing defaults and allowed values")
+	listParams := fs.Bool("list-params", false, "list the pipeline's template parameters and exit")
+	follow := fs.Bool("follow", false, "wait for the run to finish, showing its queue position while it waits for an agent")
+	interval := fs.Duration("interval", 10*time.Second, "how often to check the run with --follow")
+	var ifChanged tagFlags
+	fs.Var(&ifChanged, "if-changed", "only run when files matching this glob changed since the branch's last successful run (repeatable)")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return newUsageError(usage)
+	}
+	globs, err := parsePathGlobs(ifChanged)
+	if err != nil {
+		return newUsageError(err.Error())
+	}
+	// Listing parameters queues nothing
+	a.dryRun = *listParams
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+
+	p, err := resolvePipeline(ctx, c, positional[0])
+	if err != nil {
+		return err
+	}
+	if len(globs) > 0 && *branch == "" {
+		*branch = currentGitBranch()
+	}
+	*branch = a.pipelineBranch(p, *branch)
+	if *commit != "" && *branch == "" {
+		return newUsageError("--commit needs the --branch it is on")
+	}
+	if len(globs) > 0 {
+		changed, err := changedSinceLastSuccess(ctx, a, c, p, branch, globs)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			a.dryRun = true
+			return nil
+		}
+	}
+
+	// Check parameters locally: a typo should not cost a round trip and an
+	// opaque 400 from the API
+	declared, err := discoverParameters(ctx, c, p, *branch)
+	switch {
+	case err != nil && (*listParams || *form):
+		return err
+	case err != nil:
+		if len(given) > 0 {
+			a.warnf("Could not check parameters: %v\n", err)
+		}
+	case *listParams:
+		printParameters(a, declared)
+		return nil
+	default:
+		if err := fillParameters(a, declared, given, *form); err != nil {
+			return err
+		}
+	}
+
+	request := runRequestForBranch(*branch)
+	if *commit != "" {
+		self := request.Resources.Repositories["self"]
+		self.Version = *commit
+		request.Resources.Repositories["self"] = self
+	}
+	if len(given) > 0 {
+		request.TemplateParameters = given
+	}
+	for name, value := range variables {
+		if request.Variables == nil {
+			request.Variables = map[string]client.Variable{}
+		}
+		request.Variables[name] = client.Variable{Value: value}
+	}
+	run, err := c.RunPipeline(ctx, p.ID, request)
+	if err != nil {
+		return fmt.Errorf("failed to queue %s: %w", p.Name, err)
+	}
+	a.infof("Queued %s #%s\n", p.Name, run.Name)
+	if run.Links.Web != nil {
+		a.infof("%s\n", run.Links.Web.Href)
+	}
+	a.resultf("%d\n", run.ID)
+
+	if !*follow {
+		// One look at the queue, so a long wait is not a surprise
+		if estimate, pool, ok := queueEstimate(ctx, c, run.ID); ok {
+			a.infof("%s\n", describeQueue(a, estimate, pool))
+		}
+		return nil
+	}
+	return followRun(ctx, a, c, p, run, *interval)
+}
+
+// changedSinceLastSuccess decides whether a pipeline needs to run: whether
+// files matching globs changed on the branch since the commit of its last
+// successful run there. An empty branch is resolved to the repository's
+// default branch. The decision is printed either way.
+func changedSinceLastSuccess(ctx context.Context, a *app, c *client.Client, p *client.Pipeline, branch *string, globs []*pathGlob) (bool, error) {
+	full, err := c.GetPipeline(ctx, p.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch pipeline %s: %w", p.Name, err)
+	}
+	config := full.Configuration
+	if config == nil || config.Repository.Type != "azureReposGit" {
+		return false, fmt.Errorf("--if-changed needs a pipeline in Azure Repos; %s is not", p.Name)
+	}
+	repositoryID := config.Repository.ID
+	if *branch == "" {
+		repo, err := c.GetRepository(ctx, repositoryID)
+		if err != nil {
+			return false, fmt.Errorf("failed to fetch the repository of %s: %w", p.Name, err)
+		}
+		*branch = repo.DefaultBranch
+	}
+	name := watch.ShortBranch(*branch)
+	head, err := c.BranchCommit(ctx, repositoryID, name)
+	if err != nil {
+		return false, fmt.Errorf("failed to find branch %s: %w", name, err)
+	}
+	if head == "" {
+		return false, &client.APIError{StatusCode: http.StatusNotFound, Status: "404 Not Found", Message: fmt.Sprintf("%s has no branch %s", config.Repository.Name, name)}
+	}
+
+	last, err := c.ListBuilds(ctx, client.BuildCriteria{Definitions: []int{p.ID}, Branch: name, Result: client.RunResultSucceeded, Top: 1})
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch the runs of %s: %w", p.Name, err)
+	}
+	if len(last) == 0 || last[0].SourceVersion == "" {
+		a.infof("Running %s on %s: it has no successful run there to compare with.\n", p.Name, name)
+		return true, nil
+	}
+	base := last[0]
+	since := fmt.Sprintf("run %d (%s)", base.ID, shortCommit(base.SourceVersion))
+	if base.SourceVersion == head {
+		a.infof("Not running %s on %s: nothing was pushed since %s.\n", p.Name, name, since)
+		return false, nil
+	}
+	files, complete, err := changedFiles(ctx, c, repositoryID, base.SourceVersion, head)
+	if err != nil {
+		return false, err
+	}
+	matched := matchingFiles(files, globs)
+	if len(matched) == 0 && !complete {
+		// What was left out may match
+		a.warnf("Warning: Azure DevOps listed only %d of the files changed since %s; running %s in case the others match.\n", len(files), since, p.Name)
+		return true, nil
+	}
+	if len(matched) == 0 {
+		a.infof("Not running %s on %s: none of the %d files changed since %s match.\n", p.Name, name, len(files), since)
+		return false, nil
+	}
+	a.infof("Running %s on %s: %s changed since %s.\n", p.Name, name, describeFiles(matched), since)
+	return true, nil
+}
+
+// discoverParameters reads the parameters declared in a YAML pipeline's
+// file on the branch to be run.
+func discoverParameters(ctx context.Context, c *client.Client, p *client.Pipeline, branch string) ([]params.Parameter, error) {
+	full, err := c.GetPipeline(ctx, p.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pipeline %s: %w", p.Name, err)
+	}
+	config := full.Configuration
+	if config == nil || config.Type != "yaml" || config.Repository.Type != "azureReposGit" {
+		return nil, fmt.Errorf("%s is not a YAML pipeline in Azure Repos", p.Name)
+	}
+	content, err := c.GetFileContent(ctx, config.Repository.ID, config.Path, branch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", config.Path, err)
+	}
+	return params.Parse(content)
+}
+
+// fillParameters validates the given parameters and asks for missing
+// required ones, or for all of them with form.
+func fillParameters(a *app, declared []params.Parameter, given paramFlags, form bool) error {
+	missing, err := params.Validate(declared, given)
+	if err != nil {
+		return newUsageError(err.Error())
+	}
+
+	ask := missing
+	if form {
+		ask = declared
+	}
+	if len(ask) == 0 {
+		return nil
+	}
+	if a.noPrompt || !isTerminal(os.Stdin) {
+		var names []string
+		for _, p := range missing {
+			names = append(names, p.Name)
+		}
+		return newUsageError(fmt.Sprintf("missing required parameters: %s (set them with --param NAME=VALUE)", strings.Join(names, ", ")))
+	}
+
+	for _, p := range ask {
+		if _, ok := given[p.Name]; ok && !form {
+			continue
+		}
+		current := p.DefaultString()
+		if v, ok := given[p.Name]; ok {
+			current = v
+		}
+		for {
+			prompt := p.Label()
+			if len(p.Values) > 0 {
+				prompt += " (" + strings.Join(p.Values, ", ") + ")"
+			} else if p.Type != params.TypeString {
+				prompt += " (" + p.Type + ")"
+			}
+			if current != "" {
+				prompt += " [" + current + "]"
+			}
+			value := promptUser(prompt + ": ")
+			if value == "" {
+				value = current
+			}
+			if value == "" && p.Required() {
+				fmt.Fprintln(a.stderr, i18n.T("A value is required."))
+				continue
+			}
+			if value == "" {
+				break
+			}
+			if err := p.Check(value); err != nil {
+				fmt.Fprintln(a.stderr, err)
+				continue
+			}
+			if value != p.DefaultString() || p.Required() {
+				given[p.Name] = value
+			}
+			break
+		}
+	}
+	return nil
+}
+
+func printParameters(a *app, declared []params.Parameter) {
+	if len(declared) == 0 {
+		a.infof("The pipeline declares no parameters.\n")
+		return
+	}
+	for _, p := range declared {
+		if a.quiet {
+			a.resultf("%s\n", p.Name)
+			continue
+		}
+		line := fmt.Sprintf("%-20s %-8s", p.Name, p.Type)
+		if p.Required() {
+			line += " required"
+		} else {
+			line += " default: " + p.DefaultString()
+		}
+		if len(p.Values) > 0 {
+			line += "  values: " + strings.Join(p.Values, ", ")
+		}
+		if p.DisplayName != "" {
+			line += "  (" + p.DisplayName + ")"
+		}
+		a.resultf("%s\n", line)
+	}
+}
+
+// followRun polls a run until it completes, reporting its queue position
+// while it waits for an agent.
+func followRun(ctx context.Context, a *app, c *client.Client, p *client.Pipeline, run *client.Run, interval time.Duration) error {
+	var last string
+	for run.State != client.RunStateCompleted {
+		status := "Running"
+		if estimate, pool, ok := queueEstimate(ctx, c, run.ID); ok {
+			status = describeQueue(a, estimate, pool)
+		}
+		if status != last {
+			a.infof("%s %s\n", a.display.time(time.Now(), time.TimeOnly), status)
+			last = status
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+		latest, err := c.GetRun(ctx, p.ID, run.ID)
+		if err != nil {
+			return fmt.Errorf("failed to check run %d: %w", run.ID, err)
+		}
+		run = latest
+	}
+
+	a.infof("%s #%s %s\n", p.Name, run.Name, run.Result)
+	if run.Result != client.RunResultSucceeded {
+		return errPipelineFailed
+	}
+	return nil
+}
+
+// queueEstimate looks up the run's position in its agent pool. It reports
+// false when the run is not waiting for an agent or the pool cannot be
+// inspected.
+func queueEstimate(ctx context.Context, c *client.Client, runID int) (queue.Estimate, string, bool) {
+	build, err := c.GetBuild(ctx, runID)
+	if err != nil || build.Queue.Pool.ID == 0 {
+		return queue.Estimate{}, "", false
+	}
+	requests, err := c.ListJobRequests(ctx, build.Queue.Pool.ID)
+	if err != nil {
+		return queue.Estimate{}, "", false
+	}
+
+	// Hosted pools scale with the organization's parallel jobs, which the
+	// estimate infers from what is running
+	agents := 0
+	if !build.Queue.Pool.IsHosted {
+		if list, err := c.ListAgents(ctx, build.Queue.Pool.ID); err == nil {
+			for _, agent := range list {
+				if agent.Enabled && agent.Status == "online" {
+					agents++
+				}
+			}
+		}
+	}
+
+	estimate, ok := queue.Estimated(requests, runID, agents, time.Now())
+	return estimate, build.Queue.Pool.Name, ok
+}
+
+func describeQueue(a *app, e queue.Estimate, pool string) string {
+	if e.Ahead == 0 && e.Wait == 0 {
+		return fmt.Sprintf("Next in line for an agent in %s", pool)
+	}
+	return fmt.Sprintf("Waiting for an agent in %s: position %d, %d job(s) running on %d agent(s), starts in about %s",
+		pool, e.Position, e.Running, e.Agents, a.display.duration(e.Wait))
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"fomo/internal/client"
+	"fomo/internal/yaml"
+)
+
+// serviceMap maps directories of a monorepo to the pipelines that build
+// them, as read from the file given to run-matrix --map:
+//
+//	services:
+//	  - pipeline: service-a
+//	    paths: [src/service-a, libs/common]
+//	  - pipeline: service-b
+//	    paths: [src/service-b/**]
+//	    params: {environment: dev}
+type serviceMap struct {
+	Services []serviceEntry `yaml:"services"`
+}
+
+type serviceEntry struct {
+	Pipeline string            `yaml:"pipeline"`
+	Paths    []string          `yaml:"paths"`
+	Params   map[string]string `yaml:"params"`
+
+	globs []*pathGlob
+}
+
+// loadServiceMap reads and validates a service map.
+func loadServiceMap(path string) (*serviceMap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m serviceMap
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if len(m.Services) == 0 {
+		return nil, fmt.Errorf("%s maps no services", path)
+	}
+	for i := range m.Services {
+		s := &m.Services[i]
+		if s.Pipeline == "" || len(s.Paths) == 0 {
+			return nil, fmt.Errorf("%s: service %d needs a pipeline and paths", path, i+1)
+		}
+		if s.globs, err = parsePathGlobs(s.Paths); err != nil {
+			return nil, fmt.Errorf("%s: service %d: %w", path, i+1, err)
+		}
+	}
+	return &m, nil
+}
+
+// gitChangedFiles lists the files changed in the repository in the
+// working directory on to since it forked from from, as git diff from...to
+// does: changes made only on from do not count. With renames off, a moved
+// file counts for both its old and new directory.
+func gitChangedFiles(from, to string) ([]string, error) {
+	out, err := git("diff", "--name-only", "--no-renames", from+"..."+to)
+	if err != nil {
+		return nil, err
+	}
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// matrixRun is a pipeline run-matrix triggers, and what triggered it.
+type matrixRun struct {
+	service  serviceEntry
+	pipeline *client.Pipeline
+	matched  []string
+	run      *client.Run
+	err      error
+}
+
+func runRunMatrix(a *app, args []string) error {
+	const usage = "usage: fomo run-matrix --map FILE --from REV [--to REV] [--branch NAME] [--dry-run] [--wait]"
+	fs := a.newFlagSet("run-matrix", "--map FILE --from REV [--to REV] [--branch NAME] [--dry-run] [--wait]")
+	mapFile := fs.String("map", "", "YAML file mapping directories to the pipelines that build them")
+	from := fs.String("from", "", "revision to compare from, such as origin/main or the last deployed commit")
+	to := fs.String("to", "HEAD", "revision to compare to")
+	branch := fs.String("branch", "", "branch to run (default: the checked-out branch)")
+	dryRun := fs.Bool("dry-run", false, "show which pipelines would run without queuing them")
+	wait := fs.Bool("wait", false, "wait for every queued run to finish")
+	interval := fs.Duration("interval", 10*time.Second, "how often to check the runs with --wait")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 || *mapFile == "" || *from == "" {
+		return newUsageError(usage)
+	}
+	a.dryRun = *dryRun
+	services, err := loadServiceMap(*mapFile)
+	if err != nil {
+		return err
+	}
+	files, err := gitChangedFiles(*from, *to)
+	if err != nil {
+		return fmt.Errorf("failed to list the files changed from %s to %s: %w", *from, *to, err)
+	}
+	if *branch == "" {
+		*branch = currentGitBranch()
+	}
+
+	var runs []*matrixRun
+	for _, s := range services.Services {
+		if matched := matchingFiles(files, s.globs); len(matched) > 0 {
+			runs = append(runs, &matrixRun{service: s, matched: matched})
+		}
+	}
+	if len(runs) == 0 {
+		a.infof("None of the %d files changed from %s to %s belong to a mapped service.\n", len(files), *from, *to)
+		// Nothing was queued, so there is nothing to audit
+		a.dryRun = true
+		return nil
+	}
+	if *dryRun {
+		if a.quiet {
+			for _, r := range runs {
+				a.resultf("%s\n", r.service.Pipeline)
+			}
+			return nil
+		}
+		w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "PIPELINE\tCHANGED")
+		for _, r := range runs {
+			fmt.Fprintf(w, "%s\t%s\n", r.service.Pipeline, describeFiles(r.matched))
+		}
+		return w.Flush()
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 8)
+	for _, r := range runs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			r.pipeline, r.err = resolvePipeline(ctx, c, r.service.Pipeline)
+			if r.err != nil {
+				return
+			}
+			request := runRequestForBranch(a.pipelineBranch(r.pipeline, *branch))
+			if len(r.service.Params) > 0 {
+				request.TemplateParameters = r.service.Params
+			}
+			r.run, r.err = c.RunPipeline(ctx, r.pipeline.ID, request)
+		}()
+	}
+	wg.Wait()
+
+	var queued []*matrixRun
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	if !a.quiet {
+		fmt.Fprintln(w, "PIPELINE\tRUN\tCHANGED")
+	}
+	for _, r := range runs {
+		switch {
+		case r.err != nil:
+			a.warnf("Failed to queue %s: %v\n", r.service.Pipeline, r.err)
+		case a.quiet:
+			queued = append(queued, r)
+			a.resultf("%s\t%d\n", r.pipeline.Name, r.run.ID)
+		default:
+			queued = append(queued, r)
+			fmt.Fprintf(w, "%s\t%d\t%s\n", r.pipeline.Name, r.run.ID, describeFiles(r.matched))
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	// The runs that did queue are waited for even if others failed to
+	var waitErr error
+	if *wait && len(queued) > 0 {
+		waitErr = waitRuns(ctx, a, c, queued, *interval)
+	}
+	if failed := len(runs) - len(queued); failed > 0 && (waitErr == nil || errors.Is(waitErr, errPipelineFailed)) {
+		return fmt.Errorf("failed to queue %d of %d pipelines", failed, len(runs))
+	}
+	return waitErr
+}
+
+// waitRuns polls queued runs until all of them finish, reporting each as
+// it does. It fails with errPipelineFailed if any run did not succeed.
+func waitRuns(ctx context.Context, a *app, c *client.Client, runs []*matrixRun, interval time.Duration) error {
+	pending := len(runs)
+	unsuccessful := 0
+	for pending > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+		for _, r := range runs {
+			if r.run.State == client.RunStateCompleted {
+				continue
+			}
+			latest, err := c.GetRun(ctx, r.pipeline.ID, r.run.ID)
+			if err != nil {
+				return fmt.Errorf("failed to check run %d: %w", r.run.ID, err)
+			}
+			r.run = latest
+			if latest.State != client.RunStateCompleted {
+				continue
+			}
+			pending--
+			if latest.Result != client.RunResultSucceeded {
+				unsuccessful++
+			}
+			a.infof("%s %s #%s %s (%d still running)\n", a.display.time(time.Now(), time.TimeOnly), r.pipeline.Name, latest.Name, latest.Result, pending)
+		}
+	}
+	if unsuccessful > 0 {
+		a.infof("%d of %d runs did not succeed.\n", unsuccessful, len(runs))
+		return errPipelineFailed
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"fomo/internal/client"
+	"fomo/internal/i18n"
+)
+
+// parseRunID parses a run ID given on the command line.
+func parseRunID(arg string) (int, error) {
+	id, err := strconv.Atoi(arg)
+	if err != nil || id <= 0 {
+		return 0, newUsageError(fmt.Sprintf("invalid run ID %q", arg))
+	}
+	return id, nil
+}
+
+// findStage resolves a stage of a run by identifier or display name.
+func findStage(timeline *client.Timeline, stage string) (*client.TimelineRecord, error) {
+	var names []string
+	for i, record := range timeline.Records {
+		if record.Type != client.RecordStage {
+			continue
+		}
+		if record.Identifier == stage || record.Name == stage {
+			return &timeline.Records[i], nil
+		}
+		names = append(names, record.Identifier)
+	}
+	return nil, newUsageError(fmt.Sprintf("run has no stage %q (stages: %v)", stage, names))
+}
+
+func runRunsRetry(a *app, args []string) error {
+	fs := a.newFlagSet("runs retry", "<run-id> [--stage NAME] [--all-jobs]")
+	stage := fs.String("stage", "", "only retry this stage (identifier or display name)")
+	allJobs := fs.Bool("all-jobs", false, "retry every job of the stage, not just the failed ones")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return newUsageError("usage: fomo runs retry <run-id> [--stage NAME] [--all-jobs]")
+	}
+	runID, err := parseRunID(positional[0])
+	if err != nil {
+		return err
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+
+	if *stage == "" {
+		if *allJobs {
+			return newUsageError("--all-jobs requires --stage")
+		}
+		if err := c.RetryBuild(ctx, runID); err != nil {
+			return fmt.Errorf("failed to retry run %d: %w", runID, err)
+		}
+		a.infof("Retrying failed jobs of run %d\n", runID)
+		a.resultf("%d\n", runID)
+		return nil
+	}
+	return retryStage(ctx, a, c, runID, *stage, *allJobs)
+}
+
+func retryStage(ctx context.Context, a *app, c *client.Client, runID int, stage string, allJobs bool) error {
+	timeline, err := c.GetTimeline(ctx, runID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch timeline of run %d: %w", runID, err)
+	}
+	record, err := findStage(timeline, stage)
+	if err != nil {
+		return err
+	}
+	if err := c.RetryStage(ctx, runID, record.Identifier, allJobs); err != nil {
+		return fmt.Errorf("failed to retry stage %s of run %d: %w", record.Identifier, runID, err)
+	}
+
+	scope := "failed jobs"
+	if allJobs {
+		scope = "all jobs"
+	}
+	a.infof("Retrying %s of stage %s in run %d\n", scope, record.Name, runID)
+	a.resultf("%d\n", runID)
+	return nil
+}
+
+func runRunsCancel(a *app, args []string) error {
+	const usage = "usage: fomo runs cancel <run-id>... | --pipeline NAME [--branch NAME] [--dry-run] [--yes]"
+	fs := a.newFlagSet("runs cancel", "<run-id>... | --pipeline NAME [--branch NAME] [--dry-run] [--yes]")
+	pipeline := fs.String("pipeline", "", "cancel every queued or running run of this pipeline")
+	branch := fs.String("branch", "", "with --pipeline, only cancel the runs of this branch")
+	var flags changeFlags
+	flags.register(fs)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if (len(positional) == 0) == (*pipeline == "") || *branch != "" && *pipeline == "" {
+		return newUsageError(usage)
+	}
+	var ids []int
+	for _, arg := range positional {
+		id, err := parseRunID(arg)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	var builds []client.Build
+	if *pipeline != "" {
+		p, err := resolvePipeline(ctx, c, *pipeline)
+		if err != nil {
+			return err
+		}
+		all, err := c.ListBuilds(ctx, client.BuildCriteria{Definitions: []int{p.ID}, Branch: *branch})
+		if err != nil {
+			return fmt.Errorf("failed to fetch the runs of %s: %w", p.Name, err)
+		}
+		for _, b := range all {
+			if b.Status != client.RunStateCompleted && b.Status != client.BuildStatusCancelling {
+				builds = append(builds, b)
+			}
+		}
+		if len(builds) == 0 {
+			a.infof("%s has no queued or running runs to cancel\n", p.Name)
+			// Nothing was canceled, so there is nothing to audit
+			a.dryRun = true
+			return nil
+		}
+	}
+	for _, id := range ids {
+		b, err := c.GetBuild(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to fetch run %d: %w", id, err)
+		}
+		if b.Status == client.RunStateCompleted || b.Status == client.BuildStatusCancelling {
+			a.warnf("Run %d has already finished or is canceling\n", id)
+			continue
+		}
+		builds = append(builds, *b)
+	}
+	if len(builds) == 0 {
+		a.dryRun = true
+		return nil
+	}
+
+	change := plannedChange{verb: i18n.T("cancel"), target: plural(len(builds), "run")}
+	for _, b := range builds {
+		state := "queued " + a.display.duration(time.Since(b.QueueTime)) + " ago"
+		if b.StartTime != nil {
+			state = "running for " + a.display.duration(time.Since(*b.StartTime))
+		}
+		change.affects = append(change.affects, fmt.Sprintf("%d  %s %s on %s, %s", b.ID, b.Definition.Name, b.BuildNumber, buildBranch(b), state))
+	}
+	if ok, err := a.confirmChange(flags, change); !ok {
+		return err
+	}
+	for _, b := range builds {
+		if err := c.CancelBuild(ctx, b.ID); err != nil {
+			return fmt.Errorf("failed to cancel run %d: %w", b.ID, err)
+		}
+		a.infof("Canceling %s %s\n", b.Definition.Name, b.BuildNumber)
+		a.resultf("%d\n", b.ID)
+	}
+	return nil
+}
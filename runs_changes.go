@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+func runRunsChanges(a *app, args []string) error {
+	fs := a.newFlagSet("runs changes", "<run-id> [--top N]")
+	top := fs.Int("top", 50, "list at most this many commits")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return newUsageError("usage: fomo runs changes <run-id> [--top N]")
+	}
+	if *top <= 0 {
+		return newUsageError("--top must be positive")
+	}
+	runID, err := parseRunID(positional[0])
+	if err != nil {
+		return err
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	changes, err := c.ListBuildChanges(ctx, runID, *top)
+	if err != nil {
+		return fmt.Errorf("failed to list the changes of run %d: %w", runID, err)
+	}
+
+	// Mentioned Jira issues, when a Jira site is configured
+	keys := make([][]string, len(changes))
+	var allKeys []string
+	for i, change := range changes {
+		keys[i] = issueKeys(change.Message, a.cfg.Jira.Projects)
+		for _, key := range keys[i] {
+			if !slices.Contains(allKeys, key) {
+				allKeys = append(allKeys, key)
+			}
+		}
+	}
+	var issues map[string]jiraIssue
+	if len(allKeys) > 0 {
+		jira, err := a.jira()
+		if err != nil {
+			a.warnf("Not looking up Jira issues: %v\n", err)
+		}
+		if jira != nil {
+			if issues, err = jira.issues(ctx, allKeys); err != nil {
+				a.warnf("Could not look up Jira issues: %v\n", err)
+			}
+		}
+	}
+
+	if a.quiet {
+		for i, change := range changes {
+			a.resultf("%s\t%s\t%s\n", change.ID, change.Author.DisplayName, strings.Join(keys[i], ","))
+		}
+		return nil
+	}
+	if len(changes) == 0 {
+		a.infof("Run %d brought in no new commits.\n", runID)
+		return nil
+	}
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "COMMIT\tAUTHOR\tDATE\tMESSAGE")
+	for _, change := range changes {
+		message, _, _ := strings.Cut(change.Message, "\n")
+		fmt.Fprintf(w, "%.8s\t%s\t%s\t%s\n", change.ID, change.Author.DisplayName, a.display.time(change.Timestamp, time.DateOnly), message)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if len(issues) == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(a.stdout)
+	w = tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ISSUE\tSTATUS\tSUMMARY")
+	for _, key := range allKeys {
+		if issue, ok := issues[key]; ok {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", issue.Key, issue.Status, issue.Summary)
+		}
+	}
+	return w.Flush()
+}
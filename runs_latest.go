@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"fomo/internal/client"
+	"fomo/internal/watch"
+)
+
+// runsLatestFuncs are the functions runs latest templates may use besides
+// the built-in ones.
+var runsLatestFuncs = template.FuncMap{
+	"shortBranch": watch.ShortBranch,
+	"join":        strings.Join,
+}
+
+func runRunsLatest(a *app, args []string) error {
+	const usage = "usage: fomo runs latest <pipeline> [--branch NAME] [--result succeeded|any|RESULT] [--tag TAG]... [--format TEMPLATE]"
+	fs := a.newFlagSet("runs latest", "<pipeline> [--branch NAME] [--result succeeded|any|RESULT] [--tag TAG]... [--format TEMPLATE]")
+	branch := fs.String("branch", "", "only runs of this branch")
+	result := fs.String("result", client.RunResultSucceeded, "only runs with this result, or any for every finished run")
+	var tags tagFlags
+	fs.Var(&tags, "tag", "only runs with this tag (repeatable)")
+	format := fs.String("format", "{{.BuildNumber}}", "text/template over the run, e.g. '{{.ID}} {{.SourceVersion}}'")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return newUsageError(usage)
+	}
+	t, err := template.New("run").Funcs(runsLatestFuncs).Parse(*format)
+	if err != nil {
+		return newUsageError(fmt.Sprintf("invalid --format: %v", err))
+	}
+	if *result == "any" {
+		*result = ""
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	p, err := resolvePipeline(ctx, c, positional[0])
+	if err != nil {
+		return err
+	}
+	b, err := latestBuild(ctx, c, p, *branch, *result, tags)
+	if err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	if err := t.Execute(&out, b); err != nil {
+		return newUsageError(fmt.Sprintf("invalid --format: %v", err))
+	}
+	a.resultf("%s\n", out.String())
+	return nil
+}
+
+// latestBuild returns the latest finished run of a pipeline on branch, with
+// result unless it is empty, and with all of tags.
+func latestBuild(ctx context.Context, c *client.Client, p *client.Pipeline, branch, result string, tags []string) (*client.Build, error) {
+	builds, err := c.ListBuilds(ctx, client.BuildCriteria{
+		Definitions: []int{p.ID},
+		Branch:      branch,
+		Tags:        tags,
+		Status:      client.RunStateCompleted,
+		Result:      result,
+		Top:         1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the runs of %s: %w", p.Name, err)
+	}
+	if len(builds) == 0 {
+		what := "finished run"
+		if result != "" {
+			what = result + " run"
+		}
+		message := fmt.Sprintf("%s has no %s", p.Name, what)
+		if branch != "" {
+			message += " on " + watch.ShortBranch(branch)
+		}
+		if len(tags) > 0 {
+			message += " tagged " + strings.Join(tags, ", ")
+		}
+		return nil, &client.APIError{StatusCode: http.StatusNotFound, Status: "404 Not Found", Message: message}
+	}
+	return &builds[0], nil
+}
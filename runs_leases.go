@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"fomo/internal/client"
+	"fomo/internal/i18n"
+)
+
+// leaseOwner is the owner of the leases fomo adds and lists: the one given
+// or, by convention, User:<ID> of the PAT's user.
+func leaseOwner(ctx context.Context, c *client.Client, owner string) (string, error) {
+	if owner != "" {
+		return owner, nil
+	}
+	me, err := c.Me(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve current user: %w", err)
+	}
+	return "User:" + me.ID, nil
+}
+
+func runRunsRetain(a *app, args []string) error {
+	fs := a.newFlagSet("runs retain", "<run-id> [--days N] [--owner ID] [--protect-pipeline]")
+	days := fs.Int("days", 365, "keep the run for this many days")
+	owner := fs.String("owner", "", "owner of the lease, e.g. System:release (default: User:<your ID>)")
+	protect := fs.Bool("protect-pipeline", false, "also keep the pipeline from being deleted while the lease lasts")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return newUsageError("usage: fomo runs retain <run-id> [--days N] [--owner ID] [--protect-pipeline]")
+	}
+	runID, err := parseRunID(positional[0])
+	if err != nil {
+		return err
+	}
+	if *days <= 0 {
+		return newUsageError("--days must be positive")
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	build, err := c.GetBuild(ctx, runID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch run %d: %w", runID, err)
+	}
+	ownerID, err := leaseOwner(ctx, c, *owner)
+	if err != nil {
+		return err
+	}
+	lease, err := c.AddRetentionLease(ctx, client.NewRetentionLease{
+		DefinitionID:    build.Definition.ID,
+		RunID:           runID,
+		OwnerID:         ownerID,
+		DaysValid:       *days,
+		ProtectPipeline: *protect,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to retain run %d: %w", runID, err)
+	}
+	a.infof("Retaining %s %s (run %d) until %s, lease %d\n", build.Definition.Name, build.BuildNumber, runID,
+		a.display.time(lease.ValidUntil, time.DateOnly), lease.LeaseID)
+	a.resultf("%d\n", lease.LeaseID)
+	return nil
+}
+
+func runRunsLeasesList(a *app, args []string) error {
+	fs := a.newFlagSet("runs leases list", "[<run-id>] [--owner ID] [--pipeline NAME]")
+	owner := fs.String("owner", "", "only list the leases of this owner (default without a run: User:<your ID>)")
+	pipeline := fs.String("pipeline", "", "only list leases on runs of this pipeline (name or ID)")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 1 {
+		return newUsageError("usage: fomo runs leases list [<run-id>] [--owner ID] [--pipeline NAME]")
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	var leases []client.RetentionLease
+	if len(positional) == 1 {
+		runID, err := parseRunID(positional[0])
+		if err != nil {
+			return err
+		}
+		if *pipeline != "" {
+			return newUsageError("--pipeline cannot be used with a run ID")
+		}
+		all, err := c.ListBuildLeases(ctx, runID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch the leases of run %d: %w", runID, err)
+		}
+		for _, l := range all {
+			if *owner == "" || l.OwnerID == *owner {
+				leases = append(leases, l)
+			}
+		}
+	} else {
+		definitionID := 0
+		if *pipeline != "" {
+			p, err := resolvePipeline(ctx, c, *pipeline)
+			if err != nil {
+				return err
+			}
+			definitionID = p.ID
+		}
+		ownerID, err := leaseOwner(ctx, c, *owner)
+		if err != nil {
+			return err
+		}
+		if leases, err = c.ListOwnerLeases(ctx, ownerID, definitionID); err != nil {
+			return fmt.Errorf("failed to fetch the leases of %s: %w", ownerID, err)
+		}
+	}
+	sort.Slice(leases, func(i, j int) bool { return leases[i].ValidUntil.Before(leases[j].ValidUntil) })
+
+	if a.quiet {
+		for _, l := range leases {
+			a.resultf("%d\t%d\t%s\n", l.LeaseID, l.RunID, l.ValidUntil.UTC().Format(time.RFC3339))
+		}
+		return nil
+	}
+	if len(leases) == 0 {
+		a.infof("No retention leases found.\n")
+		return nil
+	}
+	names := map[int]string{}
+	if pipelines, err := c.ListPipelines(ctx); err == nil {
+		for _, p := range pipelines {
+			names[p.ID] = p.Name
+		}
+	}
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "LEASE\tRUN\tPIPELINE\tOWNER\tCREATED\tVALID UNTIL")
+	for _, l := range leases {
+		protected := ""
+		if l.ProtectPipeline {
+			protected = " (protects pipeline)"
+		}
+		name, ok := names[l.DefinitionID]
+		if !ok {
+			name = strconv.Itoa(l.DefinitionID)
+		}
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s%s\n", l.LeaseID, l.RunID, name, l.OwnerID,
+			a.display.time(l.CreatedOn, time.DateOnly), a.display.time(l.ValidUntil, time.DateOnly), protected)
+	}
+	return w.Flush()
+}
+
+func runRunsLeasesRemove(a *app, args []string) error {
+	fs := a.newFlagSet("runs leases remove", "<lease-id>... | --run ID [--owner ID] [--dry-run] [--yes]")
+	run := fs.Int("run", 0, "remove the leases on this run")
+	owner := fs.String("owner", "", "with --run, only remove the leases of this owner")
+	var flags changeFlags
+	flags.register(fs)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if (len(positional) == 0) == (*run == 0) || *owner != "" && *run == 0 {
+		return newUsageError("usage: fomo runs leases remove <lease-id>... | --run ID [--owner ID] [--dry-run] [--yes]")
+	}
+	var ids []int
+	for _, arg := range positional {
+		id, err := strconv.Atoi(arg)
+		if err != nil || id <= 0 {
+			return newUsageError(fmt.Sprintf("invalid lease ID %q", arg))
+		}
+		ids = append(ids, id)
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	change := plannedChange{verb: i18n.T("remove"), target: plural(len(ids), "retention lease")}
+	for _, id := range ids {
+		change.affects = append(change.affects, fmt.Sprintf("lease %d", id))
+	}
+	if *run != 0 {
+		leases, err := c.ListBuildLeases(ctx, *run)
+		if err != nil {
+			return fmt.Errorf("failed to fetch the leases of run %d: %w", *run, err)
+		}
+		for _, l := range leases {
+			if *owner == "" || l.OwnerID == *owner {
+				ids = append(ids, l.LeaseID)
+				change.affects = append(change.affects, fmt.Sprintf("lease %d of %s, until %s", l.LeaseID, l.OwnerID, a.display.time(l.ValidUntil, "2006-01-02")))
+			}
+		}
+		if len(ids) == 0 {
+			a.infof("Run %d has no retention leases to remove\n", *run)
+			return nil
+		}
+		change.target = fmt.Sprintf("%s of run %d", plural(len(ids), "retention lease"), *run)
+	}
+	if ok, err := a.confirmChange(flags, change); !ok {
+		return err
+	}
+	if err := c.DeleteRetentionLeases(ctx, ids); err != nil {
+		return fmt.Errorf("failed to remove retention leases: %w", err)
+	}
+	a.infof("Removed %d retention lease(s)\n", len(ids))
+	return nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"fomo/internal/client"
+	"fomo/internal/watch"
+)
+
+// buildStatus is the result of a finished run, or its state otherwise.
+func buildStatus(b client.Build) string {
+	if b.Status == client.RunStateCompleted {
+		return b.Result
+	}
+	return b.Status
+}
+
+// buildBranch is the branch a run built. A pull request run shows the
+// number of the pull request, and its source branch when the trigger
+// recorded it, rather than the merge ref.
+func buildBranch(b client.Build) string {
+	n := b.PullRequest()
+	switch {
+	case n == 0:
+		return watch.ShortBranch(b.SourceBranch)
+	case b.BranchName() != b.SourceBranch:
+		return fmt.Sprintf("%s (PR %d)", watch.ShortBranch(b.BranchName()), n)
+	}
+	return fmt.Sprintf("PR %d", n)
+}
+
+// runReasons maps the --reason values of runs list to the reasons of the
+// build API.
+var runReasons = map[string]string{
+	"manual":       "manual",
+	"pr":           "pullRequest",
+	"schedule":     "schedule",
+	"individualci": "individualCI",
+}
+
+func runRunsList(a *app, args []string) error {
+	const usage = "[--pipeline NAME] [--branch NAME] [--tag TAG]... [--requested-by me|USER] [--reason manual|pr|schedule|individualCI] [--top N]"
+	fs := a.newFlagSet("runs list", usage)
+	pipeline := fs.String("pipeline", "", "only list runs of this pipeline (name or ID)")
+	branch := fs.String("branch", "", "only list runs of this branch")
+	var tags tagFlags
+	fs.Var(&tags, "tag", "only list runs with this tag (repeatable; runs must have all)")
+	requestedBy := fs.String("requested-by", "", `only list runs requested by this user: me, an email address or a name, optionally as @name`)
+	reason := fs.String("reason", "", "only list runs queued for this reason: manual, pr, schedule or individualCI")
+	top := fs.Int("top", 50, "list at most this many runs")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return newUsageError("usage: fomo runs list " + usage)
+	}
+	if *top <= 0 {
+		return newUsageError("--top must be positive")
+	}
+	reasonFilter, ok := runReasons[strings.ToLower(*reason)]
+	if *reason != "" && !ok {
+		return newUsageError(fmt.Sprintf("unknown --reason %q; use manual, pr, schedule or individualCI", *reason))
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	criteria := client.BuildCriteria{Branch: *branch, Tags: tags, Reason: reasonFilter, Top: *top}
+	if *requestedBy != "" {
+		user, err := newIdentities(c).resolve(ctx, *requestedBy)
+		if err != nil {
+			return err
+		}
+		criteria.RequestedFor = user.ID
+	}
+	if *pipeline != "" {
+		p, err := resolvePipeline(ctx, c, *pipeline)
+		if err != nil {
+			return err
+		}
+		criteria.Definitions = []int{p.ID}
+	}
+	builds, err := c.ListBuilds(ctx, criteria)
+	if err != nil {
+		return fmt.Errorf("failed to list runs: %w", err)
+	}
+
+	if a.quiet {
+		for _, b := range builds {
+			a.resultf("%d\n", b.ID)
+		}
+		return nil
+	}
+	if len(builds) == 0 {
+		a.infof("No runs found.\n")
+		return nil
+	}
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tPIPELINE\tNUMBER\tBRANCH\tSTATUS\tQUEUED\tTAGS")
+	for _, b := range builds {
+		sort.Strings(b.Tags)
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", b.ID, b.Definition.Name, b.BuildNumber, buildBranch(b),
+			buildStatus(b), a.display.time(b.QueueTime, time.DateTime), orDash(strings.Join(b.Tags, ",")))
+	}
+	return w.Flush()
+}
+
+func runRunsTag(a *app, args []string) error {
+	fs := a.newFlagSet("runs tag", "<run-id> [<tag>...] [--remove]")
+	remove := fs.Bool("remove", false, "remove the tags instead of adding them")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 1 || *remove && len(positional) < 2 {
+		return newUsageError("usage: fomo runs tag <run-id> [<tag>...] [--remove]")
+	}
+	runID, err := parseRunID(positional[0])
+	if err != nil {
+		return err
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	var tags []string
+	if len(positional) == 1 {
+		if tags, err = c.ListBuildTags(ctx, runID); err != nil {
+			return fmt.Errorf("failed to fetch the tags of run %d: %w", runID, err)
+		}
+		sort.Strings(tags)
+		for _, tag := range tags {
+			a.resultf("%s\n", tag)
+		}
+		return nil
+	}
+	if err := a.checkWrite("fomo runs tag"); err != nil {
+		return err
+	}
+	for _, tag := range positional[1:] {
+		if *remove {
+			tags, err = c.DeleteBuildTag(ctx, runID, tag)
+		} else {
+			tags, err = c.AddBuildTag(ctx, runID, tag)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to tag run %d: %w", runID, err)
+		}
+	}
+	sort.Strings(tags)
+	if len(tags) == 0 {
+		a.infof("Run %d has no tags\n", runID)
+		return nil
+	}
+	a.infof("Run %d is tagged %s\n", runID, strings.Join(tags, ", "))
+	return nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"fomo/internal/client"
+)
+
+// envUnsafe is what cannot appear in an environment variable name.
+var envUnsafe = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// timelineOutputs collects the output variables the jobs of a run set,
+// named JOB.STEP.VARIABLE after the job's identifier.
+func timelineOutputs(records []client.TimelineRecord) map[string]client.TimelineVariable {
+	outputs := map[string]client.TimelineVariable{}
+	for _, r := range records {
+		job := r.Identifier
+		if job == "" {
+			job = r.Name
+		}
+		for name, v := range r.Variables {
+			outputs[job+"."+name] = v
+		}
+	}
+	return outputs
+}
+
+// runOutputs fetches the output variables of a run.
+func runOutputs(ctx context.Context, c *client.Client, runID int) (map[string]client.TimelineVariable, error) {
+	timeline, err := c.GetTimeline(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	return timelineOutputs(timeline.Records), nil
+}
+
+// envName turns an output variable name into an environment variable name
+// the way agents do: Build.Publish.setTag.imageTag becomes
+// BUILD_PUBLISH_SETTAG_IMAGETAG.
+func envName(name string) string {
+	return strings.ToUpper(envUnsafe.ReplaceAllString(name, "_"))
+}
+
+// shellQuote quotes a value for a POSIX shell.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func runRunsOutputs(a *app, args []string) error {
+	fs := a.newFlagSet("runs outputs", "<run-id> [--format table|env|json]")
+	format := fs.String("format", "table", "table, env for shell export statements, or json")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return newUsageError("usage: fomo runs outputs <run-id> [--format table|env|json]")
+	}
+	if *format != "table" && *format != "env" && *format != "json" {
+		return newUsageError("--format must be table, env or json")
+	}
+	runID, err := parseRunID(positional[0])
+	if err != nil {
+		return err
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	timeline, err := c.GetTimeline(a.ctx, runID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch timeline of run %d: %w", runID, err)
+	}
+	for _, r := range timeline.Records {
+		if r.Type == client.RecordJob && r.State != client.RunStateCompleted {
+			a.warnf("Warning: run %d is still running; jobs that have not finished may set more outputs.\n", runID)
+			break
+		}
+	}
+	outputs := timelineOutputs(timeline.Records)
+	names := slices.Sorted(maps.Keys(outputs))
+
+	// Agents never hand out secret outputs, so there is nothing to export
+	var secrets []string
+	if *format != "table" {
+		names = slices.DeleteFunc(names, func(name string) bool {
+			if outputs[name].IsSecret {
+				secrets = append(secrets, name)
+				return true
+			}
+			return false
+		})
+	}
+	if len(secrets) > 0 {
+		a.warnf("Warning: left out secret outputs %s.\n", strings.Join(secrets, ", "))
+	}
+
+	switch *format {
+	case "env":
+		for _, name := range names {
+			a.resultf("export %s=%s\n", envName(name), shellQuote(outputs[name].Value))
+		}
+		return nil
+	case "json":
+		values := map[string]string{}
+		for _, name := range names {
+			values[name] = outputs[name].Value
+		}
+		data, err := json.MarshalIndent(values, "", "  ")
+		if err != nil {
+			return err
+		}
+		a.resultf("%s\n", data)
+		return nil
+	}
+
+	shown := func(name string) string {
+		if outputs[name].IsSecret {
+			return "********"
+		}
+		return outputs[name].Value
+	}
+	if a.quiet {
+		for _, name := range names {
+			a.resultf("%s\t%s\n", name, shown(name))
+		}
+		return nil
+	}
+	if len(names) == 0 {
+		a.infof("Run %d set no output variables.\n", runID)
+		return nil
+	}
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVALUE")
+	for _, name := range names {
+		fmt.Fprintf(w, "%s\t%s\n", name, shown(name))
+	}
+	return w.Flush()
+}
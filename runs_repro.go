@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"fomo/internal/client"
+	"fomo/internal/watch"
+)
+
+func runRunsRepro(a *app, args []string) error {
+	fs := a.newFlagSet("runs repro", "<run-id> [--format command|script]")
+	format := fs.String("format", "command", "command for a fomo run command line, or script for a shell script")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return newUsageError("usage: fomo runs repro <run-id> [--format command|script]")
+	}
+	if *format != "command" && *format != "script" {
+		return newUsageError("--format must be command or script")
+	}
+	runID, err := parseRunID(positional[0])
+	if err != nil {
+		return err
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	b, err := c.GetBuild(ctx, runID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch run %d: %w", runID, err)
+	}
+	pipeline, err := reproPipeline(ctx, c, b)
+	if err != nil {
+		return err
+	}
+	variables, unavailable, err := queueVariables(b)
+	if err != nil {
+		return fmt.Errorf("failed to read the variables of run %d: %w", runID, err)
+	}
+	for _, name := range unavailable {
+		a.warnf("Warning: the value of %s is secret; set it yourself with --var %s=...\n", name, name)
+	}
+	if b.PullRequest() != 0 {
+		a.warnf("Warning: run %d validated pull request %d; the command runs the merge ref, which must still exist\n", runID, b.PullRequest())
+	}
+
+	command := []string{"fomo", "run", shellQuote(pipeline)}
+	if b.SourceBranch != "" {
+		command = append(command, "--branch", shellQuote(watch.ShortBranch(b.SourceBranch)))
+	}
+	if b.SourceVersion != "" {
+		command = append(command, "--commit", b.SourceVersion)
+	}
+	for _, name := range slices.Sorted(maps.Keys(b.TemplateParameters)) {
+		command = append(command, "--param", shellQuote(name+"="+b.TemplateParameters[name]))
+	}
+	for _, name := range slices.Sorted(maps.Keys(variables)) {
+		command = append(command, "--var", shellQuote(name+"="+variables[name]))
+	}
+
+	if *format == "command" {
+		a.resultf("%s\n", strings.Join(command, " "))
+		return nil
+	}
+	result := buildStatus(*b)
+	if b.StartTime != nil && b.FinishTime != nil {
+		result += " in " + a.display.duration(b.FinishTime.Sub(*b.StartTime))
+	}
+	a.resultf("#!/bin/sh\n")
+	a.resultf("# Reproduces run %d of %s: %s, queued %s by %s, %s.\n", b.ID, b.Definition.Name, b.BuildNumber,
+		b.QueueTime.UTC().Format(time.DateTime+" UTC"), orDash(b.RequestedFor.DisplayName), result)
+	a.resultf("# The run uses the YAML and templates of the commit, and the variable\n")
+	a.resultf("# groups, secrets and agents as they are now.\n")
+	a.resultf("set -eu\n")
+	a.resultf("export FOMO_ORG=%s FOMO_PROJECT=%s\n", shellQuote(c.Organization), shellQuote(c.Project))
+	a.resultf("%s --follow \"$@\"\n", strings.Join(command, " "))
+	return nil
+}
+
+// reproPipeline names the pipeline of a run the way fomo run resolves it:
+// by name, or by ID when another pipeline has the same name.
+func reproPipeline(ctx context.Context, c *client.Client, b *client.Build) (string, error) {
+	pipelines, err := c.ListPipelines(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch pipelines: %w", err)
+	}
+	same := 0
+	for _, p := range pipelines {
+		if p.Name == b.Definition.Name {
+			same++
+		}
+	}
+	if same == 1 {
+		return b.Definition.Name, nil
+	}
+	return strconv.Itoa(b.Definition.ID), nil
+}
+
+// queueVariables returns the variables a run was queued with, and the names
+// of those whose value the API withholds because it is secret.
+func queueVariables(b *client.Build) (map[string]string, []string, error) {
+	if b.Parameters == "" {
+		return nil, nil, nil
+	}
+	var raw map[string]any
+	if err := json.Unmarshal([]byte(b.Parameters), &raw); err != nil {
+		return nil, nil, err
+	}
+	variables := map[string]string{}
+	var unavailable []string
+	for name, value := range raw {
+		switch v := value.(type) {
+		case string:
+			variables[name] = v
+		case nil:
+			unavailable = append(unavailable, name)
+		default:
+			variables[name] = fmt.Sprint(v)
+		}
+	}
+	slices.Sort(unavailable)
+	return variables, unavailable, nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// errReadOnly reports a change refused because safe mode is on.
+var errReadOnly = errors.New("refused in safe mode")
+
+// checkWrite refuses an action that changes something, described as
+// action, when the config turns on safe mode, unless --allow-write was
+// given or the profile sets allow_write. Commands marked writes in the
+// command table are checked when they create their client, after their
+// flags are parsed; commands that only sometimes write call it before
+// their first change, which also puts them on the audit trail.
+func (a *app) checkWrite(action string) error {
+	a.writing = true
+	return a.writeRefused(action)
+}
+
+// writeRefused is checkWrite without putting the command on the audit
+// trail, for deciding once what a long-running command may change.
+func (a *app) writeRefused(action string) error {
+	if a.allowWrite {
+		return nil
+	}
+	cfg, err := a.loadConfig()
+	if err != nil {
+		return err
+	}
+	if !cfg.SafeMode || cfg.AllowWrite {
+		return nil
+	}
+	return fmt.Errorf("%w: %s makes changes; pass --allow-write, or set allow_write in your profile", errReadOnly, action)
+}
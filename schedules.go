@@ -0,0 +1,619 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fomo/internal/client"
+	"fomo/internal/ics"
+	"fomo/internal/watch"
+	"fomo/internal/yaml"
+)
+
+// windowsZones maps the Windows time zone IDs that classic schedules and
+// business hours checks use to IANA time zones, for the common ones.
+var windowsZones = map[string]string{
+	"UTC":                             "UTC",
+	"GMT Standard Time":               "Europe/London",
+	"Greenwich Standard Time":         "Atlantic/Reykjavik",
+	"W. Europe Standard Time":         "Europe/Berlin",
+	"Central Europe Standard Time":    "Europe/Budapest",
+	"Central European Standard Time":  "Europe/Warsaw",
+	"Romance Standard Time":           "Europe/Paris",
+	"GTB Standard Time":               "Europe/Bucharest",
+	"FLE Standard Time":               "Europe/Kiev",
+	"E. Europe Standard Time":         "Europe/Chisinau",
+	"Russian Standard Time":           "Europe/Moscow",
+	"Turkey Standard Time":            "Europe/Istanbul",
+	"Israel Standard Time":            "Asia/Jerusalem",
+	"South Africa Standard Time":      "Africa/Johannesburg",
+	"Arabian Standard Time":           "Asia/Dubai",
+	"Pakistan Standard Time":          "Asia/Karachi",
+	"India Standard Time":             "Asia/Kolkata",
+	"SE Asia Standard Time":           "Asia/Bangkok",
+	"China Standard Time":             "Asia/Shanghai",
+	"Singapore Standard Time":         "Asia/Singapore",
+	"Tokyo Standard Time":             "Asia/Tokyo",
+	"Korea Standard Time":             "Asia/Seoul",
+	"W. Australia Standard Time":      "Australia/Perth",
+	"E. Australia Standard Time":      "Australia/Brisbane",
+	"AUS Eastern Standard Time":       "Australia/Sydney",
+	"New Zealand Standard Time":       "Pacific/Auckland",
+	"Hawaiian Standard Time":          "Pacific/Honolulu",
+	"Alaskan Standard Time":           "America/Anchorage",
+	"Pacific Standard Time":           "America/Los_Angeles",
+	"US Mountain Standard Time":       "America/Phoenix",
+	"Mountain Standard Time":          "America/Denver",
+	"Central Standard Time":           "America/Chicago",
+	"Central Standard Time (Mexico)":  "America/Mexico_City",
+	"Eastern Standard Time":           "America/New_York",
+	"Atlantic Standard Time":          "America/Halifax",
+	"E. South America Standard Time":  "America/Sao_Paulo",
+	"Argentina Standard Time":         "America/Argentina/Buenos_Aires",
+	"SA Pacific Standard Time":        "America/Bogota",
+	"Pacific SA Standard Time":        "America/Santiago",
+	"Canada Central Standard Time":    "America/Regina",
+	"Newfoundland Standard Time":      "America/St_Johns",
+	"Cen. Australia Standard Time":    "Australia/Adelaide",
+	"Tasmania Standard Time":          "Australia/Hobart",
+	"Taipei Standard Time":            "Asia/Taipei",
+	"Egypt Standard Time":             "Africa/Cairo",
+	"W. Central Africa Standard Time": "Africa/Lagos",
+}
+
+// windowsZone returns the time zone a Windows time zone ID names. IANA names
+// are accepted too.
+func windowsZone(id string) (*time.Location, error) {
+	if id == "" {
+		return time.UTC, nil
+	}
+	name, ok := windowsZones[id]
+	if !ok {
+		name = id
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", id)
+	}
+	return loc, nil
+}
+
+// cronSchedule is the cron expression of a YAML pipeline's schedule, as
+// Azure Pipelines reads it: minute, hour, day of month, month and day of
+// week, in UTC.
+type cronSchedule struct {
+	minutes   []int
+	hours     []int
+	monthDays []int          // nil for any day
+	months    []time.Month   // nil for any month
+	weekdays  []time.Weekday // nil for any day
+}
+
+var (
+	cronMonths   = map[string]int{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6, "jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12}
+	cronWeekdays = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+)
+
+// parseCron parses a cron expression. Expressions that run more often than
+// hourly, or restrict both the day of month and the day of week, are not
+// supported: neither makes a sensible calendar entry.
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron %q does not have five fields", expr)
+	}
+	var s cronSchedule
+	var err error
+	if s.minutes, err = cronField(fields[0], 0, 59, nil); err != nil {
+		return nil, err
+	}
+	if s.hours, err = cronField(fields[1], 0, 23, nil); err != nil {
+		return nil, err
+	}
+	if len(s.minutes)*len(s.hours) > 24 {
+		return nil, fmt.Errorf("cron %q runs more than 24 times a day", expr)
+	}
+	if fields[2] != "*" {
+		if s.monthDays, err = cronField(fields[2], 1, 31, nil); err != nil {
+			return nil, err
+		}
+	}
+	if fields[3] != "*" {
+		months, err := cronField(fields[3], 1, 12, cronMonths)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range months {
+			s.months = append(s.months, time.Month(m))
+		}
+	}
+	if fields[4] != "*" {
+		days, err := cronField(fields[4], 0, 7, cronWeekdays)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range days {
+			if d == 7 {
+				d = 0
+			}
+			if !slices.Contains(s.weekdays, time.Weekday(d)) {
+				s.weekdays = append(s.weekdays, time.Weekday(d))
+			}
+		}
+		slices.Sort(s.weekdays)
+	}
+	if s.monthDays != nil && s.weekdays != nil {
+		return nil, fmt.Errorf("cron %q sets both the day of month and the day of week", expr)
+	}
+	return &s, nil
+}
+
+// cronField parses one field of a cron expression: *, a value, a range, a
+// step such as */4 or 1-10/2, or a list of those.
+func cronField(field string, lo, hi int, names map[string]int) ([]int, error) {
+	value := func(s string) (int, error) {
+		if n, ok := names[strings.ToLower(s)]; ok {
+			return n, nil
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < lo || n > hi {
+			return 0, fmt.Errorf("invalid cron field %q", field)
+		}
+		return n, nil
+	}
+	var values []int
+	for _, part := range strings.Split(field, ",") {
+		part, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid cron field %q", field)
+			}
+			step = n
+		}
+		first, last := lo, hi
+		if part != "*" {
+			from, to, isRange := strings.Cut(part, "-")
+			var err error
+			if first, err = value(from); err != nil {
+				return nil, err
+			}
+			last = first
+			if isRange {
+				if last, err = value(to); err != nil || last < first {
+					return nil, fmt.Errorf("invalid cron field %q", field)
+				}
+			} else if hasStep {
+				last = hi
+			}
+		}
+		for n := first; n <= last; n += step {
+			if !slices.Contains(values, n) {
+				values = append(values, n)
+			}
+		}
+	}
+	slices.Sort(values)
+	return values, nil
+}
+
+// scheduleDays is the bit mask of days classic schedules take.
+var scheduleDays = []struct {
+	name string
+	day  time.Weekday
+	bit  int
+}{
+	{"monday", time.Monday, 1}, {"tuesday", time.Tuesday, 2}, {"wednesday", time.Wednesday, 4},
+	{"thursday", time.Thursday, 8}, {"friday", time.Friday, 16}, {"saturday", time.Saturday, 32},
+	{"sunday", time.Sunday, 64},
+}
+
+// classicScheduleDays reads the daysToBuild of a classic schedule, which
+// the API returns as a bit mask or as day names such as "monday, friday"
+// or "all".
+func classicScheduleDays(v any) []time.Weekday {
+	mask := 0
+	switch v := v.(type) {
+	case float64:
+		mask = int(v)
+	case string:
+		for _, name := range strings.Split(strings.ToLower(v), ",") {
+			name = strings.TrimSpace(name)
+			if name == "all" {
+				mask = 127
+			}
+			for _, d := range scheduleDays {
+				if name == d.name {
+					mask |= d.bit
+				}
+			}
+		}
+	}
+	var days []time.Weekday
+	for _, d := range scheduleDays {
+		if mask&d.bit != 0 {
+			days = append(days, d.day)
+		}
+	}
+	slices.Sort(days)
+	return days
+}
+
+// pipelineSchedule is one time of day a pipeline is scheduled to run.
+type pipelineSchedule struct {
+	name     string
+	branches []string
+	hour     int
+	minute   int
+	loc      *time.Location
+	repeat   ics.Recurrence
+	cron     string // the cron expression of a YAML schedule
+}
+
+// definitionSchedules returns the scheduled triggers of a pipeline: those
+// set in the pipeline settings, which take precedence, or else those of its
+// YAML file on the default branch.
+func definitionSchedules(ctx context.Context, c *client.Client, d client.Definition) ([]pipelineSchedule, []string, error) {
+	definition, err := c.GetDefinitionRaw(ctx, d.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch pipeline %s: %w", definitionPath(d.DefinitionReference), err)
+	}
+	var schedules []pipelineSchedule
+	var warnings []string
+	triggers, _ := definition["triggers"].([]any)
+	for _, t := range triggers {
+		trigger, _ := t.(map[string]any)
+		if trigger["triggerType"] != "schedule" {
+			continue
+		}
+		entries, _ := trigger["schedules"].([]any)
+		for _, e := range entries {
+			entry, _ := e.(map[string]any)
+			zone, _ := entry["timeZoneId"].(string)
+			loc, err := windowsZone(zone)
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("%s: %v; using UTC", definitionPath(d.DefinitionReference), err))
+				loc = time.UTC
+			}
+			var branches []string
+			filters, _ := entry["branchFilters"].([]any)
+			for _, f := range filters {
+				if filter, _ := f.(string); strings.HasPrefix(filter, "+") {
+					branches = append(branches, watch.ShortBranch(filter[1:]))
+				}
+			}
+			hour, _ := entry["startHours"].(float64)
+			minute, _ := entry["startMinutes"].(float64)
+			schedules = append(schedules, pipelineSchedule{
+				branches: branches,
+				hour:     int(hour),
+				minute:   int(minute),
+				loc:      loc,
+				repeat:   ics.Recurrence{Weekdays: classicScheduleDays(entry["daysToBuild"])},
+			})
+		}
+	}
+	if len(schedules) > 0 {
+		return schedules, warnings, nil
+	}
+
+	file, content, err := definitionYAML(ctx, c, d, definition)
+	if err != nil || file == "" {
+		return nil, warnings, err
+	}
+	var pipeline struct {
+		Schedules []struct {
+			Cron        string `yaml:"cron"`
+			DisplayName string `yaml:"displayName"`
+			Branches    struct {
+				Include []string `yaml:"include"`
+			} `yaml:"branches"`
+		} `yaml:"schedules"`
+	}
+	if err := yaml.Unmarshal([]byte(content), &pipeline); err != nil {
+		// Only the schedules matter; a file fomo cannot parse has none it knows of
+		if strings.Contains(content, "schedules:") {
+			warnings = append(warnings, fmt.Sprintf("%s: could not read the schedules of %s: %v", definitionPath(d.DefinitionReference), file, err))
+		}
+		return nil, warnings, nil
+	}
+	for _, entry := range pipeline.Schedules {
+		cron, err := parseCron(entry.Cron)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: skipping schedule: %v", definitionPath(d.DefinitionReference), err))
+			continue
+		}
+		var branches []string
+		for _, b := range entry.Branches.Include {
+			branches = append(branches, watch.ShortBranch(b))
+		}
+		for _, hour := range cron.hours {
+			for _, minute := range cron.minutes {
+				schedules = append(schedules, pipelineSchedule{
+					name:     entry.DisplayName,
+					branches: branches,
+					hour:     hour,
+					minute:   minute,
+					loc:      time.UTC,
+					repeat:   ics.Recurrence{Weekdays: cron.weekdays, MonthDays: cron.monthDays, Months: cron.months},
+					cron:     entry.Cron,
+				})
+			}
+		}
+	}
+	return schedules, warnings, nil
+}
+
+// firstOccurrence returns the first time from today on, in loc, that a
+// recurrence at hour:minute happens.
+func firstOccurrence(r ics.Recurrence, hour, minute int, loc *time.Location, now time.Time) time.Time {
+	today := now.In(loc)
+	for i := range 400 {
+		day := time.Date(today.Year(), today.Month(), today.Day()+i, hour, minute, 0, 0, loc)
+		if (r.Weekdays == nil || slices.Contains(r.Weekdays, day.Weekday())) &&
+			(r.MonthDays == nil || slices.Contains(r.MonthDays, day.Day())) &&
+			(r.Months == nil || slices.Contains(r.Months, day.Month())) {
+			return day
+		}
+	}
+	return time.Date(today.Year(), today.Month(), today.Day(), hour, minute, 0, 0, loc)
+}
+
+func runSchedulesExport(a *app, args []string) error {
+	fs := a.newFlagSet("schedules export", "--ics FILE [--since 14d]")
+	out := fs.String("ics", "", "calendar file to write, or - for stdout")
+	since := fs.String("since", "14d", "how far back to include deployments")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *out == "" {
+		return newUsageError("usage: fomo schedules export --ics FILE [--since 14d]")
+	}
+	age, err := parseAge(*since)
+	if err != nil {
+		return newUsageError(err.Error())
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	now := time.Now()
+	uid := func(format string, args ...any) string {
+		return fmt.Sprintf(format, args...) + "@" + strings.ToLower(c.Organization) + ".fomo"
+	}
+
+	definitions, err := c.ListDefinitionDetails(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch pipelines: %w", err)
+	}
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		sem      = make(chan struct{}, 8)
+		events   []ics.Event
+		warnings []string
+		firstErr error
+	)
+	for _, d := range definitions {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			schedules, warned, err := definitionSchedules(ctx, c, d)
+			mu.Lock()
+			defer mu.Unlock()
+			warnings = append(warnings, warned...)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			// Block out as long as the pipeline last took
+			length := 30 * time.Minute
+			if b := d.LatestBuild; b != nil && b.StartTime != nil && b.FinishTime != nil {
+				length = max(b.FinishTime.Sub(*b.StartTime).Round(time.Minute), 15*time.Minute)
+			}
+			for i, s := range schedules {
+				start := firstOccurrence(s.repeat, s.hour, s.minute, s.loc, now)
+				summary := "Scheduled: " + d.Name
+				if s.name != "" {
+					summary += " (" + s.name + ")"
+				}
+				description := fmt.Sprintf("Pipeline %s runs on the schedule in its settings", definitionPath(d.DefinitionReference))
+				if s.cron != "" {
+					description = fmt.Sprintf("Pipeline %s runs on cron %s (UTC) in its YAML", definitionPath(d.DefinitionReference), s.cron)
+				}
+				if len(s.branches) > 0 {
+					description += " on " + strings.Join(s.branches, ", ")
+				}
+				events = append(events, ics.Event{
+					UID:         uid("schedule-%d-%d-%02d%02d", d.ID, i, s.hour, s.minute),
+					Summary:     summary,
+					Description: description,
+					URL:         c.WebURL(fmt.Sprintf("_build?definitionId=%d", d.ID)),
+					Start:       start,
+					End:         start.Add(length),
+					Repeat:      &s.repeat,
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+
+	envs, err := c.ListEnvironments(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch environments: %w", err)
+	}
+	for _, env := range envs {
+		windows, warned, err := releaseWindows(ctx, c, env, now, uid)
+		if err != nil {
+			return err
+		}
+		warnings = append(warnings, warned...)
+		events = append(events, windows...)
+
+		deployments, err := deploymentEvents(ctx, c, env, now.Add(-age), now, uid)
+		if err != nil {
+			return err
+		}
+		events = append(events, deployments...)
+	}
+	slices.SortStableFunc(events, func(x, y ics.Event) int { return strings.Compare(x.UID, y.UID) })
+
+	for _, w := range warnings {
+		a.warnf("%s\n", w)
+	}
+	name := fmt.Sprintf("%s pipelines", c.Project)
+	if *out == "-" {
+		return ics.Write(a.stdout, name, events)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return fmt.Errorf("failed to export schedules: %w", err)
+	}
+	if err := ics.Write(f, name, events); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to export schedules: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to export schedules: %w", err)
+	}
+	a.infof("Exported %d event(s) to %s\n", len(events), *out)
+	return nil
+}
+
+// releaseWindows turns the business hours checks of an environment into
+// recurring events.
+func releaseWindows(ctx context.Context, c *client.Client, env client.Environment, now time.Time, uid func(string, ...any) string) ([]ics.Event, []string, error) {
+	checks, err := c.ListEnvironmentChecks(ctx, env.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch the checks of %s: %w", env.Name, err)
+	}
+	var events []ics.Event
+	var warnings []string
+	for _, check := range checks {
+		var s client.TaskCheckSettings
+		if !check.Type.Is(client.CheckTypeTask) || json.Unmarshal(check.Settings, &s) != nil || !strings.EqualFold(s.DefinitionRef.ID, client.BusinessHoursTask.ID) {
+			continue
+		}
+		loc, err := windowsZone(s.Inputs["timeZone"])
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: %v; using UTC", env.Name, err))
+			loc = time.UTC
+		}
+		from, err1 := time.Parse("15:04", s.Inputs["startTime"])
+		to, err2 := time.Parse("15:04", s.Inputs["endTime"])
+		if err1 != nil || err2 != nil || !to.After(from) {
+			warnings = append(warnings, fmt.Sprintf("%s: skipping business hours %s-%s", env.Name, s.Inputs["startTime"], s.Inputs["endTime"]))
+			continue
+		}
+		var repeat ics.Recurrence
+		for _, name := range strings.Split(strings.ToLower(s.Inputs["businessDays"]), ",") {
+			for _, d := range scheduleDays {
+				if strings.TrimSpace(name) == d.name {
+					repeat.Weekdays = append(repeat.Weekdays, d.day)
+				}
+			}
+		}
+		slices.Sort(repeat.Weekdays)
+		if len(repeat.Weekdays) == 0 {
+			continue
+		}
+		start := firstOccurrence(repeat, from.Hour(), from.Minute(), loc, now)
+		events = append(events, ics.Event{
+			UID:         uid("window-%d-%d", env.ID, check.ID),
+			Summary:     "Release window: " + env.Name,
+			Description: fmt.Sprintf("Deployments to %s may run only during these hours (business hours check)", env.Name),
+			URL:         c.WebURL(fmt.Sprintf("_environments/%d", env.ID)),
+			Start:       start,
+			End:         start.Add(to.Sub(from)),
+			Repeat:      &repeat,
+		})
+	}
+	return events, warnings, nil
+}
+
+// deploymentEvents returns the deployments to an environment since a time,
+// one per stage of a run, from its first job's start to its last job's end.
+// Deployments still going end now.
+func deploymentEvents(ctx context.Context, c *client.Client, env client.Environment, since, now time.Time, uid func(string, ...any) string) ([]ics.Event, error) {
+	records, err := c.ListDeploymentRecords(ctx, env.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the deployments to %s: %w", env.Name, err)
+	}
+	type key struct {
+		run   int
+		stage string
+	}
+	type deployment struct {
+		event   ics.Event
+		running bool
+		result  string // the first result other than succeeded
+	}
+	byStage := map[key]*deployment{}
+	var order []key
+	for _, r := range records {
+		if r.StartTime.IsZero() || r.StartTime.Before(since) {
+			continue
+		}
+		end := r.FinishTime
+		if end.IsZero() {
+			end = now
+		}
+		k := key{r.Owner.ID, r.StageName}
+		d, ok := byStage[k]
+		if !ok {
+			d = &deployment{event: ics.Event{
+				UID:     uid("deployment-%d-%d-%s", env.ID, r.Owner.ID, strings.ToLower(r.StageName)),
+				Summary: fmt.Sprintf("Deploy %s %s to %s", r.Definition.Name, r.Owner.Name, env.Name),
+				Start:   r.StartTime,
+				End:     end,
+			}}
+			d.event.Description = fmt.Sprintf("Stage %s of run %s of %s", r.StageName, r.Owner.Name, r.Definition.Name)
+			if r.Owner.Links.Web != nil {
+				d.event.URL = r.Owner.Links.Web.Href
+			}
+			byStage[k] = d
+			order = append(order, k)
+		}
+		if r.StartTime.Before(d.event.Start) {
+			d.event.Start = r.StartTime
+		}
+		if end.After(d.event.End) {
+			d.event.End = end
+		}
+		switch {
+		case r.FinishTime.IsZero():
+			d.running = true
+		case r.Result != client.RunResultSucceeded && d.result == "":
+			d.result = r.Result
+		}
+	}
+	events := make([]ics.Event, 0, len(order))
+	for _, k := range order {
+		d := byStage[k]
+		switch {
+		case d.running:
+			d.event.Description += ", in progress"
+		case d.result != "":
+			d.event.Description += ", " + d.result
+		}
+		events = append(events, d.event)
+	}
+	return events, nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"fomo/internal/client"
+	"fomo/internal/watch"
+)
+
+// searchResult is a pipeline, run or branch matching a search.
+type searchResult struct {
+	kind  string // pipeline, branch or run
+	id    string
+	title string
+	url   string
+}
+
+// matchesQuery reports whether text contains every word of the query,
+// ignoring case.
+func matchesQuery(text string, words []string) bool {
+	text = strings.ToLower(text)
+	for _, w := range words {
+		if !strings.Contains(text, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// buildURL is the browser link of a run.
+func buildURL(c *client.Client, b client.Build) string {
+	if b.Links.Web != nil {
+		return b.Links.Web.Href
+	}
+	return c.WebURL(fmt.Sprintf("_build/results?buildId=%d", b.ID))
+}
+
+// describeBuild is a one-line summary of a run: pipeline, number, branch,
+// the first line of its commit message and its status.
+func describeBuild(b client.Build) string {
+	title := fmt.Sprintf("%s %s on %s", b.Definition.Name, b.BuildNumber, buildBranch(b))
+	if message, _, _ := strings.Cut(b.CommitMessage(), "\n"); message != "" {
+		title += ": " + strings.TrimSpace(message)
+	}
+	return title + " (" + buildStatus(b) + ")"
+}
+
+func runSearch(a *app, args []string) error {
+	fs := a.newFlagSet("search", "<query>... [--runs N]")
+	runs := fs.Int("runs", 500, "how many recent runs to search")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	words := strings.Fields(strings.ToLower(strings.Join(positional, " ")))
+	if len(words) == 0 {
+		return newUsageError("usage: fomo search <query>... [--runs N]")
+	}
+	if *runs <= 0 {
+		return newUsageError("--runs must be positive")
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	results, err := search(ctx, c, words, *runs)
+	if err != nil {
+		return err
+	}
+
+	if a.quiet {
+		for _, r := range results {
+			a.resultf("%s\t%s\t%s\n", r.kind, r.id, r.url)
+		}
+		return nil
+	}
+	if len(results) == 0 {
+		a.infof("Nothing matches %q in the pipelines and last %d runs of %s.\n", strings.Join(positional, " "), *runs, c.Project)
+		return nil
+	}
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", r.kind, r.title, r.url)
+	}
+	return w.Flush()
+}
+
+// search finds the pipelines, branches, pull requests and recent runs
+// matching words.
+// A number also finds the run with that ID.
+func search(ctx context.Context, c *client.Client, words []string, runs int) ([]searchResult, error) {
+	definitions, err := c.ListDefinitions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pipelines: %w", err)
+	}
+	builds, err := c.ListBuilds(ctx, client.BuildCriteria{Top: runs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch runs: %w", err)
+	}
+	if id, err := strconv.Atoi(strings.TrimPrefix(words[0], "#")); err == nil && len(words) == 1 {
+		if b, err := c.GetBuild(ctx, id); err == nil {
+			builds = append([]client.Build{*b}, builds...)
+		}
+	}
+
+	var results []searchResult
+	sort.Slice(definitions, func(i, j int) bool { return definitionPath(definitions[i]) < definitionPath(definitions[j]) })
+	for _, d := range definitions {
+		if matchesQuery(definitionPath(d), words) {
+			results = append(results, searchResult{kind: "pipeline", id: strconv.Itoa(d.ID), title: definitionPath(d),
+				url: c.WebURL(fmt.Sprintf("_build?definitionId=%d", d.ID))})
+		}
+	}
+
+	// A branch links to its latest run; builds are newest first
+	seenBranch := map[string]bool{}
+	var branches []searchResult
+	for _, b := range builds {
+		branch := watch.ShortBranch(b.BranchName())
+		if branch == "" || seenBranch[branch] {
+			continue
+		}
+		seenBranch[branch] = true
+		if matchesQuery(branch, words) {
+			branches = append(branches, searchResult{kind: "branch", id: branch,
+				title: fmt.Sprintf("%s, last run %s %s", branch, b.Definition.Name, b.BuildNumber), url: buildURL(c, b)})
+		}
+	}
+	sort.Slice(branches, func(i, j int) bool { return branches[i].id < branches[j].id })
+	results = append(results, branches...)
+
+	// Pull requests come from the runs that validated them and link to them
+	// where the repository is hosted
+	seenPR := map[string]bool{}
+	var pullRequests []searchResult
+	for _, b := range builds {
+		link := b.PullRequestURL()
+		if link == "" || seenPR[link] {
+			continue
+		}
+		seenPR[link] = true
+		title := fmt.Sprintf("PR %d", b.PullRequest())
+		if t := b.TriggerInfo["pr.title"]; t != "" {
+			title += ": " + t
+		}
+		if matchesQuery(title+" "+b.BranchName(), words) {
+			pullRequests = append(pullRequests, searchResult{kind: "pr", id: strconv.Itoa(b.PullRequest()), title: title, url: link})
+		}
+	}
+	results = append(results, pullRequests...)
+
+	seenRun := map[int]bool{}
+	seenCommit := map[string]bool{}
+	for _, b := range builds {
+		if seenRun[b.ID] {
+			continue
+		}
+		seenRun[b.ID] = true
+		// A commit ID also links to the commit on the host of the repository
+		if sha := b.HeadCommit(); len(words) == 1 && len(words[0]) >= 7 && strings.HasPrefix(sha, words[0]) && !seenCommit[sha] {
+			seenCommit[sha] = true
+			if link := b.CommitURL(); link != "" {
+				results = append(results, searchResult{kind: "commit", id: sha, title: fmt.Sprintf("%.7s in %s", sha, b.Repository.Name), url: link})
+			}
+		}
+		text := strings.Join([]string{strconv.Itoa(b.ID), b.Definition.Name, b.BuildNumber, b.SourceBranch, b.HeadCommit(), b.CommitMessage()}, " ")
+		if matchesQuery(text, words) {
+			results = append(results, searchResult{kind: "run", id: strconv.Itoa(b.ID), title: describeBuild(b), url: buildURL(c, b)})
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+)
+
+// promptSecret asks for a secret without echoing it to the terminal, so it
+// never ends up on screen or in scrollback. When stdin is not a terminal it
+// reads a line as is.
+func promptSecret(prompt string) string {
+	fmt.Fprint(os.Stderr, prompt)
+	if !isTerminal(os.Stdin) {
+		input, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		return strings.TrimSpace(input)
+	}
+
+	restore, err := disableEcho(os.Stdin)
+	if err != nil {
+		// Better to fail than to show the secret
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintf(os.Stderr, "Cannot hide input on this terminal: %v\n", err)
+		return ""
+	}
+
+	// Don't leave the terminal without echo if the user gives up
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-interrupt:
+			restore()
+			fmt.Fprintln(os.Stderr)
+			os.Exit(exitCanceled)
+		case <-done:
+		}
+	}()
+
+	input, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	close(done)
+	signal.Stop(interrupt)
+	restore()
+	fmt.Fprintln(os.Stderr)
+	return strings.TrimSpace(input)
+}
+
+// readPAT reads a PAT given with --pat-stdin or --pat-file. Only the first
+// line counts, so files with a trailing newline work as expected.
+func readPAT(stdin bool, file string) (string, error) {
+	var data []byte
+	var err error
+	switch {
+	case stdin && file != "":
+		return "", newUsageError("--pat-stdin and --pat-file cannot be used together")
+	case stdin:
+		data, err = io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read PAT from stdin: %w", err)
+		}
+	case file != "":
+		data, err = os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read PAT file: %w", err)
+		}
+	default:
+		return "", nil
+	}
+
+	pat, _, _ := strings.Cut(string(data), "\n")
+	pat = strings.TrimSpace(pat)
+	if pat == "" {
+		return "", fmt.Errorf("%w: the PAT is empty", errMissingInput)
+	}
+	return pat, nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"fomo/internal/client"
+)
+
+// pipelineActions are the actions security who-can answers for, by their
+// permission bit in the build namespace.
+var pipelineActions = map[string]int{
+	"queue": client.BuildPermissionQueue,
+	"edit":  client.BuildPermissionEdit,
+}
+
+// grant is where an identity's permission for an action on a pipeline
+// comes from.
+type grant struct {
+	identity client.Identity
+	allowed  bool   // false for an explicit deny
+	scope    string // project, folder \Team or pipeline
+}
+
+func runSecurityWhoCan(a *app, args []string) error {
+	fs := a.newFlagSet("security who-can", "<pipeline> [--action queue|edit]")
+	action := fs.String("action", "queue", "the action to check: queue or edit")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return newUsageError("usage: fomo security who-can <pipeline> [--action queue|edit]")
+	}
+	bit, ok := pipelineActions[*action]
+	if !ok {
+		return newUsageError(fmt.Sprintf("unknown --action %q; use queue or edit", *action))
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	p, err := resolvePipeline(ctx, c, positional[0])
+	if err != nil {
+		return err
+	}
+	project, err := c.GetProject(ctx, c.Project)
+	if err != nil {
+		return fmt.Errorf("failed to fetch project %s: %w", c.Project, err)
+	}
+
+	grants, err := pipelineGrants(ctx, c, project.ID, p, bit)
+	if err != nil {
+		return err
+	}
+	if a.quiet {
+		for _, g := range grants {
+			a.resultf("%s\t%s\t%s\t%s\n", g.identity.DisplayName, orDash(g.identity.UniqueName), grantAccess(g), g.scope)
+		}
+		return nil
+	}
+	if len(grants) == 0 {
+		a.infof("No group or user is given permission to %s %s.\n", *action, p.Name)
+		return nil
+	}
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "IDENTITY\tTYPE\tACCESS\tSET ON")
+	for _, g := range grants {
+		kind := "user"
+		if g.identity.IsContainer {
+			kind = "group"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", g.identity.DisplayName, kind, grantAccess(g), g.scope)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if slices.ContainsFunc(grants, func(g grant) bool { return g.allowed && g.identity.IsContainer }) {
+		a.infof("\nMembers of the allowed groups can %s %s too, unless they are denied it through another group.\n", *action, p.Name)
+	}
+	return nil
+}
+
+func grantAccess(g grant) string {
+	if g.allowed {
+		return "allow"
+	}
+	return "deny"
+}
+
+// pipelineGrants walks the access control lists from the project down
+// through the pipeline's folders to the pipeline itself, and returns who
+// is allowed or denied the permission bit in the end: an entry further
+// down overrides one above it, a deny wins over an allow on the same
+// level, and a list that does not inherit drops everything above it.
+// Those allowed come first.
+func pipelineGrants(ctx context.Context, c *client.Client, projectID string, p *client.Pipeline, bit int) ([]grant, error) {
+	type level struct{ token, scope string }
+	levels := []level{{projectID, "project"}}
+	token, folder := projectID, ""
+	for _, name := range strings.FieldsFunc(p.Folder, func(r rune) bool { return r == '\\' || r == '/' }) {
+		token += "/" + name
+		folder += `\` + name
+		levels = append(levels, level{token, "folder " + folder})
+	}
+	levels = append(levels, level{fmt.Sprintf("%s/%d", token, p.ID), "pipeline"})
+
+	byDescriptor := map[string]grant{}
+	for _, l := range levels {
+		acls, err := c.ListACLs(ctx, client.NamespaceBuild, l.token, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch the permissions of %s: %w", l.scope, err)
+		}
+		for _, acl := range acls {
+			if !strings.EqualFold(acl.Token, l.token) {
+				continue
+			}
+			if !acl.InheritPermissions {
+				clear(byDescriptor)
+			}
+			for descriptor, ace := range acl.ACEs {
+				switch {
+				case ace.Deny&bit != 0:
+					byDescriptor[descriptor] = grant{allowed: false, scope: l.scope}
+				case ace.Allow&bit != 0:
+					byDescriptor[descriptor] = grant{allowed: true, scope: l.scope}
+				}
+			}
+		}
+	}
+	if len(byDescriptor) == 0 {
+		return nil, nil
+	}
+
+	descriptors := slices.Sorted(maps.Keys(byDescriptor))
+	identities := newIdentities(c).byDescriptor(ctx, descriptors)
+	grants := make([]grant, 0, len(byDescriptor))
+	for _, descriptor := range descriptors {
+		g := byDescriptor[descriptor]
+		g.identity = identities[descriptor]
+		grants = append(grants, g)
+	}
+	slices.SortFunc(grants, func(a, b grant) int {
+		if a.allowed != b.allowed {
+			if a.allowed {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Compare(strings.ToLower(a.identity.DisplayName), strings.ToLower(b.identity.DisplayName))
+	})
+	return grants, nil
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"fomo/internal/client"
+	"fomo/internal/timeline"
+	"fomo/internal/watch"
+)
+
+func runStatsCriticalPath(a *app, args []string) error {
+	fs := a.newFlagSet("stats critical-path", "<pipeline>|--tag TAG [--runs N] [--branch NAME]")
+	count := fs.Int("runs", 20, "number of recent completed runs to analyze")
+	branch := fs.String("branch", "", "only analyze runs of this branch")
+	var tags tagFlags
+	fs.Var(&tags, "tag", "analyze every pipeline with this tag instead of one; repeat to require several")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if !pipelineOrTags(positional, tags) || *count <= 0 {
+		return newUsageError("usage: fomo stats critical-path <pipeline>|--tag TAG [--runs N] [--branch NAME]")
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	return forEachPipeline(ctx, a, c, positional, tags, func(p *client.Pipeline) error {
+		return criticalPath(ctx, a, c, p, *count, *branch)
+	})
+}
+
+// criticalPath analyzes the critical path of the recent runs of a
+// pipeline.
+func criticalPath(ctx context.Context, a *app, c *client.Client, p *client.Pipeline, count int, branch string) error {
+	runs, err := c.ListRuns(ctx, p.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list runs of %s: %w", p.Name, err)
+	}
+	var selected []client.Run
+	for _, run := range runs {
+		if run.State != client.RunStateCompleted || run.Result == client.RunResultCanceled {
+			continue
+		}
+		if branch != "" && watch.ShortBranch(run.Branch()) != watch.ShortBranch(branch) {
+			continue
+		}
+		selected = append(selected, run)
+		if len(selected) == count {
+			break
+		}
+	}
+	if len(selected) == 0 {
+		return fmt.Errorf("%s has no completed runs to analyze", p.Name)
+	}
+
+	timelines, err := fetchTimelines(ctx, c, selected)
+	if err != nil {
+		return err
+	}
+	summary := timeline.Summarize(timelines)
+	if summary.Runs == 0 {
+		return fmt.Errorf("the runs of %s have no job timings", p.Name)
+	}
+
+	if a.quiet {
+		for _, job := range summary.Jobs {
+			if job.OnPath > 0 {
+				a.resultf("%s\t%s\n", job.Stage, job.Name)
+			}
+		}
+		return nil
+	}
+
+	a.infof("%s: %d runs, %s on average\n\n", p.Name, summary.Runs, a.display.duration(summary.Average))
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "STAGE\tJOB\tAVG\tON PATH\tAVG WAIT")
+	for _, job := range summary.Jobs {
+		wait := "-"
+		if job.OnPath > 0 {
+			wait = a.display.exactDuration(job.Wait / time.Duration(job.OnPath))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%.0f%%\t%s\n", job.Stage, job.Name, a.display.exactDuration(job.Average()), job.PathShare()*100, wait)
+	}
+	w.Flush()
+
+	if suggestions := summary.Suggestions(); len(suggestions) > 0 {
+		a.infof("\nSuggestions:\n")
+		for _, s := range suggestions {
+			a.infof("  - %s\n", s)
+		}
+	}
+	return nil
+}
+
+// fetchTimelines fetches the timelines of several runs concurrently.
+func fetchTimelines(ctx context.Context, c *client.Client, runs []client.Run) ([][]client.TimelineRecord, error) {
+	var (
+		wg        sync.WaitGroup
+		timelines = make([][]client.TimelineRecord, len(runs))
+		errs      = make([]error, len(runs))
+		sem       = make(chan struct{}, 8)
+	)
+	for i, run := range runs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			tl, err := c.GetTimeline(ctx, run.ID)
+			if err != nil {
+				errs[i] = fmt.Errorf("failed to fetch timeline of run %d: %w", run.ID, err)
+				return
+			}
+			timelines[i] = tl.Records
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return timelines, nil
+}
+
+// parseAge parses an age such as 30d, 2w or 12h.
+func parseAge(s string) (time.Duration, error) {
+	units := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if n := len(s); n > 1 {
+		if unit, ok := units[s[n-1]]; ok {
+			if count, err := strconv.Atoi(s[:n-1]); err == nil && count > 0 {
+				return time.Duration(count) * unit, nil
+			}
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid age %q (use e.g. 30d, 2w or 12h)", s)
+	}
+	return d, nil
+}
+
+// stageSeries is the duration of one stage over a series of runs, oldest
+// first.
+type stageSeries struct {
+	name      string
+	times     []time.Time
+	durations []time.Duration
+}
+
+func runStatsStages(a *app, args []string) error {
+	fs := a.newFlagSet("stats stages", "<pipeline>|--tag TAG [--since 30d] [--branch NAME] [--threshold PERCENT]")
+	since := fs.String("since", "30d", "how far back to look")
+	branch := fs.String("branch", "", "only analyze runs of this branch")
+	threshold := fs.Float64("threshold", 20, "slowdown, in percent, reported as a regression")
+	var tags tagFlags
+	fs.Var(&tags, "tag", "analyze every pipeline with this tag instead of one; repeat to require several")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if !pipelineOrTags(positional, tags) {
+		return newUsageError("usage: fomo stats stages <pipeline>|--tag TAG [--since 30d] [--branch NAME] [--threshold PERCENT]")
+	}
+	window, err := parseAge(*since)
+	if err != nil {
+		return newUsageError(err.Error())
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	return forEachPipeline(ctx, a, c, positional, tags, func(p *client.Pipeline) error {
+		return stageTrends(ctx, a, c, p, window, *since, *branch, *threshold)
+	})
+}
+
+// stageTrends charts the stage durations of a pipeline's runs over window,
+// written as since.
+func stageTrends(ctx context.Context, a *app, c *client.Client, p *client.Pipeline, window time.Duration, since, branch string, threshold float64) error {
+	runs, err := c.ListRuns(ctx, p.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list runs of %s: %w", p.Name, err)
+	}
+	cutoff := time.Now().Add(-window)
+	var selected []client.Run
+	for _, run := range runs {
+		if run.State != client.RunStateCompleted || run.Result == client.RunResultCanceled || run.CreatedDate.Before(cutoff) {
+			continue
+		}
+		if branch != "" && watch.ShortBranch(run.Branch()) != watch.ShortBranch(branch) {
+			continue
+		}
+		selected = append(selected, run)
+	}
+	if len(selected) == 0 {
+		return fmt.Errorf("%s has no completed runs in the last %s", p.Name, since)
+	}
+	// Oldest first, so the sparklines read left to right
+	sort.Slice(selected, func(i, j int) bool { return selected[i].CreatedDate.Before(selected[j].CreatedDate) })
+
+	timelines, err := fetchTimelines(ctx, c, selected)
+	if err != nil {
+		return err
+	}
+	var series []*stageSeries
+	byName := map[string]*stageSeries{}
+	for i, records := range timelines {
+		var stages []client.TimelineRecord
+		for _, r := range records {
+			if r.Type == client.RecordStage && r.Duration() > 0 {
+				stages = append(stages, r)
+			}
+		}
+		sort.Slice(stages, func(x, y int) bool { return stages[x].Order < stages[y].Order })
+		for _, stage := range stages {
+			s := byName[stage.Name]
+			if s == nil {
+				s = &stageSeries{name: stage.Name}
+				byName[stage.Name] = s
+				series = append(series, s)
+			}
+			s.times = append(s.times, selected[i].CreatedDate)
+			s.durations = append(s.durations, stage.Duration())
+		}
+	}
+	if len(series) == 0 {
+		return fmt.Errorf("the runs of %s have no stage timings", p.Name)
+	}
+
+	a.infof("%s: %d runs in the last %s, oldest first\n\n", p.Name, len(selected), since)
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	// Plain output spells out the latest duration instead of drawing the
+	// trend
+	switch {
+	case a.quiet:
+	case a.plain:
+		fmt.Fprintln(w, "STAGE\tLATEST\tMEDIAN\tMIN\tMAX")
+	default:
+		fmt.Fprintln(w, "STAGE\tTREND\tMEDIAN\tMIN\tMAX")
+	}
+	var regressions []string
+	for _, s := range series {
+		if !a.quiet {
+			lo, hi := slices.Min(s.durations), slices.Max(s.durations)
+			trend := sparkline(s.durations, 40)
+			if a.plain {
+				trend = a.display.duration(s.durations[len(s.durations)-1])
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", s.name, trend, a.display.duration(median(s.durations)), a.display.duration(lo), a.display.duration(hi))
+		}
+		if msg := s.regression(threshold, a.display); msg != "" {
+			regressions = append(regressions, msg)
+		}
+	}
+	w.Flush()
+
+	if len(regressions) > 0 {
+		a.infof("\n")
+		for _, msg := range regressions {
+			a.resultf("%s\n", msg)
+		}
+	}
+	// Annotations can explain a spike or a regression
+	if annotations := pipelineAnnotations(a, c.Project, p.ID, cutoff); len(annotations) > 0 && !a.quiet {
+		a.resultf("\nAnnotations:\n")
+		for _, an := range annotations {
+			a.resultf("  %s  %s  %s\n", a.display.time(an.Time, "Jan 02 15:04"), an.RunName, formatAnnotation(an))
+		}
+	}
+	return nil
+}
+
+// regression compares the newest third of the series to the oldest third
+// and describes a slowdown of at least threshold percent.
+func (s *stageSeries) regression(threshold float64, d display) string {
+	n := len(s.durations) / 3
+	if n == 0 {
+		return ""
+	}
+	before, after := median(s.durations[:n]), median(s.durations[len(s.durations)-n:])
+	if before <= 0 {
+		return ""
+	}
+	change := (float64(after)/float64(before) - 1) * 100
+	if change < threshold {
+		return ""
+	}
+	// Date the baseline by the middle of its runs
+	ago := time.Since(s.times[n/2])
+	return fmt.Sprintf("%s stage %.0f%% slower than %s ago (%s vs %s)", s.name, change, humanAge(ago), d.duration(after), d.duration(before))
+}
+
+var sparkChars = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws values as a line of block characters, scaled between
+// their minimum and maximum. Only the last width values are drawn.
+func sparkline(values []time.Duration, width int) string {
+	if len(values) > width {
+		values = values[len(values)-width:]
+	}
+	lo, hi := slices.Min(values), slices.Max(values)
+	line := make([]rune, len(values))
+	for i, v := range values {
+		level := 0
+		if hi > lo {
+			level = int(float64(v-lo) / float64(hi-lo) * float64(len(sparkChars)-1))
+		}
+		line[i] = sparkChars[level]
+	}
+	return string(line)
+}
+
+func median(values []time.Duration) time.Duration {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	return sorted[len(sorted)/2]
+}
+
+// humanAge describes an age in days or weeks.
+func humanAge(d time.Duration) string {
+	days := int(d.Hours() / 24)
+	switch {
+	case days < 1:
+		return "a day"
+	case days == 1:
+		return "1 day"
+	case days < 14:
+		return fmt.Sprintf("%d days", days)
+	}
+	return fmt.Sprintf("%d weeks", days/7)
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"fomo/internal/cache"
+	"fomo/internal/client"
+	"fomo/internal/watch"
+)
+
+const defaultStatuslineTemplate = "{{.Icon}} {{.Pipeline}}{{if .Branch}}@{{.Branch}}{{end}} {{.Status}}"
+
+// statusline is the data available to statusline templates.
+type statusline struct {
+	Pipeline string
+	Branch   string
+	Run      string
+	State    string
+	Result   string
+	Finished time.Time
+	URL      string
+
+	Status string `json:"-"` // result when finished, state otherwise
+	Icon   string `json:"-"`
+	Age    string `json:"-"` // time since the run finished, e.g. "3h"
+}
+
+func runStatusline(a *app, args []string) error {
+	fs := a.newFlagSet("statusline", "[flags]")
+	pipelineName := fs.String("pipeline", "", "pipeline to report; defaults to the configured default pipelines")
+	branch := fs.String("branch", "", "branch to report; defaults to the current git branch unless --pipeline is given")
+	tmpl := fs.String("template", "", "text/template for the line")
+	color := fs.String("color", "", "color codes to emit: none, ansi or tmux")
+	emoji := fs.Bool("emoji", false, "use emoji status icons")
+	ttl := fs.Duration("ttl", 0, "reuse a fetched status for this long (default 60s)")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	// A status bar can't answer prompts
+	a.noPrompt = true
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	settings := a.cfg.Statusline
+	if *tmpl == "" {
+		*tmpl = settings.Template
+	}
+	if *tmpl == "" {
+		*tmpl = defaultStatuslineTemplate
+	}
+	if *color == "" {
+		*color = settings.Color
+	}
+	*emoji = *emoji || settings.Emoji
+	if a.plain {
+		*color, *emoji = "none", false
+	}
+	if *ttl == 0 {
+		*ttl = settings.TTL
+	}
+	if *ttl == 0 {
+		*ttl = time.Minute
+	}
+
+	t, err := template.New("statusline").Parse(*tmpl)
+	if err != nil {
+		return newUsageError(fmt.Sprintf("invalid template: %v", err))
+	}
+
+	pipelines := a.cfg.Pipelines
+	if *pipelineName != "" {
+		pipelines = []string{*pipelineName}
+	} else if *branch == "" {
+		*branch = currentGitBranch()
+	}
+	*branch = watch.ShortBranch(*branch)
+	if len(pipelines) == 0 {
+		return newUsageError("no pipeline given; use --pipeline or set default pipelines in .fomo.yaml")
+	}
+
+	// Serve from the cache while fresh; status bars redraw every few seconds
+	store, err := cache.Open("statusline")
+	if err != nil {
+		return err
+	}
+	key := strings.Join([]string{c.BaseURL, c.Organization, c.Project, strings.Join(pipelines, ","), *branch}, "|")
+	var status statusline
+	stored, ok := store.Get(key, &status)
+	if !ok || time.Since(stored) > *ttl {
+		fresh, err := fetchStatusline(a.ctx, c, pipelines, *branch)
+		switch {
+		case err == nil:
+			status = fresh
+			store.Put(key, status)
+		case !ok:
+			return err
+		}
+	}
+
+	status.Status = status.State
+	if status.State == client.RunStateCompleted {
+		status.Status = status.Result
+	}
+	status.Icon = statusIcon(status, *emoji)
+	if !status.Finished.IsZero() {
+		status.Age = a.display.duration(time.Since(status.Finished))
+	}
+
+	var line strings.Builder
+	if err := t.Execute(&line, status); err != nil {
+		return err
+	}
+	a.resultf("%s\n", colorize(strings.TrimSpace(line.String()), status, *color))
+	return nil
+}
+
+// fetchStatusline finds the latest run among pipelines, optionally limited
+// to a branch.
+func fetchStatusline(ctx context.Context, c *client.Client, pipelines []string, branch string) (statusline, error) {
+	var latest *client.Run
+	for _, name := range pipelines {
+		p, err := resolvePipeline(ctx, c, name)
+		if err != nil {
+			return statusline{}, err
+		}
+		runs, err := c.ListRuns(ctx, p.ID)
+		if err != nil {
+			return statusline{}, err
+		}
+		for i, run := range runs {
+			if branch != "" && watch.ShortBranch(run.Branch()) != watch.ShortBranch(branch) {
+				continue
+			}
+			if latest == nil || run.CreatedDate.After(latest.CreatedDate) {
+				latest = &runs[i]
+			}
+			break
+		}
+	}
+	if latest == nil {
+		return statusline{Pipeline: strings.Join(pipelines, ","), Branch: branch, State: "none"}, nil
+	}
+
+	status := statusline{
+		Pipeline: latest.Pipeline.Name,
+		Branch:   watch.ShortBranch(latest.Branch()),
+		Run:      latest.Name,
+		State:    latest.State,
+		Result:   latest.Result,
+		Finished: latest.FinishedDate,
+	}
+	if latest.Links.Web != nil {
+		status.URL = latest.Links.Web.Href
+	}
+	return status, nil
+}
+
+func statusIcon(s statusline, emoji bool) string {
+	icons := map[string][2]string{
+		client.RunResultSucceeded: {"+", "✅"},
+		client.RunResultFailed:    {"x", "❌"},
+		client.RunResultCanceled:  {"-", "🚫"},
+		client.RunStateInProgress: {"~", "⏳"},
+		"none":                    {"?", "❔"},
+	}
+	icon, ok := icons[s.Status]
+	if !ok {
+		icon = icons["none"]
+	}
+	if emoji {
+		return icon[1]
+	}
+	return icon[0]
+}
+
+// colorize wraps line in the color codes of the target.
+func colorize(line string, s statusline, target string) string {
+	colors := map[string][2]string{ // ansi, tmux
+		client.RunResultSucceeded: {"32", "green"},
+		client.RunResultFailed:    {"31", "red"},
+		client.RunResultCanceled:  {"90", "colour244"},
+		client.RunStateInProgress: {"33", "yellow"},
+	}
+	color, ok := colors[s.Status]
+	if !ok {
+		return line
+	}
+	switch target {
+	case "ansi":
+		return "\033[" + color[0] + "m" + line + "\033[0m"
+	case "tmux":
+		return "#[fg=" + color[1] + "]" + line + "#[default]"
+	}
+	return line
+}
+
+// shortDuration renders a duration with a single unit, e.g. "45s", "3h".
+func shortDuration(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
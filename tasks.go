@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"fomo/internal/client"
+)
+
+// eolHandlers are task execution handlers whose Node.js version reached
+// end of life; agents warn about them and will stop running them.
+var eolHandlers = map[string]bool{"Node": true, "Node10": true}
+
+// taskUsage is a major version of a task and where it is used.
+type taskUsage struct {
+	id        string
+	name      string
+	major     int
+	versions  map[string]bool
+	pipelines map[string]bool
+	kind      string
+	notes     []string
+}
+
+func (u *taskUsage) flagged() bool { return len(u.notes) > 0 }
+
+// majorVersion is the major version of a task version such as "2.210.0".
+func majorVersion(version string) int {
+	major, _, _ := strings.Cut(version, ".")
+	n, _ := strconv.Atoi(major)
+	return n
+}
+
+// timelineTasks fetches the timelines of the latest runs of definitions,
+// at most 8 at a time, and collects the tasks they ran. Definitions that
+// never ran are skipped; failures are reported and skipped.
+func timelineTasks(ctx context.Context, a *app, c *client.Client, definitions []client.Definition) map[string]*taskUsage {
+	usage := map[string]*taskUsage{}
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		sem = make(chan struct{}, 8)
+	)
+	for _, d := range definitions {
+		if d.LatestBuild == nil {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			timeline, err := c.GetTimeline(ctx, d.LatestBuild.ID)
+			if err != nil {
+				a.warnf("Warning: failed to fetch timeline of run %d of %s: %v\n", d.LatestBuild.ID, d.Name, err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, record := range timeline.Records {
+				if record.Type != client.RecordTask || record.Task == nil || record.Task.ID == "" {
+					continue
+				}
+				major := majorVersion(record.Task.Version)
+				key := fmt.Sprintf("%s@%d", record.Task.ID, major)
+				u, ok := usage[key]
+				if !ok {
+					u = &taskUsage{id: record.Task.ID, name: record.Task.Name, major: major, versions: map[string]bool{}, pipelines: map[string]bool{}}
+					usage[key] = u
+				}
+				u.versions[record.Task.Version] = true
+				u.pipelines[definitionPath(d.DefinitionReference)] = true
+			}
+		}()
+	}
+	wg.Wait()
+	return usage
+}
+
+// assessTask fills in the kind of a used task and what is wrong with it,
+// from the tasks installed in the organization.
+func assessTask(u *taskUsage, installed []client.TaskDefinition) {
+	var def *client.TaskDefinition
+	newest := -1
+	for i, t := range installed {
+		if t.Version.Major == u.major {
+			def = &installed[i]
+		}
+		if !t.Deprecated && !t.Version.IsTest && t.Version.Major > newest {
+			newest = t.Version.Major
+		}
+	}
+	if len(installed) > 0 {
+		t := installed[0]
+		if def != nil {
+			t = *def
+		}
+		switch {
+		case t.DefinitionType == client.TaskTypeTaskGroup:
+			u.kind = "task group"
+		case t.ContributionIdentifier != "":
+			u.kind = "extension"
+		default:
+			u.kind = "built-in"
+		}
+	}
+
+	switch {
+	case len(installed) == 0:
+		u.kind = "-"
+		u.notes = append(u.notes, "no longer installed")
+	case def == nil:
+		u.notes = append(u.notes, "version no longer installed")
+	case def.Deprecated:
+		u.notes = append(u.notes, "deprecated")
+	}
+	if newest > u.major {
+		u.notes = append(u.notes, fmt.Sprintf("@%d available", newest))
+	}
+	if def != nil && len(def.Execution) > 0 {
+		eol := true
+		var handlers []string
+		for handler := range def.Execution {
+			handlers = append(handlers, handler)
+			if !eolHandlers[handler] {
+				eol = false
+			}
+		}
+		if eol {
+			sort.Strings(handlers)
+			u.notes = append(u.notes, "only runs on end-of-life Node.js ("+strings.Join(handlers, ", ")+")")
+		}
+	}
+}
+
+func runTasksList(a *app, args []string) error {
+	fs := a.newFlagSet("tasks list", "[--used-by PIPELINE] [--deprecated]")
+	usedBy := fs.String("used-by", "", "only list the tasks of this pipeline (name or ID)")
+	deprecated := fs.Bool("deprecated", false, "only list tasks that are deprecated, outdated or on end-of-life handlers")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	definitions, err := c.ListDefinitionDetails(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch pipelines: %w", err)
+	}
+	if *usedBy != "" {
+		p, err := resolvePipeline(ctx, c, *usedBy)
+		if err != nil {
+			return err
+		}
+		definitions = slices.DeleteFunc(definitions, func(d client.Definition) bool { return d.ID != p.ID })
+		if len(definitions) == 0 || definitions[0].LatestBuild == nil {
+			return fmt.Errorf("%s has never run, so its tasks are unknown", p.Name)
+		}
+	}
+	installed, err := c.ListTaskDefinitions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch the installed tasks: %w", err)
+	}
+	byID := map[string][]client.TaskDefinition{}
+	for _, t := range installed {
+		byID[t.ID] = append(byID[t.ID], t)
+	}
+
+	usage := timelineTasks(ctx, a, c, definitions)
+	var tasks []*taskUsage
+	for _, u := range usage {
+		assessTask(u, byID[u.id])
+		if !*deprecated || u.flagged() {
+			tasks = append(tasks, u)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].name != tasks[j].name {
+			return strings.ToLower(tasks[i].name) < strings.ToLower(tasks[j].name)
+		}
+		return tasks[i].major < tasks[j].major
+	})
+
+	if a.quiet {
+		for _, u := range tasks {
+			a.resultf("%s@%d\t%s\t%d\t%s\n", u.name, u.major, strings.Join(slices.Sorted(maps.Keys(u.versions)), ","), len(u.pipelines), strings.Join(u.notes, "; "))
+		}
+		return nil
+	}
+	if len(tasks) == 0 {
+		if *deprecated {
+			a.infof("No deprecated or outdated tasks in use.\n")
+		} else {
+			a.infof("No tasks found in the latest runs.\n")
+		}
+		return nil
+	}
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TASK\tVERSIONS\tKIND\tUSED BY\tNOTES")
+	for _, u := range tasks {
+		pipelines := slices.Sorted(maps.Keys(u.pipelines))
+		usedBy := strings.Join(pipelines, ", ")
+		if len(pipelines) > 3 {
+			usedBy = fmt.Sprintf("%d pipelines", len(pipelines))
+		}
+		fmt.Fprintf(w, "%s@%d\t%s\t%s\t%s\t%s\n", u.name, u.major, strings.Join(slices.Sorted(maps.Keys(u.versions)), ", "), u.kind, usedBy, orDash(strings.Join(u.notes, "; ")))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"fomo/internal/config"
+	"fomo/internal/i18n"
+	"fomo/internal/telemetry"
+)
+
+// telemetryEndpoint is where usage statistics are sent, set at release
+// time with -ldflags "-X main.telemetryEndpoint=URL". Builds without one
+// never ask for consent and never send anything.
+var telemetryEndpoint = ""
+
+// telemetryEnv turns telemetry off when set to 0, off or false, whatever
+// was chosen before. DO_NOT_TRACK=1 does the same.
+const telemetryEnv = "FOMO_TELEMETRY"
+
+// noConsentPrompt lists commands that must never stop to ask for consent:
+// they run unattended or are embedded in other tools.
+var noConsentPrompt = map[string]bool{
+	"telemetry": true, "daemon": true, "statusline": true, "mock-server": true, "board": true,
+}
+
+func telemetryPath() (string, error) {
+	path, err := config.UserConfigPath(os.Getenv)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "telemetry.json"), nil
+}
+
+// telemetryDisabled reports whether the environment turns telemetry off.
+func telemetryDisabled() bool {
+	switch strings.ToLower(os.Getenv(telemetryEnv)) {
+	case "0", "off", "false":
+		return true
+	}
+	return os.Getenv("DO_NOT_TRACK") == "1"
+}
+
+// commandPath names the command args run, e.g. "runs list", from the
+// command table only, so no argument can end up in the statistics.
+func commandPath(cmds []*command, args []string) string {
+	var path []string
+	for len(args) > 0 {
+		found := findCommand(cmds, args[0])
+		if found == nil {
+			break
+		}
+		path = append(path, found.name)
+		if found.run != nil && (len(args) < 2 || findCommand(found.subcommands, args[1]) == nil) {
+			break
+		}
+		cmds, args = found.subcommands, args[1:]
+	}
+	if len(path) == 0 {
+		return "other" // plugins, typos and help
+	}
+	return strings.Join(path, " ")
+}
+
+// errorCategory names the category of an exit code.
+func errorCategory(code int) string {
+	switch code {
+	case exitOK:
+		return ""
+	case exitPipelineFailed:
+		return "pipeline failed"
+	case exitAuth:
+		return "auth"
+	case exitNotFound:
+		return "not found"
+	case exitThrottled:
+		return "throttled"
+	case exitUsage:
+		return "usage"
+	case exitCanceled:
+		return "canceled"
+	}
+	return "error"
+}
+
+// askTelemetryConsent asks once, on a terminal, whether to turn telemetry
+// on. Not answering yes leaves it off.
+func (a *app) askTelemetryConsent(command string) {
+	top, _, _ := strings.Cut(command, " ")
+	if telemetryEndpoint == "" || telemetryDisabled() || a.quiet || noConsentPrompt[top] ||
+		!isTerminal(os.Stdin) || !isTerminal(os.Stderr) {
+		return
+	}
+	path, err := telemetryPath()
+	if err != nil {
+		return
+	}
+	state, err := telemetry.Load(path)
+	if err != nil || state.Decided {
+		return
+	}
+	a.warnf(i18n.T("fomo can send anonymous usage statistics to its maintainers: how often each\n" +
+		"command runs and fails, with fomo's version and your OS. It never sends\n" +
+		"arguments, organization or project names, or credentials. You can change\n" +
+		"your mind any time with fomo telemetry on|off.\n"))
+	state.SetEnabled(confirm(i18n.T("Send anonymous usage statistics?")), time.Now())
+	if err := state.Save(path); err != nil {
+		a.warnf("Warning: failed to save telemetry choice: %v\n", err)
+	}
+}
+
+// recordTelemetry counts a finished command and, once a day, sends the
+// statistics. Failures are silent: telemetry must never get in the way.
+func (a *app) recordTelemetry(command string, code int) {
+	if telemetryEndpoint == "" || telemetryDisabled() || strings.HasPrefix(command, "telemetry") {
+		return
+	}
+	path, err := telemetryPath()
+	if err != nil {
+		return
+	}
+	state, err := telemetry.Load(path)
+	if err != nil || !state.Enabled {
+		return
+	}
+	now := time.Now()
+	state.Record(command, errorCategory(code))
+	if state.Due(now) {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		state.Send(ctx, telemetryEndpoint, version, now)
+		cancel()
+	}
+	state.Save(path)
+}
+
+func runTelemetryOn(a *app, args []string) error {
+	return setTelemetry(a, args, "on", true)
+}
+
+func runTelemetryOff(a *app, args []string) error {
+	return setTelemetry(a, args, "off", false)
+}
+
+func setTelemetry(a *app, args []string, name string, enabled bool) error {
+	fs := a.newFlagSet("telemetry "+name, "")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	path, err := telemetryPath()
+	if err != nil {
+		return err
+	}
+	state, err := telemetry.Load(path)
+	if err != nil {
+		return err
+	}
+	state.SetEnabled(enabled, time.Now())
+	if err := state.Save(path); err != nil {
+		return err
+	}
+	if enabled {
+		a.infof("Telemetry is on. Thank you!\n")
+		if telemetryEndpoint == "" {
+			a.warnf("Note: this build of fomo has no telemetry endpoint, so nothing is recorded or sent.\n")
+		}
+		if telemetryDisabled() {
+			a.warnf("Note: %s or DO_NOT_TRACK in your environment still turns it off.\n", telemetryEnv)
+		}
+	} else {
+		a.infof("Telemetry is off; the statistics gathered so far were deleted.\n")
+	}
+	return nil
+}
+
+func runTelemetryStatus(a *app, args []string) error {
+	fs := a.newFlagSet("telemetry status", "")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	path, err := telemetryPath()
+	if err != nil {
+		return err
+	}
+	state, err := telemetry.Load(path)
+	if err != nil {
+		return err
+	}
+
+	status := "off"
+	switch {
+	case telemetryDisabled():
+		status = "off (by " + telemetryEnv + " or DO_NOT_TRACK)"
+	case state.Enabled:
+		status = "on"
+	case !state.Decided:
+		status = "off (not asked yet)"
+	}
+	if a.quiet {
+		a.resultf("%s\n", strings.Fields(status)[0])
+		return nil
+	}
+	a.resultf("Telemetry: %s\n", status)
+	if telemetryEndpoint == "" {
+		a.resultf("Endpoint:  none in this build; nothing is ever sent\n")
+	} else {
+		a.resultf("Endpoint:  %s\n", telemetryEndpoint)
+	}
+	if !state.LastSent.IsZero() {
+		a.resultf("Last sent: %s\n", a.display.time(state.LastSent, time.DateTime))
+	}
+	if len(state.Commands) == 0 {
+		return nil
+	}
+
+	a.resultf("\nGathered since %s, to be sent:\n", a.display.time(state.Since, time.DateTime))
+	names := make([]string, 0, len(state.Commands))
+	for name := range state.Commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		a.resultf("  %-24s %d\n", name, state.Commands[name])
+	}
+	if len(state.Errors) > 0 {
+		a.resultf("\nErrors:\n")
+		names = names[:0]
+		for name := range state.Errors {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			a.resultf("  %-36s %d\n", name, state.Errors[name])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"fomo/internal/client"
+	"fomo/internal/watch"
+	"fomo/internal/yaml"
+)
+
+// templateLine is a template reference in pipeline YAML, as in
+// `template: build.yml@templates`
+var templateLine = regexp.MustCompile(`(?:^|[\s{,-])template:\s*['"]?([^'"\s#,}]+)@([\w.-]+)`)
+
+// templateUse is a pipeline's use of a template from the templates
+// repository.
+type templateUse struct {
+	pipeline string
+	ref      string // as the repository resource sets it; empty for its default branch
+	template string // empty when the pipeline only declares the repository
+	source   string // file:line
+	extends  bool
+}
+
+// templateRepoMatches reports whether the name of a repository resource,
+// such as Platform/pipeline-templates, names repo. Without a project or
+// owner, repo matches the repository in any.
+func templateRepoMatches(name, repo string) bool {
+	if strings.EqualFold(name, repo) {
+		return true
+	}
+	_, short, ok := strings.Cut(name, "/")
+	return ok && !strings.Contains(repo, "/") && strings.EqualFold(short, repo)
+}
+
+// pipelineTemplateUses finds the templates a pipeline's YAML file uses from
+// the repository repo, with the ref of the repository resource. Templates
+// referenced from other templates are not followed.
+func pipelineTemplateUses(ctx context.Context, c *client.Client, d client.Definition, repo string) ([]templateUse, error) {
+	name := definitionPath(d.DefinitionReference)
+	definition, err := c.GetDefinitionRaw(ctx, d.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pipeline %s: %w", name, err)
+	}
+	file, content, err := definitionYAML(ctx, c, d, definition)
+	if err != nil || file == "" {
+		return nil, err
+	}
+	var pipeline struct {
+		Resources struct {
+			Repositories []struct {
+				Repository string `yaml:"repository"`
+				Name       string `yaml:"name"`
+				Ref        string `yaml:"ref"`
+			} `yaml:"repositories"`
+		} `yaml:"resources"`
+		Extends struct {
+			Template string `yaml:"template"`
+		} `yaml:"extends"`
+	}
+	if err := yaml.Unmarshal([]byte(content), &pipeline); err != nil {
+		if strings.Contains(content, "@") {
+			return nil, fmt.Errorf("could not read the resources of %s in %s: %w", name, file, err)
+		}
+		return nil, nil
+	}
+
+	// The aliases the pipeline gives the templates repository, with the ref
+	// of each
+	refs := map[string]string{}
+	for _, r := range pipeline.Resources.Repositories {
+		if r.Repository != "" && templateRepoMatches(r.Name, repo) {
+			refs[r.Repository] = r.Ref
+		}
+	}
+	if len(refs) == 0 {
+		return nil, nil
+	}
+
+	var uses []templateUse
+	used := map[string]bool{}
+	for i, line := range strings.Split(content, "\n") {
+		m := templateLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		ref, ok := refs[m[2]]
+		if !ok {
+			continue
+		}
+		used[m[2]] = true
+		uses = append(uses, templateUse{
+			pipeline: name,
+			ref:      ref,
+			template: m[1],
+			source:   fmt.Sprintf("%s:%d", file, i+1),
+			extends:  pipeline.Extends.Template == m[1]+"@"+m[2],
+		})
+	}
+	for alias, ref := range refs {
+		if !used[alias] {
+			uses = append(uses, templateUse{pipeline: name, ref: ref, source: file})
+		}
+	}
+	return uses, nil
+}
+
+// templateRefLabel describes the ref a pipeline pins the templates
+// repository to.
+func templateRefLabel(ref string) string {
+	switch {
+	case ref == "":
+		return "default branch (not pinned)"
+	case strings.HasPrefix(ref, "refs/tags/"):
+		return "tag " + strings.TrimPrefix(ref, "refs/tags/")
+	case strings.HasPrefix(ref, "${{"):
+		return ref
+	}
+	return "branch " + watch.ShortBranch(ref)
+}
+
+func runTemplatesUsage(a *app, args []string) error {
+	const usage = "usage: fomo templates usage --repo NAME [--template FILE] [--pipeline NAME]"
+	fs := a.newFlagSet("templates usage", "--repo NAME [--template FILE] [--pipeline NAME]")
+	repo := fs.String("repo", "", "templates repository, as named in repository resources (repo or project/repo)")
+	template := fs.String("template", "", "only report uses of this template file of the repository")
+	pipeline := fs.String("pipeline", "", "only scan this pipeline (name or ID)")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 || *repo == "" {
+		return newUsageError(usage)
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	definitions, err := c.ListDefinitionDetails(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch pipelines: %w", err)
+	}
+	if *pipeline != "" {
+		p, err := resolvePipeline(ctx, c, *pipeline)
+		if err != nil {
+			return err
+		}
+		definitions = slices.DeleteFunc(definitions, func(d client.Definition) bool { return d.ID != p.ID })
+	}
+
+	results := make([][]templateUse, len(definitions))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 8)
+	for i, d := range definitions {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			uses, err := pipelineTemplateUses(ctx, c, d, *repo)
+			if err != nil {
+				a.warnf("Warning: %v\n", err)
+			}
+			results[i] = uses
+		}()
+	}
+	wg.Wait()
+
+	var uses []templateUse
+	for _, u := range results {
+		for _, use := range u {
+			if *template == "" || strings.EqualFold(strings.TrimPrefix(use.template, "/"), strings.TrimPrefix(*template, "/")) {
+				uses = append(uses, use)
+			}
+		}
+	}
+	sort.SliceStable(uses, func(i, j int) bool {
+		if uses[i].ref != uses[j].ref {
+			return uses[i].ref < uses[j].ref
+		}
+		return strings.ToLower(uses[i].pipeline) < strings.ToLower(uses[j].pipeline)
+	})
+
+	if a.quiet {
+		for _, u := range uses {
+			a.resultf("%s\t%s\t%s\t%s\n", u.pipeline, u.ref, u.template, u.source)
+		}
+		return nil
+	}
+	if len(uses) == 0 {
+		a.infof("None of %d pipelines uses templates from %s.\n", len(definitions), *repo)
+		return nil
+	}
+	pipelines := map[string]bool{}
+	for i, u := range uses {
+		if i == 0 || u.ref != uses[i-1].ref {
+			if i > 0 {
+				a.resultf("\n")
+			}
+			a.resultf("%s\n", templateRefLabel(u.ref))
+		}
+		pipelines[u.pipeline] = true
+		switch {
+		case u.template == "":
+			a.resultf("  %s: declares the repository without using its templates directly\n", u.pipeline)
+		case u.extends:
+			a.resultf("  %s: extends %s (%s)\n", u.pipeline, u.template, u.source)
+		default:
+			a.resultf("  %s: %s (%s)\n", u.pipeline, u.template, u.source)
+		}
+	}
+	a.infof("\n%d of %d pipelines use templates from %s.\n", len(pipelines), len(definitions), *repo)
+	return nil
+}
@@ -0,0 +1,20 @@
+//go:build !unix && !windows
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+func isTTY(f *os.File) bool {
+	return true
+}
+
+func disableEcho(f *os.File) (func(), error) {
+	return nil, errors.New("not supported on this platform")
+}
+
+func terminalSize(f *os.File) (int, int, error) {
+	return 0, 0, errors.New("not supported on this platform")
+}
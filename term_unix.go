@@ -0,0 +1,54 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// ttys remembers what isTTY found for each file descriptor.
+var ttys sync.Map
+
+// isTTY reports whether the character device f is a terminal: only a
+// terminal has settings for stty to read.
+func isTTY(f *os.File) bool {
+	if tty, ok := ttys.Load(f.Fd()); ok {
+		return tty.(bool)
+	}
+	tty := stty(f, "-g") == nil
+	ttys.Store(f.Fd(), tty)
+	return tty
+}
+
+// disableEcho turns off terminal echo on f and returns a function that
+// turns it back on.
+func disableEcho(f *os.File) (func(), error) {
+	if err := stty(f, "-echo"); err != nil {
+		return nil, err
+	}
+	return func() { stty(f, "echo") }, nil
+}
+
+func stty(f *os.File, args ...string) error {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = f
+	return cmd.Run()
+}
+
+// terminalSize returns the number of columns and rows of the terminal f.
+func terminalSize(f *os.File) (int, int, error) {
+	cmd := exec.Command("stty", "size")
+	cmd.Stdin = f
+	out, err := cmd.Output()
+	if err != nil {
+		return 0, 0, err
+	}
+	var rows, cols int
+	if _, err := fmt.Sscan(string(out), &rows, &cols); err != nil {
+		return 0, 0, err
+	}
+	return cols, rows, nil
+}
@@ -0,0 +1,55 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const enableEchoInput = 0x0004
+
+var (
+	kernel32                   = syscall.NewLazyDLL("kernel32.dll")
+	setConsoleMode             = kernel32.NewProc("SetConsoleMode")
+	getConsoleScreenBufferInfo = kernel32.NewProc("GetConsoleScreenBufferInfo")
+)
+
+// isTTY reports whether the character device f is a console rather than,
+// say, NUL.
+func isTTY(f *os.File) bool {
+	var mode uint32
+	return syscall.GetConsoleMode(syscall.Handle(f.Fd()), &mode) == nil
+}
+
+// disableEcho turns off console echo on f and returns a function that turns
+// it back on.
+func disableEcho(f *os.File) (func(), error) {
+	handle := syscall.Handle(f.Fd())
+	var mode uint32
+	if err := syscall.GetConsoleMode(handle, &mode); err != nil {
+		return nil, err
+	}
+	if ok, _, err := setConsoleMode.Call(uintptr(handle), uintptr(mode&^enableEchoInput)); ok == 0 {
+		return nil, err
+	}
+	return func() { setConsoleMode.Call(uintptr(handle), uintptr(mode)) }, nil
+}
+
+// consoleScreenBufferInfo mirrors CONSOLE_SCREEN_BUFFER_INFO.
+type consoleScreenBufferInfo struct {
+	size, cursorPosition     struct{ x, y int16 }
+	attributes               uint16
+	left, top, right, bottom int16
+	maximumWindowSize        struct{ x, y int16 }
+}
+
+// terminalSize returns the number of columns and rows of the console f.
+func terminalSize(f *os.File) (int, int, error) {
+	var info consoleScreenBufferInfo
+	if ok, _, err := getConsoleScreenBufferInfo.Call(f.Fd(), uintptr(unsafe.Pointer(&info))); ok == 0 {
+		return 0, 0, err
+	}
+	return int(info.right-info.left) + 1, int(info.bottom-info.top) + 1, nil
+}
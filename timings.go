@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"time"
+)
+
+// printTimings reports on stderr what the API requests of a command cost,
+// in total and per endpoint, for --timings.
+func (a *app) printTimings(elapsed time.Duration) {
+	total := a.metrics.Total()
+	hitRate := 0
+	if total.Calls > 0 {
+		hitRate = total.Cached * 100 / total.Calls
+	}
+	fmt.Fprintf(a.stderr, "\nTimings: API calls: %d, failed: %d, cached: %d (%d%% hit rate); sent %s, received %s; %s waiting for Azure DevOps in %s.\n",
+		total.Calls, total.Failed, total.Cached, hitRate, formatBytes(total.Sent), formatBytes(total.Received), total.Latency.Round(time.Millisecond), elapsed.Round(time.Millisecond))
+	if total.Calls == 0 {
+		return
+	}
+
+	w := tabwriter.NewWriter(a.stderr, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ENDPOINT\tCALLS\tFAILED\tCACHED\tRECEIVED\tLATENCY")
+	for _, e := range a.metrics.Endpoints() {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\t%s\n", e.Endpoint, e.Calls, e.Failed, e.Cached, formatBytes(e.Received), e.Latency.Round(time.Millisecond))
+	}
+	w.Flush()
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"fomo/internal/client"
+	"fomo/internal/otlp"
+	"fomo/internal/watch"
+)
+
+func runTrace(a *app, args []string) error {
+	fs := a.newFlagSet("trace", "<run-id> [--otlp-endpoint HOST:PORT]")
+	endpoint := fs.String("otlp-endpoint", "", "OTLP/HTTP collector to export to (default: $OTEL_EXPORTER_OTLP_ENDPOINT, or "+otlp.DefaultEndpoint+")")
+	dryRun := fs.Bool("dry-run", false, "print the OTLP/JSON request instead of sending it")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return newUsageError("usage: fomo trace <run-id> [--otlp-endpoint HOST:PORT] [--dry-run]")
+	}
+	runID, err := parseRunID(positional[0])
+	if err != nil {
+		return err
+	}
+	if *endpoint == "" {
+		*endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	if *endpoint == "" {
+		*endpoint = otlp.DefaultEndpoint
+	}
+	exporter := &otlp.Exporter{}
+	if exporter.URL, err = otlp.Endpoint(*endpoint); err != nil {
+		return newUsageError(err.Error())
+	}
+	if exporter.Headers, err = otlp.ParseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")); err != nil {
+		return err
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	build, err := c.GetBuild(ctx, runID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch run %d: %w", runID, err)
+	}
+	tl, err := c.GetTimeline(ctx, runID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch timeline of run %d: %w", runID, err)
+	}
+	resource, spans := traceSpans(c, build, tl.Records, time.Now())
+
+	if *dryRun {
+		body, err := otlp.Marshal(resource, spans)
+		if err != nil {
+			return err
+		}
+		a.resultf("%s\n", body)
+		return nil
+	}
+	if err := exporter.Export(ctx, resource, spans); err != nil {
+		return fmt.Errorf("failed to export trace: %w", err)
+	}
+	traceID := spans[0].TraceID
+	a.infof("Exported %d spans of run %s to %s\n", len(spans), build.BuildNumber, exporter.URL)
+	a.resultf("%x\n", traceID)
+	return nil
+}
+
+// traceSpans converts a run and its timeline into a trace: the run is the
+// root span and every stage, phase, job and task that started is a child
+// of its parent record. Anything still running ends at now. The IDs are
+// derived from the run, so exporting it again replaces the same trace.
+func traceSpans(c *client.Client, build *client.Build, records []client.TimelineRecord, now time.Time) (map[string]any, []otlp.Span) {
+	key := fmt.Sprintf("%s/%s/%d", c.Organization, c.Project, build.ID)
+	traceID := otlp.TraceID(key)
+	resource := map[string]any{
+		"service.name":         build.Definition.Name,
+		"cicd.pipeline.name":   build.Definition.Name,
+		"azure_devops.org":     c.Organization,
+		"azure_devops.project": c.Project,
+	}
+
+	root := otlp.Span{
+		TraceID: traceID,
+		SpanID:  otlp.SpanID(key + "/run"),
+		Name:    build.Definition.Name + " " + build.BuildNumber,
+		Start:   build.QueueTime,
+		End:     now,
+		Attributes: map[string]any{
+			"cicd.pipeline.run.id":  build.ID,
+			"azure_devops.run.name": build.BuildNumber,
+			"vcs.ref.head.name":     watch.ShortBranch(build.BranchName()),
+			"azure_devops.result":   build.Result,
+		},
+	}
+	if sha := build.HeadCommit(); sha != "" {
+		root.Attributes["vcs.ref.head.revision"] = sha
+	}
+	if repo := build.Repository.WebURL(); repo != "" && build.Repository.Host() != "" {
+		root.Attributes["vcs.repository.url.full"] = repo
+	}
+	if n := build.PullRequest(); n != 0 {
+		root.Attributes["vcs.change.id"] = strconv.Itoa(n)
+	}
+	if build.StartTime != nil {
+		root.Start = *build.StartTime
+	}
+	if build.FinishTime != nil {
+		root.End = *build.FinishTime
+	}
+	if build.Links.Web != nil {
+		root.Attributes["url.full"] = build.Links.Web.Href
+	}
+	root.Failed, root.Message = traceFailure(build.Result, nil)
+
+	started := map[string]bool{}
+	parents := map[string]string{}
+	for _, r := range records {
+		parents[r.ID] = r.ParentID
+		if r.StartTime != nil {
+			started[r.ID] = true
+		}
+	}
+
+	spans := []otlp.Span{root}
+	for _, r := range records {
+		if r.StartTime == nil {
+			continue
+		}
+		span := otlp.Span{
+			TraceID:  traceID,
+			SpanID:   otlp.SpanID(key + "/" + r.ID),
+			ParentID: root.SpanID,
+			Name:     r.Name,
+			Start:    *r.StartTime,
+			End:      now,
+			Attributes: map[string]any{
+				"cicd.pipeline.task.type": strings.ToLower(r.Type),
+				"azure_devops.result":     r.Result,
+				"azure_devops.worker":     r.WorkerName,
+				"azure_devops.attempt":    r.Attempt,
+			},
+		}
+		if r.FinishTime != nil {
+			span.End = *r.FinishTime
+		}
+		// Records that never started have no span; attach their children
+		// to the nearest ancestor that did
+		for parent := r.ParentID; parent != ""; parent = parents[parent] {
+			if started[parent] {
+				span.ParentID = otlp.SpanID(key + "/" + parent)
+				break
+			}
+		}
+		span.Failed, span.Message = traceFailure(r.Result, r.Issues)
+		spans = append(spans, span)
+	}
+	return resource, spans
+}
+
+// traceFailure reports whether a result is a failure, with the first error
+// reported by the record as the message.
+func traceFailure(result string, issues []client.Issue) (bool, string) {
+	if result != client.RunResultFailed {
+		return false, ""
+	}
+	for _, issue := range issues {
+		if issue.Type == "error" {
+			return true, issue.Message
+		}
+	}
+	return true, result
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"fomo/internal/i18n"
+	"fomo/internal/selfupdate"
+)
+
+// Set at release time with -ldflags "-X main.version=v1.2.3 -X
+// main.releaseKey=BASE64".
+var (
+	version = "dev"
+	// releaseKey is the Ed25519 public key release checksums are signed
+	// with; builds without one cannot update themselves
+	releaseKey = ""
+)
+
+// updateFeedEnv overrides the release feed, for mirrors.
+const updateFeedEnv = "FOMO_UPDATE_FEED"
+
+func newUpdater() *selfupdate.Updater {
+	return &selfupdate.Updater{Feed: os.Getenv(updateFeedEnv), PublicKey: releaseKey}
+}
+
+func runVersion(a *app, args []string) error {
+	fs := a.newFlagSet("version", "[--check] | next <pipeline> [flags]")
+	check := fs.Bool("check", false, "check whether a newer release is available")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if a.quiet {
+		a.resultf("%s\n", version)
+	} else {
+		a.resultf("fomo %s (%s %s/%s)\n", buildVersion(), runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	}
+	if !*check {
+		return nil
+	}
+
+	release, err := newUpdater().Latest(a.ctx)
+	if err != nil {
+		return err
+	}
+	if selfupdate.Newer(release.Tag, version) {
+		a.warnf("A newer release is available: fomo %s (%s)\nRun fomo update to install it.\n", release.Tag, release.URL)
+	} else {
+		a.infof("fomo is up to date\n")
+	}
+	return nil
+}
+
+func runUpdate(a *app, args []string) error {
+	fs := a.newFlagSet("update", "[--check] [--yes]")
+	check := fs.Bool("check", false, "only report whether an update is available")
+	yes := fs.Bool("yes", false, "install without asking")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	ctx := a.ctx
+	updater := newUpdater()
+	release, err := updater.Latest(ctx)
+	if err != nil {
+		return err
+	}
+	if !selfupdate.Newer(release.Tag, version) {
+		a.infof("fomo %s is the latest release\n", version)
+		return nil
+	}
+	if *check {
+		a.resultf("%s\n", release.Tag)
+		a.infof("fomo %s is available (this is %s): %s\n", release.Tag, version, release.URL)
+		return nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the fomo executable: %w", err)
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return fmt.Errorf("failed to locate the fomo executable: %w", err)
+	}
+	if !*yes {
+		if !isTerminal(os.Stdin) {
+			return newUsageError("refusing to update without confirmation; use --yes")
+		}
+		if !confirm(i18n.T("Update %s from %s to %s?", exe, version, release.Tag)) {
+			return errAborted
+		}
+	}
+
+	a.infof("Downloading %s %s...\n", selfupdate.AssetName(), release.Tag)
+	binary, err := updater.Download(ctx, release)
+	if errors.Is(err, selfupdate.ErrUnsigned) {
+		return fmt.Errorf("%w; download the release from %s instead", err, release.URL)
+	}
+	if err != nil {
+		return err
+	}
+	if err := selfupdate.Replace(exe, binary); err != nil {
+		return fmt.Errorf("failed to install update: %w", err)
+	}
+	a.infof("Updated fomo to %s\n", release.Tag)
+	return nil
+}
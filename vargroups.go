@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"fomo/internal/client"
+)
+
+// keyVaultSecretsSource is the data source of Azure Resource Manager
+// service connections that lists the secrets of a vault, as the variable
+// group editor does.
+const keyVaultSecretsSource = "KeyVaultSecrets"
+
+// resolveVariableGroup finds a variable group by ID or name
+// (case-insensitive).
+func resolveVariableGroup(ctx context.Context, c *client.Client, nameOrID string) (*client.VariableGroup, error) {
+	groups, err := c.ListVariableGroups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch variable groups: %w", err)
+	}
+	id, _ := strconv.Atoi(nameOrID)
+	for i, g := range groups {
+		if g.ID == id || strings.EqualFold(g.Name, nameOrID) {
+			return &groups[i], nil
+		}
+	}
+	return nil, &client.APIError{StatusCode: http.StatusNotFound, Status: "404 Not Found", Message: fmt.Sprintf("variable group %q not found", nameOrID)}
+}
+
+// groupKind describes the type of a variable group.
+func groupKind(g client.VariableGroup) string {
+	if g.Type == client.VariableGroupAzureKeyVault {
+		if g.ProviderData != nil && g.ProviderData.Vault != "" {
+			return "Key Vault " + g.ProviderData.Vault
+		}
+		return "Key Vault"
+	}
+	return "variables"
+}
+
+func runVargroupsList(a *app, args []string) error {
+	fs := a.newFlagSet("vargroups list", "")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	groups, err := c.ListVariableGroups(a.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch variable groups: %w", err)
+	}
+	slices.SortFunc(groups, func(x, y client.VariableGroup) int {
+		return strings.Compare(strings.ToLower(x.Name), strings.ToLower(y.Name))
+	})
+
+	if a.quiet {
+		for _, g := range groups {
+			a.resultf("%d\t%s\t%s\t%d\n", g.ID, g.Name, g.Type, len(g.Variables))
+		}
+		return nil
+	}
+	if len(groups) == 0 {
+		a.infof("No variable groups in %s.\n", c.Project)
+		return nil
+	}
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tSOURCE\tVARIABLES\tMODIFIED")
+	for _, g := range groups {
+		modified := "-"
+		if !g.ModifiedOn.IsZero() {
+			modified = a.display.time(g.ModifiedOn, time.DateOnly)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\n", g.ID, g.Name, groupKind(g), len(g.Variables), modified)
+	}
+	return w.Flush()
+}
+
+func runVargroupsShow(a *app, args []string) error {
+	fs := a.newFlagSet("vargroups show", "<group> [--verify]")
+	verify := fs.Bool("verify", false, "check that the service connection of a Key Vault-linked group can still read the vault")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return newUsageError("usage: fomo vargroups show <group> [--verify]")
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	g, err := resolveVariableGroup(ctx, c, positional[0])
+	if err != nil {
+		return err
+	}
+	keyVault := g.Type == client.VariableGroupAzureKeyVault
+	if *verify && !keyVault {
+		return newUsageError(fmt.Sprintf("--verify needs a Key Vault-linked group; %s holds its own variables", g.Name))
+	}
+	names := slices.Sorted(maps.Keys(g.Variables))
+
+	if a.quiet {
+		for _, name := range names {
+			a.resultf("%s\t%s\n", name, groupValue(g.Variables[name]))
+		}
+	} else {
+		a.resultf("Group:     %s (%d)\n", g.Name, g.ID)
+		if g.Description != "" {
+			a.resultf("About:     %s\n", g.Description)
+		}
+		if !g.ModifiedOn.IsZero() {
+			by := ""
+			if g.CreatedBy != nil {
+				by = ", created by " + g.CreatedBy.DisplayName
+			}
+			a.resultf("Modified:  %s%s\n", a.display.time(g.ModifiedOn, time.DateTime), by)
+		}
+		if keyVault {
+			showKeyVault(ctx, a, c, g)
+		}
+		a.resultf("\n")
+		if err := showGroupVariables(a, g, names); err != nil {
+			return err
+		}
+	}
+
+	if *verify {
+		return verifyKeyVault(ctx, a, c, g)
+	}
+	return nil
+}
+
+// groupValue is the value of a group variable as shown: secrets and Key
+// Vault secrets have none to show.
+func groupValue(v client.GroupVariable) string {
+	switch {
+	case v.IsSecret:
+		return "********"
+	case v.Value == nil:
+		return ""
+	}
+	return *v.Value
+}
+
+// showKeyVault prints the vault a group is linked to, the service
+// connection it reads the vault with, and when its secrets were last
+// refreshed from the vault.
+func showKeyVault(ctx context.Context, a *app, c *client.Client, g *client.VariableGroup) {
+	provider := g.ProviderData
+	if provider == nil {
+		provider = &client.VariableGroupProvider{}
+	}
+	a.resultf("Key Vault: %s\n", orDash(provider.Vault))
+	connection := provider.ServiceEndpointID
+	if connection != "" {
+		// The name is only for display; --verify reports a missing one
+		if endpoint, err := c.GetServiceEndpoint(ctx, connection); err == nil && endpoint != nil {
+			connection = endpoint.Name + " (" + endpoint.ID + ")"
+		}
+	}
+	a.resultf("Via:       %s\n", orDash(connection))
+	if provider.LastRefreshedOn == nil || provider.LastRefreshedOn.IsZero() {
+		a.resultf("Refreshed: never\n")
+		return
+	}
+	a.resultf("Refreshed: %s (%s ago)\n", a.display.time(*provider.LastRefreshedOn, time.DateTime), a.display.duration(time.Since(*provider.LastRefreshedOn)))
+}
+
+// showGroupVariables prints the variables of a group, or the secrets a Key
+// Vault-linked group takes from its vault.
+func showGroupVariables(a *app, g *client.VariableGroup, names []string) error {
+	if len(names) == 0 {
+		a.infof("No variables.\n")
+		return nil
+	}
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	if g.Type != client.VariableGroupAzureKeyVault {
+		fmt.Fprintln(w, "NAME\tVALUE")
+		for _, name := range names {
+			fmt.Fprintf(w, "%s\t%s\n", name, groupValue(g.Variables[name]))
+		}
+		return w.Flush()
+	}
+	fmt.Fprintln(w, "SECRET\tENABLED\tEXPIRES\tCONTENT TYPE")
+	for _, name := range names {
+		v := g.Variables[name]
+		enabled := "yes"
+		if v.Enabled != nil && !*v.Enabled {
+			enabled = "no"
+		}
+		expires := "-"
+		if v.Expires != nil && !v.Expires.IsZero() {
+			expires = a.display.time(*v.Expires, time.DateOnly)
+			if v.Expires.Before(time.Now()) {
+				expires += " (expired)"
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, enabled, expires, orDash(v.ContentType))
+	}
+	return w.Flush()
+}
+
+// verifyKeyVault checks that the service connection of a Key Vault-linked
+// group exists, is ready, and can list the secrets of the vault, and that
+// the secrets the group takes are still there.
+func verifyKeyVault(ctx context.Context, a *app, c *client.Client, g *client.VariableGroup) error {
+	if g.ProviderData == nil || g.ProviderData.ServiceEndpointID == "" || g.ProviderData.Vault == "" {
+		return fmt.Errorf("%s names no vault or service connection", g.Name)
+	}
+	endpoint, err := c.GetServiceEndpoint(ctx, g.ProviderData.ServiceEndpointID)
+	var apiErr *client.APIError
+	if (err == nil && endpoint == nil) || (errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound) {
+		return fmt.Errorf("the service connection %s of %s no longer exists, or you cannot see it", g.ProviderData.ServiceEndpointID, g.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch the service connection of %s: %w", g.Name, err)
+	}
+	if !endpoint.IsReady {
+		return fmt.Errorf("the service connection %s is not ready; open it in Project settings to finish or repair it", endpoint.Name)
+	}
+
+	result, err := c.QueryServiceEndpoint(ctx, endpoint.ID, keyVaultSecretsSource, map[string]string{"KeyVaultName": g.ProviderData.Vault})
+	if err != nil {
+		return fmt.Errorf("failed to query %s through %s: %w", g.ProviderData.Vault, endpoint.Name, err)
+	}
+	if result.ErrorMessage != "" || (result.StatusCode != "" && !strings.EqualFold(result.StatusCode, "ok")) {
+		message := result.ErrorMessage
+		if message == "" {
+			message = result.StatusCode
+		}
+		return fmt.Errorf("%s cannot list the secrets of %s: %s", endpoint.Name, g.ProviderData.Vault, message)
+	}
+
+	inVault := map[string]bool{}
+	for _, item := range result.Result {
+		if name, ok := item.(string); ok {
+			inVault[strings.ToLower(name)] = true
+		}
+	}
+	var missing []string
+	for name := range g.Variables {
+		if !inVault[strings.ToLower(name)] {
+			missing = append(missing, name)
+		}
+	}
+	slices.Sort(missing)
+	if len(missing) > 0 {
+		return fmt.Errorf("%s can read %s, but it no longer has %s", endpoint.Name, g.ProviderData.Vault, strings.Join(missing, ", "))
+	}
+	a.infof("\n%s can read %s and all %d secrets of %s.\n", endpoint.Name, g.ProviderData.Vault, len(g.Variables), g.Name)
+	return nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"fomo/internal/client"
+)
+
+// releaseVersion matches a MAJOR.MINOR.PATCH version in a build number or
+// tag, such as 1.4.2 in "api_1.4.2" or "v1.4.2-rc.1".
+var releaseVersion = regexp.MustCompile(`(?:^|[^\d.])v?(\d+)\.(\d+)\.(\d+)(-[0-9A-Za-z.-]+)?(?:$|[^\d.])`)
+
+// semver is a release version, without prerelease.
+type semver [3]int
+
+func (v semver) String() string {
+	return fmt.Sprintf("%d.%d.%d", v[0], v[1], v[2])
+}
+
+func (v semver) less(w semver) bool {
+	for i := range v {
+		if v[i] != w[i] {
+			return v[i] < w[i]
+		}
+	}
+	return false
+}
+
+// parseReleaseVersion finds a release version in s. Prereleases are not
+// releases and are skipped.
+func parseReleaseVersion(s string) (semver, bool) {
+	m := releaseVersion.FindStringSubmatch(s)
+	if m == nil || m[4] != "" {
+		return semver{}, false
+	}
+	var v semver
+	for i := range v {
+		n, err := strconv.Atoi(m[i+1])
+		if err != nil {
+			return semver{}, false
+		}
+		v[i] = n
+	}
+	return v, true
+}
+
+func runVersionNext(a *app, args []string) error {
+	const usage = "usage: fomo version next <pipeline> [--scheme semver|calver] [--bump major|minor|patch] [--branch NAME] [--prefix v] [--runs N]"
+	fs := a.newFlagSet("version next", "<pipeline> [--scheme semver|calver] [--bump major|minor|patch] [--branch NAME] [--prefix v] [--runs N]")
+	scheme := fs.String("scheme", "semver", "semver for MAJOR.MINOR.PATCH, or calver for YEAR.MONTH.N")
+	bump := fs.String("bump", "patch", "part of a semver version to increase: major, minor or patch")
+	branch := fs.String("branch", "", "only read the runs of this branch")
+	prefix := fs.String("prefix", "", "prefix to print before the version, such as v")
+	runs := fs.Int("runs", 200, "how many recent runs to read versions from")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return newUsageError(usage)
+	}
+	switch {
+	case *scheme != "semver" && *scheme != "calver":
+		return newUsageError("--scheme must be semver or calver")
+	case *bump != "major" && *bump != "minor" && *bump != "patch":
+		return newUsageError("--bump must be major, minor or patch")
+	case *scheme == "calver" && *bump != "patch":
+		return newUsageError("--bump only applies to --scheme semver")
+	case *runs <= 0:
+		return newUsageError("--runs must be positive")
+	}
+
+	c, err := a.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := a.ctx
+	p, err := resolvePipeline(ctx, c, positional[0])
+	if err != nil {
+		return err
+	}
+	// Every run counts, failed ones too: a version a failed release took
+	// may already be published somewhere
+	builds, err := c.ListBuilds(ctx, client.BuildCriteria{Definitions: []int{p.ID}, Branch: *branch, Top: *runs})
+	if err != nil {
+		return fmt.Errorf("failed to fetch the runs of %s: %w", p.Name, err)
+	}
+
+	now := time.Now().UTC()
+	var latest semver
+	var from string
+	found := false
+	for _, b := range builds {
+		for _, s := range append([]string{b.BuildNumber}, b.Tags...) {
+			v, ok := parseReleaseVersion(s)
+			if !ok || isCalver(v) != (*scheme == "calver") {
+				continue
+			}
+			if *scheme == "calver" && (v[0] != now.Year() || v[1] != int(now.Month())) {
+				continue
+			}
+			if !found || latest.less(v) {
+				latest, from, found = v, fmt.Sprintf("%s of run %s", s, b.BuildNumber), true
+			}
+		}
+	}
+
+	next := nextVersion(latest, found, *scheme, *bump, now)
+	if found {
+		a.infof("Latest version of %s: %s (%s)\n", p.Name, latest, from)
+	} else {
+		a.infof("No %s version in the last %d runs of %s\n", *scheme, len(builds), p.Name)
+	}
+	a.resultf("%s%s\n", *prefix, next)
+	return nil
+}
+
+// isCalver reports whether v is YEAR.MONTH.N rather than a semver version,
+// which does not reach a major version in the thousands.
+func isCalver(v semver) bool {
+	return v[0] >= 1000 && v[1] >= 1 && v[1] <= 12
+}
+
+// nextVersion follows latest, if found. A calver version continues the
+// numbering of the current month, and starts it at 0.
+func nextVersion(latest semver, found bool, scheme, bump string, now time.Time) semver {
+	if scheme == "calver" {
+		if !found {
+			return semver{now.Year(), int(now.Month()), 0}
+		}
+		return semver{latest[0], latest[1], latest[2] + 1}
+	}
+	switch bump {
+	case "major":
+		return semver{latest[0] + 1, 0, 0}
+	case "minor":
+		return semver{latest[0], latest[1] + 1, 0}
+	}
+	return semver{latest[0], latest[1], latest[2] + 1}
+}